@@ -0,0 +1,673 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/acoustid"
+	"github.com/nicolito128/tempo/internal/analysis"
+	"github.com/nicolito128/tempo/internal/cast"
+	"github.com/nicolito128/tempo/internal/components/player"
+	"github.com/nicolito128/tempo/internal/components/ui"
+	"github.com/nicolito128/tempo/internal/config"
+	"github.com/nicolito128/tempo/internal/control"
+	"github.com/nicolito128/tempo/internal/httpclient"
+	"github.com/nicolito128/tempo/internal/library"
+	"github.com/nicolito128/tempo/internal/loudness"
+	"github.com/nicolito128/tempo/internal/remote"
+	"github.com/nicolito128/tempo/internal/statusfile"
+	"github.com/nicolito128/tempo/internal/tags"
+	"github.com/nicolito128/tempo/internal/xdg"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// openIndex opens the library index at its default location.
+func openIndex() (*library.Index, error) {
+	path, err := library.DefaultIndexPath()
+	if err != nil {
+		return nil, err
+	}
+	return library.OpenIndex(path)
+}
+
+// runScan scans the music directories, the ones of the config if none is given, into the
+// library index with the library settings of the config.
+func runScan(args []string) error {
+	cfg := loadConfig()
+	dirs := parseFlags(newFlagSet("scan"), args)
+	if len(dirs) == 0 {
+		dirs = libraryDirs(cfg.Library.Dirs)
+	}
+	if len(dirs) == 0 {
+		return errors.New("usage: tempo scan <dir>..., or set dirs in the [library] section of the config")
+	}
+
+	filter, err := library.NewFilter(cfg.Library.Ignore, cfg.Library.Extensions)
+	if err != nil {
+		return fmt.Errorf("bad config: %w", err)
+	}
+	idx, err := openIndex()
+	if err != nil {
+		return err
+	}
+	defer idx.Close()
+
+	scanner := library.NewScanner(dirs, 0)
+	scanner.SetIndex(idx)
+	scanner.SetFilter(filter)
+	scanner.SetAnalyze(cfg.Library.Analyze)
+	scanner.SetMeasureLoudness(cfg.Player.Normalize && cfg.Player.ReplayGain != "off")
+	if cfg.AcoustID.Scan && cfg.AcoustID.APIKey != "" {
+		scanner.SetIdentifier(acoustid.New(httpclient.New(acoustid.Interval), cfg.AcoustID.APIKey))
+	}
+
+	done := scanner.Scan(context.Background())
+	for _, err := range done.Errors {
+		fmt.Fprintln(os.Stderr, "Warning:", err)
+	}
+	fmt.Printf("%d tracks (%d updated, %d removed) scanned in %s\n",
+		len(done.Tracks), done.Updated, len(done.Removed), done.Elapsed.Round(time.Millisecond))
+	return nil
+}
+
+// runDuplicates writes the duplicates report of the tracks in the library index.
+func runDuplicates(args []string) error {
+	parseFlags(newFlagSet("duplicates"), args)
+	idx, err := openIndex()
+	if err != nil {
+		return err
+	}
+	defer idx.Close()
+
+	tracks, err := idx.All()
+	if err != nil {
+		return err
+	}
+	return library.WriteDuplicateReport(os.Stdout, library.FindDuplicates(tracks))
+}
+
+// runDevices writes the audio output devices, one per line with the name to choose it.
+func runDevices(args []string) error {
+	parseFlags(newFlagSet("devices"), args)
+	cfg := loadConfig()
+	format, err := cast.ParseSampleFormat(cfg.Snapcast.SampleFormat)
+	if err != nil {
+		return fmt.Errorf("bad config: snapcast: %w", err)
+	}
+	output := cast.NewOutput()
+	output.SetSnapcast(xdg.ExpandHome(cfg.Snapcast.Source), format)
+	devices, err := output.Devices()
+	if err != nil {
+		return err
+	}
+	for _, d := range devices {
+		name := d.Name
+		if name == "" {
+			name = `""`
+		}
+		fmt.Printf("%-16s %s\n", name, d.Description)
+	}
+	return nil
+}
+
+// runIdentify prints the recordings matching the fingerprint of each file. Without an API
+// key only the fingerprints are printed, in the format of fpcalc.
+func runIdentify(args []string) error {
+	paths := parseFlags(newFlagSet("identify"), args)
+	if len(paths) == 0 {
+		return errors.New("usage: tempo identify <file>...")
+	}
+	apiKey := loadConfig().AcoustID.APIKey
+	if apiKey == "" {
+		fmt.Fprintln(os.Stderr, "Warning: set api_key in the [acoustid] section of the config to look up the fingerprints")
+	}
+
+	client := acoustid.New(httpclient.New(acoustid.Interval), apiKey)
+	for i, path := range paths {
+		fp, duration, err := acoustid.Fingerprint(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if len(fp) == 0 {
+			return fmt.Errorf("%s: too short to be identified", path)
+		}
+
+		if i > 0 {
+			fmt.Println()
+		}
+		if apiKey == "" {
+			fmt.Printf("FILE=%s\nDURATION=%d\nFINGERPRINT=%s\n", path, int(duration.Seconds()), acoustid.Encode(fp))
+			continue
+		}
+
+		results, err := client.Lookup(context.Background(), fp, duration)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Println(path)
+		if len(results) == 0 {
+			fmt.Println("  no recording matches")
+		}
+		for _, r := range results {
+			fmt.Printf("  %3.0f%%  %s - %s", r.Score*100, r.Tags.Artist, r.Tags.Title)
+			if r.Tags.Album != "" {
+				fmt.Printf(" (%s)", r.Tags.Album)
+			}
+			fmt.Printf("  https://musicbrainz.org/recording/%s\n", r.RecordingID)
+		}
+	}
+	return nil
+}
+
+// runAnalyze prints the tempo and key estimated for each file.
+func runAnalyze(args []string) error {
+	paths := parseFlags(newFlagSet("analyze"), args)
+	if len(paths) == 0 {
+		return errors.New("usage: tempo analyze <file>...")
+	}
+	for _, path := range paths {
+		res, err := analysis.Analyze(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Printf("%s\n  %.1f BPM  %s (%s)\n", path, res.BPM, res.Key, res.Key.Camelot())
+	}
+	return nil
+}
+
+// runLoudness prints the EBU R128 loudness of each file and the gain normalizing it.
+func runLoudness(args []string) error {
+	paths := parseFlags(newFlagSet("loudness"), args)
+	if len(paths) == 0 {
+		return errors.New("usage: tempo loudness <file>...")
+	}
+	for _, path := range paths {
+		res, err := loudness.Measure(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Printf("%s\n  %.1f LUFS  range %.1f LU  peak %.1f dBFS  gain %+.1f dB\n",
+			path, res.Integrated, res.Range, 20*math.Log10(res.Peak), res.Gain())
+	}
+	return nil
+}
+
+// fileInfo : What tempo info prints about an audio file
+type fileInfo struct {
+	Path   string `json:"path"`
+	Format string `json:"format,omitempty"`
+	// Duration in seconds, to the millisecond
+	Duration   float64 `json:"duration,omitempty"`
+	SampleRate int     `json:"sample_rate,omitempty"`
+	Channels   int     `json:"channels,omitempty"`
+	// BitDepth of the lossless formats, the lossy ones have none
+	BitDepth int       `json:"bit_depth,omitempty"`
+	Tags     tags.Tags `json:"tags"`
+	// Error if the file could not be decoded, the rest is then empty
+	Error string `json:"error,omitempty"`
+}
+
+// Names of the formats decoded by the engine, by extension
+var formatNames = map[string]string{
+	".mp3":  "MP3",
+	".wav":  "WAV",
+	".flac": "FLAC",
+	".ogg":  "Ogg Vorbis",
+	".oga":  "Ogg Vorbis",
+}
+
+// Actions of tempo config
+var configActions = []string{"init", "validate"}
+
+// configFlags defines the flags of tempo config.
+func configFlags() (*flag.FlagSet, *bool) {
+	fs := newFlagSet("config")
+	return fs, fs.Bool("force", false, "Overwrite the config file with init")
+}
+
+// runConfig writes the commented default config file with init, or reports every problem of
+// a config file with validate, the one of the profile if none is given.
+func runConfig(args []string) error {
+	flags, force := configFlags()
+	args = parseFlags(flags, args)
+	usage := errors.New("usage: tempo config <init [-force]|validate [file]>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch {
+	case args[0] == "init" && len(args) == 1:
+		path, err := config.Path()
+		if err != nil {
+			return err
+		}
+		if err := config.WriteTemplate(path, *force); errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%s already exists, -force overwrites it", path)
+		} else if err != nil {
+			return err
+		}
+		fmt.Println("Wrote", path)
+		return nil
+
+	case args[0] == "validate" && len(args) <= 2:
+		var path string
+		if len(args) == 2 {
+			path = args[1]
+		} else if p, err := config.Path(); err != nil {
+			return err
+		} else {
+			path = p
+		}
+		return validateConfig(path)
+	}
+	return usage
+}
+
+// validateConfig prints the problems of the config file at path, with the settings of the
+// TEMPO_* environment variables applied like when playing, failing if there is any.
+func validateConfig(path string) error {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("there is no config file at %s, tempo config init writes one", path)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, key := range cfg.Unknown() {
+		problems = append(problems, "unknown setting "+key)
+	}
+	for _, err := range cfg.ApplyEnv(os.Environ()) {
+		problems = append(problems, "environment variable "+err.Error())
+	}
+	for _, err := range ui.CheckConfig(cfg) {
+		problems = append(problems, err.Error())
+	}
+	if _, err := statusfile.Parse(cfg.NowPlaying.Template); err != nil {
+		problems = append(problems, err.Error())
+	}
+	for i, name := range []string{cfg.Remote.SIGUSR1, cfg.Remote.SIGUSR2} {
+		if _, err := remote.ParseCommand(name); name != "" && err != nil {
+			problems = append(problems, fmt.Sprintf("sigusr%d: %v", i+1, err))
+		}
+	}
+
+	if len(problems) == 0 {
+		fmt.Println(path, "is valid")
+		return nil
+	}
+	fmt.Println(path + ":")
+	for _, p := range problems {
+		fmt.Println(" ", p)
+	}
+	if len(problems) == 1 {
+		return errors.New("1 problem found")
+	}
+	return fmt.Errorf("%d problems found", len(problems))
+}
+
+// infoFlags defines the flags of tempo info.
+func infoFlags() (*flag.FlagSet, *bool) {
+	fs := newFlagSet("info")
+	return fs, fs.Bool("json", false, "Print a JSON array with an object for each file")
+}
+
+// runInfo prints the format, duration and tags of each file, as a JSON array with -json.
+// Files that cannot be decoded are reported and make it fail after the others are printed.
+func runInfo(args []string) error {
+	fs, asJSON := infoFlags()
+	paths := parseFlags(fs, args)
+	if len(paths) == 0 {
+		return errors.New("usage: tempo info [-json] <file>...")
+	}
+
+	infos := make([]fileInfo, 0, len(paths))
+	failed := 0
+	for _, path := range paths {
+		info := readInfo(path)
+		if info.Error != "" {
+			failed++
+		}
+		infos = append(infos, info)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(infos); err != nil {
+			return err
+		}
+	} else {
+		for i, info := range infos {
+			if i > 0 {
+				fmt.Println()
+			}
+			printInfo(info)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files could not be read", failed, len(paths))
+	}
+	return nil
+}
+
+// readInfo decodes the file at path to know its format and duration, and reads its tags.
+func readInfo(path string) fileInfo {
+	info := fileInfo{Path: path}
+	streamer, format, err := engine.Decode(path)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	defer streamer.Close()
+
+	ext := strings.ToLower(filepath.Ext(path))
+	info.Format = formatNames[ext]
+	info.Duration = math.Round(format.SampleRate.D(streamer.Len()).Seconds()*1000) / 1000
+	info.SampleRate = int(format.SampleRate)
+	info.Channels = format.NumChannels
+	if ext == ".wav" || ext == ".flac" {
+		info.BitDepth = format.Precision * 8
+	}
+	// The audio plays without tags, as it does in the player
+	info.Tags, _ = tags.ReadFile(path)
+	return info
+}
+
+// printInfo prints the info of a file in the format of the other commands.
+func printInfo(info fileInfo) {
+	fmt.Println(info.Path)
+	if info.Error != "" {
+		fmt.Println("  Error:", info.Error)
+		return
+	}
+
+	channels := "channels"
+	if info.Channels == 1 {
+		channels = "channel"
+	}
+	fmt.Printf("  %s  %d Hz  %d %s", info.Format, info.SampleRate, info.Channels, channels)
+	if info.BitDepth > 0 {
+		fmt.Printf("  %d bits", info.BitDepth)
+	}
+	fmt.Printf("  %s\n", player.FormatSecondsToString(time.Duration(info.Duration*float64(time.Second))))
+
+	t := info.Tags
+	fields := []struct {
+		name, value string
+	}{
+		{"Title", t.Title},
+		{"Artist", t.Artist},
+		{"Album artist", t.AlbumArtist},
+		{"Album", t.Album},
+		{"Genre", t.Genre},
+		{"Track", position(t.Track, t.TrackTotal)},
+		{"Disc", position(t.Disc, t.DiscTotal)},
+		{"Track gain", replayGain(t.ReplayGain.TrackGain, t.ReplayGain.TrackPeak)},
+		{"Album gain", replayGain(t.ReplayGain.AlbumGain, t.ReplayGain.AlbumPeak)},
+	}
+	if t.Compilation {
+		fields = append(fields, struct{ name, value string }{"Compilation", "yes"})
+	}
+	for _, f := range fields {
+		if f.value != "" {
+			fmt.Printf("  %-13s %s\n", f.name+":", f.value)
+		}
+	}
+}
+
+// position formats a track or disc number like 3/12, empty if it is unknown.
+func position(n, total int) string {
+	switch {
+	case n == 0:
+		return ""
+	case total == 0:
+		return fmt.Sprint(n)
+	}
+	return fmt.Sprintf("%d/%d", n, total)
+}
+
+// replayGain formats a ReplayGain gain and peak, empty if both are unknown.
+func replayGain(gain, peak float64) string {
+	switch {
+	case gain == 0 && peak == 0:
+		return ""
+	case peak == 0:
+		return fmt.Sprintf("%+.2f dB", gain)
+	}
+	return fmt.Sprintf("%+.2f dB, peak %.6f", gain, peak)
+}
+
+// Actions of tempo queue
+var queueActions = []string{"add"}
+
+// runQueue changes the queue of the running tempo: add enqueues the paths, reading the ones of
+// a - path from stdin.
+func runQueue(args []string) error {
+	usage := errors.New("usage: tempo queue add <path>...")
+	if len(args) == 0 || !slices.Contains(queueActions, args[0]) {
+		return usage
+	}
+	paths := parseFlags(newFlagSet("queue"), args[1:])
+	if len(paths) == 0 {
+		return usage
+	}
+	running, err := enqueueRunning(paths)
+	if err != nil {
+		return err
+	}
+	if !running {
+		return fmt.Errorf("%w, tempo play plays the paths", control.ErrNotRunning)
+	}
+	return nil
+}
+
+// runCtl sends a command to the running tempo through its control socket, printing the
+// status for status in the format of --format.
+func runCtl(args []string) error {
+	if len(args) == 0 {
+		var lines []string
+		for _, c := range control.Commands {
+			lines = append(lines, fmt.Sprintf("  %-28s %s", strings.TrimSpace(c.Name+" "+c.Args), c.Summary))
+		}
+		return errors.New("usage: tempo ctl <command>\n" + strings.Join(lines, "\n"))
+	}
+
+	req := control.Request{Command: args[0], Args: args[1:]}
+	format := ""
+	if req.Command == "status" {
+		var err error
+		if format, err = statusFormat(req.Args); err != nil {
+			return err
+		}
+		req.Args = nil
+	}
+	// The paths are resolved here, since tempo runs in another directory
+	if req.Command == "play" || req.Command == "add" {
+		for i, arg := range req.Args {
+			if engine.IsURL(arg) {
+				continue
+			}
+			path, err := filepath.Abs(arg)
+			if err != nil {
+				return err
+			}
+			req.Args[i] = path
+		}
+	}
+
+	path, err := control.SocketPath()
+	if err != nil {
+		return err
+	}
+	resp, err := control.Send(path, req)
+	if err != nil {
+		return err
+	}
+	if resp.Status == nil {
+		return nil
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(resp.Status)
+	case "waybar":
+		return json.NewEncoder(os.Stdout).Encode(waybarStatus(resp.Status))
+	case "plain":
+		fmt.Println(plainStatus(resp.Status))
+	default:
+		printStatus(resp.Status)
+	}
+	return nil
+}
+
+// Formats of tempo ctl status besides the default one
+var statusFormats = []string{"json", "waybar", "plain"}
+
+// statusFormat returns the format asked by the arguments of status, like --format waybar,
+// with --json being --format json.
+func statusFormat(args []string) (string, error) {
+	usage := errors.New("usage: tempo ctl status [--format " + strings.Join(statusFormats, "|") + "]")
+	format := ""
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		switch {
+		case name == "json" && !hasValue:
+			format = "json"
+		case name == "format" && hasValue:
+			format = value
+		case name == "format" && i+1 < len(args):
+			i++
+			format = args[i]
+		default:
+			return "", usage
+		}
+	}
+	if format != "" && !slices.Contains(statusFormats, format) {
+		return "", usage
+	}
+	return format, nil
+}
+
+// statusPercent returns how much of the file was played, from 0 to 100.
+func statusPercent(st *control.Status) int {
+	if st.Duration <= 0 {
+		return 0
+	}
+	return min(int(st.Position*100/st.Duration), 100)
+}
+
+// statusTimes formats the position and duration of the status like "1:23/3:45", or
+// "1:23/live" for a live stream.
+func statusTimes(st *control.Status) string {
+	position := time.Duration(st.Position * float64(time.Second))
+	duration := time.Duration(st.Duration * float64(time.Second))
+	if st.Live {
+		return statusfile.Clock(position) + "/live"
+	}
+	return statusfile.Clock(position) + "/" + statusfile.Clock(duration)
+}
+
+// statusTitle returns the artist and title of the status, like "Artist – Title".
+func statusTitle(st *control.Status) string {
+	if st.Artist == "" {
+		return st.Title
+	}
+	return st.Artist + " – " + st.Title
+}
+
+// plainStatus returns the status in one line for the status bars and tmux, like
+// "▶ Artist – Title 1:23/3:45 37%", without the percentage for a live stream, empty if
+// nothing is being played.
+func plainStatus(st *control.Status) string {
+	if st.Path == "" {
+		return ""
+	}
+	icon := "▶"
+	switch st.State {
+	case "paused":
+		icon = "⏸"
+	case "stopped":
+		icon = "⏹"
+	}
+	if st.Live {
+		return fmt.Sprintf("%s %s %s", icon, statusTitle(st), statusTimes(st))
+	}
+	return fmt.Sprintf("%s %s %s %d%%", icon, statusTitle(st), statusTimes(st), statusPercent(st))
+}
+
+// waybarModule : The output of a custom waybar module with return-type json
+type waybarModule struct {
+	Text       string `json:"text"`
+	Alt        string `json:"alt"`
+	Tooltip    string `json:"tooltip"`
+	Class      string `json:"class"`
+	Percentage int    `json:"percentage"`
+}
+
+// Escapes of the tags, read by waybar as Pango markup
+var pangoEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// waybarStatus returns the status as a custom waybar module, whose alt and class are the
+// state for its format-icons and style. The text is empty if nothing is being played, which
+// hides the module.
+func waybarStatus(st *control.Status) waybarModule {
+	m := waybarModule{Alt: st.State, Class: st.State}
+	if st.Path == "" {
+		return m
+	}
+	m.Text = pangoEscaper.Replace(statusTitle(st))
+	m.Tooltip = st.Title
+	if sub := strings.TrimPrefix(st.Artist+" – "+st.Album, " – "); sub != "" {
+		m.Tooltip += "\n" + strings.TrimSuffix(sub, " – ")
+	}
+	m.Tooltip = pangoEscaper.Replace(m.Tooltip + "\n" + statusTimes(st))
+	m.Percentage = statusPercent(st)
+	return m
+}
+
+// printStatus prints what the running tempo is playing.
+func printStatus(st *control.Status) {
+	if st.Path == "" {
+		fmt.Println("Nothing is being played")
+		return
+	}
+
+	title := st.Title
+	if st.Artist != "" {
+		title += " - " + st.Artist
+	}
+	fmt.Printf("%s%s: %s\n", strings.ToUpper(st.State[:1]), st.State[1:], title)
+	fmt.Println(" ", st.Path)
+
+	position := time.Duration(st.Position * float64(time.Second))
+	duration := player.FormatSecondsToString(time.Duration(st.Duration * float64(time.Second)))
+	if st.Live {
+		duration = "live"
+	}
+	fmt.Printf("  %s / %s  volume %d%%", player.FormatSecondsToString(position), duration, st.Volume)
+	if st.Muted {
+		fmt.Print(" (muted)")
+	}
+	if st.Speed != 1 {
+		fmt.Printf("  speed %gx", st.Speed)
+	}
+	if st.Repeat {
+		fmt.Print("  repeat")
+	}
+	fmt.Println()
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/nicolito128/tempo/internal/audio"
+	"github.com/nicolito128/tempo/internal/config"
+	"github.com/nicolito128/tempo/internal/control"
+)
+
+// Shells tempo completion writes a script for
+var shells = []string{"bash", "zsh", "fish"}
+
+// argKind : What the positional arguments of a command are
+type argKind int
+
+const (
+	noArgs argKind = iota
+	// Audio files
+	audioArgs
+	// Audio files, directories and playlists
+	playableArgs
+	dirArgs
+	// One of the words of the command, like the shells of completion
+	wordArgs
+)
+
+// valueKind : What the value of a flag is
+type valueKind int
+
+const (
+	anyValue valueKind = iota
+	dirValue
+	// Name of a smart playlist of the config
+	smartValue
+	// One of the words of the flag
+	wordValue
+)
+
+// completionFlag : A flag of a command, as completed
+type completionFlag struct {
+	name, usage string
+	// hasValue if the flag takes a value, and kind what it is
+	hasValue bool
+	kind     valueKind
+	words    []string
+}
+
+// completionCommand : A command of tempo, as completed
+type completionCommand struct {
+	name, summary string
+	args          argKind
+	words         []string
+	flags         []completionFlag
+}
+
+// Values of the flags with known ones, by flag name
+var flagValues = map[string]completionFlag{
+	"dir":     {kind: dirValue},
+	"library": {kind: dirValue},
+	"smart":   {kind: smartValue},
+	"dedup":   {kind: wordValue, words: []string{"skip", "flag", "off"}},
+}
+
+// commandArgs returns what the positional arguments of the command with the given name are.
+func commandArgs(name string) argKind {
+	switch name {
+	case "play", "serve":
+		return playableArgs
+	case "info", "identify", "analyze", "loudness":
+		return audioArgs
+	case "scan":
+		return dirArgs
+	case "completion", "config", "ctl", "queue":
+		return wordArgs
+	}
+	return noArgs
+}
+
+// Words of the commands taking one, by command name
+var argWords = map[string][]string{
+	"completion": shells,
+	"config":     configActions,
+	"ctl":        ctlCommands(),
+	"queue":      queueActions,
+}
+
+// ctlCommands returns the names of the commands of tempo ctl.
+func ctlCommands() []string {
+	names := make([]string, len(control.Commands))
+	for i, c := range control.Commands {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// commandFlags returns the flags of the command with the given name, without parsing them.
+func commandFlags(name string) *flag.FlagSet {
+	switch name {
+	case "play", "serve":
+		fs, _ := playFlags(name, config.Default())
+		return fs
+	case "info":
+		fs, _ := infoFlags()
+		return fs
+	case "completion":
+		fs, _ := completionFlags()
+		return fs
+	case "config":
+		fs, _ := configFlags()
+		return fs
+	}
+	return newFlagSet(name)
+}
+
+// completionCommands returns the commands with their arguments and flags.
+func completionCommands() []completionCommand {
+	var cmds []completionCommand
+	for _, c := range commands {
+		cc := completionCommand{name: c.name, summary: c.summary, args: commandArgs(c.name), words: argWords[c.name]}
+		commandFlags(c.name).VisitAll(func(f *flag.Flag) {
+			cf := flagValues[f.Name]
+			cf.name, cf.usage = f.Name, f.Usage
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+				cf.hasValue = true
+			}
+			cc.flags = append(cc.flags, cf)
+		})
+		cmds = append(cmds, cc)
+	}
+	return cmds
+}
+
+// completionFlags defines the flags of tempo completion.
+func completionFlags() (*flag.FlagSet, *bool) {
+	fs := newFlagSet("completion")
+	return fs, fs.Bool("smart", false, "Print the names of the smart playlists of the config, for the scripts")
+}
+
+// runCompletion writes the completion script of a shell, or the names the scripts complete.
+func runCompletion(args []string) error {
+	fs, smart := completionFlags()
+	args = parseFlags(fs, args)
+	if *smart {
+		// Without the warnings of loadConfig, which would be completed as names
+		cfg := config.Default()
+		if path, err := config.Path(); err == nil {
+			if loaded, err := config.Load(path); err == nil {
+				cfg = loaded
+			}
+		}
+		for _, sp := range cfg.SmartPlaylists {
+			fmt.Println(sp.Name)
+		}
+		return nil
+	}
+
+	if len(args) != 1 || !slices.Contains(shells, args[0]) {
+		return errors.New("usage: tempo completion <" + strings.Join(shells, "|") + ">")
+	}
+	cmds := completionCommands()
+	switch args[0] {
+	case "bash":
+		writeBash(os.Stdout, cmds)
+	case "zsh":
+		writeZsh(os.Stdout, cmds)
+	case "fish":
+		writeFish(os.Stdout, cmds)
+	}
+	return nil
+}
+
+// audioExtensions returns the extensions of the files the commands with args take, without
+// the dot.
+func audioExtensions(args argKind) []string {
+	exts := slices.Clone(audio.SupportedExtensions)
+	if args == playableArgs {
+		exts = append(exts, ".m3u", ".m3u8")
+	}
+	for i, ext := range exts {
+		exts[i] = strings.TrimPrefix(ext, ".")
+	}
+	return exts
+}
+
+func writeBash(w io.Writer, cmds []completionCommand) {
+	names := make([]string, len(cmds))
+	for i, c := range cmds {
+		names[i] = c.name
+	}
+
+	fmt.Fprint(w, `# bash completion for tempo, load it with: source <(tempo completion bash)
+
+# _tempo_files completes the directories and the files with one of the extensions in $1
+_tempo_files() {
+    local extglob
+    extglob=$(shopt -p extglob)
+    shopt -s extglob
+    compopt -o filenames
+    COMPREPLY+=($(compgen -d -- "$cur") $(compgen -f -X "!*.@($1)" -- "$cur"))
+    eval "$extglob"
+}
+
+_tempo() {
+    local cur prev cmd
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+
+    # Without a command the arguments are the ones of play
+    cmd=play
+    if (( COMP_CWORD > 1 )); then
+        case "${COMP_WORDS[1]}" in
+`)
+	fmt.Fprintf(w, "            %s) cmd=\"${COMP_WORDS[1]}\" ;;\n", strings.Join(names, "|"))
+	fmt.Fprint(w, `        esac
+    fi
+
+    case "$prev" in
+`)
+	seen := make(map[string]bool)
+	for _, c := range cmds {
+		for _, f := range c.flags {
+			if !f.hasValue || seen[f.name] {
+				continue
+			}
+			seen[f.name] = true
+			fmt.Fprintf(w, "        -%[1]s|--%[1]s)\n", f.name)
+			switch f.kind {
+			case dirValue:
+				fmt.Fprint(w, "            compopt -o filenames\n            COMPREPLY=($(compgen -d -- \"$cur\"))\n")
+			case smartValue:
+				fmt.Fprint(w, "            local IFS=$'\\n'\n            compopt -o filenames\n            COMPREPLY=($(compgen -W \"$(tempo completion -smart 2>/dev/null)\" -- \"$cur\"))\n")
+			case wordValue:
+				fmt.Fprintf(w, "            COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(f.words, " "))
+			default:
+				fmt.Fprint(w, "            COMPREPLY=()\n")
+			}
+			fmt.Fprint(w, "            return ;;\n")
+		}
+	}
+	fmt.Fprint(w, `    esac
+
+    if [[ "$cur" == -* ]]; then
+        case "$cmd" in
+`)
+	for _, c := range cmds {
+		if len(c.flags) == 0 {
+			continue
+		}
+		var flags []string
+		for _, f := range c.flags {
+			flags = append(flags, "-"+f.name)
+		}
+		fmt.Fprintf(w, "            %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", c.name, strings.Join(flags, " "))
+	}
+	fmt.Fprint(w, `        esac
+        return
+    fi
+
+    COMPREPLY=()
+    if (( COMP_CWORD == 1 )); then
+`)
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(names, " "))
+	fmt.Fprint(w, `    fi
+    case "$cmd" in
+`)
+	for _, c := range cmds {
+		switch c.args {
+		case audioArgs, playableArgs:
+			fmt.Fprintf(w, "        %s) _tempo_files '%s' ;;\n", c.name, strings.Join(audioExtensions(c.args), "|"))
+		case dirArgs:
+			fmt.Fprintf(w, "        %s)\n            compopt -o filenames\n            COMPREPLY+=($(compgen -d -- \"$cur\")) ;;\n", c.name)
+		case wordArgs:
+			fmt.Fprintf(w, "        %s) COMPREPLY+=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", c.name, strings.Join(c.words, " "))
+		}
+	}
+	fmt.Fprint(w, `    esac
+}
+complete -F _tempo tempo
+`)
+}
+
+// zshQuote escapes s for a single quoted zsh word inside the brackets of _arguments.
+func zshQuote(s string) string {
+	s = strings.NewReplacer("[", `\[`, "]", `\]`).Replace(s)
+	return strings.ReplaceAll(s, "'", `'\''`)
+}
+
+func writeZsh(w io.Writer, cmds []completionCommand) {
+	fmt.Fprint(w, `#compdef tempo
+# zsh completion for tempo, load it with: source <(tempo completion zsh)
+
+_tempo_smart() {
+    local -a names
+    names=("${(@f)$(tempo completion -smart 2>/dev/null)}")
+    _describe -t playlists 'smart playlist' names
+}
+
+_tempo() {
+    local -a commands
+    commands=(
+`)
+	for _, c := range cmds {
+		fmt.Fprintf(w, "        '%s:%s'\n", c.name, zshQuote(c.summary))
+	}
+	fmt.Fprint(w, `    )
+
+    # Without a command the arguments are the ones of play
+    local cmd=play
+    if (( CURRENT == 2 )); then
+        _describe -t commands 'tempo command' commands
+    elif (( ${commands[(I)${words[2]}:*]} )); then
+        cmd=${words[2]}
+        shift words
+        (( CURRENT-- ))
+    fi
+
+    case $cmd in
+`)
+	for _, c := range cmds {
+		var specs []string
+		for _, f := range c.flags {
+			spec := fmt.Sprintf("-%s[%s]", f.name, zshQuote(f.usage))
+			if f.hasValue {
+				switch f.kind {
+				case dirValue:
+					spec += ":directory:_files -/"
+				case smartValue:
+					spec += ":smart playlist:_tempo_smart"
+				case wordValue:
+					spec += ":value:(" + strings.Join(f.words, " ") + ")"
+				default:
+					spec += ":value:"
+				}
+			}
+			specs = append(specs, spec)
+		}
+		switch c.args {
+		case audioArgs, playableArgs:
+			specs = append(specs, fmt.Sprintf(`*:file:_files -g "*.(%s)(-.)"`, strings.Join(audioExtensions(c.args), "|")))
+		case dirArgs:
+			specs = append(specs, "*:directory:_files -/")
+		case wordArgs:
+			specs = append(specs, "1:"+c.name+":("+strings.Join(c.words, " ")+")")
+		}
+		if len(specs) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "        %s)\n            _arguments", c.name)
+		for _, spec := range specs {
+			fmt.Fprintf(w, " \\\n                '%s'", spec)
+		}
+		fmt.Fprint(w, " ;;\n")
+	}
+	fmt.Fprint(w, `    esac
+}
+
+if [[ $zsh_eval_context[-1] == loadautofunc ]]; then
+    _tempo "$@"
+else
+    compdef _tempo tempo
+fi
+`)
+}
+
+// fishQuote escapes s for a single quoted fish string.
+func fishQuote(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s)
+}
+
+func writeFish(w io.Writer, cmds []completionCommand) {
+	names := make([]string, len(cmds))
+	for i, c := range cmds {
+		names[i] = c.name
+	}
+
+	fmt.Fprintf(w, `# fish completion for tempo, load it with: tempo completion fish | source
+
+# Without a command the arguments are the ones of play
+function __tempo_command
+    set -l tokens (commandline -opc)
+    if test (count $tokens) -gt 1; and contains -- $tokens[2] %s
+        echo $tokens[2]
+    else
+        echo play
+    end
+end
+
+function __tempo_using
+    test (__tempo_command) = $argv[1]
+end
+
+function __tempo_files
+    __fish_complete_directories (commandline -ct)
+    for ext in $argv
+        __fish_complete_suffix .$ext
+    end
+end
+
+complete -c tempo -f
+`, strings.Join(names, " "))
+
+	for _, c := range cmds {
+		fmt.Fprintf(w, "complete -c tempo -n '__fish_use_subcommand' -a %s -d '%s'\n", c.name, fishQuote(c.summary))
+	}
+	for _, c := range cmds {
+		cond := fmt.Sprintf("-n '__tempo_using %s'", c.name)
+		for _, f := range c.flags {
+			fmt.Fprintf(w, "complete -c tempo %s -o %s -d '%s'", cond, f.name, fishQuote(f.usage))
+			if f.hasValue {
+				switch f.kind {
+				case dirValue:
+					fmt.Fprint(w, " -x -a '(__fish_complete_directories)'")
+				case smartValue:
+					fmt.Fprint(w, " -x -a '(tempo completion -smart 2>/dev/null)'")
+				case wordValue:
+					fmt.Fprintf(w, " -x -a '%s'", strings.Join(f.words, " "))
+				default:
+					fmt.Fprint(w, " -x")
+				}
+			}
+			fmt.Fprintln(w)
+		}
+		switch c.args {
+		case audioArgs, playableArgs:
+			fmt.Fprintf(w, "complete -c tempo %s -a '(__tempo_files %s)'\n", cond, strings.Join(audioExtensions(c.args), " "))
+		case dirArgs:
+			fmt.Fprintf(w, "complete -c tempo %s -a '(__fish_complete_directories)'\n", cond)
+		case wordArgs:
+			fmt.Fprintf(w, "complete -c tempo %s -a '%s'\n", cond, strings.Join(c.words, " "))
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/components/player"
+	"github.com/nicolito128/tempo/internal/components/queue"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// How often the position is printed while playing on a terminal
+const progressInterval time.Duration = time.Second
+
+// playHeadless plays the queue from its current item to its end without the TUI, printing
+// each file played and, on a terminal, its position. The first file starts at startAt, and
+// with record the playback is recorded from it until the end. SIGINT and SIGTERM stop the
+// playback, fading it out, and return without an error.
+func playHeadless(p *player.Player, q *queue.Queue, startAt time.Duration, record bool) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	defer p.Close()
+	// The recording is finished before the audio is closed, to tell where it is
+	defer func() {
+		if _, ok := p.Recording(); !ok {
+			return
+		}
+		rec, err := p.StopRecording()
+		if err != nil {
+			fmt.Printf("Error: cannot finish the recording %s: %v\n", rec.Path, err)
+			return
+		}
+		fmt.Printf("Recorded %s to %s\n", player.FormatSecondsToString(rec.Length), rec.Path)
+	}()
+
+	info, err := os.Stdout.Stat()
+	onTerminal := err == nil && info.Mode()&os.ModeCharDevice != 0
+
+	eng := p.Engine()
+	// A repeating queue stops once every file in a row failed, instead of trying them forever
+	failed, failedInARow := 0, 0
+	for af, ok := q.Current(); ok && failedInARow < q.Len(); af, ok = q.Next() {
+		p.SetAudioFile(af)
+		if err := p.LoadAudio(); err != nil {
+			failed++
+			failedInARow++
+			q.SetFailed(af.Path(), err)
+			fmt.Printf("Error: %s: %v\n", af.Path(), err)
+			continue
+		}
+
+		name := p.Audio().Title()
+		if artist := p.Audio().Artist(); artist != "" {
+			name = artist + " - " + name
+		}
+		length := player.FormatSecondsToString(p.Duration())
+		if eng.Live() {
+			length = "live"
+		}
+		if startAt > 0 && !eng.Live() {
+			if err := eng.Seek(startAt); err != nil {
+				fmt.Printf("Error: %s: %v\n", af.Path(), err)
+			}
+			fmt.Printf("▶ %s (%s, from %s)\n", name, length, player.FormatSecondsToString(eng.Position()))
+			startAt = 0
+		} else {
+			fmt.Printf("▶ %s (%s)\n", name, length)
+		}
+		if record {
+			record = false
+			if path, err := p.StartRecording(); err != nil {
+				fmt.Printf("Error: cannot record: %v\n", err)
+			} else {
+				fmt.Printf("● Recording to %s\n", path)
+			}
+		}
+		if err := eng.Play(); err != nil {
+			failed++
+			failedInARow++
+			fmt.Printf("Error: %s: %v\n", af.Path(), err)
+			continue
+		}
+
+		if err := waitCompleted(ctx, eng, af.Path(), onTerminal); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			failed++
+			failedInARow++
+			q.SetFailed(af.Path(), err)
+			fmt.Printf("Error: %s: %v\n", af.Path(), err)
+			continue
+		}
+		failedInARow = 0
+	}
+
+	switch {
+	case failed == 1:
+		return fmt.Errorf("a file could not be played")
+	case failed > 1:
+		return fmt.Errorf("%d files could not be played", failed)
+	}
+	return nil
+}
+
+// waitCompleted waits until the audio file at path is played to its end, printing its position
+// over the same line on a terminal, and the titles told by the radio of a live stream. It
+// returns the error of the decoder if the audio ended early, or the one of ctx if it was done
+// before.
+func waitCompleted(ctx context.Context, eng *engine.Engine, path string, onTerminal bool) error {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	// The position is written over, and cleared when done
+	defer func() {
+		if onTerminal {
+			fmt.Print("\r\033[K")
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case ev := <-eng.Events():
+			if ev.Path != path {
+				continue
+			}
+			switch ev.Kind {
+			case engine.Completed:
+				return ev.Err
+			case engine.Metadata:
+				if onTerminal {
+					fmt.Print("\r\033[K")
+				}
+				fmt.Printf("♪ %s\n", ev.Title)
+			}
+
+		case <-ticker.C:
+			if onTerminal {
+				length := player.FormatSecondsToString(eng.Duration())
+				if eng.Live() {
+					length = "live"
+				}
+				fmt.Printf("\r  %s / %s", player.FormatSecondsToString(eng.Position()), length)
+			}
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package acoustid identifies audio files by their sound, computing Chromaprint
+// fingerprints and looking them up in the AcoustID database.
+package acoustid
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/httpclient"
+	"github.com/nicolito128/tempo/internal/tags"
+)
+
+const (
+	// BaseURL of the AcoustID web service
+	BaseURL string = "https://api.acoustid.org/v2"
+	// Interval between requests allowed by AcoustID, three per second
+	Interval time.Duration = time.Second / 3
+	// Minimum score of a result to name a file after it
+	MinScore float64 = 0.5
+)
+
+// ErrNoKey is returned when looking up a fingerprint without an API key.
+var ErrNoKey = errors.New("an AcoustID API key is needed, get one at https://acoustid.org/new-application")
+
+// Result : A recording whose fingerprint matches the one looked up
+type Result struct {
+	// Score of the match from 0 to 1
+	Score float64
+
+	// MusicBrainz ID of the recording
+	RecordingID string
+
+	// Tags of the recording, with the album of one of its release groups
+	Tags tags.Tags
+}
+
+// Client : A client of the AcoustID web service
+type Client struct {
+	http    *httpclient.Client
+	baseURL string
+
+	// Key of the application registered at AcoustID
+	apiKey string
+}
+
+// New creates a client making its requests with the given HTTP client, which should
+// wait Interval between requests.
+func New(http *httpclient.Client, apiKey string) *Client {
+	return &Client{http: http, baseURL: BaseURL, apiKey: apiKey}
+}
+
+// Lookup returns the recordings matching the fingerprint of an audio file of the given
+// duration, best first.
+func (c *Client) Lookup(ctx context.Context, fp []uint32, duration time.Duration) ([]Result, error) {
+	if c.apiKey == "" {
+		return nil, ErrNoKey
+	}
+
+	form := url.Values{
+		"client":      {c.apiKey},
+		"meta":        {"recordings releasegroups"},
+		"duration":    {strconv.Itoa(int(duration.Round(time.Second).Seconds()))},
+		"fingerprint": {Encode(fp)},
+		"format":      {"json"},
+	}
+	// Fingerprints are too long for the query string
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/lookup", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	var resp response
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != "ok" {
+		return nil, errors.New("acoustid: " + resp.Error.Message)
+	}
+
+	var results []Result
+	for _, r := range resp.Results {
+		for _, rec := range r.Recordings {
+			// Recordings without metadata are only known by their ID
+			if rec.Title == "" {
+				continue
+			}
+			results = append(results, Result{
+				Score:       r.Score,
+				RecordingID: rec.ID,
+				Tags: tags.Tags{
+					Title:  rec.Title,
+					Artist: credit(rec.Artists),
+					Album:  rec.album(),
+				},
+			})
+		}
+	}
+	slices.SortStableFunc(results, func(a, b Result) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	return results, nil
+}
+
+// Identify fingerprints the audio file at path and returns the tags of the best matching
+// recording. The tags are empty if no recording matches well enough.
+func (c *Client) Identify(ctx context.Context, path string) (tags.Tags, error) {
+	if c.apiKey == "" {
+		return tags.Tags{}, ErrNoKey
+	}
+
+	fp, duration, err := Fingerprint(path)
+	if err != nil {
+		return tags.Tags{}, err
+	}
+	if len(fp) == 0 {
+		return tags.Tags{}, nil
+	}
+
+	results, err := c.Lookup(ctx, fp, duration)
+	if err != nil {
+		return tags.Tags{}, err
+	}
+	if len(results) == 0 || results[0].Score < MinScore {
+		return tags.Tags{}, nil
+	}
+	return results[0].Tags, nil
+}
+
+// response : The body of the responses of the lookup
+type response struct {
+	Status string `json:"status"`
+	Error  struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Results []struct {
+		Score      float64     `json:"score"`
+		Recordings []recording `json:"recordings"`
+	} `json:"results"`
+}
+
+type recording struct {
+	ID            string         `json:"id"`
+	Title         string         `json:"title"`
+	Artists       []artist       `json:"artists"`
+	ReleaseGroups []releaseGroup `json:"releasegroups"`
+}
+
+type artist struct {
+	Name       string `json:"name"`
+	JoinPhrase string `json:"joinphrase"`
+}
+
+type releaseGroup struct {
+	Title          string   `json:"title"`
+	Type           string   `json:"type"`
+	SecondaryTypes []string `json:"secondarytypes"`
+}
+
+// album returns the title of the first studio album with the recording, or of the first
+// release group if it is in none.
+func (r recording) album() string {
+	for _, rg := range r.ReleaseGroups {
+		if rg.Type == "Album" && len(rg.SecondaryTypes) == 0 {
+			return rg.Title
+		}
+	}
+	if len(r.ReleaseGroups) > 0 {
+		return r.ReleaseGroups[0].Title
+	}
+	return ""
+}
+
+// credit joins the names of the credited artists, like "Artist feat. Other".
+func credit(artists []artist) string {
+	var b strings.Builder
+	for _, a := range artists {
+		b.WriteString(a.Name + a.JoinPhrase)
+	}
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package acoustid
+
+import "math"
+
+// Width in frames of the widest classifier filter
+const filterWidth int = 16
+
+// classifier : A Haar-like filter over the chroma features and the thresholds quantizing its result
+type classifier struct {
+	// Shape of the filter, from 0 to 5
+	kind int
+	// First band and number of bands covered
+	band, height int
+	// Number of frames covered
+	width int
+
+	thresholds [3]float64
+}
+
+// Classifiers of the default Chromaprint algorithm
+var classifiers = []classifier{
+	{0, 4, 3, 15, [3]float64{1.98215, 2.35817, 2.63523}},
+	{4, 4, 6, 15, [3]float64{-1.03809, -0.651211, -0.282167}},
+	{1, 0, 4, 16, [3]float64{-0.298702, 0.119262, 0.558497}},
+	{3, 8, 2, 12, [3]float64{-0.105439, 0.0153946, 0.135898}},
+	{3, 4, 4, 8, [3]float64{-0.142891, 0.0258736, 0.200632}},
+	{4, 0, 3, 5, [3]float64{-0.826319, -0.590612, -0.368214}},
+	{1, 2, 2, 9, [3]float64{-0.557409, -0.233035, 0.0534525}},
+	{2, 7, 3, 4, [3]float64{-0.0646826, 0.00620476, 0.0784847}},
+	{2, 6, 2, 16, [3]float64{-0.192387, -0.029699, 0.215855}},
+	{2, 1, 3, 2, [3]float64{-0.0397818, -0.00568076, 0.0292026}},
+	{5, 10, 1, 15, [3]float64{-0.53823, -0.369934, -0.190235}},
+	{3, 6, 2, 10, [3]float64{-0.124877, 0.0296483, 0.139239}},
+	{2, 1, 1, 14, [3]float64{-0.101475, 0.0225617, 0.231971}},
+	{3, 5, 6, 4, [3]float64{-0.0799915, -0.00729616, 0.063262}},
+	{1, 9, 2, 12, [3]float64{-0.272556, 0.019424, 0.302559}},
+	{3, 4, 2, 14, [3]float64{-0.164292, -0.0321188, 0.0846339}},
+}
+
+// apply compares the sums of two regions of the features starting at the frame x, given
+// the function summing the rectangle of frames [r1, r2) and bands [b1, b2).
+func (c classifier) apply(area func(r1, b1, r2, b2 int) float64, x int) float64 {
+	y, w, h := c.band, c.width, c.height
+
+	var a, b float64
+	switch c.kind {
+	case 0:
+		a = area(x, y, x+w, y+h)
+	case 1:
+		// Upper bands against lower bands
+		a = area(x, y+h/2, x+w, y+h)
+		b = area(x, y, x+w, y+h/2)
+	case 2:
+		// Later frames against earlier frames
+		a = area(x+w/2, y, x+w, y+h)
+		b = area(x, y, x+w/2, y+h)
+	case 3:
+		// Diagonal quadrants
+		a = area(x, y+h/2, x+w/2, y+h) + area(x+w/2, y, x+w, y+h/2)
+		b = area(x, y, x+w/2, y+h/2) + area(x+w/2, y+h/2, x+w, y+h)
+	case 4:
+		// Middle third of the bands against the outer thirds
+		a = area(x, y+h/3, x+w, y+h/3*2)
+		b = area(x, y, x+w, y+h/3) + area(x, y+h/3*2, x+w, y+h)
+	case 5:
+		// Middle third of the frames against the outer thirds
+		a = area(x+w/3, y, x+w/3*2, y+h)
+		b = area(x, y, x+w/3, y+h) + area(x+w/3*2, y, x+w, y+h)
+	}
+	return math.Log1p(a) - math.Log1p(b)
+}
+
+// quantize maps the result of the filter to a value from 0 to 3.
+func (c classifier) quantize(v float64) int {
+	switch {
+	case v < c.thresholds[0]:
+		return 0
+	case v < c.thresholds[1]:
+		return 1
+	case v < c.thresholds[2]:
+		return 2
+	}
+	return 3
+}
@@ -0,0 +1,208 @@
+package acoustid
+
+import (
+	"encoding/base64"
+	"math"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/dsp"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// MaxLength of the audio fingerprinted from the start of a file, as much as AcoustID uses
+const MaxLength time.Duration = 120 * time.Second
+
+const (
+	// Sample rate the audio is converted to before analyzing it
+	sampleRate int = 11025
+	// Samples of each analyzed frame, and the step between two frames
+	frameSize int = 4096
+	frameStep int = frameSize / 3
+	// Range of the frequencies of the chroma features in Hz
+	minFreq float64 = 28
+	maxFreq float64 = 3520
+	// Number of chroma bands, one per note of the octave
+	numBands int = 12
+	// Chroma vectors with a smaller norm are considered silence
+	silenceNorm float64 = 0.01
+	// Version of the Chromaprint algorithm used, its default one
+	algorithm byte = 1
+)
+
+// Weights of the neighbour frames smoothing the chroma features
+var chromaFilter = []float64{0.25, 0.75, 1.0, 0.75, 0.25}
+
+// Fingerprint decodes the first MaxLength of the audio file at path and returns its
+// Chromaprint fingerprint, along with the duration of the whole file.
+func Fingerprint(path string) ([]uint32, time.Duration, error) {
+	streamer, format, err := engine.Decode(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer streamer.Close()
+
+	duration := format.SampleRate.D(streamer.Len())
+	samples, err := dsp.ReadMono(streamer, format.SampleRate.N(MaxLength))
+	if err != nil {
+		return nil, 0, err
+	}
+	samples = dsp.Resample(samples, int(format.SampleRate), sampleRate)
+	return compute(samples), duration, nil
+}
+
+// Encode compresses the fingerprint into the text form accepted by AcoustID.
+func Encode(fp []uint32) string {
+	// Distances between the bits changed from the previous item, ending each item with zero
+	var deltas []uint32
+	var prev uint32
+	for _, x := range fp {
+		diff := x ^ prev
+		prev = x
+		last := uint32(0)
+		for bit := uint32(1); diff != 0; bit++ {
+			if diff&1 != 0 {
+				deltas = append(deltas, bit-last)
+				last = bit
+			}
+			diff >>= 1
+		}
+		deltas = append(deltas, 0)
+	}
+
+	// The deltas take three bits, the larger ones are completed with five bits more
+	var normal, exceptional bitWriter
+	for _, d := range deltas {
+		normal.write(min(d, 7), 3)
+		if d >= 7 {
+			exceptional.write(d-7, 5)
+		}
+	}
+
+	n := len(fp)
+	out := []byte{algorithm, byte(n >> 16), byte(n >> 8), byte(n)}
+	out = append(out, normal.bytes()...)
+	out = append(out, exceptional.bytes()...)
+	return base64.RawURLEncoding.EncodeToString(out)
+}
+
+// bitWriter packs values into bytes, least significant bits first.
+type bitWriter struct {
+	buf   []byte
+	nbits int
+}
+
+func (w *bitWriter) write(v uint32, n int) {
+	for i := range n {
+		if w.nbits%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		if v>>i&1 != 0 {
+			w.buf[len(w.buf)-1] |= 1 << (w.nbits % 8)
+		}
+		w.nbits++
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	return w.buf
+}
+
+// compute returns the fingerprint of mono audio sampled at sampleRate, one item for each
+// frameStep after the first frames needed by the classifiers.
+func compute(samples []float64) []uint32 {
+	window := make([]float64, frameSize)
+	for i := range window {
+		window[i] = 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(frameSize-1))
+	}
+
+	// Note of the octave of each frequency bin
+	freqIndex := func(freq float64) int {
+		return int(math.Round(float64(frameSize) * freq / float64(sampleRate)))
+	}
+	first, last := max(1, freqIndex(minFreq)), min(frameSize/2, freqIndex(maxFreq))
+	notes := make([]int, last)
+	for i := first; i < last; i++ {
+		freq := float64(i) * float64(sampleRate) / float64(frameSize)
+		octave := math.Log2(freq / (440.0 / 16))
+		notes[i] = int(float64(numBands) * (octave - math.Floor(octave)))
+	}
+
+	var image [][numBands]float64
+	var recent [][numBands]float64
+	frame := make([]complex128, frameSize)
+	for start := 0; start+frameSize <= len(samples); start += frameStep {
+		for i := range frame {
+			frame[i] = complex(samples[start+i]*window[i], 0)
+		}
+		dsp.FFT(frame)
+
+		var chroma [numBands]float64
+		for i := first; i < last; i++ {
+			re, im := real(frame[i]), imag(frame[i])
+			chroma[notes[i]] += re*re + im*im
+		}
+
+		recent = append(recent, chroma)
+		if len(recent) > len(chromaFilter) {
+			recent = recent[1:]
+		}
+		if len(recent) < len(chromaFilter) {
+			continue
+		}
+
+		var smooth [numBands]float64
+		for j, c := range chromaFilter {
+			for b := range numBands {
+				smooth[b] += recent[j][b] * c
+			}
+		}
+		normalize(smooth[:])
+		image = append(image, smooth)
+	}
+	return classify(image)
+}
+
+// normalize scales the vector to a unit euclidean norm, or zeroes it if it is too small.
+func normalize(v []float64) {
+	var sum float64
+	for _, x := range v {
+		sum += x * x
+	}
+	norm := math.Sqrt(sum)
+	for i := range v {
+		if norm < silenceNorm {
+			v[i] = 0
+		} else {
+			v[i] /= norm
+		}
+	}
+}
+
+// classify turns the chroma features into the fingerprint items, two bits per classifier.
+func classify(image [][numBands]float64) []uint32 {
+	if len(image) < filterWidth {
+		return nil
+	}
+
+	// Integral image: sums of the features of the rows and bands before each position
+	sums := make([][numBands + 1]float64, len(image)+1)
+	for r, row := range image {
+		for b := range numBands {
+			sums[r+1][b+1] = sums[r][b+1] + sums[r+1][b] - sums[r][b] + row[b]
+		}
+	}
+	area := func(r1, b1, r2, b2 int) float64 {
+		return sums[r2][b2] - sums[r1][b2] - sums[r2][b1] + sums[r1][b1]
+	}
+
+	grayCode := [4]uint32{0, 1, 3, 2}
+	fp := make([]uint32, 0, len(image)-filterWidth+1)
+	for offset := 0; offset+filterWidth <= len(image); offset++ {
+		var bits uint32
+		for _, c := range classifiers {
+			bits = bits<<2 | grayCode[c.quantize(c.apply(area, offset))]
+		}
+		fp = append(fp, bits)
+	}
+	return fp
+}
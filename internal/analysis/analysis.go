@@ -0,0 +1,59 @@
+// Package analysis estimates the tempo and the musical key of a track from its sound.
+package analysis
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/dsp"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// MaxLength of the audio analyzed, taken from the middle of longer tracks
+const MaxLength time.Duration = 120 * time.Second
+
+// Sample rate the audio is converted to before analyzing it
+const sampleRate int = 11025
+
+// ErrNoMusic is returned for audio too short or quiet to find its tempo and key.
+var ErrNoMusic = errors.New("not enough music to analyze")
+
+// Result : The tempo and key of a track
+type Result struct {
+	// Beats per minute
+	BPM float64
+	Key Key
+}
+
+// Analyze decodes the audio file at path and estimates its tempo and key.
+func Analyze(path string) (Result, error) {
+	streamer, format, err := engine.Decode(path)
+	if err != nil {
+		return Result{}, err
+	}
+	defer streamer.Close()
+
+	// Intros and endings tend to have no beat, so long tracks are analyzed from the middle
+	length := format.SampleRate.N(MaxLength)
+	if extra := streamer.Len() - length; extra > 0 {
+		if err := streamer.Seek(extra / 2); err != nil {
+			return Result{}, err
+		}
+	}
+
+	samples, err := dsp.ReadMono(streamer, length)
+	if err != nil {
+		return Result{}, err
+	}
+	samples = dsp.Resample(samples, int(format.SampleRate), sampleRate)
+
+	bpm, ok := tempo(samples)
+	if !ok {
+		return Result{}, ErrNoMusic
+	}
+	key, ok := findKey(samples)
+	if !ok {
+		return Result{}, ErrNoMusic
+	}
+	return Result{BPM: bpm, Key: key}, nil
+}
@@ -0,0 +1,148 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/nicolito128/tempo/internal/dsp"
+)
+
+const (
+	// Samples of each frame of the key detection, long enough to tell apart the low notes
+	keyFrame int = 8192
+	// Range of the frequencies of the notes in Hz, from A1 to A6
+	minNoteFreq float64 = 55
+	maxNoteFreq float64 = 1760
+)
+
+// Names of the notes of the octave, from C
+var noteNames = []string{"C", "C#", "D", "Eb", "E", "F", "F#", "G", "Ab", "A", "Bb", "B"}
+
+// Krumhansl-Kessler profiles of how much each note of the octave belongs to a key, from its tonic
+var (
+	majorProfile = [12]float64{6.35, 2.23, 3.48, 2.33, 4.38, 4.09, 2.52, 5.19, 2.39, 3.66, 2.29, 2.88}
+	minorProfile = [12]float64{6.33, 2.68, 3.52, 5.38, 2.60, 3.53, 2.54, 4.75, 3.98, 2.69, 3.34, 3.17}
+)
+
+// Key : A musical key, like C major or A minor
+type Key struct {
+	// Tonic is the first note of the scale, from 0 (C) to 11 (B)
+	Tonic int
+	Minor bool
+}
+
+// ParseKey parses a key written like its String, such as "C# minor".
+func ParseKey(s string) (Key, error) {
+	note, mode, _ := strings.Cut(strings.TrimSpace(s), " ")
+	for i, name := range noteNames {
+		if !strings.EqualFold(note, name) {
+			continue
+		}
+		switch strings.ToLower(mode) {
+		case "major":
+			return Key{Tonic: i}, nil
+		case "minor":
+			return Key{Tonic: i, Minor: true}, nil
+		}
+	}
+	return Key{}, fmt.Errorf("invalid key %q", s)
+}
+
+func (k Key) String() string {
+	if k.Minor {
+		return noteNames[k.Tonic] + " minor"
+	}
+	return noteNames[k.Tonic] + " major"
+}
+
+// Camelot returns the key in the Camelot notation used by DJs, like 8A for A minor.
+// Keys with the same number, or numbers next to each other, mix well.
+func (k Key) Camelot() string {
+	// Relative keys share the number, which goes up by fifths
+	tonic, letter := k.Tonic, "B"
+	if k.Minor {
+		tonic, letter = (k.Tonic+3)%12, "A"
+	}
+	n := (7*tonic+7)%12 + 1
+	return fmt.Sprintf("%d%s", n, letter)
+}
+
+// findKey estimates the key of mono audio sampled at sampleRate, comparing how much each
+// note sounds with the profiles of every key.
+func findKey(samples []float64) (Key, bool) {
+	window := make([]float64, keyFrame)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(keyFrame-1))
+	}
+
+	// Note of the octave of each frequency bin, -1 outside of the range
+	notes := make([]int, keyFrame/2)
+	for i := range notes {
+		freq := float64(i) * float64(sampleRate) / float64(keyFrame)
+		notes[i] = -1
+		if freq >= minNoteFreq && freq <= maxNoteFreq {
+			semitones := int(math.Round(12 * math.Log2(freq/440)))
+			notes[i] = ((semitones+9)%12 + 12) % 12
+		}
+	}
+
+	var chroma [12]float64
+	frame := make([]complex128, keyFrame)
+	for start := 0; start+keyFrame <= len(samples); start += keyFrame / 2 {
+		for i := range frame {
+			frame[i] = complex(samples[start+i]*window[i], 0)
+		}
+		dsp.FFT(frame)
+
+		for i, note := range notes {
+			if note >= 0 {
+				re, im := real(frame[i]), imag(frame[i])
+				chroma[note] += math.Sqrt(re*re + im*im)
+			}
+		}
+	}
+
+	best, bestScore := Key{}, math.Inf(-1)
+	for tonic := range 12 {
+		for _, minor := range []bool{false, true} {
+			profile := majorProfile
+			if minor {
+				profile = minorProfile
+			}
+
+			var rotated [12]float64
+			for i := range 12 {
+				rotated[i] = chroma[(tonic+i)%12]
+			}
+			if score := correlation(rotated[:], profile[:]); score > bestScore {
+				best, bestScore = Key{Tonic: tonic, Minor: minor}, score
+			}
+		}
+	}
+	return best, !math.IsNaN(bestScore) && !math.IsInf(bestScore, -1)
+}
+
+// correlation returns the Pearson correlation of two vectors of the same length, NaN if
+// one of them is constant.
+func correlation(a, b []float64) float64 {
+	var meanA, meanB float64
+	for i := range a {
+		meanA += a[i]
+		meanB += b[i]
+	}
+	meanA /= float64(len(a))
+	meanB /= float64(len(b))
+
+	var cov, varA, varB float64
+	for i := range a {
+		da, db := a[i]-meanA, b[i]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return math.NaN()
+	}
+	return cov / math.Sqrt(varA*varB)
+}
@@ -0,0 +1,122 @@
+package analysis
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/nicolito128/tempo/internal/dsp"
+)
+
+const (
+	// Samples of each frame of the onset detection, and the step between two frames
+	onsetFrame int = 1024
+	onsetHop   int = 256
+	// Range of the detected tempos
+	minBPM float64 = 60
+	maxBPM float64 = 200
+	// Tempo preferred when several are equally likely, like the double or the half of the beat
+	preferredBPM float64 = 120
+)
+
+// tempo estimates the beats per minute of mono audio sampled at sampleRate, from the
+// periodicity of its onsets.
+func tempo(samples []float64) (float64, bool) {
+	env := onsets(samples)
+	fps := float64(sampleRate) / float64(onsetHop)
+	minLag := int(math.Floor(fps * 60 / maxBPM))
+	maxLag := int(math.Ceil(fps * 60 / minBPM))
+	if len(env) < 4*maxLag {
+		return 0, false
+	}
+
+	// Autocorrelation of the onsets, normalized by the number of overlapping frames
+	acf := make([]float64, 2*maxLag+2)
+	for lag := range acf {
+		var sum float64
+		for i := 0; i+lag < len(env); i++ {
+			sum += env[i] * env[i+lag]
+		}
+		acf[lag] = sum / float64(len(env)-lag)
+	}
+	if acf[0] == 0 {
+		return 0, false
+	}
+
+	// Periods that also repeat at twice their length are beats rather than subdivisions,
+	// and the tempos far from the preferred one are less likely
+	best, bestScore := 0, 0.0
+	for lag := minLag; lag <= maxLag; lag++ {
+		octaves := math.Log2(60 * fps / float64(lag) / preferredBPM)
+		score := (acf[lag] + acf[2*lag]/2 + acf[lag/2]/2) * math.Exp(-octaves*octaves)
+		if score > bestScore {
+			best, bestScore = lag, score
+		}
+	}
+	if best == 0 {
+		return 0, false
+	}
+
+	// The peak is interpolated between the frames with a parabola
+	period := float64(best)
+	if a, b, c := acf[best-1], acf[best], acf[best+1]; a-2*b+c < 0 {
+		period += (a - c) / (2 * (a - 2*b + c))
+	}
+	return 60 * fps / period, true
+}
+
+// onsets returns the onset strength of each frame, the increase of its spectrum from the
+// previous frame, without the slow changes of loudness.
+func onsets(samples []float64) []float64 {
+	window := make([]float64, onsetFrame)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(onsetFrame-1))
+	}
+
+	var env []float64
+	frame := make([]complex128, onsetFrame)
+	prev := make([]float64, onsetFrame/2)
+	cur := make([]float64, onsetFrame/2)
+	for start := 0; start+onsetFrame <= len(samples); start += onsetHop {
+		for i := range frame {
+			frame[i] = complex(samples[start+i]*window[i], 0)
+		}
+		dsp.FFT(frame)
+
+		// Compressed magnitudes, so quiet instruments count too
+		var flux float64
+		for i := range cur {
+			cur[i] = math.Log1p(100 * cmplx.Abs(frame[i]))
+			flux += max(cur[i]-prev[i], 0)
+		}
+		if start > 0 {
+			env = append(env, flux)
+		}
+		prev, cur = cur, prev
+	}
+
+	// Subtracting the local mean keeps the peaks of the onsets only
+	const span = 8
+	peaks := make([]float64, len(env))
+	for i := range env {
+		first, last := max(i-span, 0), min(i+span, len(env)-1)
+		var sum float64
+		for _, x := range env[first : last+1] {
+			sum += x
+		}
+		peaks[i] = max(env[i]-sum/float64(last-first+1), 0)
+	}
+
+	// Beats rarely fall on a frame boundary, so the peaks are widened to overlap at
+	// periods between two frames
+	out := make([]float64, len(peaks))
+	for i := range peaks {
+		out[i] = peaks[i] / 2
+		if i > 0 {
+			out[i] += peaks[i-1] / 4
+		}
+		if i+1 < len(peaks) {
+			out[i] += peaks[i+1] / 4
+		}
+	}
+	return out
+}
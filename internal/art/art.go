@@ -0,0 +1,102 @@
+// Package art finds the cover of a track and draws it in the terminal, using the
+// graphics protocol the terminal supports.
+package art
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Protocol : How images are drawn in the terminal
+type Protocol int
+
+const (
+	// None does not draw images
+	None Protocol = iota
+	// Kitty uses the kitty graphics protocol, also supported by Ghostty and Konsole
+	Kitty
+	// ITerm2 uses the inline images of iTerm2, also supported by WezTerm
+	ITerm2
+	// Sixel uses DEC sixel graphics, supported by foot, mlterm and xterm among others
+	Sixel
+	// Blocks draws a mosaic of colored half blocks, supported by every terminal
+	Blocks
+)
+
+var protocolNames = []string{"off", "kitty", "iterm2", "sixel", "blocks"}
+
+func (p Protocol) String() string {
+	if p < 0 || int(p) >= len(protocolNames) {
+		return "unknown"
+	}
+	return protocolNames[p]
+}
+
+// ParseProtocol converts a protocol name (off, kitty, iterm2, sixel or blocks) into a Protocol.
+// "auto" detects the protocol supported by the terminal.
+func ParseProtocol(s string) (Protocol, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "auto" || s == "" {
+		return Detect(), nil
+	}
+	for i, name := range protocolNames {
+		if s == name {
+			return Protocol(i), nil
+		}
+	}
+	return None, fmt.Errorf("unknown cover protocol %q", s)
+}
+
+// Image : An image ready to be drawn in the terminal
+type Image struct {
+	// Escape sequence that draws the image at the cursor
+	seq string
+
+	// Lines of the mosaic drawn with the Blocks protocol
+	blocks []string
+
+	// Size of the image in cells
+	Cols int
+	Rows int
+}
+
+// IsZero reports whether there is no image to draw.
+func (img Image) IsZero() bool {
+	return img.seq == "" && len(img.blocks) == 0
+}
+
+// Beside renders the image at the left of the text lines. The image is drawn from the
+// first line without moving the cursor, and the cells under it are skipped instead of
+// overwritten, so the lines can be repainted on their own without erasing the image.
+// The result must not be measured or aligned with lipgloss, which does not know the
+// width of the skipped cells, unless the image is a mosaic of blocks.
+func (img Image) Beside(lines []string) string {
+	if img.IsZero() {
+		return strings.Join(lines, "\n")
+	}
+	if len(img.blocks) > 0 {
+		return lipgloss.JoinHorizontal(lipgloss.Top, strings.Join(img.blocks, "\n"), "  ", strings.Join(lines, "\n"))
+	}
+
+	skip := fmt.Sprintf("\x1b[%dC", img.Cols+2)
+	rows := make([]string, max(len(lines), img.Rows))
+	for i := range rows {
+		rows[i] = skip
+		if i < len(lines) {
+			rows[i] += lines[i]
+		}
+	}
+	rows[0] = "\x1b7" + img.seq + "\x1b8" + rows[0]
+	return strings.Join(rows, "\n")
+}
+
+// Clear returns the escape sequence that removes the images drawn with the protocol.
+// Only the kitty images, which are not part of the text cells, need it.
+func Clear(p Protocol) string {
+	if p == Kitty {
+		return "\x1b_Ga=d,d=A,q=2\x1b\\"
+	}
+	return ""
+}
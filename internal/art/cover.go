@@ -0,0 +1,74 @@
+package art
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicolito128/tempo/internal/tags"
+)
+
+// ErrNoCover is returned when a track has no cover.
+var ErrNoCover = errors.New("no cover found")
+
+// Names of the image files used as the cover of the audio files in the same directory
+var coverNames = []string{"cover", "folder", "front", "album"}
+
+// Cover returns the cover embedded in the audio file at path, or the cover image found
+// in its directory, like cover.jpg.
+func Cover(path string) (image.Image, error) {
+	if md, err := tags.Read(path); err == nil {
+		if pic, ok := tags.Cover(md.Pictures()); ok {
+			if img, _, err := image.Decode(bytes.NewReader(pic.Data)); err == nil {
+				return img, nil
+			}
+		}
+	}
+
+	file, err := coverFile(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+// coverFile returns the path of the cover image inside dir.
+func coverFile(dir string) (string, error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	found := make(map[string]string)
+	for _, de := range des {
+		if de.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(de.Name()))
+		if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+			continue
+		}
+		stem := strings.ToLower(strings.TrimSuffix(de.Name(), filepath.Ext(de.Name())))
+		found[stem] = filepath.Join(dir, de.Name())
+	}
+
+	for _, name := range coverNames {
+		if file, ok := found[name]; ok {
+			return file, nil
+		}
+	}
+	return "", ErrNoCover
+}
@@ -0,0 +1,39 @@
+package art
+
+import (
+	"os"
+	"strings"
+)
+
+// Detect returns the graphics protocol supported by the terminal, looking at the
+// environment first and asking the terminal for its attributes otherwise. It must be
+// called before the UI starts reading the input. Terminals without graphics get Blocks.
+func Detect() Protocol {
+	// Multiplexers need every sequence wrapped and do not keep the images when redrawing
+	if os.Getenv("TMUX") != "" || strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		return Blocks
+	}
+
+	term := os.Getenv("TERM")
+	program := os.Getenv("TERM_PROGRAM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "", term == "xterm-kitty", program == "ghostty", term == "xterm-ghostty":
+		return Kitty
+	case program == "iTerm.app", program == "WezTerm", os.Getenv("LC_TERMINAL") == "iTerm2":
+		return ITerm2
+	case strings.Contains(term, "foot"), strings.Contains(term, "mlterm"), strings.Contains(term, "contour"):
+		return Sixel
+	}
+
+	// The primary device attributes list 4 when sixel graphics are supported
+	attrs, ok := queryAttributes()
+	if !ok {
+		return Blocks
+	}
+	for _, attr := range strings.Split(attrs, ";") {
+		if attr == "4" {
+			return Sixel
+		}
+	}
+	return Blocks
+}
@@ -0,0 +1,228 @@
+package art
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// Size of the base64 chunks sent with the kitty graphics protocol
+	kittyChunk int = 4096
+	// Size of a terminal cell in pixels when the terminal does not report it
+	defaultCellWidth  int = 10
+	defaultCellHeight int = 20
+)
+
+// Render prepares the image to be drawn with the protocol inside a box of the given
+// size in cells, keeping its aspect ratio.
+func Render(img image.Image, p Protocol, cols, rows int) (Image, error) {
+	if p == None || cols <= 0 || rows <= 0 {
+		return Image{}, nil
+	}
+	if p == Blocks {
+		return mosaic(img, cols, rows), nil
+	}
+
+	cw, ch := cellSize()
+	w, h := fit(img.Bounds().Dx(), img.Bounds().Dy(), cols*cw, rows*ch)
+	if w == 0 || h == 0 {
+		return Image{}, fmt.Errorf("empty image")
+	}
+	scaled := resize(img, w, h)
+
+	out := Image{Cols: (w + cw - 1) / cw, Rows: (h + ch - 1) / ch}
+	switch p {
+	case Kitty:
+		data, err := encodePNG(scaled)
+		if err != nil {
+			return Image{}, err
+		}
+		out.seq = Clear(Kitty) + kitty(data, out.Cols, out.Rows)
+
+	case ITerm2:
+		data, err := encodePNG(scaled)
+		if err != nil {
+			return Image{}, err
+		}
+		out.seq = fmt.Sprintf("\x1b]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=1:%s\a",
+			len(data), out.Cols, out.Rows, base64.StdEncoding.EncodeToString(data))
+
+	case Sixel:
+		out.seq = sixel(scaled)
+	}
+	return out, nil
+}
+
+// mosaic draws the image with half blocks, two pixels per cell, which are about square.
+func mosaic(img image.Image, cols, rows int) Image {
+	w, h := fit(img.Bounds().Dx(), img.Bounds().Dy(), cols, rows*2)
+	if w == 0 || h == 0 {
+		return Image{}
+	}
+	scaled := resize(img, w, h)
+
+	hex := func(c color.RGBA) lipgloss.Color {
+		return lipgloss.Color(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B))
+	}
+
+	out := Image{Cols: w, Rows: (h + 1) / 2}
+	for y := 0; y < h; y += 2 {
+		var line strings.Builder
+		for x := range w {
+			style := lipgloss.NewStyle().Foreground(hex(scaled.RGBAAt(x, y)))
+			if y+1 < h {
+				style = style.Background(hex(scaled.RGBAAt(x, y+1)))
+			}
+			line.WriteString(style.Render("▀"))
+		}
+		out.blocks = append(out.blocks, line.String())
+	}
+	return out
+}
+
+// kitty returns the sequence transmitting and displaying a PNG image, split in chunks.
+func kitty(data []byte, cols, rows int) string {
+	payload := base64.StdEncoding.EncodeToString(data)
+
+	var b strings.Builder
+	for i := 0; i < len(payload); i += kittyChunk {
+		end := min(i+kittyChunk, len(payload))
+		more := 0
+		if end < len(payload) {
+			more = 1
+		}
+		if i == 0 {
+			fmt.Fprintf(&b, "\x1b_Ga=T,f=100,q=2,C=1,c=%d,r=%d,m=%d;%s\x1b\\", cols, rows, more, payload[i:end])
+		} else {
+			fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, payload[i:end])
+		}
+	}
+	return b.String()
+}
+
+// sixel encodes the image as sixel graphics, with the colors reduced to a 6x6x6 cube.
+func sixel(img *image.RGBA) string {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+
+	index := func(x, y int) int {
+		c := img.RGBAAt(x, y)
+		return int(c.R)*5/255*36 + int(c.G)*5/255*6 + int(c.B)*5/255
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\x1bPq\"1;1;%d;%d", w, h)
+	for i := range 216 {
+		r, g, bl := i/36, i/6%6, i%6
+		fmt.Fprintf(&b, "#%d;2;%d;%d;%d", i, r*20, g*20, bl*20)
+	}
+
+	row := make([]byte, w)
+	for y0 := 0; y0 < h; y0 += 6 {
+		// Colors used in this band of six pixel rows
+		used := make(map[int]bool)
+		for y := y0; y < min(y0+6, h); y++ {
+			for x := range w {
+				used[index(x, y)] = true
+			}
+		}
+
+		first := true
+		for c := range 216 {
+			if !used[c] {
+				continue
+			}
+			for x := range w {
+				bits := 0
+				for dy := range 6 {
+					if y := y0 + dy; y < h && index(x, y) == c {
+						bits |= 1 << dy
+					}
+				}
+				row[x] = byte(63 + bits)
+			}
+
+			if !first {
+				b.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(&b, "#%d", c)
+			writeRuns(&b, row)
+		}
+		b.WriteByte('-')
+	}
+	b.WriteString("\x1b\\")
+	return b.String()
+}
+
+// writeRuns writes the sixel characters, compressing repeated ones.
+func writeRuns(b *strings.Builder, row []byte) {
+	for i := 0; i < len(row); {
+		j := i + 1
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			fmt.Fprintf(b, "!%d%c", n, row[i])
+		} else {
+			b.Write(row[i:j])
+		}
+		i = j
+	}
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fit returns the size of an image of w×h scaled down to fit inside maxW×maxH.
+func fit(w, h, maxW, maxH int) (int, int) {
+	if w <= 0 || h <= 0 {
+		return 0, 0
+	}
+	if w*maxH > h*maxW {
+		return maxW, max(h*maxW/w, 1)
+	}
+	return max(w*maxH/h, 1), maxH
+}
+
+// resize scales the image to w×h, averaging the source pixels of each destination pixel.
+// Transparent pixels are blended over black.
+func resize(src image.Image, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+
+	for y := range h {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := range w {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+
+			var r, g, b, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, _ := src.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+					r, g, b = r+uint64(cr), g+uint64(cg), b+uint64(cb)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: 0xFF,
+			})
+		}
+	}
+	return dst
+}
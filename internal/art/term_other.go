@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package art
+
+// cellSize returns the size of a terminal cell in pixels.
+func cellSize() (int, int) {
+	return defaultCellWidth, defaultCellHeight
+}
+
+// queryAttributes is not supported on this platform.
+func queryAttributes() (string, bool) {
+	return "", false
+}
@@ -0,0 +1,75 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package art
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"golang.org/x/sys/unix"
+)
+
+// How long to wait for the terminal to answer a query
+const queryTimeout = 200 * time.Millisecond
+
+// cellSize returns the size of a terminal cell in pixels.
+func cellSize() (int, int) {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 || ws.Row == 0 || ws.Xpixel == 0 || ws.Ypixel == 0 {
+		return defaultCellWidth, defaultCellHeight
+	}
+	return int(ws.Xpixel / ws.Col), int(ws.Ypixel / ws.Row)
+}
+
+// queryAttributes asks the terminal for its primary device attributes and returns
+// them without the "\x1b[?" prefix and the "c" suffix.
+func queryAttributes() (string, bool) {
+	in, out := os.Stdin.Fd(), os.Stdout.Fd()
+	if !term.IsTerminal(in) || !term.IsTerminal(out) {
+		return "", false
+	}
+
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return "", false
+	}
+	defer term.Restore(in, state)
+
+	if _, err := os.Stdout.WriteString("\x1b[c"); err != nil {
+		return "", false
+	}
+
+	var reply []byte
+	deadline := time.Now().Add(queryTimeout)
+	buf := make([]byte, 64)
+	for {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return "", false
+		}
+		fds := []unix.PollFd{{Fd: int32(in), Events: unix.POLLIN}}
+		if n, err := unix.Poll(fds, int(wait.Milliseconds())+1); err != nil || n == 0 {
+			if err == unix.EINTR {
+				continue
+			}
+			return "", false
+		}
+
+		n, err := unix.Read(int(in), buf)
+		if err != nil {
+			return "", false
+		}
+		reply = append(reply, buf[:n]...)
+
+		if end := strings.IndexByte(string(reply), 'c'); end >= 0 {
+			s := string(reply[:end])
+			start := strings.Index(s, "\x1b[?")
+			if start < 0 {
+				return "", false
+			}
+			return s[start+3:], true
+		}
+	}
+}
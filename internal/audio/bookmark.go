@@ -0,0 +1,10 @@
+package audio
+
+import "time"
+
+// Bookmark : A named position inside an audio file, like a part of an audiobook or a
+// rehearsal recording to come back to
+type Bookmark struct {
+	Name     string        `json:"name"`
+	Position time.Duration `json:"position"`
+}
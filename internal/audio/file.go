@@ -0,0 +1,158 @@
+// Package audio holds the audio files played, enqueued and indexed, shared by the player and
+// the library.
+package audio
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicolito128/tempo/internal/tags"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// SupportedExtensions are the audio file extensions the player is able to decode.
+var SupportedExtensions = []string{".mp3", ".wav", ".flac", ".ogg", ".oga"}
+
+// IsSupported reports whether the given path has a supported audio extension. Every HTTP(S)
+// URL is, since the type of the audio it serves is known once requested.
+func IsSupported(path string) bool {
+	if engine.IsURL(path) {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range SupportedExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// File : An audio file, or the URL of one, with the tags read from it
+type File struct {
+	name string
+	ext  string
+	path string
+
+	// Metadata embedded in the file, empty until loaded
+	tags       tags.Tags
+	tagsLoaded bool
+}
+
+func NewFile(path string) File {
+	if engine.IsURL(path) {
+		return newURLFile(path)
+	}
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	base = strings.Replace(base, ext, "", 1)
+	return File{name: base, ext: ext, path: path}
+}
+
+// newURLFile returns the audio at a URL, named after the last element of its path, or
+// after its host if the path is empty.
+func newURLFile(rawURL string) File {
+	u, _ := url.Parse(rawURL)
+	base := path.Base(u.Path)
+	if base == "/" || base == "." {
+		return File{name: u.Host, path: rawURL}
+	}
+	ext := path.Ext(base)
+	return File{name: strings.TrimSuffix(base, ext), ext: ext, path: rawURL}
+}
+
+// Named reports whether the audio was given a name other than the one of its path, like the
+// stations saved by the user.
+func (a File) Named() bool {
+	return a.name != NewFile(a.path).name
+}
+
+func (a File) FilterValue() string {
+	return a.name
+}
+
+func (a File) Name() string {
+	return a.name
+}
+
+func (a *File) SetName(name string) {
+	if name == "" {
+		return
+	}
+	a.name = name
+}
+
+func (a File) Path() string {
+	return a.path
+}
+
+// SetPath changes the path of the file, like after it is moved, keeping its tags and the name
+// it was given.
+func (a *File) SetPath(path string) {
+	moved := NewFile(path)
+	if !a.Named() {
+		a.name = moved.name
+	}
+	a.ext, a.path = moved.ext, path
+}
+
+func (a File) Ext() string {
+	return a.ext
+}
+
+// Tags returns the metadata of the file, if it was loaded.
+func (a File) Tags() tags.Tags {
+	return a.tags
+}
+
+// SetTags sets the metadata of the file, like the tags stored in the library index.
+func (a *File) SetTags(t tags.Tags) {
+	a.tags = t
+	a.tagsLoaded = true
+}
+
+// LoadTags reads the metadata embedded in the file, once. Files without tags keep
+// showing their file name.
+func (a *File) LoadTags() {
+	if a.tagsLoaded {
+		return
+	}
+	t, _ := tags.ReadFile(a.path)
+	a.SetTags(t)
+}
+
+// Title returns the title tag, or the file name without extension.
+func (a File) Title() string {
+	if a.tags.Title != "" {
+		return a.tags.Title
+	}
+	return a.name
+}
+
+// Artist returns the artist tag, empty if unknown.
+func (a File) Artist() string {
+	return a.tags.Artist
+}
+
+// Album returns the album tag, empty if unknown.
+func (a File) Album() string {
+	return a.tags.Album
+}
+
+// Label returns "Artist – Title" for tagged files, or the file name without extension.
+func (a File) Label() string {
+	if a.tags.Artist == "" {
+		return a.Title()
+	}
+	return a.tags.Artist + " – " + a.Title()
+}
+
+func (a *File) String() string {
+	if a == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("%#v", a)
+}
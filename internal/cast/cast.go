@@ -0,0 +1,312 @@
+// Package cast plays the audio on the renderers of the local network: Chromecasts and the
+// DLNA (UPnP) ones, like network speakers, receivers and TVs. The audio decoded by the engine
+// is served as an endless WAV stream over HTTP, which the renderer is told to play, so
+// everything done to the playback, from pausing to the equalizer, reaches it a few seconds
+// later, once buffered.
+//
+// It also plays as a source of a Snapserver, writing the audio as raw PCM to the pipe or the
+// TCP server the Snapserver reads, which plays it in sync on all its clients.
+package cast
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+const (
+	// Time the renderers are searched for, the longest they take to answer
+	searchTime = 2 * time.Second
+	// Time the renderers found are listed before searching again
+	searchAge = 30 * time.Second
+
+	// Title of the stream shown by the renderers
+	streamTitle = "tempo"
+)
+
+// renderer : A device of the network able to play the stream
+type renderer interface {
+	// Device returns the renderer as an output device, named with its kind like "dlna:<id>"
+	Device() engine.Device
+	// Host returns the address of the renderer, to find the one of the stream it reaches
+	Host() string
+	// Play tells the renderer to play the stream at url
+	Play(url string) error
+	// Stop tells the renderer to stop playing, and disconnects from it
+	Stop() error
+}
+
+// Output : The output devices of the system, the Snapcast source and the renderers of the
+// network, as one backend of the engine
+//
+// The speaker keeps playing what it was given when a renderer is chosen, which is nothing
+// since the engine clears the output before opening it again.
+type Output struct {
+	speaker engine.Speaker
+
+	// Renderers found by the last search, when it ended, and the search running, closed when
+	// it ends
+	mu        sync.Mutex
+	renderers map[string]renderer
+	searched  time.Time
+	searching chan struct{}
+
+	// Device chosen for the next Init, and the one of the speaker. While a renderer plays,
+	// stream is the audio it plays, playing being locked too since the searches read it
+	device      string
+	speakerName string
+	speakerRate beep.SampleRate
+	playing     renderer
+	stream      *stream
+	active      engine.Backend
+
+	// Snapcast source set in the config, and the backend writing to it while chosen
+	snapSource string
+	snapFormat SampleFormat
+	snapcast   *snapcast
+}
+
+var _ engine.DeviceBackend = (*Output)(nil)
+
+// NewOutput returns the backend playing on the speaker until a renderer is chosen.
+func NewOutput() *Output {
+	o := &Output{renderers: make(map[string]renderer)}
+	o.active = o.speaker
+	return o
+}
+
+// SetSnapcast sets the Snapcast source listed as the "snapcast" device: the path of the pipe
+// the Snapserver reads, or "tcp://host:port" for a source listening on TCP, and the sample
+// format it reads. An empty source removes the device.
+func (o *Output) SetSnapcast(source string, format SampleFormat) {
+	o.snapSource, o.snapFormat = source, format
+}
+
+// Devices lists the devices of the speaker followed by the Snapcast source and the renderers
+// of the network, searching them again if the last search is too old.
+func (o *Output) Devices() ([]engine.Device, error) {
+	devices, err := o.speaker.Devices()
+	if err != nil {
+		return nil, err
+	}
+	if o.snapSource != "" {
+		devices = append(devices, engine.Device{
+			Name:        snapcastDevice,
+			Description: "Snapcast (" + o.snapSource + ")",
+		})
+	}
+	for _, r := range o.search() {
+		devices = append(devices, r.Device())
+	}
+	return devices, nil
+}
+
+// search returns the renderers of the network, sorted by name, waiting for the search running
+// or starting one if the last one is too old.
+func (o *Output) search() []renderer {
+	o.mu.Lock()
+	if o.searching == nil && time.Since(o.searched) > searchAge {
+		o.searching = make(chan struct{})
+		go func(done chan struct{}) {
+			found := search(searchTime)
+			o.mu.Lock()
+			// The renderer playing stays known even if it did not answer this time
+			if o.playing != nil {
+				found[o.playing.Device().Name] = o.playing
+			}
+			o.renderers, o.searched, o.searching = found, time.Now(), nil
+			o.mu.Unlock()
+			close(done)
+		}(o.searching)
+	}
+	if searching := o.searching; searching != nil {
+		o.mu.Unlock()
+		<-searching
+		o.mu.Lock()
+	}
+	defer o.mu.Unlock()
+
+	renderers := slices.Collect(maps.Values(o.renderers))
+	slices.SortFunc(renderers, func(a, b renderer) int {
+		return strings.Compare(a.Device().Description, b.Device().Description)
+	})
+	return renderers
+}
+
+// search returns the renderers answering within timeout, by the name of their device.
+func search(timeout time.Duration) map[string]renderer {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		found = make(map[string]renderer)
+	)
+	for _, kind := range []struct {
+		name   string
+		search func(time.Duration) ([]renderer, error)
+	}{{"DLNA", searchDLNA}, {"Chromecast", searchChromecasts}} {
+		wg.Go(func() {
+			renderers, err := kind.search(timeout)
+			if err != nil {
+				slog.Warn("Cannot search the renderers", "kind", kind.name, "err", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range renderers {
+				found[r.Device().Name] = r
+			}
+		})
+	}
+	wg.Wait()
+	return found
+}
+
+// isRenderer reports whether the device name is the one of a renderer.
+func isRenderer(name string) bool {
+	return strings.HasPrefix(name, dlnaPrefix) || strings.HasPrefix(name, castPrefix)
+}
+
+// SetDevice chooses the device opened by the next Init. The renderers are searched for if the
+// one chosen was not found yet, like when it is the one of the config.
+func (o *Output) SetDevice(name string) error {
+	if name == snapcastDevice {
+		if o.snapSource == "" {
+			return errors.New("no Snapcast source is set in the config")
+		}
+		o.device = name
+		return nil
+	}
+	if isRenderer(name) {
+		o.mu.Lock()
+		_, ok := o.renderers[name]
+		o.mu.Unlock()
+		if !ok && !slices.ContainsFunc(o.search(), func(r renderer) bool { return r.Device().Name == name }) {
+			return fmt.Errorf("the renderer %q is not on the network", name)
+		}
+		o.device = name
+		return nil
+	}
+
+	// The speaker is still open at the device it was opened at
+	if o.speakerRate == 0 || name != o.speakerName {
+		if err := o.speaker.SetDevice(name); err != nil {
+			return err
+		}
+		o.speakerName = name
+	}
+	o.device = name
+	return nil
+}
+
+// Init plays on the device chosen, telling the renderer to play the stream if it is one.
+func (o *Output) Init(rate beep.SampleRate, bufferSize int) error {
+	switch {
+	case o.device == snapcastDevice:
+		o.stopRenderer()
+		if o.snapcast == nil || o.snapcast.rate != rate {
+			o.stopSnapcast()
+			o.snapcast = newSnapcast(o.snapSource, o.snapFormat, rate, bufferSize)
+		}
+		o.active = o.snapcast
+		return nil
+	case !isRenderer(o.device):
+		o.stopRenderer()
+		o.stopSnapcast()
+		if o.speakerRate != rate {
+			if err := o.speaker.Init(rate, bufferSize); err != nil {
+				return err
+			}
+			o.speakerRate = rate
+		}
+		o.active = o.speaker
+		return nil
+	}
+
+	o.stopSnapcast()
+	o.mu.Lock()
+	r, ok := o.renderers[o.device]
+	o.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("the renderer %q is not on the network", o.device)
+	}
+	if r == o.playing && o.stream != nil && o.stream.rate == rate {
+		o.active = o.stream
+		return nil
+	}
+
+	o.stopRenderer()
+	s, err := newStream(rate, bufferSize)
+	if err != nil {
+		return err
+	}
+	url, err := s.URL(r.Host())
+	if err == nil {
+		err = r.Play(url)
+	}
+	if err != nil {
+		s.Close()
+		return fmt.Errorf("cannot play on %s: %w", r.Device().Description, err)
+	}
+	slog.Debug("Playing on a renderer", "device", r.Device().Name, "url", url)
+	o.mu.Lock()
+	o.playing = r
+	o.mu.Unlock()
+	o.stream, o.active = s, s
+	return nil
+}
+
+// stopRenderer stops the renderer playing, if any, and the stream.
+func (o *Output) stopRenderer() {
+	if o.playing == nil {
+		return
+	}
+	if err := o.playing.Stop(); err != nil {
+		slog.Warn("Cannot stop the renderer", "device", o.playing.Device().Name, "err", err)
+	}
+	o.stream.Close()
+	o.mu.Lock()
+	o.playing = nil
+	o.mu.Unlock()
+	o.stream, o.active = nil, o.speaker
+}
+
+func (o *Output) Play(s beep.Streamer) {
+	o.active.Play(s)
+}
+
+func (o *Output) Clear() {
+	o.active.Clear()
+}
+
+func (o *Output) Lock() {
+	o.active.Lock()
+}
+
+func (o *Output) Unlock() {
+	o.active.Unlock()
+}
+
+// stopSnapcast stops writing to the Snapcast source, if it was.
+func (o *Output) stopSnapcast() {
+	if o.snapcast == nil {
+		return
+	}
+	o.snapcast.Close()
+	o.snapcast, o.active = nil, o.speaker
+}
+
+// Close stops the renderer or the Snapcast source playing and closes the speaker.
+func (o *Output) Close() {
+	o.stopRenderer()
+	o.stopSnapcast()
+	if o.speakerRate != 0 {
+		o.speaker.Close()
+	}
+}
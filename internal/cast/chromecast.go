@@ -0,0 +1,409 @@
+package cast
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+const (
+	castPrefix = "cast:"
+
+	// DNS-SD service of the Chromecasts
+	castService = "_googlecast._tcp.local"
+
+	// Application of the Chromecasts playing a URL, the Default Media Receiver
+	mediaReceiver = "CC1AD845"
+
+	// Namespaces of the messages
+	nsConnection = "urn:x-cast:com.google.cast.tp.connection"
+	nsHeartbeat  = "urn:x-cast:com.google.cast.tp.heartbeat"
+	nsReceiver   = "urn:x-cast:com.google.cast.receiver"
+	nsMedia      = "urn:x-cast:com.google.cast.media"
+
+	// Ids of the sender and the receiver of the device
+	senderID   = "sender-0"
+	receiverID = "receiver-0"
+
+	// Longest time the Chromecast takes to launch the receiver and load the stream
+	castTimeout = 15 * time.Second
+	// Time between the pings keeping the connection open
+	pingInterval = 5 * time.Second
+	// Largest message read
+	maxCastMessage = 1 << 20
+)
+
+// chromecast : A Chromecast, or a speaker or TV with Chromecast built in
+type chromecast struct {
+	id   string
+	name string
+	addr *net.TCPAddr
+
+	// Connection while playing, and the session of the receiver
+	mu      sync.Mutex
+	conn    *castConn
+	session string
+}
+
+var _ renderer = (*chromecast)(nil)
+
+func (c *chromecast) Device() engine.Device {
+	return engine.Device{Name: castPrefix + c.id, Description: c.name + " (Chromecast)"}
+}
+
+func (c *chromecast) Host() string {
+	return c.addr.String()
+}
+
+// searchChromecasts returns the Chromecasts answering the mDNS query within timeout.
+func searchChromecasts(timeout time.Duration) ([]renderer, error) {
+	services, err := browse(castService, timeout)
+	if err != nil {
+		return nil, err
+	}
+	var renderers []renderer
+	for _, s := range services {
+		c := &chromecast{id: s.txt["id"], name: s.txt["fn"], addr: s.addr}
+		if c.id == "" {
+			c.id = strings.TrimSuffix(s.name, "."+castService)
+		}
+		if c.name == "" {
+			c.name = strings.TrimSuffix(s.name, "."+castService)
+		}
+		renderers = append(renderers, c)
+	}
+	return renderers, nil
+}
+
+// Play launches the media receiver on the Chromecast and loads the stream in it, staying
+// connected until Stop.
+func (c *chromecast) Play(url string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+
+	conn, err := dialCast(c.addr.String())
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(castTimeout))
+	app, err := conn.launch()
+	if err == nil {
+		err = conn.load(app.TransportID, url)
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+	go conn.keepAlive()
+	c.conn, c.session = conn, app.SessionID
+	return nil
+}
+
+// Stop closes the media receiver, which goes back to the screen it was showing.
+func (c *chromecast) Stop() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.send(receiverID, nsReceiver, map[string]any{
+		"type": "STOP", "requestId": c.conn.nextRequest(), "sessionId": c.session,
+	})
+	c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// castConn : The connection to a Chromecast, sending and reading the messages of the Cast
+// protocol: JSON payloads in protobuf envelopes, each after its length
+type castConn struct {
+	conn *tls.Conn
+
+	// Lock of the writes, shared by the pings, and the id of the last request
+	mu      sync.Mutex
+	request int
+
+	done chan struct{}
+}
+
+// dialCast connects to the Chromecast at addr.
+func dialCast(addr string) (*castConn, error) {
+	dialer := &net.Dialer{Timeout: castTimeout}
+	// The Chromecasts present certificates signed by Google for the device, not for the address
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return nil, err
+	}
+	c := &castConn{conn: conn, done: make(chan struct{})}
+	if err := c.send(receiverID, nsConnection, map[string]any{"type": "CONNECT"}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *castConn) SetDeadline(t time.Time) {
+	c.conn.SetDeadline(t)
+}
+
+// nextRequest returns the id of a new request.
+func (c *castConn) nextRequest() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.request++
+	return c.request
+}
+
+// castApp : An application running on the Chromecast
+type castApp struct {
+	AppID       string `json:"appId"`
+	SessionID   string `json:"sessionId"`
+	TransportID string `json:"transportId"`
+}
+
+// launch launches the media receiver, returning it once running.
+func (c *castConn) launch() (castApp, error) {
+	err := c.send(receiverID, nsReceiver, map[string]any{
+		"type": "LAUNCH", "requestId": c.nextRequest(), "appId": mediaReceiver,
+	})
+	if err != nil {
+		return castApp{}, err
+	}
+	for {
+		ns, payload, err := c.read()
+		if err != nil {
+			return castApp{}, err
+		}
+		if ns != nsReceiver {
+			continue
+		}
+		var msg struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+			Status struct {
+				Applications []castApp `json:"applications"`
+			} `json:"status"`
+		}
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			return castApp{}, err
+		}
+		switch msg.Type {
+		case "LAUNCH_ERROR":
+			return castApp{}, fmt.Errorf("cannot launch the media receiver: %s", msg.Reason)
+		case "RECEIVER_STATUS":
+			for _, app := range msg.Status.Applications {
+				if app.AppID == mediaReceiver && app.TransportID != "" {
+					return app, nil
+				}
+			}
+		}
+	}
+}
+
+// load connects to the media receiver and has it play the live stream at url.
+func (c *castConn) load(transport, url string) error {
+	if err := c.send(transport, nsConnection, map[string]any{"type": "CONNECT"}); err != nil {
+		return err
+	}
+	err := c.send(transport, nsMedia, map[string]any{
+		"type":      "LOAD",
+		"requestId": c.nextRequest(),
+		"autoplay":  true,
+		"media": map[string]any{
+			"contentId":   url,
+			"contentType": "audio/wav",
+			"streamType":  "LIVE",
+			"metadata":    map[string]any{"metadataType": 3, "title": streamTitle},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		ns, payload, err := c.read()
+		if err != nil {
+			return err
+		}
+		if ns != nsMedia {
+			continue
+		}
+		var msg struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		}
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			return err
+		}
+		switch msg.Type {
+		case "MEDIA_STATUS":
+			return nil
+		case "LOAD_FAILED", "LOAD_CANCELLED", "INVALID_REQUEST":
+			return fmt.Errorf("the Chromecast cannot play the stream: %s %s", msg.Type, msg.Reason)
+		}
+	}
+}
+
+// keepAlive pings the Chromecast and answers its pings, reading the messages until the
+// connection is closed.
+func (c *castConn) keepAlive() {
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-ticker.C:
+				if err := c.send(receiverID, nsHeartbeat, map[string]any{"type": "PING"}); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	for {
+		ns, payload, err := c.read()
+		if err != nil {
+			select {
+			case <-c.done:
+			default:
+				slog.Warn("Lost the connection to the Chromecast", "err", err)
+			}
+			return
+		}
+		if ns == nsHeartbeat && strings.Contains(string(payload), `"PING"`) {
+			c.send(receiverID, nsHeartbeat, map[string]any{"type": "PONG"})
+		}
+	}
+}
+
+// Close disconnects from the Chromecast.
+func (c *castConn) Close() {
+	select {
+	case <-c.done:
+		return
+	default:
+		close(c.done)
+	}
+	c.send(receiverID, nsConnection, map[string]any{"type": "CLOSE"})
+	c.conn.Close()
+}
+
+// send sends the payload, encoded as JSON, to the destination in the namespace.
+func (c *castConn) send(destination, namespace string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	msg := marshalCastMessage(destination, namespace, string(data))
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(msg)), uint32(len(msg)))
+	frame = append(frame, msg...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.conn.Write(frame)
+	return err
+}
+
+// read returns the namespace and the payload of the next message.
+func (c *castConn) read() (string, []byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(c.conn, size[:]); err != nil {
+		return "", nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxCastMessage {
+		return "", nil, errors.New("the Chromecast sent a message too large")
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(c.conn, msg); err != nil {
+		return "", nil, err
+	}
+	return unmarshalCastMessage(msg)
+}
+
+// Fields of the CastMessage protobuf
+const (
+	fieldProtocolVersion = 1
+	fieldSourceID        = 2
+	fieldDestinationID   = 3
+	fieldNamespace       = 4
+	fieldPayloadType     = 5
+	fieldPayloadUTF8     = 6
+
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// marshalCastMessage returns the CastMessage carrying the payload from the sender.
+func marshalCastMessage(destination, namespace, payload string) []byte {
+	var b []byte
+	// Version 1.0 and string payload, both 0
+	b = binary.AppendUvarint(b, fieldProtocolVersion<<3|wireVarint)
+	b = binary.AppendUvarint(b, 0)
+	for _, f := range []struct {
+		field int
+		value string
+	}{{fieldSourceID, senderID}, {fieldDestinationID, destination}, {fieldNamespace, namespace}} {
+		b = binary.AppendUvarint(b, uint64(f.field<<3|wireBytes))
+		b = binary.AppendUvarint(b, uint64(len(f.value)))
+		b = append(b, f.value...)
+	}
+	b = binary.AppendUvarint(b, fieldPayloadType<<3|wireVarint)
+	b = binary.AppendUvarint(b, 0)
+	b = binary.AppendUvarint(b, fieldPayloadUTF8<<3|wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(payload)))
+	return append(b, payload...)
+}
+
+// unmarshalCastMessage returns the namespace and the payload of a CastMessage.
+func unmarshalCastMessage(b []byte) (string, []byte, error) {
+	var (
+		namespace string
+		payload   []byte
+	)
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return "", nil, errors.New("malformed Cast message")
+		}
+		b = b[n:]
+		switch tag & 7 {
+		case wireVarint:
+			if _, n = binary.Uvarint(b); n <= 0 {
+				return "", nil, errors.New("malformed Cast message")
+			}
+			b = b[n:]
+		case wireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < length {
+				return "", nil, errors.New("malformed Cast message")
+			}
+			value := b[n : n+int(length)]
+			b = b[n+int(length):]
+			switch tag >> 3 {
+			case fieldNamespace:
+				namespace = string(value)
+			case fieldPayloadUTF8:
+				payload = value
+			}
+		default:
+			return "", nil, errors.New("malformed Cast message")
+		}
+	}
+	return namespace, payload, nil
+}
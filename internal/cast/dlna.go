@@ -0,0 +1,261 @@
+package cast
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+const (
+	dlnaPrefix = "dlna:"
+
+	// Multicast address the UPnP devices are searched at, and the kind of device searched
+	ssdpAddr      = "239.255.255.250:1900"
+	mediaRenderer = "urn:schemas-upnp-org:device:MediaRenderer:1"
+
+	// Service of the renderers playing a URL
+	avTransport = "urn:schemas-upnp-org:service:AVTransport:"
+
+	// Longest time a renderer takes to answer a request
+	dlnaTimeout = 5 * time.Second
+)
+
+// dlnaRenderer : A DLNA renderer, controlled through its AVTransport service
+type dlnaRenderer struct {
+	id   string
+	name string
+	// Address of its description, and URL and type of its AVTransport service
+	location    *url.URL
+	controlURL  string
+	serviceType string
+}
+
+var _ renderer = (*dlnaRenderer)(nil)
+
+func (d *dlnaRenderer) Device() engine.Device {
+	return engine.Device{Name: dlnaPrefix + d.id, Description: d.name + " (DLNA)"}
+}
+
+func (d *dlnaRenderer) Host() string {
+	host := d.location.Host
+	if d.location.Port() == "" {
+		host = net.JoinHostPort(host, "80")
+	}
+	return host
+}
+
+// Play sets the stream as the media of the renderer and plays it.
+func (d *dlnaRenderer) Play(streamURL string) error {
+	metadata := `<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">` +
+		`<item id="0" parentID="-1" restricted="1">` +
+		`<dc:title>` + escapeXML(streamTitle) + `</dc:title>` +
+		`<upnp:class>object.item.audioItem.musicTrack</upnp:class>` +
+		`<res protocolInfo="http-get:*:audio/wav:*">` + escapeXML(streamURL) + `</res>` +
+		`</item></DIDL-Lite>`
+	err := d.call("SetAVTransportURI", []soapArg{
+		{"InstanceID", "0"},
+		{"CurrentURI", streamURL},
+		{"CurrentURIMetaData", metadata},
+	})
+	if err != nil {
+		return err
+	}
+	return d.call("Play", []soapArg{{"InstanceID", "0"}, {"Speed", "1"}})
+}
+
+func (d *dlnaRenderer) Stop() error {
+	return d.call("Stop", []soapArg{{"InstanceID", "0"}})
+}
+
+// soapArg : An argument of an action of a UPnP service
+type soapArg struct {
+	name  string
+	value string
+}
+
+// call runs the action of the AVTransport service with the arguments, in order.
+func (d *dlnaRenderer) call(action string, args []soapArg) error {
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	body.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" `)
+	body.WriteString(`s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&body, `<u:%s xmlns:u="%s">`, action, d.serviceType)
+	for _, arg := range args {
+		fmt.Fprintf(&body, "<%s>%s</%s>", arg.name, escapeXML(arg.value), arg.name)
+	}
+	fmt.Fprintf(&body, "</u:%s></s:Body></s:Envelope>", action)
+
+	ctx, cancel := context.WithTimeout(context.Background(), dlnaTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.controlURL, strings.NewReader(body.String()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", `"`+d.serviceType+"#"+action+`"`)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	// The faults tell what went wrong in a UPnPError
+	var fault struct {
+		Code        int    `xml:"Body>Fault>detail>UPnPError>errorCode"`
+		Description string `xml:"Body>Fault>detail>UPnPError>errorDescription"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if xml.Unmarshal(data, &fault) == nil && fault.Code != 0 {
+		return fmt.Errorf("%s failed with the UPnP error %d %s", action, fault.Code, fault.Description)
+	}
+	return fmt.Errorf("%s failed: %s", action, resp.Status)
+}
+
+// escapeXML returns s escaped as the text of an XML element.
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// searchDLNA returns the DLNA renderers answering the SSDP search within timeout.
+func searchDLNA(timeout time.Duration) ([]renderer, error) {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	addr, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return nil, err
+	}
+	search := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddr + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 1\r\n" +
+		"ST: " + mediaRenderer + "\r\n\r\n"
+	if _, err := conn.WriteTo([]byte(search), addr); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	conn.SetReadDeadline(deadline)
+	seen := make(map[string]bool)
+	var renderers []renderer
+	buf := make([]byte, 2048)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			// The deadline ends the search
+			break
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil {
+			continue
+		}
+		location := resp.Header.Get("Location")
+		if location == "" || seen[location] {
+			continue
+		}
+		seen[location] = true
+		d, err := describeDLNA(location, time.Until(deadline))
+		if err != nil {
+			continue
+		}
+		renderers = append(renderers, d)
+	}
+	return renderers, nil
+}
+
+// dlnaDevice : The description of a UPnP device, and of the ones inside it
+type dlnaDevice struct {
+	FriendlyName string `xml:"friendlyName"`
+	UDN          string `xml:"UDN"`
+	Services     []struct {
+		Type       string `xml:"serviceType"`
+		ControlURL string `xml:"controlURL"`
+	} `xml:"serviceList>service"`
+	Devices []dlnaDevice `xml:"deviceList>device"`
+}
+
+// describeDLNA returns the renderer described at location, failing if it has no AVTransport
+// service.
+func describeDLNA(location string, timeout time.Duration) (*dlnaRenderer, error) {
+	base, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), max(timeout, time.Second))
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot get the description: %s", resp.Status)
+	}
+
+	var desc struct {
+		URLBase string     `xml:"URLBase"`
+		Device  dlnaDevice `xml:"device"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&desc); err != nil {
+		return nil, err
+	}
+	if desc.URLBase != "" {
+		if u, err := url.Parse(desc.URLBase); err == nil {
+			base = u
+		}
+	}
+
+	devices := []dlnaDevice{desc.Device}
+	for len(devices) > 0 {
+		dev := devices[0]
+		devices = append(devices[1:], dev.Devices...)
+		for _, s := range dev.Services {
+			if !strings.HasPrefix(s.Type, avTransport) {
+				continue
+			}
+			control, err := base.Parse(strings.TrimSpace(s.ControlURL))
+			if err != nil {
+				return nil, err
+			}
+			id := strings.TrimPrefix(strings.TrimSpace(desc.Device.UDN), "uuid:")
+			if id == "" {
+				id = base.Host
+			}
+			name := strings.TrimSpace(desc.Device.FriendlyName)
+			if name == "" {
+				name = base.Hostname()
+			}
+			return &dlnaRenderer{
+				id:          id,
+				name:        name,
+				location:    base,
+				controlURL:  control.String(),
+				serviceType: strings.TrimSpace(s.Type),
+			}, nil
+		}
+	}
+	return nil, errors.New("the device has no AVTransport service")
+}
@@ -0,0 +1,236 @@
+package cast
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Multicast address of mDNS
+const mdnsAddr = "224.0.0.251:5353"
+
+// Types of the DNS records read
+const (
+	typeA   = 1
+	typePTR = 12
+	typeTXT = 16
+	typeSRV = 33
+)
+
+// service : An instance of a DNS-SD service answering the query
+type service struct {
+	// Instance name, like "Living-Room-<id>._googlecast._tcp.local"
+	name string
+	addr *net.TCPAddr
+	txt  map[string]string
+}
+
+// browse returns the instances of the DNS-SD service, like "_googlecast._tcp.local",
+// answering within timeout.
+//
+// The query is sent from another port than the mDNS one, which makes the responders answer it
+// directly, so it works next to the mDNS responder of the system.
+func browse(serviceName string, timeout time.Duration) ([]service, error) {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	addr, err := net.ResolveUDPAddr("udp4", mdnsAddr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteTo(dnsQuery(serviceName, typePTR), addr); err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	type srv struct {
+		target string
+		port   int
+		// Address answering, the one of the host if it does not tell it
+		from net.IP
+	}
+	var (
+		instances []string
+		srvs      = make(map[string]srv)
+		txts      = make(map[string]map[string]string)
+		hosts     = make(map[string]net.IP)
+	)
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// The deadline ends the search
+			break
+		}
+		records, err := parseDNS(buf[:n])
+		if err != nil {
+			continue
+		}
+		for _, r := range records {
+			switch r.typ {
+			case typePTR:
+				if strings.EqualFold(r.name, serviceName) && !slices.Contains(instances, r.target) {
+					instances = append(instances, r.target)
+				}
+			case typeSRV:
+				srvs[r.name] = srv{strings.ToLower(r.target), r.port, from.IP}
+			case typeTXT:
+				txts[r.name] = r.txt
+			case typeA:
+				hosts[strings.ToLower(r.name)] = r.ip
+			}
+		}
+	}
+
+	var services []service
+	for _, name := range instances {
+		s, ok := srvs[name]
+		if !ok {
+			continue
+		}
+		ip := s.from
+		if host, ok := hosts[s.target]; ok {
+			ip = host
+		}
+		services = append(services, service{name: name, addr: &net.TCPAddr{IP: ip, Port: s.port}, txt: txts[name]})
+	}
+	return services, nil
+}
+
+// dnsQuery returns a DNS query for the records of the type of name.
+func dnsQuery(name string, typ uint16) []byte {
+	// Id, flags, one question and no records
+	b := make([]byte, 12)
+	binary.BigEndian.PutUint16(b[4:], 1)
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	b = append(b, 0)
+	b = binary.BigEndian.AppendUint16(b, typ)
+	return binary.BigEndian.AppendUint16(b, 1) // IN
+}
+
+// dnsRecord : A record of a DNS answer, with the data of its type
+type dnsRecord struct {
+	name string
+	typ  uint16
+
+	// target of PTR and SRV records, port of SRV ones, ip of A ones and txt of TXT ones
+	target string
+	port   int
+	ip     net.IP
+	txt    map[string]string
+}
+
+var errBadDNS = errors.New("malformed DNS message")
+
+// parseDNS returns the records of every section of a DNS answer.
+func parseDNS(msg []byte) ([]dnsRecord, error) {
+	if len(msg) < 12 {
+		return nil, errBadDNS
+	}
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	count := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) +
+		int(binary.BigEndian.Uint16(msg[10:]))
+
+	off := 12
+	for range questions {
+		_, next, err := readName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		off = next + 4
+	}
+
+	var records []dnsRecord
+	for range count {
+		name, next, err := readName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		if next+10 > len(msg) {
+			return nil, errBadDNS
+		}
+		r := dnsRecord{name: name, typ: binary.BigEndian.Uint16(msg[next:])}
+		length := int(binary.BigEndian.Uint16(msg[next+8:]))
+		start := next + 10
+		end := start + length
+		if end > len(msg) {
+			return nil, errBadDNS
+		}
+		data := msg[start:end]
+
+		switch r.typ {
+		case typePTR:
+			r.target, _, err = readName(msg, start)
+		case typeSRV:
+			if length < 7 {
+				return nil, errBadDNS
+			}
+			r.port = int(binary.BigEndian.Uint16(data[4:]))
+			r.target, _, err = readName(msg, start+6)
+		case typeA:
+			if length == net.IPv4len {
+				r.ip = net.IP(append([]byte(nil), data...))
+			}
+		case typeTXT:
+			r.txt = make(map[string]string)
+			for len(data) > 0 {
+				n := int(data[0])
+				if 1+n > len(data) {
+					break
+				}
+				key, value, _ := strings.Cut(string(data[1:1+n]), "=")
+				r.txt[strings.ToLower(key)] = value
+				data = data[1+n:]
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+		off = end
+	}
+	return records, nil
+}
+
+// readName returns the name at off in the message, following its compression pointers, and
+// the offset after it.
+func readName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, errBadDNS
+		}
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case n&0xc0 == 0xc0:
+			if off+1 >= len(msg) || jumps > 16 {
+				return "", 0, errBadDNS
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+			jumps++
+		default:
+			if off+1+n > len(msg) {
+				return "", 0, errBadDNS
+			}
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
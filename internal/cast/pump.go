@@ -0,0 +1,127 @@
+package cast
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// Quality of the conversion to the rate of the sink, the one of the engine
+const resampleQuality = 4
+
+// pump : The backend pulling the audio of the engine at the pace it is played, without a sound
+// card setting it, and giving it to a sink chunk by chunk
+type pump struct {
+	// Rate of the engine
+	rate beep.SampleRate
+
+	// Streamers played, locked while pulled or changed
+	mu    sync.Mutex
+	mixer beep.Mixer
+
+	done chan struct{}
+}
+
+// newPump starts pulling the audio played at rate, size samples at a time, giving it to sink
+// converted to the rate out. It keeps pulling while the sink has nowhere to send the audio, so
+// the playback goes on while a renderer connects.
+func newPump(rate, out beep.SampleRate, size int, sink func([][2]float64)) *pump {
+	p := &pump{rate: rate, done: make(chan struct{})}
+	var source beep.Streamer = &p.mixer
+	if out != rate {
+		source = beep.Resample(resampleQuality, rate, out, source)
+		size = max(1, size*int(out)/int(rate))
+	}
+	go p.run(source, out, size, sink)
+	return p
+}
+
+func (p *pump) run(source beep.Streamer, rate beep.SampleRate, size int, sink func([][2]float64)) {
+	samples := make([][2]float64, size)
+	period := time.Duration(float64(time.Second) * float64(size) / float64(rate))
+	next := time.Now()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-time.After(time.Until(next)):
+		}
+		next = next.Add(period)
+		if late := time.Since(next); late > time.Second {
+			// Catching up after the system slept would flood the sink
+			next = next.Add(late)
+		}
+
+		p.mu.Lock()
+		source.Stream(samples)
+		p.mu.Unlock()
+		sink(samples)
+	}
+}
+
+func (p *pump) Init(rate beep.SampleRate, bufferSize int) error {
+	if rate != p.rate {
+		return fmt.Errorf("the output plays at %d Hz", p.rate)
+	}
+	return nil
+}
+
+func (p *pump) Play(s beep.Streamer) {
+	p.mu.Lock()
+	p.mixer.Add(s)
+	p.mu.Unlock()
+}
+
+func (p *pump) Clear() {
+	p.mu.Lock()
+	p.mixer.Clear()
+	p.mu.Unlock()
+}
+
+func (p *pump) Lock() {
+	p.mu.Lock()
+}
+
+func (p *pump) Unlock() {
+	p.mu.Unlock()
+}
+
+// stop stops pulling the audio, reporting whether it was still pulled.
+func (p *pump) stop() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		close(p.done)
+		return true
+	}
+}
+
+// encode returns the samples as little-endian signed PCM of bits per sample, mixed down if
+// there is one channel.
+func encode(samples [][2]float64, bits, channels int) []byte {
+	b := make([]byte, 0, len(samples)*channels*bits/8)
+	scale := math.Pow(2, float64(bits-1)) - 1
+	for _, sample := range samples {
+		values := sample[:]
+		if channels == 1 {
+			values = []float64{(sample[0] + sample[1]) / 2}
+		}
+		for _, v := range values {
+			n := int32(math.Round(max(-1, min(v, 1)) * scale))
+			switch bits {
+			case 16:
+				b = binary.LittleEndian.AppendUint16(b, uint16(int16(n)))
+			case 24:
+				b = append(b, byte(n), byte(n>>8), byte(n>>16))
+			case 32:
+				b = binary.LittleEndian.AppendUint32(b, uint32(n))
+			}
+		}
+	}
+	return b
+}
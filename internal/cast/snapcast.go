@@ -0,0 +1,201 @@
+package cast
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+const (
+	// Name of the Snapcast output device
+	snapcastDevice = "snapcast"
+
+	// Prefix of the sources of the Snapservers listening on TCP
+	tcpPrefix = "tcp://"
+
+	// Time waited before connecting again to the source, and the longest a connection takes
+	snapcastRetry   = 3 * time.Second
+	snapcastTimeout = 5 * time.Second
+	// Chunks of audio waiting for the source before they are dropped
+	snapcastBacklog = 16
+)
+
+// SampleFormat : The format of the PCM read by a Snapcast source, "rate:bits:channels" in the
+// config of the Snapserver
+type SampleFormat struct {
+	Rate     int
+	Bits     int
+	Channels int
+}
+
+// DefaultSampleFormat is the format of the Snapserver sources not setting one.
+var DefaultSampleFormat = SampleFormat{Rate: 48000, Bits: 16, Channels: 2}
+
+// ParseSampleFormat parses a format like "48000:16:2", of 16, 24 or 32 bits and one or two
+// channels.
+func ParseSampleFormat(s string) (SampleFormat, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return SampleFormat{}, fmt.Errorf("bad sample format %q, want rate:bits:channels", s)
+	}
+	var values [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return SampleFormat{}, fmt.Errorf("bad sample format %q, want rate:bits:channels", s)
+		}
+		values[i] = n
+	}
+	f := SampleFormat{Rate: values[0], Bits: values[1], Channels: values[2]}
+	switch {
+	case f.Rate < 8000 || f.Rate > 384000:
+		return SampleFormat{}, fmt.Errorf("bad sample rate %d", f.Rate)
+	case f.Bits != 16 && f.Bits != 24 && f.Bits != 32:
+		return SampleFormat{}, fmt.Errorf("bad sample size %d, want 16, 24 or 32 bits", f.Bits)
+	case f.Channels != 1 && f.Channels != 2:
+		return SampleFormat{}, fmt.Errorf("bad channel count %d, want 1 or 2", f.Channels)
+	}
+	return f, nil
+}
+
+func (f SampleFormat) String() string {
+	return fmt.Sprintf("%d:%d:%d", f.Rate, f.Bits, f.Channels)
+}
+
+// snapcast : The backend writing the audio to a source of a Snapserver, which plays it in sync
+// on every Snapcast client of the house. The source is a named pipe the server reads, or the
+// address of the TCP server it listens on as "tcp://host:port".
+type snapcast struct {
+	*pump
+
+	source string
+	chunks chan []byte
+}
+
+var _ engine.Backend = (*snapcast)(nil)
+
+// newSnapcast starts writing the audio played at rate, pulled size samples at a time, to the
+// source in the format. It connects in background, again each time the connection is lost, and
+// drops the audio meanwhile.
+func newSnapcast(source string, format SampleFormat, rate beep.SampleRate, size int) *snapcast {
+	s := &snapcast{source: source, chunks: make(chan []byte, snapcastBacklog)}
+	s.pump = newPump(rate, beep.SampleRate(format.Rate), size, func(samples [][2]float64) {
+		select {
+		case s.chunks <- encode(samples, format.Bits, format.Channels):
+		default:
+		}
+	})
+	go s.write()
+	return s
+}
+
+// write writes the chunks to the source, connecting to it until stopped.
+func (s *snapcast) write() {
+	failing := false
+	for {
+		w, err := s.open()
+		select {
+		case <-s.done:
+			if w != nil {
+				w.Close()
+			}
+			return
+		default:
+		}
+		if err != nil {
+			// A Snapserver not running is told once, not every few seconds
+			if !failing {
+				slog.Warn("Cannot open the Snapcast source", "source", s.source, "err", err)
+				failing = true
+			}
+			select {
+			case <-s.done:
+				return
+			case <-time.After(snapcastRetry):
+			}
+			continue
+		}
+		failing = false
+		slog.Debug("Playing on the Snapcast source", "source", s.source)
+
+		// The chunks dropped while connecting are older than the ones played after them
+		for len(s.chunks) > 0 {
+			<-s.chunks
+		}
+		// Closing the connection ends the write waiting for the Snapserver
+		copied := make(chan struct{})
+		go func() {
+			select {
+			case <-s.done:
+				w.Close()
+			case <-copied:
+			}
+		}()
+		err = s.copy(w)
+		close(copied)
+		w.Close()
+		if err == nil {
+			return
+		}
+		slog.Warn("Lost the Snapcast source", "source", s.source, "err", err)
+	}
+}
+
+// open connects to the TCP server of the source, or opens its pipe, failing if the Snapserver
+// is not reading it.
+func (s *snapcast) open() (io.WriteCloser, error) {
+	if addr, ok := strings.CutPrefix(s.source, tcpPrefix); ok {
+		conn, err := net.DialTimeout("tcp", addr, snapcastTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+	info, err := os.Stat(s.source)
+	if err != nil {
+		return nil, err
+	}
+	// Writing to a regular file would fill the disk
+	if info.Mode().IsRegular() {
+		return nil, errors.New("the source is a regular file, not a pipe")
+	}
+	f, err := os.OpenFile(s.source, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// copy writes the chunks to w until stopped, returning nil, or until a write fails.
+func (s *snapcast) copy(w io.Writer) error {
+	for {
+		select {
+		case <-s.done:
+			return nil
+		case chunk := <-s.chunks:
+			if _, err := w.Write(chunk); err != nil {
+				select {
+				case <-s.done:
+					return nil
+				default:
+					return err
+				}
+			}
+		}
+	}
+}
+
+// Close stops writing the audio and disconnects from the source.
+func (s *snapcast) Close() {
+	s.stop()
+}
@@ -0,0 +1,167 @@
+package cast
+
+import (
+	"encoding/binary"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+const (
+	// Path of the stream on its server
+	streamPath = "/stream.wav"
+	// Chunks of audio waiting for a slow client before it is dropped
+	clientBacklog = 64
+
+	// Format of the stream: 16-bit stereo PCM
+	channels       = 2
+	bytesPerSample = 2
+)
+
+// stream : The backend serving the audio as an endless WAV stream over HTTP
+type stream struct {
+	*pump
+
+	listener net.Listener
+	server   *http.Server
+
+	// Channels of the clients, given each chunk
+	clientsMu sync.Mutex
+	clients   map[chan []byte]struct{}
+}
+
+var _ engine.Backend = (*stream)(nil)
+
+// newStream starts serving the audio at rate, pulled size samples at a time.
+func newStream(rate beep.SampleRate, size int) (*stream, error) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return nil, err
+	}
+	s := &stream{
+		listener: listener,
+		clients:  make(map[chan []byte]struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(streamPath, s.serve)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	s.pump = newPump(rate, rate, size, func(samples [][2]float64) {
+		s.broadcast(encode(samples, bytesPerSample*8, channels))
+	})
+	go s.server.Serve(listener)
+	return s, nil
+}
+
+// URL returns the address of the stream for the renderer at host, on the local address it
+// reaches the renderer from.
+func (s *stream) URL(host string) (string, error) {
+	// Nothing is sent over UDP, it only picks the route
+	conn, err := net.Dial("udp", host)
+	if err != nil {
+		return "", err
+	}
+	local := conn.LocalAddr().(*net.UDPAddr).IP
+	conn.Close()
+	port := s.listener.Addr().(*net.TCPAddr).Port
+	return "http://" + net.JoinHostPort(local.String(), strconv.Itoa(port)) + streamPath, nil
+}
+
+// broadcast gives the chunk to every client, dropping the ones too slow to take it.
+func (s *stream) broadcast(chunk []byte) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	for c := range s.clients {
+		select {
+		case c <- chunk:
+		default:
+			slog.Warn("Dropping a client of the stream, it cannot keep up")
+			delete(s.clients, c)
+			close(c)
+		}
+	}
+}
+
+// serve writes the header of the stream, then the audio as it is played.
+func (s *stream) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", "audio/wav")
+	h.Set("Cache-Control", "no-cache")
+	// The DLNA renderers are told it is a live stream, which cannot be seeked
+	h.Set("transferMode.dlna.org", "Streaming")
+	h.Set("contentFeatures.dlna.org", "DLNA.ORG_OP=00;DLNA.ORG_CI=0;DLNA.ORG_FLAGS=01700000000000000000000000000000")
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	c := make(chan []byte, clientBacklog)
+	s.clientsMu.Lock()
+	s.clients[c] = struct{}{}
+	s.clientsMu.Unlock()
+	defer func() {
+		s.clientsMu.Lock()
+		if _, ok := s.clients[c]; ok {
+			delete(s.clients, c)
+			close(c)
+		}
+		s.clientsMu.Unlock()
+	}()
+	slog.Debug("A renderer connected to the stream", "addr", r.RemoteAddr)
+
+	flusher, _ := w.(http.Flusher)
+	if _, err := w.Write(wavHeader(s.rate)); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case chunk, ok := <-c:
+			if !ok {
+				return
+			}
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// wavHeader returns the header of a WAV file at rate, as long as the header allows since the
+// stream has no end.
+func wavHeader(rate beep.SampleRate) []byte {
+	const dataSize = math.MaxUint32 - 36
+	blockAlign := channels * bytesPerSample
+	b := []byte("RIFF")
+	b = binary.LittleEndian.AppendUint32(b, 36+dataSize)
+	b = append(b, "WAVEfmt "...)
+	b = binary.LittleEndian.AppendUint32(b, 16)
+	b = binary.LittleEndian.AppendUint16(b, 1) // PCM
+	b = binary.LittleEndian.AppendUint16(b, channels)
+	b = binary.LittleEndian.AppendUint32(b, uint32(rate))
+	b = binary.LittleEndian.AppendUint32(b, uint32(int(rate)*blockAlign))
+	b = binary.LittleEndian.AppendUint16(b, uint16(blockAlign))
+	b = binary.LittleEndian.AppendUint16(b, bytesPerSample*8)
+	b = append(b, "data"...)
+	return binary.LittleEndian.AppendUint32(b, dataSize)
+}
+
+// Close stops pulling the audio and disconnects the clients.
+func (s *stream) Close() {
+	if s.stop() {
+		s.server.Close()
+	}
+}
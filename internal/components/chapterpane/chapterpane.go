@@ -0,0 +1,166 @@
+// Package chapterpane lists the chapters of the playing track, like the ones of
+// audiobooks and podcasts, and seeks to the chosen one.
+package chapterpane
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/components/player"
+	"github.com/nicolito128/tempo/internal/styles"
+	"github.com/nicolito128/tempo/internal/tags"
+)
+
+// Number of chapters displayed at once
+const VisibleLines int = 11
+
+// SeekMsg asks to play the current track from the start of the chosen chapter.
+type SeekMsg struct {
+	Start time.Duration
+}
+
+// Pane : A list of the chapters of the playing track
+type Pane struct {
+	// visible if the pane is shown instead of the browser
+	visible bool
+
+	// Chapter under the cursor
+	cursor int
+
+	// Return the chapters of the playing track and the index of the one being played
+	chapters func() []tags.Chapter
+	current  func() int
+
+	width int
+}
+
+var _ tea.Model = (*Pane)(nil)
+
+// New creates a hidden pane listing the chapters returned by the functions.
+func New(chapters func() []tags.Chapter, current func() int) *Pane {
+	p := new(Pane)
+	p.chapters = chapters
+	p.current = current
+	return p
+}
+
+// Visible reports whether the pane is shown.
+func (p *Pane) Visible() bool {
+	return p.visible
+}
+
+// Toggle shows or hides the pane. Showing it moves the cursor to the chapter being played.
+func (p *Pane) Toggle() {
+	p.visible = !p.visible
+	p.cursor = max(p.current(), 0)
+}
+
+// Hide hides the pane.
+func (p *Pane) Hide() {
+	p.visible = false
+}
+
+// SetWidth sets the width available to the titles, which are cut if longer.
+func (p *Pane) SetWidth(width int) {
+	p.width = width
+}
+
+func (p *Pane) Init() tea.Cmd {
+	return nil
+}
+
+// Captures reports whether the pane handles the key, which moves the cursor or plays
+// the chapter under it.
+func (p *Pane) Captures(msg tea.KeyMsg) bool {
+	if !p.visible || len(p.chapters()) == 0 {
+		return false
+	}
+	switch msg.String() {
+	case "up", "k", "down", "j", "pgup", "pgdown", "home", "end", "enter":
+		return true
+	}
+	return false
+}
+
+func (p *Pane) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, nil
+	}
+
+	chapters := p.chapters()
+	switch key.String() {
+	case "up", "k":
+		p.cursor--
+	case "down", "j":
+		p.cursor++
+	case "pgup":
+		p.cursor -= VisibleLines
+	case "pgdown":
+		p.cursor += VisibleLines
+	case "home":
+		p.cursor = 0
+	case "end":
+		p.cursor = len(chapters) - 1
+	case "enter":
+		if p.cursor < len(chapters) {
+			start := chapters[p.cursor].Start
+			return p, func() tea.Msg { return SeekMsg{Start: start} }
+		}
+	}
+	p.cursor = max(min(p.cursor, len(chapters)-1), 0)
+	return p, nil
+}
+
+func (p *Pane) View() string {
+	if !p.visible {
+		return ""
+	}
+
+	grey := lipgloss.NewStyle().Foreground(styles.GreyColor())
+	help := styles.Help("\nℹ: ⏶/⏷ (move) | Enter (play chapter) | [/] (previous/next chapter) | C (hide chapters) | Tab (browser)")
+
+	chapters := p.chapters()
+	lines := []string{"Chapters", ""}
+	if len(chapters) == 0 {
+		lines = append(lines, grey.Render("The track has no chapters"))
+		return strings.Join(lines, "\n") + help
+	}
+	lines[0] += grey.Render(fmt.Sprintf(" %d", len(chapters)))
+
+	// The chapters of the previous track may have had more entries
+	cursor := min(p.cursor, len(chapters)-1)
+	current := p.current()
+
+	start := max(cursor-VisibleLines/2, 0)
+	start = max(min(start, len(chapters)-VisibleLines), 0)
+	end := min(start+VisibleLines, len(chapters))
+
+	cut := lipgloss.NewStyle()
+	if p.width > 0 {
+		cut = cut.MaxWidth(p.width)
+	}
+	for i := start; i < end; i++ {
+		title := chapters[i].Title
+		if title == "" {
+			title = fmt.Sprintf("Chapter %d", i+1)
+		}
+		text := fmt.Sprintf("%s  %s", player.FormatSecondsToString(chapters[i].Start), title)
+
+		marker := "  "
+		if i == current {
+			marker = "♪ "
+		}
+		switch {
+		case i == cursor:
+			text = styles.PrimaryHighlight(" " + text + " ")
+		case i < current:
+			text = grey.Render(text)
+		}
+		lines = append(lines, cut.Render(marker+text))
+	}
+	return strings.Join(lines, "\n") + help
+}
@@ -0,0 +1,154 @@
+// Package devicepane lists the audio output devices and switches the playback to the
+// chosen one.
+package devicepane
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/styles"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// SelectMsg asks to play on the chosen device.
+type SelectMsg struct {
+	Device engine.Device
+}
+
+// Pane : A list of the audio output devices
+type Pane struct {
+	// visible if the pane is shown instead of the browser
+	visible bool
+
+	// Devices listed when the pane was shown, and the error listing them
+	devices []engine.Device
+	err     error
+
+	// Device under the cursor
+	cursor int
+
+	// Return the devices available and the name of the one chosen
+	list    func() ([]engine.Device, error)
+	current func() string
+
+	width int
+}
+
+var _ tea.Model = (*Pane)(nil)
+
+// New creates a hidden pane listing the devices returned by the functions.
+func New(list func() ([]engine.Device, error), current func() string) *Pane {
+	p := new(Pane)
+	p.list = list
+	p.current = current
+	return p
+}
+
+// Visible reports whether the pane is shown.
+func (p *Pane) Visible() bool {
+	return p.visible
+}
+
+// Toggle shows or hides the pane. Showing it lists the devices again, since they can be
+// plugged in at any time, and moves the cursor to the chosen one.
+func (p *Pane) Toggle() {
+	p.visible = !p.visible
+	if !p.visible {
+		return
+	}
+
+	p.devices, p.err = p.list()
+	p.cursor = 0
+	current := p.current()
+	for i, d := range p.devices {
+		if d.Name == current {
+			p.cursor = i
+		}
+	}
+}
+
+// Hide hides the pane.
+func (p *Pane) Hide() {
+	p.visible = false
+}
+
+// SetWidth sets the width available to the names, which are cut if longer.
+func (p *Pane) SetWidth(width int) {
+	p.width = width
+}
+
+func (p *Pane) Init() tea.Cmd {
+	return nil
+}
+
+// Captures reports whether the pane handles the key, which moves the cursor or chooses
+// the device under it.
+func (p *Pane) Captures(msg tea.KeyMsg) bool {
+	if !p.visible || len(p.devices) == 0 {
+		return false
+	}
+	switch msg.String() {
+	case "up", "k", "down", "j", "home", "end", "enter":
+		return true
+	}
+	return false
+}
+
+func (p *Pane) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, nil
+	}
+
+	switch key.String() {
+	case "up", "k":
+		p.cursor--
+	case "down", "j":
+		p.cursor++
+	case "home":
+		p.cursor = 0
+	case "end":
+		p.cursor = len(p.devices) - 1
+	case "enter":
+		if p.cursor < len(p.devices) {
+			d := p.devices[p.cursor]
+			return p, func() tea.Msg { return SelectMsg{Device: d} }
+		}
+	}
+	p.cursor = max(min(p.cursor, len(p.devices)-1), 0)
+	return p, nil
+}
+
+func (p *Pane) View() string {
+	if !p.visible {
+		return ""
+	}
+
+	grey := lipgloss.NewStyle().Foreground(styles.GreyColor())
+	help := styles.Help("\nℹ: ⏶/⏷ (move) | Enter (play on device) | D (hide devices) | Tab (browser)")
+
+	lines := []string{"Output devices", ""}
+	if p.err != nil {
+		lines = append(lines, grey.Render("Cannot list the devices: "+p.err.Error()))
+		return strings.Join(lines, "\n") + help
+	}
+
+	cut := lipgloss.NewStyle()
+	if p.width > 0 {
+		cut = cut.MaxWidth(p.width)
+	}
+	current := p.current()
+	for i, d := range p.devices {
+		text := d.String()
+		marker := "  "
+		if d.Name == current {
+			marker = "♪ "
+		}
+		if i == p.cursor {
+			text = styles.PrimaryHighlight(" " + text + " ")
+		}
+		lines = append(lines, cut.Render(marker+text))
+	}
+	return strings.Join(lines, "\n") + help
+}
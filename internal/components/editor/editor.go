@@ -0,0 +1,550 @@
+package editor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/musicbrainz"
+	"github.com/nicolito128/tempo/internal/styles"
+	"github.com/nicolito128/tempo/internal/tags"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// SavedMsg is sent when the tags of a file are written, or fail to be.
+type SavedMsg struct {
+	Path string
+	Tags tags.Tags
+	Err  error
+}
+
+// ProgressMsg is sent after each file of a batch edit is written, or fails to be.
+// It should be followed by a call to Wait until the DoneMsg arrives.
+type ProgressMsg struct {
+	SavedMsg
+
+	// Files written so far, and in total
+	Done  int
+	Total int
+}
+
+// DoneMsg is sent when every file of a batch edit was written.
+type DoneMsg struct {
+	Saved int
+
+	// Errors of the files that could not be written
+	Errors []error
+}
+
+// LookupMsg carries the metadata found online for the file being edited.
+type LookupMsg struct {
+	Path    string
+	Matches []musicbrainz.Match
+	Err     error
+}
+
+// How long an online lookup may take, including the wait for the rate limit
+const lookupTimeout time.Duration = 30 * time.Second
+
+// Tags edited by the form
+const (
+	titleField  string = "Title"
+	artistField string = "Artist"
+	albumField  string = "Album"
+	genreField  string = "Genre"
+	trackField  string = "Track"
+	numberField string = "Number"
+)
+
+// field : A tag edited with a text input
+type field struct {
+	name  string
+	input textinput.Model
+}
+
+// Editor : A form to fix the tags of one or several audio files and write them back
+type Editor struct {
+	// Files being edited, in order
+	paths []string
+
+	// Tags read from the file when editing a single one, the ones not in the form are kept
+	tags tags.Tags
+
+	fields []field
+
+	// Index of the focused field
+	focus int
+
+	// active if the form is shown
+	active bool
+
+	// saving if the tags are being written
+	saving bool
+
+	// Progress of a batch edit
+	events chan tea.Msg
+	done   int
+
+	// Online lookup of the metadata, disabled if nil
+	musicbrainz *musicbrainz.Client
+
+	// looking if the lookup is running, and choosing if its matches are shown
+	looking  bool
+	choosing bool
+	matches  []musicbrainz.Match
+	choice   int
+
+	err error
+}
+
+var _ tea.Model = (*Editor)(nil)
+
+func New() *Editor {
+	return new(Editor)
+}
+
+// SetLookup sets the MusicBrainz client used to look up the metadata of a file.
+func (e *Editor) SetLookup(c *musicbrainz.Client) {
+	e.musicbrainz = c
+}
+
+// Open shows the form with the tags of the file at path.
+func (e *Editor) Open(path string) tea.Cmd {
+	t, err := tags.ReadFile(path)
+	if errors.Is(err, tags.ErrNoTags) {
+		err = nil
+	}
+
+	e.reset([]string{path}, titleField, artistField, albumField, trackField)
+	e.tags = t
+	e.err = err
+
+	e.setValue(titleField, t.Title)
+	e.setValue(artistField, t.Artist)
+	e.setValue(albumField, t.Album)
+	e.setValue(trackField, position(t.Track, t.TrackTotal))
+	e.input(trackField).Placeholder = "3 or 3/12"
+	return e.setFocus(0)
+}
+
+// OpenBatch shows the form to set the same artist, album or genre to every file, and
+// number them in the given order. The values shared by every file are filled in.
+func (e *Editor) OpenBatch(paths []string) tea.Cmd {
+	if len(paths) == 1 {
+		return e.Open(paths[0])
+	}
+
+	e.reset(paths, artistField, albumField, genreField, numberField)
+	e.tags = tags.Tags{}
+
+	var all []tags.Tags
+	for _, path := range paths {
+		t, _ := tags.ReadFile(path)
+		all = append(all, t)
+	}
+	e.setValue(artistField, common(all, func(t tags.Tags) string { return t.Artist }))
+	e.setValue(albumField, common(all, func(t tags.Tags) string { return t.Album }))
+	e.setValue(genreField, common(all, func(t tags.Tags) string { return t.Genre }))
+
+	for _, name := range []string{artistField, albumField, genreField} {
+		e.input(name).Placeholder = "unchanged"
+	}
+	e.input(numberField).Placeholder = "first track number, unchanged if empty"
+	return e.setFocus(0)
+}
+
+// reset clears the form to edit the files with the given fields.
+func (e *Editor) reset(paths []string, names ...string) {
+	e.paths = paths
+	e.fields = nil
+	for _, name := range names {
+		input := textinput.New()
+		input.Prompt = ""
+		e.fields = append(e.fields, field{name: name, input: input})
+	}
+	e.focus = 0
+	e.active = true
+	e.saving = false
+	e.events = nil
+	e.done = 0
+	e.looking = false
+	e.choosing = false
+	e.matches = nil
+	e.err = nil
+}
+
+// input returns the input of the field with the given name.
+func (e *Editor) input(name string) *textinput.Model {
+	for i := range e.fields {
+		if e.fields[i].name == name {
+			return &e.fields[i].input
+		}
+	}
+	return nil
+}
+
+func (e *Editor) setValue(name, value string) {
+	input := e.input(name)
+	input.SetValue(value)
+	input.CursorEnd()
+}
+
+func (e *Editor) value(name string) string {
+	return strings.TrimSpace(e.input(name).Value())
+}
+
+// Close hides the form without saving.
+func (e *Editor) Close() {
+	e.active = false
+	if len(e.fields) > 0 {
+		e.fields[e.focus].input.Blur()
+	}
+}
+
+// Active reports whether the form is shown.
+func (e *Editor) Active() bool {
+	return e.active
+}
+
+func (e *Editor) Init() tea.Cmd {
+	return nil
+}
+
+func (e *Editor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case SavedMsg:
+		if !e.active || len(e.paths) != 1 || msg.Path != e.paths[0] {
+			return e, nil
+		}
+		e.saving = false
+		if msg.Err != nil {
+			e.err = msg.Err
+			return e, nil
+		}
+		e.Close()
+		return e, nil
+
+	case ProgressMsg:
+		e.done = msg.Done
+		return e, e.Wait()
+
+	case DoneMsg:
+		e.events = nil
+		e.Close()
+		return e, nil
+
+	case LookupMsg:
+		if !e.active || len(e.paths) != 1 || msg.Path != e.paths[0] {
+			return e, nil
+		}
+		e.looking = false
+		switch {
+		case msg.Err != nil:
+			e.err = msg.Err
+		case len(msg.Matches) == 0:
+			e.err = errors.New("no matches found in MusicBrainz")
+		default:
+			e.matches = msg.Matches
+			e.choice = 0
+			e.choosing = true
+		}
+		return e, nil
+
+	case tea.KeyMsg:
+		if !e.active || e.saving {
+			return e, nil
+		}
+		if e.choosing {
+			e.updateChoice(msg)
+			return e, nil
+		}
+		switch msg.String() {
+		case "esc":
+			e.Close()
+			return e, nil
+
+		case "tab", "down":
+			return e, e.setFocus((e.focus + 1) % len(e.fields))
+
+		case "shift+tab", "up":
+			return e, e.setFocus((e.focus + len(e.fields) - 1) % len(e.fields))
+
+		case "enter":
+			if len(e.paths) > 1 {
+				return e, e.saveBatch()
+			}
+			return e, e.save()
+
+		case "ctrl+l":
+			return e, e.lookup()
+		}
+	}
+
+	if !e.active || len(e.fields) == 0 {
+		return e, nil
+	}
+	var cmd tea.Cmd
+	e.fields[e.focus].input, cmd = e.fields[e.focus].input.Update(msg)
+	return e, cmd
+}
+
+// updateChoice handles the keys used to choose one of the matches found online.
+func (e *Editor) updateChoice(msg tea.KeyMsg) {
+	switch msg.String() {
+	case "esc":
+		e.choosing = false
+
+	case "up", "k":
+		e.choice = max(e.choice-1, 0)
+
+	case "down", "j":
+		e.choice = min(e.choice+1, len(e.matches)-1)
+
+	case "enter":
+		t := e.matches[e.choice].Tags
+		e.setValue(titleField, t.Title)
+		e.setValue(artistField, t.Artist)
+		e.setValue(albumField, t.Album)
+		e.setValue(trackField, position(t.Track, t.TrackTotal))
+		e.choosing = false
+	}
+}
+
+// lookup searches MusicBrainz in background with the values of the form.
+func (e *Editor) lookup() tea.Cmd {
+	if e.musicbrainz == nil || len(e.paths) != 1 || e.looking {
+		return nil
+	}
+
+	t := tags.Tags{Title: e.value(titleField), Artist: e.value(artistField), Album: e.value(albumField)}
+	e.err = nil
+	e.looking = true
+
+	path, client := e.paths[0], e.musicbrainz
+	return func() tea.Msg {
+		// The length is only a hint, unknown if the file cannot be decoded
+		length, _ := engine.ProbeDuration(path)
+
+		ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+		defer cancel()
+		matches, err := client.Search(ctx, t, length)
+		return LookupMsg{Path: path, Matches: matches, Err: err}
+	}
+}
+
+// setFocus moves the cursor to the field at index i.
+func (e *Editor) setFocus(i int) tea.Cmd {
+	e.fields[e.focus].input.Blur()
+	e.focus = i
+	return e.fields[e.focus].input.Focus()
+}
+
+// save writes the tags of the form to the file in background.
+func (e *Editor) save() tea.Cmd {
+	t := e.tags
+	t.Title = e.value(titleField)
+	t.Artist = e.value(artistField)
+	t.Album = e.value(albumField)
+
+	track, total, err := parsePosition(e.value(trackField))
+	if err != nil {
+		e.err = err
+		return nil
+	}
+	t.Track, t.TrackTotal = track, total
+
+	e.err = nil
+	e.saving = true
+	path := e.paths[0]
+	return func() tea.Msg {
+		return SavedMsg{Path: path, Tags: t, Err: tags.WriteFile(path, t)}
+	}
+}
+
+// saveBatch writes the tags of the form to every file in background. The empty fields
+// are left unchanged, and the tracks are numbered in order from the given number.
+func (e *Editor) saveBatch() tea.Cmd {
+	artist, album, genre := e.value(artistField), e.value(albumField), e.value(genreField)
+
+	first := 0
+	if s := e.value(numberField); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			e.err = fmt.Errorf("invalid track number %q", s)
+			return nil
+		}
+		first = n
+	}
+
+	e.err = nil
+	e.saving = true
+	e.done = 0
+	e.events = make(chan tea.Msg, 1)
+
+	go func(paths []string, events chan<- tea.Msg) {
+		defer close(events)
+
+		var errs []error
+		for i, path := range paths {
+			t, err := tags.ReadFile(path)
+			if err == nil || errors.Is(err, tags.ErrNoTags) {
+				if artist != "" {
+					t.Artist = artist
+				}
+				if album != "" {
+					t.Album = album
+				}
+				if genre != "" {
+					t.Genre = genre
+				}
+				if first > 0 {
+					t.Track, t.TrackTotal = first+i, first+len(paths)-1
+				}
+				err = tags.WriteFile(path, t)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			}
+			events <- ProgressMsg{SavedMsg: SavedMsg{Path: path, Tags: t, Err: err}, Done: i + 1, Total: len(paths)}
+		}
+		events <- DoneMsg{Saved: len(paths) - len(errs), Errors: errs}
+	}(e.paths, e.events)
+
+	return e.Wait()
+}
+
+// Wait returns a command that waits for the next message of the running batch edit.
+func (e *Editor) Wait() tea.Cmd {
+	events := e.events
+	if events == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+func (e *Editor) View() string {
+	if !e.active {
+		return ""
+	}
+
+	grey := lipgloss.NewStyle().Foreground(styles.GreyColor())
+	label := lipgloss.NewStyle().Width(8).Foreground(styles.PrimaryColor())
+
+	title := "Edit tags " + grey.Render(filepath.Base(e.paths[0]))
+	if len(e.paths) > 1 {
+		title = "Edit tags " + grey.Render(fmt.Sprintf("%d files", len(e.paths)))
+	}
+
+	lines := []string{title, ""}
+	for _, f := range e.fields {
+		lines = append(lines, label.Render(f.name)+f.input.View())
+	}
+	lines = append(lines, "")
+
+	if e.choosing {
+		lines = append(lines, grey.Render("Matches found in MusicBrainz:"))
+		for i, m := range e.matches {
+			cursor := "  "
+			if i == e.choice {
+				cursor = lipgloss.NewStyle().Foreground(styles.PrimaryColor()).Render("> ")
+			}
+			lines = append(lines, cursor+describe(m))
+		}
+		s := strings.Join(lines, "\n")
+		s += styles.Help("\nℹ: ⏶/⏷ (choose) | Enter (fill in the form) | Esc (back)")
+		return s
+	}
+
+	switch {
+	case e.looking:
+		lines = append(lines, grey.Render("Looking up in MusicBrainz..."))
+	case e.saving && len(e.paths) > 1:
+		lines = append(lines, grey.Render(fmt.Sprintf("Writing tags... %s %d/%d", progressBar(e.done, len(e.paths)), e.done, len(e.paths))))
+	case e.saving:
+		lines = append(lines, grey.Render("Saving..."))
+	case e.err != nil:
+		lines = append(lines, lipgloss.NewStyle().Foreground(styles.ProblemColor()).Render("Error: "+e.err.Error()))
+	}
+
+	help := "\nℹ: Tab (next field) | Shift+Tab (previous field) | Enter (save) | Esc (cancel)"
+	if e.musicbrainz != nil && len(e.paths) == 1 {
+		help += " | ctrl+l (look up in MusicBrainz)"
+	}
+	return strings.Join(lines, "\n") + styles.Help(help)
+}
+
+// describe summarizes a match as "97% Artist – Title · Album (1997) · 2/12".
+func describe(m musicbrainz.Match) string {
+	s := fmt.Sprintf("%3d%% %s – %s", m.Score, m.Tags.Artist, m.Tags.Title)
+	if m.Tags.Album != "" {
+		s += " · " + m.Tags.Album
+		if m.Year > 0 {
+			s += fmt.Sprintf(" (%d)", m.Year)
+		}
+	}
+	if pos := position(m.Tags.Track, m.Tags.TrackTotal); pos != "" {
+		s += " · " + pos
+	}
+	return s
+}
+
+// progressBar draws the progress of a batch edit.
+func progressBar(done, total int) string {
+	const width = 20
+	filled := width * done / max(total, 1)
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("·", width-filled) + "]"
+}
+
+// common returns the value shared by every tag, or empty if they differ.
+func common(all []tags.Tags, value func(t tags.Tags) string) string {
+	if len(all) == 0 {
+		return ""
+	}
+	v := value(all[0])
+	for _, t := range all[1:] {
+		if value(t) != v {
+			return ""
+		}
+	}
+	return v
+}
+
+// position formats a track position like "3" or "3/12". Empty if unknown.
+func position(n, total int) string {
+	if n <= 0 {
+		return ""
+	}
+	if total > 0 {
+		return fmt.Sprintf("%d/%d", n, total)
+	}
+	return strconv.Itoa(n)
+}
+
+// parsePosition parses a track position like "3" or "3/12". Empty means unknown.
+func parsePosition(s string) (n, total int, err error) {
+	num, tot, hasTotal := strings.Cut(strings.TrimSpace(s), "/")
+	if num = strings.TrimSpace(num); num != "" {
+		if n, err = strconv.Atoi(num); err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("invalid track number %q", s)
+		}
+	}
+	if hasTotal {
+		if total, err = strconv.Atoi(strings.TrimSpace(tot)); err != nil || total < 0 {
+			return 0, 0, fmt.Errorf("invalid track total %q", s)
+		}
+	}
+	return n, total, nil
+}
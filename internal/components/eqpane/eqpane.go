@@ -0,0 +1,238 @@
+// Package eqpane shows the preamp and the bands of the equalizer and adjusts them one by
+// one, or switches to a preset or a profile of the config.
+package eqpane
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/components/player"
+	"github.com/nicolito128/tempo/internal/styles"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+const (
+	// Gain variation of a band with each key press, in dB
+	GainStep float64 = 1
+	// Width of the slider of a band, odd so 0 dB sits in the middle
+	sliderWidth int = 25
+)
+
+// ChangedMsg asks to play with the new curve of the equalizer.
+type ChangedMsg struct {
+	Curve player.Preset
+}
+
+// PreampMsg asks to play with the new gain in dB applied before the bands.
+type PreampMsg struct {
+	DB float64
+}
+
+// Pane : The sliders of the bands of the equalizer
+type Pane struct {
+	// visible if the pane is shown instead of the browser
+	visible bool
+
+	// Curve and preamp shown, read when the pane was shown and changed from it
+	curve  player.Preset
+	preamp float64
+
+	// Named curves of the config, and the custom one if there is one
+	profiles []player.Preset
+	custom   *engine.Gains
+
+	// Row under the cursor: the preamp first, then the bands
+	cursor int
+
+	// Return the curve and the preamp played
+	current       func() player.Preset
+	currentPreamp func() float64
+}
+
+var _ tea.Model = (*Pane)(nil)
+
+// New creates a hidden pane showing the curve and the preamp returned by the functions.
+func New(current func() player.Preset, preamp func() float64) *Pane {
+	p := new(Pane)
+	p.current = current
+	p.currentPreamp = preamp
+	return p
+}
+
+// SetProfiles sets the named curves switched to after the presets, and the custom curve
+// after them if not nil.
+func (p *Pane) SetProfiles(profiles []player.Preset, custom *engine.Gains) {
+	p.profiles = profiles
+	p.custom = custom
+}
+
+// Visible reports whether the pane is shown.
+func (p *Pane) Visible() bool {
+	return p.visible
+}
+
+// Toggle shows or hides the pane.
+func (p *Pane) Toggle() {
+	p.visible = !p.visible
+	if p.visible {
+		p.curve = p.current()
+		p.preamp = p.currentPreamp()
+	}
+}
+
+// Hide hides the pane.
+func (p *Pane) Hide() {
+	p.visible = false
+}
+
+func (p *Pane) Init() tea.Cmd {
+	return nil
+}
+
+// Captures reports whether the pane handles the key, which moves the cursor, changes the
+// preamp or the gain of the band under it, or switches to the next curve.
+func (p *Pane) Captures(msg tea.KeyMsg) bool {
+	if !p.visible {
+		return false
+	}
+	switch msg.String() {
+	case "up", "k", "down", "j", "left", "h", "right", "l", "0", "enter":
+		return true
+	}
+	return false
+}
+
+func (p *Pane) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, nil
+	}
+
+	var gain float64
+	switch key.String() {
+	case "up", "k":
+		p.cursor = max(p.cursor-1, 0)
+		return p, nil
+	case "down", "j":
+		p.cursor = min(p.cursor+1, len(p.curve.Gains))
+		return p, nil
+	case "enter":
+		curves := p.curves()
+		next := 0
+		for i, curve := range curves {
+			if curve.Name == p.curve.Name {
+				next = (i + 1) % len(curves)
+			}
+		}
+		p.curve = curves[next]
+		curve := p.curve
+		return p, func() tea.Msg { return ChangedMsg{Curve: curve} }
+	case "left", "h":
+		gain = -GainStep
+	case "right", "l":
+		gain = GainStep
+	}
+
+	if p.cursor == 0 {
+		p.preamp = max(min(p.preamp+gain, engine.MaxPreamp), -engine.MaxPreamp)
+		if key.String() == "0" {
+			p.preamp = 0
+		}
+		db := p.preamp
+		return p, func() tea.Msg { return PreampMsg{DB: db} }
+	}
+
+	band := p.cursor - 1
+	if key.String() == "0" {
+		p.setBand(band, 0)
+	} else {
+		p.setBand(band, max(min(p.curve.Gains[band]+gain, engine.MaxBandGain), -engine.MaxBandGain))
+	}
+	curve := p.curve
+	return p, func() tea.Msg { return ChangedMsg{Curve: curve} }
+}
+
+// setBand changes the gain of a band. The presets are kept as they are, so changing one of
+// them makes the custom curve, while a profile is changed itself.
+func (p *Pane) setBand(band int, gain float64) {
+	p.curve.Gains[band] = gain
+	if _, err := player.FindPreset(p.curve.Name); err == nil || p.curve.Name == player.Custom {
+		p.curve.Name = player.Custom
+		gains := p.curve.Gains
+		p.custom = &gains
+		return
+	}
+	for i := range p.profiles {
+		if p.profiles[i].Name == p.curve.Name {
+			p.profiles[i].Gains = p.curve.Gains
+		}
+	}
+}
+
+// curves returns the curves switched between: the presets, the profiles and the custom one.
+func (p *Pane) curves() []player.Preset {
+	curves := append(append([]player.Preset{}, player.Presets...), p.profiles...)
+	if p.custom != nil {
+		curves = append(curves, player.Preset{Name: player.Custom, Gains: *p.custom})
+	}
+	return curves
+}
+
+func (p *Pane) View() string {
+	if !p.visible {
+		return ""
+	}
+
+	grey := lipgloss.NewStyle().Foreground(styles.GreyColor())
+	help := styles.Help("\nℹ: ⏶/⏷ (preamp/band) | 🞀/🞂 (gain) | 0 (reset) | Enter (next curve) | E (hide equalizer) | Tab (browser)")
+
+	var names []string
+	for _, curve := range p.curves() {
+		if curve.Name == p.curve.Name {
+			names = append(names, styles.PrimaryHighlight(" "+curve.Name+" "))
+		} else {
+			names = append(names, grey.Render(" "+curve.Name+" "))
+		}
+	}
+
+	lines := []string{"Equalizer", "", strings.Join(names, " "), ""}
+	labels := []string{"pre"}
+	gains := []float64{p.preamp}
+	for i, freq := range engine.EqualizerBands {
+		labels = append(labels, formatFrequency(freq))
+		gains = append(gains, p.curve.Gains[i])
+	}
+	for i, gain := range gains {
+		label := fmt.Sprintf("%5s ", labels[i])
+		if i == p.cursor {
+			label = styles.PrimaryHighlight(label)
+		}
+		lines = append(lines, label+" "+slider(gain)+fmt.Sprintf(" %+3.0f dB", gain))
+		if i == 0 {
+			lines = append(lines, "")
+		}
+	}
+	return strings.Join(lines, "\n") + help
+}
+
+// slider draws a gain as a mark on a line, 0 dB in the middle.
+func slider(gain float64) string {
+	half := sliderWidth / 2
+	pos := half + int(math.Round(gain/engine.MaxBandGain*float64(half)))
+
+	track := []rune(strings.Repeat("─", sliderWidth))
+	track[half] = '┼'
+	mark := lipgloss.NewStyle().Foreground(styles.PrimaryColor()).Render("●")
+	return string(track[:pos]) + mark + string(track[pos+1:])
+}
+
+// formatFrequency writes the frequencies from 1000 Hz in kHz.
+func formatFrequency(freq float64) string {
+	if freq >= 1000 {
+		return fmt.Sprintf("%gk", freq/1000)
+	}
+	return fmt.Sprintf("%g", freq)
+}
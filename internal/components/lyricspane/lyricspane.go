@@ -0,0 +1,257 @@
+// Package lyricspane shows the lyrics of the playing track, following the playback
+// position when they are synchronized, or scrolled by the user when they are not.
+package lyricspane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/nicolito128/tempo/internal/lyrics"
+	"github.com/nicolito128/tempo/internal/styles"
+)
+
+const (
+	// Number of lines displayed at once, with the current one in the middle
+	VisibleLines int = 11
+	// Time to wait for the online providers
+	fetchTimeout time.Duration = 30 * time.Second
+)
+
+// LoadedMsg carries the lyrics found for the audio file at Path.
+type LoadedMsg struct {
+	Path   string
+	Lyrics lyrics.Lyrics
+	Err    error
+}
+
+// Pane : A view of the lyrics of the playing track
+type Pane struct {
+	// visible if the pane is shown instead of the browser
+	visible bool
+
+	// Audio file the lyrics belong to
+	path string
+
+	lyrics  lyrics.Lyrics
+	loading bool
+	err     error
+
+	// Online providers asked when there are no local lyrics, only while the pane is shown
+	providers []lyrics.Provider
+	fetched   bool
+
+	// First line shown of the unsynchronized lyrics, once wrapped
+	offset int
+
+	// Returns the playback position of the audio
+	position func() time.Duration
+
+	width int
+}
+
+var _ tea.Model = (*Pane)(nil)
+
+// New creates a hidden pane that highlights the line sung at the position returned by the function.
+func New(position func() time.Duration) *Pane {
+	p := new(Pane)
+	p.position = position
+	return p
+}
+
+// Visible reports whether the pane is shown.
+func (p *Pane) Visible() bool {
+	return p.visible
+}
+
+// SetProviders sets the online providers of the lyrics, asked in order.
+func (p *Pane) SetProviders(providers []lyrics.Provider) {
+	p.providers = providers
+}
+
+// Toggle shows or hides the pane. Showing it fetches the lyrics online if there are no
+// local ones.
+func (p *Pane) Toggle() tea.Cmd {
+	p.visible = !p.visible
+	return p.fetch()
+}
+
+// Hide hides the pane.
+func (p *Pane) Hide() {
+	p.visible = false
+}
+
+// SetWidth sets the width available to the lines, which are cut if longer.
+func (p *Pane) SetWidth(width int) {
+	p.width = width
+}
+
+// Load looks for the lyrics of the audio file at path in background.
+func (p *Pane) Load(path string) tea.Cmd {
+	p.path = path
+	p.lyrics = lyrics.Lyrics{}
+	p.err = nil
+	p.loading = true
+	p.fetched = false
+	p.offset = 0
+
+	return func() tea.Msg {
+		l, err := lyrics.Find(path)
+		return LoadedMsg{Path: path, Lyrics: l, Err: err}
+	}
+}
+
+func (p *Pane) Init() tea.Cmd {
+	return nil
+}
+
+// fetch asks the providers for the lyrics in background, once per audio file, when the
+// pane is shown and no local lyrics were found.
+func (p *Pane) fetch() tea.Cmd {
+	if !p.visible || p.fetched || len(p.providers) == 0 || !errors.Is(p.err, lyrics.ErrNotFound) {
+		return nil
+	}
+	p.fetched = true
+	p.loading = true
+	p.err = nil
+
+	path, providers := p.path, p.providers
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+		defer cancel()
+		l, err := lyrics.Fetch(ctx, providers, path)
+		return LoadedMsg{Path: path, Lyrics: l, Err: err}
+	}
+}
+
+// Captures reports whether the pane handles the key, which scrolls the unsynchronized
+// lyrics. The synchronized ones scroll on their own.
+func (p *Pane) Captures(msg tea.KeyMsg) bool {
+	if !p.visible || p.lyrics.Synced || len(p.lyrics.Lines) == 0 {
+		return false
+	}
+	switch msg.String() {
+	case "up", "k", "down", "j", "pgup", "pgdown", "home", "end":
+		return true
+	}
+	return false
+}
+
+func (p *Pane) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case LoadedMsg:
+		// Lyrics of the previous audio files are discarded
+		if msg.Path == p.path {
+			p.lyrics = msg.Lyrics
+			p.err = msg.Err
+			p.loading = false
+			return p, p.fetch()
+		}
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "up", "k":
+			p.offset--
+		case "down", "j":
+			p.offset++
+		case "pgup":
+			p.offset -= VisibleLines
+		case "pgdown":
+			p.offset += VisibleLines
+		case "home":
+			p.offset = 0
+		case "end":
+			p.offset = len(p.wrapped())
+		}
+		p.offset = max(min(p.offset, len(p.wrapped())-VisibleLines), 0)
+	}
+	return p, nil
+}
+
+// wrapped returns the lines of the unsynchronized lyrics wrapped to the width.
+func (p *Pane) wrapped() []string {
+	var lines []string
+	for _, l := range p.lyrics.Lines {
+		text := l.Text
+		if p.width > 0 {
+			text = ansi.Wrap(text, p.width, "")
+		}
+		lines = append(lines, strings.Split(text, "\n")...)
+	}
+	return lines
+}
+
+func (p *Pane) View() string {
+	if !p.visible {
+		return ""
+	}
+
+	grey := lipgloss.NewStyle().Foreground(styles.GreyColor())
+	help := styles.Help("\nℹ: L (hide lyrics) | Tab (browser)")
+	if !p.lyrics.Synced && len(p.lyrics.Lines) > 0 {
+		help = styles.Help("\nℹ: ⏶/⏷ (scroll) | PgUp/PgDown (page) | L (hide lyrics) | Tab (browser)")
+	}
+
+	title := "Lyrics"
+	if p.lyrics.Source != "" {
+		title += " " + grey.Render(p.lyrics.Source)
+	}
+	lines := []string{title, ""}
+
+	switch {
+	case p.path == "":
+		lines = append(lines, grey.Render("Nothing is playing"))
+		return strings.Join(lines, "\n") + help
+	case p.loading && p.fetched:
+		lines = append(lines, grey.Render("Looking for lyrics online..."))
+		return strings.Join(lines, "\n") + help
+	case p.loading:
+		lines = append(lines, grey.Render("Looking for lyrics..."))
+		return strings.Join(lines, "\n") + help
+	case errors.Is(p.err, lyrics.ErrNotFound):
+		lines = append(lines, grey.Render("No lyrics found for "+filepath.Base(p.path)))
+		return strings.Join(lines, "\n") + help
+	case p.err != nil:
+		lines = append(lines, lipgloss.NewStyle().Foreground(styles.ProblemColor()).Render("Error: "+p.err.Error()))
+		return strings.Join(lines, "\n") + help
+	}
+
+	if !p.lyrics.Synced {
+		wrapped := p.wrapped()
+		end := min(p.offset+VisibleLines, len(wrapped))
+		lines[0] += grey.Render(fmt.Sprintf(" %d-%d/%d", p.offset+1, end, len(wrapped)))
+		lines = append(lines, wrapped[p.offset:end]...)
+		return strings.Join(lines, "\n") + help
+	}
+
+	current := p.lyrics.Current(p.position())
+	start := max(current-VisibleLines/2, 0)
+	start = max(min(start, len(p.lyrics.Lines)-VisibleLines), 0)
+	end := min(start+VisibleLines, len(p.lyrics.Lines))
+
+	cut := lipgloss.NewStyle()
+	if p.width > 0 {
+		cut = cut.MaxWidth(p.width)
+	}
+	for i := start; i < end; i++ {
+		text := p.lyrics.Lines[i].Text
+		if text == "" && p.lyrics.Synced {
+			// Instrumental parts are usually timed empty lines
+			text = "♪"
+		}
+		switch {
+		case i == current:
+			text = styles.PrimaryHighlight(" " + text + " ")
+		case i < current:
+			text = grey.Render(text)
+		}
+		lines = append(lines, cut.Render(text))
+	}
+	return strings.Join(lines, "\n") + help
+}
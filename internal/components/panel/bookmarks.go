@@ -0,0 +1,118 @@
+package panel
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/styles"
+	"github.com/nicolito128/tempo/internal/xdg"
+)
+
+// BookmarksMsg is sent when the bookmarked directories change, so they can be persisted.
+type BookmarksMsg struct {
+	Dirs []string
+}
+
+// SetBookmarks sets the bookmarked directories.
+func (p *Panel) SetBookmarks(dirs []string) {
+	p.bookmarks = nil
+	for _, dir := range dirs {
+		if abs, err := filepath.Abs(xdg.ExpandHome(dir)); err == nil {
+			p.bookmarks = append(p.bookmarks, abs)
+		}
+	}
+}
+
+// ToggleBookmark bookmarks the browsed directory, or removes its bookmark.
+func (p *Panel) ToggleBookmark() tea.Cmd {
+	if i := slices.Index(p.bookmarks, p.dir); i >= 0 {
+		p.bookmarks = slices.Delete(p.bookmarks, i, i+1)
+	} else {
+		p.bookmarks = append(p.bookmarks, p.dir)
+	}
+	return p.bookmarksChanged()
+}
+
+// ShowBookmarks lists the bookmarked directories to jump to one of them.
+func (p *Panel) ShowBookmarks() {
+	p.view = BookmarksView
+	p.marks.visual = false
+	p.load()
+}
+
+func (p *Panel) loadBookmarks() {
+	p.entries = nil
+	p.cursor = 0
+	p.offset = 0
+	p.err = nil
+
+	for _, dir := range p.bookmarks {
+		p.entries = append(p.entries, Entry{Name: tildePath(dir), Path: dir, IsDir: true})
+	}
+}
+
+func (p *Panel) updateBookmarks(msg tea.Msg) tea.Cmd {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+	if p.updateNavigation(keyMsg) {
+		return nil
+	}
+
+	km := p.keys
+	switch {
+	case key.Matches(keyMsg, km.BrowserUp):
+		p.MoveCursor(-1)
+
+	case key.Matches(keyMsg, km.BrowserDown):
+		p.MoveCursor(1)
+
+	case key.Matches(keyMsg, km.BrowserPlay, km.BrowserOpen):
+		if e, ok := p.Selected(); ok {
+			p.view = FilesView
+			p.Open(e.Path)
+		}
+
+	case key.Matches(keyMsg, km.BrowserDelete):
+		e, ok := p.Selected()
+		if !ok {
+			return nil
+		}
+		p.bookmarks = slices.DeleteFunc(p.bookmarks, func(dir string) bool { return dir == e.Path })
+		p.Refresh()
+		return p.bookmarksChanged()
+
+	case key.Matches(keyMsg, km.Back, km.BrowserParent, km.BrowserBookmarks, km.BrowserView):
+		p.view = FilesView
+		p.Refresh()
+	}
+	return nil
+}
+
+func (p *Panel) bookmarksChanged() tea.Cmd {
+	dirs := slices.Clone(p.bookmarks)
+	return func() tea.Msg { return BookmarksMsg{Dirs: dirs} }
+}
+
+func (p *Panel) bookmarksHeader() string {
+	info := " this directory is not bookmarked"
+	if slices.Contains(p.bookmarks, p.dir) {
+		info = " this directory is bookmarked"
+	}
+	return styles.ContrastHighlight(" Bookmarks ") + lipgloss.NewStyle().Foreground(styles.GreyColor()).Render(info)
+}
+
+// tildePath shortens the home directory at the start of path to ~.
+func tildePath(path string) string {
+	home, err := os.UserHomeDir()
+	if err == nil && (path == home || strings.HasPrefix(path, home+string(filepath.Separator))) {
+		return "~" + strings.TrimPrefix(path, home)
+	}
+	return path
+}
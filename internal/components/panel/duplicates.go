@@ -0,0 +1,34 @@
+package panel
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/nicolito128/tempo/internal/library"
+	"github.com/nicolito128/tempo/internal/styles"
+)
+
+// loadDuplicates lists the library tracks that are likely duplicates, group by group.
+func (p *Panel) loadDuplicates() {
+	p.entries = nil
+	p.cursor = 0
+	p.offset = 0
+	p.err = nil
+	p.duplicateGroups = 0
+
+	if p.library == nil {
+		return
+	}
+
+	groups := library.FindDuplicates(p.library.Tracks())
+	p.duplicateGroups = len(groups)
+	for i, g := range groups {
+		for _, t := range g.Tracks {
+			p.entries = append(p.entries, Entry{
+				Name:   t.Label(),
+				Path:   t.Path,
+				Detail: fmt.Sprintf("#%d %s · %s", i+1, g.Reason, styles.ReverseCut(filepath.Dir(t.Path), Width/3)),
+			})
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package panel
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/styles"
+	"github.com/nicolito128/tempo/internal/trash"
+	"github.com/nicolito128/tempo/internal/xdg"
+)
+
+// FileMovedMsg is sent when a file or directory is moved or renamed from the panel.
+type FileMovedMsg struct {
+	From string
+	To   string
+}
+
+// FileTrashedMsg is sent when a file or directory is moved to the trash from the panel.
+type FileTrashedMsg struct {
+	Path  string
+	IsDir bool
+}
+
+// fileAction : A file management action
+type fileAction int
+
+const (
+	noFileAction fileAction = iota
+	trashAction
+	moveAction
+	renameAction
+)
+
+// fileOp : A file management action waiting to be confirmed
+type fileOp struct {
+	action fileAction
+
+	// Entry the action is applied to
+	entry Entry
+
+	// Destination of a move or the new name of a rename
+	prompt textinput.Model
+}
+
+func newFileOp() fileOp {
+	return fileOp{prompt: textinput.New()}
+}
+
+// canManageFiles reports whether the view being shown supports the file management actions.
+func (p *Panel) canManageFiles() bool {
+	return !p.searching && (p.view == FilesView || p.view == LibraryView)
+}
+
+// updateFileKeys handles the keys that start a file management action.
+// It reports whether the key was handled.
+func (p *Panel) updateFileKeys(msg tea.Msg) (tea.Cmd, bool) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || !p.canManageFiles() {
+		return nil, false
+	}
+
+	var action fileAction
+	switch {
+	case key.Matches(keyMsg, p.keys.BrowserDelete):
+		action = trashAction
+	case key.Matches(keyMsg, p.keys.BrowserMove):
+		action = moveAction
+	case key.Matches(keyMsg, p.keys.BrowserRename):
+		action = renameAction
+	default:
+		return nil, false
+	}
+
+	e, ok := p.current()
+	if !ok {
+		return nil, true
+	}
+	e.Name = filepath.Base(e.Path)
+	return p.startFileOp(action, e), true
+}
+
+// startFileOp asks for the confirmation, or the destination, of the action on the entry.
+func (p *Panel) startFileOp(action fileAction, e Entry) tea.Cmd {
+	p.err = nil
+	p.op.action = action
+	p.op.entry = e
+
+	switch action {
+	case moveAction:
+		p.op.prompt.Prompt = "Move to directory: "
+		p.op.prompt.SetValue(tildePath(filepath.Dir(e.Path)) + string(filepath.Separator))
+	case renameAction:
+		p.op.prompt.Prompt = "Rename to: "
+		p.op.prompt.SetValue(filepath.Base(e.Path))
+	default:
+		return nil
+	}
+	p.op.prompt.CursorEnd()
+	return p.op.prompt.Focus()
+}
+
+// stopFileOp cancels the action being confirmed.
+func (p *Panel) stopFileOp() {
+	p.op.action = noFileAction
+	p.op.prompt.Blur()
+}
+
+// updateFileOp handles the confirmation of a file management action.
+func (p *Panel) updateFileOp(msg tea.Msg) tea.Cmd {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if p.op.action == trashAction {
+		if !ok {
+			return nil
+		}
+		p.stopFileOp()
+		if keyMsg.String() == "y" || keyMsg.String() == "Y" {
+			return p.trashEntry(p.op.entry)
+		}
+		return nil
+	}
+
+	if ok {
+		switch keyMsg.String() {
+		case "esc":
+			p.stopFileOp()
+			return nil
+
+		case "enter":
+			action := p.op.action
+			p.stopFileOp()
+			if action == moveAction {
+				return p.moveEntry(p.op.entry, p.op.prompt.Value())
+			}
+			return p.renameEntry(p.op.entry, p.op.prompt.Value())
+		}
+	}
+
+	var cmd tea.Cmd
+	p.op.prompt, cmd = p.op.prompt.Update(msg)
+	return cmd
+}
+
+// trashEntry moves the entry to the trash.
+func (p *Panel) trashEntry(e Entry) tea.Cmd {
+	if err := trash.Move(e.Path); err != nil {
+		p.err = err
+		return nil
+	}
+
+	p.unmark(e.Path)
+	p.Refresh()
+	return func() tea.Msg { return FileTrashedMsg{Path: e.Path, IsDir: e.IsDir} }
+}
+
+// moveEntry moves the entry inside the given directory, relative to the browsed one.
+func (p *Panel) moveEntry(e Entry, dir string) tea.Cmd {
+	dir = xdg.ExpandHome(strings.TrimSpace(dir))
+	if dir == "" {
+		return nil
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(p.dir, dir)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		p.err = fmt.Errorf("the directory %s does not exist", dir)
+		return nil
+	}
+	return p.renamePath(e.Path, filepath.Join(dir, filepath.Base(e.Path)))
+}
+
+// renameEntry changes the name of the entry, keeping the extension of a file if it is not typed.
+func (p *Panel) renameEntry(e Entry, name string) tea.Cmd {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || strings.ContainsRune(name, filepath.Separator) {
+		p.err = fmt.Errorf("invalid name %q", name)
+		return nil
+	}
+	if !e.IsDir && filepath.Ext(name) == "" {
+		name += filepath.Ext(e.Path)
+	}
+	return p.renamePath(e.Path, filepath.Join(filepath.Dir(e.Path), name))
+}
+
+// renamePath moves the file at from to to, never replacing an existing file.
+func (p *Panel) renamePath(from, to string) tea.Cmd {
+	if from == to {
+		return nil
+	}
+	if _, err := os.Lstat(to); err == nil {
+		p.err = fmt.Errorf("%s already exists", to)
+		return nil
+	}
+	if err := os.Rename(from, to); err != nil {
+		if errors.Is(err, syscall.EXDEV) {
+			err = fmt.Errorf("cannot move %s to another filesystem", from)
+		}
+		p.err = err
+		return nil
+	}
+
+	if p.marks.set[from] {
+		p.unmark(from)
+		p.mark(to)
+	}
+	p.Refresh()
+	if p.view == FilesView {
+		for i, e := range p.entries {
+			if e.Path == to {
+				p.MoveCursor(i - p.cursor)
+				break
+			}
+		}
+	}
+	return func() tea.Msg { return FileMovedMsg{From: from, To: to} }
+}
+
+// fileOpView renders the confirmation of the action being confirmed.
+func (p *Panel) fileOpView() string {
+	if p.op.action != trashAction {
+		return styles.Prompt(p.op.prompt)
+	}
+	question := fmt.Sprintf("Move %s to the trash?", p.op.entry.Name)
+	if p.op.entry.IsDir {
+		question = fmt.Sprintf("Move the directory %s and everything inside to the trash?", p.op.entry.Name)
+	}
+	return lipgloss.NewStyle().Foreground(styles.ProblemColor()).Render(question) +
+		lipgloss.NewStyle().Foreground(styles.GreyColor()).Render(" (y/n)")
+}
+
+// fileOpHelp describes the keys available while confirming an action.
+func (p *Panel) fileOpHelp() string {
+	switch p.op.action {
+	case trashAction:
+		return styles.Help("\nℹ: y (move to the trash) | n/Esc (cancel)")
+	case moveAction:
+		return styles.Help("\nℹ: Enter (move) | Esc (cancel)")
+	}
+	return styles.Help("\nℹ: Enter (rename) | Esc (cancel)")
+}
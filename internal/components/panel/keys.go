@@ -0,0 +1,158 @@
+package panel
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/components/player"
+	"github.com/nicolito128/tempo/internal/styles"
+)
+
+// SetKeys sets the keys of the actions, the ones of the browser being used while it is focused.
+func (p *Panel) SetKeys(km player.KeyMap) {
+	p.keys = km
+	p.table.model.KeyMap = table.KeyMap{
+		LineUp:       km.BrowserUp,
+		LineDown:     km.BrowserDown,
+		PageUp:       km.BrowserPageUp,
+		PageDown:     km.BrowserPageDown,
+		HalfPageUp:   km.BrowserHalfPageUp,
+		HalfPageDown: km.BrowserHalfPageDown,
+		GotoTop:      km.BrowserTop,
+		GotoBottom:   km.BrowserBottom,
+	}
+}
+
+// Captures reports whether the key is handled by the panel when it is focused.
+// While searching, typing a playlist name or a station, or confirming a file action every key
+// but ctrl+c is captured.
+func (p *Panel) Captures(msg tea.KeyMsg) bool {
+	if p.searching || p.marks.saving || p.stationForm.active || p.op.action != noFileAction {
+		return msg.String() != "ctrl+c"
+	}
+	if p.jumping && p.view != LibraryView && p.view != TreeView {
+		return true
+	}
+	return key.Matches(msg, p.viewKeys()...)
+}
+
+// viewKeys returns the keys handled by the view being shown.
+func (p *Panel) viewKeys() []key.Binding {
+	km := p.keys
+	var keys []key.Binding
+	if p.canMark() {
+		keys = append(keys, km.BrowserMark, km.BrowserVisual)
+		if p.hasMarks() {
+			keys = append(keys, km.Back, km.BrowserPlaylist, km.BrowserEdit)
+		}
+	}
+	if p.canManageFiles() {
+		keys = append(keys, km.BrowserDelete, km.BrowserMove, km.BrowserRename)
+	}
+	keys = append(keys, km.BrowserUp, km.BrowserDown, km.BrowserTop, km.BrowserBottom, km.BrowserPageUp,
+		km.BrowserPageDown, km.BrowserHalfPageUp, km.BrowserHalfPageDown, km.BrowserView)
+	if p.view != LibraryView && p.view != TreeView {
+		keys = append(keys, km.BrowserJump)
+	}
+
+	switch p.view {
+	case LibraryView:
+		return append(keys, km.BrowserPlay, km.BrowserEnqueue, km.BrowserSort, km.BrowserReverse,
+			km.BrowserFavorites, km.BrowserMinRating, km.BrowserSearch)
+	case TreeView:
+		return append(keys, km.BrowserParent, km.BrowserOpen, km.BrowserToggle, km.BrowserPlay,
+			km.BrowserEnqueue, km.BrowserFavorites, km.BrowserMinRating, km.BrowserSearch)
+	case RecentView, HistoryView, StatsView, DuplicatesView:
+		return append(keys, km.BrowserPlay, km.BrowserEnqueue, km.BrowserSearch)
+	case SmartView:
+		return append(keys, km.BrowserParent, km.BrowserOpen, km.BrowserPlay, km.BrowserEnqueue,
+			km.BrowserSearch)
+	case BookmarksView:
+		return append(keys, km.BrowserParent, km.BrowserOpen, km.BrowserPlay, km.BrowserDelete,
+			km.Back, km.BrowserBookmarks)
+	case StationsView:
+		return append(keys, km.BrowserPlay, km.BrowserEnqueue, km.BrowserNew, km.BrowserEdit,
+			km.BrowserDelete, km.BrowserGenre)
+	}
+	return append(keys, km.BrowserParent, km.BrowserOpen, km.BrowserPlay, km.BrowserEnqueue,
+		km.BrowserSearch, km.BrowserSort, km.BrowserReverse, km.BrowserBookmark, km.BrowserBookmarks)
+}
+
+// keyHelp shows the keys of the enabled bindings with what they do in the view being shown,
+// like "⏶/⏷ (move)". It is empty if all of them are disabled.
+func keyHelp(desc string, bindings ...key.Binding) string {
+	var labels []string
+	for _, b := range bindings {
+		if b.Enabled() {
+			labels = append(labels, b.Help().Key)
+		}
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	return strings.Join(labels, "/") + " (" + desc + ")"
+}
+
+// helpLine renders the help below the panel, leaving out the empty entries.
+func helpLine(entries ...string) string {
+	var shown []string
+	for _, e := range entries {
+		if e != "" {
+			shown = append(shown, e)
+		}
+	}
+	return styles.Help("\nℹ: " + strings.Join(shown, " | "))
+}
+
+// viewHelp describes the keys of the view being shown.
+func (p *Panel) viewHelp() string {
+	km := p.keys
+	move := keyHelp("move", km.BrowserUp, km.BrowserDown)
+	play := keyHelp("play", km.BrowserPlay)
+	enqueue := keyHelp("enqueue", km.BrowserEnqueue)
+	search := keyHelp("search", km.BrowserSearch)
+	focus := keyHelp("switch focus", km.Focus)
+
+	// The view shown next
+	next := map[ViewMode]string{
+		FilesView:      "library",
+		LibraryView:    "tree",
+		TreeView:       "recently added",
+		RecentView:     "history",
+		HistoryView:    "statistics",
+		StatsView:      "smart playlists",
+		SmartView:      "duplicates",
+		DuplicatesView: "stations",
+		StationsView:   "files",
+	}[p.view]
+	view := keyHelp(next, km.BrowserView)
+
+	switch p.view {
+	case LibraryView:
+		return helpLine(move, play, enqueue, keyHelp("mark", km.BrowserMark), keyHelp("trash", km.BrowserDelete),
+			keyHelp("move", km.BrowserMove), keyHelp("rename", km.BrowserRename), keyHelp("sort column", km.BrowserSort),
+			keyHelp("reverse", km.BrowserReverse), keyHelp("favorites", km.BrowserFavorites),
+			keyHelp("min. rating", km.BrowserMinRating), search, view, focus)
+	case TreeView:
+		return helpLine(move, keyHelp("expand", km.BrowserOpen), keyHelp("collapse", km.BrowserParent), play, enqueue,
+			keyHelp("favorites", km.BrowserFavorites), keyHelp("min. rating", km.BrowserMinRating), search, view, focus)
+	case RecentView, HistoryView, StatsView, DuplicatesView:
+		return helpLine(move, play, enqueue, search, view, focus)
+	case SmartView:
+		return helpLine(move, keyHelp("open", km.BrowserOpen), keyHelp("back", km.BrowserParent), play, enqueue, search,
+			view, focus)
+	case BookmarksView:
+		return helpLine(move, keyHelp("open", km.BrowserPlay), keyHelp("remove", km.BrowserDelete),
+			keyHelp("back", km.Back), focus)
+	case StationsView:
+		return helpLine(move, play, enqueue, keyHelp("new", km.BrowserNew), keyHelp("edit", km.BrowserEdit),
+			keyHelp("remove", km.BrowserDelete), keyHelp("genre", km.BrowserGenre), view, focus)
+	}
+	return helpLine(move, keyHelp("top/bottom", km.BrowserTop, km.BrowserBottom), keyHelp("jump to letter", km.BrowserJump),
+		keyHelp("parent", km.BrowserParent), keyHelp("open", km.BrowserOpen), play, enqueue, keyHelp("mark", km.BrowserMark),
+		keyHelp("trash", km.BrowserDelete), keyHelp("move", km.BrowserMove), keyHelp("rename", km.BrowserRename),
+		keyHelp("sort", km.BrowserSort), keyHelp("reverse", km.BrowserReverse), keyHelp("bookmark", km.BrowserBookmark),
+		keyHelp("bookmarks", km.BrowserBookmarks), search, view, focus)
+}
@@ -0,0 +1,300 @@
+package panel
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/audio"
+	"github.com/nicolito128/tempo/internal/components/queue"
+	"github.com/nicolito128/tempo/internal/tags"
+)
+
+// PlaylistSavedMsg is sent when the marked files are added to a playlist file.
+type PlaylistSavedMsg struct {
+	Path  string
+	Count int
+}
+
+// EditTagsMsg is sent to edit the tags of the marked files all at once.
+type EditTagsMsg struct {
+	Paths []string
+}
+
+// marks : The entries marked to be played, enqueued or saved all at once
+type marks struct {
+	// Marked paths, in the order they were marked
+	paths []string
+	set   map[string]bool
+
+	// visual if the rows between the anchor and the cursor are marked too
+	visual bool
+	anchor int
+
+	// saving if the playlist path is being asked
+	saving bool
+	prompt textinput.Model
+}
+
+func newMarks() marks {
+	prompt := textinput.New()
+	prompt.Prompt = "Add to playlist: "
+	prompt.Placeholder = "name.m3u"
+	return marks{set: make(map[string]bool), prompt: prompt}
+}
+
+// canMark reports whether the view being shown supports marking entries.
+func (p *Panel) canMark() bool {
+	return !p.searching && p.view != TreeView && p.view != BookmarksView && p.view != StationsView && !p.listingSmart()
+}
+
+// rows returns the paths of the rows of the view being shown, and the cursor position.
+func (p *Panel) rows() ([]string, int) {
+	if p.view == LibraryView {
+		paths := make([]string, len(p.table.tracks))
+		for i, t := range p.table.tracks {
+			paths[i] = t.Path
+		}
+		return paths, p.table.model.Cursor()
+	}
+
+	paths := make([]string, len(p.entries))
+	for i, e := range p.entries {
+		paths[i] = e.Path
+	}
+	return paths, p.cursor
+}
+
+// isMarked reports whether the row at index i, with the given path, is marked or
+// inside the visual range.
+func (p *Panel) isMarked(i int, path string) bool {
+	if p.marks.set[path] {
+		return true
+	}
+	if !p.marks.visual {
+		return false
+	}
+
+	cursor := p.cursor
+	if p.view == LibraryView {
+		cursor = p.table.model.Cursor()
+	}
+	return i >= min(p.marks.anchor, cursor) && i <= max(p.marks.anchor, cursor)
+}
+
+// Marked returns the number of marked entries.
+func (p *Panel) Marked() int {
+	return len(p.marks.paths)
+}
+
+// ToggleMark marks or unmarks the selected entry and moves to the next one.
+func (p *Panel) ToggleMark() {
+	rows, cursor := p.rows()
+	if cursor < 0 || cursor >= len(rows) {
+		return
+	}
+	path := rows[cursor]
+	if p.marks.set[path] {
+		p.unmark(path)
+	} else {
+		p.mark(path)
+	}
+
+	if p.view == LibraryView {
+		p.table.model.MoveDown(1)
+		p.table.setRows()
+	} else {
+		p.MoveCursor(1)
+	}
+}
+
+// ToggleVisual starts marking every row the cursor moves over, or marks that range and stops.
+func (p *Panel) ToggleVisual() {
+	rows, cursor := p.rows()
+	if !p.marks.visual {
+		p.marks.visual = true
+		p.marks.anchor = cursor
+		p.table.setRows()
+		return
+	}
+
+	from, to := min(p.marks.anchor, cursor), max(p.marks.anchor, cursor)
+	for i := max(from, 0); i <= to && i < len(rows); i++ {
+		p.mark(rows[i])
+	}
+	p.marks.visual = false
+	p.table.setRows()
+}
+
+// ClearMarks unmarks every entry.
+func (p *Panel) ClearMarks() {
+	p.marks.paths = nil
+	p.marks.set = make(map[string]bool)
+	p.marks.visual = false
+	p.table.setRows()
+}
+
+func (p *Panel) mark(path string) {
+	if p.marks.set[path] {
+		return
+	}
+	p.marks.set[path] = true
+	p.marks.paths = append(p.marks.paths, path)
+}
+
+func (p *Panel) unmark(path string) {
+	delete(p.marks.set, path)
+	for i, marked := range p.marks.paths {
+		if marked == path {
+			p.marks.paths = append(p.marks.paths[:i], p.marks.paths[i+1:]...)
+			break
+		}
+	}
+}
+
+// hasMarks reports whether some entry is marked or the visual mode is on.
+func (p *Panel) hasMarks() bool {
+	return len(p.marks.paths) > 0 || p.marks.visual
+}
+
+// markedFiles returns the audio files of the marked entries, expanding directories
+// and playlists, and clears the marks.
+func (p *Panel) markedFiles() []audio.File {
+	if p.marks.visual {
+		p.ToggleVisual()
+	}
+
+	var files []audio.File
+	for _, path := range p.marks.paths {
+		expanded, err := queue.Expand(path)
+		if err != nil {
+			p.err = err
+			continue
+		}
+		files = append(files, expanded...)
+	}
+	p.ClearMarks()
+	return files
+}
+
+// updateMarks handles the keys used to mark entries. It reports whether the key was handled.
+func (p *Panel) updateMarks(msg tea.Msg) (tea.Cmd, bool) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || !p.canMark() {
+		return nil, false
+	}
+
+	km := p.keys
+	switch {
+	case key.Matches(keyMsg, km.BrowserMark):
+		p.ToggleMark()
+		return nil, true
+
+	case key.Matches(keyMsg, km.BrowserVisual):
+		p.ToggleVisual()
+		return nil, true
+	}
+
+	if !p.hasMarks() {
+		return nil, false
+	}
+
+	switch {
+	case key.Matches(keyMsg, km.Back):
+		p.ClearMarks()
+		return nil, true
+
+	case key.Matches(keyMsg, km.BrowserPlay):
+		return p.playFiles(p.markedFiles()), true
+
+	case key.Matches(keyMsg, km.BrowserEnqueue):
+		return p.enqueueFiles(p.markedFiles()), true
+
+	case key.Matches(keyMsg, km.BrowserPlaylist):
+		p.marks.saving = true
+		p.marks.prompt.SetValue("")
+		return p.marks.prompt.Focus(), true
+
+	case key.Matches(keyMsg, km.BrowserEdit):
+		return p.editMarks(), true
+	}
+	return nil, false
+}
+
+// editMarks asks to edit the tags of the marked files, skipping the formats without
+// tag writing support.
+func (p *Panel) editMarks() tea.Cmd {
+	var paths []string
+	for _, af := range p.markedFiles() {
+		if tags.CanWrite(af.Path()) {
+			paths = append(paths, af.Path())
+		}
+	}
+	if len(paths) == 0 {
+		p.err = errors.New("none of the marked files supports editing its tags")
+		return nil
+	}
+	return func() tea.Msg { return EditTagsMsg{Paths: paths} }
+}
+
+// updateSave handles the playlist path prompt.
+func (p *Panel) updateSave(msg tea.Msg) tea.Cmd {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "esc":
+			p.marks.saving = false
+			p.marks.prompt.Blur()
+			return nil
+
+		case "enter":
+			p.marks.saving = false
+			p.marks.prompt.Blur()
+			return p.saveMarks(p.marks.prompt.Value())
+		}
+	}
+
+	var cmd tea.Cmd
+	p.marks.prompt, cmd = p.marks.prompt.Update(msg)
+	return cmd
+}
+
+// saveMarks adds the marked files to the playlist at path, relative to the browsed directory.
+func (p *Panel) saveMarks(path string) tea.Cmd {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil
+	}
+	if !queue.IsPlaylist(path) {
+		path += ".m3u"
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(p.dir, path)
+	}
+
+	files := p.markedFiles()
+	if err := queue.AppendPlaylist(path, files); err != nil {
+		p.err = err
+		return nil
+	}
+	if p.view == FilesView {
+		p.Refresh()
+	}
+	return func() tea.Msg { return PlaylistSavedMsg{Path: path, Count: len(files)} }
+}
+
+// markHelp describes the keys available while there are marked entries.
+func (p *Panel) markHelp() string {
+	km := p.keys
+	playlist := keyHelp("add to playlist", km.BrowserPlaylist)
+	edit := keyHelp("edit tags", km.BrowserEdit)
+	clear := keyHelp("clear", km.Back)
+	if p.marks.visual {
+		return helpLine(keyHelp("extend", km.BrowserUp, km.BrowserDown), keyHelp("mark range", km.BrowserVisual),
+			keyHelp("play", km.BrowserPlay), keyHelp("enqueue", km.BrowserEnqueue), playlist, edit, clear)
+	}
+	return helpLine(keyHelp("mark", km.BrowserMark), keyHelp("visual", km.BrowserVisual),
+		keyHelp("play marked", km.BrowserPlay), keyHelp("enqueue marked", km.BrowserEnqueue), playlist, edit, clear)
+}
@@ -0,0 +1,60 @@
+package panel
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// updateNavigation handles the paging and jump keys of the views listing entries.
+// It reports whether the key was handled.
+func (p *Panel) updateNavigation(msg tea.KeyMsg) bool {
+	if p.jumping {
+		p.jumping = false
+		if msg.Type == tea.KeyRunes && len(msg.Runes) == 1 {
+			p.JumpTo(msg.Runes[0])
+		}
+		return true
+	}
+
+	km := p.keys
+	switch {
+	case key.Matches(msg, km.BrowserJump):
+		p.jumping = true
+	case key.Matches(msg, km.BrowserTop):
+		p.MoveCursor(-p.cursor)
+	case key.Matches(msg, km.BrowserBottom):
+		p.MoveCursor(len(p.entries))
+	case key.Matches(msg, km.BrowserPageUp):
+		p.MoveCursor(-VisibleEntries)
+	case key.Matches(msg, km.BrowserPageDown):
+		p.MoveCursor(VisibleEntries)
+	case key.Matches(msg, km.BrowserHalfPageUp):
+		p.MoveCursor(-VisibleEntries / 2)
+	case key.Matches(msg, km.BrowserHalfPageDown):
+		p.MoveCursor(VisibleEntries / 2)
+	default:
+		return false
+	}
+	return true
+}
+
+// JumpTo moves the cursor to the next entry whose name starts with the given character,
+// ignoring case. Jumping again with the same character cycles between the matching entries.
+func (p *Panel) JumpTo(r rune) {
+	n := len(p.entries)
+	if n == 0 {
+		return
+	}
+	r = unicode.ToLower(r)
+	for step := 1; step <= n; step++ {
+		i := (p.cursor + step) % n
+		if first, _ := utf8.DecodeRuneInString(strings.ToLower(p.entries[i].Name)); first == r {
+			p.MoveCursor(i - p.cursor)
+			return
+		}
+	}
+}
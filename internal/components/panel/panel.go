@@ -0,0 +1,963 @@
+package panel
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/audio"
+	"github.com/nicolito128/tempo/internal/components/player"
+	"github.com/nicolito128/tempo/internal/components/queue"
+	"github.com/nicolito128/tempo/internal/library"
+	"github.com/nicolito128/tempo/internal/styles"
+)
+
+const (
+	// Maximum number of entries displayed at once
+	VisibleEntries int = 12
+	// Width of the panel box
+	Width int = 100
+	// Maximum number of search results
+	SearchLimit int = 200
+)
+
+// PlayMsg requests to play an audio file right away.
+type PlayMsg struct {
+	Audio audio.File
+}
+
+// EnqueueMsg requests to add audio files at the end of the queue.
+type EnqueueMsg struct {
+	Files []audio.File
+}
+
+// ViewMode : What the panel is showing
+type ViewMode int
+
+const (
+	// FilesView browses the filesystem
+	FilesView ViewMode = iota
+	// LibraryView shows every library track in a table
+	LibraryView
+	// TreeView groups the library tracks by artist and album
+	TreeView
+	// RecentView lists the tracks recently added to the library
+	RecentView
+	// HistoryView lists the tracks recently played
+	HistoryView
+	// StatsView shows the listening statistics and the most played tracks
+	StatsView
+	// SmartView lists the smart playlists and their tracks
+	SmartView
+	// DuplicatesView lists the tracks that are likely duplicates
+	DuplicatesView
+	// BookmarksView lists the bookmarked directories
+	BookmarksView
+	// StationsView lists the saved internet radios
+	StationsView
+)
+
+// Entry : A directory or a playable file shown in the panel
+type Entry struct {
+	Name  string
+	Path  string
+	IsDir bool
+
+	// Detail shown next to the name
+	Detail string
+}
+
+// Panel : A filesystem browser that only shows directories and audio files
+type Panel struct {
+	// Directory being browsed
+	dir string
+
+	entries []Entry
+
+	// Index of the selected entry
+	cursor int
+
+	// Index of the first visible entry
+	offset int
+
+	// focused if the panel receives the key messages
+	focused bool
+
+	// Keys of the actions, the ones of the browser taking the place of the ones of the player
+	keys player.KeyMap
+
+	// What the panel is showing
+	view ViewMode
+
+	// Library tracks for the library view
+	table trackTable
+
+	// Library tracks for the tree view
+	tree trackTree
+
+	// Library used by the search mode
+	library *library.Library
+
+	// Index used by the history view
+	index *library.Index
+
+	// Days considered by the recently added view
+	recentDays int
+
+	// Listening statistics shown by the statistics view
+	summary library.Summary
+
+	// Filters of the library and tree views
+	favoritesOnly bool
+	minStars      int
+
+	// Smart playlists, and the name of the one opened in the smart view
+	smartPlaylists []library.SmartPlaylist
+	smartOpen      string
+
+	// Number of groups found by the duplicates view
+	duplicateGroups int
+
+	// Entries marked to act on all of them at once
+	marks marks
+
+	// jumping if the next typed character selects the entry to jump to
+	jumping bool
+
+	// Bookmarked directories
+	bookmarks []string
+
+	// Saved internet radios, the genre they are filtered by, and the one being added or edited
+	stations    []Station
+	genre       string
+	stationForm stationForm
+
+	// File management action being confirmed
+	op fileOp
+
+	// searching if the panel shows the search results instead of the directory
+	searching bool
+
+	// Search query input
+	input textinput.Model
+
+	// Cursor in the directory before searching
+	browseCursor int
+
+	// Sort order of the files
+	sortOrder   SortOrder
+	sortReverse bool
+
+	// Tags labels and durations of the files that are not in the library
+	labels    map[string]string
+	durations map[string]time.Duration
+	// Files of the directory whose label or duration are not known yet, and whether they are
+	// being probed
+	unlabeled []string
+	unprobed  []string
+	probing   bool
+
+	err error
+}
+
+var _ tea.Model = (*Panel)(nil)
+
+func New(dir string) *Panel {
+	p := new(Panel)
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	p.dir = dir
+	p.labels = make(map[string]string)
+	p.durations = make(map[string]time.Duration)
+	p.recentDays = DefaultRecentDays
+
+	p.input = textinput.New()
+	p.input.Prompt = "/ "
+	p.input.Placeholder = "Search title, artist, album or path"
+
+	p.marks = newMarks()
+	p.op = newFileOp()
+	p.stationForm = newStationForm()
+	p.table = newTrackTable()
+	p.table.marked = p.isMarked
+	p.tree = newTrackTree()
+	p.SetKeys(player.DefaultKeyMap())
+	return p
+}
+
+// SetLibrary sets the library used by the search mode.
+func (p *Panel) SetLibrary(lib *library.Library) {
+	p.library = lib
+}
+
+func (p *Panel) Init() tea.Cmd {
+	p.ReadDir()
+	return nil
+}
+
+func (p *Panel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if p.searching {
+		return p, p.updateSearch(msg)
+	}
+	if p.marks.saving {
+		return p, p.updateSave(msg)
+	}
+	if p.stationForm.active {
+		return p, p.updateStationForm(msg)
+	}
+	if p.op.action != noFileAction {
+		return p, p.updateFileOp(msg)
+	}
+	if cmd, ok := p.updateMarks(msg); ok {
+		return p, cmd
+	}
+	if cmd, ok := p.updateFileKeys(msg); ok {
+		return p, cmd
+	}
+
+	switch p.view {
+	case LibraryView:
+		return p, p.updateTable(msg)
+	case TreeView:
+		return p, p.updateTree(msg)
+	case RecentView, HistoryView, StatsView, SmartView, DuplicatesView:
+		return p, p.updateList(msg)
+	case BookmarksView:
+		return p, p.updateBookmarks(msg)
+	case StationsView:
+		return p, p.updateStations(msg)
+	}
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if p.updateNavigation(msg) {
+			return p, nil
+		}
+
+		km := p.keys
+		switch {
+		case key.Matches(msg, km.BrowserSearch):
+			return p, p.StartSearch()
+
+		case key.Matches(msg, km.BrowserView):
+			p.ToggleView()
+
+		case key.Matches(msg, km.BrowserBookmark):
+			return p, p.ToggleBookmark()
+
+		case key.Matches(msg, km.BrowserBookmarks):
+			p.ShowBookmarks()
+
+		case key.Matches(msg, km.BrowserSort):
+			msg := p.NextSort()
+			return p, func() tea.Msg { return msg }
+
+		case key.Matches(msg, km.BrowserReverse):
+			msg := p.ReverseSort()
+			return p, func() tea.Msg { return msg }
+
+		case key.Matches(msg, km.BrowserUp):
+			p.MoveCursor(-1)
+
+		case key.Matches(msg, km.BrowserDown):
+			p.MoveCursor(1)
+
+		case key.Matches(msg, km.BrowserParent):
+			p.Parent()
+
+		case key.Matches(msg, km.BrowserOpen):
+			if e, ok := p.Selected(); ok && e.IsDir {
+				p.Open(e.Path)
+			}
+
+		case key.Matches(msg, km.BrowserPlay):
+			return p, p.Play()
+
+		case key.Matches(msg, km.BrowserEnqueue):
+			return p, p.Enqueue()
+		}
+	}
+	return p, nil
+}
+
+// ToggleView cycles between the files, library, tree, recently added, history, statistics,
+// smart playlists, duplicates and stations views.
+func (p *Panel) ToggleView() {
+	// The visual range only makes sense in the rows it was started on
+	p.marks.visual = false
+
+	switch p.view {
+	case FilesView:
+		p.view = LibraryView
+		p.LibraryChanged()
+	case LibraryView:
+		p.view = TreeView
+		p.LibraryChanged()
+	case TreeView:
+		p.view = RecentView
+		p.load()
+	case RecentView:
+		p.view = HistoryView
+		p.load()
+	case HistoryView:
+		p.view = StatsView
+		p.load()
+	case StatsView:
+		p.view = SmartView
+		p.smartOpen = ""
+		p.load()
+	case SmartView:
+		p.view = DuplicatesView
+		p.load()
+	case DuplicatesView:
+		p.view = StationsView
+		p.load()
+	default:
+		p.view = FilesView
+		p.load()
+	}
+}
+
+// SetViewMode shows the given view, like ToggleView does when it gets to it.
+func (p *Panel) SetViewMode(view ViewMode) {
+	if view < FilesView || view > StationsView {
+		return
+	}
+	p.marks.visual = false
+	p.view = view
+	switch view {
+	case LibraryView, TreeView:
+		p.LibraryChanged()
+	case SmartView:
+		p.smartOpen = ""
+		p.load()
+	default:
+		p.load()
+	}
+}
+
+// ViewMode returns what the panel is showing.
+func (p *Panel) ViewMode() ViewMode {
+	return p.view
+}
+
+// LibraryChanged updates the library views with the current library tracks.
+func (p *Panel) LibraryChanged() {
+	if p.library == nil {
+		return
+	}
+	switch p.view {
+	case LibraryView:
+		p.table.SetPlays(p.playCounts())
+		p.table.SetRatings(p.ratings())
+		p.table.SetTracks(p.filteredTracks())
+	case TreeView:
+		p.tree.SetTracks(p.filteredTracks())
+	case RecentView, SmartView, DuplicatesView:
+		p.Refresh()
+	}
+}
+
+func (p *Panel) updateTable(msg tea.Msg) tea.Cmd {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		km := p.keys
+		switch {
+		case key.Matches(msg, km.BrowserSearch):
+			return p.StartSearch()
+
+		case key.Matches(msg, km.BrowserView):
+			p.ToggleView()
+			return nil
+
+		case key.Matches(msg, km.BrowserSort):
+			p.table.NextSort()
+			return nil
+
+		case key.Matches(msg, km.BrowserReverse):
+			p.table.SortBy(p.table.sortBy)
+			return nil
+
+		case key.Matches(msg, km.BrowserFavorites):
+			p.ToggleFavoritesOnly()
+			return nil
+
+		case key.Matches(msg, km.BrowserMinRating):
+			p.NextMinStars()
+			return nil
+
+		case key.Matches(msg, km.BrowserPlay):
+			return p.Play()
+
+		case key.Matches(msg, km.BrowserEnqueue):
+			return p.Enqueue()
+		}
+	}
+	cmd := p.table.Update(msg)
+	if p.marks.visual {
+		p.table.setRows()
+	}
+	return cmd
+}
+
+func (p *Panel) updateTree(msg tea.Msg) tea.Cmd {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+
+	km := p.keys
+	switch {
+	case key.Matches(keyMsg, km.BrowserSearch):
+		return p.StartSearch()
+
+	case key.Matches(keyMsg, km.BrowserView):
+		p.ToggleView()
+
+	case key.Matches(keyMsg, km.BrowserUp):
+		p.tree.MoveCursor(-1)
+
+	case key.Matches(keyMsg, km.BrowserDown):
+		p.tree.MoveCursor(1)
+
+	case key.Matches(keyMsg, km.BrowserTop):
+		p.tree.MoveCursor(-p.tree.cursor)
+
+	case key.Matches(keyMsg, km.BrowserBottom):
+		p.tree.MoveCursor(len(p.tree.rows))
+
+	case key.Matches(keyMsg, km.BrowserPageUp):
+		p.tree.MoveCursor(-VisibleEntries)
+
+	case key.Matches(keyMsg, km.BrowserPageDown):
+		p.tree.MoveCursor(VisibleEntries)
+
+	case key.Matches(keyMsg, km.BrowserHalfPageUp):
+		p.tree.MoveCursor(-VisibleEntries / 2)
+
+	case key.Matches(keyMsg, km.BrowserHalfPageDown):
+		p.tree.MoveCursor(VisibleEntries / 2)
+
+	case key.Matches(keyMsg, km.BrowserOpen):
+		p.tree.Expand()
+
+	case key.Matches(keyMsg, km.BrowserParent):
+		p.tree.Collapse()
+
+	case key.Matches(keyMsg, km.BrowserToggle):
+		p.tree.Toggle()
+
+	case key.Matches(keyMsg, km.BrowserFavorites):
+		p.ToggleFavoritesOnly()
+
+	case key.Matches(keyMsg, km.BrowserMinRating):
+		p.NextMinStars()
+
+	case key.Matches(keyMsg, km.BrowserPlay):
+		return p.Play()
+
+	case key.Matches(keyMsg, km.BrowserEnqueue):
+		return p.Enqueue()
+	}
+	return nil
+}
+
+// StartSearch switches the panel to the search mode.
+func (p *Panel) StartSearch() tea.Cmd {
+	p.searching = true
+	p.browseCursor = p.cursor
+	p.input.SetValue("")
+	p.entries = nil
+	p.cursor = 0
+	p.offset = 0
+	return p.input.Focus()
+}
+
+// StopSearch goes back to browsing the directory.
+func (p *Panel) StopSearch() {
+	p.searching = false
+	p.input.Blur()
+	if p.view != LibraryView && p.view != TreeView {
+		p.load()
+		p.MoveCursor(p.browseCursor)
+	}
+}
+
+// Searching reports whether the panel is in search mode.
+func (p *Panel) Searching() bool {
+	return p.searching
+}
+
+func (p *Panel) updateSearch(msg tea.Msg) tea.Cmd {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc":
+			p.StopSearch()
+			return nil
+
+		case "up", "ctrl+p":
+			p.MoveCursor(-1)
+			return nil
+
+		case "down", "ctrl+n":
+			p.MoveCursor(1)
+			return nil
+
+		case "enter":
+			return p.Play()
+		}
+	}
+
+	query := p.input.Value()
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	if p.input.Value() != query {
+		p.search()
+	}
+	return cmd
+}
+
+// search fills the entries with the library tracks matching the query.
+func (p *Panel) search() {
+	p.entries = nil
+	p.cursor = 0
+	p.offset = 0
+	p.err = nil
+
+	if p.library == nil {
+		return
+	}
+	for _, m := range library.Search(p.library.Tracks(), p.input.Value(), SearchLimit) {
+		p.entries = append(p.entries, Entry{
+			Name:   m.Track.Label(),
+			Path:   m.Track.Path,
+			Detail: styles.ReverseCut(filepath.Dir(m.Track.Path), Width/2),
+		})
+	}
+}
+
+func (p *Panel) View() string {
+	var lines []string
+	switch {
+	case p.searching:
+		lines = append(lines, styles.Prompt(p.input), "")
+	case p.marks.saving:
+		lines = append(lines, styles.Prompt(p.marks.prompt), "")
+	case p.stationForm.active:
+		lines = append(lines, styles.Prompt(p.stationForm.prompt), "")
+	case p.op.action != noFileAction:
+		lines = append(lines, p.fileOpView(), "")
+	case p.view == LibraryView:
+		lines = append(lines, p.tableHeader(), "", p.table.View())
+	case p.view == TreeView:
+		lines = append(lines, p.treeHeader(), "", p.tree.View())
+	case p.view == RecentView || p.view == HistoryView || p.view == StatsView ||
+		p.view == SmartView || p.view == DuplicatesView:
+		lines = append(lines, p.listHeader(), "")
+	case p.view == BookmarksView:
+		lines = append(lines, p.bookmarksHeader(), "")
+	case p.view == StationsView:
+		lines = append(lines, p.stationsHeader(), "")
+	default:
+		order := p.sortOrder.String()
+		if p.sortReverse {
+			order += ", reversed"
+		}
+		info := " · sorted by " + order
+		if len(p.entries) > VisibleEntries {
+			info += fmt.Sprintf(" · %d/%d", p.cursor+1, len(p.entries))
+		}
+		sortInfo := lipgloss.NewStyle().Foreground(styles.GreyColor()).Render(info)
+		lines = append(lines, p.breadcrumb()+sortInfo, "")
+	}
+
+	switch {
+	case p.err != nil:
+		lines = append(lines, lipgloss.NewStyle().Foreground(styles.ProblemColor()).Render("Error: "+p.err.Error()))
+	case p.searching && (p.library == nil || p.library.Len() == 0):
+		lines = append(lines, styles.Help("The library is empty, scan your music directories with -library"))
+	case p.searching && len(p.entries) == 0 && p.input.Value() != "":
+		lines = append(lines, styles.Help("No matches"))
+	case !p.searching && (p.view == HistoryView || p.view == StatsView) && len(p.entries) == 0:
+		lines = append(lines, styles.Help("Nothing played yet"))
+	case p.view == BookmarksView && len(p.entries) == 0:
+		lines = append(lines, styles.Help("There are no bookmarks, press "+p.keys.BrowserBookmark.Help().Key+" in a directory to add it"))
+	case !p.searching && p.view == StationsView && len(p.entries) == 0:
+		lines = append(lines, styles.Help("There are no stations, press "+p.keys.BrowserNew.Help().Key+" to add the URL of an internet radio"))
+	case !p.searching && p.view == SmartView && len(p.smartPlaylists) == 0:
+		lines = append(lines, styles.Help("There are no smart playlists, add them to the config file"))
+	case !p.searching && p.view != FilesView && p.view != StationsView && (p.library == nil || p.library.Len() == 0):
+		lines = append(lines, styles.Help("The library is empty, scan your music directories with -library"))
+	case !p.searching && p.view == DuplicatesView && len(p.entries) == 0:
+		lines = append(lines, styles.Help("No duplicates found"))
+	case !p.searching && p.view == RecentView && len(p.entries) == 0:
+		lines = append(lines, styles.Help(fmt.Sprintf("No tracks added in the last %d days", p.recentDays)))
+	case !p.searching && p.view == FilesView && len(p.entries) == 0:
+		lines = append(lines, styles.Help("No audio files here"))
+	}
+
+	detailStyle := lipgloss.NewStyle().Foreground(styles.GreyColor())
+
+	end := min(p.offset+VisibleEntries, len(p.entries))
+	if !p.searching && (p.view == LibraryView || p.view == TreeView) {
+		end = 0
+	}
+	for i := p.offset; i < end; i++ {
+		e := p.entries[i]
+
+		icon := "♪"
+		if e.IsDir {
+			icon = "▸"
+		} else if queue.IsPlaylist(e.Path) || p.listingSmart() {
+			icon = "≡"
+		}
+
+		mark := " "
+		if p.canMark() && p.isMarked(i, e.Path) {
+			mark = "●"
+		}
+
+		line := fmt.Sprintf("%s%s %s ", mark, icon, e.Name)
+		if e.Detail != "" {
+			line += detailStyle.Render(e.Detail)
+		}
+		if i == p.cursor {
+			line = styles.PrimaryHighlight(line)
+		} else if e.IsDir {
+			line = lipgloss.NewStyle().Foreground(styles.SecundaryColor()).Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	borderColor := styles.GreyColor()
+	if p.focused {
+		borderColor = styles.PrimaryColor()
+	}
+
+	s := lipgloss.NewStyle().
+		Padding(0, 1).
+		Width(Width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(borderColor).
+		Render(strings.Join(lines, "\n"))
+
+	switch {
+	case p.focused && p.searching:
+		s += styles.Help("\nℹ: ⏶/⏷ (move) | Enter (play) | Esc (stop searching)")
+	case p.focused && p.marks.saving:
+		s += styles.Help("\nℹ: Enter (add) | Esc (cancel)")
+	case p.focused && p.stationForm.active:
+		s += p.stationFormHelp()
+	case p.focused && p.op.action != noFileAction:
+		s += p.fileOpHelp()
+	case p.focused && p.canMark() && p.hasMarks():
+		s += p.markHelp()
+	case p.focused:
+		s += p.viewHelp()
+	}
+	return s
+}
+
+// Focus makes the panel receive the key messages.
+func (p *Panel) Focus() {
+	p.focused = true
+}
+
+// Blur stops the panel from receiving the key messages.
+func (p *Panel) Blur() {
+	p.focused = false
+}
+
+// Focused reports whether the panel is focused.
+func (p *Panel) Focused() bool {
+	return p.focused
+}
+
+// Dir returns the directory being browsed.
+func (p *Panel) Dir() string {
+	return p.dir
+}
+
+// Selected returns the entry under the cursor.
+func (p *Panel) Selected() (Entry, bool) {
+	if p.cursor < 0 || p.cursor >= len(p.entries) {
+		return Entry{}, false
+	}
+	return p.entries[p.cursor], true
+}
+
+// current returns the selected entry of the view being shown.
+func (p *Panel) current() (Entry, bool) {
+	if p.view == LibraryView && !p.searching {
+		t, ok := p.table.Selected()
+		return Entry{Name: t.Title(), Path: t.Path}, ok
+	}
+	return p.Selected()
+}
+
+// tableHeader describes the library view.
+func (p *Panel) tableHeader() string {
+	title := styles.ContrastHighlight(" Library ")
+	info := lipgloss.NewStyle().
+		Foreground(styles.GreyColor()).
+		Render(fmt.Sprintf(" %d tracks, sorted by %s", len(p.table.tracks), p.table.sortBy) + p.filterInfo())
+	return title + info
+}
+
+// treeHeader describes the tree view.
+func (p *Panel) treeHeader() string {
+	title := styles.ContrastHighlight(" Artists ")
+	info := lipgloss.NewStyle().
+		Foreground(styles.GreyColor()).
+		Render(fmt.Sprintf(" %d artists", len(p.tree.groups)) + p.filterInfo())
+	return title + info
+}
+
+// MoveCursor moves the cursor by delta entries, keeping it inside the visible window.
+func (p *Panel) MoveCursor(delta int) {
+	if len(p.entries) == 0 {
+		return
+	}
+	p.cursor = min(max(p.cursor+delta, 0), len(p.entries)-1)
+
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	}
+	if p.cursor >= p.offset+VisibleEntries {
+		p.offset = p.cursor - VisibleEntries + 1
+	}
+}
+
+// Open browses the given directory.
+func (p *Panel) Open(dir string) {
+	p.dir = dir
+	p.marks.visual = false
+	p.ReadDir()
+}
+
+// Refresh loads the entries again, keeping the selected entry if it still exists.
+func (p *Panel) Refresh() {
+	selected, _ := p.Selected()
+	cursor := p.cursor
+
+	p.load()
+	for i, e := range p.entries {
+		if e.Path == selected.Path {
+			p.MoveCursor(i)
+			return
+		}
+	}
+	p.MoveCursor(min(cursor, len(p.entries)-1))
+}
+
+// load fills the entries of the list views.
+func (p *Panel) load() {
+	switch p.view {
+	case RecentView:
+		p.loadRecent()
+	case HistoryView:
+		p.loadHistory()
+	case StatsView:
+		p.loadStats()
+	case SmartView:
+		p.loadSmart()
+	case DuplicatesView:
+		p.loadDuplicates()
+	case BookmarksView:
+		p.loadBookmarks()
+	case StationsView:
+		p.loadStations()
+	default:
+		p.ReadDir()
+	}
+}
+
+// Parent browses the parent of the current directory, selecting the directory we come from.
+func (p *Panel) Parent() {
+	parent := filepath.Dir(p.dir)
+	if parent == p.dir {
+		return
+	}
+
+	from := p.dir
+	p.marks.visual = false
+	p.Open(parent)
+	for i, e := range p.entries {
+		if e.Path == from {
+			p.MoveCursor(i)
+			break
+		}
+	}
+}
+
+// ReadDir loads the entries of the current directory: subdirectories first and then
+// the supported audio files and playlists. Hidden files are ignored.
+func (p *Panel) ReadDir() {
+	p.entries = nil
+	p.unlabeled = nil
+	p.unprobed = nil
+	p.cursor = 0
+	p.offset = 0
+	p.err = nil
+
+	des, err := os.ReadDir(p.dir)
+	if err != nil {
+		p.err = err
+		return
+	}
+
+	var files []Entry
+	for _, de := range des {
+		if strings.HasPrefix(de.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(p.dir, de.Name())
+		isDir := de.IsDir()
+		if de.Type()&os.ModeSymlink != 0 {
+			if info, err := os.Stat(path); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+
+		switch {
+		case isDir:
+			p.entries = append(p.entries, Entry{Name: de.Name(), Path: path, IsDir: true})
+		case audio.IsSupported(path):
+			files = append(files, Entry{Name: de.Name(), Path: path, Detail: p.tagsLabel(path)})
+		case queue.IsPlaylist(path):
+			files = append(files, Entry{Name: de.Name(), Path: path})
+		}
+	}
+	p.sortFiles(files)
+	p.entries = append(p.entries, files...)
+}
+
+// track returns the library track with the given path.
+func (p *Panel) track(path string) (library.Track, bool) {
+	if p.library == nil {
+		return library.Track{}, false
+	}
+	return p.library.Get(path)
+}
+
+// tagsLabel returns "Artist – Title" for a tagged audio file, taking the tags from the
+// library or from an earlier probe. Empty if the file has no tags or was not probed yet.
+func (p *Panel) tagsLabel(path string) string {
+	t, ok := p.track(path)
+	if !ok {
+		label, ok := p.labels[path]
+		if !ok {
+			p.unlabeled = append(p.unlabeled, path)
+		}
+		return label
+	}
+
+	af := audio.NewFile(path)
+	af.SetTags(t.Tags)
+	return fileLabel(af)
+}
+
+// fileLabel returns "Artist – Title" for a tagged audio file, empty without title.
+func fileLabel(af audio.File) string {
+	if af.Tags().Title == "" {
+		return ""
+	}
+	return af.Label()
+}
+
+// Play returns a command to play the selected file, or the first file of the
+// selected directory, playlist, artist or album. The rest of them are enqueued.
+func (p *Panel) Play() tea.Cmd {
+	if e, ok := p.current(); ok && e.IsDir {
+		p.Open(e.Path)
+		return nil
+	}
+
+	return p.playFiles(p.selectedFiles())
+}
+
+// playFiles returns a command to play the first file and enqueue the rest.
+func (p *Panel) playFiles(files []audio.File) tea.Cmd {
+	if len(files) == 0 {
+		return nil
+	}
+
+	cmds := []tea.Cmd{func() tea.Msg { return PlayMsg{Audio: files[0]} }}
+	if len(files) > 1 {
+		cmds = append(cmds, func() tea.Msg { return EnqueueMsg{Files: files[1:]} })
+	}
+	return tea.Sequence(cmds...)
+}
+
+// Enqueue returns a command to enqueue the selected file, or every audio file
+// inside the selected directory, playlist, artist or album.
+func (p *Panel) Enqueue() tea.Cmd {
+	return p.enqueueFiles(p.selectedFiles())
+}
+
+// enqueueFiles returns a command to enqueue the files.
+func (p *Panel) enqueueFiles(files []audio.File) tea.Cmd {
+	if len(files) == 0 {
+		return nil
+	}
+	return func() tea.Msg { return EnqueueMsg{Files: files} }
+}
+
+// selectedFiles returns the audio files referenced by the selection of the view being shown.
+func (p *Panel) selectedFiles() []audio.File {
+	if p.view == TreeView && !p.searching {
+		n, ok := p.tree.Selected()
+		if !ok {
+			return nil
+		}
+		files := make([]audio.File, len(n.tracks))
+		for i, t := range n.tracks {
+			files[i] = t.Audio()
+		}
+		return files
+	}
+
+	e, ok := p.current()
+	if !ok {
+		return nil
+	}
+	if p.listingSmart() {
+		return p.smartFiles(e.Path)
+	}
+	files, err := queue.Expand(e.Path)
+	if err != nil {
+		p.err = err
+		return nil
+	}
+	return files
+}
+
+// breadcrumb renders the current path, with the home directory shortened to ~.
+func (p *Panel) breadcrumb() string {
+	path := tildePath(p.dir)
+
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	if parts[0] == "" {
+		parts[0] = "/"
+	}
+
+	var crumbs []string
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		if i == len(parts)-1 {
+			crumbs = append(crumbs, styles.ContrastHighlight(" "+part+" "))
+		} else {
+			crumbs = append(crumbs, part)
+		}
+	}
+	return strings.Join(crumbs, " › ")
+}
@@ -0,0 +1,67 @@
+package panel
+
+import (
+	"maps"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/audio"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// ProbedMsg : The tags labels and durations of the files of a directory, read in the background
+type ProbedMsg struct {
+	Dir       string
+	Labels    map[string]string
+	Durations map[string]time.Duration
+}
+
+// Probe returns a command to read the tags and durations that the last load of the directory
+// did not know, or nil if there are none or a probe is still running. Reading them opens every
+// file, which is too slow for the UI goroutine on big directories.
+func (p *Panel) Probe() tea.Cmd {
+	if p.probing || len(p.unlabeled) == 0 && len(p.unprobed) == 0 {
+		return nil
+	}
+	dir, unlabeled, unprobed := p.dir, p.unlabeled, p.unprobed
+	p.unlabeled, p.unprobed = nil, nil
+	p.probing = true
+
+	return func() tea.Msg {
+		msg := ProbedMsg{
+			Dir:       dir,
+			Labels:    make(map[string]string, len(unlabeled)),
+			Durations: make(map[string]time.Duration, len(unprobed)),
+		}
+		// Unreadable files are remembered too, so they are not probed again
+		for _, path := range unlabeled {
+			af := audio.NewFile(path)
+			af.LoadTags()
+			msg.Labels[path] = fileLabel(af)
+		}
+		for _, path := range unprobed {
+			d, _ := engine.ProbeDuration(path)
+			msg.Durations[path] = d
+		}
+		return msg
+	}
+}
+
+// Probed keeps the probed labels and durations, and shows them if the directory is still shown.
+func (p *Panel) Probed(msg ProbedMsg) {
+	p.probing = false
+	maps.Copy(p.labels, msg.Labels)
+	maps.Copy(p.durations, msg.Durations)
+	if p.view == FilesView && p.dir == msg.Dir {
+		p.Refresh()
+	}
+}
+
+// TagsChanged reads the tags of the file again after they are edited.
+func (p *Panel) TagsChanged(path string) {
+	delete(p.labels, path)
+	if p.view == FilesView && filepath.Dir(path) == p.dir {
+		p.Refresh()
+	}
+}
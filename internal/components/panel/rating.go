@@ -0,0 +1,105 @@
+package panel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nicolito128/tempo/internal/library"
+)
+
+// RatingsChanged reloads the views showing ratings.
+func (p *Panel) RatingsChanged() {
+	if p.searching {
+		return
+	}
+	switch p.view {
+	case LibraryView, TreeView:
+		p.LibraryChanged()
+	case FilesView:
+		if p.sortOrder == SortRating {
+			p.Refresh()
+		}
+	}
+}
+
+// ToggleFavoritesOnly shows only the favorite tracks in the library and tree views, or all of them again.
+func (p *Panel) ToggleFavoritesOnly() {
+	p.favoritesOnly = !p.favoritesOnly
+	p.LibraryChanged()
+}
+
+// NextMinStars cycles the minimum rating of the tracks shown in the library and tree views.
+func (p *Panel) NextMinStars() {
+	p.minStars = (p.minStars + 1) % (library.MaxStars + 1)
+	p.LibraryChanged()
+}
+
+// filteredTracks returns the library tracks that pass the rating filters.
+func (p *Panel) filteredTracks() []library.Track {
+	tracks := p.library.Tracks()
+	if !p.favoritesOnly && p.minStars == 0 {
+		return tracks
+	}
+
+	ratings := p.ratings()
+	filtered := tracks[:0]
+	for _, t := range tracks {
+		r := ratings[t.Path]
+		if p.favoritesOnly && !r.Favorite {
+			continue
+		}
+		if r.Stars < p.minStars {
+			continue
+		}
+		filtered = append(filtered, t)
+	}
+	return filtered
+}
+
+// filterInfo describes the active rating filters.
+func (p *Panel) filterInfo() string {
+	var filters []string
+	if p.favoritesOnly {
+		filters = append(filters, "favorites")
+	}
+	if p.minStars > 0 {
+		filters = append(filters, fmt.Sprintf("%d+ stars", p.minStars))
+	}
+	if len(filters) == 0 {
+		return ""
+	}
+	return " · only " + strings.Join(filters, ", ")
+}
+
+// ratings returns the rating of every rated track.
+func (p *Panel) ratings() map[string]library.Rating {
+	if p.index == nil {
+		return nil
+	}
+	ratings, err := p.index.Ratings()
+	if err != nil {
+		return nil
+	}
+	return ratings
+}
+
+// ratingKey orders the ratings by stars, favorites first on ties.
+func ratingKey(r library.Rating) int {
+	key := r.Stars * 2
+	if r.Favorite {
+		key++
+	}
+	return key
+}
+
+// shortRating formats a rating for the table, like "★4 ♥".
+func shortRating(r library.Rating) string {
+	var s string
+	if r.Stars > 0 {
+		s = fmt.Sprintf("★%d", r.Stars)
+	}
+	if r.Favorite {
+		s = strings.TrimSpace(s + " ♥")
+	}
+	return s
+}
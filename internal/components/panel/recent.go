@@ -0,0 +1,158 @@
+package panel
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/library"
+	"github.com/nicolito128/tempo/internal/styles"
+)
+
+const (
+	// Days considered by the recently added view when not configured
+	DefaultRecentDays int = 30
+	// Max number of play events shown by the history view
+	HistoryLimit int = 200
+)
+
+// SetIndex sets the index the history view reads the play events from.
+func (p *Panel) SetIndex(idx *library.Index) {
+	p.index = idx
+}
+
+// SetRecentDays sets how many days back the recently added view looks.
+func (p *Panel) SetRecentDays(days int) {
+	if days <= 0 {
+		days = DefaultRecentDays
+	}
+	p.recentDays = days
+}
+
+// updateList handles the keys of the views listing tracks or playlists.
+func (p *Panel) updateList(msg tea.Msg) tea.Cmd {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+	if p.updateNavigation(keyMsg) {
+		return nil
+	}
+
+	km := p.keys
+	switch {
+	case key.Matches(keyMsg, km.BrowserSearch):
+		return p.StartSearch()
+
+	case key.Matches(keyMsg, km.BrowserView):
+		p.ToggleView()
+
+	case key.Matches(keyMsg, km.BrowserUp):
+		p.MoveCursor(-1)
+
+	case key.Matches(keyMsg, km.BrowserDown):
+		p.MoveCursor(1)
+
+	case key.Matches(keyMsg, km.BrowserOpen):
+		if p.view == SmartView {
+			p.openSmart()
+		}
+
+	case key.Matches(keyMsg, km.BrowserParent):
+		if p.view == SmartView {
+			p.closeSmart()
+		}
+
+	case key.Matches(keyMsg, km.BrowserPlay):
+		return p.Play()
+
+	case key.Matches(keyMsg, km.BrowserEnqueue):
+		return p.Enqueue()
+	}
+	return nil
+}
+
+// loadRecent lists the library tracks added in the last days, newest first.
+func (p *Panel) loadRecent() {
+	p.entries = nil
+	p.cursor = 0
+	p.offset = 0
+	p.err = nil
+
+	if p.library == nil {
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -p.recentDays)
+	for _, t := range p.library.RecentlyAdded(since) {
+		p.entries = append(p.entries, Entry{
+			Name:   t.Label(),
+			Path:   t.Path,
+			Detail: t.Added().Format("2006-01-02"),
+		})
+	}
+}
+
+// loadHistory lists the last played tracks, newest first.
+func (p *Panel) loadHistory() {
+	p.entries = nil
+	p.cursor = 0
+	p.offset = 0
+	p.err = nil
+
+	if p.index == nil {
+		return
+	}
+
+	events, err := p.index.History(HistoryLimit)
+	if err != nil {
+		p.err = err
+		return
+	}
+
+	now := time.Now()
+	for _, ev := range events {
+		name := filepath.Base(ev.Path)
+		if t, ok := p.track(ev.Path); ok {
+			name = t.Label()
+		}
+		p.entries = append(p.entries, Entry{
+			Name:   name,
+			Path:   ev.Path,
+			Detail: ago(now.Sub(ev.At)),
+		})
+	}
+}
+
+func (p *Panel) listHeader() string {
+	info := lipgloss.NewStyle().Foreground(styles.GreyColor())
+	switch p.view {
+	case StatsView:
+		return p.statsHeader()
+	case SmartView:
+		return p.smartHeader()
+	case DuplicatesView:
+		return styles.ContrastHighlight(" Duplicates ") + info.Render(fmt.Sprintf(" %d groups", p.duplicateGroups))
+	case HistoryView:
+		return styles.ContrastHighlight(" History ") + info.Render(fmt.Sprintf(" %d plays", len(p.entries)))
+	}
+	return styles.ContrastHighlight(" Recently added ") +
+		info.Render(fmt.Sprintf(" %d tracks in the last %d days", len(p.entries), p.recentDays))
+}
+
+// ago formats how long ago something happened, like "5m ago" or "3d ago".
+func ago(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
@@ -0,0 +1,110 @@
+package panel
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/audio"
+	"github.com/nicolito128/tempo/internal/library"
+	"github.com/nicolito128/tempo/internal/styles"
+)
+
+// SetSmartPlaylists sets the smart playlists listed by the smart view.
+func (p *Panel) SetSmartPlaylists(playlists []library.SmartPlaylist) {
+	p.smartPlaylists = playlists
+}
+
+// listingSmart reports whether the entries are the smart playlists instead of tracks.
+func (p *Panel) listingSmart() bool {
+	return p.view == SmartView && p.smartOpen == "" && !p.searching
+}
+
+// openSmart lists the tracks of the selected smart playlist.
+func (p *Panel) openSmart() {
+	e, ok := p.Selected()
+	if !ok || !p.listingSmart() {
+		return
+	}
+	p.smartOpen = e.Path
+	p.loadSmart()
+}
+
+// closeSmart goes back to the list of smart playlists, selecting the one that was open.
+func (p *Panel) closeSmart() {
+	if p.smartOpen == "" {
+		return
+	}
+	name := p.smartOpen
+	p.smartOpen = ""
+	p.loadSmart()
+	for i, e := range p.entries {
+		if e.Path == name {
+			p.MoveCursor(i)
+			break
+		}
+	}
+}
+
+// loadSmart lists the smart playlists, or the tracks of the open one.
+func (p *Panel) loadSmart() {
+	p.entries = nil
+	p.cursor = 0
+	p.offset = 0
+	p.err = nil
+
+	if p.smartOpen == "" {
+		for _, sp := range p.smartPlaylists {
+			p.entries = append(p.entries, Entry{Name: sp.Name, Path: sp.Name, Detail: sp.Query.String()})
+		}
+		return
+	}
+
+	tracks, err := p.smartTracks(p.smartOpen)
+	if err != nil {
+		p.err = err
+		return
+	}
+	for _, t := range tracks {
+		p.entries = append(p.entries, Entry{
+			Name:   t.Label(),
+			Path:   t.Path,
+			Detail: t.Artist() + " · " + t.Album(),
+		})
+	}
+}
+
+// smartTracks evaluates the smart playlist with the given name against the library.
+func (p *Panel) smartTracks(name string) ([]library.Track, error) {
+	if p.library == nil {
+		return nil, nil
+	}
+	for _, sp := range p.smartPlaylists {
+		if sp.Name == name {
+			return sp.Tracks(p.library.Tracks(), p.index)
+		}
+	}
+	return nil, fmt.Errorf("unknown smart playlist %q", name)
+}
+
+// smartFiles returns the audio files of the smart playlist with the given name.
+func (p *Panel) smartFiles(name string) []audio.File {
+	tracks, err := p.smartTracks(name)
+	if err != nil {
+		p.err = err
+		return nil
+	}
+	files := make([]audio.File, len(tracks))
+	for i, t := range tracks {
+		files[i] = t.Audio()
+	}
+	return files
+}
+
+func (p *Panel) smartHeader() string {
+	info := lipgloss.NewStyle().Foreground(styles.GreyColor())
+	if p.smartOpen == "" {
+		return styles.ContrastHighlight(" Smart playlists ") +
+			info.Render(fmt.Sprintf(" %d playlists", len(p.smartPlaylists)))
+	}
+	return styles.ContrastHighlight(" "+p.smartOpen+" ") + info.Render(fmt.Sprintf(" %d tracks", len(p.entries)))
+}
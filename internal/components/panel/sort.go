@@ -0,0 +1,153 @@
+package panel
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SortOrder : How the files of a directory are sorted
+type SortOrder int
+
+const (
+	SortName SortOrder = iota
+	SortModTime
+	SortDuration
+	SortTrack
+	SortPlays
+	SortRating
+)
+
+var sortNames = []string{"name", "mtime", "duration", "track", "plays", "rating"}
+
+func (o SortOrder) String() string {
+	return sortNames[o]
+}
+
+// ParseSortOrder converts a sort order name into a SortOrder.
+func ParseSortOrder(s string) (SortOrder, error) {
+	for i, name := range sortNames {
+		if strings.EqualFold(s, name) {
+			return SortOrder(i), nil
+		}
+	}
+	return SortName, fmt.Errorf("unknown sort order %q, use one of: %s", s, strings.Join(sortNames, ", "))
+}
+
+// SortMsg is sent when the user changes the sort order, so it can be persisted.
+type SortMsg struct {
+	Order   SortOrder
+	Reverse bool
+}
+
+// SetSort sets the sort order of the files.
+func (p *Panel) SetSort(order SortOrder, reverse bool) {
+	p.sortOrder = order
+	p.sortReverse = reverse
+}
+
+// NextSort cycles to the next sort order and sorts the entries again.
+func (p *Panel) NextSort() SortMsg {
+	p.sortOrder = (p.sortOrder + 1) % SortOrder(len(sortNames))
+	p.Refresh()
+	return SortMsg{Order: p.sortOrder, Reverse: p.sortReverse}
+}
+
+// ReverseSort reverses the sort order and sorts the entries again.
+func (p *Panel) ReverseSort() SortMsg {
+	p.sortReverse = !p.sortReverse
+	p.Refresh()
+	return SortMsg{Order: p.sortOrder, Reverse: p.sortReverse}
+}
+
+// sortFiles sorts the file entries (not the directories) with the current order.
+// Names are always used to break ties.
+func (p *Panel) sortFiles(files []Entry) {
+	var key func(e Entry) int64
+	// Files not probed yet go last, whatever the direction
+	unknown := make(map[string]bool)
+	switch p.sortOrder {
+	case SortModTime:
+		key = func(e Entry) int64 { return p.modTime(e.Path).UnixNano() }
+	case SortDuration:
+		key = func(e Entry) int64 {
+			d, ok := p.duration(e.Path)
+			if !ok {
+				unknown[e.Path] = true
+				p.unprobed = append(p.unprobed, e.Path)
+			}
+			return int64(d)
+		}
+	case SortTrack:
+		key = func(e Entry) int64 { return int64(trackNumber(e.Name)) }
+	case SortPlays:
+		// Most played first
+		plays := p.playCounts()
+		key = func(e Entry) int64 { return -int64(plays[e.Path]) }
+	case SortRating:
+		// Best rated first
+		ratings := p.ratings()
+		key = func(e Entry) int64 { return -int64(ratingKey(ratings[e.Path])) }
+	}
+
+	keys := make(map[string]int64, len(files))
+	if key != nil {
+		for _, e := range files {
+			keys[e.Path] = key(e)
+		}
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if ua, ub := unknown[a.Path], unknown[b.Path]; ua != ub {
+			return ub
+		}
+		if p.sortReverse {
+			a, b = b, a
+		}
+		if ka, kb := keys[a.Path], keys[b.Path]; ka != kb {
+			return ka < kb
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+}
+
+func (p *Panel) modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// duration returns the duration of the file, taken from the library or from an earlier probe.
+// False if the file has not been probed yet.
+func (p *Panel) duration(path string) (time.Duration, bool) {
+	if t, ok := p.track(path); ok {
+		return t.Duration, true
+	}
+	d, ok := p.durations[path]
+	return d, ok
+}
+
+// trackNumber reads the number at the start of a file name, like "01 - Song.mp3".
+// Files without number go last.
+func trackNumber(name string) int {
+	end := strings.IndexFunc(name, func(r rune) bool { return !unicode.IsDigit(r) })
+	if end == 0 {
+		return math.MaxInt
+	}
+	if end < 0 {
+		end = len(name)
+	}
+	n, err := strconv.Atoi(name[:end])
+	if err != nil {
+		return math.MaxInt
+	}
+	return n
+}
@@ -0,0 +1,57 @@
+package panel
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseSortOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    SortOrder
+		wantErr bool
+	}{
+		{"name", SortName, false},
+		{"mtime", SortModTime, false},
+		{"Duration", SortDuration, false},
+		{"TRACK", SortTrack, false},
+		{"plays", SortPlays, false},
+		{"rating", SortRating, false},
+		{"", SortName, true},
+		{"size", SortName, true},
+		{"name ", SortName, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSortOrder(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSortOrder(%q) error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseSortOrder(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	for order := SortName; order <= SortRating; order++ {
+		if got, err := ParseSortOrder(order.String()); err != nil || got != order {
+			t.Errorf("ParseSortOrder(%q) = %v, %v, want %v", order, got, err, order)
+		}
+	}
+}
+
+func TestTrackNumber(t *testing.T) {
+	tests := []struct {
+		name string
+		want int
+	}{
+		{"01 - Song.mp3", 1},
+		{"12.flac", 12},
+		{"7", 7},
+		{"Song.mp3", math.MaxInt},
+		{"", math.MaxInt},
+	}
+	for _, tt := range tests {
+		if got := trackNumber(tt.name); got != tt.want {
+			t.Errorf("trackNumber(%q) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
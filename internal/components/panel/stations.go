@@ -0,0 +1,323 @@
+package panel
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/audio"
+	"github.com/nicolito128/tempo/internal/styles"
+	"github.com/nicolito128/tempo/internal/tags"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// Station : An internet radio saved in the stations view, played from the URL of its stream
+type Station struct {
+	Name   string
+	URL    string
+	Genres []string
+}
+
+// Audio returns the audio file of the stream, named after the station and tagged with its
+// genres, so no tags are requested from the radio.
+func (s Station) Audio() audio.File {
+	a := audio.NewFile(s.URL)
+	a.SetName(s.Name)
+	a.SetTags(tags.Tags{Genre: strings.Join(s.Genres, ", ")})
+	return a
+}
+
+// StationsMsg is sent when the saved stations change, so they can be persisted.
+type StationsMsg struct {
+	Stations []Station
+}
+
+// Steps of the station form
+const (
+	stationURLStep int = iota
+	stationNameStep
+	stationGenresStep
+)
+
+// stationForm : The station being added or edited, asked one field at a time
+type stationForm struct {
+	active bool
+	step   int
+
+	// URL of the station being edited, empty when adding one
+	editing string
+	station Station
+
+	prompt textinput.Model
+}
+
+func newStationForm() stationForm {
+	return stationForm{prompt: textinput.New()}
+}
+
+// SetStations sets the stations listed by the stations view.
+func (p *Panel) SetStations(stations []Station) {
+	p.stations = slices.Clone(stations)
+	if !slices.Contains(p.stationGenres(), p.genre) {
+		p.genre = ""
+	}
+	if p.view == StationsView {
+		p.Refresh()
+	}
+}
+
+func (p *Panel) loadStations() {
+	p.entries = nil
+	p.cursor = 0
+	p.offset = 0
+	p.err = nil
+
+	for _, s := range p.stations {
+		if p.genre != "" && !slices.Contains(s.Genres, p.genre) {
+			continue
+		}
+		detail := s.URL
+		if len(s.Genres) > 0 {
+			detail = strings.Join(s.Genres, ", ") + " · " + detail
+		}
+		p.entries = append(p.entries, Entry{Name: s.Name, Path: s.URL, Detail: detail})
+	}
+}
+
+// selectedStation returns the station under the cursor.
+func (p *Panel) selectedStation() (Station, bool) {
+	e, ok := p.Selected()
+	if !ok {
+		return Station{}, false
+	}
+	i := slices.IndexFunc(p.stations, func(s Station) bool { return s.URL == e.Path })
+	if i < 0 {
+		return Station{}, false
+	}
+	return p.stations[i], true
+}
+
+func (p *Panel) updateStations(msg tea.Msg) tea.Cmd {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+	if p.updateNavigation(keyMsg) {
+		return nil
+	}
+
+	km := p.keys
+	switch {
+	case key.Matches(keyMsg, km.BrowserUp):
+		p.MoveCursor(-1)
+
+	case key.Matches(keyMsg, km.BrowserDown):
+		p.MoveCursor(1)
+
+	case key.Matches(keyMsg, km.BrowserPlay):
+		if s, ok := p.selectedStation(); ok {
+			return p.playFiles([]audio.File{s.Audio()})
+		}
+
+	case key.Matches(keyMsg, km.BrowserEnqueue):
+		if s, ok := p.selectedStation(); ok {
+			return p.enqueueFiles([]audio.File{s.Audio()})
+		}
+
+	case key.Matches(keyMsg, km.BrowserNew):
+		return p.startStationForm(Station{})
+
+	case key.Matches(keyMsg, km.BrowserEdit):
+		if s, ok := p.selectedStation(); ok {
+			return p.startStationForm(s)
+		}
+
+	case key.Matches(keyMsg, km.BrowserDelete):
+		s, ok := p.selectedStation()
+		if !ok {
+			return nil
+		}
+		p.stations = slices.DeleteFunc(p.stations, func(other Station) bool { return other.URL == s.URL })
+		if !slices.Contains(p.stationGenres(), p.genre) {
+			p.genre = ""
+		}
+		p.Refresh()
+		return p.stationsChanged()
+
+	case key.Matches(keyMsg, km.BrowserGenre):
+		p.NextGenre()
+
+	case key.Matches(keyMsg, km.BrowserView):
+		p.ToggleView()
+	}
+	return nil
+}
+
+// stationGenres returns the genres of the stations, sorted.
+func (p *Panel) stationGenres() []string {
+	var genres []string
+	for _, s := range p.stations {
+		genres = append(genres, s.Genres...)
+	}
+	slices.Sort(genres)
+	return slices.Compact(genres)
+}
+
+// NextGenre cycles the genre the stations view is filtered by, the last one showing every
+// station again.
+func (p *Panel) NextGenre() {
+	genres := p.stationGenres()
+	i := slices.Index(genres, p.genre)
+	switch {
+	case len(genres) == 0:
+		p.genre = ""
+	case p.genre == "":
+		p.genre = genres[0]
+	case i < 0 || i == len(genres)-1:
+		p.genre = ""
+	default:
+		p.genre = genres[i+1]
+	}
+	p.load()
+}
+
+// startStationForm asks for the URL, the name and the genres of the station, starting from
+// the ones of s when it is edited.
+func (p *Panel) startStationForm(s Station) tea.Cmd {
+	p.err = nil
+	p.stationForm.active = true
+	p.stationForm.editing = s.URL
+	p.stationForm.station = s
+	return p.stationStep(stationURLStep)
+}
+
+// stationStep asks for the field of the step.
+func (p *Panel) stationStep(step int) tea.Cmd {
+	f := &p.stationForm
+	f.step = step
+	switch step {
+	case stationURLStep:
+		f.prompt.Prompt = "Station URL: "
+		f.prompt.Placeholder = "http://radio.example.com:8000/stream"
+		f.prompt.SetValue(f.station.URL)
+	case stationNameStep:
+		f.prompt.Prompt = "Name: "
+		f.prompt.Placeholder = audio.NewFile(f.station.URL).Name()
+		f.prompt.SetValue(f.station.Name)
+	case stationGenresStep:
+		f.prompt.Prompt = "Genres: "
+		f.prompt.Placeholder = "jazz, blues"
+		f.prompt.SetValue(strings.Join(f.station.Genres, ", "))
+	}
+	f.prompt.CursorEnd()
+	return f.prompt.Focus()
+}
+
+// updateStationForm handles the prompts of the station form.
+func (p *Panel) updateStationForm(msg tea.Msg) tea.Cmd {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "esc":
+			p.stationForm.active = false
+			p.stationForm.prompt.Blur()
+			p.err = nil
+			return nil
+
+		case "enter":
+			return p.nextStationStep()
+		}
+	}
+
+	var cmd tea.Cmd
+	p.stationForm.prompt, cmd = p.stationForm.prompt.Update(msg)
+	return cmd
+}
+
+// nextStationStep takes the value of the prompt, asking for the next field or saving the
+// station after the last one.
+func (p *Panel) nextStationStep() tea.Cmd {
+	f := &p.stationForm
+	value := strings.TrimSpace(f.prompt.Value())
+	p.err = nil
+
+	switch f.step {
+	case stationURLStep:
+		if !engine.IsURL(value) {
+			p.err = fmt.Errorf("%q is not an HTTP(S) URL", value)
+			return nil
+		}
+		i := slices.IndexFunc(p.stations, func(s Station) bool { return s.URL == value })
+		if i >= 0 && value != f.editing {
+			p.err = fmt.Errorf("the station is already saved as %q", p.stations[i].Name)
+			return nil
+		}
+		f.station.URL = value
+		return p.stationStep(stationNameStep)
+
+	case stationNameStep:
+		if value == "" {
+			value = f.prompt.Placeholder
+		}
+		f.station.Name = value
+		return p.stationStep(stationGenresStep)
+	}
+
+	f.station.Genres = nil
+	for _, genre := range strings.Split(value, ",") {
+		if genre = strings.TrimSpace(genre); genre != "" && !slices.Contains(f.station.Genres, genre) {
+			f.station.Genres = append(f.station.Genres, genre)
+		}
+	}
+	f.active = false
+	f.prompt.Blur()
+	return p.saveStation(f.editing, f.station)
+}
+
+// saveStation replaces the station at the URL editing with s, or adds s if editing is empty,
+// and selects it.
+func (p *Panel) saveStation(editing string, s Station) tea.Cmd {
+	if i := slices.IndexFunc(p.stations, func(other Station) bool { return other.URL == editing }); editing != "" && i >= 0 {
+		p.stations[i] = s
+	} else {
+		p.stations = append(p.stations, s)
+	}
+	if p.genre != "" && !slices.Contains(s.Genres, p.genre) {
+		p.genre = ""
+	}
+	p.load()
+	for i, e := range p.entries {
+		if e.Path == s.URL {
+			p.MoveCursor(i)
+			break
+		}
+	}
+	return p.stationsChanged()
+}
+
+func (p *Panel) stationsChanged() tea.Cmd {
+	stations := slices.Clone(p.stations)
+	return func() tea.Msg { return StationsMsg{Stations: stations} }
+}
+
+func (p *Panel) stationsHeader() string {
+	info := fmt.Sprintf(" %d stations", len(p.stations))
+	if len(p.stations) == 1 {
+		info = " 1 station"
+	}
+	if p.genre != "" {
+		info += " · " + p.genre
+	}
+	return styles.ContrastHighlight(" Stations ") + lipgloss.NewStyle().Foreground(styles.GreyColor()).Render(info)
+}
+
+// stationFormHelp returns the help of the prompt being shown.
+func (p *Panel) stationFormHelp() string {
+	if p.stationForm.step == stationGenresStep {
+		return styles.Help("\nℹ: Enter (save) | Esc (cancel)")
+	}
+	return styles.Help("\nℹ: Enter (next) | Esc (cancel)")
+}
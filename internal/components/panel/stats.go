@@ -0,0 +1,115 @@
+package panel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/library"
+	"github.com/nicolito128/tempo/internal/styles"
+)
+
+const (
+	// Number of tracks listed by the statistics view
+	TopTracksLimit int = 50
+	// Number of artists shown in the statistics header
+	TopArtistsLimit int = 5
+)
+
+// PlaysChanged reloads the views showing play events or counts.
+func (p *Panel) PlaysChanged() {
+	if p.searching {
+		return
+	}
+	switch p.view {
+	case LibraryView:
+		p.LibraryChanged()
+	case HistoryView, StatsView:
+		p.Refresh()
+	}
+}
+
+// loadStats aggregates the listening statistics and lists the most played tracks.
+func (p *Panel) loadStats() {
+	p.entries = nil
+	p.cursor = 0
+	p.offset = 0
+	p.err = nil
+	p.summary = library.Summary{}
+
+	if p.index == nil {
+		return
+	}
+
+	stats, err := p.index.Stats()
+	if err != nil {
+		p.err = err
+		return
+	}
+
+	p.summary = library.Summarize(stats, p.library, TopTracksLimit)
+	for _, c := range p.summary.TopTracks {
+		p.entries = append(p.entries, Entry{
+			Name:   c.Name,
+			Path:   c.Path,
+			Detail: plural(c.Plays, "play"),
+		})
+	}
+	if len(p.summary.TopArtists) > TopArtistsLimit {
+		p.summary.TopArtists = p.summary.TopArtists[:TopArtistsLimit]
+	}
+}
+
+// playCounts returns the number of completed plays of every played track.
+func (p *Panel) playCounts() map[string]int {
+	if p.index == nil {
+		return nil
+	}
+	stats, err := p.index.Stats()
+	if err != nil {
+		return nil
+	}
+
+	counts := make(map[string]int, len(stats))
+	for path, st := range stats {
+		counts[path] = st.Plays
+	}
+	return counts
+}
+
+func (p *Panel) statsHeader() string {
+	info := lipgloss.NewStyle().Foreground(styles.GreyColor())
+
+	title := styles.ContrastHighlight(" Statistics ") + info.Render(fmt.Sprintf(" %s listened · %s · %s",
+		formatListenTime(p.summary.ListenTime),
+		plural(p.summary.Plays, "play"),
+		plural(p.summary.Skips, "skip"),
+	))
+	if len(p.summary.TopArtists) == 0 {
+		return title
+	}
+
+	artists := make([]string, len(p.summary.TopArtists))
+	for i, c := range p.summary.TopArtists {
+		artists[i] = fmt.Sprintf("%s (%d)", c.Name, c.Plays)
+	}
+	return title + "\n" + info.Render(" Top artists: "+strings.Join(artists, ", "))
+}
+
+// formatListenTime formats long durations, like "12h 05m".
+func formatListenTime(d time.Duration) string {
+	h := int(d.Hours())
+	m := int(d.Minutes()) % 60
+	if h == 0 {
+		return fmt.Sprintf("%dm", m)
+	}
+	return fmt.Sprintf("%dh %02dm", h, m)
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
@@ -0,0 +1,223 @@
+package panel
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/components/player"
+	"github.com/nicolito128/tempo/internal/library"
+	"github.com/nicolito128/tempo/internal/styles"
+)
+
+// Column : A column of the library table
+type Column int
+
+const (
+	TitleColumn Column = iota
+	ArtistColumn
+	AlbumColumn
+	DurationColumn
+	FormatColumn
+	PlaysColumn
+	RatingColumn
+)
+
+var columnNames = []string{"Title", "Artist", "Album", "Duration", "Format", "Plays", "Rating"}
+
+var columnWidths = []int{23, 14, 14, 8, 11, 7, 8}
+
+func (c Column) String() string {
+	return columnNames[c]
+}
+
+// trackTable : The library tracks shown in a table sortable by column
+type trackTable struct {
+	model table.Model
+
+	// Tracks in the same order as the rows
+	tracks []library.Track
+
+	// Number of plays of each track path
+	plays map[string]int
+
+	// Rating of each track path
+	ratings map[string]library.Rating
+
+	// marked reports whether the row with the given index and track path is marked
+	marked func(i int, path string) bool
+
+	sortBy Column
+	desc   bool
+
+	// Theme the styles were built with, rebuilt when its colors change
+	theme styles.Theme
+}
+
+// tableStyles returns the styles of the table with the colors of the current theme.
+func tableStyles() table.Styles {
+	s := table.DefaultStyles()
+	s.Header = s.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(styles.GreyColor()).
+		BorderBottom(true).
+		Foreground(styles.PrimaryColor())
+	s.Selected = s.Selected.
+		Foreground(styles.HighlightColor()).
+		Background(styles.PrimaryColor()).
+		Bold(false)
+	return s
+}
+
+func newTrackTable() trackTable {
+
+	t := trackTable{theme: styles.CurrentTheme()}
+	t.model = table.New(
+		table.WithHeight(VisibleEntries),
+		table.WithStyles(tableStyles()),
+		table.WithFocused(true),
+	)
+	t.setColumns()
+	return t
+}
+
+// SetTracks replaces the rows of the table, keeping the selected track if possible.
+func (t *trackTable) SetTracks(tracks []library.Track) {
+	selected, hasSelected := t.Selected()
+
+	t.tracks = tracks
+	t.sort()
+
+	if hasSelected {
+		for i, tr := range t.tracks {
+			if tr.Path == selected.Path {
+				t.model.SetCursor(i)
+				return
+			}
+		}
+	}
+	t.model.SetCursor(min(t.model.Cursor(), max(len(t.tracks)-1, 0)))
+}
+
+// SetPlays sets the play counts shown in the table. Call SetTracks to update the rows.
+func (t *trackTable) SetPlays(plays map[string]int) {
+	t.plays = plays
+}
+
+// SetRatings sets the ratings shown in the table. Call SetTracks to update the rows.
+func (t *trackTable) SetRatings(ratings map[string]library.Rating) {
+	t.ratings = ratings
+}
+
+// Selected returns the track under the cursor.
+func (t *trackTable) Selected() (library.Track, bool) {
+	i := t.model.Cursor()
+	if i < 0 || i >= len(t.tracks) {
+		return library.Track{}, false
+	}
+	return t.tracks[i], true
+}
+
+// SortBy sorts the table by the given column. Sorting again by the same column reverses the order.
+func (t *trackTable) SortBy(c Column) {
+	if t.sortBy == c {
+		t.desc = !t.desc
+	} else {
+		t.sortBy = c
+		t.desc = false
+	}
+	t.SetTracks(t.tracks)
+}
+
+// NextSort sorts the table by the next column.
+func (t *trackTable) NextSort() {
+	t.SortBy((t.sortBy + 1) % Column(len(columnNames)))
+}
+
+func (t *trackTable) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	t.model, cmd = t.model.Update(msg)
+	return cmd
+}
+
+func (t *trackTable) View() string {
+	if theme := styles.CurrentTheme(); theme != t.theme {
+		t.theme = theme
+		t.model.SetStyles(tableStyles())
+	}
+	return t.model.View()
+}
+
+func (t *trackTable) sort() {
+	less := func(a, b library.Track) bool {
+		switch t.sortBy {
+		case ArtistColumn:
+			return compareFold(a.Artist(), b.Artist())
+		case AlbumColumn:
+			return compareFold(a.Album(), b.Album())
+		case DurationColumn:
+			return a.Duration < b.Duration
+		case FormatColumn:
+			return a.Format() < b.Format()
+		case PlaysColumn:
+			return t.plays[a.Path] < t.plays[b.Path]
+		case RatingColumn:
+			return ratingKey(t.ratings[a.Path]) < ratingKey(t.ratings[b.Path])
+		}
+		return compareFold(a.Title(), b.Title())
+	}
+
+	sort.SliceStable(t.tracks, func(i, j int) bool {
+		if t.desc {
+			return less(t.tracks[j], t.tracks[i])
+		}
+		return less(t.tracks[i], t.tracks[j])
+	})
+
+	t.setColumns()
+	t.setRows()
+}
+
+// setRows renders the rows of the tracks, in their current order.
+func (t *trackTable) setRows() {
+	rows := make([]table.Row, len(t.tracks))
+	for i, tr := range t.tracks {
+		title := tr.Title()
+		if t.marked != nil && t.marked(i, tr.Path) {
+			title = "● " + title
+		}
+		rows[i] = table.Row{
+			title,
+			tr.Artist(),
+			tr.Album(),
+			player.FormatSecondsToString(tr.Duration),
+			tr.Format(),
+			strconv.Itoa(t.plays[tr.Path]),
+			shortRating(t.ratings[tr.Path]),
+		}
+	}
+	t.model.SetRows(rows)
+}
+
+// setColumns updates the headers, marking the sorted column.
+func (t *trackTable) setColumns() {
+	cols := make([]table.Column, len(columnNames))
+	for i, name := range columnNames {
+		if Column(i) == t.sortBy {
+			if t.desc {
+				name += " ▼"
+			} else {
+				name += " ▲"
+			}
+		}
+		cols[i] = table.Column{Title: name, Width: columnWidths[i]}
+	}
+	t.model.SetColumns(cols)
+}
+
+func compareFold(a, b string) bool {
+	return strings.ToLower(a) < strings.ToLower(b)
+}
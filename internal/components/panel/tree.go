@@ -0,0 +1,278 @@
+package panel
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/library"
+	"github.com/nicolito128/tempo/internal/styles"
+)
+
+// Artist and album the tracks without those tags are listed under
+const (
+	unknownArtist string = "Unknown artist"
+	unknownAlbum  string = "Unknown album"
+)
+
+// nodeKind : The level of a node in the tree
+type nodeKind int
+
+const (
+	artistNode nodeKind = iota
+	albumNode
+	trackNode
+)
+
+// treeNode : An artist, album or track row of the tree
+type treeNode struct {
+	kind nodeKind
+	name string
+
+	// Unique key used to remember if the node is expanded
+	key string
+
+	// Tracks under the node, or the track itself
+	tracks []library.Track
+}
+
+// trackTree : The library tracks grouped by artist and album, with collapsible nodes
+type trackTree struct {
+	// Every artist and album node, in order
+	groups []treeNode
+
+	// albums of each artist key
+	albums map[string][]treeNode
+
+	expanded map[string]bool
+
+	// Visible rows
+	rows []treeNode
+
+	cursor int
+	offset int
+}
+
+func newTrackTree() trackTree {
+	return trackTree{expanded: make(map[string]bool)}
+}
+
+// SetTracks groups the tracks by the album artist and album of their tags, so compilations are
+// a single album. The untagged ones are listed last, under unknownArtist and unknownAlbum.
+func (t *trackTree) SetTracks(tracks []library.Track) {
+	byArtist := make(map[string]map[string][]library.Track)
+	for _, tr := range tracks {
+		artist, album := treeArtist(tr), cmp.Or(tr.Tags.Album, unknownAlbum)
+		if byArtist[artist] == nil {
+			byArtist[artist] = make(map[string][]library.Track)
+		}
+		byArtist[artist][album] = append(byArtist[artist][album], tr)
+	}
+
+	t.groups = nil
+	t.albums = make(map[string][]treeNode)
+	for _, artist := range sortedKeys(byArtist, unknownArtist) {
+		artistKey := artist
+		var all []library.Track
+
+		for _, album := range sortedKeys(byArtist[artist], unknownAlbum) {
+			albumTracks := byArtist[artist][album]
+			library.SortAlbumOrder(albumTracks)
+			all = append(all, albumTracks...)
+			t.albums[artistKey] = append(t.albums[artistKey], treeNode{
+				kind:   albumNode,
+				name:   album,
+				key:    artistKey + "\x00" + album,
+				tracks: albumTracks,
+			})
+		}
+
+		t.groups = append(t.groups, treeNode{kind: artistNode, name: artist, key: artistKey, tracks: all})
+	}
+
+	t.flatten()
+}
+
+// Selected returns the node under the cursor.
+func (t *trackTree) Selected() (treeNode, bool) {
+	if t.cursor < 0 || t.cursor >= len(t.rows) {
+		return treeNode{}, false
+	}
+	return t.rows[t.cursor], true
+}
+
+// MoveCursor moves the cursor by delta rows.
+func (t *trackTree) MoveCursor(delta int) {
+	if len(t.rows) == 0 {
+		return
+	}
+	t.cursor = min(max(t.cursor+delta, 0), len(t.rows)-1)
+
+	if t.cursor < t.offset {
+		t.offset = t.cursor
+	}
+	if t.cursor >= t.offset+VisibleEntries {
+		t.offset = t.cursor - VisibleEntries + 1
+	}
+}
+
+// Expand opens the selected artist or album.
+func (t *trackTree) Expand() {
+	n, ok := t.Selected()
+	if !ok || n.kind == trackNode {
+		return
+	}
+	t.expanded[n.key] = true
+	t.flatten()
+}
+
+// Collapse closes the selected node, or moves to its parent if it is already closed.
+func (t *trackTree) Collapse() {
+	n, ok := t.Selected()
+	if !ok {
+		return
+	}
+	if n.kind != trackNode && t.expanded[n.key] {
+		t.expanded[n.key] = false
+		t.flatten()
+		return
+	}
+
+	// Move to the parent node
+	for i := t.cursor - 1; i >= 0; i-- {
+		if t.rows[i].kind < n.kind {
+			t.MoveCursor(i - t.cursor)
+			return
+		}
+	}
+}
+
+// Toggle expands or collapses the selected node.
+func (t *trackTree) Toggle() {
+	n, ok := t.Selected()
+	if !ok || n.kind == trackNode {
+		return
+	}
+	if t.expanded[n.key] {
+		t.Collapse()
+	} else {
+		t.Expand()
+	}
+}
+
+// flatten computes the visible rows, keeping the cursor over the same node.
+func (t *trackTree) flatten() {
+	selected, hasSelected := t.Selected()
+
+	t.rows = nil
+	for _, artist := range t.groups {
+		t.rows = append(t.rows, artist)
+		if !t.expanded[artist.key] {
+			continue
+		}
+		for _, album := range t.albums[artist.key] {
+			t.rows = append(t.rows, album)
+			if !t.expanded[album.key] {
+				continue
+			}
+			multiDisc := album.tracks[0].Disc() != album.tracks[len(album.tracks)-1].Disc()
+			for _, tr := range album.tracks {
+				t.rows = append(t.rows, treeNode{
+					kind:   trackNode,
+					name:   trackLabel(tr, multiDisc),
+					key:    tr.Path,
+					tracks: []library.Track{tr},
+				})
+			}
+		}
+	}
+
+	cursor := min(t.cursor, max(len(t.rows)-1, 0))
+	if hasSelected {
+		for i, n := range t.rows {
+			if n.key == selected.key {
+				cursor = i
+				break
+			}
+		}
+	}
+	t.cursor = 0
+	t.offset = 0
+	t.MoveCursor(cursor)
+}
+
+func (t *trackTree) View() string {
+	countStyle := lipgloss.NewStyle().Foreground(styles.GreyColor())
+	artistStyle := lipgloss.NewStyle().Foreground(styles.SecundaryColor())
+
+	var lines []string
+	end := min(t.offset+VisibleEntries, len(t.rows))
+	for i := t.offset; i < end; i++ {
+		n := t.rows[i]
+
+		var line string
+		switch n.kind {
+		case artistNode, albumNode:
+			arrow := "▸"
+			if t.expanded[n.key] {
+				arrow = "▾"
+			}
+			indent := strings.Repeat("  ", int(n.kind))
+			line = fmt.Sprintf(" %s%s %s ", indent, arrow, n.name)
+			if i != t.cursor {
+				if n.kind == artistNode {
+					line = artistStyle.Render(line)
+				}
+				line += countStyle.Render(fmt.Sprintf("(%d)", len(n.tracks)))
+			}
+		case trackNode:
+			line = fmt.Sprintf("     ♪ %s ", n.name)
+		}
+
+		if i == t.cursor {
+			line = styles.PrimaryHighlight(line)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// trackLabel prefixes the title with the track position, and the disc in multi-disc albums.
+func trackLabel(tr library.Track, multiDisc bool) string {
+	switch {
+	case tr.TrackNumber() == 0:
+		return tr.Title()
+	case multiDisc:
+		return fmt.Sprintf("%d-%02d %s", tr.Disc(), tr.TrackNumber(), tr.Title())
+	}
+	return fmt.Sprintf("%02d %s", tr.TrackNumber(), tr.Title())
+}
+
+// treeArtist returns the artist the album of the track is listed under, from its tags only,
+// since the directories rarely match them.
+func treeArtist(tr library.Track) string {
+	switch {
+	case tr.Tags.AlbumArtist != "":
+		return tr.Tags.AlbumArtist
+	case tr.Tags.Compilation:
+		return library.VariousArtists
+	}
+	return cmp.Or(tr.Tags.Artist, unknownArtist)
+}
+
+// sortedKeys returns the keys of m sorted without case, with the last one at the end.
+func sortedKeys[V any](m map[string]V, last string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if (keys[i] == last) != (keys[j] == last) {
+			return keys[j] == last
+		}
+		return strings.ToLower(keys[i]) < strings.ToLower(keys[j])
+	})
+	return keys
+}
@@ -0,0 +1,168 @@
+package player
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/audio"
+	"github.com/nicolito128/tempo/internal/styles"
+)
+
+// A position this close after a bookmark is at the bookmark, as seeking to it can land a
+// sample before
+const bookmarkSlack time.Duration = 50 * time.Millisecond
+
+// BookmarksMsg asks to save the bookmarks of the audio file at Path, after they changed.
+type BookmarksMsg struct {
+	Path      string
+	Bookmarks []audio.Bookmark
+}
+
+// naming : The prompt asking for the name of a new bookmark
+type naming struct {
+	// active if the prompt is shown and takes the keys
+	active bool
+	input  textinput.Model
+	// Position bookmarked, the one played when the prompt was shown
+	pos time.Duration
+}
+
+func newNaming() naming {
+	input := textinput.New()
+	input.Prompt = "Bookmark name: "
+	return naming{input: input}
+}
+
+// Bookmarks returns the bookmarks of the current audio, sorted by position.
+func (p *Player) Bookmarks() []audio.Bookmark {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.bookmarks
+}
+
+// SetBookmarks sets the bookmarks of the current audio, after it is loaded.
+func (p *Player) SetBookmarks(bookmarks []audio.Bookmark) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bookmarks = slices.Clone(bookmarks)
+	sort.SliceStable(p.bookmarks, func(i, j int) bool {
+		return p.bookmarks[i].Position < p.bookmarks[j].Position
+	})
+}
+
+// ToggleBookmark removes the bookmark just passed, or else shows the prompt asking for the
+// name of a bookmark at the current position.
+func (p *Player) ToggleBookmark() tea.Cmd {
+	if p.engine.Path() == "" || p.engine.Live() {
+		return nil
+	}
+
+	pos := p.engine.Position()
+	if i := bookmarkAt(p.bookmarks, pos); i >= 0 && pos-p.bookmarks[i].Position < chapterRestart {
+		return p.removeBookmark(i)
+	}
+
+	p.naming.active = true
+	p.naming.pos = pos.Truncate(time.Millisecond)
+	p.naming.input.SetValue("")
+	p.naming.input.Placeholder = FormatSecondsToString(p.naming.pos)
+	return p.naming.input.Focus()
+}
+
+// updateNaming handles the keys of the bookmark prompt: Enter adds the bookmark, named after
+// its position if no name was entered, and Esc closes it.
+func (p *Player) updateNaming(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		p.naming.active = false
+		p.naming.input.Blur()
+		return nil
+
+	case "enter":
+		p.naming.active = false
+		p.naming.input.Blur()
+		name := strings.TrimSpace(p.naming.input.Value())
+		if name == "" {
+			name = p.naming.input.Placeholder
+		}
+		return p.addBookmark(audio.Bookmark{Name: name, Position: p.naming.pos})
+	}
+
+	var cmd tea.Cmd
+	p.naming.input, cmd = p.naming.input.Update(msg)
+	return cmd
+}
+
+// addBookmark adds a bookmark to the current audio, in the order of the positions.
+func (p *Player) addBookmark(b audio.Bookmark) tea.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := sort.Search(len(p.bookmarks), func(i int) bool { return p.bookmarks[i].Position > b.Position })
+	p.bookmarks = slices.Insert(p.bookmarks, i, b)
+	return p.bookmarksChanged()
+}
+
+// removeBookmark removes the bookmark at index i.
+func (p *Player) removeBookmark(i int) tea.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bookmarks = slices.Delete(p.bookmarks, i, i+1)
+	return p.bookmarksChanged()
+}
+
+// NextBookmark seeks to the bookmark after the current position.
+func (p *Player) NextBookmark() tea.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	next := bookmarkAt(p.bookmarks, p.engine.Position()) + 1
+	if next >= len(p.bookmarks) {
+		return nil
+	}
+	return p.seek(p.bookmarks[next].Position)
+}
+
+// PreviousBookmark seeks to the bookmark being played, or to the previous one if it was
+// just passed.
+func (p *Player) PreviousBookmark() tea.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pos := p.engine.Position()
+	current := bookmarkAt(p.bookmarks, pos)
+	if current < 0 {
+		return nil
+	}
+	if pos-p.bookmarks[current].Position < chapterRestart && current > 0 {
+		current--
+	}
+	return p.seek(p.bookmarks[current].Position)
+}
+
+// bookmarksChanged asks to save the bookmarks of the current audio. The caller must hold
+// p.mu.
+func (p *Player) bookmarksChanged() tea.Cmd {
+	if p.currentAudio == nil {
+		return nil
+	}
+	msg := BookmarksMsg{Path: p.currentAudio.Path(), Bookmarks: slices.Clone(p.bookmarks)}
+	return func() tea.Msg { return msg }
+}
+
+// bookmarkAt returns the index of the last bookmark at or before pos, or -1 if there is
+// none.
+func bookmarkAt(bookmarks []audio.Bookmark, pos time.Duration) int {
+	return sort.Search(len(bookmarks), func(i int) bool { return bookmarks[i].Position > pos+bookmarkSlack }) - 1
+}
+
+// namingView shows the bookmark prompt.
+func (p *Player) namingView() string {
+	if !p.naming.active {
+		return ""
+	}
+	return "\n" + styles.Prompt(p.naming.input)
+}
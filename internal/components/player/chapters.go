@@ -0,0 +1,70 @@
+package player
+
+import (
+	"sort"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/tags"
+)
+
+// Going to the previous chapter this far into the current one restarts it instead
+const chapterRestart time.Duration = 3 * time.Second
+
+// Chapters returns the chapters of the current audio, empty if it has none.
+func (p *Player) Chapters() []tags.Chapter {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.chapters
+}
+
+// Chapter returns the index of the chapter being played, or -1 before the first one.
+func (p *Player) Chapter() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return chapterAt(p.chapters, p.engine.Position())
+}
+
+// NextChapter seeks to the start of the chapter after the current one.
+func (p *Player) NextChapter() tea.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	next := chapterAt(p.chapters, p.engine.Position()) + 1
+	if next >= len(p.chapters) {
+		return nil
+	}
+	return p.seek(p.chapters[next].Start)
+}
+
+// PreviousChapter seeks to the start of the current chapter, or to the previous one if
+// the current chapter has just started.
+func (p *Player) PreviousChapter() tea.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pos := p.engine.Position()
+	current := chapterAt(p.chapters, pos)
+	if current < 0 {
+		return nil
+	}
+	if pos-p.chapters[current].Start < chapterRestart && current > 0 {
+		current--
+	}
+	return p.seek(p.chapters[current].Start)
+}
+
+// loadChapters reads the chapters of the current audio from its tags.
+func (p *Player) loadChapters() {
+	p.chapters = nil
+	md, err := tags.Read(p.currentAudio.Path())
+	if err != nil {
+		return
+	}
+	p.chapters = md.Chapters()
+}
+
+// chapterAt returns the index of the chapter playing at pos, or -1 before the first one.
+func chapterAt(chapters []tags.Chapter, pos time.Duration) int {
+	return sort.Search(len(chapters), func(i int) bool { return chapters[i].Start > pos }) - 1
+}
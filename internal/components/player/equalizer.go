@@ -0,0 +1,80 @@
+package player
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// Preset : A named curve of the equalizer
+type Preset struct {
+	Name  string
+	Gains engine.Gains
+}
+
+// Presets are the curves of the equalizer built into tempo, flat first.
+var Presets = []Preset{
+	{Name: "flat"},
+	{Name: "rock", Gains: engine.Gains{5, 4, 3, 1, -1, -1, 1, 3, 4, 5}},
+	{Name: "classical", Gains: engine.Gains{0, 0, 0, 0, 0, 0, -4, -4, -4, -6}},
+}
+
+// FindPreset returns the built-in preset with the given name.
+func FindPreset(name string) (Preset, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, preset := range Presets {
+		if preset.Name == name {
+			return preset, nil
+		}
+	}
+	return Preset{}, fmt.Errorf("unknown equalizer preset %q", name)
+}
+
+// Custom is the name of the curve changed from a preset, which is not one of them anymore.
+const Custom string = "custom"
+
+// ParseGains converts the gains in dB of a config into the gains of the equalizer, flat if
+// there are none.
+func ParseGains(values []float64) (engine.Gains, error) {
+	var gains engine.Gains
+	if len(values) == 0 {
+		return gains, nil
+	}
+	if len(values) != len(gains) {
+		return gains, fmt.Errorf("%d equalizer gains instead of %d", len(values), len(gains))
+	}
+	for i, g := range values {
+		if g < -engine.MaxBandGain || g > engine.MaxBandGain {
+			return gains, fmt.Errorf("gain %g dB of the %g Hz band is not between %g and %g", g, engine.EqualizerBands[i], -engine.MaxBandGain, engine.MaxBandGain)
+		}
+		gains[i] = g
+	}
+	return gains, nil
+}
+
+// Equalizer returns the curve of the equalizer.
+func (p *Player) Equalizer() Preset {
+	return Preset{Name: p.eqName, Gains: p.engine.Equalizer()}
+}
+
+// SetEqualizer plays with the given curve of the equalizer, its gains clamped to
+// engine.MaxBandGain up or down.
+func (p *Player) SetEqualizer(curve Preset) {
+	for i, g := range curve.Gains {
+		curve.Gains[i] = max(min(g, engine.MaxBandGain), -engine.MaxBandGain)
+	}
+	p.eqName = curve.Name
+	p.engine.SetEqualizer(curve.Gains)
+}
+
+// Preamp returns the gain in dB applied before the bands of the equalizer.
+func (p *Player) Preamp() float64 {
+	return p.engine.Preamp()
+}
+
+// SetPreamp sets the gain in dB applied before the bands of the equalizer, clamped to
+// engine.MaxPreamp up or down.
+func (p *Player) SetPreamp(db float64) {
+	p.engine.SetPreamp(max(min(db, engine.MaxPreamp), -engine.MaxPreamp))
+}
@@ -0,0 +1,109 @@
+package player
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/styles"
+)
+
+// jump : The prompt asking for a position of the audio to seek to
+type jump struct {
+	// active if the prompt is shown and takes the keys
+	active bool
+	input  textinput.Model
+	// Error of the last position entered
+	err error
+}
+
+func newJump() jump {
+	input := textinput.New()
+	input.Prompt = "Jump to: "
+	input.Placeholder = "mm:ss or hh:mm:ss"
+	return jump{input: input}
+}
+
+// ParseTimestamp converts a position like 90, 1:30 or 1:02:03 into a duration. The seconds
+// may have a fraction, like 1:30.5.
+func ParseTimestamp(s string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil || !(seconds >= 0 && seconds < math.MaxInt32) || (len(parts) > 1 && seconds >= 60) {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	pos := time.Duration(seconds * float64(time.Second))
+
+	unit := time.Minute
+	for i := len(parts) - 2; i >= 0; i-- {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil || n < 0 || (i > 0 && n >= 60) {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		pos += time.Duration(n) * unit
+		unit *= 60
+	}
+	return pos, nil
+}
+
+// Prompting reports whether the jump or the bookmark prompt is shown, taking every key.
+func (p *Player) Prompting() bool {
+	return p.jump.active || p.naming.active
+}
+
+// StartJump shows the prompt asking for the position to seek to.
+func (p *Player) StartJump() tea.Cmd {
+	if p.engine.Path() == "" || p.engine.Live() {
+		return nil
+	}
+	p.jump.active = true
+	p.jump.err = nil
+	p.jump.input.SetValue("")
+	return p.jump.input.Focus()
+}
+
+// updateJump handles the keys of the jump prompt: Enter seeks to the position entered and
+// Esc closes it.
+func (p *Player) updateJump(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		p.jump.active = false
+		p.jump.input.Blur()
+		return nil
+
+	case "enter":
+		pos, err := ParseTimestamp(p.jump.input.Value())
+		if err != nil {
+			p.jump.err = err
+			return nil
+		}
+		p.jump.active = false
+		p.jump.input.Blur()
+		return p.SeekTo(pos)
+	}
+
+	var cmd tea.Cmd
+	p.jump.input, cmd = p.jump.input.Update(msg)
+	return cmd
+}
+
+// jumpView shows the jump prompt, with the error of the last position entered.
+func (p *Player) jumpView() string {
+	if !p.jump.active {
+		return ""
+	}
+	s := "\n" + styles.Prompt(p.jump.input)
+	if p.jump.err != nil {
+		s += "  " + lipgloss.NewStyle().Foreground(styles.ProblemColor()).Render(p.jump.err.Error())
+	}
+	return s
+}
@@ -0,0 +1,537 @@
+package player
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Keys that cannot be bound to an action: Ctrl+C always quits
+var fixedKeys = []string{"ctrl+c"}
+
+// Prefix of the names of the actions of the browser, whose keys take the place of the ones
+// of the player while it is focused
+const browserPrefix = "browser_"
+
+// KeyMap : The keys of the actions of the player and of the rest of the UI, which the [keys]
+// section of the config can change
+type KeyMap struct {
+	Quit      key.Binding
+	PlayPause key.Binding
+
+	Rewind       key.Binding
+	Forward      key.Binding
+	RewindLarge  key.Binding
+	ForwardLarge key.Binding
+	Jump         key.Binding
+
+	VolumeUp       key.Binding
+	VolumeDown     key.Binding
+	VolumeUpFine   key.Binding
+	VolumeDownFine key.Binding
+	Mute           key.Binding
+
+	SpeedUp      key.Binding
+	SpeedDown    key.Binding
+	PitchUp      key.Binding
+	PitchDown    key.Binding
+	BalanceRight key.Binding
+	BalanceLeft  key.Binding
+	SwapChannels key.Binding
+	Mono         key.Binding
+
+	Next     key.Binding
+	Previous key.Binding
+	Favorite key.Binding
+	CopyPath key.Binding
+	Reveal   key.Binding
+	EditTags key.Binding
+
+	ReplayGain key.Binding
+	Silence    key.Binding
+	Analyze    key.Binding
+	Loop       key.Binding
+	Resume     key.Binding
+	Record     key.Binding
+
+	Bookmark         key.Binding
+	NextBookmark     key.Binding
+	PreviousBookmark key.Binding
+	NextChapter      key.Binding
+	PreviousChapter  key.Binding
+
+	Focus     key.Binding
+	Scan      key.Binding
+	Lyrics    key.Binding
+	Chapters  key.Binding
+	Devices   key.Binding
+	Equalizer key.Binding
+
+	// Rate gives the current track as many stars as its index, 0 clearing them
+	Rate [6]key.Binding
+	// JumpPercent seeks to as many tenths of the audio as its index
+	JumpPercent [10]key.Binding
+	// Back dismisses the notices, and in the browser the marks and the bookmarks view
+	Back key.Binding
+
+	BrowserUp           key.Binding
+	BrowserDown         key.Binding
+	BrowserParent       key.Binding
+	BrowserOpen         key.Binding
+	BrowserTop          key.Binding
+	BrowserBottom       key.Binding
+	BrowserPageUp       key.Binding
+	BrowserPageDown     key.Binding
+	BrowserHalfPageUp   key.Binding
+	BrowserHalfPageDown key.Binding
+	BrowserJump         key.Binding
+	BrowserToggle       key.Binding
+
+	BrowserPlay      key.Binding
+	BrowserEnqueue   key.Binding
+	BrowserSearch    key.Binding
+	BrowserView      key.Binding
+	BrowserSort      key.Binding
+	BrowserReverse   key.Binding
+	BrowserFavorites key.Binding
+	BrowserMinRating key.Binding
+
+	BrowserBookmark  key.Binding
+	BrowserBookmarks key.Binding
+	BrowserDelete    key.Binding
+	BrowserMove      key.Binding
+	BrowserRename    key.Binding
+	BrowserMark      key.Binding
+	BrowserVisual    key.Binding
+	BrowserPlaylist  key.Binding
+	BrowserEdit      key.Binding
+	BrowserNew       key.Binding
+	BrowserGenre     key.Binding
+}
+
+// DefaultKeyMap returns the keys used when the config does not change them.
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Quit:      key.NewBinding(key.WithKeys("q", "Q"), key.WithHelp("q", "quit")),
+		PlayPause: key.NewBinding(key.WithKeys(" ", "enter"), key.WithHelp("Space", "pause/resume")),
+
+		Rewind:       key.NewBinding(key.WithKeys("left", "h"), key.WithHelp("🞀", "rewind")),
+		Forward:      key.NewBinding(key.WithKeys("right", "l"), key.WithHelp("🞂", "forward")),
+		RewindLarge:  key.NewBinding(key.WithKeys("shift+left", "ctrl+left"), key.WithHelp("Shift+🞀", "large step")),
+		ForwardLarge: key.NewBinding(key.WithKeys("shift+right", "ctrl+right"), key.WithHelp("🞂", "large step")),
+		Jump:         key.NewBinding(key.WithKeys(":"), key.WithHelp(":", "jump to time")),
+
+		VolumeUp:       key.NewBinding(key.WithKeys("up", "+", "k"), key.WithHelp("⏶", "volume up")),
+		VolumeDown:     key.NewBinding(key.WithKeys("down", "-", "j"), key.WithHelp("⏷", "volume down")),
+		VolumeUpFine:   key.NewBinding(key.WithKeys("shift+up", "K"), key.WithHelp("Shift+⏶", "by 1%")),
+		VolumeDownFine: key.NewBinding(key.WithKeys("shift+down", "J"), key.WithHelp("⏷", "by 1%")),
+		Mute:           key.NewBinding(key.WithKeys("m", "M"), key.WithHelp("m", "mute/unmute")),
+
+		SpeedUp:      key.NewBinding(key.WithKeys("}"), key.WithHelp("}", "speed")),
+		SpeedDown:    key.NewBinding(key.WithKeys("{"), key.WithHelp("{", "speed")),
+		PitchUp:      key.NewBinding(key.WithKeys(">"), key.WithHelp(">", "pitch")),
+		PitchDown:    key.NewBinding(key.WithKeys("<"), key.WithHelp("<", "pitch")),
+		BalanceRight: key.NewBinding(key.WithKeys(")"), key.WithHelp(")", "balance")),
+		BalanceLeft:  key.NewBinding(key.WithKeys("("), key.WithHelp("(", "balance")),
+		SwapChannels: key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "swap channels")),
+		Mono:         key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "mono")),
+
+		Next:     key.NewBinding(key.WithKeys("n", "N"), key.WithHelp("n", "next")),
+		Previous: key.NewBinding(key.WithKeys("p", "P"), key.WithHelp("p", "previous")),
+		Favorite: key.NewBinding(key.WithKeys("f", "F"), key.WithHelp("f", "favorite")),
+		CopyPath: key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "copy path")),
+		Reveal:   key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "show in folder")),
+		EditTags: key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit tags")),
+
+		ReplayGain: key.NewBinding(key.WithKeys("g"), key.WithHelp("g", "replaygain")),
+		Silence:    key.NewBinding(key.WithKeys("z"), key.WithHelp("z", "skip silence")),
+		Analyze:    key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "bpm/key")),
+		Loop:       key.NewBinding(key.WithKeys("i", "I"), key.WithHelp("i", "A–B loop")),
+		// r renames files in the browser
+		Resume: key.NewBinding(key.WithKeys("`"), key.WithHelp("`", "resume")),
+		Record: key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "record")),
+
+		// b and B are the bookmarked directories of the browser
+		Bookmark:         key.NewBinding(key.WithKeys("ctrl+b"), key.WithHelp("Ctrl+b", "bookmark/remove")),
+		NextBookmark:     key.NewBinding(key.WithKeys("."), key.WithHelp(".", "previous/next bookmark")),
+		PreviousBookmark: key.NewBinding(key.WithKeys(","), key.WithHelp(",", "previous/next bookmark")),
+		NextChapter:      key.NewBinding(key.WithKeys("]"), key.WithHelp("]", "previous/next chapter")),
+		PreviousChapter:  key.NewBinding(key.WithKeys("["), key.WithHelp("[", "previous/next chapter")),
+
+		Focus:     key.NewBinding(key.WithKeys("tab"), key.WithHelp("Tab", "browser")),
+		Scan:      key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("Ctrl+r", "scan")),
+		Lyrics:    key.NewBinding(key.WithKeys("L"), key.WithHelp("L", "lyrics")),
+		Chapters:  key.NewBinding(key.WithKeys("C"), key.WithHelp("C", "chapters")),
+		Devices:   key.NewBinding(key.WithKeys("D"), key.WithHelp("D", "devices")),
+		Equalizer: key.NewBinding(key.WithKeys("E"), key.WithHelp("E", "equalizer")),
+
+		Rate:        [6]key.Binding(digitBindings("", 6, "rate")),
+		JumpPercent: [10]key.Binding(digitBindings("alt+", 10, "jump to 0-90%")),
+		Back:        key.NewBinding(key.WithKeys("esc"), key.WithHelp("Esc", "back")),
+
+		BrowserUp:           key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("⏶", "move")),
+		BrowserDown:         key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("⏷", "move")),
+		BrowserParent:       key.NewBinding(key.WithKeys("left", "h", "backspace"), key.WithHelp("🞀", "parent")),
+		BrowserOpen:         key.NewBinding(key.WithKeys("right", "l"), key.WithHelp("🞂", "open")),
+		BrowserTop:          key.NewBinding(key.WithKeys("g", "home"), key.WithHelp("g", "top")),
+		BrowserBottom:       key.NewBinding(key.WithKeys("G", "end"), key.WithHelp("G", "bottom")),
+		BrowserPageUp:       key.NewBinding(key.WithKeys("pgup"), key.WithHelp("PgUp", "page")),
+		BrowserPageDown:     key.NewBinding(key.WithKeys("pgdown"), key.WithHelp("PgDown", "page")),
+		BrowserHalfPageUp:   key.NewBinding(key.WithKeys("ctrl+u"), key.WithHelp("Ctrl+u", "half page")),
+		BrowserHalfPageDown: key.NewBinding(key.WithKeys("ctrl+d"), key.WithHelp("Ctrl+d", "half page")),
+		BrowserJump:         key.NewBinding(key.WithKeys("'"), key.WithHelp("'", "jump to letter")),
+		BrowserToggle:       key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "expand/collapse")),
+
+		BrowserPlay:      key.NewBinding(key.WithKeys("enter"), key.WithHelp("Enter", "play")),
+		BrowserEnqueue:   key.NewBinding(key.WithKeys("a", "A"), key.WithHelp("a", "enqueue")),
+		BrowserSearch:    key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
+		BrowserView:      key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "next view")),
+		BrowserSort:      key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "sort")),
+		BrowserReverse:   key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "reverse")),
+		BrowserFavorites: key.NewBinding(key.WithKeys("F"), key.WithHelp("F", "favorites")),
+		BrowserMinRating: key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "min. rating")),
+
+		BrowserBookmark:  key.NewBinding(key.WithKeys("b"), key.WithHelp("b", "bookmark")),
+		BrowserBookmarks: key.NewBinding(key.WithKeys("B"), key.WithHelp("B", "bookmarks")),
+		BrowserDelete:    key.NewBinding(key.WithKeys("d", "delete"), key.WithHelp("d", "remove")),
+		BrowserMove:      key.NewBinding(key.WithKeys("m"), key.WithHelp("m", "move")),
+		BrowserRename:    key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rename")),
+		BrowserMark:      key.NewBinding(key.WithKeys(" "), key.WithHelp("Space", "mark")),
+		BrowserVisual:    key.NewBinding(key.WithKeys("V"), key.WithHelp("V", "visual")),
+		BrowserPlaylist:  key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "add to playlist")),
+		BrowserEdit:      key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit")),
+		BrowserNew:       key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "new")),
+		BrowserGenre:     key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "genre")),
+	}
+}
+
+// digitBindings returns the bindings of the first n digits, with a modifier like "alt+".
+func digitBindings(mod string, n int, desc string) []key.Binding {
+	bindings := make([]key.Binding, n)
+	for i := range bindings {
+		k := mod + strconv.Itoa(i)
+		bindings[i] = key.NewBinding(key.WithKeys(k), key.WithHelp(keyLabel(k), desc))
+	}
+	return bindings
+}
+
+// actions returns the bindings by the name of their action in the config.
+func (km *KeyMap) actions() map[string]*key.Binding {
+	actions := map[string]*key.Binding{
+		"quit":       &km.Quit,
+		"play_pause": &km.PlayPause,
+
+		"rewind":        &km.Rewind,
+		"forward":       &km.Forward,
+		"rewind_large":  &km.RewindLarge,
+		"forward_large": &km.ForwardLarge,
+		"jump":          &km.Jump,
+
+		"volume_up":        &km.VolumeUp,
+		"volume_down":      &km.VolumeDown,
+		"volume_up_fine":   &km.VolumeUpFine,
+		"volume_down_fine": &km.VolumeDownFine,
+		"mute":             &km.Mute,
+
+		"speed_up":      &km.SpeedUp,
+		"speed_down":    &km.SpeedDown,
+		"pitch_up":      &km.PitchUp,
+		"pitch_down":    &km.PitchDown,
+		"balance_right": &km.BalanceRight,
+		"balance_left":  &km.BalanceLeft,
+		"swap_channels": &km.SwapChannels,
+		"mono":          &km.Mono,
+
+		"next":      &km.Next,
+		"previous":  &km.Previous,
+		"favorite":  &km.Favorite,
+		"copy_path": &km.CopyPath,
+		"reveal":    &km.Reveal,
+		"edit_tags": &km.EditTags,
+
+		"replaygain": &km.ReplayGain,
+		"silence":    &km.Silence,
+		"analyze":    &km.Analyze,
+		"loop":       &km.Loop,
+		"resume":     &km.Resume,
+		"record":     &km.Record,
+
+		"bookmark":          &km.Bookmark,
+		"next_bookmark":     &km.NextBookmark,
+		"previous_bookmark": &km.PreviousBookmark,
+		"next_chapter":      &km.NextChapter,
+		"previous_chapter":  &km.PreviousChapter,
+
+		"focus":     &km.Focus,
+		"scan":      &km.Scan,
+		"lyrics":    &km.Lyrics,
+		"chapters":  &km.Chapters,
+		"devices":   &km.Devices,
+		"equalizer": &km.Equalizer,
+		"back":      &km.Back,
+
+		"browser_up":             &km.BrowserUp,
+		"browser_down":           &km.BrowserDown,
+		"browser_parent":         &km.BrowserParent,
+		"browser_open":           &km.BrowserOpen,
+		"browser_top":            &km.BrowserTop,
+		"browser_bottom":         &km.BrowserBottom,
+		"browser_page_up":        &km.BrowserPageUp,
+		"browser_page_down":      &km.BrowserPageDown,
+		"browser_half_page_up":   &km.BrowserHalfPageUp,
+		"browser_half_page_down": &km.BrowserHalfPageDown,
+		"browser_jump":           &km.BrowserJump,
+		"browser_toggle":         &km.BrowserToggle,
+
+		"browser_play":       &km.BrowserPlay,
+		"browser_enqueue":    &km.BrowserEnqueue,
+		"browser_search":     &km.BrowserSearch,
+		"browser_view":       &km.BrowserView,
+		"browser_sort":       &km.BrowserSort,
+		"browser_reverse":    &km.BrowserReverse,
+		"browser_favorites":  &km.BrowserFavorites,
+		"browser_min_rating": &km.BrowserMinRating,
+
+		"browser_bookmark":  &km.BrowserBookmark,
+		"browser_bookmarks": &km.BrowserBookmarks,
+		"browser_delete":    &km.BrowserDelete,
+		"browser_move":      &km.BrowserMove,
+		"browser_rename":    &km.BrowserRename,
+		"browser_mark":      &km.BrowserMark,
+		"browser_visual":    &km.BrowserVisual,
+		"browser_playlist":  &km.BrowserPlaylist,
+		"browser_edit":      &km.BrowserEdit,
+		"browser_new":       &km.BrowserNew,
+		"browser_genre":     &km.BrowserGenre,
+	}
+	// rate_0 to rate_5, and jump_0 to jump_90
+	for i := range km.Rate {
+		actions["rate_"+strconv.Itoa(i)] = &km.Rate[i]
+	}
+	for i := range km.JumpPercent {
+		actions["jump_"+strconv.Itoa(i*10)] = &km.JumpPercent[i]
+	}
+	return actions
+}
+
+// NewKeyMap returns the default keys with the ones of the actions in keys replaced, like
+// "rewind" = ["left", "a"]. An action without keys is disabled. The names of the keys are
+// the ones of Bubble Tea, like "ctrl+left", "enter" or " " for the space bar.
+func NewKeyMap(keys map[string][]string) (KeyMap, error) {
+	km := DefaultKeyMap()
+	actions := km.actions()
+
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		b, ok := actions[name]
+		if !ok {
+			return km, fmt.Errorf("unknown key action %q", name)
+		}
+		if len(keys[name]) == 0 {
+			b.SetEnabled(false)
+			continue
+		}
+		for _, k := range keys[name] {
+			if k == "" {
+				return km, fmt.Errorf("empty key for %s", name)
+			}
+			if slices.Contains(fixedKeys, k) {
+				return km, fmt.Errorf("key %q of %s cannot be rebound", k, name)
+			}
+		}
+		b.SetKeys(keys[name]...)
+		b.SetHelp(keyLabel(keys[name][0]), b.Help().Desc)
+	}
+
+	// A key doing two actions would only do the one handled first. The browser takes the keys
+	// of the player while it is focused, so its actions only conflict among themselves, and
+	// with back, which both use
+	if err := checkConflicts(actions, func(name string) bool {
+		return !strings.HasPrefix(name, browserPrefix)
+	}); err != nil {
+		return km, err
+	}
+	if err := checkConflicts(actions, func(name string) bool {
+		return strings.HasPrefix(name, browserPrefix) || name == "back"
+	}); err != nil {
+		return km, err
+	}
+	return km, nil
+}
+
+// checkConflicts returns an error if two of the enabled actions in scope share a key.
+func checkConflicts(actions map[string]*key.Binding, scope func(name string) bool) error {
+	var names []string
+	for name, b := range actions {
+		if scope(name) && b.Enabled() {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	bound := make(map[string]string)
+	for _, name := range names {
+		for _, k := range actions[name].Keys() {
+			if other, ok := bound[k]; ok {
+				return fmt.Errorf("key %q is bound to both %s and %s", k, other, name)
+			}
+			bound[k] = name
+		}
+	}
+	return nil
+}
+
+// MatchIndex returns the index of the first binding matching the key, or -1 if none does.
+func MatchIndex(msg tea.KeyMsg, bindings []key.Binding) int {
+	return slices.IndexFunc(bindings, func(b key.Binding) bool { return key.Matches(msg, b) })
+}
+
+// keyLabel returns how a key is shown in the help.
+func keyLabel(k string) string {
+	switch k {
+	case " ":
+		return "Space"
+	case "left":
+		return "🞀"
+	case "right":
+		return "🞂"
+	case "up":
+		return "⏶"
+	case "down":
+		return "⏷"
+	case "enter", "esc", "tab", "backspace", "delete", "home", "end":
+		return strings.ToUpper(k[:1]) + k[1:]
+	case "pgup":
+		return "PgUp"
+	case "pgdown":
+		return "PgDown"
+	}
+	if mod, rest, ok := strings.Cut(k, "+"); ok && rest != "" {
+		return strings.ToUpper(mod[:1]) + mod[1:] + "+" + keyLabel(rest)
+	}
+	return k
+}
+
+// Keys returns the keys of the actions.
+func (p *Player) Keys() KeyMap {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.keys
+}
+
+// SetKeys sets the keys of the actions.
+func (p *Player) SetKeys(km KeyMap) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = km
+}
+
+// helpView lists the keys of the enabled actions, the ones of opposite actions together.
+func (p *Player) helpView() string {
+	km := p.keys
+	// A live stream has no positions to move to
+	live := p.engine.Live()
+	entries := []string{
+		helpEntry(km.Quit),
+		helpEntry(km.PlayPause),
+	}
+	if !live {
+		entries = append(entries,
+			helpEntry(km.Rewind),
+			helpEntry(km.Forward),
+			helpEntry(km.RewindLarge, km.ForwardLarge),
+			helpEntry(km.Jump),
+			rangeEntry(km.JumpPercent[:]...),
+		)
+	}
+	entries = append(entries,
+		helpEntry(km.VolumeUp),
+		helpEntry(km.VolumeDown),
+		helpEntry(km.VolumeUpFine, km.VolumeDownFine),
+		helpEntry(km.SpeedDown, km.SpeedUp),
+		helpEntry(km.PitchDown, km.PitchUp),
+		helpEntry(km.BalanceLeft, km.BalanceRight),
+		helpEntry(km.SwapChannels),
+		helpEntry(km.Mono),
+		helpEntry(km.Mute),
+		helpEntry(km.Next),
+		helpEntry(km.Previous),
+		rangeEntry(km.Rate[:]...),
+		helpEntry(km.Favorite),
+		helpEntry(km.CopyPath),
+		helpEntry(km.Reveal),
+		helpEntry(km.EditTags),
+		helpEntry(km.ReplayGain),
+		helpEntry(km.Silence),
+		helpEntry(km.Analyze),
+		helpEntry(km.Record),
+	)
+	if !live {
+		entries = append(entries,
+			helpEntry(km.Loop),
+			helpEntry(km.Bookmark),
+			helpEntry(km.PreviousBookmark, km.NextBookmark),
+		)
+	}
+	entries = append(entries,
+		helpEntry(km.Lyrics),
+		helpEntry(km.Devices),
+		helpEntry(km.Equalizer),
+	)
+	if len(p.chapters) > 0 {
+		entries = append(entries, helpEntry(km.PreviousChapter, km.NextChapter), helpEntry(km.Chapters))
+	}
+
+	entries = slices.DeleteFunc(entries, func(e string) bool { return e == "" })
+	return "\nℹ: " + strings.Join(entries, " | ")
+}
+
+// helpEntry shows the keys of one action, or of a pair of opposite ones sharing the
+// description, like "{/} (speed)". It is empty if the actions are disabled.
+func helpEntry(bindings ...key.Binding) string {
+	var labels []string
+	desc := ""
+	for _, b := range bindings {
+		if b.Enabled() {
+			labels = append(labels, b.Help().Key)
+			desc = b.Help().Desc
+		}
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	return strings.Join(labels, "/") + " (" + desc + ")"
+}
+
+// rangeEntry shows the keys of a row of actions sharing the description, like "0-5 (rate)"
+// when their labels only differ in a last character counting up, or all of them otherwise.
+func rangeEntry(bindings ...key.Binding) string {
+	var enabled []key.Binding
+	for _, b := range bindings {
+		if b.Enabled() {
+			enabled = append(enabled, b)
+		}
+	}
+	if len(enabled) < 3 {
+		return helpEntry(enabled...)
+	}
+
+	first, last := enabled[0].Help().Key, enabled[len(enabled)-1].Help().Key
+	for i, b := range enabled {
+		label := b.Help().Key
+		if len(label) != len(first) || label[:len(label)-1] != first[:len(first)-1] ||
+			int(label[len(label)-1]) != int(first[len(first)-1])+i {
+			return helpEntry(enabled...)
+		}
+	}
+	return first + "-" + last[len(last)-1:] + " (" + enabled[0].Help().Desc + ")"
+}
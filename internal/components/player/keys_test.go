@@ -0,0 +1,80 @@
+package player
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNewKeyMap(t *testing.T) {
+	tests := []struct {
+		name string
+		keys map[string][]string
+		err  string
+	}{
+		{name: "defaults", keys: nil},
+		{name: "rebound", keys: map[string][]string{"rewind": {"a"}, "forward": {"d"}}},
+		{name: "swapped", keys: map[string][]string{"next": {"p"}, "previous": {"n"}}},
+		{name: "freed by a disabled action", keys: map[string][]string{"mute": {}, "mono": {"m"}}},
+		{name: "rating and jump keys", keys: map[string][]string{"rate_5": {"6"}, "jump_50": {"%"}}},
+		{name: "back in the player", keys: map[string][]string{"back": {"q"}}, err: `key "q" is bound to both back and quit`},
+		{name: "browser key used by the player", keys: map[string][]string{"browser_sort": {"x"}}},
+		{name: "taken in the browser", keys: map[string][]string{"browser_sort": {"b"}}, err: `key "b" is bound to both browser_bookmark and browser_sort`},
+		{name: "back in the browser", keys: map[string][]string{"back": {"backspace"}}, err: `key "backspace" is bound to both back and browser_parent`},
+		{name: "unknown action", keys: map[string][]string{"dance": {"d"}}, err: `unknown key action "dance"`},
+		{name: "empty key", keys: map[string][]string{"mute": {""}}, err: "empty key for mute"},
+		{name: "fixed key", keys: map[string][]string{"quit": {"ctrl+c"}}, err: `key "ctrl+c" of quit cannot be rebound`},
+		{name: "taken by a default", keys: map[string][]string{"mono": {"m"}}, err: `key "m" is bound to both mono and mute`},
+		{name: "taken by another action", keys: map[string][]string{"scan": {"a"}, "mono": {"a"}}, err: `key "a" is bound to both mono and scan`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyMap(tt.keys)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("NewKeyMap(%v): %v", tt.keys, err)
+			case tt.err != "" && err == nil:
+				t.Fatalf("NewKeyMap(%v) succeeded, want an error containing %q", tt.keys, tt.err)
+			case err != nil && !strings.Contains(err.Error(), tt.err):
+				t.Fatalf("NewKeyMap(%v) error = %q, want it to contain %q", tt.keys, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestNewKeyMapBindings(t *testing.T) {
+	km, err := NewKeyMap(map[string][]string{"rewind": {"a", "left"}, "mute": {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := km.Rewind.Keys(); !slices.Equal(got, []string{"a", "left"}) {
+		t.Errorf("rewind keys = %v, want [a left]", got)
+	}
+	if got := km.Rewind.Help().Key; got != "a" {
+		t.Errorf("rewind help = %q, want the first key", got)
+	}
+	if km.Mute.Enabled() {
+		t.Error("mute is enabled, want it disabled by the empty list")
+	}
+	if got := km.Forward.Keys(); !slices.Equal(got, DefaultKeyMap().Forward.Keys()) {
+		t.Errorf("forward keys = %v, want the defaults", got)
+	}
+}
+
+func TestRangeEntry(t *testing.T) {
+	km := DefaultKeyMap()
+	if got := rangeEntry(km.Rate[:]...); got != "0-5 (rate)" {
+		t.Errorf("rating help = %q, want 0-5 (rate)", got)
+	}
+	if got := rangeEntry(km.JumpPercent[:]...); got != "Alt+0-9 (jump to 0-90%)" {
+		t.Errorf("jump help = %q", got)
+	}
+
+	km, err := NewKeyMap(map[string][]string{"rate_0": {}, "rate_3": {"#"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rangeEntry(km.Rate[:]...); got != "1/2/#/4/5 (rate)" {
+		t.Errorf("rebound rating help = %q, want every key", got)
+	}
+}
@@ -3,114 +3,377 @@ package player
 import (
 	"errors"
 	"fmt"
-	"os"
+	"log/slog"
+	"math"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/gopxl/beep/v2"
-	"github.com/gopxl/beep/v2/effects"
-	"github.com/gopxl/beep/v2/mp3"
-	"github.com/gopxl/beep/v2/speaker"
-	"github.com/gopxl/beep/v2/wav"
+	"github.com/nicolito128/tempo/internal/art"
+	"github.com/nicolito128/tempo/internal/audio"
+	"github.com/nicolito128/tempo/internal/cast"
+	"github.com/nicolito128/tempo/internal/loudness"
 	"github.com/nicolito128/tempo/internal/styles"
+	"github.com/nicolito128/tempo/internal/tags"
+	"github.com/nicolito128/tempo/pkg/engine"
 )
 
 const (
-	// Volume up and down variation (too high shift results in poor audio)
-	VolumeShift float64 = 0.16
+	// Volume up and down variation by default, in units of totalVolume
+	VolumeStep int = 5
+	// Volume up and down variation with shift, for the small changes
+	FineVolumeStep int = 1
+	// Playback speed variation
+	SpeedStep float64 = 0.1
+	// Channel balance variation, a tenth of the way to one side
+	BalanceStep float64 = 0.1
+	// Loudness range of the volume in dB, from 100 (the audio as it is) down to 1
+	VolumeRange float64 = 40
 	// Displays only the last N characters of the path string
 	PathCharsLimit int = 32
-	// SeekCool is the cooldown time between seek actions
-	SeekCooldown time.Duration = 200 * time.Millisecond
+	// How long the presses of the seek keys are added up before the playback moves, so
+	// holding one scrubs through the audio
+	SeekDebounce time.Duration = 150 * time.Millisecond
+	// How far rewind and forward move the playback, and with Shift or Ctrl held
+	SeekStep      time.Duration = 5 * time.Second
+	LargeSeekStep time.Duration = time.Minute
+	// Size of the album cover in terminal cells
+	CoverCols int = 12
+	CoverRows int = 6
+	// How often the playback position is shown again, often enough to not skip seconds
+	TickInterval time.Duration = 250 * time.Millisecond
+	// How long the clipping indicator stays after the audio went past full scale
+	ClipHold time.Duration = time.Second
 )
 
-// TickMsg every second of the played audio
+// TickMsg every TickInterval of the played audio
 type TickMsg struct{}
 
+// CompletedMsg is sent once when the current audio reaches its end.
+type CompletedMsg struct{}
+
+// FailedMsg reports an audio file that could not be played, which the player stopped.
+type FailedMsg struct {
+	Path string
+	Err  error
+}
+
+// EventMsg carries an event of the playback engine.
+type EventMsg struct {
+	Event engine.Event
+}
+
+// CoverMsg carries the album cover of the audio file at Path, ready to be drawn.
+type CoverMsg struct {
+	Path  string
+	Image art.Image
+}
+
 // Player : An audio player
 type Player struct {
-	// Streamer audio file
-	stream beep.StreamSeekCloser
+	// Engine decoding and playing the audio, on the output devices of the system or the
+	// renderers of the network
+	engine *engine.Engine
+	output *cast.Output
+
+	// ReplayGain adjustment applied to the current audio
+	gainMode GainMode
+
+	// normalize if the audio without ReplayGain tags is played at its measured loudness,
+	// nil until measured
+	normalize bool
+	loudness  *loudness.Result
+
+	// Name of the curve of the equalizer, a preset, a profile or Custom
+	eqName string
 
-	// Buffer format for stream
-	format beep.Format
+	// When the audio played went past full scale last
+	clippedAt time.Time
 
-	// Volume controller
-	volume *effects.Volume
+	// Start of the A–B loop, if marked while its end is not
+	loopStart  time.Duration
+	loopMarked bool
 
-	// Ctrl allows to pause the streamer
-	ctrl *beep.Ctrl
+	// Prompt of the position to seek to
+	jump jump
 
-	// hasInit if player is already started and audio loaded as streamer
+	// Volume restored when unmuting, the last one above 0
+	unmutedVolume int
+
+	// Variation of the volume with each step up or down
+	volumeStep int
+
+	// hasInit if the current audio was loaded and its playback started
 	hasInit bool
 
-	// running if audio is being played
-	running bool
+	// startPaused if the first audio is loaded without playing it
+	startPaused bool
+
+	// Directory the recordings are saved to, and recordOnStart if the first audio played
+	// starts a recording
+	recordDir     string
+	recordOnStart bool
 
-	// completed if the current audio has finished
-	completed bool
+	// ticking if the TickMsg loop was started, which keeps running across audio files
+	ticking bool
+
+	// listening if the events of the engine are being received
+	listening bool
 
 	// quitting if the user requests to exit the program or if something goes wrong
 	quitting bool
 
+	// Where the audio was left at once the player is closed, which unloads it, so the
+	// position and the session can still be saved
+	closed                 bool
+	closedAt, closedLength time.Duration
+	closedCompleted        bool
+
 	// totalVolume is the volume of the streamer in a human-readable format (from 0 to 100)
 	totalVolume int
 
 	// Current audio file being played back
-	currentAudio *AudioFile
+	currentAudio *audio.File
 
 	// Time duration of the audio file
 	duration time.Duration
 
-	// Elapsed in seconds of the audio file being played
-	elapsed time.Duration
+	// Chapters of the audio file, like the ones of audiobooks and podcasts
+	chapters []tags.Chapter
+
+	// Bookmarks of the audio file sorted by position, and the prompt naming a new one
+	bookmarks []audio.Bookmark
+	naming    naming
+
+	// Stars given to the current audio, and if it is a favorite
+	stars    int
+	favorite bool
+
+	// Tempo and key of the current audio, zero until analyzed
+	bpm     float64
+	key     string
+	camelot string
+
+	// How the album cover is drawn, art.None to hide it
+	artProtocol art.Protocol
+
+	// Album cover of the current audio, empty until loaded or if there is none
+	cover art.Image
 
 	// error to handle
 	err error
 
 	mu sync.RWMutex
 
-	lastSeekTime time.Time
+	// How far rewind and forward move the playback
+	seekSteps SeekSteps
+
+	// Keys of the actions
+	keys KeyMap
+
+	// Offset of the seek keys pressed since the last seek, applied after SeekDebounce if
+	// seekPending
+	seekOffset  time.Duration
+	seekPending bool
 }
 
 var _ tea.Model = (*Player)(nil)
 
 func New(volume int) *Player {
 	p := &Player{}
-	if volume > 100 {
-		volume = 100
-	}
-	if volume < 0 {
-		volume = 0
-	}
-	p.totalVolume = volume
+	p.output = cast.NewOutput()
+	p.engine = engine.NewWithBackend(p.output)
+	p.unmutedVolume = 50
+	p.volumeStep = VolumeStep
+	p.seekSteps = DefaultSeekSteps()
+	p.keys = DefaultKeyMap()
+	p.jump = newJump()
+	p.naming = newNaming()
+	p.eqName = Presets[0].Name
+	p.setVolume(volume)
 
 	return p
 }
 
+// Engine returns the engine playing the audio, to play it without the TUI.
+func (p *Player) Engine() *engine.Engine {
+	return p.engine
+}
+
 // SetAudioFile sets the current audio file to be played.
-func (p *Player) SetAudioFile(af AudioFile) {
+func (p *Player) SetAudioFile(af audio.File) {
+	af.LoadTags()
 	p.currentAudio = &af
 }
 
+// Move follows the current audio to its new path after its file, or a directory with it, is
+// moved from from to to. Its tags and the playback are kept.
+func (p *Player) Move(from, to string) {
+	if p.currentAudio == nil {
+		return
+	}
+	old := p.currentAudio.Path()
+	path, err := filepath.Abs(old)
+	if err != nil {
+		return
+	}
+	rest, err := filepath.Rel(from, path)
+	if err != nil || rest == ".." || strings.HasPrefix(rest, ".."+string(filepath.Separator)) {
+		return
+	}
+
+	moved := filepath.Join(to, rest)
+	p.currentAudio.SetPath(moved)
+	p.engine.Rename(old, moved)
+}
+
 // Audio returns the current audio file being played.
-func (p *Player) Audio() AudioFile {
+func (p *Player) Audio() audio.File {
 	if p.currentAudio != nil {
 		return *p.currentAudio
 	}
-	return AudioFile{}
+	return audio.File{}
+}
+
+// HasAudio reports whether the player has an audio file to play.
+func (p *Player) HasAudio() bool {
+	return p.currentAudio != nil
+}
+
+// SetTags replaces the tags of the current audio, after they are edited.
+func (p *Player) SetTags(t tags.Tags) {
+	if p.currentAudio != nil {
+		p.currentAudio.SetTags(t)
+	}
+}
+
+// SetArtProtocol sets the terminal graphics protocol used to draw the album cover.
+func (p *Player) SetArtProtocol(protocol art.Protocol) {
+	p.artProtocol = protocol
+}
+
+// Devices lists the audio output devices, the default one first.
+func (p *Player) Devices() ([]engine.Device, error) {
+	return p.engine.Devices()
+}
+
+// Device returns the name of the audio output device, empty for the default one.
+func (p *Player) Device() string {
+	return p.engine.Device()
+}
+
+// SetDevice plays on the audio output device with the given name, the default one if empty.
+func (p *Player) SetDevice(name string) error {
+	return p.engine.SetDevice(name)
+}
+
+// CheckDevice returns an error if there is no audio output device with the given name, without
+// playing on it.
+func (p *Player) CheckDevice(name string) error {
+	if name == "" {
+		return nil
+	}
+	devices, err := p.engine.Devices()
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(devices, func(d engine.Device) bool { return d.Name == name }) {
+		return errors.New("there is no such output device")
+	}
+	return nil
+}
+
+// SetSnapcast sets the Snapcast source listed as the "snapcast" device, none if empty.
+func (p *Player) SetSnapcast(source string, format cast.SampleFormat) {
+	p.output.SetSnapcast(source, format)
+}
+
+// LoadCover finds and renders the album cover of the current audio in background.
+func (p *Player) LoadCover() tea.Cmd {
+	if p.artProtocol == art.None || p.currentAudio == nil {
+		return nil
+	}
+
+	path, protocol := p.currentAudio.Path(), p.artProtocol
+	return func() tea.Msg {
+		img, err := art.Cover(path)
+		if err != nil {
+			return CoverMsg{Path: path}
+		}
+		cover, err := art.Render(img, protocol, CoverCols, CoverRows)
+		if err != nil {
+			return CoverMsg{Path: path}
+		}
+		return CoverMsg{Path: path, Image: cover}
+	}
+}
+
+// SetRating sets the rating shown next to the name of the current audio.
+func (p *Player) SetRating(stars int, favorite bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stars = stars
+	p.favorite = favorite
+}
+
+// SetAnalysis sets the tempo and key shown next to the name of the current audio, with
+// the key also in the Camelot notation.
+func (p *Player) SetAnalysis(bpm float64, key, camelot string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bpm = bpm
+	p.key = key
+	p.camelot = camelot
+}
+
+// Elapsed returns the playback position of the current audio.
+func (p *Player) Elapsed() time.Duration {
+	if p.closed {
+		return p.closedAt
+	}
+	return p.engine.Position()
+}
+
+// Duration returns the length of the current audio.
+func (p *Player) Duration() time.Duration {
+	if p.closed {
+		return p.closedLength
+	}
+	return p.engine.Duration()
 }
 
-// Init initializes the player, loads the audio file, and sets up the speaker.
+// Playing reports whether the current audio is playing, not paused nor finished.
+func (p *Player) Playing() bool {
+	return p.engine.Playing()
+}
+
+// Completed reports whether the current audio reached its end.
+func (p *Player) Completed() bool {
+	if p.closed {
+		return p.closedCompleted
+	}
+	return p.engine.Completed()
+}
+
+// Live reports whether the current audio is the stream of an internet radio, without a length
+// to seek in.
+func (p *Player) Live() bool {
+	return p.engine.Live()
+}
+
+// Init initializes the player and loads the audio file.
 func (p *Player) Init() tea.Cmd {
-	p.LoadAudio()
-	if p.err != nil {
-		return p.Quit()
+	if p.currentAudio == nil {
+		return nil
+	}
+	if err := p.LoadAudio(); err != nil {
+		return p.fail(err)
 	}
-	speaker.Init(p.format.SampleRate, p.format.SampleRate.N(time.Second/10))
 	return tea.ClearScreen
 }
 
@@ -119,45 +382,133 @@ func (p *Player) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return p, p.Quit()
 	}
 
+	// Covers of the previous audio files are discarded
+	if msg, ok := msg.(CoverMsg); ok {
+		if p.currentAudio != nil && msg.Path == p.currentAudio.Path() {
+			p.cover = msg.Image
+		}
+		return p, nil
+	}
+
 	if !p.hasInit && p.currentAudio != nil {
 		return p, p.Play()
 	}
 
 	switch msg := msg.(type) {
+	case seekMsg:
+		return p, p.applySeek()
+
 	case TickMsg:
-		if !p.completed && p.running {
-			p.mu.Lock()
-			p.elapsed++
-			p.mu.Unlock()
+		if p.engine.Clipped() {
+			p.clippedAt = time.Now()
 		}
 		return p, p.tick()
 
+	case EventMsg:
+		ev := msg.Event
+		if ev.Kind == engine.Metadata && p.currentAudio != nil && ev.Path == p.currentAudio.Path() {
+			p.setStreamTitle(ev.Title)
+			return p, p.listen()
+		}
+		// Completions of the previous audio files are discarded
+		if ev.Kind != engine.Completed || p.currentAudio == nil || ev.Path != p.currentAudio.Path() {
+			return p, p.listen()
+		}
+		if ev.Err != nil {
+			return p, tea.Batch(p.listen(), p.fail(ev.Err))
+		}
+		return p, tea.Batch(p.listen(), func() tea.Msg { return CompletedMsg{} })
+
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "q", "Q":
+		if p.jump.active && msg.String() != "ctrl+c" {
+			return p, p.updateJump(msg)
+		}
+		if p.naming.active && msg.String() != "ctrl+c" {
+			return p, p.updateNaming(msg)
+		}
+
+		km := p.Keys()
+		switch {
+		case msg.String() == "ctrl+c", key.Matches(msg, km.Quit):
 			return p, p.Quit()
 
-		case "enter", " ":
-			if p.completed {
-				p.Restart()
-			} else {
-				p.StopOrResume()
-			}
+		case key.Matches(msg, km.PlayPause):
+			return p, p.StopOrResume()
 
-		case "+", "up", "k":
+		case key.Matches(msg, km.VolumeUp):
 			p.IncrementVolume()
 
-		case "-", "down", "j":
+		case key.Matches(msg, km.VolumeDown):
 			p.DecrementVolume()
 
-		case "left", "h":
-			p.Rewind()
+		case key.Matches(msg, km.VolumeUpFine):
+			p.setVolume(p.totalVolume + FineVolumeStep)
+
+		case key.Matches(msg, km.VolumeDownFine):
+			p.setVolume(p.totalVolume - FineVolumeStep)
+
+		case key.Matches(msg, km.SpeedUp):
+			p.SetSpeed(p.engine.Speed() + SpeedStep)
+
+		case key.Matches(msg, km.SpeedDown):
+			p.SetSpeed(p.engine.Speed() - SpeedStep)
+
+		case key.Matches(msg, km.PitchUp):
+			p.SetPitch(p.engine.Pitch() + 1)
 
-		case "right", "l":
-			p.Forward()
+		case key.Matches(msg, km.PitchDown):
+			p.SetPitch(p.engine.Pitch() - 1)
 
-		case "m", "M":
+		case key.Matches(msg, km.BalanceRight):
+			p.SetBalance(p.engine.Balance() + BalanceStep)
+
+		case key.Matches(msg, km.BalanceLeft):
+			p.SetBalance(p.engine.Balance() - BalanceStep)
+
+		case key.Matches(msg, km.SwapChannels):
+			p.engine.SetSwapped(!p.engine.Swapped())
+
+		case key.Matches(msg, km.Mono):
+			p.engine.SetMono(!p.engine.Mono())
+
+		case key.Matches(msg, km.Rewind):
+			return p, p.Rewind()
+
+		case key.Matches(msg, km.Forward):
+			return p, p.Forward()
+
+		case key.Matches(msg, km.RewindLarge):
+			return p, p.RewindLarge()
+
+		case key.Matches(msg, km.ForwardLarge):
+			return p, p.ForwardLarge()
+
+		case key.Matches(msg, km.JumpPercent[:]...):
+			return p, p.SeekPercent(MatchIndex(msg, km.JumpPercent[:]) * 10)
+
+		case key.Matches(msg, km.Mute):
 			p.ToggleVolume()
+
+		case key.Matches(msg, km.NextChapter):
+			return p, p.NextChapter()
+
+		case key.Matches(msg, km.PreviousChapter):
+			return p, p.PreviousChapter()
+
+		case key.Matches(msg, km.Loop):
+			p.MarkLoop()
+
+		case key.Matches(msg, km.Jump):
+			return p, p.StartJump()
+
+		case key.Matches(msg, km.Bookmark):
+			return p, p.ToggleBookmark()
+
+		case key.Matches(msg, km.NextBookmark):
+			return p, p.NextBookmark()
+
+		case key.Matches(msg, km.PreviousBookmark):
+			return p, p.PreviousBookmark()
 		}
 	}
 
@@ -177,31 +528,63 @@ func (p *Player) View() string {
 
 	var s string
 
-	if p.currentAudio != nil && p.volume != nil {
+	if p.currentAudio != nil && p.engine.Path() != "" {
 		mutedElem := lipgloss.NewStyle().Width(10).MarginRight(1).Render()
-		if p.volume.Silent || p.totalVolume == 0 {
+		if p.engine.Muted() || p.totalVolume == 0 {
 			mutedElem = lipgloss.NewStyle().
-				Background(styles.ProblemColor).
+				Background(styles.ProblemColor()).
 				Align(lipgloss.Center).
 				Width(10).
 				MarginRight(1).
 				Render(" × Muted ")
 		}
 
-		// Percentage of the audio played
-		percentage := float64(p.elapsed) / float64(p.duration.Seconds()) * 100
+		// The seek keys pressed are shown before the playback moves
+		live := p.engine.Live()
+		elapsed := max(min(p.engine.Position()+p.seekOffset, p.engine.Duration()), 0)
+		if live {
+			elapsed = p.engine.Position()
+		}
+
+		// Percentage of the audio played, none while its length is unknown, like for a live
+		// stream or a file still being probed
+		percentage := 0.0
+		if p.duration > 0 {
+			percentage = min(float64(elapsed)/float64(p.duration)*100, 100)
+		}
 
-		whiteCell := lipgloss.NewStyle().
-			Background(lipgloss.Color("white")).
-			Foreground(lipgloss.Color("white")).
+		playedCell := lipgloss.NewStyle().
+			Background(styles.ProgressColor()).
+			Foreground(styles.ProgressColor()).
 			Width(1).
 			Height(1).
 			Render("█")
 
-		loadBar := lipgloss.NewStyle().
-			Align(lipgloss.Left).
-			Render(strings.Repeat("•", 100))
-		loadBar = strings.Replace(loadBar, "•", whiteCell, int(percentage))
+		cells := make([]string, 100)
+		for i := range cells {
+			cells[i] = "•"
+			if i < int(percentage) {
+				cells[i] = playedCell
+			}
+		}
+
+		// The ends of the A–B loop are marked on the bar
+		marker := lipgloss.NewStyle().Foreground(styles.PrimaryColor()).Bold(true)
+		start, end, looping := p.engine.Loop()
+		if looping || p.loopMarked {
+			if !looping {
+				start = p.loopStart
+			}
+			cells[p.barCell(start)] = marker.Render("A")
+		}
+		if looping {
+			cells[p.barCell(end)] = marker.Render("B")
+		}
+		loadBar := strings.Join(cells, "")
+		// A live stream has no end to show the progress to
+		if live {
+			loadBar = lipgloss.NewStyle().Foreground(styles.ProblemColor()).Bold(true).Render("● LIVE")
+		}
 		loadBarBox := lipgloss.NewStyle().
 			Align(lipgloss.Center).
 			Width(100).
@@ -213,32 +596,116 @@ func (p *Player) View() string {
 		s += lipgloss.JoinHorizontal(lipgloss.Left, mutedElem, loadBarBox)
 		s += "\n\n"
 
-		if p.completed {
+		if p.engine.Completed() {
 			s += " ∎ "
 		} else {
-			if p.running {
+			if p.engine.Playing() {
 				s += " ⏵ "
 			} else {
 				s += " ⏸ "
 			}
 		}
 
-		nameElem := styles.PrimaryHighlight(fmt.Sprintf(" ♪ %s ", p.currentAudio.name))
+		nameElem := styles.PrimaryHighlight(fmt.Sprintf(" ♪ %s ", headline(*p.currentAudio)))
+		if p.stars > 0 || p.favorite {
+			rating := strings.Repeat("★", p.stars)
+			if p.favorite {
+				rating += " ♥"
+			}
+			nameElem += lipgloss.NewStyle().Foreground(styles.SecundaryColor()).Render(" " + rating)
+		}
+		if p.bpm > 0 {
+			analysis := fmt.Sprintf(" ♩ %.0f BPM", p.bpm)
+			if p.key != "" {
+				analysis += fmt.Sprintf(" · %s %s", p.key, p.camelot)
+			}
+			nameElem += lipgloss.NewStyle().Foreground(styles.GreyColor()).Render(analysis)
+		}
+
+		if i := chapterAt(p.chapters, elapsed); i >= 0 {
+			chapter := fmt.Sprintf(" § %d/%d", i+1, len(p.chapters))
+			if title := p.chapters[i].Title; title != "" {
+				chapter += " " + title
+			}
+			nameElem += lipgloss.NewStyle().Foreground(styles.GreyColor()).Render(chapter)
+		}
+		if i := bookmarkAt(p.bookmarks, elapsed); i >= 0 {
+			nameElem += lipgloss.NewStyle().Foreground(styles.GreyColor()).Render(" ⚑ " + p.bookmarks[i].Name)
+		}
 
 		volumeElem := lipgloss.NewStyle().
-			Foreground(styles.PrimaryColor).
+			Foreground(styles.PrimaryColor()).
 			Align(lipgloss.Center).
 			Width(15).
 			Render(fmt.Sprintf(" λ %d%%", p.totalVolume))
+		if speed := p.engine.Speed(); speed != 1 {
+			volumeElem += lipgloss.NewStyle().
+				Foreground(styles.GreyColor()).
+				Render(fmt.Sprintf("%g× ", speed))
+		}
+		if channels := channelsView(p.engine.Balance(), p.engine.Swapped(), p.engine.Mono()); channels != "" {
+			volumeElem += lipgloss.NewStyle().
+				Foreground(styles.GreyColor()).
+				Render(channels)
+		}
+		if looping {
+			volumeElem += lipgloss.NewStyle().
+				Foreground(styles.GreyColor()).
+				Render(fmt.Sprintf("⟲ %s–%s ", FormatSecondsToString(start), FormatSecondsToString(end)))
+		}
+		if mode := p.engine.Silence().Mode; mode != engine.SilencePlay {
+			volumeElem += lipgloss.NewStyle().
+				Foreground(styles.GreyColor()).
+				Render("silence " + mode.String() + " ")
+		}
+		if pitch := p.engine.Pitch(); pitch != 0 {
+			volumeElem += lipgloss.NewStyle().
+				Foreground(styles.GreyColor()).
+				Render(fmt.Sprintf("%+d st ", pitch))
+		}
+		if rec, ok := p.engine.Recording(); ok {
+			volumeElem += lipgloss.NewStyle().
+				Foreground(styles.ProblemColor()).
+				Bold(true).
+				Render("● REC " + FormatSecondsToString(rec.Length) + " ")
+		}
+		if time.Since(p.clippedAt) < ClipHold {
+			volumeElem += lipgloss.NewStyle().
+				Foreground(styles.ProblemColor()).
+				Bold(true).
+				Render("CLIP ")
+		}
+		if preamp := p.engine.Preamp(); preamp != 0 || p.engine.Equalizer() != (engine.Gains{}) {
+			eq := "EQ " + p.eqName + " "
+			if preamp != 0 {
+				eq += fmt.Sprintf("%+g dB ", preamp)
+			}
+			volumeElem += lipgloss.NewStyle().
+				Foreground(styles.GreyColor()).
+				Render(eq)
+		}
+		if p.normalized() && p.loudness != nil {
+			volumeElem += lipgloss.NewStyle().
+				Foreground(styles.GreyColor()).
+				Render(fmt.Sprintf("R128 %+.1f dB ", p.loudness.Gain()))
+		} else if p.gainMode != GainOff {
+			db := p.gain()
+			volumeElem += lipgloss.NewStyle().
+				Foreground(styles.GreyColor()).
+				Render(fmt.Sprintf("RG %s %+.1f dB ", p.gainMode, db))
+		}
 
-		elapsedStr := FormatSecondsToString(time.Duration(time.Second * p.elapsed))
+		elapsedStr := FormatSecondsToString(elapsed)
 		elapsedElem := lipgloss.NewStyle().
-			Foreground(styles.ContrastColor).
+			Foreground(styles.ContrastColor()).
 			Render(elapsedStr)
 
 		durationStr := FormatSecondsToString(p.duration)
+		if live {
+			durationStr = "live"
+		}
 		durationElem := lipgloss.NewStyle().
-			Foreground(styles.ContrastColor).
+			Foreground(styles.ContrastColor()).
 			Render(durationStr)
 
 		elapseBox := lipgloss.NewStyle().
@@ -246,7 +713,7 @@ func (p *Player) View() string {
 			Align(lipgloss.Center).
 			Render(fmt.Sprintf(" %s / %s ", elapsedElem, durationElem))
 
-		shortPath := reverseCutString(p.currentAudio.path, PathCharsLimit)
+		shortPath := styles.ReverseCut(p.currentAudio.Path(), PathCharsLimit)
 		pathElem := styles.ContrastHighlight(shortPath)
 
 		s += fmt.Sprintf("\t[\t %s • %s • %s • %s \t]",
@@ -257,9 +724,14 @@ func (p *Player) View() string {
 		)
 	}
 	s = styles.BaseContainer(s)
+	if p.currentAudio != nil {
+		s = p.coverView() + s
+	}
 
-	// help
-	s += styles.Help("\nℹ: q (quit) | Space (pause/resume) | 🞀 (rewind) | 🞂 (forward) | ⏶ (volume up) | ⏷ (volume down) | m (mute/unmute)\n")
+	s += p.jumpView()
+	s += p.namingView()
+
+	s += styles.Help(p.helpView() + "\n")
 
 	return s
 }
@@ -268,23 +740,30 @@ func (p *Player) View() string {
 func (p *Player) Reset() {
 	p.currentAudio = nil
 	p.hasInit = false
-	p.running = false
-	p.completed = false
 	p.quitting = false
 	p.totalVolume = 50
 	p.duration = 0
-	p.elapsed = 0
+	p.chapters = nil
+	p.stars = 0
+	p.favorite = false
+	p.bpm = 0
+	p.key = ""
+	p.camelot = ""
+	p.loudness = nil
+	p.loopMarked = false
+	p.cover = art.Image{}
 }
 
 // Close stops the player and releases resources.
 func (p *Player) Close() error {
-	var err error
-	p.running = false
-	if p.stream != nil {
-		err = p.stream.Close()
-		if err != nil {
-			p.err = err
-		}
+	if !p.closed {
+		p.closedAt, p.closedLength, p.closedCompleted = p.engine.Position(), p.engine.Duration(), p.engine.Completed()
+		p.closed = true
+	}
+	err := p.engine.Close()
+	if err != nil {
+		slog.Warn("Cannot close the audio", "err", err)
+		p.err = err
 	}
 	return err
 }
@@ -293,288 +772,369 @@ func (p *Player) Close() error {
 func (p *Player) Quit() tea.Cmd {
 	p.quitting = true
 	p.Close()
+	// A renderer of the network would keep waiting for the stream
+	p.output.Close()
 	return tea.Quit
 }
 
-// Play starts playing the audio file if it is not already running.
+// Play starts playing the audio file if it is not already running. The first one stays
+// paused if set to start paused.
 func (p *Player) Play() tea.Cmd {
 	if p.hasInit {
 		return nil
 	}
 	p.hasInit = true
 
-	// If we are already playing a song, then do nothing
-	if p.running {
-		return nil
+	if p.startPaused {
+		p.startPaused = false
+	} else if err := p.engine.Play(); err != nil {
+		return p.fail(err)
 	}
-	// Otherwise, start playing the song
-	p.running = true
-
-	done := make(chan struct{})
-	speaker.Play(beep.Seq(p.volume, beep.Callback(func() {
-		done <- struct{}{}
-	})))
-
-	go func() {
-		<-done
-		p.completed = true
-	}()
 
-	return p.tick()
+	var cmds []tea.Cmd
+	if p.recordOnStart {
+		p.recordOnStart = false
+		cmds = append(cmds, p.ToggleRecording())
+	}
+	if !p.ticking {
+		p.ticking = true
+		cmds = append(cmds, p.tick())
+	}
+	if !p.listening {
+		p.listening = true
+		cmds = append(cmds, p.listen())
+	}
+	return tea.Batch(cmds...)
 }
 
-func (p *Player) Restart() {
-	// Save important state
-	auxFile := p.currentAudio
-	auxTotalVolume := p.totalVolume
-	silent := p.volume.Silent
-
+// Load stops the current audio, if any, and starts playing the given audio file,
+// keeping the volume settings of the previous one.
+func (p *Player) Load(af audio.File) tea.Cmd {
+	totalVolume := p.totalVolume
 	p.Reset()
 
-	p.elapsed = 0
-	p.duration = p.format.SampleRate.D(p.stream.Len()).Round(time.Second)
-	p.currentAudio = auxFile
-	p.totalVolume = auxTotalVolume
-	p.volume.Silent = silent
-
-	p.stream.Seek(0)
-	p.Play()
-}
-
-// Resume resumes the audio playback if it is paused.
-func (p *Player) Resume() {
-	if !p.hasInit {
-		return
+	p.totalVolume = totalVolume
+	p.SetAudioFile(af)
+	if err := p.LoadAudio(); err != nil {
+		return p.fail(err)
 	}
-	if p.completed || p.running {
-		return
-	}
-	p.running = true
 
-	speaker.Lock()
-	p.ctrl.Paused = false
-	speaker.Unlock()
+	return tea.Batch(p.Play(), p.LoadCover())
 }
 
-// Stop stops the audio playback if it is currently running.
-func (p *Player) Stop() {
-	if !p.hasInit {
-		return
+// fail stops the current audio after an error playing it, keeping the volume settings,
+// and reports it with a FailedMsg.
+func (p *Player) fail(err error) tea.Cmd {
+	var path string
+	if p.currentAudio != nil {
+		path = p.currentAudio.Path()
 	}
-	if p.completed || !p.running {
-		return
+
+	// The previous audio keeps playing when the new one cannot be decoded
+	if err := p.engine.Close(); err != nil {
+		slog.Warn("Cannot close the audio", "path", path, "err", err)
 	}
-	p.running = false
+	totalVolume := p.totalVolume
+	p.Reset()
+	p.totalVolume = totalVolume
 
-	speaker.Lock()
-	p.ctrl.Paused = true
-	speaker.Unlock()
+	return func() tea.Msg {
+		return FailedMsg{Path: path, Err: err}
+	}
 }
 
-// StopOrResume pauses or resumes the speaker audio depending if it's running or not.
-func (p *Player) StopOrResume() {
-	if p.running {
-		p.Stop()
-	} else {
-		p.Resume()
-	}
+// SetStartPaused sets whether the first audio played is loaded paused, waiting to be resumed.
+func (p *Player) SetStartPaused(paused bool) {
+	p.startPaused = paused
 }
 
-// DecrementVolume decreases the volume by 5 units, ensuring it does not go below 0.
-func (p *Player) DecrementVolume() {
-	p.totalVolume -= 5
-	if p.totalVolume < 0 {
-		p.totalVolume = 0
+// StopOrResume pauses or resumes the audio playback depending if it's running or not.
+// A completed audio plays again from the start, and a live stream from what its radio
+// broadcasts now.
+func (p *Player) StopOrResume() tea.Cmd {
+	if !p.hasInit {
+		return nil
 	}
-
-	if p.totalVolume > 0 {
-		p.volume.Volume -= VolumeShift
+	if p.engine.Playing() {
+		p.engine.Pause()
+		return nil
 	}
-
-	if p.totalVolume == 0 {
-		p.volume.Silent = true
-	} else {
-		p.volume.Silent = false
+	// The radio drops the listeners not reading its stream for a while
+	if p.engine.Live() {
+		if err := p.LoadAudio(); err != nil {
+			return p.fail(err)
+		}
 	}
+	if err := p.engine.Play(); err != nil {
+		return p.fail(err)
+	}
+	return nil
 }
 
-// IncrementVolume increases the volume by 5 units, ensuring it does not exceed 100.
-func (p *Player) IncrementVolume() {
-	p.totalVolume += 5
-	if p.totalVolume > 100 {
-		p.totalVolume = 100
+// SetVolumeStep sets how many units the volume goes up or down with each step.
+func (p *Player) SetVolumeStep(step int) error {
+	if step < 1 || step > 100 {
+		return fmt.Errorf("volume step %d is not between 1 and 100", step)
 	}
+	p.volumeStep = step
+	return nil
+}
 
-	if p.totalVolume < 100 {
-		p.volume.Volume += VolumeShift
-	}
+// SetFade sets how long the audio fades out when paused or stopped, and in when resumed.
+func (p *Player) SetFade(length time.Duration) {
+	p.engine.SetFade(length)
+}
 
-	if p.totalVolume == 0 {
-		p.volume.Silent = true
-	} else {
-		p.volume.Silent = false
-	}
+// SetReadAhead sets how much audio is decoded ahead of the playback, from the next audio
+// file loaded.
+func (p *Player) SetReadAhead(length time.Duration) error {
+	return p.engine.SetReadAhead(length)
 }
 
-// MuteVolume sets the volume to silent, effectively muting the audio.
-func (p *Player) MuteVolume() {
-	p.volume.Silent = true
+// SetSpeed plays the audio faster or slower keeping its pitch, clamped between
+// engine.MinSpeed and engine.MaxSpeed and rounded to SpeedStep.
+func (p *Player) SetSpeed(speed float64) {
+	speed = math.Round(speed/SpeedStep) * SpeedStep
+	speed = math.Round(max(min(speed, engine.MaxSpeed), engine.MinSpeed)*100) / 100
+	p.engine.SetSpeed(speed)
 }
 
-// UnmuteVolume sets the volume to a non-silent state, allowing audio playback.
-func (p *Player) UnmuteVolume() {
-	p.volume.Silent = false
+// SetPitch shifts the audio up or down by semitones keeping its speed, clamped between
+// engine.MinPitch and engine.MaxPitch.
+func (p *Player) SetPitch(semitones int) {
+	p.engine.SetPitch(max(min(semitones, engine.MaxPitch), engine.MinPitch))
 }
 
-// ToggleVolume toggles the volume state between muted and unmuted.
-func (p *Player) ToggleVolume() {
-	if p.volume.Silent {
-		p.UnmuteVolume()
-	} else {
-		p.MuteVolume()
-	}
+// Silence returns how the stretches of near-silence are played.
+func (p *Player) Silence() engine.Silence {
+	return p.engine.Silence()
 }
 
-func (p *Player) Rewind() {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+// SetSilence sets how the stretches of near-silence are played.
+func (p *Player) SetSilence(s engine.Silence) error {
+	return p.engine.SetSilence(s)
+}
 
-	if time.Since(p.lastSeekTime) < SeekCooldown {
+// MarkLoop marks the current position as the start of the A–B loop, then as its end to
+// loop the region between them, then clears the loop.
+func (p *Player) MarkLoop() {
+	if p.engine.Live() {
 		return
 	}
-
-	if p.stream.Err() != nil {
-		p.err = p.stream.Err()
+	if _, _, ok := p.engine.Loop(); ok {
+		p.engine.ClearLoop()
 		return
 	}
 
-	skipDuration := time.Duration(5)
-	if p.elapsed+skipDuration >= p.duration {
-		skipDuration = 0
+	pos := p.engine.Position()
+	if !p.loopMarked || pos <= p.loopStart {
+		p.loopStart, p.loopMarked = pos, true
+		return
 	}
+	if err := p.engine.SetLoop(p.loopStart, pos); err == nil {
+		p.loopMarked = false
+	}
+}
 
-	currentPos := p.stream.Position()
-	offset := p.format.SampleRate.N(time.Second * skipDuration)
-
-	newPos := currentPos - offset
-	newPos = max(newPos, 0)
+// SetBalance leans the audio to the right channel, or to the left one if negative, clamped
+// between -1 and 1 and rounded to BalanceStep.
+func (p *Player) SetBalance(balance float64) {
+	balance = math.Round(balance/BalanceStep) * BalanceStep
+	p.engine.SetBalance(math.Round(max(min(balance, 1), -1)*100) / 100)
+}
 
-	p.elapsed = max(p.elapsed-skipDuration, 0)
+// Volume returns the volume (0 - 100) of the audio, the one it plays at once unmuted.
+func (p *Player) Volume() int {
+	return p.totalVolume
+}
 
-	if err := p.stream.Seek(newPos); err != nil {
-		p.err = err
-		return
-	}
+// Muted reports whether the audio is muted.
+func (p *Player) Muted() bool {
+	return p.engine.Muted()
+}
 
-	p.lastSeekTime = time.Now()
+// Speed returns how fast the audio plays, 1 being its normal speed.
+func (p *Player) Speed() float64 {
+	return p.engine.Speed()
 }
 
-func (p *Player) Forward() {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+// DecrementVolume decreases the volume by one step, ensuring it does not go below 0.
+func (p *Player) DecrementVolume() {
+	p.setVolume(p.totalVolume - p.volumeStep)
+}
 
-	if time.Since(p.lastSeekTime) < SeekCooldown {
-		return
-	}
+// IncrementVolume increases the volume by one step, ensuring it does not exceed 100.
+func (p *Player) IncrementVolume() {
+	p.setVolume(p.totalVolume + p.volumeStep)
+}
 
-	if p.stream.Err() != nil {
-		p.err = p.stream.Err()
-		return
-	}
+// SetVolume sets the volume from 0 to 100, muting the audio at 0.
+func (p *Player) SetVolume(volume int) {
+	p.setVolume(volume)
+}
 
-	skipDuration := time.Duration(5)
-	if p.elapsed+skipDuration >= p.duration {
-		skipDuration = 0
+// setVolume sets the volume (0 - 100) of the engine, muting it at 0 and unmuting it otherwise.
+func (p *Player) setVolume(volume int) {
+	volume = max(min(volume, 100), 0)
+	if volume > 0 {
+		p.unmutedVolume = volume
 	}
+	p.totalVolume = volume
+	p.engine.SetVolume(AbsVolume(volume))
+	p.engine.SetMuted(volume == 0)
+}
 
-	currentPos := p.stream.Position()
-	offset := p.format.SampleRate.N(time.Second * skipDuration)
-
-	newPos := currentPos + offset
-	newPos = min(newPos, p.stream.Len()-1)
-
-	p.elapsed = min(p.elapsed+skipDuration, p.duration)
+// MuteVolume sets the volume to silent, effectively muting the audio.
+func (p *Player) MuteVolume() {
+	p.engine.SetMuted(true)
+}
 
-	if err := p.stream.Seek(newPos); err != nil {
-		p.err = err
+// UnmuteVolume sets the volume to a non-silent state, allowing audio playback. Muted by
+// lowering the volume to 0, the last volume above it is restored.
+func (p *Player) UnmuteVolume() {
+	if p.totalVolume == 0 {
+		p.setVolume(p.unmutedVolume)
 		return
 	}
+	p.engine.SetMuted(false)
+}
 
-	p.lastSeekTime = time.Now()
+// ToggleVolume toggles the volume state between muted and unmuted.
+func (p *Player) ToggleVolume() {
+	if p.engine.Muted() {
+		p.UnmuteVolume()
+	} else {
+		p.MuteVolume()
+	}
 }
 
-// LoadAudio loads the current audio file into the player, decoding it based on its file type.
-// Currently it only supports MP3 and WAV formats.
-func (p *Player) LoadAudio() {
+// LoadAudio loads the current audio file into the engine, decoding it based on its file type.
+// It supports MP3, WAV, FLAC and Ogg Vorbis files.
+func (p *Player) LoadAudio() error {
 	if p.currentAudio == nil {
-		return
+		return nil
 	}
 
-	ext := p.currentAudio.ext
-	if ext == "" {
-		p.err = errors.New("invalid file extension")
-		return
+	if err := p.engine.Load(p.currentAudio.Path()); err != nil {
+		return err
+	}
+	p.duration = p.engine.Duration().Round(time.Second)
+	p.seekOffset, p.seekPending = 0, false
+	p.bookmarks = nil
+	slog.Info("Playing", "path", p.currentAudio.Path(), "duration", p.duration)
+	if p.engine.Live() && !p.currentAudio.Named() {
+		p.currentAudio.SetName(p.engine.Station().Name)
 	}
 
-	file, err := os.Open(p.currentAudio.path)
-	if err != nil {
-		p.err = err
-		return
+	if p.totalVolume == 0 {
+		p.engine.SetMuted(true)
 	}
+	p.applyGain()
+	p.loadChapters()
+	return nil
+}
 
-	var streamer beep.StreamSeekCloser
-	var format beep.Format
+func (p *Player) Error() error {
+	return p.err
+}
 
-	switch ext {
-	case ".mp3":
-		streamer, format, err = mp3.Decode(file)
-	case ".wav":
-		streamer, format, err = wav.Decode(file)
-	default:
-		err = errors.New("invalid file extension")
+// coverView renders the album cover next to the track details, above the player. The
+// kitty images are not part of the text, so they are deleted when there is no cover.
+func (p *Player) coverView() string {
+	if p.cover.IsZero() {
+		return art.Clear(p.artProtocol)
 	}
 
-	if err != nil {
-		p.err = err
-		return
+	af := *p.currentAudio
+	grey := lipgloss.NewStyle().Foreground(styles.GreyColor())
+	lines := []string{"", styles.PrimaryHighlight(" " + af.Title() + " ")}
+	if af.Artist() != "" {
+		lines = append(lines, lipgloss.NewStyle().Foreground(styles.SecundaryColor()).Render(af.Artist()))
 	}
-
-	// Sample audio
-	p.stream = streamer
-	p.format = format
-	p.duration = format.SampleRate.D(streamer.Len()).Round(time.Second)
-
-	// Controllers
-	p.ctrl = &beep.Ctrl{
-		Streamer: streamer,
-		Paused:   false,
+	if af.Album() != "" {
+		lines = append(lines, grey.Render(af.Album()))
 	}
-	p.volume = &effects.Volume{
-		Streamer: p.ctrl,
-		Base:     1.5,
-		Volume:   0,
-		Silent:   false,
+	if genre := af.Tags().Genre; genre != "" {
+		lines = append(lines, grey.Render(genre))
 	}
+	return p.cover.Beside(lines) + "\n"
+}
 
-	if p.totalVolume == 0 {
-		p.volume.Silent = true
+// headline describes the audio file as "Artist – Title – Album", skipping the unknown tags.
+func headline(af audio.File) string {
+	parts := []string{af.Title()}
+	if af.Artist() != "" {
+		parts = append([]string{af.Artist()}, parts...)
+	}
+	if af.Album() != "" {
+		parts = append(parts, af.Album())
 	}
+	return strings.Join(parts, " – ")
 }
 
-func (p *Player) Error() error {
-	return p.err
+// setStreamTitle shows the title told by the radio of the live stream, with the name of the
+// station as the album, the one it was saved with if any. Most radios tell "Artist - Title".
+func (p *Player) setStreamTitle(title string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t := p.currentAudio.Tags()
+	t.Artist, t.Title, t.Album = "", title, p.engine.Station().Name
+	if p.currentAudio.Named() {
+		t.Album = p.currentAudio.Name()
+	}
+	if artist, song, ok := strings.Cut(title, " - "); ok {
+		t.Artist, t.Title = strings.TrimSpace(artist), strings.TrimSpace(song)
+	}
+	p.currentAudio.SetTags(t)
+	slog.Debug("Stream title", "path", p.currentAudio.Path(), "title", title)
 }
 
-// tick sends a TickMsg every second to update the elapsed time of the audio playback.
+// tick sends a TickMsg every TickInterval to update the elapsed time of the audio playback.
 func (p *Player) tick() tea.Cmd {
-	return tea.Tick(time.Second, func(_ time.Time) tea.Msg {
+	return tea.Tick(TickInterval, func(_ time.Time) tea.Msg {
 		return TickMsg{}
 	})
 }
 
-// AbsVolume converts human-readable form volume (0 - 100) to float64 volume.
+// listen waits for the next event of the engine.
+func (p *Player) listen() tea.Cmd {
+	events := p.engine.Events()
+	return func() tea.Msg {
+		return EventMsg{Event: <-events}
+	}
+}
+
+// AbsVolume converts human-readable form volume (0 - 100) to the volume level of the engine.
+// Every unit lowers the loudness by the same dB, down to VolumeRange dB below the audio as
+// it is, which sounds even to the ear.
 func AbsVolume(volume int) float64 {
-	return (float64(volume) - 100) / 10
+	db := VolumeRange * (float64(volume)/100 - 1)
+	return db / (20 * math.Log10(engine.VolumeBase))
+}
+
+// barCell returns the cell of the progress bar at the given position of the audio.
+func (p *Player) barCell(pos time.Duration) int {
+	if p.duration <= 0 {
+		return 0
+	}
+	return max(min(int(float64(pos)/float64(p.duration)*100), 99), 0)
+}
+
+// channelsView shows how the channels are mixed, empty if they play as they are.
+func channelsView(balance float64, swapped, mono bool) string {
+	var s string
+	switch {
+	case balance < 0:
+		s += fmt.Sprintf("L%.0f ", -balance*100)
+	case balance > 0:
+		s += fmt.Sprintf("R%.0f ", balance*100)
+	}
+	if mono {
+		s += "mono "
+	} else if swapped {
+		s += "L⇄R "
+	}
+	return s
 }
 
 // FormatSecondsToString formats a total number of seconds into a human-readable string.
@@ -610,13 +1170,3 @@ func FormatSecondsToString(d time.Duration) string {
 
 	return s
 }
-
-func reverseCutString(s string, n int) string {
-	runes := []rune(s)
-	if n >= len(runes) {
-		return s
-	}
-
-	lastNRunes := runes[len(runes)-n:]
-	return fmt.Sprintf("...%s", string(lastNRunes))
-}
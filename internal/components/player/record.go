@@ -0,0 +1,98 @@
+package player
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// Layout of the time a recording started at in its file name
+const recordTimeLayout = "2006-01-02 15.04.05"
+
+// RecordingMsg is sent when a recording starts or stops, or fails to.
+type RecordingMsg struct {
+	Recording engine.Recording
+	// Started if the recording started, stopped otherwise
+	Started bool
+	Err     error
+}
+
+// SetRecordDir sets the directory the recordings are saved to, created with the first one.
+func (p *Player) SetRecordDir(dir string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recordDir = dir
+}
+
+// SetRecordOnStart sets whether the playback is recorded from the first audio played.
+func (p *Player) SetRecordOnStart(record bool) {
+	p.recordOnStart = record
+}
+
+// Recording returns the recording going on, reporting false if there is none.
+func (p *Player) Recording() (engine.Recording, bool) {
+	return p.engine.Recording()
+}
+
+// StartRecording records the playback to a WAV file in the recordings directory, named after
+// the station or the track playing and the time it starts at, returning its path.
+func (p *Player) StartRecording() (string, error) {
+	p.mu.RLock()
+	dir := p.recordDir
+	name := "tempo"
+	if p.currentAudio != nil {
+		name = p.currentAudio.Label()
+		// The title of a radio changes with every song, its name does not
+		if p.engine.Live() {
+			name = p.currentAudio.Name()
+		}
+	}
+	p.mu.RUnlock()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, recordFileName(name)+" "+time.Now().Format(recordTimeLayout)+".wav")
+	if err := p.engine.StartRecording(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// StopRecording finishes the recording, returning its file and length.
+func (p *Player) StopRecording() (engine.Recording, error) {
+	return p.engine.StopRecording()
+}
+
+// ToggleRecording starts recording the playback, or stops the recording going on.
+func (p *Player) ToggleRecording() tea.Cmd {
+	if _, ok := p.engine.Recording(); ok {
+		rec, err := p.StopRecording()
+		return func() tea.Msg { return RecordingMsg{Recording: rec, Err: err} }
+	}
+	path, err := p.StartRecording()
+	return func() tea.Msg {
+		return RecordingMsg{Recording: engine.Recording{Path: path}, Started: true, Err: err}
+	}
+}
+
+// recordFileName returns name without the characters file systems do not allow in file
+// names.
+func recordFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, " .")
+	if name == "" {
+		return "tempo"
+	}
+	return name
+}
@@ -0,0 +1,135 @@
+package player
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/nicolito128/tempo/internal/loudness"
+	"github.com/nicolito128/tempo/internal/tags"
+)
+
+// GainMode : Which ReplayGain adjustment is applied to the played audio
+type GainMode int
+
+const (
+	// GainOff plays the audio as it is
+	GainOff GainMode = iota
+	// GainTrack plays every track equally loud
+	GainTrack
+	// GainAlbum plays every album equally loud, keeping the differences between its tracks
+	GainAlbum
+)
+
+var gainModeNames = []string{"off", "track", "album"}
+
+func (m GainMode) String() string {
+	if m < 0 || int(m) >= len(gainModeNames) {
+		return "unknown"
+	}
+	return gainModeNames[m]
+}
+
+// ParseGainMode converts a mode name (off, track or album) into a GainMode.
+func ParseGainMode(s string) (GainMode, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, name := range gainModeNames {
+		if s == name {
+			return GainMode(i), nil
+		}
+	}
+	return GainOff, fmt.Errorf("unknown replaygain mode %q", s)
+}
+
+// Next returns the mode that follows m, going back to GainOff after the last one.
+func (m GainMode) Next() GainMode {
+	return (m + 1) % GainMode(len(gainModeNames))
+}
+
+// GainMode returns the ReplayGain adjustment applied to the played audio.
+func (p *Player) GainMode() GainMode {
+	return p.gainMode
+}
+
+// SetGainMode sets the ReplayGain adjustment, applying it to the current audio right away.
+func (p *Player) SetGainMode(m GainMode) {
+	p.gainMode = m
+	p.applyGain()
+}
+
+// Normalize reports whether the audio without ReplayGain tags is played at the loudness
+// measured from its sound instead.
+func (p *Player) Normalize() bool {
+	return p.normalize
+}
+
+// SetNormalize sets whether the audio without ReplayGain tags is played at the loudness
+// measured from its sound, while a ReplayGain mode is on.
+func (p *Player) SetNormalize(normalize bool) {
+	p.normalize = normalize
+	p.applyGain()
+}
+
+// NeedsLoudness reports whether the loudness of the current audio has to be measured to
+// normalize it.
+func (p *Player) NeedsLoudness() bool {
+	return p.normalized() && p.loudness == nil
+}
+
+// SetLoudness sets the loudness measured for the audio file at path, normalizing it if it
+// is the current one.
+func (p *Player) SetLoudness(path string, r loudness.Result) {
+	if p.currentAudio == nil || p.currentAudio.Path() != path {
+		return
+	}
+	p.loudness = &r
+	p.applyGain()
+}
+
+// normalized reports whether the current audio is played at its measured loudness, since
+// it has no ReplayGain tags.
+func (p *Player) normalized() bool {
+	if !p.normalize || p.gainMode == GainOff || p.currentAudio == nil {
+		return false
+	}
+	rg := p.currentAudio.Tags().ReplayGain
+	return rg.TrackGain == 0 && rg.AlbumGain == 0
+}
+
+// gain returns the adjustment in dB of the current audio, from its tags or its measured
+// loudness.
+func (p *Player) gain() float64 {
+	if p.normalized() {
+		if p.loudness == nil {
+			return 0
+		}
+		return p.loudness.Gain()
+	}
+	return replayGain(p.currentAudio.Tags().ReplayGain, p.gainMode)
+}
+
+// applyGain sets the gain of the current audio in the engine.
+func (p *Player) applyGain() {
+	if p.currentAudio == nil {
+		return
+	}
+	p.engine.SetGain(p.gain())
+}
+
+// replayGain returns the adjustment in dB for the mode, zero for audio without ReplayGain
+// tags. Tracks without album gain use their track gain, and the other way around. The gain
+// is lowered when the peak would be clipped.
+func replayGain(rg tags.ReplayGain, m GainMode) float64 {
+	if m == GainOff {
+		return 0
+	}
+
+	gain, peak := rg.TrackGain, rg.TrackPeak
+	if (m == GainAlbum && rg.AlbumGain != 0) || gain == 0 {
+		gain, peak = rg.AlbumGain, rg.AlbumPeak
+	}
+	if peak > 0 {
+		gain = min(gain, -20*math.Log10(peak))
+	}
+	return gain
+}
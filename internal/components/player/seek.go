@@ -0,0 +1,144 @@
+package player
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SeekSteps : How far rewind and forward move the playback
+type SeekSteps struct {
+	// Small step of the arrow keys
+	Small time.Duration
+	// Large step of the arrow keys with Shift or Ctrl held, to move through long files
+	Large time.Duration
+}
+
+// DefaultSeekSteps returns the steps of a new player, SeekStep and LargeSeekStep.
+func DefaultSeekSteps() SeekSteps {
+	return SeekSteps{Small: SeekStep, Large: LargeSeekStep}
+}
+
+// SeekSteps returns how far rewind and forward move the playback.
+func (p *Player) SeekSteps() SeekSteps {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.seekSteps
+}
+
+// SetSeekSteps sets how far rewind and forward move the playback.
+func (p *Player) SetSeekSteps(steps SeekSteps) error {
+	if steps.Small <= 0 {
+		return fmt.Errorf("seek step %s is not positive", steps.Small)
+	}
+	if steps.Large <= 0 {
+		return fmt.Errorf("large seek step %s is not positive", steps.Large)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seekSteps = steps
+	return nil
+}
+
+// Rewind moves the playback the small step back.
+func (p *Player) Rewind() tea.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.skip(-p.seekSteps.Small)
+}
+
+// Forward moves the playback the small step ahead, unless the audio ends before.
+func (p *Player) Forward() tea.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.skip(p.seekSteps.Small)
+}
+
+// RewindLarge moves the playback the large step back.
+func (p *Player) RewindLarge() tea.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.skip(-p.seekSteps.Large)
+}
+
+// ForwardLarge moves the playback the large step ahead, unless the audio ends before.
+func (p *Player) ForwardLarge() tea.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.skip(p.seekSteps.Large)
+}
+
+// seekMsg applies the presses of the seek keys added up during SeekDebounce.
+type seekMsg struct{}
+
+// skip adds offset to the seek keys pressed, moving the playback by all of them together
+// after SeekDebounce. The caller must hold p.mu.
+func (p *Player) skip(offset time.Duration) tea.Cmd {
+	if p.engine.Path() == "" || p.engine.Completed() || p.engine.Live() {
+		return nil
+	}
+
+	p.seekOffset += offset
+	if p.seekPending {
+		return nil
+	}
+	p.seekPending = true
+	return tea.Tick(SeekDebounce, func(time.Time) tea.Msg { return seekMsg{} })
+}
+
+// applySeek moves the playback by the seek keys pressed from the current position, unless
+// the audio ends before.
+func (p *Player) applySeek() tea.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.seekPending {
+		return nil
+	}
+
+	target := p.engine.Position() + p.seekOffset
+	p.seekOffset, p.seekPending = 0, false
+	if target >= p.engine.Duration() {
+		return nil
+	}
+	return p.seek(max(target, 0))
+}
+
+// SeekPercent moves the playback to the given percentage of the length of the audio.
+func (p *Player) SeekPercent(percent int) tea.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.engine.Path() == "" || p.engine.Completed() || p.engine.Live() {
+		return nil
+	}
+
+	p.seekOffset, p.seekPending = 0, false
+	if err := p.engine.SeekFraction(float64(percent) / 100); err != nil {
+		return p.fail(err)
+	}
+	return nil
+}
+
+// SeekTo moves the playback to the given position of the audio.
+func (p *Player) SeekTo(pos time.Duration) tea.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.seek(pos)
+}
+
+// seek moves the playback to pos, clamped to the length of the audio, dropping the seek keys
+// pressed before. The caller must hold p.mu.
+func (p *Player) seek(pos time.Duration) tea.Cmd {
+	p.seekOffset, p.seekPending = 0, false
+
+	// A finished audio only plays again when restarted, and a live stream has nowhere to go
+	if p.engine.Path() == "" || p.engine.Completed() || p.engine.Live() {
+		return nil
+	}
+
+	if err := p.engine.Seek(pos); err != nil {
+		return p.fail(err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package queue
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicolito128/tempo/internal/audio"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// IsPlaylist reports whether the file at path is a M3U playlist.
+func IsPlaylist(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".m3u" || ext == ".m3u8"
+}
+
+// ReadPlaylist reads the supported audio files listed in a M3U playlist.
+// Relative entries are resolved from the playlist directory, and URLs are kept.
+func ReadPlaylist(path string) ([]audio.File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	dir := filepath.Dir(path)
+
+	var files []audio.File
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		line = strings.TrimPrefix(line, "\ufeff")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !filepath.IsAbs(line) && !engine.IsURL(line) {
+			line = filepath.Join(dir, line)
+		}
+		if audio.IsSupported(line) {
+			files = append(files, audio.NewFile(line))
+		}
+	}
+
+	return files, scanner.Err()
+}
+
+// AppendPlaylist adds the files to the end of the M3U playlist at path, creating it if needed.
+func AppendPlaylist(path string, files []audio.File) error {
+	_, err := os.Stat(path)
+	isNew := os.IsNotExist(err)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	if isNew {
+		w.WriteString("#EXTM3U\n")
+	}
+	for _, af := range files {
+		w.WriteString(af.Path() + "\n")
+	}
+	return w.Flush()
+}
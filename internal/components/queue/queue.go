@@ -1,16 +1,313 @@
 package queue
 
-import tea "github.com/charmbracelet/bubbletea"
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
-type Queue struct{}
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/audio"
+	"github.com/nicolito128/tempo/internal/styles"
+	"github.com/nicolito128/tempo/internal/tags"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+const (
+	// Maximum number of items displayed at once
+	VisibleItems int = 10
+)
+
+// DedupMode defines what the queue does with duplicated entries.
+type DedupMode int
+
+const (
+	// DedupSkip does not enqueue duplicated entries
+	DedupSkip DedupMode = iota
+	// DedupFlag enqueues duplicated entries but flags them in the view
+	DedupFlag
+	// DedupOff does not look for duplicates at all
+	DedupOff
+)
+
+// ParseDedupMode converts a mode name (skip, flag or off) into a DedupMode.
+func ParseDedupMode(s string) (DedupMode, error) {
+	switch strings.ToLower(s) {
+	case "skip":
+		return DedupSkip, nil
+	case "flag":
+		return DedupFlag, nil
+	case "off":
+		return DedupOff, nil
+	}
+	return DedupSkip, fmt.Errorf("unknown dedup mode %q", s)
+}
+
+// Item : An entry of the queue
+type Item struct {
+	Audio audio.File
+
+	// Duplicate if the same audio was already in the queue when enqueued
+	Duplicate bool
+
+	// Err why the audio could not be played, nil unless it failed
+	Err error
+}
+
+// audioKey identifies the same audio stored in different paths.
+type audioKey struct {
+	size     int64
+	duration time.Duration
+}
+
+// Queue : A list of audio files to be played in order
+type Queue struct {
+	items []Item
+
+	// Index of the item being played
+	current int
+
+	// repeat if the first item follows the last one
+	repeat bool
+
+	// What to do with duplicated entries
+	dedup DedupMode
+
+	// compareAudio if files with same size and duration are considered duplicates
+	compareAudio bool
+
+	// Cleaned paths of the enqueued files
+	paths map[string]struct{}
+
+	// Size of the enqueued files, with its probed duration when needed
+	sizes     map[int64][]string
+	durations map[string]time.Duration
+
+	// Number of changes made to the items, for the remotes showing them
+	version int
+}
 
 var _ tea.Model = (*Queue)(nil)
 
 func New() *Queue {
 	q := new(Queue)
+	q.paths = make(map[string]struct{})
+	q.sizes = make(map[int64][]string)
+	q.durations = make(map[string]time.Duration)
 	return q
 }
 
+// SetDedup sets how duplicates are handled. If compareAudio is true, files with
+// the same size and duration are also considered duplicates.
+func (q *Queue) SetDedup(mode DedupMode, compareAudio bool) {
+	q.dedup = mode
+	q.compareAudio = compareAudio
+}
+
+// Add enqueues the given audio files, returning how many were skipped as duplicates.
+func (q *Queue) Add(files ...audio.File) (skipped int) {
+	for _, af := range files {
+		dup := q.dedup != DedupOff && q.isDuplicate(af.Path())
+		if dup && q.dedup == DedupSkip {
+			skipped++
+			continue
+		}
+
+		q.remember(af.Path())
+		q.items = append(q.items, Item{Audio: af, Duplicate: dup})
+		q.version++
+	}
+	return skipped
+}
+
+// AddPath enqueues a file, every audio file inside a directory or the entries of a playlist.
+func (q *Queue) AddPath(path string) (skipped int, err error) {
+	files, err := Expand(path)
+	if err != nil {
+		return 0, err
+	}
+	return q.Add(files...), nil
+}
+
+// Play jumps to the given audio file, enqueueing it at the end if it is not in the queue yet.
+func (q *Queue) Play(af audio.File) audio.File {
+	key := cleanPath(af.Path())
+	for i, item := range q.items {
+		if cleanPath(item.Audio.Path()) == key {
+			q.current = i
+			return item.Audio
+		}
+	}
+
+	q.remember(af.Path())
+	q.items = append(q.items, Item{Audio: af})
+	q.current = len(q.items) - 1
+	q.version++
+	return af
+}
+
+// PlayAt jumps to the item at index i.
+func (q *Queue) PlayAt(i int) (audio.File, bool) {
+	if i < 0 || i >= len(q.items) {
+		return audio.File{}, false
+	}
+	q.current = i
+	return q.Current()
+}
+
+// Index returns the index of the item being played, -1 if there is none.
+func (q *Queue) Index() int {
+	if q.current < 0 || q.current >= len(q.items) {
+		return -1
+	}
+	return q.current
+}
+
+// Version returns a number that changes each time the items do.
+func (q *Queue) Version() int {
+	return q.version
+}
+
+// Len returns the number of items in the queue.
+func (q *Queue) Len() int {
+	return len(q.items)
+}
+
+// Items returns the enqueued items.
+func (q *Queue) Items() []Item {
+	return q.items
+}
+
+// Current returns the audio file being played.
+func (q *Queue) Current() (audio.File, bool) {
+	if q.current < 0 || q.current >= len(q.items) {
+		return audio.File{}, false
+	}
+	return q.items[q.current].Audio, true
+}
+
+// Next moves to the next item of the queue, or back to the first one after the last one if
+// it repeats.
+func (q *Queue) Next() (audio.File, bool) {
+	if q.current+1 >= len(q.items) {
+		if !q.repeat || len(q.items) == 0 {
+			return audio.File{}, false
+		}
+		q.current = -1
+	}
+	q.current++
+	return q.Current()
+}
+
+// Previous moves to the previous item of the queue.
+func (q *Queue) Previous() (audio.File, bool) {
+	if q.current-1 < 0 {
+		return audio.File{}, false
+	}
+	q.current--
+	return q.Current()
+}
+
+// HasNext reports whether Next has an item to move to.
+func (q *Queue) HasNext() bool {
+	return q.current+1 < len(q.items) || (q.repeat && len(q.items) > 0)
+}
+
+// HasPrevious reports whether Previous has an item to move to.
+func (q *Queue) HasPrevious() bool {
+	return q.current > 0 && q.current <= len(q.items)
+}
+
+// Repeat reports whether the queue starts over after its last item.
+func (q *Queue) Repeat() bool {
+	return q.repeat
+}
+
+// SetRepeat sets whether the queue starts over after its last item.
+func (q *Queue) SetRepeat(repeat bool) {
+	q.repeat = repeat
+}
+
+// Shuffle puts the items in a random order, moving to the first one.
+func (q *Queue) Shuffle() {
+	rand.Shuffle(len(q.items), func(i, j int) {
+		q.items[i], q.items[j] = q.items[j], q.items[i]
+	})
+	q.current = 0
+	q.version++
+}
+
+// Move changes the path of the enqueued files moved from the file or directory at from to to.
+func (q *Queue) Move(from, to string) {
+	for i, item := range q.items {
+		rest, ok := within(item.Audio.Path(), from)
+		if !ok {
+			continue
+		}
+
+		q.items[i].Audio.SetPath(filepath.Join(cleanPath(to), rest))
+		q.version++
+	}
+	q.reindex()
+}
+
+// SetTags replaces the tags of the enqueued files with the given path, after they are edited.
+func (q *Queue) SetTags(path string, t tags.Tags) {
+	path = cleanPath(path)
+	for i := range q.items {
+		if cleanPath(q.items[i].Audio.Path()) == path {
+			q.items[i].Audio.SetTags(t)
+			q.version++
+		}
+	}
+}
+
+// SetFailed marks the enqueued files with the given path as failed to play because of err.
+func (q *Queue) SetFailed(path string, err error) {
+	path = cleanPath(path)
+	for i := range q.items {
+		if cleanPath(q.items[i].Audio.Path()) == path {
+			q.items[i].Err = err
+		}
+	}
+}
+
+// Remove removes the enqueued files that are the file or are inside the directory at path.
+// If the current item is removed, the next one is played after it.
+func (q *Queue) Remove(path string) {
+	items := q.items[:0]
+	current := -1
+	for i, item := range q.items {
+		if _, ok := within(item.Audio.Path(), path); ok {
+			q.version++
+			continue
+		}
+		if i <= q.current {
+			current++
+		}
+		items = append(items, item)
+	}
+	q.items = items
+	q.current = current
+	q.reindex()
+}
+
+// Clear removes every item.
+func (q *Queue) Clear() {
+	if len(q.items) > 0 {
+		q.version++
+	}
+	q.items = nil
+	q.current = 0
+	clear(q.paths)
+	clear(q.sizes)
+	clear(q.durations)
+}
+
 func (q *Queue) Init() tea.Cmd {
 	return nil
 }
@@ -20,5 +317,227 @@ func (q *Queue) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (q *Queue) View() string {
-	return ""
+	if len(q.items) == 0 {
+		return ""
+	}
+
+	// Keep the current item visible
+	start := max(q.current-VisibleItems/2, 0)
+	end := min(start+VisibleItems, len(q.items))
+	start = max(end-VisibleItems, 0)
+
+	dupElem := lipgloss.NewStyle().
+		Foreground(styles.ContrastColor()).
+		Render(" ⧉ duplicate")
+	failedElem := lipgloss.NewStyle().
+		Foreground(styles.ProblemColor()).
+		Render(" ✗ failed")
+
+	var lines []string
+	for i := start; i < end; i++ {
+		item := q.items[i]
+
+		line := fmt.Sprintf(" %02d. %s ", i+1, item.Audio.Label())
+		if i == q.current {
+			line = styles.PrimaryHighlight(" ▶" + line)
+		} else {
+			line = "  " + line
+		}
+		if item.Duplicate {
+			line += dupElem
+		}
+		if item.Err != nil {
+			line += failedElem
+		}
+		lines = append(lines, line)
+	}
+
+	header := fmt.Sprintf("Queue (%d/%d)", q.current+1, len(q.items))
+	if q.repeat {
+		header = fmt.Sprintf("Queue (%d/%d, repeating)", q.current+1, len(q.items))
+	}
+	header = styles.Help(header)
+	return lipgloss.JoinVertical(lipgloss.Left, header, strings.Join(lines, "\n"))
+}
+
+// isDuplicate reports whether the file at path is already in the queue.
+func (q *Queue) isDuplicate(path string) bool {
+	key := cleanPath(path)
+	if _, ok := q.paths[key]; ok {
+		return true
+	}
+	if !q.compareAudio {
+		return false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	// Durations are only probed when there is another file with the same size
+	others := q.sizes[info.Size()]
+	if len(others) == 0 {
+		return false
+	}
+
+	k, ok := q.audioKey(key, info.Size())
+	if !ok {
+		return false
+	}
+	for _, other := range others {
+		if ko, ok := q.audioKey(other, info.Size()); ok && ko == k {
+			return true
+		}
+	}
+	return false
+}
+
+// remember stores the path (and size) of an enqueued file for later duplicate checks.
+func (q *Queue) remember(path string) {
+	key := cleanPath(path)
+	q.paths[key] = struct{}{}
+
+	if info, err := os.Stat(path); err == nil {
+		q.sizes[info.Size()] = append(q.sizes[info.Size()], key)
+	}
+}
+
+// reindex forgets the files no longer enqueued, and flags again the items that are duplicates
+// of an earlier one, after items are removed or moved.
+func (q *Queue) reindex() {
+	durations := q.durations
+	q.durations = make(map[string]time.Duration)
+	clear(q.paths)
+	clear(q.sizes)
+	for i := range q.items {
+		path := q.items[i].Audio.Path()
+		if d, ok := durations[cleanPath(path)]; ok {
+			q.durations[cleanPath(path)] = d
+		}
+		dup := q.dedup != DedupOff && q.isDuplicate(path)
+		if dup != q.items[i].Duplicate {
+			q.items[i].Duplicate = dup
+			q.version++
+		}
+		q.remember(path)
+	}
+}
+
+func (q *Queue) audioKey(path string, size int64) (audioKey, bool) {
+	d, ok := q.durations[path]
+	if !ok {
+		var err error
+		d, err = engine.ProbeDuration(path)
+		if err != nil {
+			return audioKey{}, false
+		}
+		q.durations[path] = d
+	}
+	return audioKey{size: size, duration: d}, true
+}
+
+// Expand returns the audio files referenced by path: the file itself, the
+// supported files inside a directory (recursively) or the entries of a playlist. A URL is
+// the audio it serves.
+func Expand(path string) ([]audio.File, error) {
+	if engine.IsURL(path) {
+		files := []audio.File{audio.NewFile(path)}
+		loadTags(files)
+		return files, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []audio.File
+	switch {
+	case info.IsDir():
+		err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && audio.IsSupported(p) {
+				files = append(files, audio.NewFile(p))
+			}
+			return nil
+		})
+		loadTags(files)
+		sortByTrack(files)
+		return files, err
+
+	case IsPlaylist(path):
+		files, err = ReadPlaylist(path)
+		loadTags(files)
+		return files, err
+
+	case !audio.IsSupported(path):
+		return nil, fmt.Errorf("%s is not a valid audio file", path)
+	}
+
+	files = []audio.File{audio.NewFile(path)}
+	loadTags(files)
+	return files, nil
+}
+
+// loadTags reads the metadata of the files, so the queue shows their tags.
+func loadTags(files []audio.File) {
+	for i := range files {
+		files[i].LoadTags()
+	}
+}
+
+// sortByTrack sorts the files of each directory by their disc and track number tags,
+// which must be loaded.
+// Directories with untagged files keep the order of the file names.
+func sortByTrack(files []audio.File) {
+	for start := 0; start < len(files); {
+		dir := filepath.Dir(files[start].Path())
+		end := start + 1
+		for end < len(files) && filepath.Dir(files[end].Path()) == dir {
+			end++
+		}
+
+		run := files[start:end]
+		positions := make(map[string][2]int, len(run))
+		for _, af := range run {
+			tg := af.Tags()
+			if tg.Track == 0 {
+				positions = nil
+				break
+			}
+			positions[af.Path()] = [2]int{tg.Disc, tg.Track}
+		}
+		if positions != nil {
+			sort.SliceStable(run, func(i, j int) bool {
+				a, b := positions[run[i].Path()], positions[run[j].Path()]
+				if a[0] != b[0] {
+					return a[0] < b[0]
+				}
+				return a[1] < b[1]
+			})
+		}
+		start = end
+	}
+}
+
+// within reports whether path is dir or is inside of it, returning the path relative to dir.
+func within(path, dir string) (string, bool) {
+	path, dir = cleanPath(path), cleanPath(dir)
+	if path == dir {
+		return "", true
+	}
+	rest, ok := strings.CutPrefix(path, dir+string(filepath.Separator))
+	return rest, ok
+}
+
+func cleanPath(path string) string {
+	if engine.IsURL(path) {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
 }
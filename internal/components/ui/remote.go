@@ -0,0 +1,189 @@
+package ui
+
+import (
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/audio"
+	"github.com/nicolito128/tempo/internal/remote"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// AddRemote tells the remote what is being played after each message, until the UI is
+// closed.
+func (ui *UI) AddRemote(r remote.Remote) {
+	ui.remotes = append(ui.remotes, r)
+	r.Update(ui.remoteStatus())
+}
+
+// remoteStatus returns what is being played, as shown to the remotes.
+func (ui *UI) remoteStatus() remote.Status {
+	st := remote.Status{
+		Volume:       ui.player.Volume(),
+		Muted:        ui.player.Muted(),
+		Speed:        ui.player.Speed(),
+		Repeat:       ui.queue.Repeat(),
+		HasNext:      ui.queue.HasNext(),
+		HasPrevious:  ui.queue.HasPrevious(),
+		Queue:        ui.remoteQueueItems(),
+		Index:        ui.queue.Index(),
+		QueueVersion: ui.queue.Version(),
+	}
+	if !ui.player.HasAudio() {
+		return st
+	}
+
+	af := ui.player.Audio()
+	st.Path, st.Title, st.Artist, st.Album = af.Path(), af.Title(), af.Artist(), af.Album()
+	st.Track = af.Tags().Track
+	st.Length, st.Position = ui.player.Duration(), ui.player.Elapsed()
+	st.Live = ui.player.Live()
+	switch {
+	case ui.player.Playing():
+		st.State = remote.Playing
+	case ui.player.Completed():
+		st.State = remote.Stopped
+	default:
+		st.State = remote.Paused
+	}
+	return st
+}
+
+// remoteQueueItems returns the files of the queue, as shown to the remotes.
+func (ui *UI) remoteQueueItems() []remote.QueueItem {
+	if ui.remoteQueueVersion == ui.queue.Version() {
+		return ui.remoteQueue
+	}
+	// The previous slice may still be read by the remotes, so a new one is made
+	items := make([]remote.QueueItem, ui.queue.Len())
+	for i, item := range ui.queue.Items() {
+		af := item.Audio
+		items[i] = remote.QueueItem{Path: af.Path(), Title: af.Title(), Artist: af.Artist(), Album: af.Album(), Track: af.Tags().Track}
+	}
+	ui.remoteQueue, ui.remoteQueueVersion = items, ui.queue.Version()
+	return items
+}
+
+// updateRemote does what a remote requested.
+func (ui *UI) updateRemote(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case remote.CommandMsg:
+		return ui.remoteCommand(msg.Command)
+
+	case remote.SeekMsg:
+		if !ui.player.HasAudio() || ui.player.Live() {
+			return nil
+		}
+		// Seeking past the end goes to the next file, like the remotes expect
+		pos := ui.player.Elapsed() + msg.Offset
+		if pos >= ui.player.Duration() {
+			return ui.remoteCommand(remote.Next)
+		}
+		return ui.player.SeekTo(max(pos, 0))
+
+	case remote.SetPositionMsg:
+		if !ui.player.HasAudio() || ui.player.Audio().Path() != msg.Path {
+			return nil
+		}
+		if msg.Position < 0 || msg.Position > ui.player.Duration() {
+			return nil
+		}
+		return ui.player.SeekTo(msg.Position)
+
+	case remote.SetVolumeMsg:
+		ui.player.SetVolume(msg.Volume)
+
+	case remote.SetSpeedMsg:
+		ui.player.SetSpeed(msg.Speed)
+
+	case remote.SetRepeatMsg:
+		ui.queue.SetRepeat(msg.Repeat)
+
+	case remote.OpenMsg:
+		if _, err := os.Stat(msg.Path); err != nil && !engine.IsURL(msg.Path) {
+			ui.report("Cannot open "+msg.Path, err)
+			return nil
+		}
+		return ui.play(ui.queue.Play(audio.NewFile(msg.Path)))
+
+	case remote.EnqueueMsg:
+		first := ui.queue.Len()
+		for _, path := range msg.Paths {
+			if _, err := ui.queue.AddPath(path); err != nil {
+				ui.report("Cannot enqueue "+path, err)
+			}
+		}
+		if ui.player.HasAudio() || ui.queue.Len() == first {
+			return nil
+		}
+		return ui.play(ui.queue.Play(ui.queue.Items()[first].Audio))
+
+	case remote.PlayIndexMsg:
+		if af, ok := ui.queue.PlayAt(msg.Index); ok {
+			return ui.play(af)
+		}
+
+	case remote.RemoveMsg:
+		ui.queue.Remove(msg.Path)
+
+	case remote.ClearMsg:
+		ui.queue.Clear()
+	}
+	return nil
+}
+
+// remoteCommand does a command of a remote.
+func (ui *UI) remoteCommand(c remote.Command) tea.Cmd {
+	switch c {
+	case remote.Play, remote.PlayPause:
+		if !ui.player.HasAudio() {
+			if af, ok := ui.queue.Current(); ok {
+				return ui.play(af)
+			}
+			return nil
+		}
+		if c == remote.PlayPause || !ui.player.Playing() {
+			return ui.player.StopOrResume()
+		}
+
+	case remote.Pause:
+		if ui.player.Playing() {
+			return ui.player.StopOrResume()
+		}
+
+	case remote.Stop:
+		if !ui.player.HasAudio() {
+			return nil
+		}
+		var cmd tea.Cmd
+		if ui.player.Playing() {
+			cmd = ui.player.StopOrResume()
+		}
+		return tea.Batch(cmd, ui.player.SeekTo(0))
+
+	case remote.Next:
+		if af, ok := ui.queue.Next(); ok {
+			return ui.play(af)
+		}
+
+	case remote.Previous:
+		if af, ok := ui.queue.Previous(); ok {
+			return ui.play(af)
+		}
+
+	case remote.Quit:
+		return ui.player.Quit()
+	}
+	return nil
+}
+
+// updateRemotes tells the remotes what is being played.
+func (ui *UI) updateRemotes() {
+	if len(ui.remotes) == 0 {
+		return
+	}
+	st := ui.remoteStatus()
+	for _, r := range ui.remotes {
+		r.Update(st)
+	}
+}
@@ -1,36 +1,1312 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nicolito128/tempo/internal/acoustid"
+	"github.com/nicolito128/tempo/internal/analysis"
+	"github.com/nicolito128/tempo/internal/art"
+	"github.com/nicolito128/tempo/internal/audio"
+	"github.com/nicolito128/tempo/internal/cast"
+	"github.com/nicolito128/tempo/internal/components/chapterpane"
+	"github.com/nicolito128/tempo/internal/components/devicepane"
+	"github.com/nicolito128/tempo/internal/components/editor"
+	"github.com/nicolito128/tempo/internal/components/eqpane"
+	"github.com/nicolito128/tempo/internal/components/lyricspane"
+	"github.com/nicolito128/tempo/internal/components/panel"
 	"github.com/nicolito128/tempo/internal/components/player"
+	"github.com/nicolito128/tempo/internal/components/queue"
+	"github.com/nicolito128/tempo/internal/config"
+	"github.com/nicolito128/tempo/internal/desktop"
+	"github.com/nicolito128/tempo/internal/httpclient"
+	"github.com/nicolito128/tempo/internal/library"
+	"github.com/nicolito128/tempo/internal/loudness"
+	"github.com/nicolito128/tempo/internal/lyrics"
+	"github.com/nicolito128/tempo/internal/musicbrainz"
+	"github.com/nicolito128/tempo/internal/remote"
+	"github.com/nicolito128/tempo/internal/state"
+	"github.com/nicolito128/tempo/internal/styles"
+	"github.com/nicolito128/tempo/internal/tags"
+	"github.com/nicolito128/tempo/internal/xdg"
+	"github.com/nicolito128/tempo/pkg/engine"
 )
 
 // UI : Tempo user interface model
 //
-// It holds the player, queue and current state of the UI.
+// It holds the player, queue, panel and current state of the UI.
 type UI struct {
 	width  int
 	height int
 
-	player *player.Player
+	player   *player.Player
+	queue    *queue.Queue
+	panel    *panel.Panel
+	editor   *editor.Editor
+	lyrics   *lyricspane.Pane
+	chapters *chapterpane.Pane
+	devices  *devicepane.Pane
+	eq       *eqpane.Pane
+
+	library *library.Library
+	scanner *library.Scanner
+	index   *library.Index
+	watcher *library.Watcher
+	// Generation of the library directories, counting the reloads that changed them. The
+	// messages of their scanner and watcher carry it, so the ones of the replaced ones are
+	// dropped
+	libraryGen int
+
+	// checking if the config is only being checked, without changing what the program
+	// shares, like the theme and the output device
+	checking bool
+
+	// Watcher of the config file, nil if it cannot be watched
+	configWatcher *config.Watcher
+
+	// Programs controlling tempo from outside the terminal, like the media controls
+	remotes []remote.Remote
+	// Files of the queue shown to the remotes, built again when its version changes
+	remoteQueue        []remote.QueueItem
+	remoteQueueVersion int
+
+	// Which files of the library directories are indexed
+	filter *library.Filter
+
+	// Optional identifier of the untagged files found while scanning
+	identifier library.Identifier
+
+	// analyze if the tempo and key of the tracks are estimated while scanning
+	analyze bool
+
+	// scanning if the library scan is running
+	scanning bool
+
+	// recorded if the statistics of the current track were already saved
+	recorded bool
+
+	// audiobook if the tracks are resumed from where they were left at, otherwise resume is
+	// the position offered to resume the current track from, 0 if there is none
+	audiobook bool
+	resume    time.Duration
+
+	// pauseOnBlur if the playback is paused while the terminal is not focused, and blurPaused
+	// the path of the file it paused, to resume it on focus, empty if none
+	pauseOnBlur bool
+	blurPaused  string
+
+	// Position the first track starts at instead of the one it was left at, 0 if not given
+	startAt time.Duration
+
+	// Session of the last run offered to be restored, nil if there is none
+	session *state.Session
+
+	// User settings, saved when changed from the UI
+	config *config.Config
+
+	// Smart playlists parsed from the config
+	smartPlaylists []library.SmartPlaylist
+
+	// Status line shown below the panel
+	status string
+
+	// Error of the last track that could not be played, shown until dismissed
+	failure string
+	// Tracks that failed one after the other, skipped until every one of the queue failed
+	failedInRow int
 }
 
 var _ tea.Model = (*UI)(nil)
 
-func New(initVolume int) *UI {
+func New(initVolume int, dir string) *UI {
 	ui := new(UI)
 	ui.player = player.New(initVolume)
+	ui.queue = queue.New()
+	ui.panel = panel.New(dir)
+	ui.editor = editor.New()
+	ui.editor.SetLookup(newMusicBrainz())
+	ui.lyrics = lyricspane.New(ui.player.Elapsed)
+	ui.chapters = chapterpane.New(ui.player.Chapters, ui.player.Chapter)
+	ui.devices = devicepane.New(ui.player.Devices, ui.player.Device)
+	ui.eq = eqpane.New(ui.player.Equalizer, ui.player.Preamp)
+	ui.library = library.New()
+	ui.panel.SetLibrary(ui.library)
 	return ui
 }
 
-func (ui *UI) Init() tea.Cmd {
-	ui.player.Init()
+// newMusicBrainz creates the client of the online metadata lookup, caching its responses
+// when the cache directory is known.
+func newMusicBrainz() *musicbrainz.Client {
+	client := httpclient.New(musicbrainz.Interval)
+	if dir := cacheDir("musicbrainz"); dir != "" {
+		client.SetCache(dir, musicbrainz.CacheTTL)
+	}
+	return musicbrainz.New(client)
+}
+
+// cacheDir returns the directory caching the responses of an online service, or an empty
+// string if the user cache directory is unknown.
+func cacheDir(name string) string {
+	dir, err := xdg.CacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, name)
+}
+
+// newAcoustID creates the client identifying the audio files by their fingerprint.
+func newAcoustID(apiKey string) *acoustid.Client {
+	return acoustid.New(httpclient.New(acoustid.Interval), apiKey)
+}
+
+// SetConfig applies the user settings to the UI components.
+func (ui *UI) SetConfig(cfg *config.Config) error {
+	ui.config = cfg
+	for _, step := range configSteps {
+		if err := step(ui, cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckConfig returns every problem of the settings that SetConfig would stop at, like bad
+// colors or keys bound to two actions, without applying them.
+func CheckConfig(cfg *config.Config) []error {
+	ui := New(cfg.Player.Volume, "")
+	ui.config = cfg
+	ui.checking = true
+	var errs []error
+	for _, step := range configSteps {
+		if err := step(ui, cfg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// configSteps apply each group of settings, in order, and are independent of each other so
+// the problems of all of them can be checked at once.
+var configSteps = []func(ui *UI, cfg *config.Config) error{
+	(*UI).setBrowser,
+	(*UI).setPlayback,
+	func(ui *UI, cfg *config.Config) error {
+		keys, err := player.NewKeyMap(cfg.Keys)
+		if err != nil {
+			return err
+		}
+		ui.player.SetKeys(keys)
+		ui.panel.SetKeys(keys)
+		return nil
+	},
+	(*UI).setSilence,
+	func(ui *UI, cfg *config.Config) error {
+		format, err := cast.ParseSampleFormat(cfg.Snapcast.SampleFormat)
+		if err != nil {
+			return fmt.Errorf("snapcast: %w", err)
+		}
+		ui.player.SetSnapcast(xdg.ExpandHome(cfg.Snapcast.Source), format)
+		set := ui.player.SetDevice
+		if ui.checking {
+			set = ui.player.CheckDevice
+		}
+		if err := set(cfg.Player.Device); err != nil {
+			return fmt.Errorf("device %q: %w", cfg.Player.Device, err)
+		}
+		return nil
+	},
+	func(ui *UI, cfg *config.Config) error { return ui.setEqualizer(cfg.Equalizer) },
+	func(ui *UI, cfg *config.Config) error {
+		theme, err := parseTheme(cfg.Theme)
+		if err != nil {
+			return err
+		}
+		if !ui.checking {
+			styles.SetTheme(theme)
+		}
+		return nil
+	},
+	(*UI).setLibrary,
+	(*UI).setLyrics,
+	(*UI).setSmartPlaylists,
+	(*UI).setStations,
+}
+
+// setBrowser applies the settings of the file browser.
+func (ui *UI) setBrowser(cfg *config.Config) error {
+	order, err := panel.ParseSortOrder(cfg.Browser.Sort)
+	if err != nil {
+		return err
+	}
+	ui.panel.SetSort(order, cfg.Browser.SortReverse)
+	ui.panel.SetBookmarks(cfg.Browser.Bookmarks)
+	ui.panel.SetRecentDays(cfg.Library.RecentDays)
+	return nil
+}
+
+// setPlayback applies the settings of the player but the keys, the silence and the device.
+func (ui *UI) setPlayback(cfg *config.Config) error {
+	protocol, err := art.ParseProtocol(cfg.Player.Cover)
+	if err != nil {
+		return err
+	}
+	ui.player.SetArtProtocol(protocol)
+
+	gain, err := player.ParseGainMode(cfg.Player.ReplayGain)
+	if err != nil {
+		return err
+	}
+	ui.player.SetGainMode(gain)
+	ui.player.SetNormalize(cfg.Player.Normalize)
+
+	if err := ui.player.SetVolumeStep(cfg.Player.VolumeStep); err != nil {
+		return err
+	}
+	err = ui.player.SetSeekSteps(player.SeekSteps{
+		Small: time.Duration(cfg.Player.SeekSeconds) * time.Second,
+		Large: time.Duration(cfg.Player.LargeSeekSeconds) * time.Second,
+	})
+	if err != nil {
+		return err
+	}
+
+	if cfg.Player.FadeMS < 0 {
+		return fmt.Errorf("fade_ms %d is negative", cfg.Player.FadeMS)
+	}
+	ui.player.SetFade(time.Duration(cfg.Player.FadeMS) * time.Millisecond)
+	ui.player.SetRecordDir(xdg.ExpandHome(cfg.Player.RecordDir))
+	ui.pauseOnBlur = cfg.Player.PauseOnBlur
+	return ui.player.SetReadAhead(time.Duration(cfg.Player.ReadAheadMS) * time.Millisecond)
+}
+
+// setSilence applies how the stretches of silence are played.
+func (ui *UI) setSilence(cfg *config.Config) error {
+	silence, err := engine.ParseSilenceMode(cfg.Player.Silence)
+	if err != nil {
+		return err
+	}
+	return ui.player.SetSilence(engine.Silence{
+		Mode:      silence,
+		Threshold: cfg.Player.SilenceThresholdDB,
+		Length:    time.Duration(cfg.Player.SilenceMS) * time.Millisecond,
+	})
+}
+
+// setLibrary applies the settings of the library scan.
+func (ui *UI) setLibrary(cfg *config.Config) error {
+	filter, err := library.NewFilter(cfg.Library.Ignore, cfg.Library.Extensions)
+	if err != nil {
+		return err
+	}
+	ui.filter = filter
+
+	ui.identifier = nil
+	if cfg.AcoustID.Scan && cfg.AcoustID.APIKey != "" {
+		ui.identifier = newAcoustID(cfg.AcoustID.APIKey)
+	}
+	ui.analyze = cfg.Library.Analyze
+	ui.audiobook = cfg.Player.Audiobook
+	if ui.scanner != nil {
+		ui.scanner.SetFilter(filter)
+		ui.scanner.SetIdentifier(ui.identifier)
+		ui.scanner.SetAnalyze(ui.analyze)
+		ui.scanner.SetMeasureLoudness(ui.player.Normalize())
+	}
+	return nil
+}
+
+// setLyrics applies the providers of the lyrics.
+func (ui *UI) setLyrics(cfg *config.Config) error {
+	var providers []lyrics.Provider
+	for _, name := range cfg.Lyrics.Providers {
+		provider, err := lyrics.NewProvider(name, cacheDir("lyrics"))
+		if err != nil {
+			return err
+		}
+		providers = append(providers, provider)
+	}
+	ui.lyrics.SetProviders(providers)
+	return nil
+}
+
+// setSmartPlaylists parses the queries of the smart playlists.
+func (ui *UI) setSmartPlaylists(cfg *config.Config) error {
+	var playlists []library.SmartPlaylist
+	for _, sp := range cfg.SmartPlaylists {
+		q, err := library.ParseQuery(sp.Query)
+		if err != nil {
+			return fmt.Errorf("smart playlist %q: %w", sp.Name, err)
+		}
+		playlists = append(playlists, library.SmartPlaylist{Name: sp.Name, Query: q})
+	}
+	ui.smartPlaylists = playlists
+	ui.panel.SetSmartPlaylists(playlists)
+	return nil
+}
+
+// setStations lists the saved internet radios in the stations view.
+func (ui *UI) setStations(cfg *config.Config) error {
+	var stations []panel.Station
+	for _, s := range cfg.Stations {
+		if !engine.IsURL(s.URL) {
+			return fmt.Errorf("station %q: %q is not an HTTP(S) URL", s.Name, s.URL)
+		}
+		station := panel.Station{Name: s.Name, URL: s.URL, Genres: s.Genres}
+		if station.Name == "" {
+			station.Name = audio.NewFile(s.URL).Name()
+		}
+		stations = append(stations, station)
+	}
+	ui.panel.SetStations(stations)
+	return nil
+}
+
+// EnqueueSmart adds the tracks of the smart playlist with the given name to the queue.
+// The tracks are taken from the library index, so it must be set before.
+func (ui *UI) EnqueueSmart(name string) error {
+	if ui.index == nil {
+		return errors.New("smart playlists need the library index, use -library")
+	}
+
+	for _, sp := range ui.smartPlaylists {
+		if sp.Name != name {
+			continue
+		}
+
+		all, err := ui.index.All()
+		if err != nil {
+			return err
+		}
+		tracks, err := sp.Tracks(all, ui.index)
+		if err != nil {
+			return err
+		}
+		for _, t := range tracks {
+			ui.queue.Add(t.Audio())
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown smart playlist %q", name)
+}
+
+// saveConfig writes the settings changed from the UI, reporting errors in the status line.
+func (ui *UI) saveConfig() {
+	if ui.config == nil {
+		return
+	}
+	if err := ui.config.Save(); err != nil {
+		ui.report("Cannot save the config", err)
+	}
+}
+
+// cycleGain switches to the next ReplayGain mode, saving it in the config. The loudness of
+// the current track is measured if the mode normalizes it.
+func (ui *UI) cycleGain() tea.Cmd {
+	mode := ui.player.GainMode().Next()
+	ui.player.SetGainMode(mode)
+	ui.status = "ReplayGain: " + mode.String()
+	if ui.config != nil {
+		ui.config.Player.ReplayGain = mode.String()
+		ui.saveConfig()
+	}
+	return ui.normalize()
+}
+
+// cycleSilence switches to the next way of playing the silence, saving it in the config.
+func (ui *UI) cycleSilence() {
+	s := ui.player.Silence()
+	s.Mode = s.Mode.Next()
+	if err := ui.player.SetSilence(s); err != nil {
+		ui.report("Cannot change the silence mode", err)
+		return
+	}
+	ui.status = "Silence: " + s.Mode.String()
+	if ui.config != nil {
+		ui.config.Player.Silence = s.Mode.String()
+		ui.saveConfig()
+	}
+}
+
+// SetStartAt starts the first track at pos, instead of at the start or where it was left at.
+func (ui *UI) SetStartAt(pos time.Duration) {
+	ui.startAt = pos
+}
+
+// SetDevice plays on the audio output device with the given name instead of the one of
+// the config, without saving it.
+func (ui *UI) SetDevice(name string) error {
+	return ui.player.SetDevice(name)
+}
+
+// selectDevice plays on the device chosen in the picker, saving it in the config. Devices
+// that cannot be changed once open are used from the next start.
+func (ui *UI) selectDevice(d engine.Device) {
+	err := ui.player.SetDevice(d.Name)
+	switch {
+	case errors.Is(err, engine.ErrDeviceOpen):
+		ui.status = "Output device: " + d.String() + " (restart tempo to use it)"
+	case err != nil:
+		ui.report("Cannot play on "+d.String(), err)
+		return
+	default:
+		ui.status = "Output device: " + d.String()
+	}
+
+	ui.devices.Hide()
+	if ui.config != nil {
+		ui.config.Player.Device = d.Name
+		ui.saveConfig()
+	}
+}
+
+// setEqualizer plays with the curve of the config, and lets the pane switch to its profiles.
+func (ui *UI) setEqualizer(cfg config.Equalizer) error {
+	var profiles []player.Preset
+	for _, prof := range cfg.Profiles {
+		if _, err := player.FindPreset(prof.Name); err == nil || prof.Name == player.Custom || prof.Name == "" {
+			return fmt.Errorf("equalizer profile %q: the name is taken by a preset", prof.Name)
+		}
+		gains, err := player.ParseGains(prof.Gains)
+		if err != nil {
+			return fmt.Errorf("equalizer profile %q: %w", prof.Name, err)
+		}
+		profiles = append(profiles, player.Preset{Name: prof.Name, Gains: gains})
+	}
+
+	var custom *engine.Gains
+	if len(cfg.Custom) > 0 {
+		gains, err := player.ParseGains(cfg.Custom)
+		if err != nil {
+			return fmt.Errorf("custom equalizer: %w", err)
+		}
+		custom = &gains
+	}
+	ui.eq.SetProfiles(profiles, custom)
+
+	curve, err := player.FindPreset(cfg.Curve)
+	switch {
+	case err == nil:
+	case cfg.Curve == player.Custom && custom != nil:
+		curve = player.Preset{Name: player.Custom, Gains: *custom}
+	default:
+		i := slices.IndexFunc(profiles, func(p player.Preset) bool { return p.Name == cfg.Curve })
+		if i < 0 {
+			return err
+		}
+		curve = profiles[i]
+	}
+	ui.player.SetEqualizer(curve)
+
+	if math.Abs(cfg.Preamp) > engine.MaxPreamp {
+		return fmt.Errorf("equalizer preamp %g dB is not between %g and %g", cfg.Preamp, -engine.MaxPreamp, engine.MaxPreamp)
+	}
+	ui.player.SetPreamp(cfg.Preamp)
 	return nil
 }
 
+// parseTheme returns the theme of the config, a built-in one or a palette.
+func parseTheme(cfg config.Theme) (styles.Theme, error) {
+	var palettes []styles.Theme
+	for _, p := range cfg.Palettes {
+		if _, err := styles.FindTheme(p.Name); err == nil || p.Name == "" {
+			return styles.Theme{}, fmt.Errorf("theme palette %q: the name is taken by a theme", p.Name)
+		}
+		theme, err := parsePalette(p)
+		if err != nil {
+			return styles.Theme{}, fmt.Errorf("theme palette %q: %w", p.Name, err)
+		}
+		palettes = append(palettes, theme)
+	}
+
+	theme, err := styles.FindTheme(cfg.Name)
+	if err != nil {
+		i := slices.IndexFunc(palettes, func(t styles.Theme) bool { return t.Name == cfg.Name })
+		if i < 0 {
+			return styles.Theme{}, err
+		}
+		theme = palettes[i]
+	}
+	return theme, nil
+}
+
+// parsePalette converts a palette of the config into a theme, with the colors of the dark
+// theme where it has none.
+func parsePalette(p config.Palette) (styles.Theme, error) {
+	theme := styles.Themes[0]
+	theme.Name = p.Name
+	colors := []struct {
+		value string
+		color *lipgloss.Color
+	}{
+		{p.Primary, &theme.Primary},
+		{p.Secondary, &theme.Secondary},
+		{p.Contrast, &theme.Contrast},
+		{p.Problem, &theme.Problem},
+		{p.Grey, &theme.Grey},
+		{p.Highlight, &theme.Highlight},
+		{p.Progress, &theme.Progress},
+	}
+	for _, c := range colors {
+		if c.value == "" {
+			continue
+		}
+		color, err := styles.ParseColor(c.value)
+		if err != nil {
+			return theme, err
+		}
+		*c.color = color
+	}
+	return theme, nil
+}
+
+// changeEqualizer plays with the curve set in the pane, saving it in the config along with
+// the changed gains of the custom curve or the profile.
+func (ui *UI) changeEqualizer(curve player.Preset) {
+	ui.player.SetEqualizer(curve)
+	if ui.config == nil {
+		return
+	}
+
+	eq := &ui.config.Equalizer
+	eq.Curve = curve.Name
+	if curve.Name == player.Custom {
+		eq.Custom = curve.Gains[:]
+	}
+	for i := range eq.Profiles {
+		if eq.Profiles[i].Name == curve.Name {
+			eq.Profiles[i].Gains = curve.Gains[:]
+		}
+	}
+	ui.saveConfig()
+}
+
+// SetLibraryDirs sets the music directories scanned when the UI starts.
+func (ui *UI) SetLibraryDirs(dirs []string) {
+	if len(dirs) == 0 {
+		ui.scanner = nil
+		return
+	}
+	ui.scanner = library.NewScanner(dirs, 0)
+	ui.scanner.SetFilter(ui.filter)
+	ui.scanner.SetIdentifier(ui.identifier)
+	ui.scanner.SetAnalyze(ui.analyze)
+	ui.scanner.SetMeasureLoudness(ui.player.Normalize())
+}
+
+// SetIndex sets the persistent index used to load the library at startup and
+// to skip unchanged files while scanning.
+func (ui *UI) SetIndex(idx *library.Index) {
+	ui.index = idx
+	ui.panel.SetIndex(idx)
+}
+
+// play loads the audio file in the player and records it in the playback history.
+func (ui *UI) play(af audio.File) tea.Cmd {
+	outcome := library.PlaySkipped
+	if ui.player.Completed() {
+		outcome = library.PlayCompleted
+	}
+	ui.recordPlay(outcome)
+	ui.savePosition()
+	// Playing something else is not restoring the last session
+	ui.session = nil
+
+	ui.recorded = false
+	ui.failedInRow = 0
+	cmd := ui.player.Load(af)
+	if !ui.player.HasAudio() {
+		return cmd
+	}
+	ui.logPlay(af)
+	ui.showRating()
+	ui.showBookmarks()
+	ui.showAnalysis()
+	return tea.Batch(cmd, ui.lyrics.Load(af.Path()), ui.normalize(), ui.resumePosition())
+}
+
+// savePosition stores where the current track was left at, unless it was near its start
+// or its end.
+func (ui *UI) savePosition() {
+	if ui.index == nil || !ui.player.HasAudio() {
+		return
+	}
+
+	pos := ui.player.Elapsed()
+	if ui.player.Completed() || pos < library.ResumeMargin || ui.player.Duration()-pos < library.ResumeMargin {
+		pos = 0
+	}
+	if err := ui.index.SetPosition(ui.player.Audio().Path(), pos); err != nil {
+		ui.report("Cannot save the position", err)
+	}
+}
+
+// resumePosition resumes the current track from where it was left at in audiobook mode,
+// otherwise it offers to.
+func (ui *UI) resumePosition() tea.Cmd {
+	ui.resume = 0
+	if ui.index == nil || !ui.player.HasAudio() {
+		return nil
+	}
+	pos, err := ui.index.Position(ui.player.Audio().Path())
+	if err != nil || pos == 0 {
+		return nil
+	}
+
+	if ui.audiobook {
+		ui.status = "Resumed at " + player.FormatSecondsToString(pos)
+		return ui.player.SeekTo(pos)
+	}
+	ui.resume = pos
+	return nil
+}
+
+// Session returns what is being played and shown, for the next run to restore it. There is
+// none while the queue is empty.
+func (ui *UI) Session() (state.Session, bool) {
+	if ui.queue.Len() == 0 {
+		return state.Session{}, false
+	}
+
+	s := state.Session{
+		Dir:     ui.panel.Dir(),
+		View:    int(ui.panel.ViewMode()),
+		Focused: ui.panel.Focused(),
+	}
+	for _, item := range ui.queue.Items() {
+		s.Queue = append(s.Queue, item.Audio.Path())
+	}
+	if af, ok := ui.queue.Current(); ok {
+		s.Current = af.Path()
+	}
+	// A finished track is restored from its start
+	if ui.player.HasAudio() && !ui.player.Completed() {
+		s.Position = ui.player.Elapsed()
+	}
+
+	switch {
+	case ui.lyrics.Visible():
+		s.Pane = "lyrics"
+	case ui.chapters.Visible():
+		s.Pane = "chapters"
+	case ui.devices.Visible():
+		s.Pane = "devices"
+	case ui.eq.Visible():
+		s.Pane = "equalizer"
+	}
+	return s, true
+}
+
+// OfferSession offers to restore the session of the last run, when there is nothing else
+// to play.
+func (ui *UI) OfferSession(s state.Session) {
+	ui.session = &s
+}
+
+// restoreSession enqueues the files of the offered session, plays the current one from where
+// it was left at and shows what was shown. The files that no longer exist are skipped.
+func (ui *UI) restoreSession() tea.Cmd {
+	s := *ui.session
+	ui.session = nil
+
+	missing := 0
+	for _, path := range s.Queue {
+		if _, err := os.Stat(path); err != nil && !engine.IsURL(path) {
+			missing++
+			continue
+		}
+		if _, err := ui.queue.AddPath(path); err != nil {
+			missing++
+		}
+	}
+	if ui.queue.Len() == 0 {
+		ui.status = "The files of the last session no longer exist"
+		return nil
+	}
+	ui.status = fmt.Sprintf("Restored the %d files of the last session", ui.queue.Len())
+	if missing > 0 {
+		ui.status += fmt.Sprintf(" (%d missing)", missing)
+	}
+
+	if info, err := os.Stat(s.Dir); err == nil && info.IsDir() {
+		ui.panel.Open(s.Dir)
+	}
+	ui.panel.SetViewMode(panel.ViewMode(s.View))
+	if s.Focused {
+		ui.panel.Focus()
+	} else {
+		ui.panel.Blur()
+	}
+
+	// The first file plays if the current one is missing
+	af, _ := ui.queue.Current()
+	pos := time.Duration(0)
+	for _, item := range ui.queue.Items() {
+		if item.Audio.Path() == s.Current {
+			af, pos = item.Audio, s.Position
+			break
+		}
+	}
+	cmds := []tea.Cmd{ui.play(ui.queue.Play(af))}
+	if pos > 0 {
+		ui.resume = 0
+		cmds = append(cmds, ui.player.SeekTo(pos))
+	}
+
+	switch s.Pane {
+	case "lyrics":
+		cmds = append(cmds, ui.lyrics.Toggle())
+		ui.panel.Blur()
+	case "chapters":
+		ui.chapters.Toggle()
+		ui.panel.Blur()
+	case "devices":
+		ui.devices.Toggle()
+		ui.panel.Blur()
+	case "equalizer":
+		ui.eq.Toggle()
+		ui.panel.Blur()
+	}
+	return tea.Batch(cmds...)
+}
+
+// report shows the error of an action in the status line and logs it, since the status line
+// is replaced by the next message or gone when tempo quits.
+func (ui *UI) report(action string, err error) {
+	ui.status = action + ": " + err.Error()
+	slog.Warn(action, "err", err)
+}
+
+// skipFailed reports the track that could not be played, marking it in the queue, and
+// plays the next one. It stops once every track of the queue failed in a row, which with
+// repeat on would skip them forever.
+func (ui *UI) skipFailed(msg player.FailedMsg) tea.Cmd {
+	slog.Error("Cannot play", "path", msg.Path, "err", msg.Err)
+	ui.failure = fmt.Sprintf("Cannot play %s: %s", filepath.Base(msg.Path), msg.Err)
+	ui.queue.SetFailed(msg.Path, msg.Err)
+
+	failed := ui.failedInRow + 1
+	if failed >= ui.queue.Len() {
+		if failed > 1 {
+			ui.failure = fmt.Sprintf("Cannot play any of the %d tracks of the queue (last error: %s)", failed, msg.Err)
+		}
+		ui.failedInRow = 0
+		return nil
+	}
+	af, ok := ui.queue.Next()
+	if !ok {
+		return nil
+	}
+	cmd := ui.play(af)
+	ui.failedInRow = failed
+	return cmd
+}
+
+// rate changes the rating of the current track with the given function.
+func (ui *UI) rate(change func(r *library.Rating)) {
+	if ui.index == nil || !ui.player.HasAudio() {
+		return
+	}
+
+	path := ui.player.Audio().Path()
+	r, err := ui.index.Rating(path)
+	if err == nil {
+		change(&r)
+		err = ui.index.SetRating(path, r)
+	}
+	if err != nil {
+		ui.report("Cannot save the rating", err)
+		return
+	}
+
+	ui.player.SetRating(r.Stars, r.Favorite)
+	ui.panel.RatingsChanged()
+}
+
+// showRating loads the rating of the current track into the player.
+func (ui *UI) showRating() {
+	if ui.index == nil || !ui.player.HasAudio() {
+		return
+	}
+	if r, err := ui.index.Rating(ui.player.Audio().Path()); err == nil {
+		ui.player.SetRating(r.Stars, r.Favorite)
+	}
+}
+
+// showBookmarks loads the bookmarks of the current track into the player.
+func (ui *UI) showBookmarks() {
+	if ui.index == nil || !ui.player.HasAudio() {
+		return
+	}
+	if bookmarks, err := ui.index.Bookmarks(ui.player.Audio().Path()); err == nil {
+		ui.player.SetBookmarks(bookmarks)
+	}
+}
+
+// saveBookmarks stores the bookmarks of a track after they changed.
+func (ui *UI) saveBookmarks(msg player.BookmarksMsg) {
+	if ui.index == nil {
+		ui.status = "Cannot save the bookmarks without the library index"
+		return
+	}
+	if err := ui.index.SetBookmarks(msg.Path, msg.Bookmarks); err != nil {
+		ui.report("Cannot save the bookmarks", err)
+	}
+}
+
+// showAnalysis shows the tempo and key of the current track in the player, if the
+// library has them.
+func (ui *UI) showAnalysis() {
+	if !ui.player.HasAudio() {
+		return
+	}
+	path, err := filepath.Abs(ui.player.Audio().Path())
+	if err != nil {
+		return
+	}
+	if t, ok := ui.library.Get(path); ok {
+		ui.player.SetAnalysis(t.BPM, t.Key, t.Camelot())
+	}
+}
+
+// analyzedMsg carries the tempo and key estimated for the audio file at path.
+type analyzedMsg struct {
+	path   string
+	result analysis.Result
+	err    error
+}
+
+// analyzeTrack estimates the tempo and key of the current track in background.
+func (ui *UI) analyzeTrack() tea.Cmd {
+	// A radio plays a different track by the time it is analyzed
+	if !ui.player.HasAudio() || ui.player.Live() {
+		return nil
+	}
+	path, err := filepath.Abs(ui.player.Audio().Path())
+	if err != nil {
+		ui.report("Cannot analyze the track", err)
+		return nil
+	}
+
+	ui.status = "Analyzing " + filepath.Base(path) + "..."
+	return func() tea.Msg {
+		res, err := analysis.Analyze(path)
+		return analyzedMsg{path: path, result: res, err: err}
+	}
+}
+
+// storeAnalysis shows the tempo and key of an analyzed track, storing them in the
+// library and the index.
+func (ui *UI) storeAnalysis(msg analyzedMsg) {
+	if msg.err != nil {
+		ui.report("Cannot analyze "+filepath.Base(msg.path), msg.err)
+		return
+	}
+	bpm, key := msg.result.BPM, msg.result.Key
+	ui.status = fmt.Sprintf("%s: %.0f BPM, %s (%s)", filepath.Base(msg.path), bpm, key, key.Camelot())
+
+	if current, err := filepath.Abs(ui.player.Audio().Path()); err == nil && ui.player.HasAudio() && current == msg.path {
+		ui.player.SetAnalysis(bpm, key.String(), key.Camelot())
+	}
+
+	track, ok := ui.library.Get(msg.path)
+	if !ok {
+		return
+	}
+	track.BPM, track.Key = bpm, key.String()
+	ui.library.Put(track)
+	ui.panel.LibraryChanged()
+	if ui.index != nil {
+		if err := ui.index.Put(track); err != nil {
+			ui.report("Cannot update the library index", err)
+		}
+	}
+}
+
+// measuredMsg carries the loudness measured for the audio file at path, which is absolute
+// in abs.
+type measuredMsg struct {
+	path   string
+	abs    string
+	result loudness.Result
+	err    error
+}
+
+// normalize gives the player the loudness of the current track when it has to normalize
+// it, from the library or measured in background.
+func (ui *UI) normalize() tea.Cmd {
+	if !ui.player.NeedsLoudness() {
+		return nil
+	}
+	path := ui.player.Audio().Path()
+	// The files served over HTTP would be downloaded whole to be measured
+	if engine.IsURL(path) {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	if t, ok := ui.library.Get(abs); ok {
+		if res, ok := t.LoudnessResult(); ok {
+			ui.player.SetLoudness(path, res)
+			return nil
+		}
+	}
+
+	return func() tea.Msg {
+		res, err := loudness.Measure(abs)
+		return measuredMsg{path: path, abs: abs, result: res, err: err}
+	}
+}
+
+// storeLoudness normalizes the measured track if it is still playing, storing its loudness
+// in the library and the index.
+func (ui *UI) storeLoudness(msg measuredMsg) {
+	if msg.err != nil {
+		ui.report("Cannot measure the loudness of "+filepath.Base(msg.path), msg.err)
+		return
+	}
+	ui.player.SetLoudness(msg.path, msg.result)
+
+	track, ok := ui.library.Get(msg.abs)
+	if !ok {
+		return
+	}
+	track.SetLoudness(msg.result)
+	ui.library.Put(track)
+	if ui.index != nil {
+		if err := ui.index.Put(track); err != nil {
+			ui.report("Cannot update the library index", err)
+		}
+	}
+}
+
+// recordPlay saves the statistics of the current track once.
+func (ui *UI) recordPlay(outcome library.PlayOutcome) {
+	if ui.index == nil || ui.recorded || !ui.player.HasAudio() {
+		return
+	}
+	ui.recorded = true
+
+	err := ui.index.RecordPlay(ui.player.Audio().Path(), outcome, ui.player.Elapsed())
+	if err != nil {
+		ui.report("Cannot save the play statistics", err)
+		return
+	}
+	ui.panel.PlaysChanged()
+}
+
+func (ui *UI) logPlay(af audio.File) {
+	if ui.index == nil {
+		return
+	}
+	if err := ui.index.LogPlay(library.PlayEvent{Path: af.Path(), At: time.Now()}); err != nil {
+		ui.report("Cannot save the playback history", err)
+		return
+	}
+	ui.panel.PlaysChanged()
+}
+
+// copyPath copies the absolute path of the current track to the clipboard.
+func (ui *UI) copyPath() {
+	if !ui.player.HasAudio() {
+		return
+	}
+	path, err := filepath.Abs(ui.player.Audio().Path())
+	if err == nil {
+		err = desktop.CopyText(path)
+	}
+	if err != nil {
+		ui.report("Cannot copy the path", err)
+		return
+	}
+	ui.status = "Copied " + path
+}
+
+// reveal opens the directory of the current track with the file manager.
+func (ui *UI) reveal() {
+	if !ui.player.HasAudio() {
+		return
+	}
+	if err := desktop.Reveal(ui.player.Audio().Path()); err != nil {
+		ui.report("Cannot open the file manager", err)
+	}
+}
+
+// editTags opens the tag editor with the current track.
+func (ui *UI) editTags() tea.Cmd {
+	if !ui.player.HasAudio() {
+		return nil
+	}
+	path, err := filepath.Abs(ui.player.Audio().Path())
+	if err != nil {
+		ui.report("Cannot edit the tags", err)
+		return nil
+	}
+	if !tags.CanWrite(path) {
+		ui.status = fmt.Sprintf("Cannot edit the tags of %s files", ui.player.Audio().Ext())
+		return nil
+	}
+	return ui.editor.Open(path)
+}
+
+// retag shows the edited tags of a file in the player, the queue and the library, and
+// stores them in the index. The panel is not refreshed.
+func (ui *UI) retag(path string, t tags.Tags) {
+	if current, err := filepath.Abs(ui.player.Audio().Path()); err == nil && ui.player.HasAudio() && current == path {
+		ui.player.SetTags(t)
+	}
+	ui.queue.SetTags(path, t)
+	// Once the library has the new tags
+	defer ui.panel.TagsChanged(path)
+
+	old, ok := ui.library.Get(path)
+	if !ok {
+		return
+	}
+	track, err := library.ScanFile(path)
+	if err != nil {
+		ui.report("Cannot read the edited file", err)
+		return
+	}
+	track.AddedAt = old.AddedAt
+	track.BPM, track.Key = old.BPM, old.Key
+	ui.library.Put(track)
+	if ui.index != nil {
+		if err := ui.index.Put(track); err != nil {
+			ui.report("Cannot update the library index", err)
+		}
+	}
+}
+
+// moveFile updates the library, the index, the queue and the playing file after a file or directory is moved.
+func (ui *UI) moveFile(from, to string) {
+	if ui.index != nil {
+		if err := ui.index.Move(from, to); err != nil {
+			ui.report("Cannot update the library index", err)
+		}
+	}
+	ui.library.Move(from, to)
+	ui.queue.Move(from, to)
+	ui.player.Move(from, to)
+	ui.panel.LibraryChanged()
+}
+
+// forgetFile removes a deleted file, or every file inside a deleted directory, from the
+// library, the index and the queue.
+func (ui *UI) forgetFile(path string, isDir bool) {
+	paths := []string{path}
+	if isDir {
+		ui.library.RemoveDir(path)
+		if ui.index != nil {
+			inside, err := ui.index.Paths(path)
+			if err != nil {
+				ui.report("Cannot update the library index", err)
+			}
+			paths = inside
+		}
+	}
+	ui.library.Remove(paths...)
+	if ui.index != nil {
+		if err := ui.index.Delete(paths...); err != nil {
+			ui.report("Cannot update the library index", err)
+		}
+	}
+	ui.queue.Remove(path)
+	ui.panel.LibraryChanged()
+}
+
+func (ui *UI) Init() tea.Cmd {
+	if af, ok := ui.queue.Current(); ok {
+		ui.player.SetAudioFile(af)
+	}
+	initCmd := ui.player.Init()
+	if ui.player.HasAudio() {
+		ui.logPlay(ui.player.Audio())
+	}
+	ui.showRating()
+	ui.showBookmarks()
+	var resumeCmd tea.Cmd
+	if ui.startAt > 0 && ui.player.HasAudio() {
+		resumeCmd = ui.player.SeekTo(ui.startAt)
+	} else {
+		resumeCmd = ui.resumePosition()
+	}
+	ui.panel.Init()
+
+	// Without anything to play the user starts browsing files
+	if !ui.player.HasAudio() {
+		ui.panel.Focus()
+	}
+
+	if ui.index != nil {
+		tracks, err := ui.index.All()
+		if err != nil {
+			ui.report("Library index error", err)
+		}
+		ui.library.Put(tracks...)
+	}
+	ui.showAnalysis()
+
+	cmds := []tea.Cmd{initCmd, resumeCmd, ui.Scan(), ui.player.LoadCover(), ui.panel.Probe()}
+	if ui.player.HasAudio() {
+		cmds = append(cmds, ui.lyrics.Load(ui.player.Audio().Path()))
+	}
+	cmds = append(cmds, ui.watchLibrary())
+	// Without a config directory there is no file to reload
+	if ui.config != nil && ui.config.FilePath() != "" {
+		if w, err := config.NewWatcher(ui.config.FilePath()); err == nil {
+			ui.configWatcher = w
+			cmds = append(cmds, w.Start())
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// watchLibrary starts watching the library directories for changes, if there are any.
+func (ui *UI) watchLibrary() tea.Cmd {
+	if ui.scanner == nil {
+		return nil
+	}
+	w, warnings, err := library.NewWatcher(ui.scanner.Dirs(), ui.index, ui.filter)
+	if err != nil {
+		ui.report("Cannot watch the library", err)
+		return nil
+	}
+	// The other directories are still watched
+	for _, err := range warnings {
+		ui.report("Cannot watch the library", err)
+	}
+	ui.watcher = w
+	return ui.libraryCmd(w.Start())
+}
+
+// reloadConfig applies the theme, the keys and the library directories of the config file
+// changed on disk. The other settings are kept, to be saved from the UI as they are in the
+// file, and applied by the next run.
+func (ui *UI) reloadConfig(msg config.ChangedMsg) tea.Cmd {
+	if msg.Err != nil {
+		ui.report("Cannot reload the config", msg.Err)
+		return nil
+	}
+	// Saving the config from the UI changes the file too
+	if ui.config == nil || msg.Config.Equal(ui.config) {
+		return nil
+	}
+
+	// Nothing is applied unless everything can be
+	keys, err := player.NewKeyMap(msg.Config.Keys)
+	if err != nil {
+		ui.report("Cannot reload the config", err)
+		return nil
+	}
+	theme, err := parseTheme(msg.Config.Theme)
+	if err != nil {
+		ui.report("Cannot reload the config", err)
+		return nil
+	}
+	styles.SetTheme(theme)
+	ui.player.SetKeys(keys)
+	ui.panel.SetKeys(keys)
+
+	old := ui.config
+	ui.config = msg.Config
+	ui.status = "Reloaded the config"
+	if slices.Equal(old.Library.Dirs, msg.Config.Library.Dirs) {
+		return nil
+	}
+
+	var dirs []string
+	for _, dir := range msg.Config.Library.Dirs {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			dirs = append(dirs, xdg.ExpandHome(dir))
+		}
+	}
+	// The scan and the watcher of the old directories stop, and their last messages are dropped
+	if ui.scanner != nil {
+		ui.scanner.Cancel()
+		ui.scanning = false
+	}
+	if ui.watcher != nil {
+		ui.watcher.Close()
+		ui.watcher = nil
+	}
+	ui.libraryGen++
+	ui.SetLibraryDirs(dirs)
+	cmd := tea.Batch(ui.Scan(), ui.watchLibrary())
+	if ui.scanner != nil {
+		ui.status = "Reloaded the config, scanning the new library directories..."
+	}
+	return cmd
+}
+
+// Close releases the resources used by the UI once the program ends.
+func (ui *UI) Close() {
+	ui.recordPlay(library.PlayStopped)
+	ui.savePosition()
+	if ui.watcher != nil {
+		ui.watcher.Close()
+	}
+	if ui.configWatcher != nil {
+		ui.configWatcher.Close()
+	}
+	for _, r := range ui.remotes {
+		r.Close()
+	}
+}
+
+// Scan starts scanning the library directories in background.
+func (ui *UI) Scan() tea.Cmd {
+	if ui.scanner == nil || ui.scanning {
+		return nil
+	}
+	ui.scanning = true
+	ui.status = "Scanning library..."
+	ui.scanner.SetIndex(ui.index)
+	return ui.libraryCmd(ui.scanner.Start())
+}
+
+// libraryMsg : A message of the scanner or the watcher of the library directories, with the
+// generation of the directories they were started for
+type libraryMsg struct {
+	gen int
+	msg tea.Msg
+}
+
+// libraryCmd tags the message of cmd, a command of the scanner or the watcher, with the
+// current generation of the library directories.
+func (ui *UI) libraryCmd(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	gen := ui.libraryGen
+	return func() tea.Msg {
+		msg := cmd()
+		if msg == nil {
+			return nil
+		}
+		return libraryMsg{gen: gen, msg: msg}
+	}
+}
+
 func (ui *UI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := ui.update(msg)
+	ui.updateRemotes()
+	// Whatever the message was, the panel may have loaded files it has to probe
+	return model, tea.Batch(cmd, ui.panel.Probe())
+}
+
+func (ui *UI) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if ui.Error() != nil {
 		return ui, tea.Quit
 	}
@@ -39,7 +1315,401 @@ func (ui *UI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		ui.width = msg.Width
 		ui.height = msg.Height
+		ui.lyrics.SetWidth(msg.Width)
+		ui.chapters.SetWidth(msg.Width)
+		ui.devices.SetWidth(msg.Width)
 		return ui, tea.ClearScreen
+
+	case config.ChangedMsg:
+		return ui, tea.Batch(ui.reloadConfig(msg), ui.configWatcher.Wait())
+
+	case remote.CommandMsg, remote.SeekMsg, remote.SetPositionMsg, remote.SetVolumeMsg,
+		remote.SetSpeedMsg, remote.SetRepeatMsg, remote.OpenMsg, remote.EnqueueMsg,
+		remote.PlayIndexMsg, remote.RemoveMsg, remote.ClearMsg:
+		// What the user does from elsewhere is not undone on focus
+		ui.blurPaused = ""
+		return ui, ui.updateRemote(msg)
+
+	case tea.BlurMsg:
+		if !ui.pauseOnBlur || !ui.player.Playing() {
+			return ui, nil
+		}
+		ui.blurPaused = ui.player.Audio().Path()
+		return ui, ui.player.StopOrResume()
+
+	case tea.FocusMsg:
+		path := ui.blurPaused
+		ui.blurPaused = ""
+		if path == "" || !ui.player.HasAudio() || ui.player.Audio().Path() != path ||
+			ui.player.Playing() || ui.player.Completed() {
+			return ui, nil
+		}
+		return ui, ui.player.StopOrResume()
+
+	case lyricspane.LoadedMsg:
+		_, cmd := ui.lyrics.Update(msg)
+		return ui, cmd
+
+	case analyzedMsg:
+		ui.storeAnalysis(msg)
+		return ui, nil
+
+	case measuredMsg:
+		ui.storeLoudness(msg)
+		return ui, nil
+
+	case eqpane.ChangedMsg:
+		ui.changeEqualizer(msg.Curve)
+		return ui, nil
+
+	case eqpane.PreampMsg:
+		ui.player.SetPreamp(msg.DB)
+		if ui.config != nil {
+			ui.config.Equalizer.Preamp = msg.DB
+			ui.saveConfig()
+		}
+		return ui, nil
+
+	case player.BookmarksMsg:
+		ui.saveBookmarks(msg)
+		return ui, nil
+
+	case player.RecordingMsg:
+		switch {
+		case msg.Err != nil && msg.Started:
+			ui.report("Cannot record", msg.Err)
+		case msg.Err != nil:
+			ui.report("Cannot finish the recording "+msg.Recording.Path, msg.Err)
+		case msg.Started:
+			ui.status = "Recording to " + msg.Recording.Path
+		default:
+			ui.status = fmt.Sprintf("Recorded %s to %s", player.FormatSecondsToString(msg.Recording.Length), msg.Recording.Path)
+		}
+		return ui, nil
+
+	case chapterpane.SeekMsg:
+		return ui, ui.player.SeekTo(msg.Start)
+
+	case devicepane.SelectMsg:
+		ui.selectDevice(msg.Device)
+		return ui, nil
+
+	case player.FailedMsg:
+		return ui, ui.skipFailed(msg)
+
+	case player.CompletedMsg:
+		ui.recordPlay(library.PlayCompleted)
+		ui.savePosition()
+		if af, ok := ui.queue.Next(); ok {
+			return ui, ui.play(af)
+		}
+		return ui, nil
+
+	case libraryMsg:
+		// The scanner or the watcher was replaced by the ones of other directories
+		if msg.gen != ui.libraryGen {
+			return ui, nil
+		}
+		return ui.update(msg.msg)
+
+	case library.ProgressMsg:
+		ui.status = fmt.Sprintf("Scanning library... %d/%d files", msg.Scanned, msg.Found)
+		return ui, ui.libraryCmd(ui.scanner.Wait())
+
+	case library.DoneMsg:
+		ui.scanning = false
+		ui.library.Remove(msg.Removed...)
+		ui.library.Put(msg.Tracks...)
+		ui.panel.LibraryChanged()
+		ui.showAnalysis()
+		ui.status = fmt.Sprintf("Library: %d tracks (%d updated, %d removed) scanned in %s",
+			len(msg.Tracks), msg.Updated, len(msg.Removed), msg.Elapsed.Round(time.Millisecond))
+		if len(msg.Errors) > 0 {
+			ui.status += fmt.Sprintf(" (%d files failed)", len(msg.Errors))
+		}
+		for _, err := range msg.Errors {
+			slog.Warn("Cannot scan a file", "err", err)
+		}
+		return ui, nil
+
+	case library.ChangedMsg:
+		for _, err := range msg.Errors {
+			slog.Warn("Cannot update the library after a change", "err", err)
+		}
+		for _, dir := range msg.RemovedDirs {
+			ui.library.RemoveDir(dir)
+		}
+		ui.library.Remove(msg.Removed...)
+		ui.library.Put(msg.Updated...)
+		ui.panel.LibraryChanged()
+
+		for _, dir := range msg.Dirs() {
+			if dir == ui.panel.Dir() {
+				ui.panel.Refresh()
+				break
+			}
+		}
+
+		if len(msg.Updated)+len(msg.Removed) > 0 {
+			ui.status = fmt.Sprintf("Library changed: %d updated, %d removed (%d tracks)",
+				len(msg.Updated), len(msg.Removed), ui.library.Len())
+		}
+		return ui, ui.libraryCmd(ui.watcher.Wait())
+
+	case editor.SavedMsg:
+		ui.editor.Update(msg)
+		if msg.Err != nil {
+			return ui, nil
+		}
+		ui.status = "Saved the tags of " + msg.Path
+		ui.retag(msg.Path, msg.Tags)
+		ui.panel.LibraryChanged()
+		return ui, nil
+
+	case editor.ProgressMsg:
+		if msg.Err == nil {
+			ui.retag(msg.Path, msg.Tags)
+		}
+		_, cmd := ui.editor.Update(msg)
+		return ui, cmd
+
+	case editor.LookupMsg:
+		_, cmd := ui.editor.Update(msg)
+		return ui, cmd
+
+	case editor.DoneMsg:
+		ui.editor.Update(msg)
+		ui.panel.LibraryChanged()
+		ui.status = fmt.Sprintf("Saved the tags of %d files", msg.Saved)
+		if len(msg.Errors) > 0 {
+			ui.status += fmt.Sprintf(" (%d failed, %s)", len(msg.Errors), msg.Errors[0])
+		}
+		for _, err := range msg.Errors {
+			slog.Warn("Cannot save the tags", "err", err)
+		}
+		return ui, nil
+
+	case panel.EditTagsMsg:
+		return ui, ui.editor.OpenBatch(msg.Paths)
+
+	case panel.PlaylistSavedMsg:
+		ui.status = fmt.Sprintf("Added %d files to %s", msg.Count, msg.Path)
+		return ui, nil
+
+	case panel.FileMovedMsg:
+		ui.status = fmt.Sprintf("Moved %s to %s", msg.From, msg.To)
+		ui.moveFile(msg.From, msg.To)
+		return ui, nil
+
+	case panel.FileTrashedMsg:
+		ui.status = fmt.Sprintf("Moved %s to the trash", msg.Path)
+		ui.forgetFile(msg.Path, msg.IsDir)
+		return ui, nil
+
+	case panel.BookmarksMsg:
+		if ui.config != nil {
+			ui.config.Browser.Bookmarks = msg.Dirs
+			ui.saveConfig()
+		}
+		return ui, nil
+
+	case panel.StationsMsg:
+		if ui.config != nil {
+			ui.config.Stations = nil
+			for _, s := range msg.Stations {
+				ui.config.Stations = append(ui.config.Stations, config.Station{Name: s.Name, URL: s.URL, Genres: s.Genres})
+			}
+			ui.saveConfig()
+		}
+		return ui, nil
+
+	case panel.SortMsg:
+		if ui.config != nil {
+			ui.config.Browser.Sort = msg.Order.String()
+			ui.config.Browser.SortReverse = msg.Reverse
+			ui.saveConfig()
+		}
+		return ui, nil
+
+	case panel.ProbedMsg:
+		ui.panel.Probed(msg)
+		return ui, nil
+
+	case panel.PlayMsg:
+		return ui, ui.play(ui.queue.Play(msg.Audio))
+
+	case panel.EnqueueMsg:
+		ui.queue.Add(msg.Files...)
+		if !ui.player.HasAudio() {
+			if af, ok := ui.queue.Current(); ok {
+				return ui, ui.play(af)
+			}
+		}
+		return ui, nil
+
+	case tea.KeyMsg:
+		if ui.editor.Active() && msg.String() != "ctrl+c" {
+			_, cmd := ui.editor.Update(msg)
+			return ui, cmd
+		}
+		if ui.player.Prompting() {
+			_, cmd := ui.player.Update(msg)
+			return ui, cmd
+		}
+		if ui.panel.Focused() && ui.panel.Captures(msg) {
+			_, cmd := ui.panel.Update(msg)
+			return ui, cmd
+		}
+		if ui.lyrics.Captures(msg) {
+			_, cmd := ui.lyrics.Update(msg)
+			return ui, cmd
+		}
+		if ui.chapters.Captures(msg) {
+			_, cmd := ui.chapters.Update(msg)
+			return ui, cmd
+		}
+		if ui.devices.Captures(msg) {
+			_, cmd := ui.devices.Update(msg)
+			return ui, cmd
+		}
+		if ui.eq.Captures(msg) {
+			_, cmd := ui.eq.Update(msg)
+			return ui, cmd
+		}
+
+		km := ui.player.Keys()
+		switch {
+		case key.Matches(msg, km.Focus):
+			// The browser takes the place of the lyrics, the chapters, the devices and the
+			// equalizer
+			if ui.lyrics.Visible() || ui.chapters.Visible() || ui.devices.Visible() || ui.eq.Visible() {
+				ui.lyrics.Hide()
+				ui.chapters.Hide()
+				ui.devices.Hide()
+				ui.eq.Hide()
+				ui.panel.Focus()
+				return ui, nil
+			}
+			if ui.panel.Focused() {
+				ui.panel.Blur()
+			} else {
+				ui.panel.Focus()
+			}
+			return ui, nil
+
+		case key.Matches(msg, km.Scan):
+			return ui, ui.Scan()
+
+		case key.Matches(msg, km.Back):
+			if ui.failure != "" {
+				ui.failure = ""
+				return ui, nil
+			}
+			if ui.resume > 0 {
+				ui.resume = 0
+				return ui, nil
+			}
+			if ui.session != nil {
+				ui.session = nil
+				return ui, nil
+			}
+
+		case key.Matches(msg, km.Next):
+			if af, ok := ui.queue.Next(); ok {
+				return ui, ui.play(af)
+			}
+			return ui, nil
+
+		case key.Matches(msg, km.Previous):
+			if af, ok := ui.queue.Previous(); ok {
+				return ui, ui.play(af)
+			}
+			return ui, nil
+
+		case key.Matches(msg, km.Rate[:]...):
+			stars := player.MatchIndex(msg, km.Rate[:])
+			ui.rate(func(r *library.Rating) { r.Stars = stars })
+			return ui, nil
+
+		case key.Matches(msg, km.Favorite):
+			ui.rate(func(r *library.Rating) { r.Favorite = !r.Favorite })
+			return ui, nil
+
+		case key.Matches(msg, km.CopyPath):
+			ui.copyPath()
+			return ui, nil
+
+		case key.Matches(msg, km.Reveal):
+			ui.reveal()
+			return ui, nil
+
+		case key.Matches(msg, km.EditTags):
+			return ui, ui.editTags()
+
+		case key.Matches(msg, km.ReplayGain):
+			return ui, ui.cycleGain()
+
+		case key.Matches(msg, km.Silence):
+			ui.cycleSilence()
+			return ui, nil
+
+		case key.Matches(msg, km.Analyze):
+			return ui, ui.analyzeTrack()
+
+		case key.Matches(msg, km.Record):
+			return ui, ui.player.ToggleRecording()
+
+		case key.Matches(msg, km.Resume):
+			if ui.resume > 0 {
+				pos := ui.resume
+				ui.resume = 0
+				return ui, ui.player.SeekTo(pos)
+			}
+			if ui.session != nil {
+				return ui, ui.restoreSession()
+			}
+			return ui, nil
+
+		case key.Matches(msg, km.Lyrics):
+			cmd := ui.lyrics.Toggle()
+			if ui.lyrics.Visible() {
+				ui.chapters.Hide()
+				ui.devices.Hide()
+				ui.eq.Hide()
+				ui.panel.Blur()
+			}
+			return ui, cmd
+
+		case key.Matches(msg, km.Chapters):
+			ui.chapters.Toggle()
+			if ui.chapters.Visible() {
+				ui.lyrics.Hide()
+				ui.devices.Hide()
+				ui.eq.Hide()
+				ui.panel.Blur()
+			}
+			return ui, nil
+
+		case key.Matches(msg, km.Devices):
+			ui.devices.Toggle()
+			if ui.devices.Visible() {
+				ui.lyrics.Hide()
+				ui.chapters.Hide()
+				ui.eq.Hide()
+				ui.panel.Blur()
+			}
+			return ui, nil
+
+		case key.Matches(msg, km.Equalizer):
+			ui.eq.Toggle()
+			if ui.eq.Visible() {
+				ui.lyrics.Hide()
+				ui.chapters.Hide()
+				ui.devices.Hide()
+				ui.panel.Blur()
+			}
+			return ui, nil
+		}
 	}
 
 	_, cmd := ui.player.Update(msg)
@@ -56,7 +1726,44 @@ func (ui *UI) View() string {
 	}
 
 	var xs string
+	if ui.editor.Active() {
+		xs += ui.editor.View() + "\n"
+	} else if ui.lyrics.Visible() {
+		xs += ui.lyrics.View() + "\n"
+	} else if ui.chapters.Visible() {
+		xs += ui.chapters.View() + "\n"
+	} else if ui.devices.Visible() {
+		xs += ui.devices.View() + "\n"
+	} else if ui.eq.Visible() {
+		xs += ui.eq.View() + "\n"
+	} else {
+		xs += ui.panel.View() + "\n"
+	}
+	if ui.status != "" {
+		xs += styles.Help(ui.status) + "\n"
+	}
+	km := ui.player.Keys()
+	dismiss := ""
+	if km.Back.Enabled() {
+		dismiss = " (" + km.Back.Help().Key + " to dismiss)"
+	}
+	if ui.resume > 0 {
+		xs += styles.Help("Left at "+player.FormatSecondsToString(ui.resume)+", "+km.Resume.Help().Key+" resumes from there"+dismiss) + "\n"
+	}
+	if ui.session != nil {
+		files := fmt.Sprintf("%d files", len(ui.session.Queue))
+		if len(ui.session.Queue) == 1 {
+			files = "1 file"
+		}
+		xs += styles.Help("Last session: "+files+", "+km.Resume.Help().Key+" restores it"+dismiss) + "\n"
+	}
+	if ui.failure != "" {
+		xs += styles.Banner(ui.failure+dismiss) + "\n"
+	}
 	xs += ui.player.View()
+	if ui.queue.Len() > 1 {
+		xs += "\n" + ui.queue.View()
+	}
 
 	return xs
 }
@@ -71,3 +1778,15 @@ func (ui *UI) Error() error {
 func (ui *UI) Player() *player.Player {
 	return ui.player
 }
+
+func (ui *UI) Queue() *queue.Queue {
+	return ui.queue
+}
+
+func (ui *UI) Panel() *panel.Panel {
+	return ui.panel
+}
+
+func (ui *UI) Library() *library.Library {
+	return ui.library
+}
@@ -0,0 +1,502 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/nicolito128/tempo/internal/xdg"
+)
+
+// File name of the configuration inside the config directory
+const FileName string = "config.toml"
+
+// Config : User settings stored in a TOML file
+type Config struct {
+	// Path of the file the config was loaded from
+	path string
+	// Settings replaced by environment variables, which are not saved to the file
+	overrides []override
+	// Keys of the file that are not settings, like misspelled ones
+	unknown []string
+	// Error reading the file, which is then never saved over
+	loadErr error
+
+	Browser Browser `toml:"browser"`
+	Library Library `toml:"library"`
+	Player  Player  `toml:"player"`
+	Theme   Theme   `toml:"theme"`
+
+	Equalizer Equalizer `toml:"equalizer"`
+
+	Lyrics   Lyrics   `toml:"lyrics"`
+	AcoustID AcoustID `toml:"acoustid"`
+
+	Remote  Remote  `toml:"remote"`
+	Discord Discord `toml:"discord"`
+	Hooks   Hooks   `toml:"hooks"`
+
+	NowPlaying NowPlaying `toml:"now_playing"`
+	Snapcast   Snapcast   `toml:"snapcast"`
+
+	// Keys of the actions replacing the default ones, like rewind = ["left", "a"]
+	Keys map[string][]string `toml:"keys"`
+
+	SmartPlaylists []SmartPlaylist `toml:"smart_playlist"`
+	Stations       []Station       `toml:"station"`
+}
+
+// Browser : Settings of the file browser panel
+type Browser struct {
+	// Sort order of the files: name, mtime, duration, track, plays or rating
+	Sort string `toml:"sort"`
+	// SortReverse if the sort order is reversed
+	SortReverse bool `toml:"sort_reverse"`
+	// Bookmarks are directories to jump to, ~ is the home directory
+	Bookmarks []string `toml:"bookmarks"`
+}
+
+// Library : Settings of the library views
+type Library struct {
+	// Dirs are the music directories scanned when tempo starts, ~ is the home directory
+	Dirs []string `toml:"dirs"`
+	// RecentDays how many days back the recently added view looks
+	RecentDays int `toml:"recent_days"`
+	// Ignore glob patterns of the files and directories not scanned, like "**/.git/**"
+	Ignore []string `toml:"ignore"`
+	// Extensions of the files scanned, every supported one if empty
+	Extensions []string `toml:"extensions"`
+	// Analyze if the tempo and key of the new and changed files are estimated while scanning
+	Analyze bool `toml:"analyze"`
+}
+
+// Player : Settings of the audio player
+type Player struct {
+	// Cover how the album cover is drawn: auto, kitty, iterm2, sixel, blocks or off
+	Cover string `toml:"cover"`
+	// Volume the audio starts playing at the first run, from 0 to 100, the next ones start at the
+	// volume of the last one
+	Volume int `toml:"volume"`
+	// ReplayGain adjustment of the loudness: off, track or album
+	ReplayGain string `toml:"replaygain"`
+	// Normalize if the files without ReplayGain tags are played at their measured loudness,
+	// while a ReplayGain mode is on
+	Normalize bool `toml:"normalize"`
+	// PauseOnBlur if the playback is paused while the terminal is not focused, and resumed when
+	// it is again, in the terminals reporting it
+	PauseOnBlur bool `toml:"pause_on_blur"`
+	// PauseOnSleep if the playback is paused when the system goes to sleep, staying paused when
+	// it wakes up
+	PauseOnSleep bool `toml:"pause_on_sleep"`
+	// Audiobook if the files are resumed from where they were left at, instead of offering it
+	Audiobook bool `toml:"audiobook"`
+	// Device the audio is played on, the default one of the system if empty
+	Device string `toml:"device"`
+	// VolumeStep how much the volume goes up or down with each key press, from 1 to 100
+	VolumeStep int `toml:"volume_step"`
+	// SeekSeconds how far the arrow keys move the playback, and LargeSeekSeconds how far they
+	// move it with Shift or Ctrl held
+	SeekSeconds      int `toml:"seek_seconds"`
+	LargeSeekSeconds int `toml:"large_seek_seconds"`
+	// FadeMS how many milliseconds the audio fades out when paused or stopped, and in when resumed
+	FadeMS int `toml:"fade_ms"`
+	// ReadAheadMS how many milliseconds of audio are decoded ahead of the playback, 0 to
+	// decode it while playing it
+	ReadAheadMS int `toml:"read_ahead_ms"`
+	// Silence how the stretches of near-silence are played: off, skip or fast
+	Silence string `toml:"silence"`
+	// SilenceThresholdDB level in dBFS under which the audio is silent
+	SilenceThresholdDB float64 `toml:"silence_threshold_db"`
+	// SilenceMS how many milliseconds of each stretch of silence are played before skipping it
+	SilenceMS int `toml:"silence_ms"`
+	// RecordDir where the recordings of the playback are saved as WAV files
+	RecordDir string `toml:"record_dir"`
+}
+
+// Theme : Settings of the colors of the UI
+type Theme struct {
+	// Name of the theme: dark, light, monochrome, gruvbox or the name of a palette
+	Name string `toml:"name"`
+	// Palettes are themes defined by the user
+	Palettes []Palette `toml:"palette"`
+}
+
+// Palette : A theme defined by the user, with colors like "#6b84ff" or ANSI ones from "0" to
+// "255". The ones left out are the ones of the dark theme
+type Palette struct {
+	Name      string `toml:"name"`
+	Primary   string `toml:"primary"`
+	Secondary string `toml:"secondary"`
+	Contrast  string `toml:"contrast"`
+	Problem   string `toml:"problem"`
+	Grey      string `toml:"grey"`
+	Highlight string `toml:"highlight"`
+	Progress  string `toml:"progress"`
+}
+
+// Equalizer : Settings of the equalizer
+type Equalizer struct {
+	// Curve played: flat, rock, classical, custom or the name of a profile
+	Curve string `toml:"curve"`
+	// Preamp gain in dB applied before the bands, lowered to keep the boosted ones from clipping
+	Preamp float64 `toml:"preamp"`
+	// Custom gains in dB of the bands from 31 Hz to 16 kHz, changed from a preset in the
+	// equalizer pane
+	Custom []float64 `toml:"custom"`
+	// Profiles are named curves, like one for the headphones and one for the speakers
+	Profiles []EqualizerProfile `toml:"profile"`
+}
+
+// EqualizerProfile : A named curve of the equalizer, with the gains of its 10 bands in dB
+type EqualizerProfile struct {
+	Name  string    `toml:"name"`
+	Gains []float64 `toml:"gains"`
+}
+
+// Lyrics : Settings of the lyrics pane
+type Lyrics struct {
+	// Providers asked in order for the lyrics of the tracks without local ones, like "lrclib"
+	Providers []string `toml:"providers"`
+}
+
+// AcoustID : Settings of the identification of the audio files by their fingerprint
+type AcoustID struct {
+	// APIKey of the application registered at https://acoustid.org/new-application
+	APIKey string `toml:"api_key"`
+	// Scan if the new files without tags are named while scanning the library
+	Scan bool `toml:"scan"`
+}
+
+// Remote : Settings of the programs controlling tempo from outside the terminal
+type Remote struct {
+	// MediaKeys if the media keys and the media controls of the system control the player,
+	// through MPRIS on Linux, the Now Playing center on macOS and the media flyout on Windows
+	MediaKeys bool `toml:"media_keys"`
+	// Socket if tempo ctl can control the player through a Unix socket
+	Socket bool `toml:"socket"`
+	// SingleInstance if tempo play enqueues its paths into the tempo running already through
+	// the socket, instead of playing them along
+	SingleInstance bool `toml:"single_instance"`
+	// MPD address like "localhost:6600" where the clients of the Music Player Daemon can
+	// control the player, off if empty
+	MPD string `toml:"mpd"`
+	// HTTP address like ":8080" where the HTTP API is served, off if empty, and the token its
+	// requests need. tempo serve makes up a token if there is none
+	HTTP      string `toml:"http"`
+	HTTPToken string `toml:"http_token"`
+	// SIGUSR1 and SIGUSR2 are the commands of tempo ctl run by the signals, like "toggle" for
+	// pkill -USR1 tempo, ignored if empty. Windows has no such signals
+	SIGUSR1 string `toml:"sigusr1"`
+	SIGUSR2 string `toml:"sigusr2"`
+}
+
+// Discord : Settings of the track shown on the Discord profile, through its desktop app
+type Discord struct {
+	// Enabled if the track playing is shown on the profile
+	Enabled bool `toml:"enabled"`
+	// ClientID of the application registered at https://discord.com/developers/applications,
+	// whose name is shown as "Listening to <name>"
+	ClientID string `toml:"client_id"`
+	// ShowAlbum and ShowElapsed if the album and the time played are shown along with the
+	// title and the artist
+	ShowAlbum   bool `toml:"show_album"`
+	ShowElapsed bool `toml:"show_elapsed"`
+	// HideFileNames if the files without a title tag are shown as an unknown track instead of
+	// by their file name
+	HideFileNames bool `toml:"hide_file_names"`
+	// Exclude are directories whose files are never shown, ~ is the home directory
+	Exclude []string `toml:"exclude"`
+}
+
+// Hooks : Shell commands run when the playback changes, told what is being played in TEMPO_*
+// environment variables
+type Hooks struct {
+	// OnTrackChange runs when a file starts
+	OnTrackChange string `toml:"on_track_change"`
+	// OnPlay and OnPause run when the playback is resumed and paused
+	OnPlay  string `toml:"on_play"`
+	OnPause string `toml:"on_pause"`
+	// OnStop runs when the queue ends or tempo quits while playing
+	OnStop string `toml:"on_stop"`
+}
+
+// NowPlaying : Settings of the file kept with what is being played, for OBS and status bars
+type NowPlaying struct {
+	// File written, off if empty, ~ is the home directory
+	File string `toml:"file"`
+	// Template of the text, like "{{.Artist}} – {{.Title}}", given the State, Path, Title,
+	// Artist, Album, Track, Position, Duration, Percent and Volume
+	Template string `toml:"template"`
+}
+
+// Snapcast : Settings of the Snapserver source tempo plays to, for the audio of every room in sync
+type Snapcast struct {
+	// Source listed as the "snapcast" device: the pipe the Snapserver reads, like
+	// "/tmp/snapfifo", or "tcp://host:port" for a source listening on TCP. Off if empty
+	Source string `toml:"source"`
+	// SampleFormat of the source in the Snapserver config, as rate:bits:channels
+	SampleFormat string `toml:"sample_format"`
+}
+
+// SmartPlaylist : A named query over the library, like `genre = "jazz" AND rating >= 4`
+type SmartPlaylist struct {
+	Name  string `toml:"name"`
+	Query string `toml:"query"`
+}
+
+// Station : An internet radio saved in the stations view of the browser, by the URL of its stream
+type Station struct {
+	Name   string   `toml:"name"`
+	URL    string   `toml:"url"`
+	Genres []string `toml:"genres"`
+}
+
+// Default returns the configuration used when there is no config file.
+func Default() *Config {
+	return &Config{
+		Browser: Browser{
+			Sort: "name",
+		},
+		Library: Library{
+			RecentDays: 30,
+		},
+		Player: Player{
+			Volume:             50,
+			Cover:              "auto",
+			ReplayGain:         "off",
+			VolumeStep:         5,
+			SeekSeconds:        5,
+			LargeSeekSeconds:   60,
+			FadeMS:             150,
+			ReadAheadMS:        2000,
+			Silence:            "off",
+			SilenceThresholdDB: -50,
+			SilenceMS:          1000,
+			RecordDir:          "~/Music/Recordings",
+			PauseOnSleep:       true,
+		},
+		Theme: Theme{
+			Name: "dark",
+		},
+		Equalizer: Equalizer{
+			Curve: "flat",
+		},
+		Remote: Remote{
+			MediaKeys: true,
+			Socket:    true,
+			SIGUSR1:   "toggle",
+			SIGUSR2:   "next",
+		},
+		Discord: Discord{
+			ShowAlbum:     true,
+			ShowElapsed:   true,
+			HideFileNames: true,
+		},
+		NowPlaying: NowPlaying{
+			Template: "{{if .Artist}}{{.Artist}} – {{end}}{{.Title}}",
+		},
+		Snapcast: Snapcast{
+			SampleFormat: "48000:16:2",
+		},
+	}
+}
+
+// Path returns the location of the config file inside the user config directory:
+// ~/.config/tempo on Linux, ~/Library/Application Support/tempo on macOS and
+// %AppData%\tempo on Windows.
+func Path() (string, error) {
+	dir, err := xdg.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Load reads the config file at path. A missing file results in the default config. A file
+// that cannot be read results in the default config too, along with the error, which refuses
+// to be saved so the file can still be fixed.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	cfg.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		cfg.loadErr = err
+		return cfg, err
+	}
+
+	meta, err := toml.Decode(string(data), cfg)
+	if err != nil {
+		// The settings decoded before the error are dropped
+		cfg = Default()
+		cfg.path = path
+		cfg.loadErr = err
+		return cfg, err
+	}
+	for _, key := range meta.Undecoded() {
+		cfg.unknown = append(cfg.unknown, key.String())
+	}
+	return cfg, nil
+}
+
+// Unknown returns the keys of the file that are not settings, like "player.volum", which
+// are ignored.
+func (c *Config) Unknown() []string {
+	return c.unknown
+}
+
+// Save writes the config back to the file it was loaded from. Only the settings that changed
+// are written over the ones of the file, keeping its comments, its layout and the keys that
+// are not settings. A file that cannot be changed that way is written whole, keeping those
+// keys but not the comments, so a file with comments is first copied to a .bak file next to
+// it. A file that could not be read is not saved over.
+func (c *Config) Save() error {
+	if c.path == "" {
+		return errors.New("config file path is not set")
+	}
+	if c.loadErr != nil {
+		return fmt.Errorf("the config file could not be read, so it is not saved over: %w", c.loadErr)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+
+	old, err := os.ReadFile(c.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if len(old) > 0 {
+		data, err := c.patch(old)
+		if err == nil {
+			return os.WriteFile(c.path, data, 0o644)
+		}
+		if !errors.Is(err, errUnpatchable) {
+			return err
+		}
+	}
+
+	data, err := c.encode(old)
+	if err != nil {
+		return err
+	}
+	if hasComments(old) {
+		if err := os.WriteFile(c.path+".bak", old, 0o644); err != nil {
+			return fmt.Errorf("cannot back up the config file: %w", err)
+		}
+	}
+	return os.WriteFile(c.path, data, 0o644)
+}
+
+// encode returns the file of the config, with the keys of the old file that are not settings.
+func (c *Config) encode(old []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(c.fileValues()); err != nil {
+		return nil, err
+	}
+	if len(old) == 0 {
+		return buf.Bytes(), nil
+	}
+
+	// The file may have been changed since it was loaded
+	meta, err := toml.Decode(string(old), Default())
+	if err != nil {
+		return nil, fmt.Errorf("the config file cannot be read, so it is not saved over: %w", err)
+	}
+	unknown := meta.Undecoded()
+	if len(unknown) == 0 {
+		return buf.Bytes(), nil
+	}
+
+	var file, values map[string]any
+	if _, err := toml.Decode(string(old), &file); err != nil {
+		return nil, err
+	}
+	if _, err := toml.Decode(buf.String(), &values); err != nil {
+		return nil, err
+	}
+	for _, key := range unknown {
+		if value, ok := lookupKey(file, key); ok {
+			setKey(values, key, value)
+		}
+	}
+	buf.Reset()
+	if err := toml.NewEncoder(&buf).Encode(values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// lookupKey returns the value of the key in the decoded tables, reporting false if it is in
+// none, or in an array of tables.
+func lookupKey(tables map[string]any, key toml.Key) (any, bool) {
+	for _, name := range key[:len(key)-1] {
+		var ok bool
+		if tables, ok = tables[name].(map[string]any); !ok {
+			return nil, false
+		}
+	}
+	value, ok := tables[key[len(key)-1]]
+	return value, ok
+}
+
+// setKey sets the key in the decoded tables, creating the tables it is in. A key in an array
+// of tables is left out.
+func setKey(tables map[string]any, key toml.Key, value any) {
+	for _, name := range key[:len(key)-1] {
+		next, ok := tables[name]
+		if !ok {
+			next = make(map[string]any)
+			tables[name] = next
+		}
+		if tables, ok = next.(map[string]any); !ok {
+			return
+		}
+	}
+	tables[key[len(key)-1]] = value
+}
+
+// hasComments reports whether the TOML file has comments, a # out of the strings.
+func hasComments(data []byte) bool {
+	text := string(data)
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '#':
+			return true
+		case '"', '\'':
+			quote := text[i : i+1]
+			if strings.HasPrefix(text[i:], strings.Repeat(quote, 3)) {
+				quote = strings.Repeat(quote, 3)
+			}
+			i = stringEnd(text, i+len(quote), quote)
+		}
+	}
+	return false
+}
+
+// stringEnd returns the index of the last byte of the string starting at i, before its
+// closing quote. Only the strings in double quotes have escapes.
+func stringEnd(text string, i int, quote string) int {
+	for ; i < len(text); i++ {
+		if text[i] == '\\' && quote[0] == '"' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(text[i:], quote) {
+			return i + len(quote) - 1
+		}
+	}
+	return len(text)
+}
+
+// FilePath returns the path of the config file.
+func (c *Config) FilePath() string {
+	return c.path
+}
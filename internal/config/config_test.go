@@ -0,0 +1,264 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSave(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		change func(c *Config)
+		want   string
+		backup bool
+	}{
+		{
+			name: "changed key in place",
+			file: `# My settings
+[browser]
+  # How the files are listed
+  sort = "name" # name or mtime
+  sort_reverse = false
+
+[player]
+  volume = 40
+`,
+			change: func(c *Config) {
+				c.Browser.Sort = "duration"
+				c.Player.Volume = 55
+			},
+			want: `# My settings
+[browser]
+  # How the files are listed
+  sort = "duration" # name or mtime
+  sort_reverse = false
+
+[player]
+  volume = 55
+`,
+		},
+		{
+			name: "new key after the last one of its table",
+			file: `[browser]
+  sort = "name"
+
+# The player
+[player]
+  volume = 40
+`,
+			change: func(c *Config) { c.Browser.Bookmarks = []string{"~/Music", "/mnt/music"} },
+			want: `[browser]
+  sort = "name"
+  bookmarks = ["~/Music", "/mnt/music"]
+
+# The player
+[player]
+  volume = 40
+`,
+		},
+		{
+			name:   "new table at the end",
+			file:   "# Nothing yet\n",
+			change: func(c *Config) { c.Player.Volume = 70 },
+			want: `# Nothing yet
+
+[player]
+  volume = 70
+`,
+		},
+		{
+			name: "unknown keys are kept",
+			file: `[player]
+  volum = 30 # typo
+  volume = 40
+
+[mystery]
+  x = 1 # keep
+`,
+			change: func(c *Config) { c.Player.Volume = 45 },
+			want: `[player]
+  volum = 30 # typo
+  volume = 45
+
+[mystery]
+  x = 1 # keep
+`,
+		},
+		{
+			name: "default value removes the key",
+			file: `[browser]
+  # Where to jump
+  bookmarks = ["~/Music"] # with b
+  sort = "mtime"
+`,
+			change: func(c *Config) { c.Browser.Bookmarks = nil },
+			want: `[browser]
+  # Where to jump
+  sort = "mtime"
+`,
+		},
+		{
+			name: "array tables replaced",
+			file: `# Radios
+[[station]]
+  name = "One"
+  url = "http://one.example/stream"
+
+[[station]]
+  name = "Two"
+  url = "http://two.example/stream"
+
+[player]
+  volume = 40
+`,
+			change: func(c *Config) {
+				c.Stations = []Station{{Name: "Three", URL: "http://three.example/stream", Genres: []string{"jazz"}}}
+			},
+			want: `# Radios
+[[station]]
+  name = "Three"
+  url = "http://three.example/stream"
+  genres = ["jazz"]
+
+[player]
+  volume = 40
+`,
+		},
+		{
+			name: "array tables appended",
+			file: `[player]
+  volume = 40
+`,
+			change: func(c *Config) {
+				c.Stations = []Station{
+					{Name: "One", URL: "http://one.example/stream"},
+					{Name: "Two", URL: "http://two.example/stream"},
+				}
+			},
+			want: `[player]
+  volume = 40
+
+[[station]]
+  name = "One"
+  url = "http://one.example/stream"
+
+[[station]]
+  name = "Two"
+  url = "http://two.example/stream"
+`,
+		},
+		{
+			name: "dotted keys are written whole",
+			file: `# Comment
+player.volume = 40
+`,
+			change: func(c *Config) { c.Player.Volume = 50 },
+			want:   "",
+			backup: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FileName)
+			if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			c, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			tt.change(c)
+			if err := c.Save(); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want != "" && string(data) != tt.want {
+				t.Errorf("saved file:\n%s\nwant:\n%s", data, tt.want)
+			}
+
+			backup, err := os.ReadFile(path + ".bak")
+			switch {
+			case tt.backup && err != nil:
+				t.Errorf("no backup: %v", err)
+			case tt.backup && string(backup) != tt.file:
+				t.Errorf("backup:\n%s\nwant the old file", backup)
+			case !tt.backup && !errors.Is(err, fs.ErrNotExist):
+				t.Errorf("backup written, want none (%v)", err)
+			}
+
+			// Whatever way it was saved, loading it again gives the same settings
+			again, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load after Save: %v", err)
+			}
+			if !again.Equal(c.fileValues()) {
+				t.Errorf("settings changed by the round trip:\n%+v\nwant:\n%+v", again.fileValues(), c.fileValues())
+			}
+			if !reflect.DeepEqual(again.Unknown(), c.Unknown()) {
+				t.Errorf("unknown keys = %v, want %v", again.Unknown(), c.Unknown())
+			}
+		})
+	}
+}
+
+func TestSaveUnreadable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	const broken = "[player\nvolume = 40\n"
+	if err := os.WriteFile(path, []byte(broken), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err == nil {
+		t.Fatal("Load succeeded, want the syntax error")
+	}
+	c.Player.Volume = 10
+	if err := c.Save(); err == nil {
+		t.Fatal("Save succeeded, want it to refuse")
+	}
+	if data, _ := os.ReadFile(path); string(data) != broken {
+		t.Errorf("file saved over:\n%s", data)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := WriteTemplate(path, false); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(c.Unknown()) > 0 {
+		t.Errorf("unknown keys in the template: %v", c.Unknown())
+	}
+	if c.Player.Volume != Default().Player.Volume || c.Browser.Sort != Default().Browser.Sort {
+		t.Errorf("template settings differ from the defaults: %+v", c)
+	}
+
+	// Saving a change keeps every comment explaining the settings
+	c.Player.Volume = 35
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Count(string(data), "#"), strings.Count(Template, "#"); got != want {
+		t.Errorf("saved template has %d comment marks, want %d", got, want)
+	}
+	if err := WriteTemplate(path, false); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("WriteTemplate over a file = %v, want fs.ErrExist", err)
+	}
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Prefix of the environment variables overriding the settings, like TEMPO_PLAYER_VOLUME
+const EnvPrefix string = "TEMPO_"
+
+// Short names of the most used variables, by the one they stand for
+var envAliases = map[string]string{
+	"TEMPO_VOLUME":    "TEMPO_PLAYER_VOLUME",
+	"TEMPO_MUSIC_DIR": "TEMPO_LIBRARY_DIRS",
+	"TEMPO_THEME":     "TEMPO_THEME_NAME",
+	"TEMPO_DEVICE":    "TEMPO_PLAYER_DEVICE",
+}
+
+// Variables with the TEMPO_ prefix that are not settings
+var envIgnored = []string{"TEMPO_PROFILE", "TEMPO_LOG_LEVEL"}
+
+// override : A setting replaced by an environment variable, kept to save the file without it
+type override struct {
+	// Name of the setting, like player.volume
+	name string
+	// Index of the field in Config, and key of the map for the keys
+	index []int
+	key   string
+	// Value of the setting in the file and from the variable
+	file, env reflect.Value
+	// inFile if the map had the key before the variable
+	inFile bool
+}
+
+// ApplyEnv overrides the settings with the TEMPO_<SECTION>_<KEY> variables of environ, in the
+// "NAME=value" form of os.Environ, like TEMPO_PLAYER_VOLUME=30 or TEMPO_KEYS_REWIND=left,a.
+// Lists are separated by commas, and the tables like [[smart_playlist]] cannot be set. The
+// variables that cannot be applied are returned as errors, the others are applied anyway.
+func (c *Config) ApplyEnv(environ []string) []error {
+	vars := make(map[string]string)
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) || slices.Contains(envIgnored, name) {
+			continue
+		}
+		if long, ok := envAliases[name]; ok {
+			// The long name is the more specific one
+			if _, set := vars[long]; set {
+				continue
+			}
+			name = long
+		}
+		vars[name] = value
+	}
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(vars)) {
+		if err := c.applyVar(name, vars[name]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errs
+}
+
+// applyVar sets the setting of the variable with the given name.
+func (c *Config) applyVar(name, value string) error {
+	root := reflect.ValueOf(c).Elem()
+	rest := strings.TrimPrefix(name, EnvPrefix)
+
+	for i := range root.NumField() {
+		section := root.Type().Field(i)
+		tag := tomlName(section)
+		if tag == "" {
+			continue
+		}
+		prefix := strings.ToUpper(tag) + "_"
+		if !strings.HasPrefix(rest, prefix) {
+			continue
+		}
+		key := strings.TrimPrefix(rest, prefix)
+
+		switch section.Type.Kind() {
+		case reflect.Struct:
+			for j := range section.Type.NumField() {
+				field := section.Type.Field(j)
+				if strings.ToUpper(tomlName(field)) != key {
+					continue
+				}
+				return c.override(tag+"."+tomlName(field), []int{i, j}, "", value)
+			}
+		case reflect.Map:
+			return c.override(tag+"."+strings.ToLower(key), []int{i}, strings.ToLower(key), value)
+		}
+	}
+	return fmt.Errorf("unknown setting")
+}
+
+// override sets the field at index, or its key if it is a map, to the parsed value.
+func (c *Config) override(name string, index []int, key, value string) error {
+	field := reflect.ValueOf(c).Elem().FieldByIndex(index)
+	typ := field.Type()
+	if key != "" {
+		typ = typ.Elem()
+	}
+	parsed, err := parseEnvValue(typ, value)
+	if err != nil {
+		return err
+	}
+
+	o := override{name: name, index: index, key: key, env: parsed}
+	if key == "" {
+		o.file = reflect.New(typ).Elem()
+		o.file.Set(field)
+		field.Set(parsed)
+	} else {
+		if field.IsNil() {
+			field.Set(reflect.MakeMap(field.Type()))
+		}
+		if old := field.MapIndex(reflect.ValueOf(key)); old.IsValid() {
+			o.file, o.inFile = old, true
+		}
+		field.SetMapIndex(reflect.ValueOf(key), parsed)
+	}
+	c.overrides = append(c.overrides, o)
+	return nil
+}
+
+// parseEnvValue converts the value of a variable into the type of its setting.
+func parseEnvValue(typ reflect.Type, value string) (reflect.Value, error) {
+	v := reflect.New(typ).Elem()
+	switch typ.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return v, fmt.Errorf("%q is not a whole number", value)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return v, fmt.Errorf("%q is not a number", value)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return v, fmt.Errorf("%q is not true or false", value)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		// An empty value is an empty list, like for the keys disabling an action
+		items := reflect.MakeSlice(typ, 0, 0)
+		if strings.TrimSpace(value) != "" {
+			for item := range strings.SplitSeq(value, ",") {
+				elem, err := parseEnvValue(typ.Elem(), strings.TrimSpace(item))
+				if err != nil {
+					return v, err
+				}
+				items = reflect.Append(items, elem)
+			}
+		}
+		v.Set(items)
+	default:
+		return v, fmt.Errorf("cannot be set from the environment")
+	}
+	return v, nil
+}
+
+// FromEnv reports whether the setting with the given name, like player.volume, was set by an
+// environment variable.
+func (c *Config) FromEnv(name string) bool {
+	return slices.ContainsFunc(c.overrides, func(o override) bool { return o.name == name })
+}
+
+// fileValues returns the config with the settings of the file in place of the ones set by the
+// environment variables, unless they were changed since.
+func (c *Config) fileValues() *Config {
+	cp := *c
+	root := reflect.ValueOf(&cp).Elem()
+	cloned := make(map[int]bool)
+	for _, o := range c.overrides {
+		field := root.FieldByIndex(o.index)
+		if o.key == "" {
+			if reflect.DeepEqual(field.Interface(), o.env.Interface()) {
+				field.Set(o.file)
+			}
+			continue
+		}
+
+		// The map is shared with c until cloned
+		if !cloned[o.index[0]] {
+			clone := reflect.MakeMap(field.Type())
+			for iter := field.MapRange(); iter.Next(); {
+				clone.SetMapIndex(iter.Key(), iter.Value())
+			}
+			field.Set(clone)
+			cloned[o.index[0]] = true
+		}
+		key := reflect.ValueOf(o.key)
+		if current := field.MapIndex(key); !current.IsValid() || !reflect.DeepEqual(current.Interface(), o.env.Interface()) {
+			continue
+		}
+		if o.inFile {
+			field.SetMapIndex(key, o.file)
+		} else {
+			field.SetMapIndex(key, reflect.Value{})
+		}
+	}
+	return &cp
+}
+
+// tomlName returns the name of the field in the file, empty if it has none.
+func tomlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,440 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// errUnpatchable is returned patching a file whose layout cannot be changed in place, like
+// one with dotted or quoted keys, which is then written whole.
+var errUnpatchable = errors.New("the config file cannot be changed in place")
+
+// tomlEntry : A table header or a key of a TOML file, over its lines from start to end
+type tomlEntry struct {
+	header bool
+	// array if the header is the one of an array of tables, like [[station]]
+	array bool
+	// Name of the header, or of the table the key is in
+	table string
+	key   string
+
+	start, end int
+	// Comment after the value, with its #
+	comment string
+}
+
+// patcher : The lines of a config file being changed to the values of the encoded config
+type patcher struct {
+	lines []string
+
+	// Lines of the encoded config and its entries, the changed values are copied from
+	encoded        []string
+	encodedEntries []tomlEntry
+
+	// Keys of the file that are not settings, and the values of the settings left out
+	unknown  map[string]bool
+	defaults map[string]any
+}
+
+// patch returns the old file with the settings changed since it was read changed in place,
+// keeping its comments, its layout and the keys that are not settings. It fails with
+// errUnpatchable if the file cannot be changed that way, and if it cannot be read.
+func (c *Config) patch(old []byte) ([]byte, error) {
+	meta, err := toml.Decode(string(old), Default())
+	if err != nil {
+		return nil, fmt.Errorf("the config file cannot be read, so it is not saved over: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(c.fileValues()); err != nil {
+		return nil, err
+	}
+	var file, values, defaults map[string]any
+	if _, err := toml.Decode(string(old), &file); err != nil {
+		return nil, err
+	}
+	if _, err := toml.Decode(buf.String(), &values); err != nil {
+		return nil, err
+	}
+	if defaults, err = decodedValues(Default()); err != nil {
+		return nil, err
+	}
+
+	p := &patcher{
+		lines:    strings.Split(string(old), "\n"),
+		encoded:  strings.Split(buf.String(), "\n"),
+		unknown:  make(map[string]bool),
+		defaults: defaults,
+	}
+	for _, key := range meta.Undecoded() {
+		p.unknown[key.String()] = true
+	}
+	if p.encodedEntries, err = parseEntries(p.encoded); err != nil {
+		return nil, err
+	}
+	if err := p.table(nil, values, file); err != nil {
+		return nil, err
+	}
+
+	// The changed file has to read back as the config
+	data := []byte(strings.Join(p.lines, "\n"))
+	check := Default()
+	if _, err := toml.Decode(string(data), check); err != nil || !check.Equal(c.fileValues()) {
+		return nil, errUnpatchable
+	}
+	return data, nil
+}
+
+// decodedValues returns the config as the values of its file.
+func decodedValues(c *Config) (map[string]any, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(c); err != nil {
+		return nil, err
+	}
+	var values map[string]any
+	_, err := toml.Decode(buf.String(), &values)
+	return values, err
+}
+
+// table changes the keys of the table at path from the ones of file to the ones of values.
+func (p *patcher) table(path []string, values, file map[string]any) error {
+	keys := slices.Sorted(maps.Keys(values))
+	for key := range file {
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range keys {
+		keyPath := append(slices.Clone(path), key)
+		name := strings.Join(keyPath, ".")
+		if p.unknown[name] {
+			continue
+		}
+		value, inValues := values[key]
+		was, inFile := file[key]
+
+		switch value := value.(type) {
+		case map[string]any:
+			wasTable, _ := was.(map[string]any)
+			if err := p.table(keyPath, value, wasTable); err != nil {
+				return err
+			}
+			continue
+		case []map[string]any:
+			if !reflect.DeepEqual(value, was) {
+				if err := p.arrayTables(name); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		switch {
+		case !inValues:
+			if _, ok := was.([]map[string]any); ok {
+				if err := p.arrayTables(name); err != nil {
+					return err
+				}
+			} else if _, ok := was.(map[string]any); !ok {
+				if err := p.remove(path, key); err != nil {
+					return err
+				}
+			}
+		case inFile && reflect.DeepEqual(value, was):
+		case !inFile && reflect.DeepEqual(value, lookupPath(p.defaults, keyPath)):
+			// Left out of the file, it is the default already
+		default:
+			if err := p.set(path, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lookupPath returns the value at the path of keys in the decoded tables, nil if there is none.
+func lookupPath(tables map[string]any, path []string) any {
+	for _, name := range path[:len(path)-1] {
+		var ok bool
+		if tables, ok = tables[name].(map[string]any); !ok {
+			return nil
+		}
+	}
+	return tables[path[len(path)-1]]
+}
+
+// set writes the key of the table at path with its encoded value, over the one of the file or
+// after the other keys of the table, adding the table at the end of the file if it has none.
+func (p *patcher) set(path []string, key string) error {
+	table := strings.Join(path, ".")
+	if table == "" {
+		return errUnpatchable
+	}
+	i := slices.IndexFunc(p.encodedEntries, func(e tomlEntry) bool {
+		return !e.header && e.table == table && e.key == key
+	})
+	if i < 0 {
+		return errUnpatchable
+	}
+	src := p.encodedEntries[i]
+	value := make([]string, 0, src.end-src.start+1)
+	for _, line := range p.encoded[src.start : src.end+1] {
+		value = append(value, strings.TrimSpace(line))
+	}
+
+	entries, err := parseEntries(p.lines)
+	if err != nil {
+		return err
+	}
+	// The key is replaced keeping its indentation and comment
+	for _, e := range entries {
+		if e.header || e.table != table || e.key != key {
+			continue
+		}
+		indent := leadingSpace(p.lines[e.start])
+		lines := indented(value, indent)
+		if e.comment != "" {
+			lines[len(lines)-1] += " " + e.comment
+		}
+		p.lines = slices.Replace(p.lines, e.start, e.end+1, lines...)
+		return nil
+	}
+
+	// Or added after the last key of its table
+	header := slices.IndexFunc(entries, func(e tomlEntry) bool { return e.header && !e.array && e.table == table })
+	if header < 0 {
+		lines := append([]string{"", "[" + table + "]"}, indented(value, "  ")...)
+		p.lines = slices.Insert(p.lines, trimmedEnd(p.lines), lines...)
+		return nil
+	}
+	last, indent := entries[header].end, "  "
+	for _, e := range entries[header+1:] {
+		if e.header {
+			break
+		}
+		last, indent = e.end, leadingSpace(p.lines[e.start])
+	}
+	p.lines = slices.Insert(p.lines, last+1, indented(value, indent)...)
+	return nil
+}
+
+// remove removes the key of the table at path from the file.
+func (p *patcher) remove(path []string, key string) error {
+	entries, err := parseEntries(p.lines)
+	if err != nil {
+		return err
+	}
+	table := strings.Join(path, ".")
+	for _, e := range entries {
+		if !e.header && e.table == table && e.key == key {
+			p.lines = slices.Delete(p.lines, e.start, e.end+1)
+			return nil
+		}
+	}
+	return nil
+}
+
+// arrayTables replaces the tables of the array with the given name, like [[station]], with the
+// encoded ones, where the first one was or else at the end of the file. The comments between
+// the tables are lost, but not the ones before the next table.
+func (p *patcher) arrayTables(name string) error {
+	var tables []string
+	for i, block := range arrayBlocks(p.encodedEntries, name) {
+		if i > 0 {
+			tables = append(tables, "")
+		}
+		for _, line := range p.encoded[block[0] : block[1]+1] {
+			if line = strings.TrimSpace(line); !strings.HasPrefix(line, "[") {
+				line = "  " + line
+			}
+			tables = append(tables, line)
+		}
+	}
+
+	entries, err := parseEntries(p.lines)
+	if err != nil {
+		return err
+	}
+	blocks := arrayBlocks(entries, name)
+	if len(blocks) == 0 {
+		if len(tables) > 0 {
+			p.lines = slices.Insert(p.lines, trimmedEnd(p.lines), append([]string{""}, tables...)...)
+		}
+		return nil
+	}
+	for i := len(blocks) - 1; i > 0; i-- {
+		// With the blank lines separating it from the previous block
+		start := blocks[i][0]
+		for start > blocks[i-1][1]+1 && strings.TrimSpace(p.lines[start-1]) == "" {
+			start--
+		}
+		p.lines = slices.Delete(p.lines, start, blocks[i][1]+1)
+	}
+	p.lines = slices.Replace(p.lines, blocks[0][0], blocks[0][1]+1, tables...)
+	return nil
+}
+
+// arrayBlocks returns the first and last lines of the tables of the array with the given name,
+// from their header to their last key.
+func arrayBlocks(entries []tomlEntry, name string) [][2]int {
+	var blocks [][2]int
+	for i, e := range entries {
+		if !e.header || !e.array || e.table != name {
+			continue
+		}
+		end := e.end
+		for _, next := range entries[i+1:] {
+			if next.header {
+				break
+			}
+			end = next.end
+		}
+		blocks = append(blocks, [2]int{e.start, end})
+	}
+	return blocks
+}
+
+// parseEntries returns the headers and keys of the lines of a TOML file. Only the files with
+// bare keys and table names are parsed, failing with errUnpatchable otherwise.
+func parseEntries(lines []string) ([]tomlEntry, error) {
+	var entries []tomlEntry
+	table := ""
+	for i := 0; i < len(lines); i++ {
+		text := strings.TrimSpace(lines[i])
+		if text == "" || text[0] == '#' {
+			continue
+		}
+
+		if text[0] == '[' {
+			array := strings.HasPrefix(text, "[[")
+			end, comment := valueEnd([]string{text}, 0, 0)
+			if end != 0 {
+				return nil, errUnpatchable
+			}
+			name := strings.TrimSpace(strings.TrimSuffix(text, comment))
+			name = strings.TrimSpace(strings.Trim(name, "[]"))
+			if !bareName(name, true) {
+				return nil, errUnpatchable
+			}
+			table = name
+			entries = append(entries, tomlEntry{header: true, array: array, table: name, start: i, end: i})
+			continue
+		}
+
+		key, _, ok := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !bareName(key, false) {
+			return nil, errUnpatchable
+		}
+		end, comment := valueEnd(lines, i, strings.Index(lines[i], "=")+1)
+		entries = append(entries, tomlEntry{table: table, key: key, start: i, end: end, comment: comment})
+		i = end
+	}
+	return entries, nil
+}
+
+// valueEnd returns the last line of the value starting at the given line and column, once its
+// brackets and strings are closed, with the comment after it.
+func valueEnd(lines []string, line, col int) (int, string) {
+	depth := 0
+	quote := ""
+	for ; line < len(lines); line, col = line+1, 0 {
+		text := lines[line]
+		comment := ""
+	scan:
+		for i := col; i < len(text); i++ {
+			c := text[i]
+			if quote != "" {
+				switch {
+				case c == '\\' && quote[0] == '"':
+					i++
+				case strings.HasPrefix(text[i:], quote):
+					i += len(quote) - 1
+					quote = ""
+				}
+				continue
+			}
+			switch c {
+			case '#':
+				comment = text[i:]
+				break scan
+			case '"', '\'':
+				quote = text[i : i+1]
+				if strings.HasPrefix(text[i:], strings.Repeat(quote, 3)) {
+					quote = strings.Repeat(quote, 3)
+				}
+				i += len(quote) - 1
+			case '[', '{':
+				depth++
+			case ']', '}':
+				depth--
+			}
+		}
+		// Only the strings in three quotes go on in the next line
+		if len(quote) == 1 {
+			quote = ""
+		}
+		if depth <= 0 && quote == "" {
+			return line, comment
+		}
+	}
+	return len(lines) - 1, ""
+}
+
+// bareName reports whether name is a bare key, or bare keys joined by dots if dotted.
+func bareName(name string, dotted bool) bool {
+	parts := []string{name}
+	if dotted {
+		parts = strings.Split(name, ".")
+	}
+	for _, part := range parts {
+		if part == "" {
+			return false
+		}
+		for _, r := range part {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// indented returns the lines with the indentation of the first one, and the lines after it
+// one level deeper, like the values of a multi-line array.
+func indented(lines []string, indent string) []string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			out[i] = indent + line
+		case i == len(lines)-1 && strings.HasPrefix(line, "]"):
+			out[i] = indent + line
+		default:
+			out[i] = indent + "  " + line
+		}
+	}
+	return out
+}
+
+// leadingSpace returns the indentation of the line.
+func leadingSpace(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// trimmedEnd returns the index after the last line that is not empty.
+func trimmedEnd(lines []string) int {
+	end := len(lines)
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	return end
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Template is the default config with every setting commented, written by tempo config init.
+// Its settings are the ones of Default.
+const Template string = `# Settings of tempo. The ones left out take their default value, which is the one below.
+# tempo config validate checks this file for mistakes before playing.
+
+[browser]
+  # Sort order of the files: name, mtime, duration, track, plays or rating, s cycles it
+  sort = "name"
+  # Reverse the sort order, S toggles it
+  sort_reverse = false
+  # Directories to jump to, ~ being the home directory. b bookmarks one and B lists them
+  bookmarks = [] # like ["~/Music", "/mnt/nas/music"]
+
+[library]
+  # Music directories scanned when tempo starts, like -library
+  dirs = [] # like ["~/Music", "/mnt/nas/music"]
+  # How many days back the recently added view looks
+  recent_days = 30
+  # Globs of the files and directories not scanned, relative to the library directories
+  ignore = [] # like ["**/.git/**", "*.cue", "backup"]
+  # Extensions of the files scanned, every supported one if empty
+  extensions = [] # like [".mp3", ".flac"]
+  # Estimate the tempo and key of the new and changed files while scanning
+  analyze = false
+
+[player]
+  # Volume in % the audio starts playing at the first run, like -vol. The next runs start at
+  # the volume of the last one
+  volume = 50
+  # How the album cover is drawn: auto, kitty, iterm2, sixel, blocks or off
+  cover = "auto"
+  # ReplayGain adjustment of the loudness: off, track or album, g cycles it
+  replaygain = "off"
+  # Measure the loudness of the files without ReplayGain tags, while a ReplayGain mode is on
+  normalize = false
+  # Resume every file from where it was left at, instead of offering it
+  audiobook = false
+  # Pause while the terminal is not focused and resume when it is again, like for podcasts
+  # while working, in the terminals reporting it
+  pause_on_blur = false
+  # Pause when the system goes to sleep, staying paused when it wakes up. Linux tells it before
+  # sleeping, the other systems only after waking up
+  pause_on_sleep = true
+  # Output device listed by tempo devices, the default one of the system if empty. The
+  # Chromecasts and DLNA renderers of the network are named like "cast:<id>" and "dlna:<id>"
+  device = ""
+  # Volume in % changed by each press of up or down, Shift changes it by 1
+  volume_step = 5
+  # Seconds moved by left or right, and by Shift or Ctrl with them
+  seek_seconds = 5
+  large_seek_seconds = 60
+  # Milliseconds of fade out when pausing or quitting and in when resuming, 0 to cut at once
+  fade_ms = 150
+  # Milliseconds of audio decoded ahead of the playback, up to 60000, 0 to turn it off
+  read_ahead_ms = 2000
+  # How the stretches of near-silence are played: off, skip or fast, z cycles it
+  silence = "off"
+  # Level in dBFS under which the audio is silent
+  silence_threshold_db = -50.0
+  # Milliseconds of each stretch of silence played as it is before skipping the rest
+  silence_ms = 1000
+  # Directory R saves the recordings of the playback to, as WAV files
+  record_dir = "~/Music/Recordings"
+
+[theme]
+  # Colors of the UI: dark, light, monochrome, gruvbox or the name of a palette
+  name = "dark"
+
+# Palettes are themes of your own, with hex colors like "#88c0d0" or ANSI ones from "0" to
+# "255". The ones left out are the ones of the dark theme
+#
+# [[theme.palette]]
+#   name = "nord"
+#   primary = "#88c0d0" # borders, prompts and the selected row
+#   secondary = "#81a1c1" # artists and ratings
+#   contrast = "#d08770" # the playback position and highlighted details
+#   problem = "#bf616a" # errors and the muted indicator
+#   grey = "#4c566a" # help and secondary details
+#   highlight = "#2e3440" # text over the colored backgrounds
+#   progress = "#eceff4" # played part of the progress bar, the text color if left out
+
+[equalizer]
+  # Curve played: flat, rock, classical, custom or the name of a profile, Enter in the E pane
+  # cycles them
+  curve = "flat"
+  # Gain in dB applied before the bands, from -12 to 12
+  preamp = 0.0
+  # 10 gains in dB of the bands from 31 Hz to 16 kHz, saved when a preset is changed
+  custom = []
+
+# Profiles are named curves, like one for each output
+#
+# [[equalizer.profile]]
+#   name = "headphones"
+#   gains = [3, 2, 1, 0, 0, 0, 0, 1, 2, 1]
+
+[lyrics]
+  # Providers asked in order for the lyrics of the tracks without local ones, like ["lrclib"]
+  providers = []
+
+[acoustid]
+  # Key of the application registered at https://acoustid.org/new-application
+  api_key = ""
+  # Identify the untagged files while scanning the library
+  scan = false
+
+[remote]
+  # Let the media keys and the media controls of the system control the player, and show them
+  # what is being played: through MPRIS on Linux, which playerctl uses too, the Now Playing
+  # center on macOS and the media flyout of the volume on Windows
+  media_keys = true
+  # Let tempo ctl control the player through a socket in the runtime directory
+  socket = true
+  # Enqueue the paths of tempo play into the tempo running already, instead of playing along
+  single_instance = false
+  # Address like "localhost:6600" where the MPD clients, like ncmpcpp, can control the player.
+  # Anyone reaching it can, so it is off by default
+  mpd = ""
+  # Address like ":8080" where the HTTP API is served while playing, off if empty, and the token
+  # its requests need in an "Authorization: Bearer <token>" header. tempo serve -http serves it
+  # too, with a random token if this one is empty
+  http = ""
+  http_token = ""
+  # Commands of tempo ctl run when tempo gets SIGUSR1 and SIGUSR2, like with pkill -USR1 tempo:
+  # play, pause, toggle, stop, next, previous or quit, the signal being ignored if empty. Not
+  # on Windows
+  sigusr1 = "toggle"
+  sigusr2 = "next"
+
+[discord]
+  # Show the track playing on the Discord profile, through its desktop app
+  enabled = false
+  # Id of an application registered at https://discord.com/developers/applications, whose name
+  # is shown as "Listening to <name>"
+  client_id = ""
+  # Show the album and the time played along with the title and the artist
+  show_album = true
+  show_elapsed = true
+  # Show the files without a title tag as an unknown track instead of by their file name
+  hide_file_names = true
+  # Directories whose files are never shown, like ["~/Audiobooks"]
+  exclude = []
+
+[hooks]
+  # Shell commands run when the playback changes, one at a time. What is being played is in the
+  # TEMPO_EVENT, TEMPO_STATE, TEMPO_PATH, TEMPO_TITLE, TEMPO_ARTIST, TEMPO_ALBUM, TEMPO_TRACK,
+  # TEMPO_DURATION and TEMPO_POSITION environment variables, like
+  # on_track_change = 'notify-send "$TEMPO_TITLE" "$TEMPO_ARTIST"'
+  on_track_change = ""
+  on_play = ""
+  on_pause = ""
+  # Also run when tempo quits while playing
+  on_stop = ""
+
+[now_playing]
+  # File kept with what is being played, for OBS text sources and status bars, off if empty.
+  # It is emptied while nothing is loaded and when tempo quits
+  file = ""
+  # Go template of its text, given the State, Path, Title, Artist, Album, Track, Position,
+  # Duration, Percent and Volume, like "{{.Title}} [{{.Position}}/{{.Duration}}]"
+  template = "{{if .Artist}}{{.Artist}} – {{end}}{{.Title}}"
+
+[snapcast]
+  # Snapserver source listed as the snapcast device, to play in sync in every room: the pipe
+  # it reads, like "/tmp/snapfifo", or "tcp://host:port" for a source with mode=server. Off if
+  # empty
+  source = ""
+  # Sample format of the source, the sampleformat of its stream in snapserver.conf
+  sample_format = "48000:16:2"
+
+# Keys of the actions replacing the default ones, an empty list disabling the action. The
+# actions are quit, play_pause, rewind, forward, rewind_large, forward_large, jump, volume_up,
+# volume_down, volume_up_fine, volume_down_fine, mute, speed_up, speed_down, pitch_up,
+# pitch_down, balance_right, balance_left, swap_channels, mono, next, previous, favorite,
+# copy_path, reveal, edit_tags, replaygain, silence, analyze, loop, resume, record, bookmark,
+# next_bookmark, previous_bookmark, next_chapter, previous_chapter, focus, scan, lyrics,
+# chapters, devices and equalizer
+#
+# [keys]
+#   rewind = ["left", "a"]
+#   forward = ["right", "d"]
+#   mute = []
+
+# Smart playlists are queries over the library, enqueued with -smart <name>
+#
+# [[smart_playlist]]
+#   name = "Forgotten jazz"
+#   query = 'genre = "jazz" AND rating >= 4 AND lastplayed > 30d'
+
+# Internet radios of the stations view of the browser, saved there with n
+#
+# [[station]]
+#   name = "Jazz Radio"
+#   url = "http://radio.example.com:8000/jazz"
+#   genres = ["jazz", "blues"]
+`
+
+// WriteTemplate writes the commented default config to path, failing with an error matching
+// fs.ErrExist if there is a file already, unless overwrite.
+func WriteTemplate(path string, overwrite bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(Template); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
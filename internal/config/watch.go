@@ -0,0 +1,145 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Time without changes to the config file before it is read again. Editors write it in
+// several steps, like to a temporary file renamed over it.
+const ReloadDebounce time.Duration = 300 * time.Millisecond
+
+// ChangedMsg reports the config file changed on disk, with the settings read from it or the
+// error reading them.
+type ChangedMsg struct {
+	Config *Config
+	Err    error
+}
+
+// Watcher : Watches the config file to read it again when it changes
+type Watcher struct {
+	fw   *fsnotify.Watcher
+	path string
+
+	events chan tea.Msg
+	done   chan struct{}
+}
+
+// NewWatcher watches the config file at path. Its directory is the one watched, since
+// editors replace the file instead of writing it, so it must exist.
+func NewWatcher(path string) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := fw.Add(filepath.Dir(path)); err != nil {
+		fw.Close()
+		return nil, err
+	}
+	return &Watcher{
+		fw:     fw,
+		path:   filepath.Clean(path),
+		events: make(chan tea.Msg, 1),
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Start watches in background and returns a command that waits for the first change.
+func (w *Watcher) Start() tea.Cmd {
+	go w.run()
+	return w.Wait()
+}
+
+// Wait returns a command that waits for the next ChangedMsg.
+func (w *Watcher) Wait() tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-w.events
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	select {
+	case <-w.done:
+		return nil
+	default:
+	}
+	close(w.done)
+	return w.fw.Close()
+}
+
+func (w *Watcher) run() {
+	defer close(w.events)
+
+	timer := time.NewTimer(ReloadDebounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+
+		case ev, ok := <-w.fw.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(ev.Name) == w.path && !ev.Has(fsnotify.Chmod) {
+				timer.Reset(ReloadDebounce)
+			}
+
+		case err, ok := <-w.fw.Errors:
+			if !ok {
+				return
+			}
+			if !w.send(ChangedMsg{Err: err}) {
+				return
+			}
+
+		case <-timer.C:
+			// A file removed for good leaves the settings as they are
+			if _, err := os.Stat(w.path); os.IsNotExist(err) {
+				continue
+			}
+			cfg, err := Load(w.path)
+			if err == nil {
+				// The variables were reported when tempo started
+				cfg.ApplyEnv(os.Environ())
+			}
+			if !w.send(ChangedMsg{Config: cfg, Err: err}) {
+				return
+			}
+		}
+	}
+}
+
+func (w *Watcher) send(msg ChangedMsg) bool {
+	select {
+	case w.events <- msg:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// Equal reports whether c and other have the same settings, like a config saved from the UI
+// and the one read back from its file.
+func (c *Config) Equal(other *Config) bool {
+	var a, b bytes.Buffer
+	if err := toml.NewEncoder(&a).Encode(c); err != nil {
+		return false
+	}
+	if err := toml.NewEncoder(&b).Encode(other); err != nil {
+		return false
+	}
+	return bytes.Equal(a.Bytes(), b.Bytes())
+}
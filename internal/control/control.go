@@ -0,0 +1,125 @@
+// Package control lets scripts control a running tempo through a Unix socket, which tempo ctl
+// talks to. Each request is a line of JSON with a command and its arguments, like
+// {"command":"seek","args":["+30"]}, answered with a line of JSON.
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/remote"
+	"github.com/nicolito128/tempo/internal/xdg"
+)
+
+// File name of the socket inside the runtime directory
+const SocketName string = "tempo.sock"
+
+// Time given to the running tempo to answer
+const timeout = 5 * time.Second
+
+// Commands : The commands of the socket and their arguments, in the order they are listed
+var Commands = []struct {
+	Name, Args, Summary string
+}{
+	{"play", "[path]", "Resume the playback, or play a file"},
+	{"pause", "", "Pause the playback"},
+	{"toggle", "", "Pause or resume the playback"},
+	{"stop", "", "Pause the playback and go back to the start"},
+	{"next", "", "Play the next file of the queue"},
+	{"previous", "", "Play the previous file of the queue"},
+	{"seek", "<[+|-]position>", "Seek to a position like 1:30, or by an offset like +30"},
+	{"volume", "<[+|-]percent>", "Set the volume, or change it by an offset like -5"},
+	{"add", "<path>...", "Add files, directories and playlists to the queue"},
+	{"status", "[--format <format>]", "Print what is being played, as json, waybar or plain with --format"},
+	{"quit", "", "Quit tempo"},
+}
+
+// Request : A command sent to the running tempo
+type Request struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// Response : The answer to a request, with Error set if it failed
+type Response struct {
+	Error  string  `json:"error,omitempty"`
+	Status *Status `json:"status,omitempty"`
+}
+
+// Status : What is being played, as answered to the status command
+type Status struct {
+	// State is playing, paused or stopped
+	State  string `json:"state"`
+	Path   string `json:"path,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Artist string `json:"artist,omitempty"`
+	Album  string `json:"album,omitempty"`
+	Track  int    `json:"track,omitempty"`
+	// Position and Duration in seconds, the duration being 0 for a Live stream
+	Position float64 `json:"position"`
+	Duration float64 `json:"duration"`
+	Live     bool    `json:"live,omitempty"`
+	Volume   int     `json:"volume"`
+	Muted    bool    `json:"muted"`
+	Speed    float64 `json:"speed"`
+	Repeat   bool    `json:"repeat"`
+}
+
+// NewStatus returns the status of the remotes as answered to the status command.
+func NewStatus(st remote.Status) *Status {
+	return &Status{
+		State:    strings.ToLower(st.State.String()),
+		Path:     st.Path,
+		Title:    st.Title,
+		Artist:   st.Artist,
+		Album:    st.Album,
+		Track:    st.Track,
+		Position: st.Position.Seconds(),
+		Duration: st.Length.Seconds(),
+		Live:     st.Live,
+		Volume:   st.Volume,
+		Muted:    st.Muted,
+		Speed:    st.Speed,
+		Repeat:   st.Repeat,
+	}
+}
+
+// SocketPath returns the location of the socket inside the runtime directory.
+func SocketPath() (string, error) {
+	dir, err := xdg.RuntimeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, SocketName), nil
+}
+
+// ErrNotRunning is returned by Send when no tempo is listening at the socket.
+var ErrNotRunning = errors.New("tempo is not running")
+
+// Send sends a request to the tempo listening at the socket at path, failing with the error of
+// the response if it has one.
+func Send(path string, req Request) (Response, error) {
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return Response{}, ErrNotRunning
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, err
+	}
+	var resp Response
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&resp); err != nil {
+		return Response{}, err
+	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
@@ -0,0 +1,218 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/components/player"
+	"github.com/nicolito128/tempo/internal/remote"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// Server : The socket of a running tempo, turning the requests into remote messages
+type Server struct {
+	listener net.Listener
+	send     func(tea.Msg)
+
+	// Status told last by the UI
+	mu     sync.Mutex
+	status remote.Status
+
+	closeOnce sync.Once
+}
+
+var _ remote.Remote = (*Server)(nil)
+
+// Listen creates the socket at path and answers the requests on it in background, sending the
+// commands to the program with send. It fails if another tempo is listening there already.
+func Listen(path string, send func(tea.Msg)) (*Server, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	// A socket nobody answers on was left by a tempo that did not quit cleanly
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("another tempo is listening at %s", path)
+	}
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// Only the user can control their player
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	s := &Server{listener: listener, send: send}
+	go s.accept()
+	return s, nil
+}
+
+// Update keeps the status to answer the status requests with.
+func (s *Server) Update(st remote.Status) {
+	s.mu.Lock()
+	s.status = st
+	s.mu.Unlock()
+}
+
+// Close stops listening and removes the socket.
+func (s *Server) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		err = s.listener.Close()
+	})
+	return err
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			slog.Warn("Cannot accept a control connection", "err", err)
+			continue
+		}
+		go s.serve(conn)
+	}
+}
+
+// serve answers the requests of a connection until it is closed.
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	enc := json.NewEncoder(conn)
+	for {
+		var req Request
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		resp, err := s.handle(req)
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// handle does a request.
+func (s *Server) handle(req Request) (Response, error) {
+	s.mu.Lock()
+	st := s.status
+	s.mu.Unlock()
+
+	if command, err := remote.ParseCommand(req.Command); err == nil {
+		if len(req.Args) > 0 && !(req.Command == "play" && len(req.Args) == 1) {
+			return Response{}, fmt.Errorf("%s takes no arguments", req.Command)
+		}
+		var msg tea.Msg = remote.CommandMsg{Command: command}
+		if len(req.Args) == 1 {
+			path, err := checkPath(req.Args[0])
+			if err != nil {
+				return Response{}, err
+			}
+			msg = remote.OpenMsg{Path: path}
+		}
+		s.send(msg)
+		return Response{}, nil
+	}
+
+	switch req.Command {
+	case "seek":
+		if len(req.Args) != 1 {
+			return Response{}, errors.New("usage: seek <[+|-]position>")
+		}
+		if st.Path == "" {
+			return Response{}, errors.New("nothing is being played")
+		}
+		if st.Live {
+			return Response{}, engine.ErrLive
+		}
+		arg := req.Args[0]
+		sign := arg[:min(len(arg), 1)]
+		pos, err := player.ParseTimestamp(strings.TrimLeft(arg, "+-"))
+		if err != nil {
+			return Response{}, err
+		}
+		switch sign {
+		case "+":
+			s.send(remote.SeekMsg{Offset: pos})
+		case "-":
+			s.send(remote.SeekMsg{Offset: -pos})
+		default:
+			if pos > st.Length {
+				return Response{}, fmt.Errorf("%s is past the end of the file", arg)
+			}
+			s.send(remote.SetPositionMsg{Path: st.Path, Position: pos})
+		}
+
+	case "volume":
+		if len(req.Args) != 1 {
+			return Response{}, errors.New("usage: volume <[+|-]percent>")
+		}
+		arg := req.Args[0]
+		volume, err := strconv.Atoi(strings.TrimLeft(arg, "+-"))
+		if err != nil || volume < 0 {
+			return Response{}, fmt.Errorf("invalid volume %q", arg)
+		}
+		switch arg[0] {
+		case '+':
+			volume = st.Volume + volume
+		case '-':
+			volume = st.Volume - volume
+		}
+		s.send(remote.SetVolumeMsg{Volume: max(min(volume, 100), 0)})
+
+	case "add":
+		if len(req.Args) == 0 {
+			return Response{}, errors.New("usage: add <path>...")
+		}
+		paths := make([]string, 0, len(req.Args))
+		for _, arg := range req.Args {
+			path, err := checkPath(arg)
+			if err != nil {
+				return Response{}, err
+			}
+			paths = append(paths, path)
+		}
+		s.send(remote.EnqueueMsg{Paths: paths})
+
+	case "status":
+		return Response{Status: NewStatus(st)}, nil
+
+	default:
+		return Response{}, fmt.Errorf("unknown command %q", req.Command)
+	}
+	return Response{}, nil
+}
+
+// checkPath returns the path of a request if it exists. It must be absolute, since tempo runs
+// in another directory than the client, or a URL.
+func checkPath(path string) (string, error) {
+	if engine.IsURL(path) {
+		return path, nil
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("%s is not an absolute path", path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,122 @@
+// Package crash records the panics of the TUI, so tempo can save what was being played
+// after Bubble Tea restores the terminal, and writes a report of them to send with a bug.
+package crash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/xdg"
+)
+
+// Guard : A model recording the first panic of the model it wraps and of its commands,
+// which keep panicking so Bubble Tea restores the terminal and quits
+type Guard struct {
+	model tea.Model
+
+	mu    sync.Mutex
+	value any
+	stack []byte
+}
+
+var _ tea.Model = (*Guard)(nil)
+
+// NewGuard wraps the model run by the program.
+func NewGuard(model tea.Model) *Guard {
+	return &Guard{model: model}
+}
+
+func (g *Guard) Init() tea.Cmd {
+	defer g.record()
+	return g.wrap(g.model.Init())
+}
+
+func (g *Guard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	defer g.record()
+	model, cmd := g.model.Update(msg)
+	g.model = model
+	return g, g.wrap(cmd)
+}
+
+func (g *Guard) View() string {
+	defer g.record()
+	return g.model.View()
+}
+
+// wrap records the panics of cmd and of the commands of the batch it returns. The ones of a
+// sequence are only recovered by Bubble Tea.
+func (g *Guard) wrap(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		defer g.record()
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			wrapped := make(tea.BatchMsg, len(batch))
+			for i, c := range batch {
+				wrapped[i] = g.wrap(c)
+			}
+			return wrapped
+		}
+		return msg
+	}
+}
+
+// record keeps the value and the stack of a panic, and panics again. It must be deferred.
+func (g *Guard) record() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	g.mu.Lock()
+	if g.value == nil {
+		g.value, g.stack = r, debug.Stack()
+	}
+	g.mu.Unlock()
+	panic(r)
+}
+
+// Panic returns the value and the stack of the first panic, if there was one.
+func (g *Guard) Panic() (value any, stack []byte, ok bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.value, g.stack, g.value != nil
+}
+
+// WriteReport writes the report of a panic to a new file in the state directory, returning
+// its path. Without a stack the panic is one that only Bubble Tea recovered from.
+func WriteReport(value any, stack []byte) (string, error) {
+	dir, err := xdg.StateDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	var b strings.Builder
+	fmt.Fprintf(&b, "tempo crashed at %s\n\n", now.Format(time.RFC3339))
+	version := "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		version = info.Main.Version
+	}
+	fmt.Fprintf(&b, "Version: %s, %s %s/%s\n", version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "Command: %s\n\n", strings.Join(os.Args, " "))
+	if stack == nil {
+		fmt.Fprintln(&b, "panic in a background command, its stack was printed to the terminal")
+	} else {
+		fmt.Fprintf(&b, "panic: %v\n\n%s", value, stack)
+	}
+
+	path := filepath.Join(dir, "crash-"+now.Format("20060102-150405")+".txt")
+	return path, os.WriteFile(path, []byte(b.String()), 0o644)
+}
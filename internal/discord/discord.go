@@ -0,0 +1,241 @@
+// Package discord shows the track tempo plays on the Discord profile of the user, as a
+// "Listening to" Rich Presence set through the IPC socket of the desktop app. Discord does not
+// need to be running: tempo connects whenever it is, and the presence goes away with tempo.
+package discord
+
+import (
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nicolito128/tempo/internal/remote"
+)
+
+const (
+	// Least time between two activities sent, since Discord takes 5 every 20 seconds
+	sendInterval = 4 * time.Second
+	// Time between the attempts to connect, while Discord is not running
+	dialInterval = 15 * time.Second
+
+	// Title of the files without a title tag, with HideFileNames
+	unknownTrack = "Unknown track"
+)
+
+// Options : What the presence shows
+type Options struct {
+	// ClientID of the application whose name is shown as "Listening to <name>"
+	ClientID string
+	// ShowAlbum and ShowElapsed if the album and the time played are shown along with the
+	// title and the artist
+	ShowAlbum   bool
+	ShowElapsed bool
+	// HideFileNames if the files without a title tag are shown as an unknown track
+	HideFileNames bool
+	// Exclude are directories whose files are never shown
+	Exclude []string
+}
+
+// Presence : The Rich Presence of tempo
+//
+// The UI tells it the status, and a goroutine sets the activity when the track, the playback
+// or its position change, connecting to Discord when needed.
+type Presence struct {
+	opts Options
+
+	// Status not shown yet, the newest one told
+	mu      sync.Mutex
+	pending remote.Status
+	wake    chan struct{}
+	done    chan struct{}
+
+	// Status shown last and when, and whether it still has to be sent
+	shown   remote.Status
+	shownAt time.Time
+	dirty   bool
+
+	conn     *conn
+	sentAt   time.Time
+	dialedAt time.Time
+	// cleared if no activity is shown on the connection
+	cleared bool
+}
+
+var _ remote.Remote = (*Presence)(nil)
+
+// New returns the presence showing the status told with opts.
+func New(opts Options) (*Presence, error) {
+	if opts.ClientID == "" {
+		return nil, errors.New("the Discord presence needs client_id in the config")
+	}
+	p := &Presence{
+		opts: opts,
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	go p.run()
+	return p, nil
+}
+
+// Update shows the status on Discord in background.
+func (p *Presence) Update(st remote.Status) {
+	p.mu.Lock()
+	p.pending = st
+	p.mu.Unlock()
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close disconnects from Discord, which removes the presence.
+func (p *Presence) Close() error {
+	select {
+	case <-p.done:
+	default:
+		close(p.done)
+	}
+	return nil
+}
+
+func (p *Presence) run() {
+	timer := time.NewTimer(0)
+	<-timer.C
+	defer func() {
+		timer.Stop()
+		if p.conn != nil {
+			p.conn.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-p.wake:
+			p.mu.Lock()
+			st := p.pending
+			p.mu.Unlock()
+			p.show(st)
+		case <-timer.C:
+		}
+		if !p.dirty {
+			continue
+		}
+		if wait := p.flush(); wait > 0 {
+			timer.Reset(wait)
+		}
+	}
+}
+
+// show marks the status to be sent if what Discord shows of it changed.
+func (p *Presence) show(st remote.Status) {
+	prev, now := p.shown, time.Now()
+	seeked := st.Seeked(prev, now.Sub(p.shownAt))
+	p.shown, p.shownAt = st, now
+	if seeked || st.Path != prev.Path || st.State != prev.State || st.Speed != prev.Speed ||
+		st.Title != prev.Title || st.Artist != prev.Artist || st.Album != prev.Album {
+		p.dirty = true
+	}
+}
+
+// flush sends the activity of the status shown last, returning how long to wait before
+// trying again if it cannot be sent yet.
+func (p *Presence) flush() time.Duration {
+	now := time.Now()
+	if since := now.Sub(p.sentAt); since < sendInterval {
+		return sendInterval - since
+	}
+	if p.conn == nil {
+		if since := now.Sub(p.dialedAt); since < dialInterval {
+			return dialInterval - since
+		}
+		p.dialedAt = now
+		c, err := dial(p.opts.ClientID)
+		if err != nil {
+			// Discord is not running, most of the time
+			slog.Debug("Cannot connect to Discord", "err", err)
+			return dialInterval
+		}
+		// A new connection shows nothing until told
+		p.conn, p.cleared = c, true
+	}
+
+	act := p.activity(p.shown, p.shownAt)
+	if act == nil && p.cleared {
+		p.dirty = false
+		return 0
+	}
+	if err := p.conn.setActivity(act); err != nil {
+		slog.Warn("Cannot set the Discord presence", "err", err)
+		p.conn.Close()
+		p.conn = nil
+		return dialInterval
+	}
+	p.dirty, p.sentAt, p.cleared = false, now, act == nil
+	return 0
+}
+
+// activity returns what Discord shows of the status told at, nil to show nothing.
+func (p *Presence) activity(st remote.Status, at time.Time) *activity {
+	if st.Path == "" || st.State != remote.Playing || p.excluded(st.Path) {
+		return nil
+	}
+
+	title := st.Title
+	name := filepath.Base(st.Path)
+	if p.opts.HideFileNames && (title == name || title == strings.TrimSuffix(name, filepath.Ext(name))) {
+		title = unknownTrack
+	}
+	state := st.Artist
+	if p.opts.ShowAlbum && st.Album != "" {
+		state = strings.TrimPrefix(state+" – "+st.Album, " – ")
+	}
+	act := &activity{Type: activityListening, Details: field(title), State: field(state)}
+
+	if p.opts.ShowElapsed && st.Speed > 0 {
+		// The times are told as Discord sees them pass, faster or slower than the audio
+		start := at.Add(-time.Duration(float64(st.Position) / st.Speed))
+		act.Timestamps = &timestamps{Start: start.UnixMilli()}
+		if st.Length > 0 {
+			act.Timestamps.End = start.Add(time.Duration(float64(st.Length) / st.Speed)).UnixMilli()
+		}
+	}
+	return act
+}
+
+// excluded reports whether the file at path is inside one of the excluded directories.
+func (p *Presence) excluded(path string) bool {
+	for _, dir := range p.opts.Exclude {
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Shortest and longest texts of the activity Discord takes
+const (
+	minField = 2
+	maxField = 128
+)
+
+// field returns s cut to the length Discord takes, padded if too short and empty if there is
+// nothing.
+func field(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	if utf8.RuneCountInString(s) > maxField {
+		s = string([]rune(s)[:maxField-1]) + "…"
+	}
+	for utf8.RuneCountInString(s) < minField {
+		s += " "
+	}
+	return s
+}
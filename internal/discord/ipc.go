@@ -0,0 +1,145 @@
+package discord
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync/atomic"
+)
+
+// Opcodes of the frames of the IPC protocol
+const (
+	opHandshake = 0
+	opFrame     = 1
+	opClose     = 2
+	opPing      = 3
+	opPong      = 4
+)
+
+// Largest frame read, far more than the answers of Discord
+const maxFrame = 1 << 20
+
+// Type of the activities shown as "Listening to"
+const activityListening = 2
+
+// activity : What the profile shows, as set with SET_ACTIVITY
+type activity struct {
+	Type       int         `json:"type"`
+	Details    string      `json:"details,omitempty"`
+	State      string      `json:"state,omitempty"`
+	Timestamps *timestamps `json:"timestamps,omitempty"`
+}
+
+// timestamps : The start and end of the track in Unix milliseconds, shown as a progress bar
+type timestamps struct {
+	Start int64 `json:"start,omitempty"`
+	End   int64 `json:"end,omitempty"`
+}
+
+// conn : A connection to the desktop app, through a Unix socket or a named pipe
+type conn struct {
+	io.ReadWriteCloser
+}
+
+// Nonce of the last command sent, each one needing its own
+var nonce atomic.Int64
+
+// dial connects to the desktop app, introducing tempo as the application of clientID.
+func dial(clientID string) (*conn, error) {
+	rwc, err := open()
+	if err != nil {
+		return nil, err
+	}
+	c := &conn{rwc}
+	if err := c.write(opHandshake, map[string]any{"v": 1, "client_id": clientID}); err != nil {
+		c.Close()
+		return nil, err
+	}
+	// The READY event, or a close frame if the id is wrong
+	if _, err := c.read(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// setActivity shows the activity, or nothing if it is nil.
+func (c *conn) setActivity(act *activity) error {
+	err := c.write(opFrame, map[string]any{
+		"cmd":   "SET_ACTIVITY",
+		"args":  map[string]any{"pid": os.Getpid(), "activity": act},
+		"nonce": strconv.FormatInt(nonce.Add(1), 10),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.read()
+	return err
+}
+
+// write sends a frame with the payload encoded as JSON.
+func (c *conn) write(op uint32, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, [2]uint32{op, uint32(len(data))})
+	buf.Write(data)
+	_, err = c.Write(buf.Bytes())
+	return err
+}
+
+// response : The payload of the frames Discord sends
+type response struct {
+	Evt  string `json:"evt"`
+	Data struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"data"`
+	// Code and Message of the close frames
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// read returns the next frame answering a command, failing if it is an error or closes the
+// connection. Pings are answered on the way.
+func (c *conn) read() (*response, error) {
+	for {
+		var header [2]uint32
+		if err := binary.Read(c, binary.LittleEndian, &header); err != nil {
+			return nil, err
+		}
+		op, size := header[0], header[1]
+		if size > maxFrame {
+			return nil, fmt.Errorf("frame of %d bytes is too large", size)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(c, data); err != nil {
+			return nil, err
+		}
+
+		var resp response
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.write(opPong, json.RawMessage(data)); err != nil {
+				return nil, err
+			}
+		case opClose:
+			return nil, fmt.Errorf("discord closed the connection: %s (%d)", resp.Message, resp.Code)
+		case opFrame:
+			if resp.Evt == "ERROR" {
+				return nil, errors.New(resp.Data.Message)
+			}
+			return &resp, nil
+		}
+	}
+}
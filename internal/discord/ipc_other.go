@@ -0,0 +1,39 @@
+//go:build !windows
+
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// Directories inside the temporary ones where the sandboxed apps create their socket
+var sandboxes = []string{"", "app/com.discordapp.Discord", "snap.discord", ".flatpak/com.discordapp.Discord/xdg-run"}
+
+// open connects to the socket of the desktop app, which is numbered from 0 to 9 in the
+// runtime or temporary directory.
+func open() (io.ReadWriteCloser, error) {
+	var dirs []string
+	for _, env := range []string{"XDG_RUNTIME_DIR", "TMPDIR", "TMP", "TEMP"} {
+		if dir := os.Getenv(env); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	dirs = append(dirs, "/tmp")
+
+	for _, dir := range dirs {
+		for _, sandbox := range sandboxes {
+			for i := range 10 {
+				path := filepath.Join(dir, sandbox, fmt.Sprintf("discord-ipc-%d", i))
+				if c, err := net.Dial("unix", path); err == nil {
+					return c, nil
+				}
+			}
+		}
+	}
+	return nil, errors.New("discord is not running")
+}
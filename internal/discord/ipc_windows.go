@@ -0,0 +1,18 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// open connects to the named pipe of the desktop app, which is numbered from 0 to 9.
+func open() (io.ReadWriteCloser, error) {
+	for i := range 10 {
+		if f, err := os.OpenFile(fmt.Sprintf(`\\.\pipe\discord-ipc-%d`, i), os.O_RDWR, 0); err == nil {
+			return f, nil
+		}
+	}
+	return nil, errors.New("discord is not running")
+}
@@ -0,0 +1,74 @@
+package dsp
+
+import (
+	"math"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// ReadMono reads up to n samples of the streamer, mixing its channels.
+func ReadMono(s beep.Streamer, n int) ([]float64, error) {
+	out := make([]float64, 0, n)
+	buf := make([][2]float64, 4096)
+	for len(out) < n {
+		read, ok := s.Stream(buf[:min(len(buf), n-len(out))])
+		for _, sample := range buf[:read] {
+			out = append(out, (sample[0]+sample[1])/2)
+		}
+		if !ok {
+			break
+		}
+	}
+	return out, s.Err()
+}
+
+// Resample converts the samples from one rate to another with a windowed sinc filter,
+// which also removes the frequencies above the new Nyquist frequency.
+func Resample(in []float64, from, to int) []float64 {
+	if from == to || len(in) == 0 {
+		return in
+	}
+
+	ratio := float64(to) / float64(from)
+	cutoff := 0.8 * min(ratio, 1)
+	half := int(math.Ceil(8 / cutoff))
+
+	// The filter is sampled finely once and interpolated for each output sample
+	const steps = 64
+	kernel := make([]float64, half*steps+2)
+	for i := range kernel {
+		x := float64(i) / steps
+		if x >= float64(half) {
+			break
+		}
+		window := 0.42 + 0.5*math.Cos(math.Pi*x/float64(half)) + 0.08*math.Cos(2*math.Pi*x/float64(half))
+		kernel[i] = cutoff * sinc(cutoff*x) * window
+	}
+	weight := func(x float64) float64 {
+		pos := math.Abs(x) * steps
+		i := int(pos)
+		frac := pos - float64(i)
+		return kernel[i]*(1-frac) + kernel[i+1]*frac
+	}
+
+	out := make([]float64, int(float64(len(in))*ratio))
+	for i := range out {
+		center := float64(i) / ratio
+		first := max(int(math.Ceil(center))-half, 0)
+		last := min(int(center)+half, len(in)-1)
+
+		var sum float64
+		for j := first; j <= last; j++ {
+			sum += in[j] * weight(float64(j)-center)
+		}
+		out[i] = sum
+	}
+	return out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
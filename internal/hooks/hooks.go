@@ -0,0 +1,191 @@
+// Package hooks runs the shell commands of the config when the playback changes: a track
+// starts, it is paused or resumed, or the playback stops. What is being played is given to
+// them in TEMPO_* environment variables, so scripts can notify, scrobble or log it.
+package hooks
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/remote"
+)
+
+// Event : A change of the playback running a command
+type Event string
+
+const (
+	TrackChange Event = "track_change"
+	Pause       Event = "pause"
+	Play        Event = "play"
+	Stop        Event = "stop"
+)
+
+const (
+	// Longest time a command runs before being killed, since they run one at a time
+	timeout = time.Minute
+	// Most events waiting for the commands before them, the newer ones being dropped
+	backlog = 16
+	// Longest time Close waits for the commands to end
+	closeWait = 2 * time.Second
+)
+
+// Hooks : The commands run on the events, taking the place of a remote
+type Hooks struct {
+	commands map[Event]string
+
+	// Status told last, to tell the events from, and whether a file started since the last
+	// stop
+	mu      sync.Mutex
+	prev    remote.Status
+	started bool
+	closed  bool
+
+	runs chan run
+	done chan struct{}
+}
+
+var _ remote.Remote = (*Hooks)(nil)
+
+// run : A command to run with the status of its event
+type run struct {
+	event   Event
+	command string
+	status  remote.Status
+}
+
+// New returns the hooks running the commands, in the shell, of the events they are set for.
+func New(commands map[Event]string) *Hooks {
+	h := &Hooks{
+		commands: make(map[Event]string),
+		runs:     make(chan run, backlog),
+		done:     make(chan struct{}),
+	}
+	for event, command := range commands {
+		if command != "" {
+			h.commands[event] = command
+		}
+	}
+	go h.loop()
+	return h
+}
+
+// Update runs the commands of the events between the previous status and st.
+func (h *Hooks) Update(st remote.Status) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	prev := h.prev
+	h.prev = st
+	for _, event := range events(prev, st) {
+		h.started = event != Stop
+		h.queue(event, st)
+	}
+}
+
+// Close runs the stop command if something was being played, and waits a little for the
+// commands to end.
+func (h *Hooks) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	if st := h.prev; h.started && st.Path != "" {
+		st.State = remote.Stopped
+		h.queue(Stop, st)
+	}
+	close(h.runs)
+	h.mu.Unlock()
+
+	select {
+	case <-h.done:
+	case <-time.After(closeWait):
+	}
+	return nil
+}
+
+// events returns the events from prev to st.
+func events(prev, st remote.Status) []Event {
+	wasPlaying := prev.Path != "" && prev.State != remote.Stopped
+	playing := st.Path != "" && st.State != remote.Stopped
+	switch {
+	case playing && (st.Path != prev.Path || !wasPlaying && st.State == remote.Playing):
+		if st.State == remote.Paused {
+			// Loaded paused, like when restoring the session
+			return []Event{TrackChange, Pause}
+		}
+		return []Event{TrackChange}
+	case wasPlaying && !playing && (st.Path == "" || !st.HasNext):
+		// A file ending before the next one starts is not a stop
+		return []Event{Stop}
+	case playing && prev.State == remote.Playing && st.State == remote.Paused:
+		return []Event{Pause}
+	case playing && prev.State == remote.Paused && st.State == remote.Playing:
+		return []Event{Play}
+	}
+	return nil
+}
+
+// queue runs the command of the event after the ones before it, if it has one.
+func (h *Hooks) queue(event Event, st remote.Status) {
+	command, ok := h.commands[event]
+	if !ok {
+		return
+	}
+	select {
+	case h.runs <- run{event, command, st}:
+	default:
+		slog.Warn("Skipping a hook, too many are running", "event", event)
+	}
+}
+
+// loop runs the commands one at a time, so they see the events in order.
+func (h *Hooks) loop() {
+	defer close(h.done)
+	for r := range h.runs {
+		if err := r.run(); err != nil {
+			slog.Warn("The hook failed", "event", r.event, "command", r.command, "err", err)
+		}
+	}
+}
+
+// run runs the command in the shell, without its output messing with the terminal.
+func (r run) run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", r.command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", r.command)
+	}
+	cmd.Env = append(os.Environ(), env(r.event, r.status)...)
+	return cmd.Run()
+}
+
+// env returns the environment variables telling the command of the event what is being
+// played.
+func env(event Event, st remote.Status) []string {
+	return []string{
+		"TEMPO_EVENT=" + string(event),
+		"TEMPO_STATE=" + strings.ToLower(st.State.String()),
+		"TEMPO_PATH=" + st.Path,
+		"TEMPO_TITLE=" + st.Title,
+		"TEMPO_ARTIST=" + st.Artist,
+		"TEMPO_ALBUM=" + st.Album,
+		"TEMPO_TRACK=" + strconv.Itoa(st.Track),
+		"TEMPO_DURATION=" + strconv.Itoa(int(st.Length.Seconds())),
+		"TEMPO_POSITION=" + strconv.Itoa(int(st.Position.Seconds())),
+	}
+}
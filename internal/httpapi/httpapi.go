@@ -0,0 +1,351 @@
+// Package httpapi serves a JSON API over HTTP to control tempo from other devices: its status,
+// the queue, the playback and a search of the library. Every request needs the token of the
+// server in an "Authorization: Bearer <token>" header. A web remote using the API is served
+// at the root, for the phones without an app.
+package httpapi
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/control"
+	"github.com/nicolito128/tempo/internal/library"
+	"github.com/nicolito128/tempo/internal/remote"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// Most results of a library search, unless a limit is given
+const defaultLimit = 50
+
+// Page of the web remote, asking for the token itself
+//
+//go:embed web/index.html
+var remotePage []byte
+
+// Server : The HTTP API of a running tempo, turning the requests into remote messages
+type Server struct {
+	token   string
+	send    func(tea.Msg)
+	library *library.Library
+	srv     *http.Server
+
+	mu     sync.Mutex
+	status remote.Status
+}
+
+var _ remote.Remote = (*Server)(nil)
+
+// New returns the API answering the requests with token, sending the commands to the program
+// with send and searching lib.
+func New(token string, send func(tea.Msg), lib *library.Library) *Server {
+	s := &Server{token: token, send: send, library: lib, status: remote.Status{Index: -1}}
+	s.srv = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	return s
+}
+
+// NewToken returns a random token, for when none is configured.
+func NewToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Listen serves the API at address, like :8080, in background.
+func (s *Server) Listen(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := s.srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			slog.Warn("The HTTP API stopped", "err", err)
+		}
+	}()
+	return nil
+}
+
+// Update keeps the status to answer with.
+func (s *Server) Update(st remote.Status) {
+	s.mu.Lock()
+	s.status = st
+	s.mu.Unlock()
+}
+
+// Close stops serving the API.
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+// Handler returns the handler of the API and the web remote.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(remotePage)
+	})
+	mux.Handle("/api/", s.authorize(s.api()))
+	return mux
+}
+
+// api returns the handler of the API, without checking the token.
+func (s *Server) api() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/status", s.getStatus)
+	mux.HandleFunc("GET /api/queue", s.getQueue)
+	mux.HandleFunc("POST /api/queue", s.postQueue)
+	mux.HandleFunc("DELETE /api/queue", s.deleteQueue)
+	mux.HandleFunc("POST /api/queue/{index}/play", s.playIndex)
+	mux.HandleFunc("DELETE /api/queue/{index}", s.deleteIndex)
+	for name, c := range commands {
+		mux.HandleFunc("POST /api/"+name, func(w http.ResponseWriter, r *http.Request) {
+			s.send(remote.CommandMsg{Command: c})
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+	mux.HandleFunc("POST /api/seek", s.seek)
+	mux.HandleFunc("POST /api/volume", s.volume)
+	mux.HandleFunc("GET /api/library", s.search)
+	return mux
+}
+
+// Commands without arguments, by the last element of their path
+var commands = map[string]remote.Command{
+	"play":     remote.Play,
+	"pause":    remote.Pause,
+	"toggle":   remote.PlayPause,
+	"stop":     remote.Stop,
+	"next":     remote.Next,
+	"previous": remote.Previous,
+}
+
+// authorize answers the requests without the token with 401 Unauthorized.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("missing or wrong token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// current returns the status told last.
+func (s *Server) current() remote.Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+func (s *Server) getStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, control.NewStatus(s.current()))
+}
+
+// queueItem : A file of the queue, as answered by GET /api/queue
+type queueItem struct {
+	Path   string `json:"path"`
+	Title  string `json:"title"`
+	Artist string `json:"artist,omitempty"`
+	Album  string `json:"album,omitempty"`
+	Track  int    `json:"track,omitempty"`
+}
+
+func (s *Server) getQueue(w http.ResponseWriter, r *http.Request) {
+	st := s.current()
+	items := make([]queueItem, len(st.Queue))
+	for i, item := range st.Queue {
+		items[i] = queueItem(item)
+	}
+	writeJSON(w, struct {
+		// Index of the current file, -1 if there is none
+		Index int         `json:"index"`
+		Items []queueItem `json:"items"`
+	}{st.Index, items})
+}
+
+// postQueue enqueues the absolute paths of the body, like {"paths": ["/music/album"]}.
+func (s *Server) postQueue(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Paths []string `json:"paths"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if len(body.Paths) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("no paths given"))
+		return
+	}
+	for _, path := range body.Paths {
+		if engine.IsURL(path) {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("%s is not an absolute path", path))
+			return
+		}
+		if _, err := os.Stat(path); err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+	}
+	s.send(remote.EnqueueMsg{Paths: body.Paths})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteQueue(w http.ResponseWriter, r *http.Request) {
+	s.send(remote.ClearMsg{})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// queueIndex returns the index of the path of the request, answering with an error if it
+// is not in the queue.
+func (s *Server) queueIndex(w http.ResponseWriter, r *http.Request) (int, remote.Status, bool) {
+	st := s.current()
+	i, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil || i < 0 || i >= len(st.Queue) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("there is no file at index %s of the queue", r.PathValue("index")))
+		return 0, st, false
+	}
+	return i, st, true
+}
+
+func (s *Server) playIndex(w http.ResponseWriter, r *http.Request) {
+	if i, _, ok := s.queueIndex(w, r); ok {
+		s.send(remote.PlayIndexMsg{Index: i})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) deleteIndex(w http.ResponseWriter, r *http.Request) {
+	if i, st, ok := s.queueIndex(w, r); ok {
+		s.send(remote.RemoveMsg{Path: st.Queue[i].Path})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// seek moves the playback to the position of the body in seconds, like {"position": 90}, or
+// by its offset, like {"offset": -10}.
+func (s *Server) seek(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Position *float64 `json:"position"`
+		Offset   *float64 `json:"offset"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	st := s.current()
+	if st.Path == "" {
+		writeError(w, http.StatusConflict, errors.New("nothing is being played"))
+		return
+	}
+	if st.Live {
+		writeError(w, http.StatusConflict, engine.ErrLive)
+		return
+	}
+
+	switch {
+	case body.Position != nil && body.Offset == nil:
+		pos := time.Duration(*body.Position * float64(time.Second))
+		if pos < 0 || pos > st.Length {
+			writeError(w, http.StatusBadRequest, errors.New("the position is out of the file"))
+			return
+		}
+		s.send(remote.SetPositionMsg{Path: st.Path, Position: pos})
+	case body.Offset != nil && body.Position == nil:
+		s.send(remote.SeekMsg{Offset: time.Duration(*body.Offset * float64(time.Second))})
+	default:
+		writeError(w, http.StatusBadRequest, errors.New("either position or offset must be given"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// volume sets the volume of the body, from 0 to 100, like {"volume": 40}.
+func (s *Server) volume(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Volume *int `json:"volume"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if body.Volume == nil || *body.Volume < 0 || *body.Volume > 100 {
+		writeError(w, http.StatusBadRequest, errors.New("the volume must be between 0 and 100"))
+		return
+	}
+	s.send(remote.SetVolumeMsg{Volume: *body.Volume})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// track : A track of the library, as answered by GET /api/library
+type track struct {
+	Path   string `json:"path"`
+	Title  string `json:"title"`
+	Artist string `json:"artist,omitempty"`
+	Album  string `json:"album,omitempty"`
+	// Duration in seconds
+	Duration float64 `json:"duration"`
+}
+
+// search answers the tracks of the library best matching the q parameter, at most limit.
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("the q parameter is missing"))
+		return
+	}
+	limit := defaultLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, errors.New("the limit must be a positive number"))
+			return
+		}
+		limit = n
+	}
+
+	matches := library.Search(s.library.Tracks(), query, limit)
+	tracks := make([]track, len(matches))
+	for i, m := range matches {
+		t := m.Track
+		tracks[i] = track{Path: t.Path, Title: t.Audio().Title(), Artist: t.Tags.Artist, Album: t.Tags.Album, Duration: t.Duration.Seconds()}
+	}
+	writeJSON(w, tracks)
+}
+
+// readJSON decodes the body of the request into v, answering with an error if it cannot.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
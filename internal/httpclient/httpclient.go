@@ -0,0 +1,175 @@
+// Package httpclient makes the requests to online services, like MusicBrainz, keeping
+// to their rate limits and caching the responses on disk.
+package httpclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// Time to wait for a response
+	Timeout time.Duration = 15 * time.Second
+	// Maximum size of a response body
+	MaxBodySize int64 = 8 << 20
+)
+
+// UserAgent identifies tempo to the online services, some of which reject anonymous clients.
+const UserAgent string = "tempo ( https://github.com/nicolito128/tempo )"
+
+// StatusError is returned when the server answers with an error status.
+type StatusError struct {
+	URL  string
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %d %s", e.URL, e.Code, http.StatusText(e.Code))
+}
+
+// Client : An HTTP client that waits between requests and caches the responses
+type Client struct {
+	http *http.Client
+
+	// Minimum time between the start of two requests
+	interval time.Duration
+
+	// When the next request can start
+	mu   sync.Mutex
+	next time.Time
+
+	// Directory of the cached responses, nothing is cached if empty
+	cacheDir string
+	cacheTTL time.Duration
+}
+
+// New creates a client that makes one request per interval at most.
+func New(interval time.Duration) *Client {
+	c := new(Client)
+	c.http = &http.Client{Timeout: Timeout}
+	c.interval = interval
+	return c
+}
+
+// SetCache keeps the responses in dir for ttl, so the same request is answered from the disk.
+func (c *Client) SetCache(dir string, ttl time.Duration) {
+	c.cacheDir = dir
+	c.cacheTTL = ttl
+}
+
+// Get requests the url and returns the response body.
+func (c *Client) Get(ctx context.Context, url string) ([]byte, error) {
+	if body, ok := c.cached(url); ok {
+		return body, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	body, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	c.store(url, body)
+	return body, nil
+}
+
+// GetJSON requests the url and decodes the JSON response into v.
+func (c *Client) GetJSON(ctx context.Context, url string, v any) error {
+	body, err := c.Get(ctx, url)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// Do sends the request once the rate limit allows it and returns the response body.
+// The responses are not cached.
+func (c *Client) Do(req *http.Request) ([]byte, error) {
+	if err := c.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{URL: req.URL.Redacted(), Code: resp.StatusCode}
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, MaxBodySize))
+}
+
+// wait blocks until the next request can start, or the context is done.
+func (c *Client) wait(ctx context.Context) error {
+	c.mu.Lock()
+	now := time.Now()
+	start := now
+	if c.next.After(now) {
+		start = c.next
+	}
+	c.next = start.Add(c.interval)
+	c.mu.Unlock()
+
+	if start.Equal(now) {
+		return nil
+	}
+	timer := time.NewTimer(start.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// cachePath returns the file caching the response of the url.
+func (c *Client) cachePath(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.cacheDir, hex.EncodeToString(sum[:]))
+}
+
+// cached returns the cached response of the url, if it is not too old.
+func (c *Client) cached(url string) ([]byte, bool) {
+	if c.cacheDir == "" {
+		return nil, false
+	}
+	path := c.cachePath(url)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > c.cacheTTL {
+		return nil, false
+	}
+	body, err := os.ReadFile(path)
+	return body, err == nil
+}
+
+// store caches the response of the url. A failure only means it is requested again.
+func (c *Client) store(url string, body []byte) {
+	if c.cacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(c.cacheDir, 0o755); err != nil {
+		slog.Debug("Cannot cache the response", "url", url, "err", err)
+		return
+	}
+	if err := os.WriteFile(c.cachePath(url), body, 0o644); err != nil {
+		slog.Debug("Cannot cache the response", "url", url, "err", err)
+	}
+}
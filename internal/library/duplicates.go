@@ -0,0 +1,133 @@
+package library
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DuplicateReason : Why a group of tracks is considered the same song
+type DuplicateReason int
+
+const (
+	// SameContent the files have exactly the same content
+	SameContent DuplicateReason = iota
+	// SameTitleDuration the tracks have the same title and duration
+	SameTitleDuration
+)
+
+func (r DuplicateReason) String() string {
+	if r == SameContent {
+		return "same content"
+	}
+	return "same title and duration"
+}
+
+// DuplicateGroup : Tracks that are likely copies of each other
+type DuplicateGroup struct {
+	Reason DuplicateReason
+	Tracks []Track
+}
+
+// FindDuplicates groups the tracks that are likely duplicates. Files with the same size
+// are hashed to find exact copies; the rest are compared by title and duration, rounded
+// to the second. Groups are sorted by title, and a track belongs to one group at most.
+func FindDuplicates(tracks []Track) []DuplicateGroup {
+	var groups []DuplicateGroup
+	grouped := make(map[string]bool)
+
+	bySize := make(map[int64][]Track)
+	for _, t := range tracks {
+		bySize[t.Size] = append(bySize[t.Size], t)
+	}
+	for _, same := range bySize {
+		if len(same) < 2 {
+			continue
+		}
+		byHash := make(map[string][]Track)
+		for _, t := range same {
+			sum, err := hashFile(t.Path)
+			if err != nil {
+				continue
+			}
+			byHash[sum] = append(byHash[sum], t)
+		}
+		for _, copies := range byHash {
+			if len(copies) < 2 {
+				continue
+			}
+			for _, t := range copies {
+				grouped[t.Path] = true
+			}
+			groups = append(groups, DuplicateGroup{Reason: SameContent, Tracks: copies})
+		}
+	}
+
+	type titleKey struct {
+		title    string
+		duration time.Duration
+	}
+	byTitle := make(map[titleKey][]Track)
+	for _, t := range tracks {
+		if grouped[t.Path] || t.Duration == 0 {
+			continue
+		}
+		k := titleKey{strings.ToLower(strings.TrimSpace(t.Title())), t.Duration.Round(time.Second)}
+		byTitle[k] = append(byTitle[k], t)
+	}
+	for _, same := range byTitle {
+		if len(same) > 1 {
+			groups = append(groups, DuplicateGroup{Reason: SameTitleDuration, Tracks: same})
+		}
+	}
+
+	for _, g := range groups {
+		sort.Slice(g.Tracks, func(i, j int) bool { return g.Tracks[i].Path < g.Tracks[j].Path })
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i].Tracks[0], groups[j].Tracks[0]
+		if ta, tb := strings.ToLower(a.Title()), strings.ToLower(b.Title()); ta != tb {
+			return ta < tb
+		}
+		return a.Path < b.Path
+	})
+	return groups
+}
+
+// WriteDuplicateReport writes the duplicate groups in a human-readable format.
+func WriteDuplicateReport(w io.Writer, groups []DuplicateGroup) error {
+	if len(groups) == 0 {
+		_, err := fmt.Fprintln(w, "No duplicates found")
+		return err
+	}
+	for i, g := range groups {
+		if _, err := fmt.Fprintf(w, "#%d %s (%s)\n", i+1, g.Tracks[0].Title(), g.Reason); err != nil {
+			return err
+		}
+		for _, t := range g.Tracks {
+			if _, err := fmt.Fprintf(w, "    %s\n", t.Path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,127 @@
+package library
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nicolito128/tempo/internal/audio"
+)
+
+// Filter : Decides which files of the library directories are indexed
+//
+// Ignore patterns are globs matched against the path relative to the library directory,
+// using / as separator. `**` matches any number of directories, and patterns without a
+// slash match the name of a file or directory at any level, like `*.cue` or `.git`.
+type Filter struct {
+	ignore [][]string
+
+	// Allowed extensions, every supported one if empty
+	exts []string
+}
+
+// NewFilter creates a filter from the ignore patterns and the extensions to index.
+func NewFilter(ignore, extensions []string) (*Filter, error) {
+	f := new(Filter)
+	for _, pattern := range ignore {
+		pattern = strings.Trim(strings.TrimSpace(pattern), "/")
+		if pattern == "" {
+			continue
+		}
+		if !strings.Contains(pattern, "/") {
+			pattern = "**/" + pattern
+		}
+
+		segments := strings.Split(pattern, "/")
+		for _, seg := range segments {
+			if _, err := path.Match(seg, ""); err != nil {
+				return nil, fmt.Errorf("bad ignore pattern %q: %w", pattern, err)
+			}
+		}
+		f.ignore = append(f.ignore, segments)
+	}
+
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if !slices.Contains(audio.SupportedExtensions, ext) {
+			return nil, fmt.Errorf("extension %s is not supported, use one of: %s",
+				ext, strings.Join(audio.SupportedExtensions, ", "))
+		}
+		f.exts = append(f.exts, ext)
+	}
+	return f, nil
+}
+
+// Ignored reports whether the file or directory at p, inside the library directory root,
+// matches an ignore pattern. A nil filter ignores nothing.
+func (f *Filter) Ignored(root, p string) bool {
+	if f == nil || len(f.ignore) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(root, p)
+	if err != nil || rel == "." {
+		return false
+	}
+
+	// Everything inside an ignored directory is ignored too
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for _, pattern := range f.ignore {
+		for n := 1; n <= len(segments); n++ {
+			if matchSegments(pattern, segments[:n]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Includes reports whether the file at p, inside the library directory root, is indexed.
+// A nil filter includes every supported file.
+func (f *Filter) Includes(root, p string) bool {
+	if !audio.IsSupported(p) {
+		return false
+	}
+	if f == nil {
+		return true
+	}
+	if len(f.exts) > 0 && !slices.Contains(f.exts, strings.ToLower(filepath.Ext(p))) {
+		return false
+	}
+	return !f.Ignored(root, p)
+}
+
+// matchSegments matches the path segments against the pattern segments, where ** matches
+// zero or more segments.
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], segments[0])
+	return ok && matchSegments(pattern[1:], segments[1:])
+}
+
+// rootOf returns the library directory containing p.
+func rootOf(roots []string, p string) string {
+	for _, root := range roots {
+		if p == root || strings.HasPrefix(p, root+string(filepath.Separator)) {
+			return root
+		}
+	}
+	return filepath.Dir(p)
+}
@@ -0,0 +1,408 @@
+package library
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/audio"
+	"github.com/nicolito128/tempo/internal/xdg"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// File name of the index inside the data directory
+	IndexFileName string = "library.db"
+	// Time to wait for the index lock held by another tempo instance
+	IndexTimeout time.Duration = time.Second
+	// Tracks left this close to their start or their end are not resumed
+	ResumeMargin time.Duration = 10 * time.Second
+)
+
+var (
+	tracksBucket    = []byte("tracks")
+	playsBucket     = []byte("plays")
+	statsBucket     = []byte("stats")
+	ratingsBucket   = []byte("ratings")
+	bookmarksBucket = []byte("bookmarks")
+	positionsBucket = []byte("positions")
+)
+
+// PlayEvent : A track played at some point
+type PlayEvent struct {
+	Path string    `json:"path"`
+	At   time.Time `json:"at"`
+}
+
+// Index : Persistent storage of scanned tracks, so unchanged files are not decoded again
+type Index struct {
+	db *bolt.DB
+}
+
+// DefaultIndexPath returns the path of the index inside the user data directory.
+func DefaultIndexPath() (string, error) {
+	dir, err := xdg.Ensure(xdg.DataDir())
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, IndexFileName), nil
+}
+
+// OpenIndex opens (or creates) the index database at path.
+func OpenIndex(path string) (*Index, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: IndexTimeout})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{tracksBucket, playsBucket, statsBucket, ratingsBucket, bookmarksBucket, positionsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Index{db: db}, nil
+}
+
+// Close releases the database.
+func (idx *Index) Close() error {
+	return idx.db.Close()
+}
+
+// Get returns the stored track with the given path.
+func (idx *Index) Get(path string) (Track, bool, error) {
+	var t Track
+	var found bool
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(tracksBucket).Get([]byte(path))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &t)
+	})
+	return t, found, err
+}
+
+// Put stores the given tracks, replacing the previous ones with the same path.
+func (idx *Index) Put(tracks ...Track) error {
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tracksBucket)
+		for _, t := range tracks {
+			data, err := json.Marshal(t)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(t.Path), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete removes the tracks with the given paths.
+func (idx *Index) Delete(paths ...string) error {
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tracksBucket)
+		for _, path := range paths {
+			if err := b.Delete([]byte(path)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// All returns every stored track, sorted by path.
+func (idx *Index) All() ([]Track, error) {
+	var tracks []Track
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tracksBucket).ForEach(func(_, data []byte) error {
+			var t Track
+			if err := json.Unmarshal(data, &t); err != nil {
+				return err
+			}
+			tracks = append(tracks, t)
+			return nil
+		})
+	})
+	return tracks, err
+}
+
+// Paths returns the paths of the stored tracks inside dir.
+func (idx *Index) Paths(dir string) ([]string, error) {
+	prefix := []byte(strings.TrimSuffix(dir, string(filepath.Separator)) + string(filepath.Separator))
+
+	var paths []string
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(tracksBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, _ = c.Next() {
+			paths = append(paths, string(k))
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// Move changes the path of the track at from, or of every track inside the directory
+// from, keeping their statistics, ratings, bookmarks and positions.
+func (idx *Index) Move(from, to string) error {
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{tracksBucket, statsBucket, ratingsBucket, bookmarksBucket, positionsBucket} {
+			b := tx.Bucket(name)
+
+			moved := make(map[string][]byte)
+			err := b.ForEach(func(k, data []byte) error {
+				// Values cannot be used after changing the bucket
+				if _, ok := movedPath(string(k), from, to); ok {
+					moved[string(k)] = bytes.Clone(data)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			for old, data := range moved {
+				path, _ := movedPath(old, from, to)
+				if bytes.Equal(name, tracksBucket) {
+					var t Track
+					if err := json.Unmarshal(data, &t); err != nil {
+						return err
+					}
+					t.Path = path
+					if data, err = json.Marshal(t); err != nil {
+						return err
+					}
+				}
+				if err := b.Delete([]byte(old)); err != nil {
+					return err
+				}
+				if err := b.Put([]byte(path), data); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// LogPlay stores a play event in the playback history.
+func (idx *Index) LogPlay(ev PlayEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	// Keys are sorted by time, the path avoids collisions
+	key := binary.BigEndian.AppendUint64(nil, uint64(ev.At.UnixNano()))
+	key = append(key, ev.Path...)
+
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(playsBucket).Put(key, data)
+	})
+}
+
+// History returns the last play events, newest first. A limit lower than 1 returns all of them.
+func (idx *Index) History(limit int) ([]PlayEvent, error) {
+	var events []PlayEvent
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(playsBucket).Cursor()
+		for k, data := c.Last(); k != nil; k, data = c.Prev() {
+			if limit > 0 && len(events) >= limit {
+				break
+			}
+			var ev PlayEvent
+			if err := json.Unmarshal(data, &ev); err != nil {
+				return err
+			}
+			events = append(events, ev)
+		}
+		return nil
+	})
+	return events, err
+}
+
+// RecordPlay adds the outcome of playing a track to its statistics.
+func (idx *Index) RecordPlay(path string, outcome PlayOutcome, listened time.Duration) error {
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(statsBucket)
+
+		var st TrackStats
+		if data := b.Get([]byte(path)); data != nil {
+			if err := json.Unmarshal(data, &st); err != nil {
+				return err
+			}
+		}
+		st.add(outcome, listened, time.Now())
+
+		data, err := json.Marshal(st)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(path), data)
+	})
+}
+
+// Stats returns the statistics of every played track, by path.
+func (idx *Index) Stats() (map[string]TrackStats, error) {
+	stats := make(map[string]TrackStats)
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(statsBucket).ForEach(func(k, data []byte) error {
+			var st TrackStats
+			if err := json.Unmarshal(data, &st); err != nil {
+				return err
+			}
+			stats[string(k)] = st
+			return nil
+		})
+	})
+	return stats, err
+}
+
+// Rating returns the rating of the track with the given path.
+func (idx *Index) Rating(path string) (Rating, error) {
+	var r Rating
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(ratingsBucket).Get([]byte(path))
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &r)
+	})
+	return r, err
+}
+
+// SetRating stores the rating of a track. A zero rating removes it.
+func (idx *Index) SetRating(path string, r Rating) error {
+	r.Stars = min(max(r.Stars, 0), MaxStars)
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(ratingsBucket)
+		if r.IsZero() {
+			return b.Delete([]byte(path))
+		}
+
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(path), data)
+	})
+}
+
+// Ratings returns the rating of every rated track, by path.
+func (idx *Index) Ratings() (map[string]Rating, error) {
+	ratings := make(map[string]Rating)
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(ratingsBucket).ForEach(func(k, data []byte) error {
+			var r Rating
+			if err := json.Unmarshal(data, &r); err != nil {
+				return err
+			}
+			ratings[string(k)] = r
+			return nil
+		})
+	})
+	return ratings, err
+}
+
+// Bookmarks returns the bookmarks of the file with the given path.
+func (idx *Index) Bookmarks(path string) ([]audio.Bookmark, error) {
+	var bookmarks []audio.Bookmark
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bookmarksBucket).Get([]byte(path))
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &bookmarks)
+	})
+	return bookmarks, err
+}
+
+// SetBookmarks stores the bookmarks of a file. No bookmarks removes them.
+func (idx *Index) SetBookmarks(path string, bookmarks []audio.Bookmark) error {
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bookmarksBucket)
+		if len(bookmarks) == 0 {
+			return b.Delete([]byte(path))
+		}
+
+		data, err := json.Marshal(bookmarks)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(path), data)
+	})
+}
+
+// Position returns the position the file with the given path was left at, 0 if it was not.
+func (idx *Index) Position(path string) (time.Duration, error) {
+	var pos time.Duration
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(positionsBucket).Get([]byte(path))
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &pos)
+	})
+	return pos, err
+}
+
+// SetPosition stores the position a file was left at, to resume it from there. A zero
+// position removes it.
+func (idx *Index) SetPosition(path string, pos time.Duration) error {
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(positionsBucket)
+		if pos <= 0 {
+			return b.Delete([]byte(path))
+		}
+
+		data, err := json.Marshal(pos)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(path), data)
+	})
+}
+
+// movedPath returns the new path of path after moving from to, if it is from or it is inside of it.
+func movedPath(path, from, to string) (string, bool) {
+	if path == from {
+		return to, true
+	}
+	prefix := strings.TrimSuffix(from, string(filepath.Separator)) + string(filepath.Separator)
+	if rest, ok := strings.CutPrefix(path, prefix); ok {
+		return filepath.Join(to, rest), true
+	}
+	return "", false
+}
+
+// stampAdded sets when the track was added to the library, keeping the time of the
+// indexed track if any. Without index the modification time of the file is used.
+func stampAdded(idx *Index, t *Track) {
+	if idx == nil {
+		t.AddedAt = t.ModTime
+		return
+	}
+	if old, ok, err := idx.Get(t.Path); err == nil && ok && !old.AddedAt.IsZero() {
+		t.AddedAt = old.AddedAt
+		return
+	}
+	t.AddedAt = time.Now()
+}
@@ -0,0 +1,349 @@
+package library
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/analysis"
+	"github.com/nicolito128/tempo/internal/audio"
+	"github.com/nicolito128/tempo/internal/loudness"
+	"github.com/nicolito128/tempo/internal/tags"
+)
+
+// Track : An audio file found in the library
+type Track struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+
+	// Duration of the audio
+	Duration time.Duration
+
+	// Format of the decoded audio
+	SampleRate int
+	Channels   int
+	Precision  int
+
+	// AddedAt is when the track was indexed for the first time
+	AddedAt time.Time
+
+	// Tags read from the file, if any
+	Tags tags.Tags
+
+	// Tempo in beats per minute and musical key, like "A minor", empty until analyzed
+	BPM float64 `json:",omitempty"`
+	Key string  `json:",omitempty"`
+
+	// Integrated loudness in LUFS, its range in LU and the peak of the samples, zero until
+	// measured
+	Loudness      float64 `json:",omitempty"`
+	LoudnessRange float64 `json:",omitempty"`
+	Peak          float64 `json:",omitempty"`
+
+	// Version of the scanner that read the track, to scan it again when it changes
+	Version int `json:",omitempty"`
+}
+
+// Version of the information read by ScanFile
+const TrackVersion int = 2
+
+// Album artist of the compilations without one
+const VariousArtists string = "Various Artists"
+
+// Directory names used for the discs of an album, like "CD1" or "Disc 2"
+var discDirPattern = regexp.MustCompile(`(?i)^(cd|disc|disk)\s*(\d+)$`)
+
+// Name returns the file name of the track without its extension.
+func (t Track) Name() string {
+	return audio.NewFile(t.Path).Name()
+}
+
+// Added returns when the track was added to the library. Tracks indexed without
+// that information use the modification time of the file.
+func (t Track) Added() time.Time {
+	if t.AddedAt.IsZero() {
+		return t.ModTime
+	}
+	return t.AddedAt
+}
+
+// Title returns the title of the track, or its file name if it has no tags.
+func (t Track) Title() string {
+	if t.Tags.Title != "" {
+		return t.Tags.Title
+	}
+	return t.Name()
+}
+
+// Artist returns the artist of the track. Without tags, it is guessed from the usual
+// Artist/Album/Track layout.
+func (t Track) Artist() string {
+	if t.Tags.Artist != "" {
+		return t.Tags.Artist
+	}
+	return filepath.Base(filepath.Dir(t.albumDir()))
+}
+
+// Album returns the album of the track. Without tags, it is guessed from the usual
+// Artist/Album/Track layout.
+func (t Track) Album() string {
+	if t.Tags.Album != "" {
+		return t.Tags.Album
+	}
+	return filepath.Base(t.albumDir())
+}
+
+// AlbumArtist returns the artist the album of the track is grouped under.
+// Compilations without album artist are grouped under VariousArtists.
+func (t Track) AlbumArtist() string {
+	switch {
+	case t.Tags.AlbumArtist != "":
+		return t.Tags.AlbumArtist
+	case t.Tags.Compilation:
+		return VariousArtists
+	}
+	return t.Artist()
+}
+
+// Disc returns the number of the disc of the track, read from the tags or from a
+// directory named like "CD2". Zero if unknown.
+func (t Track) Disc() int {
+	if t.Tags.Disc > 0 {
+		return t.Tags.Disc
+	}
+	if m := discDirPattern.FindStringSubmatch(filepath.Base(filepath.Dir(t.Path))); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return n
+	}
+	return 0
+}
+
+// TrackNumber returns the position of the track in its disc, read from the tags or from
+// the number at the start of the file name. Zero if unknown.
+func (t Track) TrackNumber() int {
+	if t.Tags.Track > 0 {
+		return t.Tags.Track
+	}
+	name := filepath.Base(t.Path)
+	end := strings.IndexFunc(name, func(r rune) bool { return r < '0' || r > '9' })
+	if end < 0 {
+		end = len(name)
+	}
+	n, _ := strconv.Atoi(name[:end])
+	return n
+}
+
+// albumDir returns the directory of the album, skipping the disc directories.
+func (t Track) albumDir() string {
+	dir := filepath.Dir(t.Path)
+	if discDirPattern.MatchString(filepath.Base(dir)) {
+		return filepath.Dir(dir)
+	}
+	return dir
+}
+
+// SortAlbumOrder sorts the tracks by album artist, album, disc and track number.
+func SortAlbumOrder(tracks []Track) {
+	sort.SliceStable(tracks, func(i, j int) bool {
+		a, b := tracks[i], tracks[j]
+		if x, y := strings.ToLower(a.AlbumArtist()), strings.ToLower(b.AlbumArtist()); x != y {
+			return x < y
+		}
+		if x, y := strings.ToLower(a.Album()), strings.ToLower(b.Album()); x != y {
+			return x < y
+		}
+		if a.Disc() != b.Disc() {
+			return a.Disc() < b.Disc()
+		}
+		if a.TrackNumber() != b.TrackNumber() {
+			return a.TrackNumber() < b.TrackNumber()
+		}
+		return a.Path < b.Path
+	})
+}
+
+// Format returns a short description of the audio format, like "MP3 44.1kHz".
+func (t Track) Format() string {
+	ext := strings.ToUpper(strings.TrimPrefix(t.Ext(), "."))
+	if t.SampleRate == 0 {
+		return ext
+	}
+	return fmt.Sprintf("%s %gkHz", ext, float64(t.SampleRate)/1000)
+}
+
+// Ext returns the extension of the track file.
+func (t Track) Ext() string {
+	return filepath.Ext(t.Path)
+}
+
+// Analyze estimates the tempo and key of the track from its audio.
+func (t *Track) Analyze() error {
+	res, err := analysis.Analyze(t.Path)
+	if err != nil {
+		return err
+	}
+	t.BPM = res.BPM
+	t.Key = res.Key.String()
+	return nil
+}
+
+// MeasureLoudness measures the loudness of the track from its audio.
+func (t *Track) MeasureLoudness() error {
+	res, err := loudness.Measure(t.Path)
+	if err != nil {
+		return err
+	}
+	t.SetLoudness(res)
+	return nil
+}
+
+// SetLoudness sets the measured loudness of the track.
+func (t *Track) SetLoudness(res loudness.Result) {
+	t.Loudness, t.LoudnessRange, t.Peak = res.Integrated, res.Range, res.Peak
+}
+
+// LoudnessResult returns the measured loudness of the track, reporting false if it was not
+// measured.
+func (t Track) LoudnessResult() (loudness.Result, bool) {
+	if t.Loudness == 0 {
+		return loudness.Result{}, false
+	}
+	return loudness.Result{Integrated: t.Loudness, Range: t.LoudnessRange, Peak: t.Peak}, true
+}
+
+// Camelot returns the key of the track in the Camelot notation, like 8A, or an empty
+// string if it was not analyzed.
+func (t Track) Camelot() string {
+	key, err := analysis.ParseKey(t.Key)
+	if err != nil {
+		return ""
+	}
+	return key.Camelot()
+}
+
+// Audio returns the track as an audio file for the player, with its indexed tags.
+func (t Track) Audio() audio.File {
+	af := audio.NewFile(t.Path)
+	af.SetTags(t.Tags)
+	return af
+}
+
+// Label returns "Artist – Title" for tagged tracks, or the file name without extension.
+func (t Track) Label() string {
+	return t.Audio().Label()
+}
+
+// Library : A collection of tracks indexed by path, safe for concurrent use
+type Library struct {
+	mu     sync.RWMutex
+	tracks map[string]Track
+}
+
+func New() *Library {
+	l := new(Library)
+	l.tracks = make(map[string]Track)
+	return l
+}
+
+// Put adds or replaces the given tracks.
+func (l *Library) Put(tracks ...Track) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, t := range tracks {
+		l.tracks[t.Path] = t
+	}
+}
+
+// Remove deletes the tracks with the given paths.
+func (l *Library) Remove(paths ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, path := range paths {
+		delete(l.tracks, path)
+	}
+}
+
+// RemoveDir deletes the tracks inside the given directory.
+func (l *Library) RemoveDir(dir string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	prefix := strings.TrimSuffix(dir, string(filepath.Separator)) + string(filepath.Separator)
+	for path := range l.tracks {
+		if strings.HasPrefix(path, prefix) {
+			delete(l.tracks, path)
+		}
+	}
+}
+
+// Move changes the path of the track at from, or of every track inside the directory from.
+func (l *Library) Move(from, to string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var moved []Track
+	for path, t := range l.tracks {
+		if newPath, ok := movedPath(path, from, to); ok {
+			delete(l.tracks, path)
+			t.Path = newPath
+			moved = append(moved, t)
+		}
+	}
+	for _, t := range moved {
+		l.tracks[t.Path] = t
+	}
+}
+
+// Get returns the track with the given path.
+func (l *Library) Get(path string) (Track, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	t, ok := l.tracks[path]
+	return t, ok
+}
+
+// Len returns the number of tracks in the library.
+func (l *Library) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return len(l.tracks)
+}
+
+// RecentlyAdded returns the tracks added since the given time, newest first.
+func (l *Library) RecentlyAdded(since time.Time) []Track {
+	var tracks []Track
+	for _, t := range l.Tracks() {
+		if t.Added().After(since) {
+			tracks = append(tracks, t)
+		}
+	}
+	sort.SliceStable(tracks, func(i, j int) bool {
+		return tracks[i].Added().After(tracks[j].Added())
+	})
+	return tracks
+}
+
+// Tracks returns every track of the library sorted by path.
+func (l *Library) Tracks() []Track {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	tracks := make([]Track, 0, len(l.tracks))
+	for _, t := range l.tracks {
+		tracks = append(tracks, t)
+	}
+	sort.Slice(tracks, func(i, j int) bool {
+		return tracks[i].Path < tracks[j].Path
+	})
+	return tracks
+}
@@ -0,0 +1,442 @@
+package library
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Query : A parsed smart playlist expression, like `genre = "jazz" AND rating >= 4`
+//
+// Comparisons are joined with AND, OR and NOT, and grouped with parentheses.
+// Text fields (title, artist, album, genre, path, format, key, camelot) support =, != and
+// ~ (contains), ignoring case. Numeric fields (rating, plays, skips, duration, bpm) support
+// every comparison; durations can be written like 3m or 90s. Time fields (added, lastplayed)
+// are compared by age: `added < 7d` matches the tracks added in the last week, `lastplayed >
+// 30d` the tracks not played in a month (or never). favorite is compared with true or false.
+type Query struct {
+	source string
+	root   node
+}
+
+// Facts : Everything a query can ask about a track
+type Facts struct {
+	Track  Track
+	Stats  TrackStats
+	Rating Rating
+}
+
+// ParseQuery parses a smart playlist expression.
+func ParseQuery(s string) (*Query, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != endToken {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return &Query{source: s, root: root}, nil
+}
+
+// Match reports whether the track described by the facts matches the query.
+func (q *Query) Match(f Facts, now time.Time) bool {
+	return q.root.eval(f, now)
+}
+
+func (q *Query) String() string {
+	return q.source
+}
+
+// Filter returns the tracks matching the query, with their statistics and ratings.
+func (q *Query) Filter(tracks []Track, stats map[string]TrackStats, ratings map[string]Rating) []Track {
+	now := time.Now()
+	var matched []Track
+	for _, t := range tracks {
+		f := Facts{Track: t, Stats: stats[t.Path], Rating: ratings[t.Path]}
+		if q.Match(f, now) {
+			matched = append(matched, t)
+		}
+	}
+	return matched
+}
+
+type node interface {
+	eval(f Facts, now time.Time) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(f Facts, now time.Time) bool {
+	return n.left.eval(f, now) && n.right.eval(f, now)
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(f Facts, now time.Time) bool { return n.left.eval(f, now) || n.right.eval(f, now) }
+
+type notNode struct{ inner node }
+
+func (n notNode) eval(f Facts, now time.Time) bool { return !n.inner.eval(f, now) }
+
+// fieldKind : The type of the values of a field
+type fieldKind int
+
+const (
+	textField fieldKind = iota
+	numberField
+	ageField
+	boolField
+)
+
+// field : A property of the tracks usable in queries
+type field struct {
+	kind fieldKind
+
+	text   func(f Facts) string
+	number func(f Facts) float64
+	// time returns the zero time when the event never happened
+	time    func(f Facts) time.Time
+	boolean func(f Facts) bool
+}
+
+var fields = map[string]field{
+	"title":   {kind: textField, text: func(f Facts) string { return f.Track.Title() }},
+	"artist":  {kind: textField, text: func(f Facts) string { return f.Track.Artist() }},
+	"album":   {kind: textField, text: func(f Facts) string { return f.Track.Album() }},
+	"genre":   {kind: textField, text: func(f Facts) string { return f.Track.Tags.Genre }},
+	"path":    {kind: textField, text: func(f Facts) string { return f.Track.Path }},
+	"format":  {kind: textField, text: func(f Facts) string { return f.Track.Format() }},
+	"key":     {kind: textField, text: func(f Facts) string { return f.Track.Key }},
+	"camelot": {kind: textField, text: func(f Facts) string { return f.Track.Camelot() }},
+
+	"rating":   {kind: numberField, number: func(f Facts) float64 { return float64(f.Rating.Stars) }},
+	"plays":    {kind: numberField, number: func(f Facts) float64 { return float64(f.Stats.Plays) }},
+	"skips":    {kind: numberField, number: func(f Facts) float64 { return float64(f.Stats.Skips) }},
+	"duration": {kind: numberField, number: func(f Facts) float64 { return f.Track.Duration.Seconds() }},
+	"bpm":      {kind: numberField, number: func(f Facts) float64 { return math.Round(f.Track.BPM) }},
+
+	"added":      {kind: ageField, time: func(f Facts) time.Time { return f.Track.Added() }},
+	"lastplayed": {kind: ageField, time: func(f Facts) time.Time { return f.Stats.LastPlayed }},
+
+	"favorite": {kind: boolField, boolean: func(f Facts) bool { return f.Rating.Favorite }},
+}
+
+// compareNode : A comparison between a field and a literal value
+type compareNode struct {
+	field field
+	op    string
+
+	text    string
+	number  float64
+	boolean bool
+}
+
+func (n compareNode) eval(f Facts, now time.Time) bool {
+	switch n.field.kind {
+	case textField:
+		v := strings.ToLower(n.field.text(f))
+		switch n.op {
+		case "=":
+			return v == n.text
+		case "!=":
+			return v != n.text
+		case "~":
+			return strings.Contains(v, n.text)
+		}
+
+	case numberField:
+		return compareNumbers(n.field.number(f), n.op, n.number)
+
+	case ageField:
+		// Events that never happened are infinitely old
+		t := n.field.time(f)
+		age := float64(^uint64(0) >> 1)
+		if !t.IsZero() {
+			age = now.Sub(t).Seconds()
+		}
+		return compareNumbers(age, n.op, n.number)
+
+	case boolField:
+		v := n.field.boolean(f)
+		if n.op == "!=" {
+			return v != n.boolean
+		}
+		return v == n.boolean
+	}
+	return false
+}
+
+func compareNumbers(a float64, op string, b float64) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+// tokenKind : The type of a token of the query
+type tokenKind int
+
+const (
+	endToken tokenKind = iota
+	identToken
+	stringToken
+	opToken
+	openToken
+	closeToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{kind: openToken, text: "(", pos: i})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: closeToken, text: ")", pos: i})
+			i++
+
+		case r == '"':
+			start := i
+			var b strings.Builder
+			for i++; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			tokens = append(tokens, token{kind: stringToken, text: b.String(), pos: start})
+			i++
+
+		case strings.ContainsRune("=!<>~", r):
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' && r != '=' && r != '~' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected \"!\" at position %d, use != or NOT", i)
+			}
+			tokens = append(tokens, token{kind: opToken, text: op, pos: i})
+			i += len(op)
+
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("()\"=!<>~", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: identToken, text: string(runes[start:i]), pos: start})
+		}
+	}
+	return append(tokens, token{kind: endToken, text: "end of query", pos: len(runes)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != endToken {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword reports whether the next token is the given keyword, consuming it.
+func (p *parser) keyword(word string) bool {
+	tok := p.peek()
+	if tok.kind == identToken && strings.EqualFold(tok.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) not() (node, error) {
+	if p.keyword("NOT") {
+		inner, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	if p.peek().kind == openToken {
+		p.next()
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != closeToken {
+			return nil, fmt.Errorf("expected \")\" at position %d", tok.pos)
+		}
+		return inner, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	tok := p.next()
+	if tok.kind != identToken {
+		return nil, fmt.Errorf("expected a field at position %d, found %q", tok.pos, tok.text)
+	}
+	name := strings.ToLower(tok.text)
+	f, ok := fields[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q at position %d", tok.text, tok.pos)
+	}
+
+	opTok := p.next()
+	if opTok.kind != opToken {
+		return nil, fmt.Errorf("expected an operator after %q at position %d", tok.text, opTok.pos)
+	}
+	n := compareNode{field: f, op: opTok.text}
+
+	switch f.kind {
+	case textField:
+		if n.op != "=" && n.op != "!=" && n.op != "~" {
+			return nil, fmt.Errorf("operator %s cannot be used with %s, use =, != or ~", n.op, name)
+		}
+	case boolField:
+		if n.op != "=" && n.op != "!=" {
+			return nil, fmt.Errorf("operator %s cannot be used with %s, use = or !=", n.op, name)
+		}
+	default:
+		if n.op == "~" {
+			return nil, fmt.Errorf("operator ~ cannot be used with %s", name)
+		}
+	}
+
+	val := p.next()
+	if val.kind != identToken && val.kind != stringToken {
+		return nil, fmt.Errorf("expected a value for %s at position %d", name, val.pos)
+	}
+
+	switch f.kind {
+	case textField:
+		n.text = strings.ToLower(val.text)
+
+	case numberField:
+		v, err := parseNumber(val.text, name == "duration")
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s at position %d: %w", name, val.pos, err)
+		}
+		n.number = v
+
+	case ageField:
+		d, err := parseAge(val.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s at position %d: %w", name, val.pos, err)
+		}
+		n.number = d.Seconds()
+
+	case boolField:
+		v, err := strconv.ParseBool(val.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s at position %d, use true or false", name, val.pos)
+		}
+		n.boolean = v
+	}
+	return n, nil
+}
+
+// parseNumber parses a number, or a duration in seconds if allowed.
+func parseNumber(s string, duration bool) (float64, error) {
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, nil
+	}
+	if duration {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, err
+		}
+		return d.Seconds(), nil
+	}
+	return 0, fmt.Errorf("%q is not a number", s)
+}
+
+// parseAge parses an age like 30d, 2w, 12h or 45m.
+func parseAge(s string) (time.Duration, error) {
+	if n, ok := strings.CutSuffix(s, "d"); ok {
+		days, err := strconv.Atoi(n)
+		return time.Duration(days) * 24 * time.Hour, err
+	}
+	if n, ok := strings.CutSuffix(s, "w"); ok {
+		weeks, err := strconv.Atoi(n)
+		return time.Duration(weeks) * 7 * 24 * time.Hour, err
+	}
+	return time.ParseDuration(s)
+}
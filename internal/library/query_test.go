@@ -0,0 +1,99 @@
+package library
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/tags"
+)
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{`genre = "jazz`, "unterminated string at position 8"},
+		{`rating ! 3`, `unexpected "!" at position 7`},
+		{`mood = "calm"`, `unknown field "mood"`},
+		{`rating 3`, `expected an operator after "rating"`},
+		{`title > "a"`, "operator > cannot be used with title"},
+		{`favorite < true`, "operator < cannot be used with favorite"},
+		{`plays ~ 3`, "operator ~ cannot be used with plays"},
+		{`rating >=`, "expected a value for rating"},
+		{`plays = many`, `invalid value for plays at position 8: "many" is not a number`},
+		{`added < soon`, "invalid value for added"},
+		{`favorite = maybe`, "invalid value for favorite at position 11, use true or false"},
+		{`(rating = 5`, `expected ")" at position 11`},
+		{`rating = 5 plays = 1`, `unexpected "plays" at position 11`},
+		{`AND rating = 5`, `unknown field "AND"`},
+		{``, `expected a field at position 0, found "end of query"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := ParseQuery(tt.query)
+			if err == nil {
+				t.Fatalf("ParseQuery(%q) succeeded, want an error containing %q", tt.query, tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseQuery(%q) error = %q, want it to contain %q", tt.query, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	facts := Facts{
+		Track: Track{
+			Path:     "/music/Miles Davis/Kind of Blue/01 So What.flac",
+			Duration: 9*time.Minute + 22*time.Second,
+			AddedAt:  now.Add(-3 * 24 * time.Hour),
+			BPM:      135.6,
+			Tags:     tags.Tags{Title: "So What", Artist: "Miles Davis", Album: "Kind of Blue", Genre: "Jazz"},
+		},
+		Stats:  TrackStats{Plays: 12, Skips: 1},
+		Rating: Rating{Stars: 5, Favorite: true},
+	}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{`genre = "jazz"`, true},
+		{`genre = jazz`, true},
+		{`genre != "Jazz"`, false},
+		{`artist ~ "davis"`, true},
+		{`title ~ "blue"`, false},
+		{`path ~ "kind of blue"`, true},
+		{`rating >= 4`, true},
+		{`rating < 5`, false},
+		{`plays > 10 AND skips <= 1`, true},
+		{`bpm = 136`, true},
+		{`duration > 9m`, true},
+		{`duration <= 90s`, false},
+		{`duration = 562`, true},
+		{`added < 7d`, true},
+		{`added > 2w`, false},
+		{`lastplayed > 30d`, true},
+		{`favorite = true`, true},
+		{`favorite != true`, false},
+		{`NOT genre = "rock"`, true},
+		{`not not genre = "rock"`, false},
+		{`genre = "rock" OR rating = 5`, true},
+		{`genre = "rock" OR rating = 5 AND plays = 0`, false},
+		{`(genre = "rock" OR rating = 5) AND plays = 12`, true},
+		{`album = "Kind \"of\" Blue"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+			}
+			if got := q.Match(facts, now); got != tt.want {
+				t.Errorf("%q matched = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package library
+
+// Highest rating a track can have
+const MaxStars int = 5
+
+// Rating : The stars and favorite flag the user gave to a track
+type Rating struct {
+	Stars    int  `json:"stars"`
+	Favorite bool `json:"favorite"`
+}
+
+// IsZero reports whether the track was not rated nor marked as favorite.
+func (r Rating) IsZero() bool {
+	return r.Stars == 0 && !r.Favorite
+}
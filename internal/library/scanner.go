@@ -0,0 +1,454 @@
+package library
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/analysis"
+	"github.com/nicolito128/tempo/internal/loudness"
+	"github.com/nicolito128/tempo/internal/tags"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+const (
+	// Minimum time between two progress messages
+	ProgressInterval time.Duration = 100 * time.Millisecond
+)
+
+// ProgressMsg reports the state of a running scan.
+type ProgressMsg struct {
+	// Found is the number of audio files discovered so far
+	Found int
+	// Scanned is the number of files already processed
+	Scanned int
+	// Current is the last scanned path
+	Current string
+}
+
+// DoneMsg is sent when a scan finishes.
+type DoneMsg struct {
+	// Tracks scanned without errors
+	Tracks []Track
+	// Updated is the number of tracks decoded because they were new or changed
+	Updated int
+	// Removed paths that were indexed but do not exist anymore
+	Removed []string
+	// Errors found while walking the directories or decoding files
+	Errors []error
+	// Elapsed time of the scan
+	Elapsed time.Duration
+}
+
+// Identifier finds the tags of an audio file from its sound, like the AcoustID client.
+// The tags are empty if the file is not recognized.
+type Identifier interface {
+	Identify(ctx context.Context, path string) (tags.Tags, error)
+}
+
+// Scanner : Recursively scans directories for audio files using a pool of workers
+type Scanner struct {
+	dirs    []string
+	workers int
+
+	// Optional index to skip unchanged files and store the results
+	index *Index
+
+	// Optional filter of the indexed files
+	filter *Filter
+
+	// Optional identifier naming the files without tags
+	identifier Identifier
+
+	// analyze if the tempo and key of the new and changed files are estimated
+	analyze bool
+
+	// measure if the loudness of the new and changed files without ReplayGain tags is
+	// measured
+	measure bool
+
+	events chan tea.Msg
+	cancel context.CancelFunc
+}
+
+// NewScanner creates a scanner for the given directories. If workers is not
+// positive, one worker per CPU is used.
+func NewScanner(dirs []string, workers int) *Scanner {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	abs := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if a, err := filepath.Abs(dir); err == nil {
+			dir = a
+		}
+		abs = append(abs, dir)
+	}
+	return &Scanner{dirs: abs, workers: workers}
+}
+
+// SetIndex sets the index used to skip unchanged files. Scan results are stored in it.
+func (s *Scanner) SetIndex(idx *Index) {
+	s.index = idx
+}
+
+// SetFilter sets which files are indexed. Indexed files that are not included anymore are removed.
+func (s *Scanner) SetFilter(f *Filter) {
+	s.filter = f
+}
+
+// SetIdentifier sets the identifier used to name the new files that have no title tag.
+func (s *Scanner) SetIdentifier(id Identifier) {
+	s.identifier = id
+}
+
+// SetAnalyze sets whether the tempo and key of the new and changed files are estimated,
+// which decodes a good part of each file.
+func (s *Scanner) SetAnalyze(analyze bool) {
+	s.analyze = analyze
+}
+
+// SetMeasureLoudness sets whether the loudness of the new and changed files without
+// ReplayGain tags is measured, which decodes each of them whole.
+func (s *Scanner) SetMeasureLoudness(measure bool) {
+	s.measure = measure
+}
+
+// Dirs returns the directories being scanned.
+func (s *Scanner) Dirs() []string {
+	return s.dirs
+}
+
+// Start runs the scan in the background and returns a command that waits for its first message.
+// Each ProgressMsg should be followed by a call to Wait until the DoneMsg arrives.
+func (s *Scanner) Start() tea.Cmd {
+	s.Cancel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.events = make(chan tea.Msg, 1)
+
+	go s.run(ctx, s.events)
+	return s.Wait()
+}
+
+// Wait returns a command that waits for the next message of the running scan.
+func (s *Scanner) Wait() tea.Cmd {
+	events := s.events
+	if events == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// Cancel stops the running scan, if any.
+func (s *Scanner) Cancel() {
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+}
+
+// Scan walks the directories synchronously and returns every track found.
+func (s *Scanner) Scan(ctx context.Context) DoneMsg {
+	events := make(chan tea.Msg, 1)
+	go s.run(ctx, events)
+
+	var done DoneMsg
+	for msg := range events {
+		if d, ok := msg.(DoneMsg); ok {
+			done = d
+		}
+	}
+	return done
+}
+
+// ScanError : An error found while decoding a file
+type ScanError struct {
+	Path string
+	Err  error
+}
+
+func (e *ScanError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+type result struct {
+	track   Track
+	updated bool
+	err     error
+
+	// Error that did not prevent scanning the file
+	warning error
+}
+
+func (s *Scanner) run(ctx context.Context, events chan<- tea.Msg) {
+	defer close(events)
+	start := time.Now()
+
+	paths := make(chan string, s.workers*4)
+	results := make(chan result, s.workers*4)
+
+	var mu sync.Mutex
+	var walkErrs []error
+	found := 0
+	foundInDir := make(map[string]int)
+
+	// Walker
+	go func() {
+		defer close(paths)
+		for _, dir := range s.dirs {
+			err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					mu.Lock()
+					walkErrs = append(walkErrs, err)
+					mu.Unlock()
+					if d != nil && d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if d.IsDir() {
+					if path != dir && (strings.HasPrefix(d.Name(), ".") || s.filter.Ignored(dir, path)) {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if !s.filter.Includes(dir, path) {
+					return nil
+				}
+
+				mu.Lock()
+				found++
+				foundInDir[dir]++
+				mu.Unlock()
+
+				select {
+				case paths <- path:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// Workers
+	var wg sync.WaitGroup
+	for range s.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				res := s.scanFile(ctx, path)
+				select {
+				case results <- res:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Collector
+	var done DoneMsg
+	var updated []Track
+	var lastProgress time.Time
+	scanned := 0
+	for res := range results {
+		scanned++
+		if res.err != nil {
+			done.Errors = append(done.Errors, res.err)
+		} else {
+			done.Tracks = append(done.Tracks, res.track)
+		}
+		if res.warning != nil {
+			done.Errors = append(done.Errors, res.warning)
+		}
+		if res.updated {
+			updated = append(updated, res.track)
+		}
+
+		if time.Since(lastProgress) < ProgressInterval {
+			continue
+		}
+		lastProgress = time.Now()
+
+		mu.Lock()
+		progress := ProgressMsg{Found: found, Scanned: scanned, Current: res.track.Path}
+		mu.Unlock()
+
+		// Progress is dropped if the previous one was not received yet
+		select {
+		case events <- progress:
+		default:
+		}
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	done.Errors = append(walkErrs, done.Errors...)
+	done.Updated = len(updated)
+
+	if s.index != nil {
+		if err := s.index.Put(updated...); err != nil {
+			done.Errors = append(done.Errors, err)
+		}
+
+		removed, err := s.removed(done.Tracks, foundInDir)
+		if err != nil {
+			done.Errors = append(done.Errors, err)
+		}
+		done.Removed = removed
+	}
+
+	done.Elapsed = time.Since(start)
+	select {
+	case events <- done:
+	case <-ctx.Done():
+	}
+}
+
+// scanFile reads a file, reusing the indexed track if the file did not change.
+func (s *Scanner) scanFile(ctx context.Context, path string) result {
+	var old Track
+	var unchanged bool
+	if s.index != nil {
+		info, err := os.Stat(path)
+		if err != nil {
+			return result{err: err}
+		}
+
+		t, ok, err := s.index.Get(path)
+		unchanged = err == nil && ok && t.Size == info.Size() && t.ModTime.Equal(info.ModTime())
+		if unchanged && t.Version == TrackVersion {
+			return result{track: t}
+		}
+		old = t
+	}
+
+	track, err := ScanFile(path)
+	if err != nil {
+		return result{err: err}
+	}
+	stampAdded(s.index, &track)
+
+	// Files read again by a newer scanner keep their analysis and loudness
+	if unchanged {
+		track.BPM, track.Key = old.BPM, old.Key
+		track.Loudness, track.LoudnessRange, track.Peak = old.Loudness, old.LoudnessRange, old.Peak
+	}
+
+	res := result{track: track, updated: true}
+	if s.identifier != nil && track.Tags.Title == "" {
+		// The identified tags are only kept in the index, the file is not modified
+		tg, err := s.identifier.Identify(ctx, path)
+		if err != nil {
+			res.warning = &ScanError{Path: path, Err: err}
+		} else if tg.Title != "" {
+			res.track.Tags.Title = tg.Title
+			res.track.Tags.Artist = cmp.Or(res.track.Tags.Artist, tg.Artist)
+			res.track.Tags.Album = cmp.Or(res.track.Tags.Album, tg.Album)
+		}
+	}
+	if s.analyze && res.track.Key == "" {
+		if err := res.track.Analyze(); err != nil && !errors.Is(err, analysis.ErrNoMusic) {
+			res.warning = errors.Join(res.warning, &ScanError{Path: path, Err: err})
+		}
+	}
+	rg := res.track.Tags.ReplayGain
+	if s.measure && res.track.Loudness == 0 && rg.TrackGain == 0 && rg.AlbumGain == 0 {
+		if err := res.track.MeasureLoudness(); err != nil && !errors.Is(err, loudness.ErrSilent) {
+			res.warning = errors.Join(res.warning, &ScanError{Path: path, Err: err})
+		}
+	}
+	return res
+}
+
+// removed deletes from the index the tracks of the scanned directories that were not found,
+// or that are not included by the filter anymore. A directory without any file found is
+// ignored, since it is probably an unmounted drive.
+func (s *Scanner) removed(tracks []Track, foundInDir map[string]int) ([]string, error) {
+	seen := make(map[string]struct{}, len(tracks))
+	for _, t := range tracks {
+		seen[t.Path] = struct{}{}
+	}
+
+	var removed []string
+	for _, dir := range s.dirs {
+		if foundInDir[dir] == 0 {
+			continue
+		}
+
+		paths, err := s.index.Paths(dir)
+		if err != nil {
+			return removed, err
+		}
+		for _, path := range paths {
+			if _, ok := seen[path]; ok {
+				continue
+			}
+			if _, err := os.Stat(path); os.IsNotExist(err) || !s.filter.Includes(dir, path) {
+				removed = append(removed, path)
+			}
+		}
+	}
+
+	return removed, s.index.Delete(removed...)
+}
+
+// ScanFile reads the information of a single audio file.
+func ScanFile(path string) (Track, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Track{}, err
+	}
+
+	format, duration, err := engine.Probe(path)
+	if err != nil {
+		return Track{}, &ScanError{Path: path, Err: err}
+	}
+
+	// Files without tags are still indexed
+	tg, _ := tags.ReadFile(path)
+
+	return Track{
+		Path:       path,
+		Size:       info.Size(),
+		ModTime:    info.ModTime(),
+		Duration:   duration,
+		SampleRate: int(format.SampleRate),
+		Channels:   format.NumChannels,
+		Precision:  format.Precision,
+		Tags:       tg,
+		Version:    TrackVersion,
+	}, nil
+}
@@ -0,0 +1,101 @@
+package library
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// Score bonus for a match in the track name instead of its path
+	NameBonus int = 10
+	// Score bonus for a matched character right after the previous one
+	ConsecutiveBonus int = 5
+	// Score bonus for a matched character at the start of a word
+	WordStartBonus int = 8
+)
+
+// Match : A track matching a search query
+type Match struct {
+	Track Track
+	Score int
+}
+
+// Search fuzzy matches the query against the tracks, returning at most limit
+// matches sorted by score. A limit lower than 1 returns every match.
+func Search(tracks []Track, query string, limit int) []Match {
+	q := []rune(strings.ToLower(strings.TrimSpace(query)))
+	if len(q) == 0 {
+		return nil
+	}
+
+	var matches []Match
+	for _, t := range tracks {
+		best, ok := 0, false
+		for i, field := range t.searchFields() {
+			score, matched := FuzzyScore(q, field)
+			if !matched {
+				continue
+			}
+			if i == 0 {
+				score += NameBonus
+			}
+			if !ok || score > best {
+				best, ok = score, true
+			}
+		}
+		if ok {
+			matches = append(matches, Match{Track: t, Score: best})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return len(matches[i].Track.Path) < len(matches[j].Track.Path)
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// FuzzyScore reports whether the lowercase query is a subsequence of s and how well it matches.
+// Consecutive characters and characters at the start of words score higher.
+func FuzzyScore(query []rune, s string) (int, bool) {
+	if len(query) == 0 {
+		return 0, true
+	}
+
+	score := 0
+	qi := 0
+	last := -2
+	prev := ' '
+	for i, r := range []rune(strings.ToLower(s)) {
+		if qi < len(query) && r == query[qi] {
+			score++
+			if last == i-1 {
+				score += ConsecutiveBonus
+			}
+			if isSeparator(prev) {
+				score += WordStartBonus
+			}
+			last = i
+			qi++
+		}
+		prev = r
+	}
+
+	return score, qi == len(query)
+}
+
+// searchFields returns the texts of the track matched by Search, the first one being its title.
+func (t Track) searchFields() []string {
+	return []string{t.Title(), t.Artist(), t.Album(), t.Path}
+}
+
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r)
+}
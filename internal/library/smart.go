@@ -0,0 +1,24 @@
+package library
+
+// SmartPlaylist : A saved query whose tracks are taken from the library
+type SmartPlaylist struct {
+	Name  string
+	Query *Query
+}
+
+// Tracks returns the tracks matching the playlist query. The play statistics and
+// ratings are read from the index, when there is one.
+func (sp SmartPlaylist) Tracks(tracks []Track, idx *Index) ([]Track, error) {
+	var stats map[string]TrackStats
+	var ratings map[string]Rating
+	if idx != nil {
+		var err error
+		if stats, err = idx.Stats(); err != nil {
+			return nil, err
+		}
+		if ratings, err = idx.Ratings(); err != nil {
+			return nil, err
+		}
+	}
+	return sp.Query.Filter(tracks, stats, ratings), nil
+}
@@ -0,0 +1,101 @@
+package library
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// PlayOutcome : How the playback of a track ended
+type PlayOutcome int
+
+const (
+	// PlayCompleted the track was played until its end
+	PlayCompleted PlayOutcome = iota
+	// PlaySkipped the user moved to another track before the end
+	PlaySkipped
+	// PlayStopped the program was closed while playing
+	PlayStopped
+)
+
+// TrackStats : Listening statistics of a track
+type TrackStats struct {
+	Plays      int           `json:"plays"`
+	Skips      int           `json:"skips"`
+	ListenTime time.Duration `json:"listen_time"`
+	LastPlayed time.Time     `json:"last_played"`
+}
+
+func (st *TrackStats) add(outcome PlayOutcome, listened time.Duration, at time.Time) {
+	switch outcome {
+	case PlayCompleted:
+		st.Plays++
+	case PlaySkipped:
+		st.Skips++
+	}
+	st.ListenTime += listened
+	st.LastPlayed = at
+}
+
+// Count : How many times an artist or track was played
+type Count struct {
+	Name  string
+	Path  string
+	Plays int
+}
+
+// Summary : Aggregated statistics of the whole library
+type Summary struct {
+	ListenTime time.Duration
+	Plays      int
+	Skips      int
+
+	// Most played artists and tracks, most played first
+	TopArtists []Count
+	TopTracks  []Count
+}
+
+// Summarize aggregates the statistics of every track, keeping the top limit artists
+// and tracks. Artists are taken from the library when the track is indexed.
+func Summarize(stats map[string]TrackStats, lib *Library, limit int) Summary {
+	var sum Summary
+	artists := make(map[string]int)
+	for path, st := range stats {
+		sum.ListenTime += st.ListenTime
+		sum.Plays += st.Plays
+		sum.Skips += st.Skips
+
+		t := Track{Path: path}
+		if lib != nil {
+			if indexed, ok := lib.Get(path); ok {
+				t = indexed
+			}
+		}
+
+		if st.Plays > 0 {
+			artists[t.Artist()] += st.Plays
+			sum.TopTracks = append(sum.TopTracks, Count{Name: t.Label(), Path: path, Plays: st.Plays})
+		}
+	}
+
+	for name, plays := range artists {
+		sum.TopArtists = append(sum.TopArtists, Count{Name: name, Plays: plays})
+	}
+
+	sum.TopArtists = top(sum.TopArtists, limit)
+	sum.TopTracks = top(sum.TopTracks, limit)
+	return sum
+}
+
+func top(counts []Count, limit int) []Count {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Plays != counts[j].Plays {
+			return counts[i].Plays > counts[j].Plays
+		}
+		return strings.ToLower(counts[i].Name) < strings.ToLower(counts[j].Name)
+	})
+	if limit > 0 && len(counts) > limit {
+		counts = counts[:limit]
+	}
+	return counts
+}
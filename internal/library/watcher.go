@@ -0,0 +1,288 @@
+package library
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+	"github.com/nicolito128/tempo/internal/audio"
+)
+
+const (
+	// Time without filesystem events before the pending changes are applied.
+	// Files being copied or ripped are written in many steps.
+	WatchDebounce time.Duration = 500 * time.Millisecond
+)
+
+// ChangedMsg reports the tracks changed in the library directories while watching them.
+type ChangedMsg struct {
+	Updated []Track
+	Removed []string
+	// RemovedDirs are removed directories, whose tracks must be forgotten
+	RemovedDirs []string
+	Errors      []error
+}
+
+// Dirs returns the directories containing the changed tracks.
+func (msg ChangedMsg) Dirs() []string {
+	seen := make(map[string]struct{})
+	var dirs []string
+	add := func(path string) {
+		dir := filepath.Dir(path)
+		if _, ok := seen[dir]; !ok {
+			seen[dir] = struct{}{}
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, t := range msg.Updated {
+		add(t.Path)
+	}
+	for _, path := range msg.Removed {
+		add(path)
+	}
+	for _, path := range msg.RemovedDirs {
+		add(path)
+	}
+	return dirs
+}
+
+// Watcher : Watches the library directories and keeps the index up to date
+type Watcher struct {
+	fw    *fsnotify.Watcher
+	index *Index
+
+	// Library directories and the filter of the indexed files
+	roots  []string
+	filter *Filter
+
+	// Directories being watched
+	watched map[string]struct{}
+
+	events chan tea.Msg
+	done   chan struct{}
+}
+
+// NewWatcher watches the given directories and all their subdirectories.
+// The index and the filter are optional. The directories that cannot be watched, like the
+// missing ones, are skipped, returning why as warnings.
+func NewWatcher(dirs []string, idx *Index, filter *Filter) (*Watcher, []error, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w := &Watcher{
+		fw:      fw,
+		index:   idx,
+		filter:  filter,
+		watched: make(map[string]struct{}),
+		events:  make(chan tea.Msg, 1),
+		done:    make(chan struct{}),
+	}
+	var warnings []error
+	for _, dir := range dirs {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		w.roots = append(w.roots, dir)
+		if err := w.addTree(dir); err != nil {
+			warnings = append(warnings, fmt.Errorf("cannot watch %s: %w", dir, err))
+		}
+	}
+	return w, warnings, nil
+}
+
+// Start watches in background and returns a command that waits for the first change.
+func (w *Watcher) Start() tea.Cmd {
+	go w.run()
+	return w.Wait()
+}
+
+// Wait returns a command that waits for the next ChangedMsg.
+func (w *Watcher) Wait() tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-w.events
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	select {
+	case <-w.done:
+		return nil
+	default:
+	}
+	close(w.done)
+	return w.fw.Close()
+}
+
+func (w *Watcher) run() {
+	defer close(w.events)
+
+	pending := make(map[string]struct{})
+	timer := time.NewTimer(WatchDebounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+
+		case ev, ok := <-w.fw.Events:
+			if !ok {
+				return
+			}
+			pending[ev.Name] = struct{}{}
+			timer.Reset(WatchDebounce)
+
+		case err, ok := <-w.fw.Errors:
+			if !ok {
+				return
+			}
+			if !w.send(ChangedMsg{Errors: []error{err}}) {
+				return
+			}
+
+		case <-timer.C:
+			msg := w.apply(pending)
+			pending = make(map[string]struct{})
+			if len(msg.Updated)+len(msg.Removed)+len(msg.RemovedDirs)+len(msg.Errors) == 0 {
+				continue
+			}
+			if !w.send(msg) {
+				return
+			}
+		}
+	}
+}
+
+func (w *Watcher) send(msg ChangedMsg) bool {
+	select {
+	case w.events <- msg:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// apply scans the changed paths and updates the index.
+func (w *Watcher) apply(paths map[string]struct{}) ChangedMsg {
+	var msg ChangedMsg
+
+	for path := range paths {
+		info, err := os.Stat(path)
+		switch {
+		case os.IsNotExist(err):
+			w.remove(path, &msg)
+
+		case err != nil:
+			msg.Errors = append(msg.Errors, err)
+
+		case info.IsDir():
+			// A new directory: watch it and scan everything inside
+			if err := w.addTree(path); err != nil {
+				msg.Errors = append(msg.Errors, err)
+			}
+			filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() && w.includes(p) {
+					w.update(p, &msg)
+				}
+				return nil
+			})
+
+		case w.includes(path):
+			w.update(path, &msg)
+		}
+	}
+
+	if w.index != nil {
+		if err := w.index.Put(msg.Updated...); err != nil {
+			msg.Errors = append(msg.Errors, err)
+		}
+		if err := w.index.Delete(msg.Removed...); err != nil {
+			msg.Errors = append(msg.Errors, err)
+		}
+	}
+	return msg
+}
+
+// includes reports whether the file at path is indexed.
+func (w *Watcher) includes(path string) bool {
+	return w.filter.Includes(rootOf(w.roots, path), path)
+}
+
+func (w *Watcher) update(path string, msg *ChangedMsg) {
+	t, err := ScanFile(path)
+	if err != nil {
+		// Probably a file still being written, it will change again
+		msg.Errors = append(msg.Errors, err)
+		return
+	}
+	stampAdded(w.index, &t)
+	msg.Updated = append(msg.Updated, t)
+}
+
+// remove stops watching a removed path and forgets the tracks that were inside of it.
+func (w *Watcher) remove(path string, msg *ChangedMsg) {
+	if _, ok := w.watched[path]; !ok {
+		if audio.IsSupported(path) {
+			msg.Removed = append(msg.Removed, path)
+		}
+		return
+	}
+
+	prefix := path + string(filepath.Separator)
+	for dir := range w.watched {
+		if dir == path || strings.HasPrefix(dir, prefix) {
+			delete(w.watched, dir)
+		}
+	}
+	msg.RemovedDirs = append(msg.RemovedDirs, path)
+
+	if w.index != nil {
+		paths, err := w.index.Paths(path)
+		if err != nil {
+			msg.Errors = append(msg.Errors, err)
+		}
+		msg.Removed = append(msg.Removed, paths...)
+	}
+}
+
+// addTree watches dir and all its subdirectories, except the hidden and ignored ones.
+func (w *Watcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if w.filter.Ignored(rootOf(w.roots, path), path) {
+			return filepath.SkipDir
+		}
+		if _, ok := w.watched[path]; ok {
+			return nil
+		}
+
+		if err := w.fw.Add(path); err != nil {
+			return err
+		}
+		w.watched[path] = struct{}{}
+		return nil
+	})
+}
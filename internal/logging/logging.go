@@ -0,0 +1,129 @@
+// Package logging writes the log of tempo to a file in the state directory, with the errors
+// that are not shown in the UI and the details of the playback that help finding out why
+// some audio does not play.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nicolito128/tempo/internal/xdg"
+)
+
+// File name of the log inside the state directory
+const FileName string = "log"
+
+// Size of the log over which it is moved aside, to the file name with .old, when opened
+const MaxSize int64 = 1 << 20
+
+// LevelOff is the level that logs nothing and does not create the file.
+const LevelOff slog.Level = slog.LevelError + 4
+
+// Names of the levels, from the one logging the most
+var levels = []struct {
+	name  string
+	level slog.Level
+}{
+	{"debug", slog.LevelDebug},
+	{"info", slog.LevelInfo},
+	{"warn", slog.LevelWarn},
+	{"error", slog.LevelError},
+	{"off", LevelOff},
+}
+
+// LevelNames returns the names of the levels, from the one logging the most.
+func LevelNames() []string {
+	names := make([]string, len(levels))
+	for i, l := range levels {
+		names[i] = l.name
+	}
+	return names
+}
+
+// ParseLevel returns the level with the given name: debug, info, warn, error or off.
+func ParseLevel(name string) (slog.Level, error) {
+	for _, l := range levels {
+		if strings.EqualFold(name, l.name) {
+			return l.level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q, use one of: %s", name, strings.Join(LevelNames(), ", "))
+}
+
+// Path returns the location of the log inside the user state directory.
+func Path() (string, error) {
+	dir, err := xdg.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Open makes the default slog logger write the records of level and above to the file at
+// path, appending to it. The file is created by the first record, so the runs without any
+// leave no log behind, and closed by the returned closer. At LevelOff nothing is logged.
+func Open(path string, level slog.Level) io.Closer {
+	if level >= LevelOff {
+		Discard()
+		return &file{}
+	}
+	f := &file{path: path}
+	slog.SetDefault(slog.New(slog.NewTextHandler(f, &slog.HandlerOptions{Level: level})))
+	return f
+}
+
+// file : The log file, opened when first written to
+type file struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	// Error opening the file, which keeps it from being tried again
+	err error
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.f == nil && f.err == nil {
+		f.f, f.err = openFile(f.path)
+	}
+	if f.err != nil {
+		return 0, f.err
+	}
+	return f.f.Write(p)
+}
+
+func (f *file) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var err error
+	if f.f != nil {
+		err = f.f.Close()
+	}
+	f.f, f.err = nil, os.ErrClosed
+	return err
+}
+
+// openFile opens the log at path to append to it, moving it aside first if it is too big.
+func openFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() > MaxSize {
+		if err := os.Rename(path, path+".old"); err != nil {
+			return nil, err
+		}
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+}
+
+// Discard makes the default slog logger drop every record, instead of writing them to the
+// terminal drawn by the UI.
+func Discard() {
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+}
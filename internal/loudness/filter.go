@@ -0,0 +1,65 @@
+package loudness
+
+import (
+	"math"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// biquad : A second order filter of one channel
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// kWeighting : The filter of BS.1770 shaping the audio like the ear hears it: a shelf
+// raising the high frequencies, like the head does, then a high-pass cutting the lowest ones
+type kWeighting struct {
+	shelf, highPass biquad
+}
+
+// newKWeighting creates the K-weighting filter for the given sample rate. The coefficients
+// of the standard are only given for 48 kHz, so the filters are designed again from their
+// analog parameters, like libebur128 does.
+func newKWeighting(rate beep.SampleRate) kWeighting {
+	fs := float64(rate)
+	var k kWeighting
+
+	// High shelf of about +4 dB above 1.5 kHz
+	f0, gain, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	kk := math.Tan(math.Pi * f0 / fs)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + kk/q + kk*kk
+	k.shelf = biquad{
+		b0: (vh + vb*kk/q + kk*kk) / a0,
+		b1: 2 * (kk*kk - vh) / a0,
+		b2: (vh - vb*kk/q + kk*kk) / a0,
+		a1: 2 * (kk*kk - 1) / a0,
+		a2: (1 - kk/q + kk*kk) / a0,
+	}
+
+	// High-pass at about 38 Hz
+	f0, q = 38.13547087602444, 0.5003270373238773
+	kk = math.Tan(math.Pi * f0 / fs)
+	a0 = 1 + kk/q + kk*kk
+	k.highPass = biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (kk*kk - 1) / a0,
+		a2: (1 - kk/q + kk*kk) / a0,
+	}
+	return k
+}
+
+func (k *kWeighting) process(x float64) float64 {
+	return k.highPass.process(k.shelf.process(x))
+}
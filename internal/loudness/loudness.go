@@ -0,0 +1,185 @@
+// Package loudness measures the loudness of a track as perceived, following EBU R128 and
+// ITU-R BS.1770, to play every track equally loud.
+package loudness
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+const (
+	// Target loudness in LUFS of the played audio, the one of ReplayGain 2.0
+	Target float64 = -18
+	// Blocks below this loudness in LUFS are silence, not counted
+	absoluteGate float64 = -70
+	// Blocks more than this many LU below the loudness of the rest are not counted
+	relativeGate float64 = -10
+	// Same gate for the loudness range, which keeps more of the quiet passages
+	rangeGate float64 = -20
+	// Segments of the audio per second, the blocks overlap by all but one segment
+	segmentsPerSecond int = 10
+	// Segments of the blocks of the integrated loudness (400 ms) and of the range (3 s)
+	blockSegments int = 4
+	rangeSegments int = 30
+)
+
+// ErrSilent is returned for audio too short or quiet to be measured.
+var ErrSilent = errors.New("audio too short or silent to measure its loudness")
+
+// Result : The loudness of a track
+type Result struct {
+	// Integrated loudness of the whole track in LUFS
+	Integrated float64
+	// Range between its quiet and loud passages in LU
+	Range float64
+	// Peak of the samples, 1 being full scale
+	Peak float64
+}
+
+// Gain returns the adjustment in dB playing the track at the Target loudness, lowered when
+// its peak would be clipped.
+func (r Result) Gain() float64 {
+	gain := Target - r.Integrated
+	if r.Peak > 0 {
+		gain = min(gain, -20*math.Log10(r.Peak))
+	}
+	return gain
+}
+
+// Measure decodes the audio file at path and measures its loudness.
+func Measure(path string) (Result, error) {
+	streamer, format, err := engine.Decode(path)
+	if err != nil {
+		return Result{}, err
+	}
+	defer streamer.Close()
+
+	powers, peak, err := segments(streamer, format)
+	if err != nil {
+		return Result{}, err
+	}
+
+	integrated, ok := gatedLoudness(blocks(powers, blockSegments), relativeGate)
+	if !ok {
+		return Result{}, ErrSilent
+	}
+	return Result{
+		Integrated: integrated,
+		Range:      loudnessRange(blocks(powers, rangeSegments)),
+		Peak:       peak,
+	}, nil
+}
+
+// segments returns the mean square of the K-weighted audio of every segment, summed over
+// its channels, and the peak of the samples.
+func segments(s beep.Streamer, format beep.Format) ([]float64, float64, error) {
+	length := int(format.SampleRate) / segmentsPerSecond
+	filters := [2]kWeighting{newKWeighting(format.SampleRate), newKWeighting(format.SampleRate)}
+
+	// Mono audio is decoded into two equal channels, but counted once
+	channels := min(format.NumChannels, 2)
+
+	var powers []float64
+	var peak, sum float64
+	var n int
+	buf := make([][2]float64, 4096)
+	for {
+		read, ok := s.Stream(buf)
+		for _, sample := range buf[:read] {
+			for ch := range channels {
+				peak = max(peak, math.Abs(sample[ch]))
+				y := filters[ch].process(sample[ch])
+				sum += y * y
+			}
+			if n++; n == length {
+				powers = append(powers, sum/float64(length))
+				sum, n = 0, 0
+			}
+		}
+		if !ok {
+			break
+		}
+	}
+	return powers, peak, s.Err()
+}
+
+// blocks returns the power of the blocks of the given number of segments, one starting at
+// each segment.
+func blocks(powers []float64, size int) []float64 {
+	if len(powers) < size {
+		return nil
+	}
+	out := make([]float64, 0, len(powers)-size+1)
+	var sum float64
+	for i, p := range powers {
+		sum += p
+		if i >= size {
+			sum -= powers[i-size]
+		}
+		if i >= size-1 {
+			out = append(out, sum/float64(size))
+		}
+	}
+	return out
+}
+
+// gatedLoudness returns the loudness of the blocks that are neither silent nor more than
+// gate LU below the rest, reporting false if all of them are silent.
+func gatedLoudness(blocks []float64, gate float64) (float64, bool) {
+	loudness, ok := meanLoudness(blocks, absoluteGate)
+	if !ok {
+		return 0, false
+	}
+	return meanLoudness(blocks, max(loudness+gate, absoluteGate))
+}
+
+// meanLoudness returns the loudness of the blocks louder than threshold, reporting false if
+// there are none.
+func meanLoudness(blocks []float64, threshold float64) (float64, bool) {
+	var sum float64
+	var n int
+	for _, p := range blocks {
+		if lufs(p) > threshold {
+			sum += p
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return lufs(sum / float64(n)), true
+}
+
+// loudnessRange returns the spread in LU of the loudness of the blocks that are neither
+// silent nor quiet passages, from the 10th to the 95th percentile.
+func loudnessRange(blocks []float64) float64 {
+	loudness, ok := meanLoudness(blocks, absoluteGate)
+	if !ok {
+		return 0
+	}
+	threshold := max(loudness+rangeGate, absoluteGate)
+
+	var levels []float64
+	for _, p := range blocks {
+		if l := lufs(p); l > threshold {
+			levels = append(levels, l)
+		}
+	}
+	if len(levels) == 0 {
+		return 0
+	}
+	sort.Float64s(levels)
+	at := func(q float64) float64 {
+		return levels[int(math.Round(q*float64(len(levels)-1)))]
+	}
+	return at(0.95) - at(0.10)
+}
+
+// lufs converts the power of the K-weighted audio into loudness.
+func lufs(power float64) float64 {
+	return -0.691 + 10*math.Log10(power)
+}
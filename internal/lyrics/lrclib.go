@@ -0,0 +1,97 @@
+package lyrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/httpclient"
+	"github.com/nicolito128/tempo/internal/tags"
+)
+
+const (
+	// Base URL of the LRCLIB API
+	LRCLIBURL string = "https://lrclib.net/api"
+	// Interval between requests to LRCLIB, which asks clients to be gentle
+	LRCLIBInterval time.Duration = time.Second / 2
+)
+
+// LRCLIB : A client of LRCLIB, a free database of synchronized lyrics
+type LRCLIB struct {
+	http    *httpclient.Client
+	baseURL string
+}
+
+var _ Provider = (*LRCLIB)(nil)
+
+// NewLRCLIB creates a client making its requests with the given HTTP client.
+func NewLRCLIB(http *httpclient.Client) *LRCLIB {
+	return &LRCLIB{http: http, baseURL: LRCLIBURL}
+}
+
+func (c *LRCLIB) Name() string {
+	return "lrclib"
+}
+
+// Fetch returns the lyrics of the track, preferring the synchronized ones. Tracks with
+// artist and duration are matched exactly, the others are searched by title.
+func (c *LRCLIB) Fetch(ctx context.Context, t tags.Tags, duration time.Duration) (Lyrics, error) {
+	if t.Artist != "" && duration > 0 {
+		params := url.Values{
+			"track_name":  {t.Title},
+			"artist_name": {t.Artist},
+			"duration":    {strconv.Itoa(int(duration.Round(time.Second).Seconds()))},
+		}
+		if t.Album != "" {
+			params.Set("album_name", t.Album)
+		}
+
+		var rec lrclibRecord
+		err := c.http.GetJSON(ctx, c.baseURL+"/get?"+params.Encode(), &rec)
+		var status *httpclient.StatusError
+		if errors.As(err, &status) && status.Code == http.StatusNotFound {
+			return Lyrics{}, ErrNotFound
+		}
+		if err != nil {
+			return Lyrics{}, err
+		}
+		return rec.lyrics()
+	}
+
+	params := url.Values{"track_name": {t.Title}}
+	if t.Artist != "" {
+		params.Set("artist_name", t.Artist)
+	}
+	var recs []lrclibRecord
+	if err := c.http.GetJSON(ctx, c.baseURL+"/search?"+params.Encode(), &recs); err != nil {
+		return Lyrics{}, err
+	}
+	for _, rec := range recs {
+		if l, err := rec.lyrics(); err == nil {
+			return l, nil
+		}
+	}
+	return Lyrics{}, ErrNotFound
+}
+
+// lrclibRecord : A track in the responses of LRCLIB
+type lrclibRecord struct {
+	Instrumental bool   `json:"instrumental"`
+	PlainLyrics  string `json:"plainLyrics"`
+	SyncedLyrics string `json:"syncedLyrics"`
+}
+
+func (r lrclibRecord) lyrics() (Lyrics, error) {
+	text := r.SyncedLyrics
+	if text == "" {
+		text = r.PlainLyrics
+	}
+	l := ParseLRC(text)
+	if r.Instrumental || len(l.Lines) == 0 {
+		return Lyrics{}, ErrNotFound
+	}
+	return l, nil
+}
@@ -0,0 +1,182 @@
+// Package lyrics finds the lyrics of a track, in a .lrc file next to it or in its tags,
+// and parses the LRC format.
+package lyrics
+
+import (
+	"cmp"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/tags"
+)
+
+// ErrNotFound is returned when a track has no lyrics.
+var ErrNotFound = errors.New("no lyrics found")
+
+// Line : A line of the lyrics, with the time it starts if they are synchronized
+type Line struct {
+	Time time.Duration
+	Text string
+}
+
+// Lyrics : The lyrics of a track
+type Lyrics struct {
+	Lines []Line
+
+	// Synced if the lines have times, sorted from the first one
+	Synced bool
+
+	// Source where the lyrics were found, like the path of a .lrc file
+	Source string
+}
+
+// Find returns the lyrics of the audio file at path, read from the .lrc file with the same
+// name or from its tags, preferring the synchronized lyrics.
+func Find(path string) (Lyrics, error) {
+	stem := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, ext := range []string{".lrc", ".LRC"} {
+		data, err := os.ReadFile(stem + ext)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return Lyrics{}, err
+		}
+		l := ParseLRC(string(data))
+		l.Source = filepath.Base(stem + ext)
+		return l, nil
+	}
+
+	md, err := tags.Read(path)
+	if err != nil {
+		return Lyrics{}, ErrNotFound
+	}
+	if id3, ok := md.(*tags.ID3v2); ok {
+		if synced := id3.SyncedLyrics(); len(synced) > 0 {
+			l := Lyrics{Synced: true, Source: "tags"}
+			for _, s := range synced {
+				l.Lines = append(l.Lines, Line{Time: s.Time, Text: s.Text})
+			}
+			return l, nil
+		}
+	}
+
+	// Some taggers store LRC text as the plain lyrics
+	if text := md.Lyrics(); text != "" {
+		l := ParseLRC(text)
+		l.Source = "tags"
+		if len(l.Lines) > 0 {
+			return l, nil
+		}
+	}
+	return Lyrics{}, ErrNotFound
+}
+
+var (
+	// Time tags at the start of a line, like [01:23.45]
+	lineTime = regexp.MustCompile(`^\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+	// ID tags, like [ar:Artist] or [offset:+250]. Other bracketed lines, like [Chorus: Artist],
+	// are part of the lyrics
+	idTag = regexp.MustCompile(`(?i)^\[(ar|al|ti|au|by|length|offset|re|tool|ve|#):(.*)\]$`)
+	// Times of the words in the enhanced format, like <01:23.45>
+	wordTime = regexp.MustCompile(`<\d+:\d{1,2}(?:[.:]\d{1,3})?>`)
+)
+
+// ParseLRC parses lyrics in the LRC format. A line may start with several times when it
+// is repeated, and the offset tag shifts every time. Text without any time is read as
+// unsynchronized lyrics.
+func ParseLRC(text string) Lyrics {
+	text = strings.TrimPrefix(text, "\uFEFF")
+
+	var l Lyrics
+	var plain []Line
+	var offset time.Duration
+	for raw := range strings.Lines(text) {
+		line := strings.TrimSpace(raw)
+
+		var times []time.Duration
+		for {
+			m := lineTime.FindStringSubmatch(line)
+			if m == nil {
+				break
+			}
+			times = append(times, parseTime(m[1], m[2], m[3]))
+			line = line[len(m[0]):]
+		}
+		line = strings.TrimSpace(wordTime.ReplaceAllString(line, ""))
+
+		if len(times) == 0 {
+			if m := idTag.FindStringSubmatch(line); m != nil {
+				if strings.EqualFold(m[1], "offset") {
+					ms, _ := strconv.Atoi(strings.TrimSpace(m[2]))
+					offset = time.Duration(ms) * time.Millisecond
+				}
+				continue
+			}
+			plain = append(plain, Line{Text: line})
+			continue
+		}
+		for _, t := range times {
+			l.Lines = append(l.Lines, Line{Time: t, Text: line})
+		}
+	}
+
+	if len(l.Lines) == 0 {
+		// Blank lines only matter between paragraphs
+		l.Lines = trimBlank(plain)
+		return l
+	}
+
+	l.Synced = true
+	for i := range l.Lines {
+		// A positive offset shows the lines sooner
+		l.Lines[i].Time = max(l.Lines[i].Time-offset, 0)
+	}
+	slices.SortStableFunc(l.Lines, func(a, b Line) int {
+		return cmp.Compare(a.Time, b.Time)
+	})
+	return l
+}
+
+// parseTime converts the minutes, seconds and fraction of a time tag into a duration.
+// The fraction is in hundredths with two digits, and in milliseconds with three.
+func parseTime(minutes, seconds, frac string) time.Duration {
+	m, _ := strconv.Atoi(minutes)
+	s, _ := strconv.Atoi(seconds)
+	d := time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	if frac != "" {
+		f, _ := strconv.Atoi(frac)
+		for range 3 - len(frac) {
+			f *= 10
+		}
+		d += time.Duration(f) * time.Millisecond
+	}
+	return d
+}
+
+// trimBlank removes the blank lines at the start and end of the lines.
+func trimBlank(lines []Line) []Line {
+	for len(lines) > 0 && lines[0].Text == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1].Text == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Current returns the index of the line being sung at pos, or -1 before the first line
+// and for unsynchronized lyrics.
+func (l Lyrics) Current(pos time.Duration) int {
+	if !l.Synced {
+		return -1
+	}
+	return sort.Search(len(l.Lines), func(i int) bool { return l.Lines[i].Time > pos }) - 1
+}
@@ -0,0 +1,100 @@
+package lyrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLRC(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name string
+		text string
+		want Lyrics
+	}{
+		{
+			name: "synced",
+			text: "[ar:Artist]\n[ti:Title]\n[00:01.50]First\n[00:03.25]Second\n",
+			want: Lyrics{Synced: true, Lines: []Line{{1500 * ms, "First"}, {3250 * ms, "Second"}}},
+		},
+		{
+			name: "fractions",
+			text: "[00:01.5]Tenths\n[00:02.05]Hundredths\n[00:03.125]Milliseconds\n[01:04]Whole\n[00:05:50]Colon\n",
+			want: Lyrics{Synced: true, Lines: []Line{
+				{1500 * ms, "Tenths"}, {2050 * ms, "Hundredths"}, {3125 * ms, "Milliseconds"},
+				{5500 * ms, "Colon"}, {64 * time.Second, "Whole"},
+			}},
+		},
+		{
+			name: "repeated lines are sorted",
+			text: "[00:10.00][00:02.00]Chorus\n[00:05.00]Verse\n",
+			want: Lyrics{Synced: true, Lines: []Line{{2 * time.Second, "Chorus"}, {5 * time.Second, "Verse"}, {10 * time.Second, "Chorus"}}},
+		},
+		{
+			name: "offset shows the lines sooner",
+			text: "[offset:+500]\n[00:00.20]Clamped\n[00:02.00]Sooner\n",
+			want: Lyrics{Synced: true, Lines: []Line{{0, "Clamped"}, {1500 * ms, "Sooner"}}},
+		},
+		{
+			name: "negative offset",
+			text: "[offset:-250]\n[00:01.00]Later\n",
+			want: Lyrics{Synced: true, Lines: []Line{{1250 * ms, "Later"}}},
+		},
+		{
+			name: "enhanced word times",
+			text: "[00:01.00]<00:01.00>One <00:01.50>two\n",
+			want: Lyrics{Synced: true, Lines: []Line{{time.Second, "One two"}}},
+		},
+		{
+			name: "lines without time are dropped from synced lyrics",
+			text: "[00:01.00]Hello\n[Chorus: Someone]\n",
+			want: Lyrics{Synced: true, Lines: []Line{{time.Second, "Hello"}}},
+		},
+		{
+			name: "plain",
+			text: "\uFEFF\n\n[Chorus: Someone]\nFirst line\n\nSecond paragraph\n\n",
+			want: Lyrics{Lines: []Line{{Text: "[Chorus: Someone]"}, {Text: "First line"}, {Text: ""}, {Text: "Second paragraph"}}},
+		},
+		{
+			name: "windows line endings",
+			text: "[00:01.00]One\r\n[00:02.00]Two\r\n",
+			want: Lyrics{Synced: true, Lines: []Line{{time.Second, "One"}, {2 * time.Second, "Two"}}},
+		},
+		{
+			name: "empty",
+			text: "",
+			want: Lyrics{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseLRC(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLRC(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	l := Lyrics{Synced: true, Lines: []Line{{time.Second, "One"}, {2 * time.Second, "Two"}, {2 * time.Second, "Also two"}}}
+	tests := []struct {
+		pos  time.Duration
+		want int
+	}{
+		{0, -1},
+		{time.Second, 0},
+		{1999 * time.Millisecond, 0},
+		{2 * time.Second, 2},
+		{time.Hour, 2},
+	}
+	for _, tt := range tests {
+		if got := l.Current(tt.pos); got != tt.want {
+			t.Errorf("Current(%v) = %d, want %d", tt.pos, got, tt.want)
+		}
+	}
+
+	if got := (Lyrics{Lines: l.Lines}).Current(time.Hour); got != -1 {
+		t.Errorf("Current of unsynchronized lyrics = %d, want -1", got)
+	}
+}
@@ -0,0 +1,66 @@
+package lyrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/httpclient"
+	"github.com/nicolito128/tempo/internal/tags"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// How long the lyrics fetched online are cached
+const CacheTTL time.Duration = 30 * 24 * time.Hour
+
+// Provider : An online service with the lyrics of many tracks
+type Provider interface {
+	// Name of the provider in the config, shown as the source of its lyrics
+	Name() string
+
+	// Fetch returns the lyrics of the track with the given tags and duration, which is zero
+	// if unknown. ErrNotFound is returned if the provider does not have them.
+	Fetch(ctx context.Context, t tags.Tags, duration time.Duration) (Lyrics, error)
+}
+
+// NewProvider creates the provider with the given name, caching its responses in cacheDir
+// unless it is empty.
+func NewProvider(name string, cacheDir string) (Provider, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "lrclib":
+		client := httpclient.New(LRCLIBInterval)
+		if cacheDir != "" {
+			client.SetCache(cacheDir, CacheTTL)
+		}
+		return NewLRCLIB(client), nil
+	}
+	return nil, fmt.Errorf("unknown lyrics provider %q", name)
+}
+
+// Fetch asks the providers in order for the lyrics of the audio file at path, searching
+// them by its tags, and returns the first ones found.
+func Fetch(ctx context.Context, providers []Provider, path string) (Lyrics, error) {
+	t, err := tags.ReadFile(path)
+	if err != nil || t.Title == "" {
+		return Lyrics{}, ErrNotFound
+	}
+	duration, _ := engine.ProbeDuration(path)
+
+	var errs []error
+	for _, p := range providers {
+		l, err := p.Fetch(ctx, t, duration)
+		if err == nil {
+			l.Source = p.Name()
+			return l, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+		}
+	}
+	if len(errs) > 0 {
+		return Lyrics{}, errors.Join(errs...)
+	}
+	return Lyrics{}, ErrNotFound
+}
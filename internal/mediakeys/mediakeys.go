@@ -0,0 +1,5 @@
+// Package mediakeys routes the media keys of the keyboard, and the media controls of the
+// system showing what is being played, into the player. Each platform has its own way: MPRIS
+// on Linux, whose desktops send it the keys, the Now Playing center on macOS and the System
+// Media Transport Controls on Windows.
+package mediakeys
@@ -0,0 +1,23 @@
+package mediakeys
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/nowplaying"
+	"github.com/nicolito128/tempo/internal/remote"
+)
+
+// Main runs main while the main thread waits for the commands of the system, which macOS
+// delivers there.
+func Main(main func()) {
+	nowplaying.Main(main)
+}
+
+// Start shows the player in the Now Playing center, sending the commands of the media keys,
+// Control Center and the headphones to the program with send.
+func Start(send func(tea.Msg)) (remote.Remote, error) {
+	server, err := nowplaying.New(send)
+	if err != nil {
+		return nil, err
+	}
+	return server, nil
+}
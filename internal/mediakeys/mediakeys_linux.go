@@ -0,0 +1,22 @@
+package mediakeys
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/mpris"
+	"github.com/nicolito128/tempo/internal/remote"
+)
+
+// Main runs main.
+func Main(main func()) {
+	main()
+}
+
+// Start shows the player to the media controls of the desktop over MPRIS, sending the keys
+// pressed to the program with send.
+func Start(send func(tea.Msg)) (remote.Remote, error) {
+	server, err := mpris.New(send)
+	if err != nil {
+		return nil, err
+	}
+	return server, nil
+}
@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !windows
+
+package mediakeys
+
+import (
+	"errors"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/remote"
+)
+
+// Main runs main.
+func Main(main func()) {
+	main()
+}
+
+// Start is not supported on this platform.
+func Start(send func(tea.Msg)) (remote.Remote, error) {
+	return nil, errors.ErrUnsupported
+}
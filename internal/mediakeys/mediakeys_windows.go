@@ -0,0 +1,22 @@
+package mediakeys
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/remote"
+	"github.com/nicolito128/tempo/internal/smtc"
+)
+
+// Main runs main.
+func Main(main func()) {
+	main()
+}
+
+// Start shows the player in the media flyout of Windows, sending the buttons pressed there and
+// the media keys to the program with send.
+func Start(send func(tea.Msg)) (remote.Remote, error) {
+	server, err := smtc.New(send)
+	if err != nil {
+		return nil, err
+	}
+	return server, nil
+}
@@ -0,0 +1,610 @@
+package mpd
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/remote"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// request : A command of a client, with the status when it was received
+type request struct {
+	s    *Server
+	st   remote.Status
+	args []string
+	out  *bytes.Buffer
+}
+
+// send sends a message to the program.
+func (r *request) send(msg tea.Msg) {
+	r.s.send(msg)
+}
+
+// printf writes a line of the response.
+func (r *request) printf(format string, a ...any) {
+	fmt.Fprintf(r.out, format+"\n", a...)
+}
+
+// handler : The function answering a command
+type handler func(r *request) error
+
+// Commands served, set in init since commands lists them
+var handlers map[string]handler
+
+func init() {
+	handlers = map[string]handler{
+		"ping":           func(r *request) error { return nil },
+		"status":         status,
+		"currentsong":    currentSong,
+		"playlistinfo":   playlistInfo,
+		"playlistid":     playlistID,
+		"plchanges":      plChanges,
+		"plchangesposid": plChangesPosID,
+		"play":           play,
+		"playid":         playID,
+		"pause":          pause,
+		"stop":           command(remote.Stop),
+		"next":           command(remote.Next),
+		"previous":       command(remote.Previous),
+		"seek":           seek,
+		"seekid":         seekID,
+		"seekcur":        seekCur,
+		"setvol":         setVol,
+		"volume":         volume,
+		"getvol":         getVol,
+		"repeat":         repeat,
+		"random":         unsupported("random"),
+		"single":         unsupported("single"),
+		"consume":        unsupported("consume"),
+		"add":            add,
+		"addid":          addID,
+		"delete":         deleteSongs,
+		"deleteid":       deleteID,
+		"clear":          clearQueue,
+		"stats":          stats,
+		"outputs":        outputs,
+		"commands":       commands,
+		"notcommands":    func(r *request) error { return nil },
+		"tagtypes":       tagTypes,
+		"urlhandlers": func(r *request) error {
+			for _, handler := range []string{"file://", "http://", "https://"} {
+				r.printf("handler: %s", handler)
+			}
+			return nil
+		},
+		"decoders":      decoders,
+		"listplaylists": func(r *request) error { return nil },
+		"lsinfo":        func(r *request) error { return nil },
+		"replay_gain_status": func(r *request) error {
+			r.printf("replay_gain_mode: off")
+			return nil
+		},
+		"binarylimit": func(r *request) error { return nil },
+	}
+}
+
+// Tags of the songs
+var tagNames = []string{"Artist", "Album", "Title", "Track"}
+
+// Extensions of the files tempo plays
+var suffixes = []string{"mp3", "flac", "ogg", "oga", "wav"}
+
+// songID returns the id of the song at path, which stays the same while it is enqueued.
+func songID(path string) int {
+	h := fnv.New32a()
+	h.Write([]byte(path))
+	return int(h.Sum32() & math.MaxInt32)
+}
+
+// writeSong writes the song at index i of the queue.
+func (r *request) writeSong(i int) {
+	item := r.st.Queue[i]
+	r.printf("file: %s", item.Path)
+	r.printf("Title: %s", item.Title)
+	if item.Artist != "" {
+		r.printf("Artist: %s", item.Artist)
+	}
+	if item.Album != "" {
+		r.printf("Album: %s", item.Album)
+	}
+	if item.Track > 0 {
+		r.printf("Track: %d", item.Track)
+	}
+	// Only the length of the current song is known
+	if i == r.st.Index && r.st.Length > 0 {
+		r.printf("Time: %d", int(r.st.Length.Seconds()))
+		r.printf("duration: %.3f", r.st.Length.Seconds())
+	}
+	r.printf("Pos: %d", i)
+	r.printf("Id: %d", songID(item.Path))
+}
+
+func status(r *request) error {
+	st := r.st
+	volume := st.Volume
+	if st.Muted {
+		volume = 0
+	}
+	r.printf("volume: %d", volume)
+	r.printf("repeat: %s", bit(st.Repeat))
+	r.printf("random: 0")
+	r.printf("single: 0")
+	r.printf("consume: 0")
+	r.printf("playlist: %d", st.QueueVersion+1)
+	r.printf("playlistlength: %d", len(st.Queue))
+
+	state := "stop"
+	switch st.State {
+	case remote.Playing:
+		state = "play"
+	case remote.Paused:
+		state = "pause"
+	}
+	r.printf("state: %s", state)
+	if st.Index >= 0 {
+		r.printf("song: %d", st.Index)
+		r.printf("songid: %d", songID(st.Queue[st.Index].Path))
+	}
+	if next := st.Index + 1; st.HasNext && st.Index >= 0 {
+		if next >= len(st.Queue) {
+			next = 0
+		}
+		r.printf("nextsong: %d", next)
+		r.printf("nextsongid: %d", songID(st.Queue[next].Path))
+	}
+	if st.Path != "" {
+		r.printf("time: %d:%d", int(st.Position.Seconds()), int(st.Length.Seconds()))
+		r.printf("elapsed: %.3f", st.Position.Seconds())
+		// Like MPD, the duration of a radio is not told
+		if !st.Live {
+			r.printf("duration: %.3f", st.Length.Seconds())
+		}
+	}
+	return nil
+}
+
+func currentSong(r *request) error {
+	if r.st.Index >= 0 {
+		r.writeSong(r.st.Index)
+	}
+	return nil
+}
+
+func playlistInfo(r *request) error {
+	start, end, err := r.rangeArg(0)
+	if err != nil {
+		return err
+	}
+	for i := start; i < end; i++ {
+		r.writeSong(i)
+	}
+	return nil
+}
+
+func playlistID(r *request) error {
+	if len(r.args) == 0 {
+		return playlistInfo(r)
+	}
+	i, err := r.idArg(0)
+	if err != nil {
+		return err
+	}
+	r.writeSong(i)
+	return nil
+}
+
+// plChanges writes the songs changed since a version of the queue, which are all of them if
+// it is another one.
+func plChanges(r *request) error {
+	if err := r.needArgs(1); err != nil {
+		return err
+	}
+	if r.args[0] == strconv.Itoa(r.st.QueueVersion+1) {
+		return nil
+	}
+	r.args = r.args[1:]
+	return playlistInfo(r)
+}
+
+func plChangesPosID(r *request) error {
+	if err := r.needArgs(1); err != nil {
+		return err
+	}
+	if r.args[0] == strconv.Itoa(r.st.QueueVersion+1) {
+		return nil
+	}
+	for i, item := range r.st.Queue {
+		r.printf("cpos: %d", i)
+		r.printf("Id: %d", songID(item.Path))
+	}
+	return nil
+}
+
+func play(r *request) error {
+	if len(r.args) == 0 {
+		r.send(remote.CommandMsg{Command: remote.Play})
+		return nil
+	}
+	i, err := r.posArg(0)
+	if err != nil {
+		return err
+	}
+	r.send(remote.PlayIndexMsg{Index: i})
+	return nil
+}
+
+func playID(r *request) error {
+	if len(r.args) == 0 {
+		r.send(remote.CommandMsg{Command: remote.Play})
+		return nil
+	}
+	i, err := r.idArg(0)
+	if err != nil {
+		return err
+	}
+	r.send(remote.PlayIndexMsg{Index: i})
+	return nil
+}
+
+func pause(r *request) error {
+	if len(r.args) == 0 {
+		r.send(remote.CommandMsg{Command: remote.PlayPause})
+		return nil
+	}
+	paused, err := r.boolArg(0)
+	if err != nil {
+		return err
+	}
+	if paused {
+		r.send(remote.CommandMsg{Command: remote.Pause})
+	} else {
+		r.send(remote.CommandMsg{Command: remote.Play})
+	}
+	return nil
+}
+
+// command returns the handler sending a command without arguments.
+func command(c remote.Command) handler {
+	return func(r *request) error {
+		r.send(remote.CommandMsg{Command: c})
+		return nil
+	}
+}
+
+// seekTo moves the playback of the song at index i to the position in the argument at arg,
+// playing it first if it is not the current one.
+func (r *request) seekTo(i, arg int) error {
+	pos, err := r.timeArg(arg)
+	if err != nil {
+		return err
+	}
+	if i != r.st.Index {
+		r.send(remote.PlayIndexMsg{Index: i})
+	}
+	r.send(remote.SetPositionMsg{Path: r.st.Queue[i].Path, Position: pos})
+	return nil
+}
+
+func seek(r *request) error {
+	if err := r.needArgs(2); err != nil {
+		return err
+	}
+	i, err := r.posArg(0)
+	if err != nil {
+		return err
+	}
+	return r.seekTo(i, 1)
+}
+
+func seekID(r *request) error {
+	if err := r.needArgs(2); err != nil {
+		return err
+	}
+	i, err := r.idArg(0)
+	if err != nil {
+		return err
+	}
+	return r.seekTo(i, 1)
+}
+
+// seekCur seeks in the current song, by an offset if the time starts with + or -.
+func seekCur(r *request) error {
+	if err := r.needArgs(1); err != nil {
+		return err
+	}
+	if r.st.Index < 0 {
+		return &ackError{code: ackNoExist, msg: "Not playing"}
+	}
+	arg := r.args[0]
+	if !strings.HasPrefix(arg, "+") && !strings.HasPrefix(arg, "-") {
+		return r.seekTo(r.st.Index, 0)
+	}
+	r.args[0] = arg[1:]
+	offset, err := r.timeArg(0)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(arg, "-") {
+		offset = -offset
+	}
+	r.send(remote.SeekMsg{Offset: offset})
+	return nil
+}
+
+func setVol(r *request) error {
+	if err := r.needArgs(1); err != nil {
+		return err
+	}
+	volume, err := strconv.Atoi(r.args[0])
+	if err != nil || volume < 0 || volume > 100 {
+		return &ackError{code: ackArg, msg: "Invalid volume value"}
+	}
+	r.send(remote.SetVolumeMsg{Volume: volume})
+	return nil
+}
+
+// volume changes the volume by an offset.
+func volume(r *request) error {
+	if err := r.needArgs(1); err != nil {
+		return err
+	}
+	offset, err := strconv.Atoi(r.args[0])
+	if err != nil || offset < -100 || offset > 100 {
+		return &ackError{code: ackArg, msg: "Invalid volume value"}
+	}
+	r.send(remote.SetVolumeMsg{Volume: max(min(r.st.Volume+offset, 100), 0)})
+	return nil
+}
+
+func getVol(r *request) error {
+	r.printf("volume: %d", r.st.Volume)
+	return nil
+}
+
+func repeat(r *request) error {
+	if err := r.needArgs(1); err != nil {
+		return err
+	}
+	on, err := r.boolArg(0)
+	if err != nil {
+		return err
+	}
+	r.send(remote.SetRepeatMsg{Repeat: on})
+	return nil
+}
+
+// unsupported returns the handler of a mode tempo does not have, which can only be turned off.
+func unsupported(mode string) handler {
+	return func(r *request) error {
+		if err := r.needArgs(1); err != nil {
+			return err
+		}
+		if on, err := r.boolArg(0); err != nil {
+			return err
+		} else if on {
+			return &ackError{code: ackArg, msg: mode + " is not supported"}
+		}
+		return nil
+	}
+}
+
+func add(r *request) error {
+	if err := r.needArgs(1); err != nil {
+		return err
+	}
+	path, err := r.pathArg(0)
+	if err != nil {
+		return err
+	}
+	r.send(remote.EnqueueMsg{Paths: []string{path}})
+	return nil
+}
+
+func addID(r *request) error {
+	if err := add(r); err != nil {
+		return err
+	}
+	path, _ := r.pathArg(0)
+	r.printf("Id: %d", songID(path))
+	return nil
+}
+
+func deleteSongs(r *request) error {
+	if err := r.needArgs(1); err != nil {
+		return err
+	}
+	start, end, err := r.rangeArg(0)
+	if err != nil {
+		return err
+	}
+	for _, item := range r.st.Queue[start:end] {
+		r.send(remote.RemoveMsg{Path: item.Path})
+	}
+	return nil
+}
+
+func deleteID(r *request) error {
+	if err := r.needArgs(1); err != nil {
+		return err
+	}
+	i, err := r.idArg(0)
+	if err != nil {
+		return err
+	}
+	r.send(remote.RemoveMsg{Path: r.st.Queue[i].Path})
+	return nil
+}
+
+func clearQueue(r *request) error {
+	r.send(remote.ClearMsg{})
+	return nil
+}
+
+func stats(r *request) error {
+	r.printf("uptime: %d", int(time.Since(r.s.started).Seconds()))
+	r.printf("playtime: 0")
+	r.printf("artists: 0")
+	r.printf("albums: 0")
+	r.printf("songs: 0")
+	r.printf("db_playtime: 0")
+	return nil
+}
+
+func outputs(r *request) error {
+	r.printf("outputid: 0")
+	r.printf("outputname: tempo")
+	r.printf("plugin: tempo")
+	r.printf("outputenabled: 1")
+	return nil
+}
+
+func commands(r *request) error {
+	names := make([]string, 0, len(handlers)+3)
+	for name := range handlers {
+		names = append(names, name)
+	}
+	names = append(names, "close", "idle", "noidle")
+	sort.Strings(names)
+	for _, name := range names {
+		r.printf("command: %s", name)
+	}
+	return nil
+}
+
+// tagTypes lists the tags, ignoring the requests to choose them.
+func tagTypes(r *request) error {
+	if len(r.args) > 0 {
+		return nil
+	}
+	for _, name := range tagNames {
+		r.printf("tagtype: %s", name)
+	}
+	return nil
+}
+
+func decoders(r *request) error {
+	r.printf("plugin: tempo")
+	for _, suffix := range suffixes {
+		r.printf("suffix: %s", suffix)
+	}
+	return nil
+}
+
+// needArgs fails if the command has less than n arguments.
+func (r *request) needArgs(n int) error {
+	if len(r.args) < n {
+		return &ackError{code: ackArg, msg: "too few arguments"}
+	}
+	return nil
+}
+
+// posArg returns the position in the queue of the argument at arg.
+func (r *request) posArg(arg int) (int, error) {
+	i, err := strconv.Atoi(r.args[arg])
+	if err != nil {
+		return 0, &ackError{code: ackArg, msg: fmt.Sprintf("Integer expected: %s", r.args[arg])}
+	}
+	if i < 0 || i >= len(r.st.Queue) {
+		return 0, &ackError{code: ackArg, msg: "Bad song index"}
+	}
+	return i, nil
+}
+
+// idArg returns the position in the queue of the song with the id of the argument at arg.
+func (r *request) idArg(arg int) (int, error) {
+	id, err := strconv.Atoi(r.args[arg])
+	if err != nil {
+		return 0, &ackError{code: ackArg, msg: fmt.Sprintf("Integer expected: %s", r.args[arg])}
+	}
+	for i, item := range r.st.Queue {
+		if songID(item.Path) == id {
+			return i, nil
+		}
+	}
+	return 0, &ackError{code: ackNoExist, msg: "No such song"}
+}
+
+// rangeArg returns the positions of the queue in the argument at arg, a position or a range
+// like 3:10 or 3:, and the whole queue without it.
+func (r *request) rangeArg(arg int) (start, end int, err error) {
+	if arg >= len(r.args) {
+		return 0, len(r.st.Queue), nil
+	}
+	from, to, isRange := strings.Cut(r.args[arg], ":")
+	bad := &ackError{code: ackArg, msg: "Bad song index"}
+	if start, err = strconv.Atoi(from); err != nil || start < 0 {
+		return 0, 0, bad
+	}
+	end = start + 1
+	if isRange {
+		end = len(r.st.Queue)
+		if to != "" {
+			if end, err = strconv.Atoi(to); err != nil {
+				return 0, 0, bad
+			}
+		}
+	}
+	end = min(end, len(r.st.Queue))
+	if start >= end && !(isRange && start == end) {
+		return 0, 0, bad
+	}
+	return start, end, nil
+}
+
+// timeArg returns the time in seconds of the argument at arg, which may have a fraction.
+func (r *request) timeArg(arg int) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(r.args[arg], 64)
+	if err != nil || seconds < 0 || seconds > math.MaxInt32 {
+		return 0, &ackError{code: ackArg, msg: fmt.Sprintf("Number expected: %s", r.args[arg])}
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// boolArg returns the 0 or 1 of the argument at arg.
+func (r *request) boolArg(arg int) (bool, error) {
+	switch r.args[arg] {
+	case "0":
+		return false, nil
+	case "1":
+		return true, nil
+	}
+	return false, &ackError{code: ackArg, msg: fmt.Sprintf("Boolean (0/1) expected: %s", r.args[arg])}
+}
+
+// pathArg returns the path of the file of the argument at arg, which must be absolute since
+// the files are not relative to a music directory, and may be a file:// URI or an HTTP(S) URL.
+func (r *request) pathArg(arg int) (string, error) {
+	path := r.args[arg]
+	if engine.IsURL(path) {
+		return path, nil
+	}
+	if u, err := url.Parse(path); err == nil && u.Scheme == "file" {
+		path = u.Path
+	}
+	if !filepath.IsAbs(path) {
+		return "", &ackError{code: ackNoExist, msg: "Only absolute paths are supported"}
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", &ackError{code: ackNoExist, msg: "No such file or directory"}
+	}
+	return path, nil
+}
+
+// bit returns the 0 or 1 of b.
+func bit(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,373 @@
+// Package mpd serves a subset of the protocol of the Music Player Daemon on a TCP port, so its
+// clients, like ncmpcpp or the phone apps, can show and control what tempo plays: the status,
+// the queue and the playback. The library and the stored playlists are not served.
+package mpd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/remote"
+)
+
+// Version of the protocol told to the clients
+const protocolVersion = "0.23.5"
+
+// Subsystems of idle, told when what they stand for changes
+var subsystems = []string{"player", "mixer", "options", "playlist"}
+
+// Server : The MPD port of a running tempo, turning the commands into remote messages
+type Server struct {
+	listener net.Listener
+	send     func(tea.Msg)
+	started  time.Time
+
+	mu     sync.Mutex
+	status remote.Status
+	// When the status was told, to tell the seeks from the playback moving on
+	statusAt time.Time
+	// Number of changes of each subsystem
+	events map[string]int
+	// changed is closed when a subsystem changes, to wake the idle connections
+	changed chan struct{}
+	conns   map[net.Conn]struct{}
+	closed  bool
+}
+
+var _ remote.Remote = (*Server)(nil)
+
+// Listen listens for MPD clients at address, like localhost:6600, sending their commands to
+// the program with send.
+func Listen(address string, send func(tea.Msg)) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listener: listener,
+		send:     send,
+		started:  time.Now(),
+		status:   remote.Status{Index: -1},
+		events:   make(map[string]int),
+		changed:  make(chan struct{}),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	go s.accept()
+	return s, nil
+}
+
+// Update keeps the status to answer with, waking the idle clients if it changed.
+func (s *Server) Update(st remote.Status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, now := s.status, time.Now()
+	var changed []string
+	if st.State != prev.State || st.Path != prev.Path || st.Index != prev.Index || st.Seeked(prev, now.Sub(s.statusAt)) {
+		changed = append(changed, "player")
+	}
+	if st.Volume != prev.Volume || st.Muted != prev.Muted {
+		changed = append(changed, "mixer")
+	}
+	if st.Repeat != prev.Repeat || st.Speed != prev.Speed {
+		changed = append(changed, "options")
+	}
+	if st.QueueVersion != prev.QueueVersion {
+		changed = append(changed, "playlist")
+	}
+	s.status, s.statusAt = st, now
+
+	if len(changed) == 0 {
+		return
+	}
+	for _, name := range changed {
+		s.events[name]++
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Close stops listening and disconnects the clients.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	return s.listener.Close()
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			slog.Warn("Cannot accept an MPD client", "err", err)
+			continue
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		go s.serve(conn)
+	}
+}
+
+// snapshot returns the status along with the number of changes of each subsystem.
+func (s *Server) snapshot() (remote.Status, map[string]int, chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := make(map[string]int, len(s.events))
+	for name, n := range s.events {
+		events[name] = n
+	}
+	return s.status, events, s.changed
+}
+
+// client : A connection of an MPD client
+type client struct {
+	s     *Server
+	lines <-chan string
+	w     *bufio.Writer
+	// Number of changes of each subsystem the client was told of with idle
+	seen map[string]int
+}
+
+// serve answers the commands of a connection until it is closed.
+func (s *Server) serve(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	// The lines are read in background to wake the idle connections on noidle
+	lines := make(chan string)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			select {
+			case lines <- strings.TrimSuffix(scanner.Text(), "\r"):
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	_, seen, _ := s.snapshot()
+	c := &client{s: s, lines: lines, w: bufio.NewWriter(conn), seen: seen}
+	fmt.Fprintf(c.w, "OK MPD %s\n", protocolVersion)
+	for c.w.Flush() == nil {
+		line, ok := <-lines
+		if !ok || !c.handle(line) {
+			return
+		}
+	}
+}
+
+// handle answers a line, or the command list starting at it, returning false to close the
+// connection.
+func (c *client) handle(line string) bool {
+	name, _, _ := strings.Cut(line, " ")
+	switch name {
+	case "close":
+		return false
+	case "idle":
+		return c.idle(line)
+	case "command_list_begin", "command_list_ok_begin":
+		return c.commandList(name == "command_list_ok_begin")
+	}
+
+	var out bytes.Buffer
+	if err := c.run(line, &out); err != nil {
+		c.ack(err, 0)
+		return true
+	}
+	out.WriteTo(c.w)
+	c.w.WriteString("OK\n")
+	return true
+}
+
+// commandList runs the commands until command_list_end, stopping at the first one failing.
+// With listOK each one is followed by list_OK.
+func (c *client) commandList(listOK bool) bool {
+	var commands []string
+	for {
+		line, ok := <-c.lines
+		if !ok {
+			return false
+		}
+		if line == "command_list_end" {
+			break
+		}
+		commands = append(commands, line)
+	}
+
+	for i, line := range commands {
+		var out bytes.Buffer
+		if err := c.run(line, &out); err != nil {
+			c.ack(err, i)
+			return true
+		}
+		out.WriteTo(c.w)
+		if listOK {
+			c.w.WriteString("list_OK\n")
+		}
+	}
+	c.w.WriteString("OK\n")
+	return true
+}
+
+// idle waits for one of the subsystems given, or any if none, to change, or for noidle.
+func (c *client) idle(line string) bool {
+	args, err := splitArgs(line)
+	if err != nil {
+		c.ack(err, 0)
+		return true
+	}
+	wanted := args[1:]
+	if len(wanted) == 0 {
+		wanted = subsystems
+	}
+
+	for {
+		_, events, changed := c.s.snapshot()
+		var names []string
+		for _, name := range wanted {
+			if events[name] != c.seen[name] {
+				names = append(names, name)
+				c.seen[name] = events[name]
+			}
+		}
+		if len(names) > 0 {
+			for _, name := range names {
+				fmt.Fprintf(c.w, "changed: %s\n", name)
+			}
+			c.w.WriteString("OK\n")
+			return true
+		}
+
+		select {
+		case <-changed:
+		case line, ok := <-c.lines:
+			// Only noidle can be sent while idle
+			if !ok || line != "noidle" {
+				return false
+			}
+			c.w.WriteString("OK\n")
+			return true
+		}
+	}
+}
+
+// ack writes the error of the command at index i of a list.
+func (c *client) ack(err error, i int) {
+	var ae *ackError
+	if !errors.As(err, &ae) {
+		ae = &ackError{code: ackSystem, msg: err.Error()}
+	}
+	fmt.Fprintf(c.w, "ACK [%d@%d] {%s} %s\n", ae.code, i, ae.command, ae.msg)
+}
+
+// run runs a command, writing its response to out.
+func (c *client) run(line string, out *bytes.Buffer) error {
+	args, err := splitArgs(line)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return &ackError{code: ackUnknown, msg: "No command given"}
+	}
+	cmd, ok := handlers[args[0]]
+	if !ok {
+		return &ackError{code: ackUnknown, msg: fmt.Sprintf("unknown command %q", args[0])}
+	}
+
+	st, _, _ := c.s.snapshot()
+	err = cmd(&request{s: c.s, st: st, args: args[1:], out: out})
+	var ae *ackError
+	if errors.As(err, &ae) {
+		ae.command = args[0]
+	} else if err != nil {
+		err = &ackError{code: ackSystem, command: args[0], msg: err.Error()}
+	}
+	return err
+}
+
+// splitArgs splits a command line into its arguments, which are separated by spaces and may
+// be quoted, escaping the quotes and backslashes inside with a backslash.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+		if line[i] != '"' {
+			end := strings.IndexAny(line[i:], " \t")
+			if end < 0 {
+				end = len(line) - i
+			}
+			args = append(args, line[i:i+end])
+			i += end
+			continue
+		}
+
+		var arg strings.Builder
+		i++
+		for ; i < len(line) && line[i] != '"'; i++ {
+			if line[i] == '\\' && i+1 < len(line) {
+				i++
+			}
+			arg.WriteByte(line[i])
+		}
+		if i >= len(line) {
+			return nil, &ackError{code: ackArg, msg: "Missing closing '\"'"}
+		}
+		i++
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// Codes of the errors of the protocol
+const (
+	ackArg     = 2
+	ackUnknown = 5
+	ackNoExist = 50
+	ackSystem  = 52
+)
+
+// ackError : An error answered to a command
+type ackError struct {
+	code    int
+	command string
+	msg     string
+}
+
+func (e *ackError) Error() string {
+	return e.msg
+}
@@ -0,0 +1,22 @@
+// Package mpris shows tempo on the D-Bus session bus with the MPRIS interfaces, so the media
+// controls of the desktop, its applets and playerctl show what is being played and control
+// it. It is only supported on Linux.
+package mpris
+
+// BusName is the name tempo takes on the session bus, with ".instance<pid>" appended when
+// another tempo has it.
+const BusName string = "org.mpris.MediaPlayer2.tempo"
+
+const (
+	objectPath  = "/org/mpris/MediaPlayer2"
+	rootIface   = "org.mpris.MediaPlayer2"
+	playerIface = "org.mpris.MediaPlayer2.Player"
+
+	// Object path of the tracks, followed by an id of their path
+	trackPath = "/org/mpris/MediaPlayer2/tempo/track/"
+	// Object path of the track when there is none
+	noTrack = "/org/mpris/MediaPlayer2/TrackList/NoTrack"
+)
+
+// Types of the files tempo plays
+var mimeTypes = []string{"audio/mpeg", "audio/flac", "audio/ogg", "audio/vorbis", "audio/wav", "audio/x-wav"}
@@ -0,0 +1,395 @@
+package mpris
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/url"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+	"github.com/nicolito128/tempo/internal/remote"
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// Server : The MPRIS service of tempo on the session bus
+//
+// The UI tells it the status, which a goroutine turns into the properties of the service,
+// and the calls of the clients are sent to the program as remote messages.
+type Server struct {
+	conn  *dbus.Conn
+	props *prop.Properties
+	send  func(tea.Msg)
+
+	// Status not shown yet, the newest one told
+	mu      sync.Mutex
+	pending remote.Status
+	wake    chan struct{}
+	done    chan struct{}
+
+	// Status shown last and when, to tell the seeks from the playback moving on
+	shown   remote.Status
+	shownAt time.Time
+}
+
+var _ remote.Remote = (*Server)(nil)
+
+// New connects to the session bus and takes BusName, sending the requests of the clients to
+// the program with send.
+func New(send func(tea.Msg)) (*Server, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		conn: conn,
+		send: send,
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	if err := s.export(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := s.requestName(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go s.run()
+	return s, nil
+}
+
+// export serves the interfaces at the MPRIS object path.
+func (s *Server) export() error {
+	if err := s.conn.Export(rootMethods{s}, objectPath, rootIface); err != nil {
+		return err
+	}
+	if err := s.conn.ExportWithMap(playerMethods{s}, playerNames, objectPath, playerIface); err != nil {
+		return err
+	}
+
+	props, err := prop.Export(s.conn, objectPath, s.properties())
+	if err != nil {
+		return err
+	}
+	s.props = props
+
+	node := &introspect.Node{
+		Name: objectPath,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{
+				Name:       rootIface,
+				Methods:    introspect.Methods(rootMethods{}),
+				Properties: props.Introspection(rootIface),
+			},
+			{
+				Name:       playerIface,
+				Methods:    playerIntrospection(),
+				Properties: props.Introspection(playerIface),
+				Signals: []introspect.Signal{{
+					Name: "Seeked",
+					Args: []introspect.Arg{{Name: "Position", Type: "x"}},
+				}},
+			},
+		},
+	}
+	return s.conn.Export(introspect.NewIntrospectable(node), objectPath, "org.freedesktop.DBus.Introspectable")
+}
+
+// requestName takes BusName, or a name of its own if another tempo has it.
+func (s *Server) requestName() error {
+	for _, name := range []string{BusName, fmt.Sprintf("%s.instance%d", BusName, os.Getpid())} {
+		reply, err := s.conn.RequestName(name, dbus.NameFlagDoNotQueue)
+		if err != nil {
+			return err
+		}
+		if reply == dbus.RequestNameReplyPrimaryOwner {
+			return nil
+		}
+	}
+	return fmt.Errorf("the name %s is taken", BusName)
+}
+
+// properties returns the properties of the interfaces before anything is played.
+func (s *Server) properties() prop.Map {
+	return prop.Map{
+		rootIface: {
+			"CanQuit":             {Value: true, Emit: prop.EmitConst},
+			"CanRaise":            {Value: false, Emit: prop.EmitConst},
+			"HasTrackList":        {Value: false, Emit: prop.EmitConst},
+			"Identity":            {Value: "tempo", Emit: prop.EmitConst},
+			"SupportedUriSchemes": {Value: []string{"file", "http", "https"}, Emit: prop.EmitConst},
+			"SupportedMimeTypes":  {Value: mimeTypes, Emit: prop.EmitConst},
+		},
+		playerIface: {
+			"PlaybackStatus": {Value: remote.Stopped.String(), Emit: prop.EmitTrue},
+			"LoopStatus":     {Value: "None", Writable: true, Emit: prop.EmitTrue, Callback: s.setLoopStatus},
+			"Rate":           {Value: 1.0, Writable: true, Emit: prop.EmitTrue, Callback: s.setRate},
+			"Metadata":       {Value: metadata(remote.Status{}), Emit: prop.EmitTrue},
+			"Volume":         {Value: 1.0, Writable: true, Emit: prop.EmitTrue, Callback: s.setVolume},
+			"Position":       {Value: int64(0), Emit: prop.EmitFalse},
+			"MinimumRate":    {Value: engine.MinSpeed, Emit: prop.EmitConst},
+			"MaximumRate":    {Value: engine.MaxSpeed, Emit: prop.EmitConst},
+			"CanGoNext":      {Value: false, Emit: prop.EmitTrue},
+			"CanGoPrevious":  {Value: false, Emit: prop.EmitTrue},
+			"CanPlay":        {Value: false, Emit: prop.EmitTrue},
+			"CanPause":       {Value: false, Emit: prop.EmitTrue},
+			"CanSeek":        {Value: false, Emit: prop.EmitTrue},
+			"CanControl":     {Value: true, Emit: prop.EmitConst},
+		},
+	}
+}
+
+// Update shows the status to the clients in background.
+func (s *Server) Update(st remote.Status) {
+	s.mu.Lock()
+	s.pending = st
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close leaves the bus.
+func (s *Server) Close() error {
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	close(s.done)
+	return s.conn.Close()
+}
+
+func (s *Server) run() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.wake:
+			s.mu.Lock()
+			st := s.pending
+			s.mu.Unlock()
+			s.show(st)
+		}
+	}
+}
+
+// show sets the properties that changed, and tells the clients of a seek.
+func (s *Server) show(st remote.Status) {
+	volume := float64(st.Volume) / 100
+	if st.Muted {
+		volume = 0
+	}
+	loop := "None"
+	if st.Repeat {
+		loop = "Playlist"
+	}
+	hasAudio := st.Path != ""
+
+	s.set(playerIface, "PlaybackStatus", st.State.String())
+	s.set(playerIface, "LoopStatus", loop)
+	s.set(playerIface, "Rate", st.Speed)
+	s.set(playerIface, "Metadata", metadata(st))
+	s.set(playerIface, "Volume", volume)
+	s.set(playerIface, "Position", st.Position.Microseconds())
+	s.set(playerIface, "CanGoNext", st.HasNext)
+	s.set(playerIface, "CanGoPrevious", st.HasPrevious)
+	s.set(playerIface, "CanPlay", hasAudio || st.HasNext)
+	s.set(playerIface, "CanPause", hasAudio)
+	s.set(playerIface, "CanSeek", hasAudio && !st.Live)
+
+	now := time.Now()
+	if st.Seeked(s.shown, now.Sub(s.shownAt)) {
+		s.conn.Emit(objectPath, playerIface+".Seeked", st.Position.Microseconds())
+	}
+	s.shown, s.shownAt = st, now
+}
+
+// set sets a property, which tells the clients of it, unless it has that value already.
+func (s *Server) set(iface, name string, value any) {
+	if reflect.DeepEqual(s.props.GetMust(iface, name), value) {
+		return
+	}
+	s.props.SetMust(iface, name, value)
+}
+
+// metadata returns the metadata of the file being played.
+func metadata(st remote.Status) map[string]dbus.Variant {
+	if st.Path == "" {
+		return map[string]dbus.Variant{"mpris:trackid": dbus.MakeVariant(dbus.ObjectPath(noTrack))}
+	}
+
+	m := map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(trackID(st.Path)),
+		"xesam:title":   dbus.MakeVariant(st.Title),
+		"xesam:url":     dbus.MakeVariant(fileURL(st.Path)),
+	}
+	// A live stream has no length
+	if !st.Live {
+		m["mpris:length"] = dbus.MakeVariant(st.Length.Microseconds())
+	}
+	if st.Artist != "" {
+		m["xesam:artist"] = dbus.MakeVariant([]string{st.Artist})
+	}
+	if st.Album != "" {
+		m["xesam:album"] = dbus.MakeVariant(st.Album)
+	}
+	if st.Track > 0 {
+		m["xesam:trackNumber"] = dbus.MakeVariant(int32(st.Track))
+	}
+	return m
+}
+
+// trackID returns the object path standing for the file at path, which only takes some
+// characters.
+func trackID(path string) dbus.ObjectPath {
+	h := fnv.New64a()
+	h.Write([]byte(path))
+	return dbus.ObjectPath(fmt.Sprintf("%s%016x", trackPath, h.Sum64()))
+}
+
+// fileURL returns the URL of the file at path, which is the path itself for the ones served
+// over HTTP.
+func fileURL(path string) string {
+	if engine.IsURL(path) {
+		return path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+func (s *Server) setLoopStatus(c *prop.Change) *dbus.Error {
+	switch c.Value.(string) {
+	case "None":
+		s.send(remote.SetRepeatMsg{Repeat: false})
+	case "Playlist", "Track":
+		// Repeating the track is not supported, the queue is repeated instead
+		s.send(remote.SetRepeatMsg{Repeat: true})
+	default:
+		return prop.ErrInvalidArg
+	}
+	return nil
+}
+
+func (s *Server) setRate(c *prop.Change) *dbus.Error {
+	rate := c.Value.(float64)
+	if rate < engine.MinSpeed || rate > engine.MaxSpeed {
+		return prop.ErrInvalidArg
+	}
+	s.send(remote.SetSpeedMsg{Speed: rate})
+	return nil
+}
+
+func (s *Server) setVolume(c *prop.Change) *dbus.Error {
+	volume := max(min(c.Value.(float64), 1), 0)
+	s.send(remote.SetVolumeMsg{Volume: int(math.Round(volume * 100))})
+	return nil
+}
+
+// rootMethods : The methods of the org.mpris.MediaPlayer2 interface
+type rootMethods struct {
+	s *Server
+}
+
+// Raise does nothing, the terminal of tempo cannot be brought to the front.
+func (m rootMethods) Raise() *dbus.Error {
+	return nil
+}
+
+func (m rootMethods) Quit() *dbus.Error {
+	m.s.send(remote.CommandMsg{Command: remote.Quit})
+	return nil
+}
+
+// playerMethods : The methods of the org.mpris.MediaPlayer2.Player interface
+type playerMethods struct {
+	s *Server
+}
+
+// Names of the methods of playerMethods on the bus which are not their Go names, as Seek
+// would take the signature of io.Seeker
+var playerNames = map[string]string{"SeekBy": "Seek"}
+
+// playerIntrospection returns the methods of playerMethods with their names on the bus.
+func playerIntrospection() []introspect.Method {
+	methods := introspect.Methods(playerMethods{})
+	for i, m := range methods {
+		if name, ok := playerNames[m.Name]; ok {
+			methods[i].Name = name
+		}
+	}
+	return methods
+}
+
+func (m playerMethods) Next() *dbus.Error {
+	m.s.send(remote.CommandMsg{Command: remote.Next})
+	return nil
+}
+
+func (m playerMethods) Previous() *dbus.Error {
+	m.s.send(remote.CommandMsg{Command: remote.Previous})
+	return nil
+}
+
+func (m playerMethods) Pause() *dbus.Error {
+	m.s.send(remote.CommandMsg{Command: remote.Pause})
+	return nil
+}
+
+func (m playerMethods) PlayPause() *dbus.Error {
+	m.s.send(remote.CommandMsg{Command: remote.PlayPause})
+	return nil
+}
+
+func (m playerMethods) Stop() *dbus.Error {
+	m.s.send(remote.CommandMsg{Command: remote.Stop})
+	return nil
+}
+
+func (m playerMethods) Play() *dbus.Error {
+	m.s.send(remote.CommandMsg{Command: remote.Play})
+	return nil
+}
+
+// SeekBy moves the playback by offset microseconds.
+func (m playerMethods) SeekBy(offset int64) *dbus.Error {
+	m.s.send(remote.SeekMsg{Offset: time.Duration(offset) * time.Microsecond})
+	return nil
+}
+
+// SetPosition moves the playback of the track to position microseconds, unless it is not
+// the one being played.
+func (m playerMethods) SetPosition(track dbus.ObjectPath, position int64) *dbus.Error {
+	m.s.mu.Lock()
+	path := m.s.pending.Path
+	m.s.mu.Unlock()
+	if path == "" || trackID(path) != track {
+		return nil
+	}
+	m.s.send(remote.SetPositionMsg{Path: path, Position: time.Duration(position) * time.Microsecond})
+	return nil
+}
+
+// OpenUri plays the file of a file:// URI, or the audio of an HTTP(S) URL.
+func (m playerMethods) OpenUri(uri string) *dbus.Error {
+	if engine.IsURL(uri) {
+		m.s.send(remote.OpenMsg{Path: uri})
+		return nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return dbus.MakeFailedError(fmt.Errorf("unsupported URI %q, only file:// and http(s):// ones are", uri))
+	}
+	m.s.send(remote.OpenMsg{Path: u.Path})
+	return nil
+}
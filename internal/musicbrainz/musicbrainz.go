@@ -0,0 +1,215 @@
+// Package musicbrainz looks up the metadata of recordings in the MusicBrainz database.
+package musicbrainz
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/httpclient"
+	"github.com/nicolito128/tempo/internal/tags"
+)
+
+const (
+	// BaseURL of the MusicBrainz web service
+	BaseURL string = "https://musicbrainz.org/ws/2"
+	// Interval between requests allowed by MusicBrainz
+	Interval time.Duration = time.Second
+	// How long the responses are cached
+	CacheTTL time.Duration = 7 * 24 * time.Hour
+	// Maximum number of matches returned by a search
+	MaxMatches int = 10
+)
+
+// Match : A recording of the MusicBrainz database, as found in one of its releases
+type Match struct {
+	// Score of the search from 0 to 100, 100 for lookups by ID
+	Score int
+
+	RecordingID string
+	ReleaseID   string
+
+	// Tags of the recording in the release
+	Tags tags.Tags
+
+	// Year of the release, zero if unknown
+	Year int
+
+	// Length of the recording, zero if unknown
+	Length time.Duration
+}
+
+// Client : A client of the MusicBrainz web service
+type Client struct {
+	http    *httpclient.Client
+	baseURL string
+}
+
+// New creates a client making its requests with the given HTTP client, which should
+// wait Interval between requests.
+func New(http *httpclient.Client) *Client {
+	return &Client{http: http, baseURL: BaseURL}
+}
+
+// Search finds the recordings matching the title, artist and album of the tags, best first.
+// The length of the audio, if known, helps to choose between the results.
+func (c *Client) Search(ctx context.Context, t tags.Tags, length time.Duration) ([]Match, error) {
+	if strings.TrimSpace(t.Title) == "" {
+		return nil, errors.New("a title is needed to search MusicBrainz")
+	}
+
+	query := "recording:" + phrase(t.Title)
+	if t.Artist != "" {
+		query += " AND artist:" + phrase(t.Artist)
+	}
+	// Optional terms only raise the score of the results having them
+	if t.Album != "" {
+		query += " release:" + phrase(t.Album)
+	}
+	if length > 0 {
+		query += " qdur:" + strconv.FormatInt(length.Milliseconds()/2000, 10)
+	}
+
+	params := url.Values{"query": {query}, "fmt": {"json"}, "limit": {"10"}}
+	var resp struct {
+		Recordings []recording `json:"recordings"`
+	}
+	if err := c.http.GetJSON(ctx, c.baseURL+"/recording/?"+params.Encode(), &resp); err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	for _, r := range resp.Recordings {
+		matches = append(matches, r.matches()...)
+	}
+
+	// The releases named like the album come first among the ones of the same score
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		return cmp.Compare(sameTitle(b.Tags.Album, t.Album), sameTitle(a.Tags.Album, t.Album))
+	})
+	if len(matches) > MaxMatches {
+		matches = matches[:MaxMatches]
+	}
+	return matches, nil
+}
+
+// Lookup returns the recording with the given MusicBrainz ID, once per release.
+func (c *Client) Lookup(ctx context.Context, id string) ([]Match, error) {
+	params := url.Values{"inc": {"artists releases media"}, "fmt": {"json"}}
+	var r recording
+	if err := c.http.GetJSON(ctx, c.baseURL+"/recording/"+url.PathEscape(id)+"?"+params.Encode(), &r); err != nil {
+		return nil, err
+	}
+	r.Score = 100
+	return r.matches(), nil
+}
+
+// recording : A recording in the responses of the web service
+type recording struct {
+	ID           string         `json:"id"`
+	Score        int            `json:"score"`
+	Title        string         `json:"title"`
+	Length       int64          `json:"length"`
+	ArtistCredit []artistCredit `json:"artist-credit"`
+	Releases     []release      `json:"releases"`
+}
+
+type artistCredit struct {
+	Name       string `json:"name"`
+	JoinPhrase string `json:"joinphrase"`
+}
+
+type release struct {
+	ID           string         `json:"id"`
+	Title        string         `json:"title"`
+	Date         string         `json:"date"`
+	ArtistCredit []artistCredit `json:"artist-credit"`
+	Media        []medium       `json:"media"`
+}
+
+type medium struct {
+	Position   int `json:"position"`
+	TrackCount int `json:"track-count"`
+
+	// Searches name the list of tracks "track", and lookups "tracks"
+	Track  []track `json:"track"`
+	Tracks []track `json:"tracks"`
+}
+
+type track struct {
+	Number string `json:"number"`
+}
+
+// matches returns the recording once per release, or alone if it has no releases.
+func (r recording) matches() []Match {
+	base := Match{
+		Score:       r.Score,
+		RecordingID: r.ID,
+		Length:      time.Duration(r.Length) * time.Millisecond,
+		Tags: tags.Tags{
+			Title:  r.Title,
+			Artist: credit(r.ArtistCredit),
+		},
+	}
+	if len(r.Releases) == 0 {
+		return []Match{base}
+	}
+
+	var matches []Match
+	for _, rel := range r.Releases {
+		m := base
+		m.ReleaseID = rel.ID
+		m.Tags.Album = rel.Title
+		if artist := credit(rel.ArtistCredit); artist != "" && artist != m.Tags.Artist {
+			m.Tags.AlbumArtist = artist
+		}
+		if len(rel.Date) >= 4 {
+			m.Year, _ = strconv.Atoi(rel.Date[:4])
+		}
+
+		// Only the medium with the recording is listed
+		if len(rel.Media) > 0 {
+			med := rel.Media[0]
+			m.Tags.Disc = med.Position
+			m.Tags.TrackTotal = med.TrackCount
+			tracks := append(med.Track, med.Tracks...)
+			if len(tracks) > 0 {
+				m.Tags.Track, _ = strconv.Atoi(tracks[0].Number)
+			}
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// credit joins the names of the credited artists, like "Artist feat. Other".
+func credit(credits []artistCredit) string {
+	var b strings.Builder
+	for _, c := range credits {
+		b.WriteString(c.Name + c.JoinPhrase)
+	}
+	return b.String()
+}
+
+// phrase quotes the text as a phrase of a search query.
+func phrase(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// sameTitle returns 1 if both titles are equal ignoring case, and 0 otherwise.
+func sameTitle(a, b string) int {
+	if b != "" && strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b)) {
+		return 1
+	}
+	return 0
+}
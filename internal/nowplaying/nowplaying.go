@@ -0,0 +1,18 @@
+// Package nowplaying shows what tempo plays in the Now Playing center of macOS, with its
+// cover, and takes the commands of the system: the media keys, Control Center and the
+// gestures of the headphones. It is only supported on macOS.
+package nowplaying
+
+// State of the playback, as told to the Now Playing center
+const (
+	statePlaying = 1
+	statePaused  = 2
+	stateStopped = 3
+)
+
+// Answers of the command handlers
+const (
+	commandSuccess = 0
+	// Nothing is being played to apply the command to
+	commandNoItem = 110
+)
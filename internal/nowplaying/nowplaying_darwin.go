@@ -0,0 +1,350 @@
+package nowplaying
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+	"unsafe"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ebitengine/purego"
+	"github.com/ebitengine/purego/objc"
+	"github.com/nicolito128/tempo/internal/art"
+	"github.com/nicolito128/tempo/internal/remote"
+)
+
+func init() {
+	// The application loop of Main must run on the main thread, where main starts
+	runtime.LockOSThread()
+}
+
+const (
+	appKitPath      = "/System/Library/Frameworks/AppKit.framework/AppKit"
+	mediaPlayerPath = "/System/Library/Frameworks/MediaPlayer.framework/MediaPlayer"
+
+	// An application without windows nor icon in the Dock
+	activationProhibited = 2
+)
+
+var (
+	selAlloc         = objc.RegisterName("alloc")
+	selNew           = objc.RegisterName("new")
+	selRelease       = objc.RegisterName("release")
+	selDrain         = objc.RegisterName("drain")
+	selString        = objc.RegisterName("stringWithUTF8String:")
+	selNumberDouble  = objc.RegisterName("numberWithDouble:")
+	selNumberInteger = objc.RegisterName("numberWithInteger:")
+	selDictionary    = objc.RegisterName("dictionary")
+	selSetObject     = objc.RegisterName("setObject:forKey:")
+	selDataWithBytes = objc.RegisterName("dataWithBytes:length:")
+	selInitWithData  = objc.RegisterName("initWithData:")
+	selInitWithImage = objc.RegisterName("initWithImage:")
+	selDefaultCenter = objc.RegisterName("defaultCenter")
+	selSetInfo       = objc.RegisterName("setNowPlayingInfo:")
+	selSetState      = objc.RegisterName("setPlaybackState:")
+	selCommandCenter = objc.RegisterName("sharedCommandCenter")
+	selAddHandler    = objc.RegisterName("addTargetWithHandler:")
+	selRemoveTarget  = objc.RegisterName("removeTarget:")
+	selSetEnabled    = objc.RegisterName("setEnabled:")
+	selPositionTime  = objc.RegisterName("positionTime")
+	selSharedApp     = objc.RegisterName("sharedApplication")
+	selSetActivation = objc.RegisterName("setActivationPolicy:")
+	selRun           = objc.RegisterName("run")
+	selPlayCommand   = objc.RegisterName("playCommand")
+	selPauseCommand  = objc.RegisterName("pauseCommand")
+	selToggleCommand = objc.RegisterName("togglePlayPauseCommand")
+	selStopCommand   = objc.RegisterName("stopCommand")
+	selNextCommand   = objc.RegisterName("nextTrackCommand")
+	selPrevCommand   = objc.RegisterName("previousTrackCommand")
+	selPositionCmd   = objc.RegisterName("changePlaybackPositionCommand")
+)
+
+// Main runs main on another goroutine while the main thread runs the application loop the
+// commands of the system are delivered on, and exits when main returns. Without AppKit main
+// just runs.
+func Main(main func()) {
+	if _, err := purego.Dlopen(appKitPath, purego.RTLD_GLOBAL|purego.RTLD_NOW); err != nil {
+		main()
+		return
+	}
+	app := objc.ID(objc.GetClass("NSApplication")).Send(selSharedApp)
+	app.Send(selSetActivation, activationProhibited)
+
+	go func() {
+		main()
+		os.Exit(0)
+	}()
+	app.Send(selRun)
+}
+
+// Keys of the Now Playing information
+var infoKeys = []string{
+	"MPMediaItemPropertyTitle",
+	"MPMediaItemPropertyArtist",
+	"MPMediaItemPropertyAlbumTitle",
+	"MPMediaItemPropertyAlbumTrackNumber",
+	"MPMediaItemPropertyPlaybackDuration",
+	"MPMediaItemPropertyArtwork",
+	"MPNowPlayingInfoPropertyElapsedPlaybackTime",
+	"MPNowPlayingInfoPropertyPlaybackRate",
+	"MPNowPlayingInfoPropertyIsLiveStream",
+}
+
+// Server : The entry of tempo in the Now Playing center
+//
+// The UI tells it the status, which a goroutine shows in the center, and the commands of the
+// system are sent to the program as remote messages.
+type Server struct {
+	send     func(tea.Msg)
+	center   objc.ID
+	commands objc.ID
+	keys     map[string]objc.ID
+
+	// Handlers added to the commands, removed when closing
+	targets map[objc.ID]objc.ID
+	blocks  []objc.Block
+
+	// Status not shown yet, the newest one told
+	mu      sync.Mutex
+	pending remote.Status
+	wake    chan struct{}
+	done    chan struct{}
+
+	// Status shown last and when, to tell the seeks from the playback moving on, and the
+	// cover of its file
+	shown   remote.Status
+	shownAt time.Time
+	artwork objc.ID
+}
+
+var _ remote.Remote = (*Server)(nil)
+
+// New shows tempo in the Now Playing center, sending the commands of the system to the program
+// with send. The commands only arrive while Main runs the application loop.
+func New(send func(tea.Msg)) (*Server, error) {
+	lib, err := purego.Dlopen(mediaPlayerPath, purego.RTLD_GLOBAL|purego.RTLD_NOW)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		send:    send,
+		keys:    make(map[string]objc.ID, len(infoKeys)),
+		targets: make(map[objc.ID]objc.ID),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	for _, name := range infoKeys {
+		// The keys are NSString constants, read through the address of their symbol
+		sym, err := purego.Dlsym(lib, name)
+		if err != nil {
+			return nil, err
+		}
+		s.keys[name] = **(**objc.ID)(unsafe.Pointer(&sym))
+	}
+	s.center = objc.ID(objc.GetClass("MPNowPlayingInfoCenter")).Send(selDefaultCenter)
+	s.commands = objc.ID(objc.GetClass("MPRemoteCommandCenter")).Send(selCommandCenter)
+
+	for sel, c := range map[objc.SEL]remote.Command{
+		selPlayCommand:   remote.Play,
+		selPauseCommand:  remote.Pause,
+		selToggleCommand: remote.PlayPause,
+		selStopCommand:   remote.Stop,
+		selNextCommand:   remote.Next,
+		selPrevCommand:   remote.Previous,
+	} {
+		s.handle(sel, func(objc.ID) int {
+			s.send(remote.CommandMsg{Command: c})
+			return commandSuccess
+		})
+	}
+	s.handle(selPositionCmd, s.changePosition)
+
+	go s.run()
+	return s, nil
+}
+
+// handle adds the handler to the command of the command center.
+func (s *Server) handle(sel objc.SEL, handler func(event objc.ID) int) {
+	block := objc.NewBlock(func(_ objc.Block, event objc.ID) int {
+		return handler(event)
+	})
+	command := s.commands.Send(sel)
+	s.targets[command] = command.Send(selAddHandler, block)
+	s.blocks = append(s.blocks, block)
+}
+
+// changePosition moves the playback to where the seek bar of the center was dragged.
+func (s *Server) changePosition(event objc.ID) int {
+	s.mu.Lock()
+	st := s.pending
+	s.mu.Unlock()
+	if st.Path == "" {
+		return commandNoItem
+	}
+	seconds := objc.Send[float64](event, selPositionTime)
+	pos := time.Duration(seconds * float64(time.Second))
+	s.send(remote.SetPositionMsg{Path: st.Path, Position: max(min(pos, st.Length), 0)})
+	return commandSuccess
+}
+
+// Update shows the status in the center in background.
+func (s *Server) Update(st remote.Status) {
+	s.mu.Lock()
+	s.pending = st
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close removes tempo from the center and stops taking its commands.
+func (s *Server) Close() error {
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	close(s.done)
+	for command, target := range s.targets {
+		command.Send(selRemoveTarget, target)
+	}
+	for _, block := range s.blocks {
+		block.Release()
+	}
+	return nil
+}
+
+func (s *Server) run() {
+	// Objective-C objects are released by the autorelease pools of the thread making them
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	for {
+		select {
+		case <-s.done:
+			s.clear()
+			return
+		case <-s.wake:
+			s.mu.Lock()
+			st := s.pending
+			s.mu.Unlock()
+			s.show(st)
+		}
+	}
+}
+
+// show sets the information of the center if the file, the playback or its position changed.
+func (s *Server) show(st remote.Status) {
+	prev, now := s.shown, time.Now()
+	first, seeked := s.shownAt.IsZero(), st.Seeked(prev, now.Sub(s.shownAt))
+	s.shown, s.shownAt = st, now
+
+	pool := objc.ID(objc.GetClass("NSAutoreleasePool")).Send(selNew)
+	defer pool.Send(selDrain)
+
+	if st.HasNext != prev.HasNext || st.HasPrevious != prev.HasPrevious || first {
+		s.commands.Send(selNextCommand).Send(selSetEnabled, st.HasNext)
+		s.commands.Send(selPrevCommand).Send(selSetEnabled, st.HasPrevious)
+	}
+	if st.Path != prev.Path {
+		s.setArtwork(st.Path)
+	}
+	if !seeked && st.Path == prev.Path && st.State == prev.State && st.Speed == prev.Speed &&
+		st.Title == prev.Title && st.Artist == prev.Artist && st.Album == prev.Album && st.Length == prev.Length {
+		return
+	}
+
+	if st.Path == "" {
+		s.center.Send(selSetInfo, objc.ID(0))
+		s.center.Send(selSetState, stateStopped)
+		return
+	}
+	rate := st.Speed
+	if st.State != remote.Playing {
+		rate = 0
+	}
+	info := objc.ID(objc.GetClass("NSMutableDictionary")).Send(selDictionary)
+	s.setString(info, "MPMediaItemPropertyTitle", st.Title)
+	s.setString(info, "MPMediaItemPropertyArtist", st.Artist)
+	s.setString(info, "MPMediaItemPropertyAlbumTitle", st.Album)
+	if st.Track > 0 {
+		info.Send(selSetObject, number(selNumberInteger, st.Track), s.keys["MPMediaItemPropertyAlbumTrackNumber"])
+	}
+	if st.Live {
+		info.Send(selSetObject, number(selNumberInteger, 1), s.keys["MPNowPlayingInfoPropertyIsLiveStream"])
+	} else {
+		info.Send(selSetObject, number(selNumberDouble, st.Length.Seconds()), s.keys["MPMediaItemPropertyPlaybackDuration"])
+	}
+	info.Send(selSetObject, number(selNumberDouble, st.Position.Seconds()), s.keys["MPNowPlayingInfoPropertyElapsedPlaybackTime"])
+	info.Send(selSetObject, number(selNumberDouble, rate), s.keys["MPNowPlayingInfoPropertyPlaybackRate"])
+	if s.artwork != 0 {
+		info.Send(selSetObject, s.artwork, s.keys["MPMediaItemPropertyArtwork"])
+	}
+	s.center.Send(selSetInfo, info)
+
+	state := statePaused
+	switch st.State {
+	case remote.Playing:
+		state = statePlaying
+	case remote.Stopped:
+		state = stateStopped
+	}
+	s.center.Send(selSetState, state)
+}
+
+// setString sets the key of the information to s, unless it is empty.
+func (s *Server) setString(info objc.ID, key, value string) {
+	if value != "" {
+		info.Send(selSetObject, nsString(value), s.keys[key])
+	}
+}
+
+// setArtwork keeps the cover of the file at path to show along with it, if it has one.
+func (s *Server) setArtwork(path string) {
+	if s.artwork != 0 {
+		s.artwork.Send(selRelease)
+		s.artwork = 0
+	}
+	if path == "" {
+		return
+	}
+	img, err := art.Cover(path)
+	if err != nil {
+		return
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return
+	}
+	b := buf.Bytes()
+	data := objc.ID(objc.GetClass("NSData")).Send(selDataWithBytes, &b[0], len(b))
+	picture := objc.ID(objc.GetClass("NSImage")).Send(selAlloc).Send(selInitWithData, data)
+	if picture == 0 {
+		return
+	}
+	s.artwork = objc.ID(objc.GetClass("MPMediaItemArtwork")).Send(selAlloc).Send(selInitWithImage, picture)
+	picture.Send(selRelease)
+}
+
+// clear removes tempo from the center.
+func (s *Server) clear() {
+	s.center.Send(selSetInfo, objc.ID(0))
+	s.center.Send(selSetState, stateStopped)
+	if s.artwork != 0 {
+		s.artwork.Send(selRelease)
+		s.artwork = 0
+	}
+}
+
+// nsString returns s as an autoreleased NSString.
+func nsString(s string) objc.ID {
+	return objc.ID(objc.GetClass("NSString")).Send(selString, s)
+}
+
+// number returns n as an autoreleased NSNumber, made with the selector of its type.
+func number[T int | float64](sel objc.SEL, n T) objc.ID {
+	return objc.ID(objc.GetClass("NSNumber")).Send(sel, n)
+}
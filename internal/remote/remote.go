@@ -0,0 +1,188 @@
+// Package remote connects tempo to the programs controlling it from outside the terminal,
+// like the media controls of the desktop. They are told what is being played with a Status
+// after each change, and their requests reach the UI as messages sent to the program.
+package remote
+
+import (
+	"fmt"
+	"time"
+)
+
+// State : Whether the audio is playing
+type State int
+
+const (
+	// Nothing is loaded, or the audio reached its end
+	Stopped State = iota
+	Playing
+	Paused
+)
+
+func (s State) String() string {
+	switch s {
+	case Playing:
+		return "Playing"
+	case Paused:
+		return "Paused"
+	default:
+		return "Stopped"
+	}
+}
+
+// Status : What is being played, as shown to the remotes
+type Status struct {
+	State State
+	// Path of the audio file, empty if there is none
+	Path   string
+	Title  string
+	Artist string
+	Album  string
+	Track  int
+
+	Length   time.Duration
+	Position time.Duration
+	// Live if the audio is the stream of an internet radio, which has no Length and cannot be
+	// seeked
+	Live bool
+
+	// Volume from 0 to 100, and Speed of the playback, 1 being the normal one
+	Volume int
+	Muted  bool
+	Speed  float64
+
+	// Repeat if the queue starts over after its last file
+	Repeat bool
+	// HasNext and HasPrevious if the queue has a file after and before the current one
+	HasNext     bool
+	HasPrevious bool
+
+	// Queue of files, shared between the statuses so it must not be changed, with the Index
+	// of the current one, -1 if there is none. QueueVersion changes each time the queue does.
+	Queue        []QueueItem
+	Index        int
+	QueueVersion int
+}
+
+// QueueItem : A file of the queue
+type QueueItem struct {
+	Path   string
+	Title  string
+	Artist string
+	Album  string
+	Track  int
+}
+
+// Largest difference between the position of a status and where the playback was expected to
+// be from the previous one before it counts as a seek, more than the time between two updates
+const seekTolerance = time.Second
+
+// Seeked reports whether the playback was moved since prev, told elapsed ago, instead of
+// going on from there.
+func (st Status) Seeked(prev Status, elapsed time.Duration) bool {
+	if st.Path == "" || st.Path != prev.Path {
+		return false
+	}
+	expected := prev.Position
+	if prev.State == Playing {
+		expected += time.Duration(float64(elapsed) * prev.Speed)
+	}
+	diff := st.Position - expected
+	return diff > seekTolerance || diff < -seekTolerance
+}
+
+// Remote : A program controlling tempo, told the status after each change
+type Remote interface {
+	// Update tells the current status, without blocking the UI
+	Update(Status)
+	Close() error
+}
+
+// Command : A request of a remote without arguments
+type Command int
+
+const (
+	Play Command = iota
+	Pause
+	PlayPause
+	// Stop pauses the playback and moves it back to the start
+	Stop
+	Next
+	Previous
+	Quit
+)
+
+// Names of the commands, the ones of tempo ctl
+var commandNames = map[string]Command{
+	"play":     Play,
+	"pause":    Pause,
+	"toggle":   PlayPause,
+	"stop":     Stop,
+	"next":     Next,
+	"previous": Previous,
+	"quit":     Quit,
+}
+
+// ParseCommand returns the command named like in tempo ctl, like "toggle".
+func ParseCommand(name string) (Command, error) {
+	command, ok := commandNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown command %q, it is play, pause, toggle, stop, next, previous or quit", name)
+	}
+	return command, nil
+}
+
+// CommandMsg carries a command of a remote.
+type CommandMsg struct {
+	Command Command
+}
+
+// SeekMsg moves the playback by Offset from the current position, backwards if negative.
+type SeekMsg struct {
+	Offset time.Duration
+}
+
+// SetPositionMsg moves the playback of the file at Path to Position, unless another one is
+// being played.
+type SetPositionMsg struct {
+	Path     string
+	Position time.Duration
+}
+
+// SetVolumeMsg sets the volume, from 0 to 100.
+type SetVolumeMsg struct {
+	Volume int
+}
+
+// SetSpeedMsg sets the speed of the playback.
+type SetSpeedMsg struct {
+	Speed float64
+}
+
+// SetRepeatMsg sets if the queue starts over after its last file.
+type SetRepeatMsg struct {
+	Repeat bool
+}
+
+// OpenMsg adds the file at Path to the queue and plays it.
+type OpenMsg struct {
+	Path string
+}
+
+// PlayIndexMsg plays the file at Index in the queue.
+type PlayIndexMsg struct {
+	Index int
+}
+
+// RemoveMsg removes the files at Path from the queue.
+type RemoveMsg struct {
+	Path string
+}
+
+// ClearMsg removes every file from the queue.
+type ClearMsg struct{}
+
+// EnqueueMsg adds the files at Paths to the end of the queue, playing the first one if
+// nothing is being played. They may be directories and playlists too.
+type EnqueueMsg struct {
+	Paths []string
+}
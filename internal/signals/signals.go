@@ -0,0 +1,68 @@
+// Package signals controls the player with the user signals of POSIX, so the key bindings of
+// window managers can run commands like pkill -USR1 tempo without tempo ctl.
+package signals
+
+import (
+	"os"
+	"os/signal"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/remote"
+)
+
+// Listener : The signals sending their commands, taking the place of a remote
+type Listener struct {
+	signals chan os.Signal
+	done    chan struct{}
+}
+
+var _ remote.Remote = (*Listener)(nil)
+
+// listen sends the command of each signal of commands as it arrives. The ones with an empty
+// name are ignored, rather than killing tempo.
+func listen(commands map[os.Signal]string, send func(tea.Msg)) (*Listener, error) {
+	msgs := make(map[os.Signal]tea.Msg)
+	for sig, name := range commands {
+		if name == "" {
+			msgs[sig] = nil
+			continue
+		}
+		command, err := remote.ParseCommand(name)
+		if err != nil {
+			return nil, err
+		}
+		msgs[sig] = remote.CommandMsg{Command: command}
+	}
+
+	l := &Listener{signals: make(chan os.Signal, 1), done: make(chan struct{})}
+	for sig := range msgs {
+		signal.Notify(l.signals, sig)
+	}
+	go func() {
+		for {
+			select {
+			case <-l.done:
+				return
+			case sig := <-l.signals:
+				if msg := msgs[sig]; msg != nil {
+					send(msg)
+				}
+			}
+		}
+	}()
+	return l, nil
+}
+
+// Update does nothing, the signals do not show the status.
+func (l *Listener) Update(remote.Status) {}
+
+// Close stops listening, so the signals kill tempo again as it quits.
+func (l *Listener) Close() error {
+	select {
+	case <-l.done:
+	default:
+		signal.Stop(l.signals)
+		close(l.done)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package signals
+
+import (
+	"errors"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Listen is not supported on this platform, which has no user signals.
+func Listen(usr1, usr2 string, send func(tea.Msg)) (*Listener, error) {
+	return nil, errors.ErrUnsupported
+}
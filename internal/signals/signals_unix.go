@@ -0,0 +1,16 @@
+//go:build unix
+
+package signals
+
+import (
+	"os"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Listen sends the commands named usr1 and usr2, like "toggle" and "next", when tempo gets
+// SIGUSR1 and SIGUSR2.
+func Listen(usr1, usr2 string, send func(tea.Msg)) (*Listener, error) {
+	return listen(map[os.Signal]string{syscall.SIGUSR1: usr1, syscall.SIGUSR2: usr2}, send)
+}
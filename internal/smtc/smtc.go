@@ -0,0 +1,21 @@
+// Package smtc shows what tempo plays in the System Media Transport Controls of Windows, the
+// flyout of the volume and media keys, and takes the presses of its buttons. It is only
+// supported on Windows.
+package smtc
+
+// Status of the playback, as told to the controls
+const (
+	statusClosed  = 0
+	statusStopped = 2
+	statusPlaying = 3
+	statusPaused  = 4
+)
+
+// Buttons of the controls
+const (
+	buttonPlay     = 0
+	buttonPause    = 1
+	buttonStop     = 2
+	buttonNext     = 6
+	buttonPrevious = 7
+)
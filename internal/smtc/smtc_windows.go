@@ -0,0 +1,468 @@
+package smtc
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/remote"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	activatableClass = "Windows.Media.SystemMediaTransportControls"
+
+	roInitMultithreaded = 1
+	wmQuit              = 0x0012
+	// Message of Update, waking the thread of the controls
+	wmApp = 0x8000
+
+	mediaPlaybackTypeMusic = 1
+)
+
+var (
+	combase              = windows.NewLazySystemDLL("combase.dll")
+	procRoInitialize     = combase.NewProc("RoInitialize")
+	procRoUninitialize   = combase.NewProc("RoUninitialize")
+	procRoGetFactory     = combase.NewProc("RoGetActivationFactory")
+	procWindowsCreateStr = combase.NewProc("WindowsCreateString")
+	procWindowsDeleteStr = combase.NewProc("WindowsDeleteString")
+
+	user32                = windows.NewLazySystemDLL("user32.dll")
+	procRegisterClassEx   = user32.NewProc("RegisterClassExW")
+	procCreateWindowEx    = user32.NewProc("CreateWindowExW")
+	procDestroyWindow     = user32.NewProc("DestroyWindow")
+	procDefWindowProc     = user32.NewProc("DefWindowProcW")
+	procGetMessage        = user32.NewProc("GetMessageW")
+	procDispatchMessage   = user32.NewProc("DispatchMessageW")
+	procPostThreadMessage = user32.NewProc("PostThreadMessageW")
+
+	kernel32            = windows.NewLazySystemDLL("kernel32.dll")
+	procGetModuleHandle = kernel32.NewProc("GetModuleHandleW")
+)
+
+// Class of the hidden windows, registered once
+var (
+	className         = windows.StringToUTF16Ptr("tempo-smtc")
+	registerClassOnce sync.Once
+	errRegisterClass  error
+)
+
+// Methods of the handlers of the buttons, made once since the callbacks are never freed
+var (
+	handlerMethods     *handlerVtbl
+	handlerMethodsOnce sync.Once
+)
+
+// Identifiers of the interfaces used
+var (
+	iidUnknown      = windows.GUID{Data1: 0x00000000, Data2: 0x0000, Data3: 0x0000, Data4: [8]byte{0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}}
+	iidAgileObject  = windows.GUID{Data1: 0x94ea2b94, Data2: 0xe9cc, Data3: 0x49e0, Data4: [8]byte{0xc0, 0xff, 0xee, 0x64, 0xca, 0x8f, 0x5b, 0x90}}
+	iidInterop      = windows.GUID{Data1: 0xddb0472d, Data2: 0xc911, Data3: 0x4a1f, Data4: [8]byte{0x86, 0xd9, 0xdc, 0x3d, 0x71, 0xa9, 0x5f, 0x5a}}
+	iidControls     = windows.GUID{Data1: 0x99fa3ff4, Data2: 0x1742, Data3: 0x42a6, Data4: [8]byte{0x90, 0x2e, 0x08, 0x7d, 0x41, 0xf9, 0x65, 0xec}}
+	iidButtonHandle = windows.GUID{Data1: 0x0557e996, Data2: 0x7b23, Data3: 0x5bae, Data4: [8]byte{0xaa, 0x81, 0xea, 0x0d, 0x67, 0x11, 0x43, 0xa4}}
+)
+
+// Methods of the interfaces, by their index in the table of the object
+const (
+	methodRelease = 2
+
+	// ISystemMediaTransportControlsInterop
+	methodGetForWindow = 6
+
+	// ISystemMediaTransportControls
+	methodPutPlaybackStatus    = 7
+	methodGetDisplayUpdater    = 8
+	methodPutIsEnabled         = 11
+	methodPutIsPlayEnabled     = 13
+	methodPutIsStopEnabled     = 15
+	methodPutIsPauseEnabled    = 17
+	methodPutIsPreviousEnabled = 25
+	methodPutIsNextEnabled     = 27
+	methodAddButtonPressed     = 32
+	methodRemoveButtonPressed  = 33
+
+	// ISystemMediaTransportControlsButtonPressedEventArgs
+	methodGetButton = 6
+
+	// ISystemMediaTransportControlsDisplayUpdater
+	methodPutType            = 7
+	methodGetMusicProperties = 12
+	methodClearAll           = 16
+	methodUpdate             = 17
+
+	// IMusicDisplayProperties
+	methodPutTitle  = 7
+	methodPutArtist = 11
+)
+
+// object : A COM object, reached through the table of its methods
+type object struct {
+	methods *[64]uintptr
+}
+
+// call calls a method of the object, failing with the HRESULT it returns.
+func (o *object) call(method int, args ...uintptr) error {
+	hr, _, _ := syscall.SyscallN(o.methods[method], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...)
+	if int32(hr) < 0 {
+		return syscall.Errno(hr)
+	}
+	return nil
+}
+
+func (o *object) release() {
+	if o != nil {
+		o.call(methodRelease)
+	}
+}
+
+// Server : The entry of tempo in the System Media Transport Controls
+//
+// The controls belong to a hidden window whose thread makes every call, showing the status
+// the UI tells when woken, and the presses of the buttons are sent to the program as remote
+// messages.
+type Server struct {
+	send   func(tea.Msg)
+	thread uint32
+
+	// Objects of the thread, with the registration of the handler
+	hwnd     uintptr
+	controls *object
+	updater  *object
+	music    *object
+	handler  *buttonHandler
+	token    int64
+
+	// Status not shown yet, the newest one told
+	mu      sync.Mutex
+	pending remote.Status
+	// woken if the thread was told of pending already
+	woken  bool
+	closed bool
+	done   chan struct{}
+
+	shown remote.Status
+	first bool
+}
+
+var _ remote.Remote = (*Server)(nil)
+
+// New registers tempo with the controls, sending the buttons pressed to the program with send.
+func New(send func(tea.Msg)) (*Server, error) {
+	s := &Server{send: send, done: make(chan struct{}), first: true}
+	started := make(chan error, 1)
+	go s.run(started)
+	if err := <-started; err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Update shows the status in the controls in background.
+func (s *Server) Update(st remote.Status) {
+	s.mu.Lock()
+	s.pending = st
+	wake := !s.woken && !s.closed
+	s.woken = true
+	s.mu.Unlock()
+	if wake {
+		procPostThreadMessage.Call(uintptr(s.thread), wmApp, 0, 0)
+	}
+}
+
+// Close removes tempo from the controls.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+	procPostThreadMessage.Call(uintptr(s.thread), wmQuit, 0, 0)
+	<-s.done
+	return nil
+}
+
+// run registers with the controls on a thread of its own, which then waits for the messages
+// of its window and of Update until Close.
+func (s *Server) run(started chan<- error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	defer close(s.done)
+
+	s.thread = windows.GetCurrentThreadId()
+	procRoInitialize.Call(roInitMultithreaded)
+	defer procRoUninitialize.Call()
+	err := s.register()
+	defer s.unregister()
+	started <- err
+	if err != nil {
+		return
+	}
+
+	var msg struct {
+		hwnd    uintptr
+		message uint32
+		wParam  uintptr
+		lParam  uintptr
+		time    uint32
+		pt      struct{ x, y int32 }
+		private uint32
+	}
+	for {
+		ret, _, _ := procGetMessage.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+		if int32(ret) <= 0 {
+			return
+		}
+		if msg.message == wmApp && msg.hwnd == 0 {
+			s.mu.Lock()
+			st := s.pending
+			s.woken = false
+			s.mu.Unlock()
+			s.show(st)
+			continue
+		}
+		procDispatchMessage.Call(uintptr(unsafe.Pointer(&msg)))
+	}
+}
+
+// register creates the hidden window the controls belong to, and takes them with their
+// display updater.
+func (s *Server) register() error {
+	hwnd, err := newWindow()
+	if err != nil {
+		return err
+	}
+	s.hwnd = hwnd
+
+	class, err := newHString(activatableClass)
+	if err != nil {
+		return err
+	}
+	defer deleteHString(class)
+	var interop *object
+	hr, _, _ := procRoGetFactory.Call(class, uintptr(unsafe.Pointer(&iidInterop)), uintptr(unsafe.Pointer(&interop)))
+	if int32(hr) < 0 {
+		return fmt.Errorf("cannot get the media controls: %w", syscall.Errno(hr))
+	}
+	defer interop.release()
+
+	if err := interop.call(methodGetForWindow, s.hwnd, uintptr(unsafe.Pointer(&iidControls)), uintptr(unsafe.Pointer(&s.controls))); err != nil {
+		return fmt.Errorf("cannot get the media controls of the window: %w", err)
+	}
+	for _, method := range []int{methodPutIsEnabled, methodPutIsPlayEnabled, methodPutIsPauseEnabled, methodPutIsStopEnabled} {
+		if err := s.controls.call(method, 1); err != nil {
+			return err
+		}
+	}
+	if err := s.controls.call(methodGetDisplayUpdater, uintptr(unsafe.Pointer(&s.updater))); err != nil {
+		return err
+	}
+	if err := s.updater.call(methodPutType, mediaPlaybackTypeMusic); err != nil {
+		return err
+	}
+	if err := s.updater.call(methodGetMusicProperties, uintptr(unsafe.Pointer(&s.music))); err != nil {
+		return err
+	}
+
+	s.handler = newButtonHandler(s)
+	return s.controls.call(methodAddButtonPressed, uintptr(unsafe.Pointer(s.handler)), uintptr(unsafe.Pointer(&s.token)))
+}
+
+// unregister releases what register took.
+func (s *Server) unregister() {
+	if s.controls != nil {
+		if s.handler != nil {
+			s.controls.call(methodRemoveButtonPressed, uintptr(s.token))
+		}
+		s.controls.call(methodPutPlaybackStatus, statusClosed)
+	}
+	s.music.release()
+	s.updater.release()
+	s.controls.release()
+	if s.hwnd != 0 {
+		procDestroyWindow.Call(s.hwnd)
+	}
+}
+
+// show tells the controls the playback status, and what is being played if it changed.
+func (s *Server) show(st remote.Status) {
+	prev := s.shown
+	first := s.first
+	s.shown, s.first = st, false
+
+	if first || st.HasNext != prev.HasNext || st.HasPrevious != prev.HasPrevious {
+		s.controls.call(methodPutIsNextEnabled, boolArg(st.HasNext))
+		s.controls.call(methodPutIsPreviousEnabled, boolArg(st.HasPrevious))
+	}
+	if first || st.State != prev.State || (st.Path == "") != (prev.Path == "") {
+		status := uintptr(statusPaused)
+		switch {
+		case st.Path == "":
+			status = statusClosed
+		case st.State == remote.Playing:
+			status = statusPlaying
+		case st.State == remote.Stopped:
+			status = statusStopped
+		}
+		s.controls.call(methodPutPlaybackStatus, status)
+	}
+	if !first && st.Path == prev.Path && st.Title == prev.Title && st.Artist == prev.Artist {
+		return
+	}
+
+	if st.Path == "" {
+		s.updater.call(methodClearAll)
+		s.updater.call(methodPutType, mediaPlaybackTypeMusic)
+	} else {
+		s.setString(methodPutTitle, st.Title)
+		s.setString(methodPutArtist, st.Artist)
+	}
+	s.updater.call(methodUpdate)
+}
+
+// setString sets a text of the music properties.
+func (s *Server) setString(method int, value string) {
+	h, err := newHString(value)
+	if err != nil {
+		return
+	}
+	defer deleteHString(h)
+	s.music.call(method, h)
+}
+
+// pressed sends the command of a button of the controls.
+func (s *Server) pressed(button uint32) {
+	commands := map[uint32]remote.Command{
+		buttonPlay:     remote.Play,
+		buttonPause:    remote.Pause,
+		buttonStop:     remote.Stop,
+		buttonNext:     remote.Next,
+		buttonPrevious: remote.Previous,
+	}
+	if c, ok := commands[button]; ok {
+		s.send(remote.CommandMsg{Command: c})
+	}
+}
+
+// buttonHandler : The handler of the ButtonPressed event, a COM object made in Go
+//
+// Its methods are callbacks shared by every handler, and the Server keeps it alive, so the
+// count of its references is only kept for COM.
+type buttonHandler struct {
+	methods *handlerVtbl
+	refs    int32
+	s       *Server
+}
+
+// handlerVtbl : The table of methods of a buttonHandler
+type handlerVtbl struct {
+	queryInterface uintptr
+	addRef         uintptr
+	release        uintptr
+	invoke         uintptr
+}
+
+func newButtonHandler(s *Server) *buttonHandler {
+	handlerMethodsOnce.Do(func() {
+		handlerMethods = &handlerVtbl{
+			queryInterface: syscall.NewCallback(handlerQueryInterface),
+			addRef:         syscall.NewCallback(handlerAddRef),
+			release:        syscall.NewCallback(handlerRelease),
+			invoke:         syscall.NewCallback(handlerInvoke),
+		}
+	})
+	return &buttonHandler{methods: handlerMethods, refs: 1, s: s}
+}
+
+func handlerQueryInterface(h *buttonHandler, iid *windows.GUID, out **buttonHandler) uintptr {
+	if *iid != iidUnknown && *iid != iidAgileObject && *iid != iidButtonHandle {
+		*out = nil
+		return uintptr(windows.E_NOINTERFACE)
+	}
+	atomic.AddInt32(&h.refs, 1)
+	*out = h
+	return uintptr(windows.S_OK)
+}
+
+func handlerAddRef(h *buttonHandler) uintptr {
+	return uintptr(atomic.AddInt32(&h.refs, 1))
+}
+
+func handlerRelease(h *buttonHandler) uintptr {
+	return uintptr(atomic.AddInt32(&h.refs, -1))
+}
+
+func handlerInvoke(h *buttonHandler, sender, args *object) uintptr {
+	var button uint32
+	if err := args.call(methodGetButton, uintptr(unsafe.Pointer(&button))); err == nil {
+		h.s.pressed(button)
+	}
+	return uintptr(windows.S_OK)
+}
+
+// newWindow creates the hidden window the controls belong to, since a console has none of
+// its own.
+func newWindow() (uintptr, error) {
+	instance, _, _ := procGetModuleHandle.Call(0)
+	registerClassOnce.Do(func() {
+		class := struct {
+			size       uint32
+			style      uint32
+			wndProc    uintptr
+			clsExtra   int32
+			wndExtra   int32
+			instance   uintptr
+			icon       uintptr
+			cursor     uintptr
+			background uintptr
+			menuName   *uint16
+			className  *uint16
+			iconSm     uintptr
+		}{wndProc: procDefWindowProc.Addr(), instance: instance, className: className}
+		class.size = uint32(unsafe.Sizeof(class))
+		if atom, _, err := procRegisterClassEx.Call(uintptr(unsafe.Pointer(&class))); atom == 0 {
+			errRegisterClass = err
+		}
+	})
+	if errRegisterClass != nil {
+		return 0, errRegisterClass
+	}
+	title := windows.StringToUTF16Ptr("tempo")
+	hwnd, _, err := procCreateWindowEx.Call(0, uintptr(unsafe.Pointer(className)), uintptr(unsafe.Pointer(title)),
+		0, 0, 0, 0, 0, 0, 0, instance, 0)
+	if hwnd == 0 {
+		return 0, err
+	}
+	return hwnd, nil
+}
+
+// newHString returns s as an HSTRING, to be deleted with deleteHString.
+func newHString(s string) (uintptr, error) {
+	u, err := windows.UTF16FromString(s)
+	if err != nil {
+		return 0, err
+	}
+	var h uintptr
+	hr, _, _ := procWindowsCreateStr.Call(uintptr(unsafe.Pointer(&u[0])), uintptr(len(u)-1), uintptr(unsafe.Pointer(&h)))
+	if int32(hr) < 0 {
+		return 0, syscall.Errno(hr)
+	}
+	return h, nil
+}
+
+func deleteHString(h uintptr) {
+	procWindowsDeleteStr.Call(h)
+}
+
+func boolArg(b bool) uintptr {
+	if b {
+		return 1
+	}
+	return 0
+}
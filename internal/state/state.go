@@ -0,0 +1,99 @@
+// Package state stores the settings changed while playing, like the volume, and the session
+// played, so the next run of tempo starts with them.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/xdg"
+)
+
+// File name of the state inside the state directory
+const FileName string = "state.json"
+
+// State : Playback settings of the last run
+type State struct {
+	// Path of the file the state was loaded from
+	path string
+
+	// Volume from 0 to 100, kept while muted
+	Volume int  `json:"volume"`
+	Muted  bool `json:"muted"`
+	// Repeat if the queue starts over after its last file, and Shuffle if it is played in a
+	// random order
+	Repeat  bool `json:"repeat"`
+	Shuffle bool `json:"shuffle"`
+	// Speed of the playback, 1 being the normal one
+	Speed float64 `json:"speed"`
+
+	// Session the last run ended with, nil if it never played anything
+	Session *Session `json:"session,omitempty"`
+}
+
+// Session : What was being played and shown, offered to be restored by the next run
+type Session struct {
+	// Queue paths in order, Current the one being played and Position where it was left at
+	Queue    []string      `json:"queue"`
+	Current  string        `json:"current"`
+	Position time.Duration `json:"position"`
+
+	// Dir browsed, View of the browser, if it was Focused, and the Pane shown in its place:
+	// lyrics, chapters, devices, equalizer or none if empty
+	Dir     string `json:"dir"`
+	View    int    `json:"view"`
+	Focused bool   `json:"focused"`
+	Pane    string `json:"pane,omitempty"`
+}
+
+// Path returns the location of the state file inside the user state directory.
+func Path() (string, error) {
+	dir, err := xdg.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Load reads the state file at path over defaults, so a missing file or setting results in
+// the default one.
+func Load(path string, defaults State) (*State, error) {
+	st := defaults
+	st.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &st, nil
+	}
+	if err != nil {
+		return &st, err
+	}
+
+	if err := json.Unmarshal(data, &st); err != nil {
+		// A state that cannot be read is not kept half applied
+		st = defaults
+		st.path = path
+		return &st, err
+	}
+	return &st, nil
+}
+
+// Save writes the state back to the file it was loaded from.
+func (s *State) Save() error {
+	if s.path == "" {
+		return errors.New("state file path is not set")
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,216 @@
+// Package statusfile keeps a file with what tempo is playing, written with a template of the
+// user like "{{.Artist}} – {{.Title}}", for OBS text sources and the status bars of window
+// managers to read.
+package statusfile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/nicolito128/tempo/internal/remote"
+)
+
+// Data : What the template is given
+type Data struct {
+	// State is playing, paused or stopped
+	State  string
+	Path   string
+	Title  string
+	Artist string
+	Album  string
+	Track  int
+	// Position and Duration like "1:23", the duration being "live" for the stream of an
+	// internet radio, and the Percent of the file played
+	Position string
+	Duration string
+	Percent  int
+	Volume   int
+}
+
+// File : The file kept with the status, taking the place of a remote
+//
+// The UI tells it the status, and a goroutine writes the file when its text changes.
+type File struct {
+	path string
+	tmpl *template.Template
+
+	// Status not written yet, the newest one told
+	mu      sync.Mutex
+	pending remote.Status
+	wake    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+
+	// Text written last, and whether the template failed, to report it once
+	written string
+	failed  bool
+}
+
+var _ remote.Remote = (*File)(nil)
+
+// Parse returns the template text, failing if it does not parse or uses what Data lacks.
+func Parse(text string) (*template.Template, error) {
+	tmpl, err := template.New("now_playing").Parse(text)
+	if err == nil {
+		err = tmpl.Execute(io.Discard, Data{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("now playing template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// New returns the file at path written with the template text. The file is emptied while
+// nothing is loaded and when tempo quits.
+func New(path, text string) (*File, error) {
+	tmpl, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f := &File{
+		path:    path,
+		tmpl:    tmpl,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		// Written so a file left by a tempo that crashed is emptied
+		written: "\x00",
+	}
+	go f.run()
+	return f, nil
+}
+
+// Update writes the status to the file in background.
+func (f *File) Update(st remote.Status) {
+	f.mu.Lock()
+	f.pending = st
+	f.mu.Unlock()
+	select {
+	case f.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close empties the file.
+func (f *File) Close() error {
+	select {
+	case <-f.done:
+		return nil
+	default:
+		close(f.done)
+	}
+	<-f.stopped
+	return f.write("")
+}
+
+func (f *File) run() {
+	defer close(f.stopped)
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-f.wake:
+		}
+		f.mu.Lock()
+		st := f.pending
+		f.mu.Unlock()
+
+		text, err := f.render(st)
+		if err != nil {
+			if !f.failed {
+				slog.Warn("Cannot write the now playing file", "err", err)
+			}
+			f.failed = true
+			continue
+		}
+		if err := f.write(text); err != nil {
+			if !f.failed {
+				slog.Warn("Cannot write the now playing file", "path", f.path, "err", err)
+			}
+			f.failed = true
+			continue
+		}
+		f.failed = false
+	}
+}
+
+// render returns the text of the file for the status, empty if nothing is loaded.
+func (f *File) render(st remote.Status) (string, error) {
+	if st.Path == "" {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := f.tmpl.Execute(&buf, NewData(st)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// write replaces the file with text if it changed, at once so it is never read half written.
+func (f *File) write(text string) error {
+	if text == f.written {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), "."+filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(text)
+	if err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return err
+	}
+	f.written = text
+	return nil
+}
+
+// NewData returns what the template is given of the status.
+func NewData(st remote.Status) Data {
+	d := Data{
+		State:    strings.ToLower(st.State.String()),
+		Path:     st.Path,
+		Title:    st.Title,
+		Artist:   st.Artist,
+		Album:    st.Album,
+		Track:    st.Track,
+		Position: Clock(st.Position),
+		Duration: Clock(st.Length),
+		Volume:   st.Volume,
+	}
+	if st.Length > 0 {
+		d.Percent = min(int(st.Position*100/st.Length), 100)
+	}
+	if st.Live {
+		d.Duration = "live"
+	}
+	return d
+}
+
+// Clock formats d like "3:05", or "1:02:03" from an hour up.
+func Clock(d time.Duration) string {
+	s := int(d.Seconds())
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
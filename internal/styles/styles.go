@@ -1,47 +1,78 @@
 package styles
 
-import "github.com/charmbracelet/lipgloss"
-
-const (
-	PrimaryColor   lipgloss.Color = "#6b84ff"
-	SecundaryColor lipgloss.Color = "#6bddff"
-	ContrastColor  lipgloss.Color = "#ff6b6b"
-	ProblemColor   lipgloss.Color = "#df4e45"
-	GreyColor      lipgloss.Color = "#777b7d"
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
 )
 
-var (
-	BaseContainerStyle = lipgloss.NewStyle().
-				Padding(1, 3).
-				Align(lipgloss.Center, lipgloss.Center).
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(PrimaryColor)
+// The styles are built each time they are used, with the colors of the current theme
 
-	PrimaryHighlightStyle = lipgloss.NewStyle().
-				Background(PrimaryColor).
-				Foreground(lipgloss.Color("white"))
+func BaseContainerStyle() lipgloss.Style {
+	return lipgloss.NewStyle().
+		Padding(1, 3).
+		Align(lipgloss.Center, lipgloss.Center).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(PrimaryColor())
+}
 
-	ContrastHighlightStyle = lipgloss.NewStyle().
-				Background(ContrastColor).
-				Foreground(lipgloss.Color("white"))
+func PrimaryHighlightStyle() lipgloss.Style {
+	return lipgloss.NewStyle().
+		Background(PrimaryColor()).
+		Foreground(HighlightColor())
+}
 
-	HelpStyle = lipgloss.NewStyle().
-			Padding(1, 2).
-			Foreground(GreyColor)
-)
+func ContrastHighlightStyle() lipgloss.Style {
+	return lipgloss.NewStyle().
+		Background(ContrastColor()).
+		Foreground(HighlightColor())
+}
+
+func HelpStyle() lipgloss.Style {
+	return lipgloss.NewStyle().
+		Padding(1, 2).
+		Foreground(GreyColor())
+}
+
+func BannerStyle() lipgloss.Style {
+	return lipgloss.NewStyle().
+		Background(ProblemColor()).
+		Foreground(HighlightColor()).
+		Padding(0, 1).
+		MarginLeft(2)
+}
 
 func BaseContainer(xs ...string) string {
-	return BaseContainerStyle.Render(xs...)
+	return BaseContainerStyle().Render(xs...)
 }
 
 func PrimaryHighlight(xs ...string) string {
-	return PrimaryHighlightStyle.Render(xs...)
+	return PrimaryHighlightStyle().Render(xs...)
 }
 
 func ContrastHighlight(xs ...string) string {
-	return ContrastHighlightStyle.Render(xs...)
+	return ContrastHighlightStyle().Render(xs...)
 }
 
 func Help(xs ...string) string {
-	return HelpStyle.Render(xs...)
+	return HelpStyle().Render(xs...)
+}
+
+func Banner(xs ...string) string {
+	return BannerStyle().Render(xs...)
+}
+
+// Prompt renders a text input with its prompt in the primary color.
+func Prompt(input textinput.Model) string {
+	input.PromptStyle = lipgloss.NewStyle().Foreground(PrimaryColor())
+	return input.View()
+}
+
+// ReverseCut shortens s to its last n characters after "...", like the end of a long path,
+// leaving it as it is if it fits.
+func ReverseCut(s string, n int) string {
+	runes := []rune(s)
+	if n >= len(runes) {
+		return s
+	}
+	return "..." + string(runes[len(runes)-n:])
 }
@@ -0,0 +1,119 @@
+package styles
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme : The colors of the UI, an empty one being the one of the terminal text
+type Theme struct {
+	Name string
+
+	// Primary color of the borders, the prompts and the selection, Secondary of the artists
+	// and ratings, and Contrast of the highlighted details like the playback position
+	Primary   lipgloss.Color
+	Secondary lipgloss.Color
+	Contrast  lipgloss.Color
+	// Problem color of the errors and the muted indicator
+	Problem lipgloss.Color
+	// Grey color of the help and the secondary information
+	Grey lipgloss.Color
+	// Highlight color of the text over the Primary, Contrast and Problem backgrounds
+	Highlight lipgloss.Color
+	// Progress color of the played part of the progress bar
+	Progress lipgloss.Color
+}
+
+// Themes are the themes built into tempo, dark first.
+var Themes = []Theme{
+	{
+		Name:      "dark",
+		Primary:   "#6b84ff",
+		Secondary: "#6bddff",
+		Contrast:  "#ff6b6b",
+		Problem:   "#df4e45",
+		Grey:      "#777b7d",
+		Highlight: "#ffffff",
+	},
+	{
+		Name:      "light",
+		Primary:   "#3451d1",
+		Secondary: "#0a7ea4",
+		Contrast:  "#c92a2a",
+		Problem:   "#b3261e",
+		Grey:      "#6b6f72",
+		Highlight: "#ffffff",
+	},
+	{
+		// ANSI colors only, for terminals without true color or to keep their palette
+		Name:      "monochrome",
+		Primary:   "7",
+		Secondary: "7",
+		Contrast:  "15",
+		Problem:   "15",
+		Grey:      "8",
+		Highlight: "0",
+	},
+	{
+		Name:      "gruvbox",
+		Primary:   "#83a598",
+		Secondary: "#8ec07c",
+		Contrast:  "#fe8019",
+		Problem:   "#fb4934",
+		Grey:      "#928374",
+		Highlight: "#282828",
+		Progress:  "#ebdbb2",
+	},
+}
+
+// Theme the UI is drawn with
+var current = Themes[0]
+
+// FindTheme returns the built-in theme with the given name.
+func FindTheme(name string) (Theme, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, theme := range Themes {
+		if theme.Name == name {
+			return theme, nil
+		}
+	}
+	return Theme{}, fmt.Errorf("unknown theme %q", name)
+}
+
+// CurrentTheme returns the theme the UI is drawn with.
+func CurrentTheme() Theme {
+	return current
+}
+
+// SetTheme sets the theme the UI is drawn with from the next time it is drawn.
+func SetTheme(theme Theme) {
+	current = theme
+}
+
+// ParseColor checks a color of a config: a hex one like "#6b84ff" or "#68f", an ANSI one
+// from "0" to "255", or an empty one for the color of the terminal text.
+func ParseColor(s string) (lipgloss.Color, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		if _, err := strconv.ParseUint(hex, 16, 32); err == nil && (len(hex) == 3 || len(hex) == 6) {
+			return lipgloss.Color(s), nil
+		}
+	} else if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 255 {
+		return lipgloss.Color(s), nil
+	}
+	return "", fmt.Errorf("bad color %q, expected one like \"#6b84ff\" or an ANSI one from 0 to 255", s)
+}
+
+func PrimaryColor() lipgloss.Color   { return current.Primary }
+func SecundaryColor() lipgloss.Color { return current.Secondary }
+func ContrastColor() lipgloss.Color  { return current.Contrast }
+func ProblemColor() lipgloss.Color   { return current.Problem }
+func GreyColor() lipgloss.Color      { return current.Grey }
+func HighlightColor() lipgloss.Color { return current.Highlight }
+func ProgressColor() lipgloss.Color  { return current.Progress }
@@ -0,0 +1,64 @@
+// Package suspend pauses the playback when the system goes to sleep, so the audio does not
+// come back garbled, or in the middle of a meeting, when it wakes up. The playback stays
+// paused until resumed by the user.
+package suspend
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/remote"
+)
+
+const (
+	// Time between two looks at the clocks
+	clockInterval = 2 * time.Second
+	// Least time the wall clock is ahead of the monotonic one after a sleep, more than the
+	// steps of the time synchronization
+	clockGap = 10 * time.Second
+)
+
+// pause is the message sent when the system sleeps
+var pause tea.Msg = remote.CommandMsg{Command: remote.Pause}
+
+// clock : The watcher telling the sleeps after the system wakes up, by the wall clock going on
+// while the monotonic one stops
+type clock struct {
+	done chan struct{}
+}
+
+// watchClock sends the pause when the system wakes up from a sleep.
+func watchClock(send func(tea.Msg)) *clock {
+	c := &clock{done: make(chan struct{})}
+	go func() {
+		ticker := time.NewTicker(clockInterval)
+		defer ticker.Stop()
+		last := time.Now()
+		for {
+			select {
+			case <-c.done:
+				return
+			case now := <-ticker.C:
+				// Round(0) drops the monotonic reading, leaving the wall clock
+				if now.Round(0).Sub(last.Round(0))-now.Sub(last) > clockGap {
+					send(pause)
+				}
+				last = now
+			}
+		}
+	}()
+	return c
+}
+
+// Update does nothing, the sleeps do not depend on the status.
+func (c *clock) Update(remote.Status) {}
+
+// Close stops watching.
+func (c *clock) Close() error {
+	select {
+	case <-c.done:
+	default:
+		close(c.done)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package suspend
+
+import (
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/godbus/dbus/v5"
+	"github.com/nicolito128/tempo/internal/remote"
+)
+
+const (
+	logindName      = "org.freedesktop.login1"
+	logindPath      = "/org/freedesktop/login1"
+	logindInterface = "org.freedesktop.login1.Manager"
+
+	// Time the sleep is held back for the playback to fade out, once paused
+	pauseDelay = 500 * time.Millisecond
+)
+
+// logind : The watcher told by systemd-logind that the system is about to sleep
+//
+// It holds a delay lock, which makes logind wait for it to be released before sleeping, so the
+// playback is paused while the audio device is still there.
+type logind struct {
+	conn    *dbus.Conn
+	send    func(tea.Msg)
+	signals chan *dbus.Signal
+
+	mu   sync.Mutex
+	lock *os.File
+}
+
+// Watch pauses the playback when the system is about to sleep, as told by systemd-logind, or
+// when it wakes up if there is no logind.
+func Watch(send func(tea.Msg)) remote.Remote {
+	l, err := watchLogind(send)
+	if err != nil {
+		slog.Debug("Cannot watch the sleeps through logind", "err", err)
+		return watchClock(send)
+	}
+	return l
+}
+
+func watchLogind(send func(tea.Msg)) (*logind, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, err
+	}
+	err = conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(logindPath),
+		dbus.WithMatchInterface(logindInterface),
+		dbus.WithMatchMember("PrepareForSleep"),
+	)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	l := &logind{conn: conn, send: send, signals: make(chan *dbus.Signal, 8)}
+	if err := l.inhibit(); err != nil {
+		// Without the lock, the playback is paused as the system sleeps, which is mostly in time
+		slog.Debug("Cannot hold back the sleeps", "err", err)
+	}
+	conn.Signal(l.signals)
+	go l.run()
+	return l, nil
+}
+
+// run pauses before each sleep, and takes a new lock after it.
+func (l *logind) run() {
+	for sig := range l.signals {
+		if sig.Name != logindInterface+".PrepareForSleep" || len(sig.Body) != 1 {
+			continue
+		}
+		if sleeping, _ := sig.Body[0].(bool); !sleeping {
+			if err := l.inhibit(); err != nil {
+				slog.Debug("Cannot hold back the sleeps", "err", err)
+			}
+			continue
+		}
+		l.send(pause)
+		time.Sleep(pauseDelay)
+		l.release()
+	}
+}
+
+// inhibit takes the delay lock, if not held already.
+func (l *logind) inhibit() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lock != nil {
+		return nil
+	}
+	var fd dbus.UnixFD
+	err := l.conn.Object(logindName, logindPath).
+		Call(logindInterface+".Inhibit", 0, "sleep", "tempo", "Pausing the playback", "delay").
+		Store(&fd)
+	if err != nil {
+		return err
+	}
+	l.lock = os.NewFile(uintptr(fd), "logind inhibitor")
+	return nil
+}
+
+// release lets the system sleep.
+func (l *logind) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lock != nil {
+		l.lock.Close()
+		l.lock = nil
+	}
+}
+
+// Update does nothing, the sleeps do not depend on the status.
+func (l *logind) Update(remote.Status) {}
+
+// Close releases the lock and disconnects, which ends run.
+func (l *logind) Close() error {
+	l.release()
+	return l.conn.Close()
+}
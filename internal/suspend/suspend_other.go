@@ -0,0 +1,14 @@
+//go:build !linux
+
+package suspend
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nicolito128/tempo/internal/remote"
+)
+
+// Watch pauses the playback when the system wakes up from a sleep, the earliest it can be told
+// on this platform.
+func Watch(send func(tea.Msg)) remote.Remote {
+	return watchClock(send)
+}
@@ -0,0 +1,113 @@
+package tags
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Chapter : A part of a long track, like a chapter of an audiobook or a podcast
+type Chapter struct {
+	Start time.Duration
+	Title string
+}
+
+// Chapters returns the chapters of the CHAP frames, sorted by their start.
+func (t *ID3v2) Chapters() []Chapter {
+	var chapters []Chapter
+	for _, f := range t.Frames {
+		if f.ID != "CHAP" {
+			continue
+		}
+
+		// Element ID, then the start and end times in milliseconds, the start and end
+		// byte offsets, and the frames describing the chapter
+		end := bytes.IndexByte(f.Data, 0)
+		if end < 0 || len(f.Data) < end+17 {
+			continue
+		}
+		ms := binary.BigEndian.Uint32(f.Data[end+1:])
+		sub, _ := parseFrames(t.Version, f.Data[end+17:])
+
+		title := (&ID3v2{Version: t.Version, Frames: sub}).Text("TIT2")
+		chapters = append(chapters, Chapter{Start: time.Duration(ms) * time.Millisecond, Title: title})
+	}
+	return sortChapters(chapters)
+}
+
+// Chapters returns the chapters of the Vorbis comment.
+func (f *FLAC) Chapters() []Chapter {
+	if f.Comment == nil {
+		return nil
+	}
+	return f.Comment.Chapters()
+}
+
+// Fields of the chapters of a Vorbis comment, like CHAPTER001=00:01:30.000 and CHAPTER001NAME=Title
+var vorbisChapter = regexp.MustCompile(`^(?i)CHAPTER(\d+)(NAME)?$`)
+
+// Chapters returns the chapters of the CHAPTERxxx fields, sorted by their start.
+func (c *VorbisComment) Chapters() []Chapter {
+	starts := make(map[string]time.Duration)
+	titles := make(map[string]string)
+	for _, f := range c.Fields {
+		m := vorbisChapter.FindStringSubmatch(f.Name)
+		if m == nil {
+			continue
+		}
+		if m[2] != "" {
+			titles[m[1]] = strings.TrimSpace(f.Value)
+		} else if start, ok := parseClock(f.Value); ok {
+			starts[m[1]] = start
+		}
+	}
+
+	var chapters []Chapter
+	for n, start := range starts {
+		chapters = append(chapters, Chapter{Start: start, Title: titles[n]})
+	}
+	return sortChapters(chapters)
+}
+
+// parseClock parses a time like 01:02:03.456, with optional hours and fraction.
+func parseClock(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	var frac time.Duration
+	if whole, decimals, ok := strings.Cut(s, "."); ok {
+		n, err := strconv.Atoi(decimals)
+		if err != nil || len(decimals) > 9 {
+			return 0, false
+		}
+		frac = time.Duration(n) * time.Second
+		for range decimals {
+			frac /= 10
+		}
+		s = whole
+	}
+
+	var d time.Duration
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		d = d*60 + time.Duration(n)*time.Second
+	}
+	return d + frac, true
+}
+
+func sortChapters(chapters []Chapter) []Chapter {
+	slices.SortStableFunc(chapters, func(a, b Chapter) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+	return chapters
+}
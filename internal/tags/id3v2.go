@@ -0,0 +1,262 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ID3v2 : The frames of an ID3v2 tag
+type ID3v2 struct {
+	// Major version of the tag: 2, 3 or 4
+	Version int
+
+	Frames []Frame
+}
+
+// Frame : A frame of an ID3v2 tag, with the four character ID of ID3v2.3
+type Frame struct {
+	ID   string
+	Data []byte
+}
+
+// IDs of ID3v2.2 frames, which have three characters
+var id3v22Frames = map[string]string{
+	"TT2": "TIT2", "TP1": "TPE1", "TP2": "TPE2", "TAL": "TALB", "TRK": "TRCK",
+	"TPA": "TPOS", "TCO": "TCON", "TCP": "TCMP", "COM": "COMM", "ULT": "USLT",
+	"PIC": "APIC", "TXX": "TXXX", "SLT": "SYLT",
+}
+
+// ReadID3v2 reads the ID3v2 tag at the start of r.
+func ReadID3v2(r io.Reader) (*ID3v2, error) {
+	var header [10]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, ErrNoTags
+	}
+	if string(header[:3]) != "ID3" {
+		return nil, ErrNoTags
+	}
+
+	version := int(header[3])
+	if version < 2 || version > 4 {
+		return nil, errors.New("unsupported ID3v2 version 2." + strconv.Itoa(version))
+	}
+	flags := header[5]
+
+	data := make([]byte, syncsafe(header[6:10]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	// Before ID3v2.4 the unsynchronisation is applied to the whole tag
+	if flags&0x80 != 0 && version < 4 {
+		data = unsynchronise(data)
+	}
+
+	// Skip the extended header
+	if flags&0x40 != 0 && version > 2 && len(data) >= 4 {
+		size := int(binary.BigEndian.Uint32(data[:4])) + 4
+		if version == 4 {
+			size = syncsafe(data[:4])
+		}
+		if size > len(data) {
+			return nil, errors.New("bad ID3v2 extended header")
+		}
+		data = data[size:]
+	}
+
+	frames, err := parseFrames(version, data)
+	return &ID3v2{Version: version, Frames: frames}, err
+}
+
+// parseFrames parses the frames of an ID3v2 tag of the given version, up to the padding.
+// The frames read before an error are returned with it.
+func parseFrames(version int, data []byte) ([]Frame, error) {
+	var frames []Frame
+	for len(data) > 0 {
+		var id string
+		var size int
+		var frameFlags uint16
+
+		if version == 2 {
+			if len(data) < 6 {
+				break
+			}
+			id = id3v22Frames[string(data[:3])]
+			size = int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+			data = data[6:]
+		} else {
+			if len(data) < 10 {
+				break
+			}
+			id = string(data[:4])
+			if version == 4 {
+				size = syncsafe(data[4:8])
+			} else {
+				size = int(binary.BigEndian.Uint32(data[4:8]))
+			}
+			frameFlags = binary.BigEndian.Uint16(data[8:10])
+			data = data[10:]
+		}
+
+		// Padding reached
+		if size == 0 || (id != "" && id[0] == 0) {
+			break
+		}
+		if size > len(data) {
+			return frames, errors.New("bad ID3v2 frame size")
+		}
+		body := data[:size]
+		data = data[size:]
+
+		if id == "" {
+			continue
+		}
+		if version == 4 {
+			// Compressed and encrypted frames are not supported
+			if frameFlags&0x000C != 0 {
+				continue
+			}
+			if frameFlags&0x0001 != 0 && len(body) >= 4 {
+				body = body[4:]
+			}
+			if frameFlags&0x0002 != 0 {
+				body = unsynchronise(body)
+			}
+		} else if frameFlags&0x00C0 != 0 {
+			continue
+		}
+
+		frames = append(frames, Frame{ID: id, Data: body})
+	}
+	return frames, nil
+}
+
+// Frame returns the data of the first frame with the given ID.
+func (t *ID3v2) Frame(id string) ([]byte, bool) {
+	for _, f := range t.Frames {
+		if f.ID == id {
+			return f.Data, true
+		}
+	}
+	return nil, false
+}
+
+// Text returns the value of the first text frame with the given ID.
+// Multiple values are joined with "; ".
+func (t *ID3v2) Text(id string) string {
+	data, ok := t.Frame(id)
+	if !ok || len(data) == 0 {
+		return ""
+	}
+	values := strings.Split(decodeText(data[0], data[1:]), "\x00")
+
+	var clean []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			clean = append(clean, v)
+		}
+	}
+	return strings.Join(clean, "; ")
+}
+
+// UserText returns the value of the first TXXX frame with the given description, ignoring case.
+func (t *ID3v2) UserText(description string) string {
+	for _, f := range t.Frames {
+		if f.ID != "TXXX" || len(f.Data) == 0 {
+			continue
+		}
+		desc, value, _ := strings.Cut(decodeText(f.Data[0], f.Data[1:]), "\x00")
+		if strings.EqualFold(desc, description) {
+			return strings.TrimSpace(strings.ReplaceAll(value, "\x00", "; "))
+		}
+	}
+	return ""
+}
+
+// Tags returns the common tags.
+func (t *ID3v2) Tags() Tags {
+	tags := Tags{
+		Title:       t.Text("TIT2"),
+		Artist:      t.Text("TPE1"),
+		AlbumArtist: t.Text("TPE2"),
+		Album:       t.Text("TALB"),
+		Genre:       genre(t.Text("TCON")),
+		Compilation: t.Text("TCMP") == "1",
+	}
+	tags.Track, tags.TrackTotal = parsePosition(t.Text("TRCK"))
+	tags.Disc, tags.DiscTotal = parsePosition(t.Text("TPOS"))
+	tags.ReplayGain = ReplayGain{
+		TrackGain: parseGain(t.UserText("REPLAYGAIN_TRACK_GAIN")),
+		AlbumGain: parseGain(t.UserText("REPLAYGAIN_ALBUM_GAIN")),
+		TrackPeak: parseGain(t.UserText("REPLAYGAIN_TRACK_PEAK")),
+		AlbumPeak: parseGain(t.UserText("REPLAYGAIN_ALBUM_PEAK")),
+	}
+	return tags
+}
+
+// decodeText decodes text with the given ID3v2 encoding byte.
+func decodeText(encoding byte, data []byte) string {
+	switch encoding {
+	case 0:
+		// ISO-8859-1 maps directly to the first Unicode code points
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return strings.TrimRight(string(runes), "\x00")
+
+	case 1, 2:
+		order := binary.ByteOrder(binary.BigEndian)
+		if encoding == 1 && len(data) >= 2 {
+			if data[0] == 0xFF && data[1] == 0xFE {
+				order = binary.LittleEndian
+			}
+			if (data[0] == 0xFF && data[1] == 0xFE) || (data[0] == 0xFE && data[1] == 0xFF) {
+				data = data[2:]
+			}
+		}
+
+		units := make([]uint16, 0, len(data)/2)
+		for i := 0; i+1 < len(data); i += 2 {
+			u := order.Uint16(data[i : i+2])
+			// A new BOM starts each value of a multiple value frame
+			if u == 0xFEFF || u == 0xFFFE {
+				continue
+			}
+			units = append(units, u)
+		}
+		return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+
+	default:
+		return strings.TrimRight(string(data), "\x00")
+	}
+}
+
+// genre resolves the ID3v1 genre numbers, written like "17" or "(17)".
+func genre(s string) string {
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(s, "("), ")")
+	if n, err := strconv.Atoi(trimmed); err == nil && n >= 0 && n < len(id3v1Genres) {
+		return id3v1Genres[n]
+	}
+	// "(17)Rock" or "(17)" followed by a refinement
+	if strings.HasPrefix(s, "(") {
+		if end := strings.Index(s, ")"); end > 0 && end < len(s)-1 {
+			return s[end+1:]
+		}
+	}
+	return s
+}
+
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+// unsynchronise reverts the unsynchronisation scheme, removing the 0x00 after each 0xFF.
+func unsynchronise(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte{0xFF, 0x00}, []byte{0xFF})
+}
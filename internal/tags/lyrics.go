@@ -0,0 +1,136 @@
+package tags
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SyncedLine : A line of lyrics and the time it starts being sung
+type SyncedLine struct {
+	Time time.Duration
+	Text string
+}
+
+// SyncedLyrics returns the lines of the first SYLT frame with lyrics, sorted by time.
+// Frames timed in MPEG frames instead of milliseconds are ignored.
+func (t *ID3v2) SyncedLyrics() []SyncedLine {
+	for _, f := range t.Frames {
+		if f.ID != "SYLT" {
+			continue
+		}
+		if lines, ok := parseSYLT(f.Data); ok && len(lines) > 0 {
+			return lines
+		}
+	}
+	return nil
+}
+
+// Lyrics returns the text of the first USLT frame.
+func (t *ID3v2) Lyrics() string {
+	for _, f := range t.Frames {
+		// Encoding and language, followed by a description and the text
+		if f.ID != "USLT" || len(f.Data) < 4 {
+			continue
+		}
+		if _, text, ok := cutText(f.Data[0], f.Data[4:]); ok {
+			if lyrics := strings.TrimSpace(decodeText(f.Data[0], text)); lyrics != "" {
+				return lyrics
+			}
+		}
+	}
+	return ""
+}
+
+// Lyrics returns the unsynchronized lyrics of the Vorbis comment.
+func (f *FLAC) Lyrics() string {
+	if f.Comment == nil {
+		return ""
+	}
+	return f.Comment.Lyrics()
+}
+
+// Lyrics returns the LYRICS field, or the UNSYNCEDLYRICS field written by some taggers.
+func (c *VorbisComment) Lyrics() string {
+	for _, name := range []string{"LYRICS", "UNSYNCEDLYRICS"} {
+		for _, v := range c.Values(name) {
+			if v = strings.TrimSpace(v); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// parseSYLT parses the body of a SYLT frame. Each entry may be a whole line or a syllable,
+// in which case the lines are the entries starting with a line break.
+func parseSYLT(data []byte) ([]SyncedLine, bool) {
+	// Encoding, language, timestamp format and content type
+	if len(data) < 6 {
+		return nil, false
+	}
+	encoding, format, content := data[0], data[4], data[5]
+	if format != 2 || (content != 0 && content != 1) {
+		return nil, false
+	}
+
+	_, data, ok := cutText(encoding, data[6:])
+	if !ok {
+		return nil, false
+	}
+
+	var entries []SyncedLine
+	syllables := false
+	for len(data) > 0 {
+		var text string
+		text, data, ok = cutText(encoding, data)
+		if !ok || len(data) < 4 {
+			break
+		}
+		ms := binary.BigEndian.Uint32(data[:4])
+		data = data[4:]
+
+		if strings.HasPrefix(text, "\n") || strings.HasPrefix(text, "\r") {
+			syllables = true
+		}
+		entries = append(entries, SyncedLine{Time: time.Duration(ms) * time.Millisecond, Text: text})
+	}
+
+	var lines []SyncedLine
+	for _, e := range entries {
+		if !syllables || len(lines) == 0 || strings.HasPrefix(e.Text, "\n") || strings.HasPrefix(e.Text, "\r") {
+			lines = append(lines, e)
+			continue
+		}
+		lines[len(lines)-1].Text += e.Text
+	}
+	for i := range lines {
+		lines[i].Text = strings.TrimSpace(lines[i].Text)
+	}
+	slices.SortStableFunc(lines, func(a, b SyncedLine) int {
+		return cmp.Compare(a.Time, b.Time)
+	})
+	return lines, true
+}
+
+// cutText returns the null terminated text at the start of data, decoded with the given
+// ID3v2 encoding, and the data after it.
+func cutText(encoding byte, data []byte) (string, []byte, bool) {
+	if encoding == 1 || encoding == 2 {
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return decodeText(encoding, data[:i]), data[i+2:], true
+			}
+		}
+		return "", nil, false
+	}
+
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return "", nil, false
+	}
+	return decodeText(encoding, data[:end]), data[end+1:], true
+}
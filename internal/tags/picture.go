@@ -0,0 +1,202 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// PictureType : What an embedded picture shows, as defined by ID3v2 and FLAC
+type PictureType byte
+
+const (
+	PictureOther      PictureType = 0
+	PictureFrontCover PictureType = 3
+	PictureBackCover  PictureType = 4
+)
+
+// Picture : An image embedded in an audio file, like its cover
+type Picture struct {
+	Type        PictureType
+	MIME        string
+	Description string
+	Data        []byte
+}
+
+// Cover returns the front cover of the pictures, or the first picture if there is no
+// front cover.
+func Cover(pictures []Picture) (Picture, bool) {
+	for _, pic := range pictures {
+		if pic.Type == PictureFrontCover {
+			return pic, true
+		}
+	}
+	if len(pictures) == 0 {
+		return Picture{}, false
+	}
+	return pictures[0], true
+}
+
+// Pictures returns the pictures of the APIC frames.
+func (t *ID3v2) Pictures() []Picture {
+	var pictures []Picture
+	for _, f := range t.Frames {
+		if f.ID != "APIC" || len(f.Data) < 2 {
+			continue
+		}
+		if pic, err := t.parsePicture(f.Data); err == nil {
+			pictures = append(pictures, pic)
+		}
+	}
+	return pictures
+}
+
+// parsePicture parses the body of an APIC frame, or a PIC frame in ID3v2.2.
+func (t *ID3v2) parsePicture(data []byte) (Picture, error) {
+	bad := errors.New("bad ID3v2 picture frame")
+
+	encoding := data[0]
+	data = data[1:]
+
+	var pic Picture
+	if t.Version == 2 {
+		// A three letter image format instead of a MIME type
+		if len(data) < 3 {
+			return pic, bad
+		}
+		pic.MIME = "image/" + strings.ToLower(string(data[:3]))
+		data = data[3:]
+	} else {
+		end := bytes.IndexByte(data, 0)
+		if end < 0 {
+			return pic, bad
+		}
+		pic.MIME = strings.ToLower(string(data[:end]))
+		data = data[end+1:]
+	}
+
+	if len(data) < 1 {
+		return pic, bad
+	}
+	pic.Type = PictureType(data[0])
+	data = data[1:]
+
+	// The description ends with a null character of the size of the encoding
+	end := -1
+	if encoding == 1 || encoding == 2 {
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				end = i
+				break
+			}
+		}
+		if end >= 0 {
+			pic.Description = decodeText(encoding, data[:end])
+			data = data[end+2:]
+		}
+	} else if end = bytes.IndexByte(data, 0); end >= 0 {
+		pic.Description = decodeText(encoding, data[:end])
+		data = data[end+1:]
+	}
+	if end < 0 {
+		return pic, bad
+	}
+
+	pic.Data = data
+	return pic, nil
+}
+
+// Pictures returns the pictures of the PICTURE blocks.
+func (f *FLAC) Pictures() []Picture {
+	var pictures []Picture
+	for _, b := range f.Blocks {
+		if b.Type != FLACPictureBlock {
+			continue
+		}
+		if pic, err := parseFLACPicture(b.Data); err == nil {
+			pictures = append(pictures, pic)
+		}
+	}
+	if f.Comment != nil {
+		pictures = append(pictures, f.Comment.Pictures()...)
+	}
+	return pictures
+}
+
+// Pictures returns the pictures stored in the METADATA_BLOCK_PICTURE fields, or in the
+// older COVERART fields.
+func (c *VorbisComment) Pictures() []Picture {
+	var pictures []Picture
+	for _, value := range c.Values("METADATA_BLOCK_PICTURE") {
+		data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		if pic, err := parseFLACPicture(data); err == nil {
+			pictures = append(pictures, pic)
+		}
+	}
+	for _, value := range c.Values("COVERART") {
+		data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		pictures = append(pictures, Picture{Type: PictureFrontCover, MIME: c.Text("COVERARTMIME"), Data: data})
+	}
+	return pictures
+}
+
+// parseFLACPicture parses a FLAC PICTURE block.
+func parseFLACPicture(data []byte) (Picture, error) {
+	bad := errors.New("bad FLAC picture")
+
+	u32 := func() (uint32, bool) {
+		if len(data) < 4 {
+			return 0, false
+		}
+		n := binary.BigEndian.Uint32(data[:4])
+		data = data[4:]
+		return n, true
+	}
+	bytesField := func() ([]byte, bool) {
+		n, ok := u32()
+		if !ok || uint64(n) > uint64(len(data)) {
+			return nil, false
+		}
+		b := data[:n]
+		data = data[n:]
+		return b, true
+	}
+
+	var pic Picture
+	typ, ok := u32()
+	if !ok {
+		return pic, bad
+	}
+	pic.Type = PictureType(typ)
+
+	mime, ok := bytesField()
+	if !ok {
+		return pic, bad
+	}
+	pic.MIME = strings.ToLower(string(mime))
+
+	desc, ok := bytesField()
+	if !ok {
+		return pic, bad
+	}
+	pic.Description = string(desc)
+
+	// Width, height, color depth and number of colors
+	if len(data) < 16 {
+		return pic, bad
+	}
+	data = data[16:]
+
+	if pic.Data, ok = bytesField(); !ok {
+		return pic, bad
+	}
+	return pic, nil
+}
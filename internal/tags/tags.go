@@ -0,0 +1,124 @@
+// Package tags reads the metadata embedded in audio files: the ID3v2 tags of MP3 files
+// and the Vorbis comments of FLAC and Ogg files.
+package tags
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nicolito128/tempo/pkg/engine"
+)
+
+// ErrNoTags is returned when a file has no supported metadata.
+var ErrNoTags = errors.New("no tags found")
+
+// Tags : The metadata of an audio file
+type Tags struct {
+	Title       string `json:"title,omitempty"`
+	Artist      string `json:"artist,omitempty"`
+	AlbumArtist string `json:"album_artist,omitempty"`
+	Album       string `json:"album,omitempty"`
+	Genre       string `json:"genre,omitempty"`
+
+	// Position of the track in its disc, and of the disc in the album. Zero if unknown
+	Track      int `json:"track,omitempty"`
+	TrackTotal int `json:"track_total,omitempty"`
+	Disc       int `json:"disc,omitempty"`
+	DiscTotal  int `json:"disc_total,omitempty"`
+
+	// Compilation if the album is a compilation of several artists
+	Compilation bool `json:"compilation,omitempty"`
+
+	ReplayGain ReplayGain `json:"replaygain,omitzero"`
+}
+
+// ReplayGain : The loudness adjustments of a track and its album
+type ReplayGain struct {
+	// Gains in dB, zero if unknown
+	TrackGain float64 `json:"track_gain,omitempty"`
+	AlbumGain float64 `json:"album_gain,omitempty"`
+
+	// Peak sample values, where 1 is full scale. Zero if unknown
+	TrackPeak float64 `json:"track_peak,omitempty"`
+	AlbumPeak float64 `json:"album_peak,omitempty"`
+}
+
+// Metadata : The tag block of an audio file, in the format of its container
+type Metadata interface {
+	// Tags returns the common tags, the same for every format
+	Tags() Tags
+	// Pictures returns the embedded images, like the cover
+	Pictures() []Picture
+	// Lyrics returns the unsynchronized lyrics, empty if there are none
+	Lyrics() string
+	// Chapters returns the chapters sorted by their start, empty if there are none
+	Chapters() []Chapter
+}
+
+var (
+	_ Metadata = (*ID3v2)(nil)
+	_ Metadata = (*FLAC)(nil)
+	_ Metadata = (*VorbisComment)(nil)
+)
+
+// IsZero reports whether no tag was found.
+func (t Tags) IsZero() bool {
+	return t == Tags{}
+}
+
+// ReadFile reads the tags of the file at path. Files without tags return ErrNoTags.
+func ReadFile(path string) (Tags, error) {
+	md, err := Read(path)
+	if err != nil {
+		return Tags{}, err
+	}
+	return md.Tags(), nil
+}
+
+// Read reads the metadata of the file at path, based on its extension, requesting the start
+// of the ones served over HTTP. Files without tags return ErrNoTags.
+func Read(path string) (Metadata, error) {
+	var read func(r io.Reader) (Metadata, error)
+	switch engine.Ext(path) {
+	case ".mp3":
+		read = func(r io.Reader) (Metadata, error) { return ReadID3v2(r) }
+	case ".flac":
+		read = func(r io.Reader) (Metadata, error) { return ReadFLAC(r) }
+	case ".ogg", ".oga", ".opus":
+		read = func(r io.Reader) (Metadata, error) { return ReadOgg(r) }
+	default:
+		return nil, ErrNoTags
+	}
+
+	f, err := engine.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	md, err := read(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	return md, nil
+}
+
+// parseGain parses ReplayGain values like "-6.54 dB" or "0.988553".
+func parseGain(s string) float64 {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(s, "dB"), "db"))
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+// parsePosition parses positions like "3" or "3/12".
+func parsePosition(s string) (n, total int) {
+	s = strings.TrimSpace(s)
+	num, tot, _ := strings.Cut(s, "/")
+	n, _ = strconv.Atoi(strings.TrimSpace(num))
+	total, _ = strconv.Atoi(strings.TrimSpace(tot))
+	return n, total
+}
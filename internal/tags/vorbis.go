@@ -0,0 +1,290 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Maximum size of an Ogg packet read while looking for the comments
+	maxOggPacket int = 16 << 20
+)
+
+// VorbisComment : The comment block used by FLAC, Ogg Vorbis and Opus files
+type VorbisComment struct {
+	Vendor string
+
+	// Fields in the order they are stored
+	Fields []Field
+}
+
+// Field : A NAME=value entry of a Vorbis comment
+type Field struct {
+	Name  string
+	Value string
+}
+
+// FLAC : The metadata blocks of a FLAC file
+type FLAC struct {
+	// Blocks with their type, only the comments and pictures are kept
+	Blocks []Block
+
+	// Comment is nil if the file has no Vorbis comment block
+	Comment *VorbisComment
+}
+
+// Block : A metadata block of a FLAC file
+type Block struct {
+	Type byte
+	Data []byte
+}
+
+// Types of the FLAC metadata blocks
+const (
+	FLACCommentBlock byte = 4
+	FLACPictureBlock byte = 6
+)
+
+// ReadFLAC reads the metadata blocks at the start of a FLAC file. An ID3v2 tag
+// before them is skipped.
+func ReadFLAC(r io.Reader) (*FLAC, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, ErrNoTags
+	}
+	if string(magic[:3]) == "ID3" {
+		if _, err := ReadID3v2(io.MultiReader(bytes.NewReader(magic[:]), r)); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, magic[:]); err != nil {
+			return nil, ErrNoTags
+		}
+	}
+	if string(magic[:]) != "fLaC" {
+		return nil, ErrNoTags
+	}
+
+	f := new(FLAC)
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return f, err
+		}
+		last := header[0]&0x80 != 0
+		typ := header[0] & 0x7F
+		size := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+
+		if typ == FLACCommentBlock || typ == FLACPictureBlock {
+			data := make([]byte, size)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return f, err
+			}
+			f.Blocks = append(f.Blocks, Block{Type: typ, Data: data})
+
+			if typ == FLACCommentBlock && f.Comment == nil {
+				c, err := parseVorbisComment(data)
+				if err != nil {
+					return f, err
+				}
+				f.Comment = c
+			}
+		} else if _, err := io.CopyN(io.Discard, r, size); err != nil {
+			return f, err
+		}
+
+		if last {
+			return f, nil
+		}
+	}
+}
+
+// Tags returns the common tags.
+func (f *FLAC) Tags() Tags {
+	if f.Comment == nil {
+		return Tags{}
+	}
+	return f.Comment.Tags()
+}
+
+// ReadOgg reads the comment header of the first stream of an Ogg Vorbis or Opus file.
+func ReadOgg(r io.Reader) (*VorbisComment, error) {
+	var packets [][]byte
+	var packet []byte
+	var serial uint32
+
+	for first := true; len(packets) < 2; first = false {
+		var header [27]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if first {
+				return nil, ErrNoTags
+			}
+			return nil, err
+		}
+		if string(header[:4]) != "OggS" {
+			if first {
+				return nil, ErrNoTags
+			}
+			return nil, errors.New("bad Ogg page")
+		}
+
+		segments := make([]byte, header[26])
+		if _, err := io.ReadFull(r, segments); err != nil {
+			return nil, err
+		}
+		size := 0
+		for _, s := range segments {
+			size += int(s)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		// Pages of other streams are skipped
+		pageSerial := binary.LittleEndian.Uint32(header[14:18])
+		if first {
+			serial = pageSerial
+		}
+		if pageSerial != serial {
+			continue
+		}
+
+		// A segment shorter than 255 bytes ends a packet
+		for _, s := range segments {
+			packet = append(packet, data[:s]...)
+			data = data[s:]
+			if len(packet) > maxOggPacket {
+				return nil, errors.New("Ogg comment packet too large")
+			}
+			if s < 255 {
+				packets = append(packets, packet)
+				packet = nil
+			}
+		}
+	}
+
+	comment := packets[1]
+	switch {
+	case bytes.HasPrefix(comment, []byte("\x03vorbis")):
+		return parseVorbisComment(comment[7:])
+	case bytes.HasPrefix(comment, []byte("OpusTags")):
+		return parseVorbisComment(comment[8:])
+	}
+	return nil, ErrNoTags
+}
+
+// parseVorbisComment parses the vendor string and the fields of a comment block.
+func parseVorbisComment(data []byte) (*VorbisComment, error) {
+	bad := errors.New("bad Vorbis comment")
+
+	next := func() (string, bool) {
+		if len(data) < 4 {
+			return "", false
+		}
+		n := binary.LittleEndian.Uint32(data[:4])
+		if uint64(n) > uint64(len(data)-4) {
+			return "", false
+		}
+		s := string(data[4 : 4+n])
+		data = data[4+n:]
+		return s, true
+	}
+
+	vendor, ok := next()
+	if !ok || len(data) < 4 {
+		return nil, bad
+	}
+	c := &VorbisComment{Vendor: vendor}
+
+	count := binary.LittleEndian.Uint32(data[:4])
+	data = data[4:]
+	for range count {
+		field, ok := next()
+		if !ok {
+			return c, bad
+		}
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		c.Fields = append(c.Fields, Field{Name: strings.ToUpper(name), Value: value})
+	}
+	return c, nil
+}
+
+// Values returns every value of the fields with the given name, ignoring case.
+func (c *VorbisComment) Values(name string) []string {
+	var values []string
+	for _, f := range c.Fields {
+		if strings.EqualFold(f.Name, name) {
+			values = append(values, f.Value)
+		}
+	}
+	return values
+}
+
+// Text returns the values of the first of the given field names found, joined with "; ".
+func (c *VorbisComment) Text(names ...string) string {
+	for _, name := range names {
+		var clean []string
+		for _, v := range c.Values(name) {
+			if v = strings.TrimSpace(v); v != "" {
+				clean = append(clean, v)
+			}
+		}
+		if len(clean) > 0 {
+			return strings.Join(clean, "; ")
+		}
+	}
+	return ""
+}
+
+// Tags returns the common tags.
+func (c *VorbisComment) Tags() Tags {
+	tags := Tags{
+		Title:       c.Text("TITLE"),
+		Artist:      c.Text("ARTIST"),
+		AlbumArtist: c.Text("ALBUMARTIST", "ALBUM ARTIST", "ALBUM_ARTIST"),
+		Album:       c.Text("ALBUM"),
+		Genre:       c.Text("GENRE"),
+		Compilation: c.Text("COMPILATION") == "1",
+	}
+
+	// The totals are usually in their own fields, but "3/12" is found too
+	tags.Track, tags.TrackTotal = parsePosition(c.Text("TRACKNUMBER"))
+	if n, _ := parsePosition(c.Text("TRACKTOTAL", "TOTALTRACKS")); n > 0 {
+		tags.TrackTotal = n
+	}
+	tags.Disc, tags.DiscTotal = parsePosition(c.Text("DISCNUMBER"))
+	if n, _ := parsePosition(c.Text("DISCTOTAL", "TOTALDISCS")); n > 0 {
+		tags.DiscTotal = n
+	}
+
+	tags.ReplayGain = ReplayGain{
+		TrackGain: parseGain(c.Text("REPLAYGAIN_TRACK_GAIN")),
+		AlbumGain: parseGain(c.Text("REPLAYGAIN_ALBUM_GAIN")),
+		TrackPeak: parseGain(c.Text("REPLAYGAIN_TRACK_PEAK")),
+		AlbumPeak: parseGain(c.Text("REPLAYGAIN_ALBUM_PEAK")),
+	}
+	// Opus stores the gains as Q7.8 numbers relative to -23 LUFS, 5 dB below ReplayGain
+	if tags.ReplayGain.TrackGain == 0 {
+		tags.ReplayGain.TrackGain = r128Gain(c.Text("R128_TRACK_GAIN"))
+	}
+	if tags.ReplayGain.AlbumGain == 0 {
+		tags.ReplayGain.AlbumGain = r128Gain(c.Text("R128_ALBUM_GAIN"))
+	}
+	return tags
+}
+
+// r128Gain converts an Opus R128 gain to a ReplayGain gain in dB. Zero if unknown.
+func r128Gain(s string) float64 {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0
+	}
+	return float64(n)/256 + 5
+}
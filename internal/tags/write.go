@@ -0,0 +1,604 @@
+package tags
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	// Free space left after a new ID3v2 tag, so players can grow it without rewriting the file
+	id3v2Padding int = 1024
+	// Vendor string of the Vorbis comments created from scratch
+	vendor string = "tempo"
+)
+
+// CanWrite reports whether WriteFile supports the file at path, based on its extension.
+func CanWrite(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3", ".flac", ".ogg", ".oga", ".opus":
+		return true
+	}
+	return false
+}
+
+// WriteFile stores the common tags in the file at path, using the tag format of its
+// container: ID3v2 for MP3 files and Vorbis comments for FLAC and Ogg files. Only the
+// tags that differ from the ones in the file are written, and empty tags are removed.
+// The other frames and fields, like the pictures or the ReplayGain values, are kept.
+//
+// The file is rewritten into a temporary file that replaces it once complete.
+func WriteFile(path string, t Tags) error {
+	var write func(r *bufio.Reader, w io.Writer, t Tags) error
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".mp3":
+		write = writeMP3
+	case ".flac":
+		write = writeFLAC
+	case ".ogg", ".oga", ".opus":
+		write = writeOgg
+	default:
+		return fmt.Errorf("cannot write tags to %s files", ext)
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	info, err := src.Stat()
+	if err != nil {
+		src.Close()
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		src.Close()
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	err = write(bufio.NewReader(src), w, t)
+	src.Close()
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// position formats a track or disc position like "3" or "3/12". Empty if unknown.
+func position(n, total int) string {
+	if n <= 0 {
+		return ""
+	}
+	if total > 0 {
+		return strconv.Itoa(n) + "/" + strconv.Itoa(total)
+	}
+	return strconv.Itoa(n)
+}
+
+// number formats a positive number, empty if unknown.
+func number(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// flag formats a boolean tag as "1", or empty if false.
+func flag(b bool) string {
+	if b {
+		return "1"
+	}
+	return ""
+}
+
+// writeMP3 copies an MP3 file replacing its ID3v2 tag.
+func writeMP3(r *bufio.Reader, w io.Writer, t Tags) error {
+	tag := &ID3v2{Version: 4}
+	if header, err := r.Peek(10); err == nil && string(header[:3]) == "ID3" {
+		footer := header[3] == 4 && header[5]&0x10 != 0
+
+		old, err := ReadID3v2(r)
+		if err != nil {
+			return err
+		}
+		if footer {
+			if _, err := r.Discard(10); err != nil {
+				return err
+			}
+		}
+		tag = old
+	}
+
+	tag.apply(t)
+	if _, err := w.Write(tag.encode()); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, r)
+	return err
+}
+
+// apply replaces the text frames of the tags that changed.
+func (t *ID3v2) apply(tags Tags) {
+	old := t.Tags()
+	set := func(id, value, oldValue string) {
+		if value != oldValue {
+			t.SetText(id, value)
+		}
+	}
+	set("TIT2", tags.Title, old.Title)
+	set("TPE1", tags.Artist, old.Artist)
+	set("TPE2", tags.AlbumArtist, old.AlbumArtist)
+	set("TALB", tags.Album, old.Album)
+	set("TCON", tags.Genre, old.Genre)
+	set("TRCK", position(tags.Track, tags.TrackTotal), position(old.Track, old.TrackTotal))
+	set("TPOS", position(tags.Disc, tags.DiscTotal), position(old.Disc, old.DiscTotal))
+	if tags.Compilation != old.Compilation {
+		t.SetText("TCMP", flag(tags.Compilation))
+	}
+}
+
+// SetText replaces the text frames with the given ID by one with the value.
+// An empty value removes them.
+func (t *ID3v2) SetText(id, value string) {
+	frames := t.Frames[:0]
+	found := false
+	for _, f := range t.Frames {
+		if f.ID != id {
+			frames = append(frames, f)
+			continue
+		}
+		if !found && value != "" {
+			frames = append(frames, Frame{ID: id, Data: t.encodeText(value)})
+		}
+		found = true
+	}
+	if !found && value != "" {
+		frames = append(frames, Frame{ID: id, Data: t.encodeText(value)})
+	}
+	t.Frames = frames
+}
+
+// encodeText encodes the body of a text frame: UTF-8 in ID3v2.4, and ISO-8859-1 or
+// UTF-16 before, which does not support UTF-8.
+func (t *ID3v2) encodeText(s string) []byte {
+	if t.Version != 3 {
+		return append([]byte{3}, s...)
+	}
+
+	latin := []byte{0}
+	for _, r := range s {
+		if r > 0xFF {
+			data := []byte{1, 0xFF, 0xFE}
+			for _, u := range utf16.Encode([]rune(s)) {
+				data = binary.LittleEndian.AppendUint16(data, u)
+			}
+			return data
+		}
+		latin = append(latin, byte(r))
+	}
+	return latin
+}
+
+// encode returns the tag with its header, in ID3v2.3 if it was read as such and in
+// ID3v2.4 otherwise.
+func (t *ID3v2) encode() []byte {
+	version := byte(4)
+	if t.Version == 3 {
+		version = 3
+	}
+
+	var body []byte
+	for _, f := range t.Frames {
+		data := f.Data
+		// The pictures of ID3v2.2 have a three letter format instead of a MIME type
+		if t.Version == 2 && f.ID == "APIC" && len(data) >= 4 {
+			mime := "image/" + strings.ToLower(string(data[1:4]))
+			data = append(append([]byte{data[0]}, mime...), append([]byte{0}, data[4:]...)...)
+		}
+
+		body = append(body, f.ID...)
+		if version == 4 {
+			body = append(body, syncsafeBytes(len(data))...)
+		} else {
+			body = binary.BigEndian.AppendUint32(body, uint32(len(data)))
+		}
+		body = append(body, 0, 0)
+		body = append(body, data...)
+	}
+	body = append(body, make([]byte, id3v2Padding)...)
+
+	header := []byte{'I', 'D', '3', version, 0, 0}
+	header = append(header, syncsafeBytes(len(body))...)
+	return append(header, body...)
+}
+
+func syncsafeBytes(n int) []byte {
+	return []byte{byte(n>>21) & 0x7F, byte(n>>14) & 0x7F, byte(n>>7) & 0x7F, byte(n) & 0x7F}
+}
+
+// writeFLAC copies a FLAC file replacing its Vorbis comment block.
+func writeFLAC(r *bufio.Reader, w io.Writer, t Tags) error {
+	// An ID3v2 tag before the FLAC stream is kept as it is
+	if header, err := r.Peek(10); err == nil && string(header[:3]) == "ID3" {
+		size := int64(syncsafe(header[6:10])) + 10
+		if header[3] == 4 && header[5]&0x10 != 0 {
+			size += 10
+		}
+		if _, err := io.CopyN(w, r, size); err != nil {
+			return err
+		}
+	}
+
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil || string(magic[:]) != "fLaC" {
+		return errors.New("not a FLAC file")
+	}
+
+	var blocks []Block
+	for last := false; !last; {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return err
+		}
+		last = header[0]&0x80 != 0
+		size := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return err
+		}
+		blocks = append(blocks, Block{Type: header[0] & 0x7F, Data: data})
+	}
+
+	// Only the first comment block is kept, a new one follows the stream information
+	comment := -1
+	for i := 0; i < len(blocks); i++ {
+		if blocks[i].Type != FLACCommentBlock {
+			continue
+		}
+		if comment >= 0 {
+			blocks = append(blocks[:i], blocks[i+1:]...)
+			i--
+			continue
+		}
+		comment = i
+	}
+
+	c := &VorbisComment{Vendor: vendor}
+	if comment >= 0 {
+		parsed, err := parseVorbisComment(blocks[comment].Data)
+		if err != nil {
+			return err
+		}
+		c = parsed
+	} else {
+		comment = min(1, len(blocks))
+		blocks = append(blocks[:comment], append([]Block{{Type: FLACCommentBlock}}, blocks[comment:]...)...)
+	}
+	c.apply(t)
+	blocks[comment].Data = c.encode()
+
+	if _, err := w.Write(magic[:]); err != nil {
+		return err
+	}
+	for i, b := range blocks {
+		if len(b.Data) >= 1<<24 {
+			return errors.New("FLAC metadata block too large")
+		}
+		typ := b.Type
+		if i == len(blocks)-1 {
+			typ |= 0x80
+		}
+		header := []byte{typ, byte(len(b.Data) >> 16), byte(len(b.Data) >> 8), byte(len(b.Data))}
+		if _, err := w.Write(append(header, b.Data...)); err != nil {
+			return err
+		}
+	}
+	_, err := io.Copy(w, r)
+	return err
+}
+
+// apply replaces the fields of the tags that changed.
+func (c *VorbisComment) apply(t Tags) {
+	old := c.Tags()
+	set := func(value, oldValue, name string, aliases ...string) {
+		if value == oldValue {
+			return
+		}
+		for _, alias := range aliases {
+			c.Set(alias, "")
+		}
+		c.Set(name, value)
+	}
+	set(t.Title, old.Title, "TITLE")
+	set(t.Artist, old.Artist, "ARTIST")
+	set(t.AlbumArtist, old.AlbumArtist, "ALBUMARTIST", "ALBUM ARTIST", "ALBUM_ARTIST")
+	set(t.Album, old.Album, "ALBUM")
+	set(t.Genre, old.Genre, "GENRE")
+	set(number(t.Track), number(old.Track), "TRACKNUMBER")
+	set(number(t.TrackTotal), number(old.TrackTotal), "TRACKTOTAL", "TOTALTRACKS")
+	set(number(t.Disc), number(old.Disc), "DISCNUMBER")
+	set(number(t.DiscTotal), number(old.DiscTotal), "DISCTOTAL", "TOTALDISCS")
+	if t.Compilation != old.Compilation {
+		c.Set("COMPILATION", flag(t.Compilation))
+	}
+
+	// The totals may have been written along with the positions, like "3/12"
+	if t.Track != old.Track || t.TrackTotal != old.TrackTotal {
+		c.Set("TRACKNUMBER", number(t.Track))
+	}
+	if t.Disc != old.Disc || t.DiscTotal != old.DiscTotal {
+		c.Set("DISCNUMBER", number(t.Disc))
+	}
+}
+
+// Set replaces the fields with the given name, ignoring case, by one with the value.
+// An empty value removes them.
+func (c *VorbisComment) Set(name, value string) {
+	name = strings.ToUpper(name)
+	fields := c.Fields[:0]
+	found := false
+	for _, f := range c.Fields {
+		if !strings.EqualFold(f.Name, name) {
+			fields = append(fields, f)
+			continue
+		}
+		if !found && value != "" {
+			fields = append(fields, Field{Name: name, Value: value})
+		}
+		found = true
+	}
+	if !found && value != "" {
+		fields = append(fields, Field{Name: name, Value: value})
+	}
+	c.Fields = fields
+}
+
+// encode returns the comment block, without the framing bit of Ogg Vorbis.
+func (c *VorbisComment) encode() []byte {
+	data := binary.LittleEndian.AppendUint32(nil, uint32(len(c.Vendor)))
+	data = append(data, c.Vendor...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(c.Fields)))
+	for _, f := range c.Fields {
+		field := f.Name + "=" + f.Value
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(field)))
+		data = append(data, field...)
+	}
+	return data
+}
+
+// oggPage : A page of an Ogg stream
+type oggPage struct {
+	header   [27]byte
+	segments []byte
+	data     []byte
+}
+
+func readOggPage(r io.Reader) (oggPage, error) {
+	var p oggPage
+	if _, err := io.ReadFull(r, p.header[:]); err != nil {
+		return p, err
+	}
+	if string(p.header[:4]) != "OggS" {
+		return p, errors.New("bad Ogg page")
+	}
+	p.segments = make([]byte, p.header[26])
+	if _, err := io.ReadFull(r, p.segments); err != nil {
+		return p, err
+	}
+	size := 0
+	for _, s := range p.segments {
+		size += int(s)
+	}
+	p.data = make([]byte, size)
+	_, err := io.ReadFull(r, p.data)
+	return p, err
+}
+
+func (p *oggPage) serial() uint32 {
+	return binary.LittleEndian.Uint32(p.header[14:18])
+}
+
+func (p *oggPage) sequence() uint32 {
+	return binary.LittleEndian.Uint32(p.header[18:22])
+}
+
+// bytes returns the page with its checksum updated.
+func (p *oggPage) bytes() []byte {
+	clear(p.header[22:26])
+	page := append(append(p.header[:], p.segments...), p.data...)
+	binary.LittleEndian.PutUint32(page[22:26], oggCRC(page))
+	return page
+}
+
+// newOggPages splits the packets into pages of the stream, starting at the sequence number.
+// The pages are for header packets, so their granule position is zero.
+func newOggPages(packets [][]byte, serial, sequence uint32) []oggPage {
+	type segment struct {
+		data []byte
+		end  bool
+	}
+	var segments []segment
+	for _, packet := range packets {
+		for len(packet) >= 255 {
+			segments = append(segments, segment{data: packet[:255]})
+			packet = packet[255:]
+		}
+		segments = append(segments, segment{data: packet, end: true})
+	}
+
+	var pages []oggPage
+	continued := false
+	for len(segments) > 0 {
+		n := min(255, len(segments))
+
+		var p oggPage
+		copy(p.header[:], "OggS")
+		if continued {
+			p.header[5] = 0x01
+		}
+		// Pages where no packet ends have no granule position
+		granule := ^uint64(0)
+		for _, s := range segments[:n] {
+			p.segments = append(p.segments, byte(len(s.data)))
+			p.data = append(p.data, s.data...)
+			if s.end {
+				granule = 0
+			}
+		}
+		binary.LittleEndian.PutUint64(p.header[6:14], granule)
+		binary.LittleEndian.PutUint32(p.header[14:18], serial)
+		binary.LittleEndian.PutUint32(p.header[18:22], sequence)
+		p.header[26] = byte(n)
+
+		pages = append(pages, p)
+		continued = !segments[n-1].end
+		segments = segments[n:]
+		sequence++
+	}
+	return pages
+}
+
+// writeOgg copies an Ogg Vorbis or Opus file replacing the comment header of its first
+// stream. The following pages of the stream are renumbered when the header takes more
+// or less pages than before.
+func writeOgg(r *bufio.Reader, w io.Writer, t Tags) error {
+	first, err := readOggPage(r)
+	if err != nil {
+		return err
+	}
+	serial := first.serial()
+
+	// The identification header is alone in the first page, and tells how many headers follow
+	var headers int
+	var prefix, suffix string
+	ends := 0
+	for _, s := range first.segments {
+		if s < 255 {
+			ends++
+		}
+	}
+	switch {
+	case ends != 1 || first.segments[len(first.segments)-1] == 255:
+		return errors.New("bad Ogg identification header")
+	case strings.HasPrefix(string(first.data), "\x01vorbis"):
+		headers, prefix, suffix = 2, "\x03vorbis", "\x01"
+	case strings.HasPrefix(string(first.data), "OpusHead"):
+		headers, prefix = 1, "OpusTags"
+	default:
+		return errors.New("unsupported Ogg codec")
+	}
+
+	var packets [][]byte
+	var packet []byte
+	var old int
+	for len(packets) < headers || len(packet) > 0 {
+		p, err := readOggPage(r)
+		if err != nil {
+			return err
+		}
+		if p.serial() != serial {
+			return errors.New("multiplexed Ogg streams are not supported")
+		}
+		old++
+
+		data := p.data
+		for _, s := range p.segments {
+			packet = append(packet, data[:s]...)
+			data = data[s:]
+			if s < 255 {
+				packets = append(packets, packet)
+				packet = nil
+			}
+		}
+		if len(packets) > headers {
+			return errors.New("Ogg headers do not end a page")
+		}
+	}
+
+	c, err := parseVorbisComment(packets[0][len(prefix):])
+	if err != nil {
+		return err
+	}
+	c.apply(t)
+	packets[0] = append(append([]byte(prefix), c.encode()...), suffix...)
+
+	pages := newOggPages(packets, serial, first.sequence()+1)
+	delta := uint32(len(pages) - old)
+
+	if _, err := w.Write(append(append(first.header[:], first.segments...), first.data...)); err != nil {
+		return err
+	}
+	for _, p := range pages {
+		if _, err := w.Write(p.bytes()); err != nil {
+			return err
+		}
+	}
+	if delta == 0 {
+		_, err := io.Copy(w, r)
+		return err
+	}
+
+	for {
+		p, err := readOggPage(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if p.serial() == serial {
+			binary.LittleEndian.PutUint32(p.header[18:22], p.sequence()+delta)
+		}
+		if _, err := w.Write(p.bytes()); err != nil {
+			return err
+		}
+	}
+}
+
+var oggCRCTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		r := uint32(i) << 24
+		for range 8 {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04C11DB7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}()
+
+// oggCRC returns the checksum of an Ogg page, computed with its checksum field set to zero.
+func oggCRC(page []byte) uint32 {
+	var crc uint32
+	for _, b := range page {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
@@ -0,0 +1,133 @@
+// Package trash moves files to the trash of the desktop instead of deleting them,
+// following the FreeDesktop.org Trash specification on Unix and the user trash on macOS.
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrUnsupported is returned on the platforms without a known trash location.
+var ErrUnsupported = errors.New("the trash is not supported on this platform")
+
+// Dir returns the trash directory of the user.
+// $XDG_DATA_HOME/Trash, falling back to ~/.local/share/Trash, or ~/.Trash on macOS.
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, ".Trash"), nil
+	case "windows", "ios", "android", "plan9", "js", "wasip1":
+		return "", ErrUnsupported
+	}
+
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, "Trash"), nil
+	}
+	return filepath.Join(home, ".local", "share", "Trash"), nil
+}
+
+// Move moves the file or directory at path to the trash. It only works when the trash
+// is in the same filesystem as the file, nothing is copied.
+func Move(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(path); err != nil {
+		return err
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS == "darwin" {
+		return moveUnique(path, dir, nil)
+	}
+
+	// The info file stores where the file comes from, so it can be restored
+	files, info := filepath.Join(dir, "files"), filepath.Join(dir, "info")
+	for _, d := range []string{files, info} {
+		if err := os.MkdirAll(d, 0o700); err != nil {
+			return err
+		}
+	}
+	return moveUnique(path, files, func(name string) (func(), error) {
+		infoPath := filepath.Join(info, name+".trashinfo")
+		f, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		_, err = fmt.Fprintf(f, "[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+			escapePath(path), time.Now().Format("2006-01-02T15:04:05"))
+		if err != nil {
+			os.Remove(infoPath)
+			return nil, err
+		}
+		return func() { os.Remove(infoPath) }, nil
+	})
+}
+
+// moveUnique renames path into dir, adding a number to the name until it is not taken.
+// reserve is called before renaming to claim the name, returning how to release it.
+func moveUnique(path, dir string, reserve func(name string) (func(), error)) error {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name = fmt.Sprintf("%s.%d%s", stem, i, ext)
+		}
+
+		release := func() {}
+		if reserve != nil {
+			r, err := reserve(name)
+			if errors.Is(err, os.ErrExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			release = r
+		}
+
+		target := filepath.Join(dir, name)
+		if _, err := os.Lstat(target); err == nil {
+			release()
+			continue
+		}
+		if err := os.Rename(path, target); err != nil {
+			release()
+			if errors.Is(err, syscall.EXDEV) {
+				return fmt.Errorf("cannot move %s to the trash from another filesystem", path)
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+// escapePath percent-encodes a path for the trash info file, keeping the separators.
+func escapePath(path string) string {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
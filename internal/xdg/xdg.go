@@ -0,0 +1,119 @@
+// Package xdg resolves where tempo stores its files, following the XDG Base
+// Directory specification on Unix and the platform conventions elsewhere.
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// AppName is the name of the directory created inside the base directories.
+const AppName = "tempo"
+
+// ProfilesDir is the directory of the profiles inside the config, data and state
+// directories, with one directory for each.
+const ProfilesDir = "profiles"
+
+// Profile whose files are used instead of the default ones, if not empty
+var profile string
+
+// SetProfile makes the config, data and state directories the ones of a profile, a named
+// set of settings and data kept apart from the default one. The cache stays shared.
+func SetProfile(name string) {
+	profile = name
+}
+
+// ConfigDir returns the directory for the configuration files.
+// $XDG_CONFIG_HOME/tempo, falling back to ~/.config/tempo.
+func ConfigDir() (string, error) {
+	return profileDir(baseDir("XDG_CONFIG_HOME", ".config"))
+}
+
+// DataDir returns the directory for user data, like the library index.
+// $XDG_DATA_HOME/tempo, falling back to ~/.local/share/tempo.
+func DataDir() (string, error) {
+	return profileDir(baseDir("XDG_DATA_HOME", filepath.Join(".local", "share")))
+}
+
+// StateDir returns the directory for data that should outlive a run but is not worth
+// backing up, like the last volume. $XDG_STATE_HOME/tempo, falling back to
+// ~/.local/state/tempo.
+func StateDir() (string, error) {
+	return profileDir(baseDir("XDG_STATE_HOME", filepath.Join(".local", "state")))
+}
+
+// CacheDir returns the directory for files that can be fetched again, like the
+// responses of online services. $XDG_CACHE_HOME/tempo, falling back to ~/.cache/tempo.
+func CacheDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios":
+		if os.Getenv("XDG_CACHE_HOME") == "" {
+			dir, err := os.UserCacheDir()
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(dir, AppName), nil
+		}
+	}
+	return baseDir("XDG_CACHE_HOME", ".cache")
+}
+
+// RuntimeDir returns the directory for the files that only exist while tempo runs, like its
+// control socket. $XDG_RUNTIME_DIR/tempo, falling back to the state directory.
+func RuntimeDir() (string, error) {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && filepath.IsAbs(dir) {
+		return profileDir(filepath.Join(dir, AppName), nil)
+	}
+	return StateDir()
+}
+
+// profileDir returns the directory of the profile in use inside dir.
+func profileDir(dir string, err error) (string, error) {
+	if err != nil || profile == "" {
+		return dir, err
+	}
+	return filepath.Join(dir, ProfilesDir, profile), nil
+}
+
+func baseDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, AppName), nil
+	}
+
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios":
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, AppName), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, fallback, AppName), nil
+}
+
+// ExpandHome replaces a leading ~ with the home directory.
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return home + strings.TrimPrefix(path, "~")
+}
+
+// Ensure creates the directory if it does not exist yet and returns it.
+func Ensure(dir string, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	return dir, os.MkdirAll(dir, 0o755)
+}
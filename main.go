@@ -1,48 +1,780 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/nicolito128/tempo/internal/components/player"
+	"github.com/nicolito128/tempo/internal/components/queue"
 	"github.com/nicolito128/tempo/internal/components/ui"
+	"github.com/nicolito128/tempo/internal/config"
+	"github.com/nicolito128/tempo/internal/control"
+	"github.com/nicolito128/tempo/internal/crash"
+	"github.com/nicolito128/tempo/internal/discord"
+	"github.com/nicolito128/tempo/internal/hooks"
+	"github.com/nicolito128/tempo/internal/httpapi"
+	"github.com/nicolito128/tempo/internal/library"
+	"github.com/nicolito128/tempo/internal/logging"
+	"github.com/nicolito128/tempo/internal/mediakeys"
+	"github.com/nicolito128/tempo/internal/mpd"
+	"github.com/nicolito128/tempo/internal/signals"
+	"github.com/nicolito128/tempo/internal/state"
+	"github.com/nicolito128/tempo/internal/statusfile"
+	"github.com/nicolito128/tempo/internal/suspend"
+	"github.com/nicolito128/tempo/internal/xdg"
+	"github.com/nicolito128/tempo/pkg/engine"
 )
 
-var (
-	play = flag.String("play", "", "Load an audio file from the given path")
-	vol  = flag.Int("vol", 50, "Initial volume to play the audio")
-)
+// command : A subcommand of tempo, like tempo scan
+type command struct {
+	name string
+	// Arguments after the name, for the usage
+	args    string
+	summary string
+	run     func(args []string) error
+}
+
+// Commands in the order they are listed by tempo help, set in init since help lists them
+var commands []command
+
+func init() {
+	commands = []command{
+		{"play", "[flags] [path...]", "Play audio files, directories and playlists in the TUI (the default)", runPlay},
+		{"serve", "[-http address] [flags] [path...]", "Play in background, controlled through the HTTP API and the other remotes", runServe},
+		{"queue", "add <path...>", "Enqueue audio files, directories, playlists and URLs into the running tempo", runQueue},
+		{"scan", "[dir...]", "Scan music directories into the library index", runScan},
+		{"duplicates", "", "Print the likely duplicated tracks of the library index", runDuplicates},
+		{"info", "[-json] <file>...", "Print the format, duration and tags of audio files", runInfo},
+		{"devices", "", "List the audio output devices", runDevices},
+		{"identify", "<file>...", "Identify audio files by their fingerprint with AcoustID", runIdentify},
+		{"analyze", "<file>...", "Estimate the tempo and key of audio files", runAnalyze},
+		{"loudness", "<file>...", "Measure the EBU R128 loudness of audio files", runLoudness},
+		{"config", "<init [-force]|validate [file]>", "Write the default config file, or check one for mistakes", runConfig},
+		{"ctl", "<command> [args...]", "Control the running tempo, like tempo ctl pause or tempo ctl status", runCtl},
+		{"completion", "<bash|zsh|fish>", "Print the shell completion script of tempo", runCompletion},
+		{"help", "", "Show this help", runHelp},
+	}
+}
 
 func main() {
-	if len(os.Args)-1 <= 0 {
-		fmt.Println("Bad arguments: You must set a path to a song")
-		os.Exit(1)
+	// macOS delivers the media keys on the main thread, so tempo runs on another one there
+	mediakeys.Main(func() {
+		// Exiting skips the deferred calls, so it waits for run to return, like for the
+		// library index to be closed
+		if err := run(); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	})
+}
+
+// run does the command of the arguments, returning its error once everything it opened is
+// closed.
+func run() error {
+	opts, args, err := parseGlobal(os.Args[1:])
+	if err == nil {
+		err = useProfile(opts, args)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Logging is optional, so a log that cannot be found is not an error
+	logging.Discard()
+	if path, err := logging.Path(); err == nil {
+		defer logging.Open(path, opts.logLevel).Close()
+	}
+
+	// Without a command the arguments are the ones of play, so tempo song.mp3 still works
+	cmd := commands[0]
+	if len(args) > 0 {
+		if c, ok := findCommand(args[0]); ok {
+			cmd, args = c, args[1:]
+		} else if args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+			cmd, args = mustFindCommand("help"), nil
+		}
+	}
+	return cmd.run(args)
+}
+
+// Level of the log when not given
+const defaultLogLevel = "warn"
+
+// globalOptions : The options of every command, given before it
+type globalOptions struct {
+	// profile whose files are used, if given
+	profile      string
+	profileGiven bool
+	logLevel     slog.Level
+}
+
+// parseGlobal parses -profile <name> and -log-level <level> (or with --, or with an =) given
+// before the command in any order, or else TEMPO_PROFILE and TEMPO_LOG_LEVEL, returning the
+// arguments after them.
+func parseGlobal(args []string) (globalOptions, []string, error) {
+	var opts globalOptions
+	opts.profile, opts.profileGiven = os.LookupEnv("TEMPO_PROFILE")
+	level, ok := os.LookupEnv("TEMPO_LOG_LEVEL")
+	if !ok {
+		level = defaultLogLevel
+	}
+
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(args[0], "-"), "-"), "=")
+		if name != "profile" && name != "log-level" {
+			break
+		}
+		args = args[1:]
+		if !hasValue {
+			if len(args) == 0 {
+				return opts, nil, fmt.Errorf("-%s needs a value", name)
+			}
+			value, args = args[0], args[1:]
+		}
+		if name == "profile" {
+			opts.profile, opts.profileGiven = value, true
+		} else {
+			level = value
+		}
+	}
+
+	var err error
+	opts.logLevel, err = logging.ParseLevel(level)
+	return opts, args, err
+}
+
+// useProfile uses the files of the profile of opts, if any. The profile must have a config
+// file, but to write it with tempo config init, the command in args.
+func useProfile(opts globalOptions, args []string) error {
+	if !opts.profileGiven {
+		return nil
+	}
+	value := opts.profile
+	if value == "" || value == "." || value == ".." || filepath.Base(value) != value {
+		return fmt.Errorf("%q is not a profile name", value)
+	}
+
+	xdg.SetProfile(value)
+	path, err := config.Path()
+	if err != nil {
+		return fmt.Errorf("cannot find the config location: %w", err)
+	}
+	// Unless the command is the one writing it
+	if len(args) > 1 && args[0] == "config" && args[1] == "init" {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("the profile %s has no config file, create %s or run tempo -profile %s config init", value, path, value)
+	}
+	return nil
+}
+
+// findCommand returns the command with the given name.
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// mustFindCommand returns the command with the given name, which must exist.
+func mustFindCommand(name string) command {
+	c, ok := findCommand(name)
+	if !ok {
+		panic("unknown command " + name)
+	}
+	return c
+}
+
+// newFlagSet creates the flags of a command, printing its usage on -h or bad flags.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("tempo "+name, flag.ExitOnError)
+	fs.Usage = func() {
+		c := mustFindCommand(name)
+		fmt.Fprintf(fs.Output(), "usage: tempo %s %s\n\n%s.\n", c.name, c.args, c.summary)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(fs.Output(), "\nFlags:")
+			fs.PrintDefaults()
+		}
 	}
-	flag.Parse()
+	return fs
+}
+
+// parseFlags parses the flags found anywhere among the arguments, not only before the first
+// positional one, and returns the positional ones. The ones after -- are never flags.
+func parseFlags(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional
+		}
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...)
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// loadConfig reads the config file, using the default settings if it cannot be read, which
+// then leave the file as it is, with the settings of the TEMPO_* environment variables in
+// place of the ones of the file.
+func loadConfig() *config.Config {
+	cfg := config.Default()
+	if path, err := config.Path(); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: cannot find the config location:", err)
+	} else if cfg, err = config.Load(path); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: cannot read the config file, the default settings are used and not saved:", err)
+	}
+	for _, key := range cfg.Unknown() {
+		fmt.Fprintln(os.Stderr, "Warning: ignoring the unknown setting", key, "of the config file")
+	}
+	for _, err := range cfg.ApplyEnv(os.Environ()) {
+		fmt.Fprintln(os.Stderr, "Warning: ignoring the environment variable", err)
+	}
+	return cfg
+}
+
+// libraryDirs expands the ~ of directories of the config, like the music ones, skipping the
+// empty ones.
+func libraryDirs(dirs []string) []string {
+	var expanded []string
+	for _, dir := range dirs {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			expanded = append(expanded, xdg.ExpandHome(dir))
+		}
+	}
+	return expanded
+}
 
-	// Handle error in case the file does not exist
-	if _, err := os.Stat(*play); err != nil {
-		fmt.Println("Error: the file does not exist")
-		os.Exit(1)
+// runHelp prints the commands.
+func runHelp(args []string) error {
+	fmt.Println("usage: tempo [-profile name] [-log-level level] [command] [flags] [args]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	for _, c := range commands {
+		fmt.Printf("  %-11s %s\n", c.name, c.summary)
 	}
+	fmt.Println()
+	fmt.Println("Without a command the arguments are the ones of play. Run tempo <command> -h for the flags of a command.")
+	return nil
+}
+
+// playOptions : The flags of tempo play
+type playOptions struct {
+	vol        int
+	dedup      string
+	dedupAudio bool
+	dir        string
+	lib        string
+	smart      string
+	device     string
+	speed      float64
+	loop       bool
+	shuffle    bool
+	startAt    string
+	paused     bool
+	record     bool
+	noUI       bool
+	// newInstance starts playing even if another tempo is running, with single_instance
+	newInstance bool
+
+	// Address and token of the HTTP API of tempo serve
+	httpAddr string
+	token    string
+}
 
-	// Handle error in case the file is not a valid audio file (mp3 or wav)
-	ext := filepath.Ext(*play)
-	if ext != ".mp3" && ext != ".wav" {
-		fmt.Println("Error: the file is not a valid audio file. Try using a .mp3 or .wav file")
-		os.Exit(1)
+// playFlags defines the flags of tempo play, or of tempo serve with the ones of the HTTP API
+// instead of the ones of the terminal, with the defaults of the config.
+func playFlags(name string, cfg *config.Config) (*flag.FlagSet, *playOptions) {
+	fs := newFlagSet(name)
+	opts := new(playOptions)
+	fs.IntVar(&opts.vol, "vol", cfg.Player.Volume, "Initial volume to play the audio")
+	fs.StringVar(&opts.dedup, "dedup", "skip", "What to do with duplicated queue entries: skip, flag or off")
+	fs.BoolVar(&opts.dedupAudio, "dedup-audio", false, "Also treat files with the same size and duration as duplicates")
+	fs.StringVar(&opts.dir, "dir", ".", "Directory to start browsing from")
+	fs.StringVar(&opts.lib, "library", strings.Join(cfg.Library.Dirs, ","), "Comma separated list of music directories to scan")
+	fs.StringVar(&opts.smart, "smart", "", "Enqueue the tracks of the smart playlist with the given name")
+	fs.StringVar(&opts.device, "device", "", "Audio output device to play on, listed by \"tempo devices\"")
+	fs.Float64Var(&opts.speed, "speed", 1, "Playback speed, from 0.5 to 3, keeping the pitch")
+	fs.BoolVar(&opts.loop, "loop", false, "Start the queue over after its last file")
+	fs.BoolVar(&opts.shuffle, "shuffle", false, "Play the queue in a random order")
+	fs.StringVar(&opts.startAt, "start-at", "", "Position the first file starts at, like 90, 1:30 or 1:23:45")
+	fs.BoolVar(&opts.paused, "paused", false, "Load the first file paused, waiting for Space to play it")
+	fs.BoolVar(&opts.record, "record", false, "Record the playback to a WAV file in the record_dir of the config")
+	if name == "serve" {
+		fs.StringVar(&opts.httpAddr, "http", cfg.Remote.HTTP, "Address to serve the HTTP API at, like :8080")
+		fs.StringVar(&opts.token, "token", cfg.Remote.HTTPToken, "Token of the HTTP API, a random one if empty")
+		return fs, opts
 	}
+	fs.BoolVar(&opts.noUI, "no-ui", false, "Play the paths without the TUI, printing the files played, until the queue ends")
+	fs.BoolVar(&opts.newInstance, "new-instance", false, "Play even if tempo is running already, with single_instance in the config")
+	return fs, opts
+}
 
-	af := player.NewAudioFile(*play)
-	tui := ui.New(*vol)
-	tui.Player().SetAudioFile(af)
+// runPlay opens the TUI, playing the given paths.
+func runPlay(args []string) error {
+	return play("play", args)
+}
+
+// runServe plays in background without the TUI, controlled through the HTTP API and the other
+// remotes of the config, until it is told to quit or interrupted.
+func runServe(args []string) error {
+	return play("serve", args)
+}
+
+// play runs the player of the command with the given name, play or serve.
+func play(name string, args []string) error {
+	cfg := loadConfig()
+	serving := name == "serve"
+
+	// The flags override the settings of the config
+	fs, opts := playFlags(name, cfg)
+	paths := parseFlags(fs, args)
+
+	// A second tempo would fight the running one over the audio device
+	if cfg.Remote.SingleInstance && !opts.newInstance && !serving {
+		if enqueued, err := enqueueRunning(paths); enqueued || err != nil {
+			return err
+		}
+	}
+
+	// The settings without a flag are the ones the last run ended with
+	st := loadState(cfg)
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	// The volume of the environment is given like the one of the flag
+	volumeGiven := given["vol"] || cfg.FromEnv("player.volume")
+	if !volumeGiven {
+		opts.vol = st.Volume
+	}
+	if !given["speed"] {
+		opts.speed = st.Speed
+	}
+	if !given["loop"] {
+		opts.loop = st.Repeat
+	}
+	if !given["shuffle"] {
+		opts.shuffle = st.Shuffle
+	}
+
+	mode, err := queue.ParseDedupMode(opts.dedup)
+	if err != nil {
+		return err
+	}
+
+	// Handle error in case the directory to browse does not exist
+	if info, err := os.Stat(opts.dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("the directory %s does not exist", opts.dir)
+	}
+
+	if opts.vol < 0 || opts.vol > 100 {
+		return fmt.Errorf("the volume must be between 0 and 100")
+	}
+
+	var start time.Duration
+	if opts.startAt != "" {
+		if start, err = player.ParseTimestamp(opts.startAt); err != nil {
+			return fmt.Errorf("-start-at: %w", err)
+		}
+	}
+
+	tui := ui.New(opts.vol, opts.dir)
+	if opts.speed < engine.MinSpeed || opts.speed > engine.MaxSpeed {
+		return fmt.Errorf("the speed must be between %g and %g", engine.MinSpeed, engine.MaxSpeed)
+	}
+	tui.Player().SetSpeed(opts.speed)
+	// A volume given with -vol is meant to be heard
+	if st.Muted && !volumeGiven {
+		tui.Player().MuteVolume()
+	}
+
+	if err := tui.SetConfig(cfg); err != nil {
+		return fmt.Errorf("bad config: %w", err)
+	}
+	tui.Queue().SetDedup(mode, opts.dedupAudio)
+
+	if opts.device != "" {
+		if err := tui.SetDevice(opts.device); err != nil {
+			return err
+		}
+	}
+
+	if opts.lib != "" {
+		tui.SetLibraryDirs(libraryDirs(strings.Split(opts.lib, ",")))
+
+		// The library still works without an index, scanning every file each time
+		if path, err := library.DefaultIndexPath(); err != nil {
+			fmt.Fprintln(os.Stderr, "Warning: cannot find the library index location:", err)
+		} else if idx, err := library.OpenIndex(path); err != nil {
+			fmt.Fprintln(os.Stderr, "Warning: cannot open the library index:", err)
+		} else {
+			defer idx.Close()
+			tui.SetIndex(idx)
+		}
+	}
+
+	fromStdin := false
+	for _, path := range paths {
+		if path == "-" {
+			if fromStdin {
+				continue
+			}
+			fromStdin = true
+			if err := enqueueLines(tui.Queue(), os.Stdin); err != nil {
+				return fmt.Errorf("cannot read the paths from stdin: %w", err)
+			}
+			continue
+		}
+
+		// Handle error in case the file does not exist, the URLs are requested once played
+		if _, err := os.Stat(path); err != nil && !engine.IsURL(path) {
+			return fmt.Errorf("the file %s does not exist", path)
+		}
+
+		// Handle error in case the file is not a valid audio file (mp3, wav, flac or ogg) nor a directory or playlist
+		if _, err := tui.Queue().AddPath(path); err != nil {
+			return err
+		}
+	}
+
+	if opts.smart != "" {
+		if err := tui.EnqueueSmart(opts.smart); err != nil {
+			return err
+		}
+	}
+
+	if (len(paths) > 0 || opts.smart != "") && tui.Queue().Len() == 0 {
+		return fmt.Errorf("there is no valid audio file to play")
+	}
+
+	tui.Queue().SetRepeat(opts.loop)
+	if opts.shuffle {
+		tui.Queue().Shuffle()
+	}
+
+	if opts.noUI {
+		if tui.Queue().Len() == 0 {
+			return fmt.Errorf("-no-ui needs paths or -smart to play")
+		}
+		if opts.paused {
+			return fmt.Errorf("-paused cannot be resumed with -no-ui")
+		}
+		defer tui.Close()
+		err := playHeadless(tui.Player(), tui.Queue(), start, opts.record)
+		saveState(st, tui, opts.shuffle)
+		return err
+	}
+	tui.Player().SetStartPaused(opts.paused)
+	tui.Player().SetRecordOnStart(opts.record)
+	tui.SetStartAt(start)
+	if tui.Queue().Len() == 0 && st.Session != nil && !serving {
+		tui.OfferSession(*st.Session)
+	}
+
+	defer tui.Close()
+
+	// The keys are read from the terminal when stdin was the list of paths
+	var options []tea.ProgramOption
+	if serving {
+		options = append(options, tea.WithoutRenderer(), tea.WithInput(nil))
+	} else if fromStdin {
+		options = append(options, tea.WithInputTTY())
+	}
+	if !serving && cfg.Player.PauseOnBlur {
+		options = append(options, tea.WithReportFocus())
+	}
+	// Bubble Tea restores the terminal after a panic, and the guard keeps it to report it
+	// after saving the session
+	guard := crash.NewGuard(tui)
+	program := tea.NewProgram(guard, options...)
+	startRemotes(cfg, tui, program)
+	if serving {
+		if opts.httpAddr != "" {
+			if err := serveHTTP(opts.httpAddr, opts.token, true, tui, program); err != nil {
+				return err
+			}
+		}
+		fmt.Println("Playing in background, Ctrl+C or tempo ctl quit stops it")
+	} else if cfg.Remote.HTTP != "" {
+		if err := serveHTTP(cfg.Remote.HTTP, cfg.Remote.HTTPToken, false, tui, program); err != nil {
+			slog.Warn("Cannot serve the HTTP API", "err", err)
+		}
+	}
+	_, err = program.Run()
+	crashed := errors.Is(err, tea.ErrProgramPanic)
+	// Serving ends with Ctrl+C, like the other servers
+	if serving && errors.Is(err, tea.ErrInterrupted) {
+		err = nil
+	}
+	if err != nil && !crashed {
+		return err
+	}
+	var report string
+	if crashed {
+		value, stack, _ := guard.Panic()
+		slog.Error("Crashed", "panic", value)
+		if report, err = crash.WriteReport(value, stack); err != nil {
+			fmt.Fprintln(os.Stderr, "Warning: cannot write the crash report:", err)
+		}
+	}
+
+	saveState(st, tui, opts.shuffle)
+	if crashed {
+		if report == "" {
+			return errors.New("tempo crashed, the session was saved")
+		}
+		return fmt.Errorf("tempo crashed, the session was saved and the report written to %s", report)
+	}
+	return nil
+}
+
+// loadState reads the playback settings the last run ended with, the ones of the config if
+// they cannot be read.
+func loadState(cfg *config.Config) *state.State {
+	defaults := state.State{Volume: cfg.Player.Volume, Speed: 1}
+	path, err := state.Path()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: cannot find the state location:", err)
+		return &defaults
+	}
+	st, err := state.Load(path, defaults)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: cannot read the playback settings:", err)
+	}
+	// An edited file does not keep tempo from starting
+	st.Volume = max(min(st.Volume, 100), 0)
+	if st.Speed < engine.MinSpeed || st.Speed > engine.MaxSpeed {
+		st.Speed = 1
+	}
+	return st
+}
+
+// saveState saves the playback settings and the session the run ended with, to start the next
+// one from them.
+func saveState(st *state.State, tui *ui.UI, shuffle bool) {
+	st.Volume, st.Muted, st.Speed = tui.Player().Volume(), tui.Player().Muted(), tui.Player().Speed()
+	st.Repeat, st.Shuffle = tui.Queue().Repeat(), shuffle
+	// Closing without playing anything keeps the last session to be restored
+	if session, ok := tui.Session(); ok {
+		st.Session = &session
+	}
+	if err := st.Save(); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: cannot save the playback settings:", err)
+	}
+}
+
+// startRemotes starts the remotes enabled in the config, which control the program from
+// outside the terminal. The ones that cannot start are logged, since the terminal still works.
+func startRemotes(cfg *config.Config, tui *ui.UI, program *tea.Program) {
+	if cfg.Remote.MediaKeys {
+		// Without a session bus, or on other platforms, tempo is only controlled from the terminal
+		if server, err := mediakeys.Start(program.Send); err != nil {
+			slog.Warn("Cannot listen for the media keys", "err", err)
+		} else {
+			tui.AddRemote(server)
+		}
+	}
+	if cfg.Remote.Socket {
+		if path, err := control.SocketPath(); err != nil {
+			slog.Warn("Cannot find the control socket location", "err", err)
+		} else if server, err := control.Listen(path, program.Send); err != nil {
+			slog.Warn("Cannot listen at the control socket", "err", err)
+		} else {
+			tui.AddRemote(server)
+		}
+	}
+	if cfg.Player.PauseOnSleep {
+		tui.AddRemote(suspend.Watch(program.Send))
+	}
+	if r := cfg.Remote; r.SIGUSR1 != "" || r.SIGUSR2 != "" {
+		// Windows has no user signals, so there is nothing to warn about
+		if listener, err := signals.Listen(r.SIGUSR1, r.SIGUSR2, program.Send); err == nil {
+			tui.AddRemote(listener)
+		} else if !errors.Is(err, errors.ErrUnsupported) {
+			slog.Warn("Cannot listen for the signals", "err", err)
+		}
+	}
+	if cfg.Discord.Enabled {
+		presence, err := discord.New(discord.Options{
+			ClientID:      cfg.Discord.ClientID,
+			ShowAlbum:     cfg.Discord.ShowAlbum,
+			ShowElapsed:   cfg.Discord.ShowElapsed,
+			HideFileNames: cfg.Discord.HideFileNames,
+			Exclude:       libraryDirs(cfg.Discord.Exclude),
+		})
+		if err != nil {
+			slog.Warn("Cannot show the Discord presence", "err", err)
+		} else {
+			tui.AddRemote(presence)
+		}
+	}
+	if h := cfg.Hooks; h.OnTrackChange != "" || h.OnPlay != "" || h.OnPause != "" || h.OnStop != "" {
+		tui.AddRemote(hooks.New(map[hooks.Event]string{
+			hooks.TrackChange: h.OnTrackChange,
+			hooks.Play:        h.OnPlay,
+			hooks.Pause:       h.OnPause,
+			hooks.Stop:        h.OnStop,
+		}))
+	}
+	if cfg.NowPlaying.File != "" {
+		if file, err := statusfile.New(xdg.ExpandHome(cfg.NowPlaying.File), cfg.NowPlaying.Template); err != nil {
+			slog.Warn("Cannot keep the now playing file", "err", err)
+		} else {
+			tui.AddRemote(file)
+		}
+	}
+	if cfg.Remote.MPD != "" {
+		if server, err := mpd.Listen(cfg.Remote.MPD, program.Send); err != nil {
+			slog.Warn("Cannot listen for the MPD clients", "err", err)
+		} else {
+			tui.AddRemote(server)
+		}
+	}
+}
+
+// serveHTTP serves the HTTP API at address. tempo serve makes up a token if none is given and
+// prints it, while the TUI, which would hide it, needs one in the config.
+func serveHTTP(address, token string, serving bool, tui *ui.UI, program *tea.Program) error {
+	given := token != ""
+	if !given && !serving {
+		return errors.New("the HTTP API needs http_token in the config")
+	}
+	if !given {
+		token = httpapi.NewToken()
+	}
+
+	server := httpapi.New(token, program.Send, tui.Library())
+	if err := server.Listen(address); err != nil {
+		return err
+	}
+	tui.AddRemote(server)
+	switch {
+	case serving && given:
+		fmt.Println("Serving the HTTP API at", address)
+		fmt.Println("The web remote is at", remoteURL(address))
+	case serving:
+		fmt.Printf("Serving the HTTP API at %s with the token %s\n", address, token)
+		fmt.Printf("The web remote is at %s#token=%s\n", remoteURL(address), token)
+	}
+	return nil
+}
+
+// remoteURL returns the URL of the web remote served at address, on this host if it has none.
+func remoteURL(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "http://" + address + "/"
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port) + "/"
+}
+
+// enqueueRunning enqueues the paths into the tempo running already through its control socket,
+// reading the ones of a - path from stdin. It returns false if no tempo is running, leaving
+// stdin unread, and fails without paths since the running one is playing already.
+func enqueueRunning(paths []string) (bool, error) {
+	socket, err := control.SocketPath()
+	if err != nil {
+		return false, nil
+	}
+	if _, err := control.Send(socket, control.Request{Command: "status"}); errors.Is(err, control.ErrNotRunning) {
+		return false, nil
+	} else if err != nil {
+		return true, err
+	}
+	if len(paths) == 0 {
+		return true, errors.New("tempo is running already, tempo ctl controls it and -new-instance starts another")
+	}
+
+	var args []string
+	fromStdin := false
+	for _, path := range paths {
+		if path == "-" {
+			if !fromStdin {
+				fromStdin = true
+				lines, err := existingLines(os.Stdin)
+				if err != nil {
+					return true, fmt.Errorf("cannot read the paths from stdin: %w", err)
+				}
+				args = append(args, lines...)
+			}
+			continue
+		}
+		if _, err := os.Stat(path); err != nil && !engine.IsURL(path) {
+			return true, fmt.Errorf("the file %s does not exist", path)
+		}
+		args = append(args, path)
+	}
+	if len(args) == 0 {
+		return true, fmt.Errorf("there is no valid audio file to play")
+	}
+
+	// The running tempo resolves the paths from another directory
+	for i, path := range args {
+		if engine.IsURL(path) {
+			continue
+		}
+		if args[i], err = filepath.Abs(path); err != nil {
+			return true, err
+		}
+	}
+	if _, err := control.Send(socket, control.Request{Command: "add", Args: args}); err != nil {
+		return true, err
+	}
+	if len(args) == 1 {
+		fmt.Println("Enqueued", args[0], "into the running tempo")
+	} else {
+		fmt.Printf("Enqueued %d paths into the running tempo\n", len(args))
+	}
+	return true, nil
+}
+
+// existingLines returns the paths read from r, one per line like the output of find, skipping
+// the ones that do not exist with a warning naming their line.
+func existingLines(r io.Reader) ([]string, error) {
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		path := strings.TrimSuffix(scanner.Text(), "\r")
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil && !engine.IsURL(path) {
+			fmt.Fprintf(os.Stderr, "Warning: line %d: the file %s does not exist\n", n, path)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths, scanner.Err()
+}
 
-	program := tea.NewProgram(tui)
-	if _, err := program.Run(); err != nil {
-		log.Fatal(err)
+// enqueueLines enqueues the paths read from r, one per line like the output of find. The
+// ones that cannot be enqueued are skipped with a warning.
+func enqueueLines(q *queue.Queue, r io.Reader) error {
+	paths, err := existingLines(r)
+	for _, path := range paths {
+		if _, err := q.AddPath(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", path, err)
+		}
 	}
+	return err
 }
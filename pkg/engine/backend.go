@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// Backend : An audio output the engine plays on. Implementing it plugs in outputs other
+// than the default one, like PortAudio, PulseAudio, PipeWire or JACK.
+type Backend interface {
+	// Init opens the output at the given sample rate, the one of the first audio played or
+	// higher, pulling bufferSize samples at a time. A DeviceBackend is initialized again when its device changes.
+	Init(rate beep.SampleRate, bufferSize int) error
+	// Play adds the streamer to the ones being played, until it is drained
+	Play(s beep.Streamer)
+	// Clear removes every streamer being played
+	Clear()
+	// Lock stops pulling samples until Unlock, so the streamers can be changed safely
+	Lock()
+	Unlock()
+	// Close closes the output
+	Close()
+}
+
+// Speaker : The default backend, playing through the speaker package of beep (oto under
+// the hood). On Linux it can choose the ALSA sound card, only before the output is opened.
+type Speaker struct{}
+
+var _ DeviceBackend = Speaker{}
+
+// The speaker of beep can be opened only once per process
+var (
+	speakerMu   sync.Mutex
+	speakerOpen bool
+)
+
+func (Speaker) Init(rate beep.SampleRate, bufferSize int) error {
+	speakerMu.Lock()
+	defer speakerMu.Unlock()
+	if err := speaker.Init(rate, bufferSize); err != nil {
+		return err
+	}
+	speakerOpen = true
+	return nil
+}
+
+// Devices lists the default device followed by the sound cards.
+func (Speaker) Devices() ([]Device, error) {
+	devices := []Device{{Description: "Default"}}
+	cards, err := speakerDevices()
+	if err != nil {
+		// Without the list of cards the default device is still there
+		slog.Warn("Cannot list the sound cards", "err", err)
+		return devices, nil
+	}
+	return append(devices, cards...), nil
+}
+
+func (Speaker) SetDevice(name string) error {
+	speakerMu.Lock()
+	defer speakerMu.Unlock()
+	if speakerOpen {
+		return ErrDeviceOpen
+	}
+
+	// Unknown cards would make ALSA fall back to any other device
+	if cards, err := speakerDevices(); name != "" && err == nil {
+		found := false
+		for _, d := range cards {
+			found = found || d.Name == name
+		}
+		if !found {
+			return fmt.Errorf("unknown output device %q", name)
+		}
+	}
+	return setSpeakerDevice(name)
+}
+
+func (Speaker) Play(s beep.Streamer) {
+	speaker.Play(s)
+}
+
+func (Speaker) Clear() {
+	speaker.Clear()
+}
+
+func (Speaker) Lock() {
+	speaker.Lock()
+}
+
+func (Speaker) Unlock() {
+	speaker.Unlock()
+}
+
+func (Speaker) Close() {
+	speaker.Close()
+}
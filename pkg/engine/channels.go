@@ -0,0 +1,55 @@
+package engine
+
+import "github.com/gopxl/beep/v2"
+
+// channelMatrix : A streamer mixing the channels into each other, for the balance, the
+// swapped channels and the mono downmix
+type channelMatrix struct {
+	Streamer beep.Streamer
+	// Share of the left and right input in the left output, then in the right one
+	ll, lr, rl, rr float64
+}
+
+// newChannelMatrix creates a channel matrix passing the channels through untouched.
+func newChannelMatrix(s beep.Streamer) *channelMatrix {
+	return &channelMatrix{Streamer: s, ll: 1, rr: 1}
+}
+
+// set mixes the channels for the given balance, from -1 (only the left one) to 1 (only the
+// right one), swapping them and folding them into one if asked. The caller must hold the
+// backend lock.
+func (m *channelMatrix) set(balance float64, swapped, mono bool) {
+	m.ll, m.lr, m.rl, m.rr = 1, 0, 0, 1
+	if mono {
+		m.ll, m.lr, m.rl, m.rr = 0.5, 0.5, 0.5, 0.5
+	} else if swapped {
+		m.ll, m.lr, m.rl, m.rr = 0, 1, 1, 0
+	}
+
+	// The balance lowers the channel opposite to the side it leans to
+	left, right := 1.0, 1.0
+	if balance > 0 {
+		left = 1 - balance
+	} else {
+		right = 1 + balance
+	}
+	m.ll, m.lr = m.ll*left, m.lr*left
+	m.rl, m.rr = m.rl*right, m.rr*right
+}
+
+func (m *channelMatrix) Stream(samples [][2]float64) (int, bool) {
+	n, ok := m.Streamer.Stream(samples)
+	if m.ll == 1 && m.lr == 0 && m.rl == 0 && m.rr == 1 {
+		return n, ok
+	}
+	for i := range samples[:n] {
+		l, r := samples[i][0], samples[i][1]
+		samples[i][0] = m.ll*l + m.lr*r
+		samples[i][1] = m.rl*l + m.rr*r
+	}
+	return n, ok
+}
+
+func (m *channelMatrix) Err() error {
+	return m.Streamer.Err()
+}
@@ -0,0 +1,25 @@
+package engine
+
+import "github.com/gopxl/beep/v2"
+
+// clipMeter : A streamer noting whether the audio went past full scale, which the output
+// cuts, distorting it
+type clipMeter struct {
+	Streamer beep.Streamer
+	clipped  bool
+}
+
+func (m *clipMeter) Stream(samples [][2]float64) (int, bool) {
+	n, ok := m.Streamer.Stream(samples)
+	for _, s := range samples[:n] {
+		if s[0] > 1 || s[0] < -1 || s[1] > 1 || s[1] < -1 {
+			m.clipped = true
+			break
+		}
+	}
+	return n, ok
+}
+
+func (m *clipMeter) Err() error {
+	return m.Streamer.Err()
+}
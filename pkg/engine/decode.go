@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/flac"
+	"github.com/gopxl/beep/v2/mp3"
+	"github.com/gopxl/beep/v2/vorbis"
+	"github.com/gopxl/beep/v2/wav"
+)
+
+// ErrUnsupported is returned for files with an extension that cannot be decoded.
+var ErrUnsupported = errors.New("invalid file extension")
+
+// Decode opens the file at path and decodes it based on its extension: MP3, WAV, FLAC and
+// Ogg Vorbis are supported. A path may be an HTTP(S) URL, which is streamed as it is played,
+// and decoded based on its media type if it has no extension. The stream of an internet radio
+// has no length and cannot be seeked. The streamer must be closed when done.
+func Decode(path string) (beep.StreamSeekCloser, beep.Format, error) {
+	file, err := Open(path)
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
+
+	var streamer beep.StreamSeekCloser
+	var format beep.Format
+
+	ext := Ext(path)
+	remote, _ := file.(*remoteFile)
+	if remote != nil && !slices.Contains([]string{".mp3", ".wav", ".flac", ".ogg", ".oga"}, ext) {
+		ext = remote.ext()
+	}
+	switch ext {
+	case ".mp3":
+		if remote != nil {
+			streamer, format, err = decodeStreamedMP3(remote)
+		} else {
+			streamer, format, err = mp3.Decode(file)
+		}
+	case ".wav":
+		streamer, format, err = wav.Decode(file)
+	case ".flac":
+		streamer, format, err = flac.Decode(file)
+	case ".ogg", ".oga":
+		streamer, format, err = vorbis.Decode(file)
+	default:
+		err = ErrUnsupported
+	}
+
+	if err != nil {
+		file.Close()
+		return nil, beep.Format{}, err
+	}
+	// An internet radio broadcasts for as long as it is listened to
+	if remote != nil && remote.size < 0 && (streamer.Len() <= 0 || remote.radio()) {
+		streamer = &liveStream{StreamSeekCloser: streamer, file: remote}
+	}
+	return streamer, format, nil
+}
+
+// Probe decodes the file at path just to know its format and duration.
+func Probe(path string) (beep.Format, time.Duration, error) {
+	streamer, format, err := Decode(path)
+	if err != nil {
+		return beep.Format{}, 0, err
+	}
+	defer streamer.Close()
+
+	return format, format.SampleRate.D(streamer.Len()).Round(time.Second), nil
+}
+
+// ProbeDuration decodes the file at path just to know its duration.
+func ProbeDuration(path string) (time.Duration, error) {
+	_, d, err := Probe(path)
+	return d, err
+}
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoDevices is returned when the backend always plays on the default device.
+	ErrNoDevices = errors.New("the audio backend cannot choose the output device")
+	// ErrDeviceOpen is returned when the backend cannot change the device it already opened.
+	ErrDeviceOpen = errors.New("the output device is already open")
+)
+
+// Device : An audio output device, like a sound card or a USB DAC
+type Device struct {
+	// Name chooses the device, empty for the default one of the system
+	Name        string
+	Description string
+}
+
+func (d Device) String() string {
+	switch {
+	case d.Name == "":
+		return d.Description
+	case d.Description == "":
+		return d.Name
+	}
+	return fmt.Sprintf("%s (%s)", d.Description, d.Name)
+}
+
+// DeviceBackend : A Backend able to play on other devices than the default one
+type DeviceBackend interface {
+	Backend
+	// Devices lists the output devices available, the default one first
+	Devices() ([]Device, error)
+	// SetDevice chooses the device opened by the next Init, the default one if name is empty
+	SetDevice(name string) error
+}
+
+// Devices lists the output devices the engine can play on, the default one first.
+func (e *Engine) Devices() ([]Device, error) {
+	db, ok := e.backend.(DeviceBackend)
+	if !ok {
+		return []Device{{Description: "Default"}}, nil
+	}
+	return db.Devices()
+}
+
+// Device returns the name of the output device chosen, empty for the default one.
+func (e *Engine) Device() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.device
+}
+
+// SetDevice chooses the output device by its name, the default one if empty. Once the
+// output is open, the audio being played moves to the new device.
+func (e *Engine) SetDevice(name string) error {
+	db, ok := e.backend.(DeviceBackend)
+	if !ok {
+		if name == "" {
+			return nil
+		}
+		return ErrNoDevices
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := db.SetDevice(name); err != nil {
+		return err
+	}
+	e.device = name
+	if e.rate == 0 {
+		return nil
+	}
+
+	// Reopening the output drops the streamers, so the audio is given again
+	e.backend.Clear()
+	if err := e.backend.Init(e.rate, e.rate.N(bufferLength)); err != nil {
+		return err
+	}
+	if e.started && !e.completed {
+		e.start()
+	}
+	return nil
+}
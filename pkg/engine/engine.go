@@ -0,0 +1,888 @@
+// Package engine plays audio files on the default output device, or on any other Backend.
+// It holds the decoding, volume, pause and seek logic of tempo without any user interface,
+// so other programs can embed it. The errors it cannot return, like the ones closing the
+// previous audio, are logged with the default slog logger.
+//
+//	e := engine.New()
+//	defer e.Close()
+//	if err := e.Load("song.mp3"); err != nil {
+//		return err
+//	}
+//	e.Play()
+//	for ev := range e.Events() {
+//		if ev.Kind == engine.Completed {
+//			break
+//		}
+//	}
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/effects"
+)
+
+const (
+	// Base of the volume level: each step multiplies the amplitude by it
+	VolumeBase float64 = 1.5
+	// Quality of the conversion of the audio files with another sample rate than the output
+	resampleQuality int = 4
+	// Lowest sample rate the output is opened at, so the music played after a podcast or a
+	// voice recording at a low rate is not converted down to it
+	minOutputRate beep.SampleRate = 44100
+	// Events kept for a slow reader before the new ones are dropped
+	eventBuffer int = 16
+	// Range of the pitch shift in semitones, an octave up or down
+	MinPitch int = -12
+	MaxPitch int = 12
+	// Audio buffered by the output, longer is more reliable but slower to react
+	bufferLength time.Duration = time.Second / 10
+)
+
+// ErrNotLoaded is returned when there is no audio file to play.
+var ErrNotLoaded = errors.New("no audio loaded")
+
+// EventKind : What happened to the playback
+type EventKind int
+
+const (
+	// Playing is sent when the playback starts or resumes
+	Playing EventKind = iota
+	// Paused is sent when the playback is paused
+	Paused
+	// Seeked is sent when the playback moves to another position
+	Seeked
+	// Completed is sent when the audio reaches its end
+	Completed
+	// Metadata is sent when the live stream starts playing what its radio told the title of
+	Metadata
+)
+
+var eventKindNames = []string{"playing", "paused", "seeked", "completed", "metadata"}
+
+func (k EventKind) String() string {
+	if k < 0 || int(k) >= len(eventKindNames) {
+		return "unknown"
+	}
+	return eventKindNames[k]
+}
+
+// Event : A change of the playback
+type Event struct {
+	Kind EventKind
+	// Path of the audio file
+	Path string
+	// Position of the playback when it happened
+	Position time.Duration
+	// Err of the decoder if the audio completed early because of it
+	Err error
+	// Title of what the live stream plays, for Metadata
+	Title string
+}
+
+// Engine : An audio player without user interface, safe for concurrent use
+type Engine struct {
+	mu sync.Mutex
+
+	// Output the audio is played on, and the name of its device
+	backend Backend
+	device  string
+
+	// Audio file loaded and its decoded stream, decoded readAhead ahead of the playback
+	path      string
+	stream    beep.StreamSeekCloser
+	format    beep.Format
+	readAhead time.Duration
+
+	// Stream of the internet radio loaded, nil for the other audio, and the title of what it
+	// plays
+	live  *liveStream
+	title string
+
+	// Chain of the played audio: loop, then silence, then speed, then pitch and sample rate,
+	// then the recorder, then preamp and equalizer, then pause, then fade, then gain, then
+	// volume, then channels, then the clipping meter
+	loop     *looper
+	silence  *silenceSkipper
+	stretch  *stretcher
+	resample *beep.Resampler
+	record   *recorder
+	eq       *equalizer
+	ctrl     *beep.Ctrl
+	fader    *fader
+	gain     *effects.Volume
+	volume   *effects.Volume
+	channels *channelMatrix
+	meter    *clipMeter
+
+	// Settings kept across audio files
+	level   float64
+	muted   bool
+	gainDB  float64
+	fade    time.Duration
+	speed   float64
+	pitch   int
+	bands   Gains
+	preamp  float64
+	balance float64
+	swapped bool
+	mono    bool
+	skip    Silence
+
+	// Recording the audio is written to, kept across audio files, nil if not recording
+	rec *recording
+
+	// started if the loaded audio was given to the backend
+	started bool
+	// completed if the loaded audio reached its end
+	completed bool
+
+	// Sample rate of the output, zero until the backend is initialized with the first audio,
+	// at its rate or minOutputRate if higher
+	rate beep.SampleRate
+
+	// Incremented with every audio loaded, so the callbacks of the previous ones are ignored
+	generation int
+
+	events chan Event
+}
+
+// New creates an engine with nothing loaded, playing on the default device of the system.
+// The output device is opened by the first Load.
+func New() *Engine {
+	return NewWithBackend(Speaker{})
+}
+
+// NewWithBackend creates an engine with nothing loaded, playing on the given backend.
+func NewWithBackend(b Backend) *Engine {
+	e := new(Engine)
+	e.backend = b
+	e.speed = 1
+	e.skip = DefaultSilence()
+	e.readAhead = DefaultReadAhead
+	e.events = make(chan Event, eventBuffer)
+	return e
+}
+
+// Events returns the channel of the playback events. Events are dropped while it is full,
+// so a slow reader never blocks the playback.
+func (e *Engine) Events() <-chan Event {
+	return e.events
+}
+
+// Load stops the current audio, if any, and decodes the audio file at path, ready to Play.
+// The volume, mute and gain settings are kept.
+func (e *Engine) Load(path string) error {
+	streamer, format, err := Decode(path)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.unload(); err != nil {
+		slog.Warn("Cannot close the previous audio", "err", err)
+	}
+
+	// The output cannot be opened again, at least the speaker of beep, so it keeps the rate
+	// of the first audio and the ones with another rate are resampled to it
+	if e.rate == 0 {
+		rate := max(format.SampleRate, minOutputRate)
+		if err := e.backend.Init(rate, rate.N(bufferLength)); err != nil {
+			streamer.Close()
+			return fmt.Errorf("cannot open the audio output: %w", err)
+		}
+		slog.Debug("Opened the audio output", "rate", int(rate), "buffer", rate.N(bufferLength), "device", e.device)
+		e.rate = rate
+	}
+	slog.Debug("Loaded", "path", path, "rate", int(format.SampleRate), "channels", format.NumChannels,
+		"precision", format.Precision, "samples", streamer.Len())
+
+	e.generation++
+	live, _ := streamer.(*liveStream)
+	if e.readAhead > 0 {
+		streamer = newReadAhead(streamer, format.SampleRate.N(e.readAhead))
+	}
+	e.path = path
+	e.stream = streamer
+	e.format = format
+	e.live = live
+	e.loop = &looper{Streamer: streamer}
+	if live != nil {
+		generation := e.generation
+		e.loop.Streamer = &titleWatch{StreamSeeker: streamer, live: live, tell: func(title string) {
+			go e.retitle(generation, title)
+		}}
+	}
+	e.silence = newSilenceSkipper(e.loop, format.SampleRate, e.skip)
+	e.stretch = newStretcher(e.silence, format.SampleRate)
+	e.resample = beep.ResampleRatio(resampleQuality, 1, e.stretch)
+	e.applyRate()
+	e.record = &recorder{Streamer: e.resample, rec: e.rec}
+	e.eq = newEqualizer(e.record, e.rate, e.bands, e.preamp)
+	e.ctrl = &beep.Ctrl{Streamer: e.eq}
+	e.fader = newFader(e.ctrl, e.ctrl)
+	e.gain = &effects.Volume{Streamer: e.fader, Base: 10, Volume: e.gainDB / 20}
+	e.volume = &effects.Volume{Streamer: e.gain, Base: VolumeBase, Volume: e.level, Silent: e.muted}
+	e.channels = newChannelMatrix(e.volume)
+	e.channels.set(e.balance, e.swapped, e.mono)
+	e.meter = &clipMeter{Streamer: e.channels}
+	return nil
+}
+
+// unload stops and closes the current audio. The caller must hold e.mu.
+func (e *Engine) unload() error {
+	if e.stream == nil {
+		return nil
+	}
+	if e.started {
+		e.backend.Clear()
+	}
+	err := e.stream.Close()
+
+	e.path = ""
+	e.stream = nil
+	e.live, e.title = nil, ""
+	e.loop, e.silence, e.stretch, e.resample, e.record, e.eq, e.ctrl = nil, nil, nil, nil, nil, nil, nil
+	e.fader, e.gain, e.volume, e.channels, e.meter = nil, nil, nil, nil, nil
+	e.started = false
+	e.completed = false
+	return err
+}
+
+// Close stops the playback, fading it out, finishes the recording and releases the audio file.
+func (e *Engine) Close() error {
+	e.mu.Lock()
+	var wait time.Duration
+	if e.fade > 0 && e.playing() {
+		wait = e.fade
+		e.backend.Lock()
+		e.fader.fadeOut(e.rate, e.fade)
+		e.backend.Unlock()
+	}
+	e.mu.Unlock()
+
+	// The backend keeps streaming while waiting for the fade to end
+	time.Sleep(wait)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.rec != nil {
+		if _, err := e.stopRecording(); err != nil {
+			slog.Warn("Cannot finish the recording", "err", err)
+		}
+	}
+	return e.unload()
+}
+
+// Play starts or resumes the playback, fading it in when resumed. A completed audio plays
+// again from the start.
+func (e *Engine) Play() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stream == nil {
+		return ErrNotLoaded
+	}
+
+	if e.completed {
+		if err := e.stream.Seek(0); err != nil {
+			return err
+		}
+		e.silence.reset()
+		e.stretch.reset()
+		e.completed = false
+		e.started = false
+	}
+
+	if !e.started {
+		e.ctrl.Paused = false
+		e.start()
+	} else {
+		e.backend.Lock()
+		e.fader.fadeIn(e.rate, e.fade)
+		e.backend.Unlock()
+	}
+
+	e.emit(Event{Kind: Playing})
+	return nil
+}
+
+// start gives the loaded audio to the backend. The caller must hold e.mu.
+func (e *Engine) start() {
+	e.started = true
+
+	// The callback runs on the backend goroutine while it holds the backend lock,
+	// so the state is updated from a separate goroutine
+	generation := e.generation
+	e.backend.Play(beep.Seq(e.meter, beep.Callback(func() {
+		go e.complete(generation)
+	})))
+}
+
+// Pause pauses the playback, keeping its position. The audio is faded out first, so the
+// position moves ahead for as long as the fade lasts.
+func (e *Engine) Pause() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stream == nil || !e.started || e.completed {
+		return
+	}
+
+	e.backend.Lock()
+	if e.fade > 0 {
+		e.fader.fadeOut(e.rate, e.fade)
+	} else {
+		e.ctrl.Paused = true
+	}
+	e.backend.Unlock()
+	e.emit(Event{Kind: Paused})
+}
+
+// complete marks the audio of the given generation as completed.
+func (e *Engine) complete(generation int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if generation != e.generation || e.stream == nil {
+		return
+	}
+	e.completed = true
+	e.emit(Event{Kind: Completed, Err: e.stream.Err()})
+}
+
+// retitle sets the title of what the live stream of the given generation plays.
+func (e *Engine) retitle(generation int, title string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if generation != e.generation || e.stream == nil {
+		return
+	}
+	e.title = title
+	e.emit(Event{Kind: Metadata, Title: title})
+}
+
+// Seek moves the playback to pos, clamped to the length of the audio. Seeking a completed
+// audio leaves it paused at pos, and a live stream fails with ErrLive.
+func (e *Engine) Seek(pos time.Duration) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stream == nil {
+		return ErrNotLoaded
+	}
+	return e.seek(e.format.SampleRate.N(pos))
+}
+
+// SeekFraction moves the playback to the given fraction of the length of the audio, from 0
+// (the start) to 1 (the end).
+func (e *Engine) SeekFraction(fraction float64) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stream == nil {
+		return ErrNotLoaded
+	}
+	return e.seek(int(fraction * float64(e.stream.Len())))
+}
+
+// seek moves the stream to sample n, clamped to its length. The caller must hold e.mu.
+func (e *Engine) seek(n int) error {
+	// The samples decoded ahead would be dropped
+	if e.live != nil {
+		return ErrLive
+	}
+	n = max(min(n, e.stream.Len()-1), 0)
+	// Seeking the audio decoded ahead can wait for a slow disk or server, so the backend is
+	// only held to seek the audio it streams from directly
+	var err error
+	if r, ok := e.stream.(*readAhead); ok {
+		err = r.Seek(n)
+		e.backend.Lock()
+	} else {
+		e.backend.Lock()
+		err = e.stream.Seek(n)
+	}
+	e.silence.reset()
+	e.stretch.reset()
+	e.backend.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// The backend dropped the completed audio, so it has to be given again
+	if e.completed {
+		e.completed = false
+		e.started = false
+	}
+	e.emit(Event{Kind: Seeked})
+	return nil
+}
+
+// Path returns the path of the loaded audio file, empty if there is none.
+func (e *Engine) Path() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.path
+}
+
+// Rename changes the path of the loaded audio file after the file is moved, so the events
+// of the playback carry the new one. The open file keeps playing.
+func (e *Engine) Rename(from, to string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.path == from {
+		e.path = to
+	}
+}
+
+// Position returns the playback position of the loaded audio.
+func (e *Engine) Position() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.position()
+}
+
+// position reads the position of the stream while the backend is not streaming it. The
+// caller must hold e.mu.
+func (e *Engine) position() time.Duration {
+	if e.stream == nil {
+		return 0
+	}
+	e.backend.Lock()
+	defer e.backend.Unlock()
+	return e.format.SampleRate.D(e.stream.Position())
+}
+
+// Duration returns the length of the loaded audio.
+func (e *Engine) Duration() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stream == nil {
+		return 0
+	}
+	return e.format.SampleRate.D(e.stream.Len())
+}
+
+// Live reports whether the loaded audio is the endless stream of an internet radio, which has
+// no length and cannot be seeked.
+func (e *Engine) Live() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.live != nil
+}
+
+// Station returns what the internet radio loaded tells about itself, empty for other audio.
+func (e *Engine) Station() Station {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.live == nil {
+		return Station{}
+	}
+	return e.live.file.station
+}
+
+// Title returns the title of what the live stream plays, empty until its radio tells it.
+func (e *Engine) Title() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.title
+}
+
+// Playing reports whether the audio is being played, neither paused nor completed.
+func (e *Engine) Playing() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.playing()
+}
+
+// playing reports whether the audio is being played, counting the audio fading out to
+// pause as paused. The caller must hold e.mu.
+func (e *Engine) playing() bool {
+	if e.stream == nil || !e.started || e.completed {
+		return false
+	}
+	e.backend.Lock()
+	defer e.backend.Unlock()
+	return !e.ctrl.Paused && !e.fader.pausing
+}
+
+// Completed reports whether the loaded audio reached its end.
+func (e *Engine) Completed() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.completed
+}
+
+// Volume returns the volume level, the exponent of VolumeBase applied to the amplitude.
+func (e *Engine) Volume() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.level
+}
+
+// SetVolume sets the volume level: 0 plays the audio as it is, negative levels lower it
+// and positive ones raise it.
+func (e *Engine) SetVolume(level float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.level = level
+	if e.volume != nil {
+		e.backend.Lock()
+		e.volume.Volume = level
+		e.backend.Unlock()
+	}
+}
+
+// Muted reports whether the audio is silenced.
+func (e *Engine) Muted() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.muted
+}
+
+// SetMuted silences the audio or brings it back, keeping the volume level.
+func (e *Engine) SetMuted(muted bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.muted = muted
+	if e.volume != nil {
+		e.backend.Lock()
+		e.volume.Silent = muted
+		e.backend.Unlock()
+	}
+}
+
+// SetGain sets an adjustment in dB applied before the volume, like the ReplayGain of the
+// loaded audio. It is kept for the next audio files until changed.
+func (e *Engine) SetGain(db float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.gainDB = db
+	if e.gain != nil {
+		e.backend.Lock()
+		e.gain.Volume = db / 20
+		e.backend.Unlock()
+	}
+}
+
+// ReadAhead returns how much audio is decoded ahead of the playback.
+func (e *Engine) ReadAhead() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.readAhead
+}
+
+// SetReadAhead sets how much audio is decoded ahead of the playback on a separate
+// goroutine, from the next audio loaded. Zero decodes it while playing it.
+func (e *Engine) SetReadAhead(length time.Duration) error {
+	if length < 0 || length > MaxReadAhead {
+		return fmt.Errorf("read-ahead %s is not between 0 and %s", length, MaxReadAhead)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.readAhead = length
+	return nil
+}
+
+// SetFade sets how long the audio fades out when paused or closed, and in when resumed.
+// Zero pauses and resumes it at once.
+func (e *Engine) SetFade(length time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fade = max(length, 0)
+}
+
+// Speed returns the playback speed, 1 being the normal one.
+func (e *Engine) Speed() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.speed
+}
+
+// SetSpeed plays the audio faster or slower, from MinSpeed to MaxSpeed, keeping its pitch.
+// It is kept for the next audio files until changed.
+func (e *Engine) SetSpeed(speed float64) error {
+	if speed < MinSpeed || speed > MaxSpeed {
+		return fmt.Errorf("speed %g is not between %g and %g", speed, MinSpeed, MaxSpeed)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.speed = speed
+	if e.stream != nil {
+		e.backend.Lock()
+		e.applyRate()
+		e.backend.Unlock()
+	}
+	return nil
+}
+
+// Pitch returns how many semitones the audio is shifted up, or down if negative.
+func (e *Engine) Pitch() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.pitch
+}
+
+// SetPitch shifts the audio up or down by semitones, from MinPitch to MaxPitch, keeping
+// its speed. It is kept for the next audio files until changed.
+func (e *Engine) SetPitch(semitones int) error {
+	if semitones < MinPitch || semitones > MaxPitch {
+		return fmt.Errorf("pitch %d is not between %d and %d semitones", semitones, MinPitch, MaxPitch)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pitch = semitones
+	if e.stream != nil {
+		e.backend.Lock()
+		e.applyRate()
+		e.backend.Unlock()
+	}
+	return nil
+}
+
+// Equalizer returns the gain in dB of each band of the equalizer.
+func (e *Engine) Equalizer() Gains {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.bands
+}
+
+// SetEqualizer sets the gain in dB of each band of EqualizerBands, up to MaxBandGain up or
+// down. It is kept for the next audio files until changed.
+func (e *Engine) SetEqualizer(gains Gains) error {
+	for i, g := range gains {
+		if math.Abs(g) > MaxBandGain {
+			return fmt.Errorf("gain %g dB of the %g Hz band is not between %g and %g", g, EqualizerBands[i], -MaxBandGain, MaxBandGain)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.bands = gains
+	if e.eq != nil {
+		e.backend.Lock()
+		e.eq.set(gains)
+		e.backend.Unlock()
+	}
+	return nil
+}
+
+// Preamp returns the gain in dB applied before the bands of the equalizer.
+func (e *Engine) Preamp() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.preamp
+}
+
+// SetPreamp sets a gain in dB applied before the bands of the equalizer, up to MaxPreamp up
+// or down, usually lowered to keep the boosted bands from clipping. It is kept for the next
+// audio files until changed.
+func (e *Engine) SetPreamp(db float64) error {
+	if math.Abs(db) > MaxPreamp {
+		return fmt.Errorf("preamp %g dB is not between %g and %g", db, -MaxPreamp, MaxPreamp)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.preamp = db
+	if e.eq != nil {
+		e.backend.Lock()
+		e.eq.setPreamp(db)
+		e.backend.Unlock()
+	}
+	return nil
+}
+
+// Balance returns how much the audio leans to the right channel, or to the left one if
+// negative.
+func (e *Engine) Balance() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.balance
+}
+
+// SetBalance lowers one channel to lean the audio to the other one, from -1 (only the left
+// one) to 1 (only the right one), 0 leaving both as they are. It is kept for the next audio
+// files until changed.
+func (e *Engine) SetBalance(balance float64) error {
+	if balance < -1 || balance > 1 {
+		return fmt.Errorf("balance %g is not between -1 and 1", balance)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.balance = balance
+	e.applyChannels()
+	return nil
+}
+
+// Swapped reports whether the left and right channels are swapped.
+func (e *Engine) Swapped() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.swapped
+}
+
+// SetSwapped plays the left channel on the right and the other way around, or puts them
+// back. It is kept for the next audio files until changed.
+func (e *Engine) SetSwapped(swapped bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.swapped = swapped
+	e.applyChannels()
+}
+
+// Mono reports whether both channels play their mix.
+func (e *Engine) Mono() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.mono
+}
+
+// SetMono plays the mix of both channels on each of them, so nothing is missed with a
+// single earphone, or plays them apart again. It is kept for the next audio files until
+// changed.
+func (e *Engine) SetMono(mono bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.mono = mono
+	e.applyChannels()
+}
+
+// applyChannels mixes the channels of the loaded audio. The caller must hold e.mu.
+func (e *Engine) applyChannels() {
+	if e.channels == nil {
+		return
+	}
+	e.backend.Lock()
+	e.channels.set(e.balance, e.swapped, e.mono)
+	e.backend.Unlock()
+}
+
+// Loop returns the region of the loaded audio played again and again, reporting false if
+// there is none.
+func (e *Engine) Loop() (start, end time.Duration, ok bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.loop == nil || e.loop.end <= e.loop.start {
+		return 0, 0, false
+	}
+	rate := e.format.SampleRate
+	return rate.D(e.loop.start), rate.D(e.loop.end), true
+}
+
+// SetLoop plays the region of the loaded audio from start to end again and again, until
+// cleared or another audio is loaded. The playback jumps to start if already past end.
+func (e *Engine) SetLoop(start, end time.Duration) error {
+	if end <= start {
+		return fmt.Errorf("loop end %s is not after its start %s", end, start)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stream == nil {
+		return ErrNotLoaded
+	}
+	if e.live != nil {
+		return ErrLive
+	}
+	rate := e.format.SampleRate
+	from, to := max(rate.N(start), 0), min(rate.N(end), e.stream.Len())
+	if r, ok := e.stream.(*readAhead); ok {
+		if err := r.loop(from, to); err != nil {
+			return err
+		}
+	}
+	e.backend.Lock()
+	e.loop.set(from, to)
+	e.backend.Unlock()
+	return nil
+}
+
+// ClearLoop plays the loaded audio on past the end of its loop.
+func (e *Engine) ClearLoop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.loop == nil {
+		return
+	}
+	if r, ok := e.stream.(*readAhead); ok {
+		if err := r.loop(0, 0); err != nil {
+			slog.Warn("Cannot stop looping the audio decoded ahead", "err", err)
+		}
+	}
+	e.backend.Lock()
+	e.loop.set(0, 0)
+	e.backend.Unlock()
+}
+
+// Silence returns how the stretches of near-silence are played.
+func (e *Engine) Silence() Silence {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.skip
+}
+
+// SetSilence sets how the stretches of near-silence are played, like skipping the pauses of
+// a lecture. It is kept for the next audio files until changed.
+func (e *Engine) SetSilence(s Silence) error {
+	if s.Mode < SilencePlay || s.Mode > SilenceFast {
+		return fmt.Errorf("unknown silence mode %d", s.Mode)
+	}
+	if s.Threshold >= 0 {
+		return fmt.Errorf("silence threshold %g dBFS is not negative", s.Threshold)
+	}
+	if s.Length < 0 {
+		return fmt.Errorf("silence length %s is negative", s.Length)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.skip = s
+	if e.silence != nil {
+		e.backend.Lock()
+		e.silence.set(s)
+		e.backend.Unlock()
+	}
+	return nil
+}
+
+// Clipped reports whether the audio played went past full scale since the last call, so
+// the output cut it.
+func (e *Engine) Clipped() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.meter == nil {
+		return false
+	}
+	e.backend.Lock()
+	defer e.backend.Unlock()
+	clipped := e.meter.clipped
+	e.meter.clipped = false
+	return clipped
+}
+
+// applyRate sets the speed and the pitch of the loaded audio. Played faster by resampling,
+// its pitch goes up by the same ratio, so it is stretched back to its speed before. The
+// caller must hold e.mu and, while playing, the backend lock.
+func (e *Engine) applyRate() {
+	ratio := math.Pow(2, float64(e.pitch)/12)
+	e.stretch.setSpeed(e.speed / ratio)
+	e.resample.SetRatio(float64(e.format.SampleRate) / float64(e.rate) * ratio)
+}
+
+// emit sends the event without blocking, with the path and position of the loaded audio.
+// The caller must hold e.mu.
+func (e *Engine) emit(ev Event) {
+	ev.Path = e.path
+	ev.Position = e.position()
+	select {
+	case e.events <- ev:
+	default:
+	}
+}
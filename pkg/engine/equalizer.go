@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"math"
+
+	"github.com/gopxl/beep/v2"
+)
+
+const (
+	// Range of the gain of each band of the equalizer in dB
+	MaxBandGain float64 = 12
+	// Range of the gain applied before the bands in dB, to make room for the boosted ones
+	MaxPreamp float64 = 12
+	// Width of the bands, wide enough to overlap the next ones an octave away
+	bandQ float64 = 1.41
+)
+
+// EqualizerBands are the center frequencies in Hz of the bands of the equalizer, an octave
+// apart.
+var EqualizerBands = [10]float64{31, 62, 125, 250, 500, 1000, 2000, 4000, 8000, 16000}
+
+// Gains : The gain in dB of each band of the equalizer, 0 leaving it untouched
+type Gains [len(EqualizerBands)]float64
+
+// biquad : A second order filter boosting or cutting a band, for both channels
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	// Previous inputs and outputs of each channel
+	x1, x2, y1, y2 [2]float64
+}
+
+// peaking returns the filter changing by gain dB the band around freq, from the Audio EQ
+// Cookbook of Robert Bristow-Johnson.
+func peaking(freq, gain float64, rate beep.SampleRate) biquad {
+	a := math.Pow(10, gain/40)
+	w0 := 2 * math.Pi * freq / float64(rate)
+	alpha := math.Sin(w0) / (2 * bandQ)
+	cos := math.Cos(w0)
+
+	a0 := 1 + alpha/a
+	return biquad{
+		b0: (1 + alpha*a) / a0,
+		b1: -2 * cos / a0,
+		b2: (1 - alpha*a) / a0,
+		a1: -2 * cos / a0,
+		a2: (1 - alpha/a) / a0,
+	}
+}
+
+func (f *biquad) process(x float64, ch int) float64 {
+	y := f.b0*x + f.b1*f.x1[ch] + f.b2*f.x2[ch] - f.a1*f.y1[ch] - f.a2*f.y2[ch]
+	f.x2[ch], f.x1[ch] = f.x1[ch], x
+	f.y2[ch], f.y1[ch] = f.y1[ch], y
+	return y
+}
+
+// equalizer : A streamer amplifying the audio by the preamp, then shaping it with a filter
+// per band, skipping the flat ones
+type equalizer struct {
+	Streamer beep.Streamer
+	rate     beep.SampleRate
+	preamp   float64
+	filters  []biquad
+}
+
+// newEqualizer creates an equalizer for audio at the given sample rate.
+func newEqualizer(s beep.Streamer, rate beep.SampleRate, gains Gains, preamp float64) *equalizer {
+	eq := &equalizer{Streamer: s, rate: rate}
+	eq.set(gains)
+	eq.setPreamp(preamp)
+	return eq
+}
+
+// setPreamp changes the gain in dB applied before the bands. The caller must hold the
+// backend lock.
+func (eq *equalizer) setPreamp(db float64) {
+	eq.preamp = math.Pow(10, db/20)
+}
+
+// set changes the gains of the bands. The caller must hold the backend lock.
+func (eq *equalizer) set(gains Gains) {
+	eq.filters = eq.filters[:0]
+	for i, freq := range EqualizerBands {
+		// Bands past the highest frequency of the audio cannot be filtered
+		if gains[i] == 0 || freq >= 0.45*float64(eq.rate) {
+			continue
+		}
+		eq.filters = append(eq.filters, peaking(freq, gains[i], eq.rate))
+	}
+}
+
+func (eq *equalizer) Stream(samples [][2]float64) (int, bool) {
+	n, ok := eq.Streamer.Stream(samples)
+	if eq.preamp != 1 {
+		for j := range samples[:n] {
+			samples[j][0] *= eq.preamp
+			samples[j][1] *= eq.preamp
+		}
+	}
+	for i := range eq.filters {
+		f := &eq.filters[i]
+		for j := range samples[:n] {
+			samples[j][0] = f.process(samples[j][0], 0)
+			samples[j][1] = f.process(samples[j][1], 1)
+		}
+	}
+	return n, ok
+}
+
+func (eq *equalizer) Err() error {
+	return eq.Streamer.Err()
+}
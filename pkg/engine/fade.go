@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// fader : A gain envelope fading the audio in and out, which pauses its ctrl once faded out
+type fader struct {
+	Streamer beep.Streamer
+	ctrl     *beep.Ctrl
+
+	// Gain applied to the audio, moving by step with each sample towards the target
+	gain   float64
+	target float64
+	step   float64
+
+	// pausing if the ctrl is paused once the audio is faded out
+	pausing bool
+}
+
+// newFader creates a fader at full gain for the streamer paused by ctrl.
+func newFader(s beep.Streamer, ctrl *beep.Ctrl) *fader {
+	return &fader{Streamer: s, ctrl: ctrl, gain: 1, target: 1, step: 1}
+}
+
+// fadeOut lowers the gain to silence in length, then pauses the audio. The caller must
+// hold the backend lock.
+func (f *fader) fadeOut(rate beep.SampleRate, length time.Duration) {
+	f.fade(0, rate, length)
+	f.pausing = true
+}
+
+// fadeIn resumes the audio, raising the gain back in length. The caller must hold the
+// backend lock.
+func (f *fader) fadeIn(rate beep.SampleRate, length time.Duration) {
+	f.ctrl.Paused = false
+	f.pausing = false
+	f.fade(1, rate, length)
+}
+
+// fade moves the gain to target in length, at once if it is zero.
+func (f *fader) fade(target float64, rate beep.SampleRate, length time.Duration) {
+	f.target = target
+	f.step = 1
+	if n := rate.N(length); n > 0 {
+		f.step = 1 / float64(n)
+	}
+}
+
+func (f *fader) Stream(samples [][2]float64) (int, bool) {
+	n, ok := f.Streamer.Stream(samples)
+	for i := range samples[:n] {
+		if f.gain < f.target {
+			f.gain = min(f.gain+f.step, f.target)
+		} else if f.gain > f.target {
+			f.gain = max(f.gain-f.step, f.target)
+		}
+		samples[i][0] *= f.gain
+		samples[i][1] *= f.gain
+	}
+
+	if f.pausing && f.gain == 0 {
+		f.ctrl.Paused = true
+		f.pausing = false
+	}
+	return n, ok
+}
+
+func (f *fader) Err() error {
+	return f.Streamer.Err()
+}
@@ -0,0 +1,278 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Size of the buffer the files served over HTTP are read through
+	httpBufferSize int = 64 << 10
+	// Longest forward seek read through instead of making another request
+	httpSkipLimit int64 = 256 << 10
+	// Longest time the server takes to answer a request, the body is read as it is played
+	httpTimeout time.Duration = 15 * time.Second
+)
+
+// Client of the files served over HTTP, without a timeout for the whole body, reading the
+// answers of the SHOUTcast servers too
+var httpClient = &http.Client{
+	Transport: func() http.RoundTripper {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.ResponseHeaderTimeout = httpTimeout
+		dial := t.DialContext
+		t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dial(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return &icyConn{Conn: conn}, nil
+		}
+		return t
+	}(),
+}
+
+// Extensions of the types of the audio served over HTTP, for the URLs without one
+var mediaTypes = map[string]string{
+	"audio/mpeg":      ".mp3",
+	"audio/mp3":       ".mp3",
+	"audio/wav":       ".wav",
+	"audio/wave":      ".wav",
+	"audio/x-wav":     ".wav",
+	"audio/vnd.wave":  ".wav",
+	"audio/flac":      ".flac",
+	"audio/x-flac":    ".flac",
+	"audio/ogg":       ".ogg",
+	"audio/vorbis":    ".ogg",
+	"application/ogg": ".ogg",
+}
+
+// IsURL reports whether path is the address of a file served over HTTP or HTTPS, which is
+// streamed instead of opened.
+func IsURL(path string) bool {
+	u, err := url.Parse(path)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Ext returns the extension of the file at path, or of the path of a URL, in lower case.
+func Ext(p string) string {
+	if IsURL(p) {
+		u, _ := url.Parse(p)
+		return strings.ToLower(path.Ext(u.Path))
+	}
+	return strings.ToLower(filepath.Ext(p))
+}
+
+// Open opens the file at path for reading, or the one at an HTTP(S) URL, which is read as it
+// is needed, seeking with range requests if the server supports them.
+func Open(path string) (io.ReadSeekCloser, error) {
+	if IsURL(path) {
+		return openURL(path)
+	}
+	return os.Open(path)
+}
+
+// remoteFile : A file served over HTTP, read through a buffer as the decoder needs it. The
+// seeks make another request for the range from there, or read through the file if the
+// server does not serve ranges. The stream of an internet radio is read without the metadata
+// sent along, which tells the title of what it broadcasts.
+type remoteFile struct {
+	url string
+
+	// Size of the file, -1 if unknown, its media type and whether the server serves ranges
+	size      int64
+	mediaType string
+	ranges    bool
+
+	// What the radio tells about itself, and the last title it told if not taken yet
+	station Station
+	title   string
+	titled  bool
+
+	// Position of the next read, and the body of the response being read at offset
+	pos    int64
+	body   io.ReadCloser
+	reader io.Reader
+	offset int64
+}
+
+// openURL requests the file at rawURL, failing if the server does not serve it.
+func openURL(rawURL string) (*remoteFile, error) {
+	f := &remoteFile{url: rawURL, size: -1}
+	if err := f.request(0); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// request starts reading the file at off. The range is asked even from the start, to know
+// if the server serves them.
+func (f *remoteFile) request(off int64) error {
+	f.closeBody()
+	req, err := http.NewRequest(http.MethodGet, f.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", off))
+	req.Header.Set("Icy-MetaData", "1")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		f.ranges = true
+		// Content-Range is like "bytes 100-999/1000", the total being * if unknown
+		if _, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok {
+			if size, err := strconv.ParseInt(total, 10, 64); err == nil {
+				f.size = size
+			}
+		}
+	case http.StatusOK:
+		if off > 0 {
+			resp.Body.Close()
+			return errors.New("the server cannot seek in the file")
+		}
+		f.ranges = false
+		f.size = resp.ContentLength
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Reading at the end or after it
+		resp.Body.Close()
+		f.body, f.reader, f.offset = http.NoBody, http.NoBody, off
+		return nil
+	default:
+		resp.Body.Close()
+		return fmt.Errorf("%s: %s", f.url, resp.Status)
+	}
+	if f.mediaType == "" {
+		f.mediaType, _, _ = mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	}
+	f.station = Station{
+		Name:        resp.Header.Get("Icy-Name"),
+		Genre:       resp.Header.Get("Icy-Genre"),
+		Description: resp.Header.Get("Icy-Description"),
+		URL:         resp.Header.Get("Icy-Url"),
+	}
+	f.station.Bitrate, _ = strconv.Atoi(resp.Header.Get("Icy-Br"))
+	f.body = resp.Body
+	f.reader = bufio.NewReaderSize(resp.Body, httpBufferSize)
+	if interval, _ := strconv.Atoi(resp.Header.Get("Icy-Metaint")); interval > 0 {
+		f.reader = &icyReader{r: f.reader, interval: interval, left: interval, onTitle: func(title string) {
+			f.title, f.titled = title, true
+		}}
+	}
+	f.offset = off
+	return nil
+}
+
+// radio reports whether the file is the stream of an internet radio, which tells about itself
+// in icy-* headers.
+func (f *remoteFile) radio() bool {
+	_, metadata := f.reader.(*icyReader)
+	return metadata || f.station != Station{}
+}
+
+// takeTitle returns the title the radio told since the last call, reporting false if none.
+func (f *remoteFile) takeTitle() (string, bool) {
+	title, titled := f.title, f.titled
+	f.titled = false
+	return title, titled
+}
+
+// ext returns the extension of the media type of the file, empty if it is not audio decoded.
+func (f *remoteFile) ext() string {
+	return mediaTypes[f.mediaType]
+}
+
+func (f *remoteFile) Read(p []byte) (int, error) {
+	if f.body == nil {
+		return 0, os.ErrClosed
+	}
+	if f.pos != f.offset {
+		if err := f.move(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.reader.Read(p)
+	if err != nil && err != io.EOF && n == 0 && f.ranges {
+		// The connection was lost, the file goes on from there
+		if err = f.request(f.pos); err == nil {
+			n, err = f.reader.Read(p)
+		}
+	}
+	f.pos += int64(n)
+	f.offset += int64(n)
+	return n, err
+}
+
+// move makes the response being read reach the position of the next read.
+func (f *remoteFile) move() error {
+	skip := f.pos - f.offset
+	switch {
+	case skip > 0 && (skip <= httpSkipLimit || !f.ranges):
+		// Reading through is faster than another request for short seeks, and the only way
+		// without ranges
+		n, err := io.CopyN(io.Discard, f.reader, skip)
+		f.offset += n
+		if err == io.EOF {
+			err = nil
+		}
+		return err
+	case f.ranges:
+		return f.request(f.pos)
+	}
+	// Without ranges the file is read again from the start
+	if err := f.request(0); err != nil {
+		return err
+	}
+	return f.move()
+}
+
+func (f *remoteFile) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = f.pos + offset
+	case io.SeekEnd:
+		if f.size < 0 {
+			return 0, errors.New("the server does not tell the size of the file")
+		}
+		pos = f.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if pos < 0 {
+		return 0, errors.New("negative position")
+	}
+	// The request is made by the next read, so seeking twice in a row makes one
+	f.pos = pos
+	return pos, nil
+}
+
+func (f *remoteFile) closeBody() {
+	if f.body != nil {
+		f.body.Close()
+	}
+}
+
+func (f *remoteFile) Close() error {
+	f.closeBody()
+	f.body, f.reader = nil, nil
+	return nil
+}
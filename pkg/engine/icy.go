@@ -0,0 +1,189 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// ErrLive is returned seeking in a live stream, which has no length.
+var ErrLive = errors.New("cannot seek in a live stream")
+
+// Station : What an internet radio tells about itself in the icy-* headers of its stream
+type Station struct {
+	Name        string
+	Genre       string
+	Description string
+	URL         string
+	// Bitrate in kbit/s, 0 if unknown
+	Bitrate int
+}
+
+// icyReader : The audio of an Icecast or SHOUTcast stream with a block of metadata every
+// interval bytes, which is taken out and read for the title of what is broadcast
+type icyReader struct {
+	r        io.Reader
+	interval int
+	// Bytes of audio before the next block
+	left int
+
+	onTitle func(string)
+}
+
+func (r *icyReader) Read(p []byte) (int, error) {
+	if r.left == 0 {
+		if err := r.readMetadata(); err != nil {
+			return 0, err
+		}
+		r.left = r.interval
+	}
+	n, err := r.r.Read(p[:min(len(p), r.left)])
+	r.left -= n
+	return n, err
+}
+
+// readMetadata reads a block, its length in 16 bytes first, which is 0 while the title does
+// not change.
+func (r *icyReader) readMetadata() error {
+	var length [1]byte
+	if _, err := io.ReadFull(r.r, length[:]); err != nil {
+		return err
+	}
+	if length[0] == 0 {
+		return nil
+	}
+	block := make([]byte, int(length[0])*16)
+	if _, err := io.ReadFull(r.r, block); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if title, ok := parseStreamTitle(block); ok {
+		r.onTitle(title)
+	}
+	return nil
+}
+
+// parseStreamTitle returns the title of a block like "StreamTitle='Artist - Title';" padded
+// with zeros. Most radios send it in UTF-8, the others in Latin-1.
+func parseStreamTitle(block []byte) (string, bool) {
+	s := strings.TrimRight(string(block), "\x00")
+	_, title, ok := strings.Cut(s, "StreamTitle='")
+	if !ok {
+		return "", false
+	}
+	// The title may hold quotes, so it ends at the one before the next field
+	if end := strings.Index(title, "';"); end >= 0 {
+		title = title[:end]
+	} else {
+		title = strings.TrimSuffix(title, "'")
+	}
+	if !utf8.ValidString(title) {
+		runes := make([]rune, len(title))
+		for i := range len(title) {
+			runes[i] = rune(title[i])
+		}
+		title = string(runes)
+	}
+	return strings.TrimSpace(title), true
+}
+
+// icyConn : A connection to a server, which may be a SHOUTcast one answering with "ICY 200 OK"
+// instead of a status line the HTTP client reads
+type icyConn struct {
+	net.Conn
+	read    bool
+	pending []byte
+}
+
+func (c *icyConn) Read(p []byte) (int, error) {
+	if len(c.pending) > 0 {
+		n := copy(p, c.pending)
+		c.pending = c.pending[n:]
+		return n, nil
+	}
+	n, err := c.Conn.Read(p)
+	if !c.read && n > 0 {
+		c.read = true
+		if rest, ok := bytes.CutPrefix(p[:n], []byte("ICY ")); ok {
+			c.pending = append([]byte("HTTP/1.0 "), rest...)
+			return c.Read(p)
+		}
+	}
+	return n, err
+}
+
+// liveStream : An endless stream, like the one of an internet radio, which has no length and
+// cannot be seeked. The titles the radio tells are kept with the sample they came with, so
+// they are shown once it is played rather than decoded.
+type liveStream struct {
+	beep.StreamSeekCloser
+	file *remoteFile
+
+	mu     sync.Mutex
+	titles []streamTitle
+}
+
+// streamTitle : A title told by the radio, from the sample at
+type streamTitle struct {
+	at    int
+	title string
+}
+
+func (l *liveStream) Stream(samples [][2]float64) (int, bool) {
+	n, ok := l.StreamSeekCloser.Stream(samples)
+	if title, told := l.file.takeTitle(); told {
+		l.mu.Lock()
+		l.titles = append(l.titles, streamTitle{at: l.Position(), title: title})
+		l.mu.Unlock()
+	}
+	return n, ok
+}
+
+func (l *liveStream) Len() int {
+	return 0
+}
+
+func (l *liveStream) Seek(p int) error {
+	return ErrLive
+}
+
+// titleAt returns the last title told up to the sample pos, reporting false if none was since
+// the previous call.
+func (l *liveStream) titleAt(pos int) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i := 0
+	for i < len(l.titles) && l.titles[i].at <= pos {
+		i++
+	}
+	if i == 0 {
+		return "", false
+	}
+	title := l.titles[i-1].title
+	l.titles = l.titles[i:]
+	return title, true
+}
+
+// titleWatch : The audio of a live stream after it is decoded ahead, telling the titles of the
+// radio as they are played
+type titleWatch struct {
+	beep.StreamSeeker
+	live *liveStream
+	tell func(string)
+}
+
+func (w *titleWatch) Stream(samples [][2]float64) (int, bool) {
+	n, ok := w.StreamSeeker.Stream(samples)
+	if title, told := w.live.titleAt(w.StreamSeeker.Position()); told {
+		w.tell(title)
+	}
+	return n, ok
+}
@@ -0,0 +1,50 @@
+package engine
+
+import "github.com/gopxl/beep/v2"
+
+// looper : A streamer playing a region of the audio again and again, or all of it once
+// while end is not after start. The audio decoded ahead loops itself, so the output never
+// waits for a seek.
+type looper struct {
+	Streamer beep.StreamSeeker
+	// Samples where the region starts and ends
+	start, end int
+	err        error
+}
+
+// set loops the region between the samples start and end, or stops looping if end is not
+// after start. The caller must hold the backend lock.
+func (l *looper) set(start, end int) {
+	l.start, l.end = start, end
+}
+
+func (l *looper) Stream(samples [][2]float64) (int, bool) {
+	if _, ahead := l.Streamer.(*readAhead); ahead || l.end <= l.start {
+		return l.Streamer.Stream(samples)
+	}
+
+	n := 0
+	for n < len(samples) {
+		pos := l.Streamer.Position()
+		if pos >= l.end {
+			if err := l.Streamer.Seek(l.start); err != nil {
+				l.err = err
+				break
+			}
+			continue
+		}
+		m, ok := l.Streamer.Stream(samples[n:min(len(samples), n+l.end-pos)])
+		n += m
+		if !ok || m == 0 {
+			break
+		}
+	}
+	return n, n > 0
+}
+
+func (l *looper) Err() error {
+	if l.err != nil {
+		return l.err
+	}
+	return l.Streamer.Err()
+}
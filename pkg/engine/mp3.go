@@ -0,0 +1,260 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/mp3"
+)
+
+// Bytes read at the start of the MP3 files served over HTTP to find their first frame
+const mp3HeadSize int = 8 << 10
+
+// Bitrates of the MPEG layer III frames in kbit/s by index, for MPEG-1 and MPEG-2 or 2.5
+var mp3Bitrates = [2][15]int{
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+// Sample rates of the MPEG frames by index, for MPEG-1, 2 and 2.5
+var mp3Rates = [3][3]int{
+	{44100, 48000, 32000},
+	{22050, 24000, 16000},
+	{11025, 12000, 8000},
+}
+
+// streamedMP3 : An MP3 file served over HTTP, decoded from where it is played since go-mp3
+// reads the whole file to know its length when it can seek. The length and the offsets of the
+// seeks are estimated from the first frame, exact with a constant bitrate or a Xing header.
+type streamedMP3 struct {
+	file *remoteFile
+	dec  beep.StreamSeekCloser
+
+	// Offset and size of the frames in the file, their length in samples, 0 if unknown, and
+	// the seek table of the Xing header, if any
+	start, size int64
+	length      int
+	toc         []byte
+
+	pos int
+}
+
+// decodeStreamedMP3 decodes the MP3 file from its first frame.
+func decodeStreamedMP3(f *remoteFile) (*streamedMP3, beep.Format, error) {
+	head, err := readHead(f)
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
+
+	// The ID3v2 tag, with the cover maybe, is not downloaded if the server serves ranges
+	var start int64
+	if len(head) >= 10 && string(head[:3]) == "ID3" {
+		start = 10 + (int64(head[6]&0x7f)<<21 | int64(head[7]&0x7f)<<14 | int64(head[8]&0x7f)<<7 | int64(head[9]&0x7f))
+		if head[5]&0x10 != 0 {
+			start += 10
+		}
+		if start < int64(len(head)) {
+			head = head[start:]
+		} else {
+			if _, err := f.Seek(start, io.SeekStart); err != nil {
+				return nil, beep.Format{}, err
+			}
+			if head, err = readHead(f); err != nil {
+				return nil, beep.Format{}, err
+			}
+		}
+	}
+
+	m := &streamedMP3{file: f, start: start}
+	frame, ok := findMP3Frame(head)
+	if ok {
+		m.start += int64(frame.offset)
+		if f.size > 0 {
+			m.size = f.size - m.start
+		}
+		switch {
+		case frame.frames > 0:
+			m.length = frame.frames * frame.samples
+			if frame.toc != nil && frame.bytes > 0 {
+				m.toc, m.size = frame.toc, int64(frame.bytes)
+			}
+		case m.size > 0 && frame.bitrate > 0:
+			m.length = int(m.size * 8 * int64(frame.rate) / int64(frame.bitrate))
+		}
+		head = head[frame.offset:]
+	}
+
+	dec, format, err := mp3.Decode(io.NopCloser(io.MultiReader(bytes.NewReader(head), f)))
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
+	m.dec = dec
+	return m, format, nil
+}
+
+// readHead reads the bytes where an MP3 frame is looked for.
+func readHead(r io.Reader) ([]byte, error) {
+	head := make([]byte, mp3HeadSize)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// mp3Frame : The first frame of an MP3 file, and the Xing header in it if any
+type mp3Frame struct {
+	// Offset of the frame in the bytes read
+	offset int
+	// Sample rate, bitrate in bit/s and samples of each frame
+	rate, bitrate, samples int
+
+	// Frames and bytes of the file and its seek table, told by the Xing header
+	frames, bytes int
+	toc           []byte
+}
+
+// findMP3Frame returns the first MPEG layer III frame in head followed by another one, or by
+// the end of head.
+func findMP3Frame(head []byte) (mp3Frame, bool) {
+	for i := 0; i+4 <= len(head); i++ {
+		frame, size, ok := parseMP3Header(head[i:])
+		if !ok {
+			continue
+		}
+		if next := i + size; next+4 <= len(head) {
+			if _, _, ok := parseMP3Header(head[next:]); !ok {
+				continue
+			}
+		}
+		frame.offset = i
+		parseXing(head[i:], &frame)
+		return frame, true
+	}
+	return mp3Frame{}, false
+}
+
+// parseMP3Header parses the header of an MPEG layer III frame, returning the size of the
+// frame.
+func parseMP3Header(b []byte) (mp3Frame, int, bool) {
+	if b[0] != 0xff || b[1]&0xe0 != 0xe0 {
+		return mp3Frame{}, 0, false
+	}
+	version := b[1] >> 3 & 3
+	layer := b[1] >> 1 & 3
+	bitrateIndex := b[2] >> 4
+	rateIndex := b[2] >> 2 & 3
+	if version == 1 || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return mp3Frame{}, 0, false
+	}
+
+	f := mp3Frame{samples: 1152}
+	rates, bitrates, factor := 0, 0, 144
+	switch version {
+	case 3: // MPEG-1
+	case 2: // MPEG-2
+		rates, bitrates, factor, f.samples = 1, 1, 72, 576
+	case 0: // MPEG-2.5
+		rates, bitrates, factor, f.samples = 2, 1, 72, 576
+	}
+	f.rate = mp3Rates[rates][rateIndex]
+	f.bitrate = mp3Bitrates[bitrates][bitrateIndex] * 1000
+	size := factor*f.bitrate/f.rate + int(b[2]>>1&1)
+	return f, size, true
+}
+
+// parseXing reads the frames, bytes and seek table of the Xing or Info header of the frame,
+// if it has one.
+func parseXing(b []byte, f *mp3Frame) {
+	mono := b[3]>>6 == 3
+	off := 4 + 32
+	switch {
+	case f.samples == 1152 && mono, f.samples == 576 && !mono:
+		off = 4 + 17
+	case f.samples == 576 && mono:
+		off = 4 + 9
+	}
+	if off+8 > len(b) || (string(b[off:off+4]) != "Xing" && string(b[off:off+4]) != "Info") {
+		return
+	}
+	flags := binary.BigEndian.Uint32(b[off+4:])
+	off += 8
+	if flags&1 != 0 && off+4 <= len(b) {
+		f.frames = int(binary.BigEndian.Uint32(b[off:]))
+		off += 4
+	}
+	if flags&2 != 0 && off+4 <= len(b) {
+		f.bytes = int(binary.BigEndian.Uint32(b[off:]))
+		off += 4
+	}
+	if flags&4 != 0 && off+100 <= len(b) {
+		f.toc = bytes.Clone(b[off : off+100])
+	}
+}
+
+func (m *streamedMP3) Stream(samples [][2]float64) (int, bool) {
+	n, ok := m.dec.Stream(samples)
+	m.pos += n
+	return n, ok
+}
+
+func (m *streamedMP3) Err() error {
+	return m.dec.Err()
+}
+
+func (m *streamedMP3) Len() int {
+	return m.length
+}
+
+func (m *streamedMP3) Position() int {
+	return m.pos
+}
+
+// Seek decodes the file again from the frame estimated to hold the sample p. go-mp3 stops at
+// the first bytes looking like a frame, so the decoder is given the file from the first one
+// followed by another.
+func (m *streamedMP3) Seek(p int) error {
+	if m.length <= 0 || m.size <= 0 {
+		return errors.New("cannot seek in an MP3 stream of unknown length")
+	}
+	if p < 0 || p > m.length {
+		return fmt.Errorf("seek position %v out of range [%v, %v]", p, 0, m.length)
+	}
+
+	fraction := float64(p) / float64(m.length)
+	if m.toc != nil {
+		// The table tells the offset of each percent of the duration, in 256ths of the size
+		percent := min(fraction*100, 99.999)
+		i := int(percent)
+		a, b := float64(m.toc[i]), 256.0
+		if i < 99 {
+			b = float64(m.toc[i+1])
+		}
+		fraction = (a + (b-a)*(percent-float64(i))) / 256
+	}
+	if _, err := m.file.Seek(m.start+int64(fraction*float64(m.size)), io.SeekStart); err != nil {
+		return err
+	}
+	head, err := readHead(m.file)
+	if err != nil {
+		return err
+	}
+	frame, ok := findMP3Frame(head)
+	if !ok {
+		return errors.New("cannot find an MP3 frame there")
+	}
+	dec, _, err := mp3.Decode(io.NopCloser(io.MultiReader(bytes.NewReader(head[frame.offset:]), m.file)))
+	if err != nil {
+		return err
+	}
+	m.dec, m.pos = dec, p
+	return nil
+}
+
+func (m *streamedMP3) Close() error {
+	return m.file.Close()
+}
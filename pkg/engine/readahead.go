@@ -0,0 +1,246 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+const (
+	// Audio decoded ahead of the playback by default
+	DefaultReadAhead time.Duration = 2 * time.Second
+	// Most audio decoded ahead, a minute of it takes around 5 MB
+	MaxReadAhead time.Duration = time.Minute
+	// Samples decoded at once
+	readAheadChunk int = 4096
+)
+
+// readAhead : A stream decoding the audio ahead of the playback into a ring buffer, on its
+// own goroutine, so a slow disk or a busy decoder does not make the output run dry
+type readAhead struct {
+	// Decoded audio, only used by the decoding goroutine, or while holding srcMu
+	src    beep.StreamSeekCloser
+	srcMu  sync.Mutex
+	length int
+
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// Ring buffer of the decoded samples, the first one at start
+	buf         [][2]float64
+	start, size int
+
+	// Position of the next sample streamed
+	pos int
+
+	// Region decoded again and again while end is after start, and the samples of the buffer
+	// where the position jumps back to its start. decoded and streamed count the samples since
+	// the last seek
+	loopStart, loopEnd int
+	wraps              []wrap
+	decoded, streamed  int
+
+	// drained if the decoded audio ended, with its error, and closed once it is closed
+	drained bool
+	err     error
+	closed  bool
+}
+
+// wrap : A jump of the position of the decoded audio, after the sample at counted in decoded
+// and streamed
+type wrap struct {
+	at, to int
+}
+
+// newReadAhead starts decoding src ahead, holding up to size samples.
+func newReadAhead(src beep.StreamSeekCloser, size int) *readAhead {
+	r := &readAhead{src: src, length: src.Len(), pos: src.Position()}
+	r.cond = sync.NewCond(&r.mu)
+	r.buf = make([][2]float64, max(size, readAheadChunk))
+	go r.decode()
+	return r
+}
+
+// decode fills the ring buffer until the stream is closed.
+func (r *readAhead) decode() {
+	chunk := make([][2]float64, readAheadChunk)
+	for {
+		r.mu.Lock()
+		for !r.closed && (r.drained || len(r.buf)-r.size < len(chunk)) {
+			r.cond.Wait()
+		}
+		r.mu.Unlock()
+
+		// Seeking waits for the chunk being decoded, which is then of the new position
+		r.srcMu.Lock()
+		r.mu.Lock()
+		closed := r.closed
+		start, end, decoded := r.loopStart, r.loopEnd, r.decoded
+		r.mu.Unlock()
+		if closed {
+			r.srcMu.Unlock()
+			return
+		}
+
+		// The loop goes back to its start here, ahead of the playback, so the output does
+		// not wait for the seek
+		size := len(chunk)
+		if end > start {
+			if r.src.Position() >= end {
+				err := r.src.Seek(start)
+				r.mu.Lock()
+				if err != nil {
+					r.drained, r.err = true, err
+				} else {
+					r.wraps = append(r.wraps, wrap{at: decoded, to: r.src.Position()})
+					r.jump()
+				}
+				r.mu.Unlock()
+				if err != nil {
+					r.srcMu.Unlock()
+					continue
+				}
+			}
+			size = min(size, end-r.src.Position())
+		}
+		n, ok := r.src.Stream(chunk[:size])
+
+		r.mu.Lock()
+		for _, sample := range chunk[:n] {
+			r.buf[(r.start+r.size)%len(r.buf)] = sample
+			r.size++
+		}
+		r.decoded += n
+		if !ok || n == 0 {
+			r.drained = true
+			r.err = r.src.Err()
+		}
+		r.cond.Broadcast()
+		r.mu.Unlock()
+		r.srcMu.Unlock()
+	}
+}
+
+// Stream fills samples from the ring buffer. When the decoding goroutine is behind, the rest
+// is filled with silence instead of waiting for it, as the backend streams while holding its
+// lock.
+func (r *readAhead) Stream(samples [][2]float64) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for ; n < len(samples) && r.size > 0; n++ {
+		samples[n] = r.buf[r.start]
+		r.start = (r.start + 1) % len(r.buf)
+		r.size--
+		r.pos++
+		r.streamed++
+		r.jump()
+	}
+	r.cond.Broadcast()
+	if n == len(samples) || r.drained || r.closed {
+		return n, n > 0
+	}
+
+	// The silence is not part of the audio, so the position stays
+	clear(samples[n:])
+	return len(samples), true
+}
+
+// jump moves the position back to the start of the loop once its last sample is streamed.
+// The caller must hold r.mu.
+func (r *readAhead) jump() {
+	if len(r.wraps) > 0 && r.wraps[0].at == r.streamed {
+		r.pos = r.wraps[0].to
+		r.wraps = r.wraps[1:]
+	}
+}
+
+func (r *readAhead) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *readAhead) Len() int {
+	return r.length
+}
+
+func (r *readAhead) Position() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pos
+}
+
+// Seek drops the decoded samples and decodes again from sample n. The decoding goroutine is
+// stopped while the audio is seeked, without holding the buffer the backend streams from, so
+// the caller does not need to hold the backend lock.
+func (r *readAhead) Seek(n int) error {
+	r.srcMu.Lock()
+	defer r.srcMu.Unlock()
+	err := r.src.Seek(n)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.start, r.size = 0, 0
+	r.pos = r.src.Position()
+	r.drained, r.err = false, nil
+	r.wraps, r.decoded, r.streamed = nil, 0, 0
+	r.cond.Broadcast()
+	return err
+}
+
+// loop decodes the region between the samples start and end again and again, or stops looping
+// if end is not after start. The samples already decoded are kept up to the end of the region,
+// and the playback jumps to its start if it is already past its end. Like Seek, it does not
+// need the backend lock.
+func (r *readAhead) loop(start, end int) error {
+	r.srcMu.Lock()
+	defer r.srcMu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer r.cond.Broadcast()
+
+	// The samples decoded in a row from the position, without jumping back
+	keep := r.size
+	if len(r.wraps) > 0 {
+		keep = r.wraps[0].at - r.streamed
+	}
+	if end > start {
+		keep = max(min(keep, end-r.pos), 0)
+	}
+	r.loopStart, r.loopEnd = start, end
+	r.size = keep
+	r.wraps, r.decoded, r.streamed = nil, keep, 0
+
+	// Decoding goes on after the kept samples, from the start of the region if they reach
+	// its end
+	next := r.pos + keep
+	if end > start && next >= end {
+		r.wraps = append(r.wraps, wrap{at: keep, to: start})
+		r.jump()
+		next = start
+	}
+	if next == r.src.Position() {
+		return nil
+	}
+	if next >= r.length {
+		r.drained = true
+		return nil
+	}
+	r.drained, r.err = false, nil
+	return r.src.Seek(next)
+}
+
+// Close stops the decoding goroutine and closes the decoded audio.
+func (r *readAhead) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	r.cond.Broadcast()
+	r.mu.Unlock()
+
+	r.srcMu.Lock()
+	defer r.srcMu.Unlock()
+	return r.src.Close()
+}
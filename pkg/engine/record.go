@@ -0,0 +1,250 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+const (
+	// Chunks of audio waiting to be written before they are dropped
+	recordBacklog int = 64
+	// Size of the buffer the recording is written through
+	recordBufferSize int = 64 << 10
+	// Size of the header of a WAV file, and the most bytes of audio its sizes can tell, the
+	// size of the file after the first 8 bytes being 32 bits
+	wavHeaderSize int64 = 44
+	wavMaxData    int64 = math.MaxUint32 - wavHeaderSize + 8
+)
+
+// ErrRecording is returned starting a recording while there is one already.
+var ErrRecording = errors.New("already recording")
+
+// ErrNotRecording is returned stopping a recording when there is none.
+var ErrNotRecording = errors.New("not recording")
+
+// Recording : A WAV file the played audio is written to
+type Recording struct {
+	Path string
+	// Length of the audio written
+	Length time.Duration
+}
+
+// recorder : A streamer writing the audio passing through it to the recording, if any
+type recorder struct {
+	Streamer beep.Streamer
+	rec      *recording
+}
+
+func (r *recorder) Stream(samples [][2]float64) (int, bool) {
+	n, ok := r.Streamer.Stream(samples)
+	if r.rec != nil && n > 0 {
+		r.rec.write(samples[:n])
+	}
+	return n, ok
+}
+
+func (r *recorder) Err() error {
+	return r.Streamer.Err()
+}
+
+// recording : The 16-bit stereo WAV file the audio is written to at rate. The audio goroutine
+// only encodes the samples, a goroutine of its own writes them so the disk never holds the
+// playback back, and the chunks it cannot keep up with are dropped.
+type recording struct {
+	path string
+	file *os.File
+	rate beep.SampleRate
+
+	chunks chan []byte
+	done   chan struct{}
+
+	// Samples written and dropped, and the error writing them
+	samples atomic.Int64
+	dropped atomic.Int64
+	err     error
+}
+
+// newRecording creates the file at path, failing if it exists, and starts writing to it.
+func newRecording(path string, rate beep.SampleRate) (*recording, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	// The sizes are known once the recording stops
+	if _, err := f.Write(wavHeader(rate, 0)); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	r := &recording{
+		path:   path,
+		file:   f,
+		rate:   rate,
+		chunks: make(chan []byte, recordBacklog),
+		done:   make(chan struct{}),
+	}
+	go r.run()
+	return r, nil
+}
+
+// wavHeader returns the header of a 16-bit stereo WAV file with size bytes of audio.
+func wavHeader(rate beep.SampleRate, size int64) []byte {
+	const channels, bytesPerSample = 2, 2
+	h := make([]byte, 0, wavHeaderSize)
+	h = append(h, "RIFF"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(size+wavHeaderSize-8))
+	h = append(h, "WAVEfmt "...)
+	h = binary.LittleEndian.AppendUint32(h, 16)
+	h = binary.LittleEndian.AppendUint16(h, 1) // PCM
+	h = binary.LittleEndian.AppendUint16(h, channels)
+	h = binary.LittleEndian.AppendUint32(h, uint32(rate))
+	h = binary.LittleEndian.AppendUint32(h, uint32(int(rate)*channels*bytesPerSample))
+	h = binary.LittleEndian.AppendUint16(h, channels*bytesPerSample)
+	h = binary.LittleEndian.AppendUint16(h, 8*bytesPerSample)
+	h = append(h, "data"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(size))
+	return h
+}
+
+// write encodes the samples and hands them to the writing goroutine, dropping them if it is
+// behind.
+func (r *recording) write(samples [][2]float64) {
+	chunk := make([]byte, 0, len(samples)*4)
+	for _, s := range samples {
+		for _, v := range s {
+			v = max(min(v, 1), -1)
+			chunk = binary.LittleEndian.AppendUint16(chunk, uint16(int16(v*math.MaxInt16)))
+		}
+	}
+	select {
+	case r.chunks <- chunk:
+		r.samples.Add(int64(len(samples)))
+	default:
+		r.dropped.Add(int64(len(samples)))
+	}
+}
+
+// run writes the chunks until the channel is closed, stopping at the first error or once the
+// file is as long as a WAV file can be.
+func (r *recording) run() {
+	defer close(r.done)
+	w := bufio.NewWriterSize(r.file, recordBufferSize)
+	var written int64
+	for chunk := range r.chunks {
+		if r.err != nil {
+			continue
+		}
+		if written+int64(len(chunk)) > wavMaxData {
+			r.err = errors.New("the recording reached the 4 GiB limit of WAV files")
+			slog.Warn("Stopped writing the recording", "path", r.path, "err", r.err)
+			continue
+		}
+		if _, err := w.Write(chunk); err != nil {
+			r.err = err
+			slog.Warn("Cannot write the recording", "path", r.path, "err", err)
+			continue
+		}
+		written += int64(len(chunk))
+	}
+	if err := w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	// The header tells the size of what was written
+	if _, err := r.file.Seek(0, io.SeekStart); err == nil {
+		_, err = r.file.Write(wavHeader(r.rate, written))
+		if r.err == nil {
+			r.err = err
+		}
+	} else if r.err == nil {
+		r.err = err
+	}
+}
+
+// length returns how long the audio handed to the recording is.
+func (r *recording) length() time.Duration {
+	return r.rate.D(int(r.samples.Load()))
+}
+
+// close finishes writing the file. No samples may be written after it is called.
+func (r *recording) close() error {
+	close(r.chunks)
+	<-r.done
+	if dropped := r.dropped.Load(); dropped > 0 {
+		slog.Warn("Dropped audio of the recording, the disk was too slow", "path", r.path,
+			"dropped", r.rate.D(int(dropped)))
+	}
+	err := r.file.Close()
+	if r.err != nil {
+		return r.err
+	}
+	return err
+}
+
+// StartRecording writes the audio being played, and the one played after it, to a new 16-bit
+// WAV file at path, at the sample rate of the output. It is taken before the equalizer, the
+// volume and the other effects, and after the speed and pitch changes. The recording goes on
+// across the audio files loaded until StopRecording or Close.
+func (e *Engine) StartRecording(path string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stream == nil {
+		return ErrNotLoaded
+	}
+	if e.rec != nil {
+		return ErrRecording
+	}
+	rec, err := newRecording(path, e.rate)
+	if err != nil {
+		return err
+	}
+	e.rec = rec
+	e.backend.Lock()
+	e.record.rec = rec
+	e.backend.Unlock()
+	slog.Info("Recording", "path", path, "rate", int(e.rate))
+	return nil
+}
+
+// StopRecording finishes the recording, returning its file and length.
+func (e *Engine) StopRecording() (Recording, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stopRecording()
+}
+
+// stopRecording finishes the recording. The caller must hold e.mu.
+func (e *Engine) stopRecording() (Recording, error) {
+	if e.rec == nil {
+		return Recording{}, ErrNotRecording
+	}
+	rec := e.rec
+	e.rec = nil
+	if e.record != nil {
+		e.backend.Lock()
+		e.record.rec = nil
+		e.backend.Unlock()
+	}
+	r := Recording{Path: rec.path, Length: rec.length()}
+	err := rec.close()
+	slog.Info("Stopped recording", "path", r.Path, "length", r.Length, "err", err)
+	return r, err
+}
+
+// Recording returns the recording going on, reporting false if there is none.
+func (e *Engine) Recording() (Recording, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.rec == nil {
+		return Recording{}, false
+	}
+	return Recording{Path: e.rec.path, Length: e.rec.length()}, true
+}
@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+const (
+	// Length of the windows the audio is told silent or not by, long enough to hold a
+	// whole wave of the lowest notes
+	silenceWindow time.Duration = 20 * time.Millisecond
+	// Only one of this many silent windows past the kept length is played in SilenceFast
+	silenceFastFactor int = 4
+	// Most silence dropped at once, so a long stretch does not hold the output for too long
+	silenceMaxDrop time.Duration = 2 * time.Second
+)
+
+// SilenceMode : How the stretches of near-silence of the audio are played
+type SilenceMode int
+
+const (
+	// SilencePlay plays the silence as it is
+	SilencePlay SilenceMode = iota
+	// SilenceSkip cuts every stretch of silence down to its kept length
+	SilenceSkip
+	// SilenceFast plays the silence past its kept length several times faster
+	SilenceFast
+)
+
+var silenceModeNames = []string{"off", "skip", "fast"}
+
+func (m SilenceMode) String() string {
+	if m < 0 || int(m) >= len(silenceModeNames) {
+		return "unknown"
+	}
+	return silenceModeNames[m]
+}
+
+// Next returns the mode that follows m, going back to SilencePlay after the last one.
+func (m SilenceMode) Next() SilenceMode {
+	return (m + 1) % SilenceMode(len(silenceModeNames))
+}
+
+// ParseSilenceMode converts a mode name (off, skip or fast) into a SilenceMode.
+func ParseSilenceMode(s string) (SilenceMode, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, name := range silenceModeNames {
+		if s == name {
+			return SilenceMode(i), nil
+		}
+	}
+	return SilencePlay, fmt.Errorf("unknown silence mode %q", s)
+}
+
+// Silence : How the stretches of near-silence, like the pauses of lectures and interviews,
+// are played
+type Silence struct {
+	Mode SilenceMode
+	// Threshold in dBFS under which the audio is silent
+	Threshold float64
+	// Length of each stretch played as it is, before it is skipped or sped through
+	Length time.Duration
+}
+
+// DefaultSilence returns the silence settings of a new engine, playing it as it is.
+func DefaultSilence() Silence {
+	return Silence{Mode: SilencePlay, Threshold: -50, Length: time.Second}
+}
+
+// silenceSkipper : A streamer skipping or speeding through the stretches of silence of the
+// audio, once they last longer than the kept length
+type silenceSkipper struct {
+	Streamer beep.Streamer
+	settings Silence
+
+	// Amplitude under which the audio is silent, and the samples of each window, of the
+	// silence kept and of the most silence dropped at once
+	threshold             float64
+	window, keep, maxDrop int
+	rate                  beep.SampleRate
+
+	// Window being streamed and how much of it was streamed already
+	buf [][2]float64
+	pos int
+
+	// Samples of the current stretch of silence, and its silent windows past the kept length
+	run, dropped int
+
+	// drained if the input has ended
+	drained bool
+}
+
+// newSilenceSkipper creates a silence skipper for audio at the given sample rate.
+func newSilenceSkipper(s beep.Streamer, rate beep.SampleRate, settings Silence) *silenceSkipper {
+	sk := &silenceSkipper{Streamer: s, rate: rate}
+	sk.window = rate.N(silenceWindow)
+	sk.maxDrop = rate.N(silenceMaxDrop)
+	sk.set(settings)
+	return sk
+}
+
+// set changes how the silence is played. The caller must hold the backend lock.
+func (s *silenceSkipper) set(settings Silence) {
+	s.settings = settings
+	s.threshold = math.Pow(10, settings.Threshold/20)
+	s.keep = s.rate.N(settings.Length)
+}
+
+// reset forgets the current stretch of silence, after the input is seeked. The caller must
+// hold the backend lock.
+func (s *silenceSkipper) reset() {
+	s.buf = s.buf[:0]
+	s.pos = 0
+	s.run, s.dropped = 0, 0
+	s.drained = false
+}
+
+func (s *silenceSkipper) Stream(samples [][2]float64) (int, bool) {
+	n := 0
+	for n < len(samples) {
+		if s.pos < len(s.buf) {
+			c := copy(samples[n:], s.buf[s.pos:])
+			s.pos += c
+			n += c
+			continue
+		}
+
+		if s.settings.Mode == SilencePlay {
+			s.run, s.dropped = 0, 0
+			m, ok := s.Streamer.Stream(samples[n:])
+			n += m
+			if !ok || m == 0 {
+				break
+			}
+			continue
+		}
+
+		if !s.next() {
+			break
+		}
+	}
+	return n, n > 0
+}
+
+// next reads the next window to play, dropping the silent ones past the kept length, and
+// reports false once the input ends.
+func (s *silenceSkipper) next() bool {
+	drop := 0
+	for {
+		if !s.read() {
+			return false
+		}
+		if !s.silent() {
+			s.run, s.dropped = 0, 0
+			return true
+		}
+
+		s.run += len(s.buf)
+		if s.run <= s.keep || drop >= s.maxDrop {
+			return true
+		}
+		if s.settings.Mode == SilenceFast {
+			if s.dropped++; s.dropped%silenceFastFactor == 0 {
+				return true
+			}
+		}
+		drop += len(s.buf)
+	}
+}
+
+// read fills the buffer with the next window of the input, reporting false if it ended.
+func (s *silenceSkipper) read() bool {
+	if cap(s.buf) < s.window {
+		s.buf = make([][2]float64, s.window)
+	}
+	s.buf = s.buf[:s.window]
+	s.pos = 0
+
+	n := 0
+	for n < s.window && !s.drained {
+		m, ok := s.Streamer.Stream(s.buf[n:])
+		n += m
+		if !ok || m == 0 {
+			s.drained = true
+		}
+	}
+	s.buf = s.buf[:n]
+	return n > 0
+}
+
+// silent reports whether the window in the buffer stays under the threshold.
+func (s *silenceSkipper) silent() bool {
+	for _, sample := range s.buf {
+		if math.Abs(sample[0]) > s.threshold || math.Abs(sample[1]) > s.threshold {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *silenceSkipper) Err() error {
+	return s.Streamer.Err()
+}
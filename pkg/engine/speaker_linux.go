@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// Sound cards registered in ALSA, one per entry like " 1 [DAC ]: USB-Audio - USB Audio DAC"
+const alsaCards string = "/proc/asound/cards"
+
+// speakerDevices lists the ALSA sound cards by their id.
+func speakerDevices() ([]Device, error) {
+	file, err := os.Open(alsaCards)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseCards(file)
+}
+
+// parseCards reads the sound cards in the format of /proc/asound/cards.
+func parseCards(r io.Reader) ([]Device, error) {
+	var devices []Device
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// The second line of each card starts with spaces
+		line := scanner.Text()
+		left, right := strings.Index(line, "["), strings.Index(line, "]")
+		if strings.HasPrefix(line, "  ") || left < 0 || right < left {
+			continue
+		}
+		d := Device{Name: strings.TrimSpace(line[left+1 : right])}
+		if _, desc, ok := strings.Cut(line[right:], " - "); ok {
+			d.Description = strings.TrimSpace(desc)
+		}
+		devices = append(devices, d)
+	}
+	return devices, scanner.Err()
+}
+
+// setSpeakerDevice makes the default ALSA device play on the card with the given id. Sound
+// servers like PulseAudio and PipeWire ignore it, since they are the default device.
+func setSpeakerDevice(name string) error {
+	if name == "" {
+		return os.Unsetenv("ALSA_CARD")
+	}
+	return os.Setenv("ALSA_CARD", name)
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package engine
+
+// speakerDevices is not supported on this platform.
+func speakerDevices() ([]Device, error) {
+	return nil, nil
+}
+
+// setSpeakerDevice is not supported on this platform.
+func setSpeakerDevice(name string) error {
+	if name == "" {
+		return nil
+	}
+	return ErrNoDevices
+}
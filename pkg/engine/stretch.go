@@ -0,0 +1,202 @@
+package engine
+
+import (
+	"math"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+const (
+	// Range of the playback speed
+	MinSpeed float64 = 0.5
+	MaxSpeed float64 = 3
+	// Length of the blocks joined to stretch the audio, half of the overlapping frames
+	stretchHop time.Duration = 20 * time.Millisecond
+	// How far from the expected position the frame joined best is searched, about the
+	// period of a low voice
+	stretchTolerance time.Duration = 10 * time.Millisecond
+	// Only every few samples are compared while searching, which is enough to align waves
+	searchStride int = 4
+)
+
+// stretcher : A streamer changing the speed of the audio without changing its pitch. It
+// joins overlapping frames of the input, each one taken where its wave continues the
+// previous frame best (WSOLA). At normal speed the input passes through untouched.
+type stretcher struct {
+	Streamer beep.Streamer
+	speed    float64
+
+	// Samples of every output block, and how many around the expected position are searched
+	hop, tolerance int
+
+	// Rising half of the window crossfading the frames, the falling half is its complement
+	window []float64
+
+	// Input read and not dropped yet, the start in it of the frame joined last (-1 before
+	// the first one), and the position expected for the next frame
+	buf  [][2]float64
+	prev int
+	next float64
+
+	// Output block being streamed and how much of it was streamed already
+	out    [][2]float64
+	outPos int
+
+	// drained if the input has ended
+	drained bool
+}
+
+// newStretcher creates a stretcher for audio at the given sample rate, at normal speed.
+func newStretcher(s beep.Streamer, rate beep.SampleRate) *stretcher {
+	st := &stretcher{Streamer: s, speed: 1, prev: -1}
+	st.hop = rate.N(stretchHop)
+	st.tolerance = rate.N(stretchTolerance)
+	st.window = make([]float64, st.hop)
+	for i := range st.window {
+		st.window[i] = 0.5 - 0.5*math.Cos(math.Pi*(float64(i)+0.5)/float64(st.hop))
+	}
+	return st
+}
+
+// setSpeed changes the speed. Going back to normal speed streams the input read ahead
+// before passing it through. The caller must hold the backend lock.
+func (s *stretcher) setSpeed(speed float64) {
+	if speed == s.speed {
+		return
+	}
+	if speed == 1 && s.prev >= 0 {
+		s.out = append(s.out[s.outPos:], s.buf[s.prev+s.hop:]...)
+		s.outPos = 0
+		s.buf = s.buf[:0]
+		s.prev, s.next = -1, 0
+	}
+	s.speed = speed
+}
+
+// reset forgets the input read ahead, after the input is seeked. The caller must hold the
+// backend lock.
+func (s *stretcher) reset() {
+	s.buf = s.buf[:0]
+	s.prev, s.next = -1, 0
+	s.out = s.out[:0]
+	s.outPos = 0
+	s.drained = false
+}
+
+func (s *stretcher) Stream(samples [][2]float64) (int, bool) {
+	n := 0
+	for n < len(samples) {
+		if s.outPos < len(s.out) {
+			c := copy(samples[n:], s.out[s.outPos:])
+			s.outPos += c
+			n += c
+			continue
+		}
+
+		if s.speed == 1 {
+			m, ok := s.Streamer.Stream(samples[n:])
+			n += m
+			if !ok || m == 0 {
+				break
+			}
+			continue
+		}
+
+		if !s.block() {
+			break
+		}
+	}
+	return n, n > 0
+}
+
+// block joins the next frame of the input into a new output block, reporting false once
+// the input ends.
+func (s *stretcher) block() bool {
+	s.drop()
+
+	target := int(math.Round(s.next))
+	if !s.fill(max(target+s.tolerance, s.prev+s.hop) + s.hop) {
+		// The rest of the last frame ends the audio
+		if s.prev < 0 || s.prev+s.hop >= len(s.buf) {
+			return false
+		}
+		s.out = append(s.out[:0], s.buf[s.prev+s.hop:]...)
+		s.outPos = 0
+		s.prev = len(s.buf)
+		return true
+	}
+
+	s.out = s.out[:0]
+	s.outPos = 0
+	if s.prev < 0 {
+		target = min(target, len(s.buf)-s.hop)
+		s.out = append(s.out, s.buf[target:target+s.hop]...)
+	} else {
+		// The continuation of the previous frame fades out while the new one fades in
+		natural := s.prev + s.hop
+		target = s.search(natural, target)
+		for i, w := range s.window {
+			a, b := s.buf[natural+i], s.buf[target+i]
+			s.out = append(s.out, [2]float64{
+				a[0]*(1-w) + b[0]*w,
+				a[1]*(1-w) + b[1]*w,
+			})
+		}
+	}
+
+	s.prev = target
+	s.next += float64(s.hop) * s.speed
+	return true
+}
+
+// search returns the start of the frame around target whose wave is the most similar to
+// the continuation of the previous frame at natural.
+func (s *stretcher) search(natural, target int) int {
+	first := max(target-s.tolerance, 0)
+	last := min(target+s.tolerance, len(s.buf)-s.hop)
+
+	best, bestScore := min(max(target, first), last), math.Inf(-1)
+	for k := first; k <= last; k++ {
+		var corr, energy float64
+		for i := 0; i < s.hop; i += searchStride {
+			a := s.buf[natural+i][0] + s.buf[natural+i][1]
+			b := s.buf[k+i][0] + s.buf[k+i][1]
+			corr += a * b
+			energy += b * b
+		}
+		if score := corr / math.Sqrt(energy+1e-9); score > bestScore {
+			best, bestScore = k, score
+		}
+	}
+	return best
+}
+
+// fill reads the input until the buffer holds n samples, reporting false if it ends before.
+func (s *stretcher) fill(n int) bool {
+	var chunk [512][2]float64
+	for len(s.buf) < n && !s.drained {
+		m, ok := s.Streamer.Stream(chunk[:])
+		s.buf = append(s.buf, chunk[:m]...)
+		if !ok {
+			s.drained = true
+		}
+	}
+	return len(s.buf) >= n
+}
+
+// drop forgets the input before the frames that can still be joined, once there is enough
+// of it to be worth moving the rest.
+func (s *stretcher) drop() {
+	d := min(s.prev, int(s.next)-s.tolerance)
+	if d < 4*s.hop {
+		return
+	}
+	s.buf = s.buf[:copy(s.buf, s.buf[d:])]
+	s.prev -= d
+	s.next -= float64(d)
+}
+
+func (s *stretcher) Err() error {
+	return s.Streamer.Err()
+}